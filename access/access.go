@@ -0,0 +1,109 @@
+// Package access counts downloads in memory and writes them out in batches,
+// so that a popular file doesn't turn every download into a write on its row.
+package access
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Recorder struct {
+	db  db_access.FileRepo
+	log *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]db_access.FileAccess
+}
+
+func New(db db_access.FileRepo, log *slog.Logger) *Recorder {
+	return &Recorder{
+		db:      db,
+		log:     log.With(slog.String("component", "access")),
+		pending: make(map[string]db_access.FileAccess),
+	}
+}
+
+// Record notes a download of the file; it shows up in the db after the next Flush
+func (r *Recorder) Record(generatedName string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	access := r.pending[generatedName]
+	access.GeneratedName = generatedName
+	access.Count++
+	if at.After(time.Time(access.At)) {
+		access.At = db_access.Time(at)
+	}
+	r.pending[generatedName] = access
+}
+
+// Flush writes out what was recorded so far; on failure it is kept for the next attempt
+func (r *Recorder) Flush() error {
+	const op = "access.Recorder.Flush"
+
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[string]db_access.FileAccess)
+	r.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	accesses := make([]db_access.FileAccess, 0, len(pending))
+	for _, access := range pending {
+		accesses = append(accesses, access)
+	}
+
+	if err := r.db.RecordFileAccesses(accesses); err != nil {
+		r.mu.Lock()
+		for _, access := range accesses {
+			r.merge(access)
+		}
+		r.mu.Unlock()
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// merge puts back an access that could not be written; r.mu must be held
+func (r *Recorder) merge(access db_access.FileAccess) {
+	newer, ok := r.pending[access.GeneratedName]
+	if !ok {
+		r.pending[access.GeneratedName] = access
+		return
+	}
+
+	newer.Count += access.Count
+	if time.Time(access.At).After(time.Time(newer.At)) {
+		newer.At = access.At
+	}
+	r.pending[access.GeneratedName] = newer
+}
+
+// Run flushes every interval until ctx is done and once more after that.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Flush(); err != nil {
+				r.log.Error("Could not record file accesses", slogext.Error(err))
+			}
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				r.log.Error("Could not record file accesses", slogext.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package access_test
+
+import (
+	"cloud-storage/access"
+	"cloud-storage/db_access/sqlite"
+	slogext "cloud-storage/utils/slogExt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlush(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	for _, name := range []string{"old", "new", "untouched"} {
+		require.NoError(t, db.AddFile(name, "enc:"+name, 1, 1))
+	}
+
+	now := time.Now().Truncate(time.Second)
+	r := access.New(db, slogext.NewDiscardLogger())
+	r.Record("old", now.Add(-time.Hour))
+	r.Record("new", now)
+	// out of order downloads must not move the last access back
+	r.Record("new", now.Add(-time.Minute))
+	r.Record("gone", now)
+	require.NoError(t, r.Flush())
+
+	r.Record("new", now.Add(-2*time.Minute))
+	require.NoError(t, r.Flush())
+	// nothing recorded since the last flush
+	require.NoError(t, r.Flush())
+
+	files, err := db.GetRecentFiles(1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
+
+	assert.Equal(t, "new", files[0].GeneratedName)
+	assert.Equal(t, now, time.Time(files[0].LastAccess))
+	assert.Equal(t, int64(3), files[0].DownloadCount)
+
+	assert.Equal(t, "old", files[1].GeneratedName)
+	assert.Equal(t, now.Add(-time.Hour), time.Time(files[1].LastAccess))
+	assert.Equal(t, int64(1), files[1].DownloadCount)
+
+	files, err = db.GetRecentFiles(1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+}
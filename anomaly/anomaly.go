@@ -0,0 +1,356 @@
+// Package anomaly raises alerts on activity far off what is usual for a user: an hour of many times
+// the usual downloads, mass deletes and logins from a country the user hasn't logged in from before.
+// Alerts are recorded as audit events and handed to the notifiers, webhooks or mail.
+//
+// The counters of the current hour are kept in memory, so each server of a cluster only sees its own share.
+package anomaly
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// alerts waiting for the notifiers; more are dropped after being recorded
+const queueSize = 100
+
+// notifyTimeout bounds how long one notifier may take with an alert
+const notifyTimeout = 30 * time.Second
+
+type Kind string
+
+const (
+	DownloadVolume Kind = "download-volume"
+	MassDelete     Kind = "mass-delete"
+	NewCountry     Kind = "new-country"
+)
+
+type Alert struct {
+	Kind       Kind      `json:"kind"`
+	UserId     int64     `json:"user_id"`
+	Detail     string    `json:"detail"`
+	At         time.Time `json:"at"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	RequestId  string    `json:"request_id,omitempty"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Thresholds are what is unusual for a user; see db_access.AlertThresholds
+type Thresholds struct {
+	DownloadFactor   float64
+	MinDownloadBytes int64
+	DeletesPerHour   int64
+	NewCountries     bool
+}
+
+// With applies the overrides of a user
+func (t Thresholds) With(o db_access.AlertThresholds) Thresholds {
+	if o.DownloadFactor != nil {
+		t.DownloadFactor = *o.DownloadFactor
+	}
+	if o.MinDownloadBytes != nil {
+		t.MinDownloadBytes = *o.MinDownloadBytes
+	}
+	if o.DeletesPerHour != nil {
+		t.DeletesPerHour = *o.DeletesPerHour
+	}
+	if o.NewCountries != nil {
+		t.NewCountries = *o.NewCountries
+	}
+	return t
+}
+
+type Config struct {
+	// Defaults apply to users without thresholds of their own
+	Defaults Thresholds
+	// CountryHeader carries the ISO 3166 country of the client, as a fronting proxy or CDN sets it,
+	// e.g. CF-IPCountry; logins aren't checked without one. Clients must not be able to set it themselves.
+	CountryHeader string
+	// Cooldown is how long a user gets no second alert of the same kind
+	Cooldown  time.Duration
+	Notifiers []Notifier
+}
+
+type Detector struct {
+	db     db_access.DbAccess
+	cfg    Config
+	log    *slog.Logger
+	alerts chan Alert
+
+	mu     sync.Mutex
+	hour   time.Time
+	users  map[int64]*window
+	raised map[raisedKey]time.Time
+}
+
+// window counts what a user did in the current hour
+type window struct {
+	thresholds Thresholds
+	// usual is the hourly download volume of the user over this month and the one before
+	usual      float64
+	downloaded int64
+	deleted    int64
+}
+
+type raisedKey struct {
+	userId int64
+	kind   Kind
+}
+
+func New(db db_access.DbAccess, cfg Config, log *slog.Logger) *Detector {
+	return &Detector{
+		db:     db,
+		cfg:    cfg,
+		log:    log.With(slog.String("component", "anomaly")),
+		alerts: make(chan Alert, queueSize),
+		users:  make(map[int64]*window),
+		raised: make(map[raisedKey]time.Time),
+	}
+}
+
+func (d *Detector) Defaults() Thresholds {
+	if d == nil {
+		return Thresholds{}
+	}
+	return d.cfg.Defaults
+}
+
+// Forget drops what the detector has loaded of the user, so that changed thresholds apply
+func (d *Detector) Forget(userId int64) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.users, userId)
+}
+
+// Run hands raised alerts to the notifiers until ctx is done
+func (d *Detector) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alert := <-d.alerts:
+			for _, notifier := range d.cfg.Notifiers {
+				notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+				if err := notifier.Notify(notifyCtx, alert); err != nil {
+					d.log.Error("Could not send alert", slogext.Error(err), slog.String("kind", string(alert.Kind)))
+				}
+				cancel()
+			}
+		}
+	}
+}
+
+// Downloaded counts n bytes the user downloaded with r
+func (d *Detector) Downloaded(r *http.Request, userId int64, n int64) {
+	if d == nil || userId < 0 || n <= 0 {
+		return
+	}
+
+	now := time.Now()
+	w, ok := d.window(userId, now)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	w.downloaded += n
+	downloaded, t, usual := w.downloaded, w.thresholds, w.usual
+	d.mu.Unlock()
+
+	if t.DownloadFactor <= 0 || float64(downloaded) <= max(float64(t.MinDownloadBytes), t.DownloadFactor*usual) {
+		return
+	}
+	d.raise(r, Alert{
+		Kind:   DownloadVolume,
+		UserId: userId,
+		Detail: fmt.Sprintf("downloaded %d bytes this hour, usually %.0f", downloaded, usual),
+		At:     now,
+	})
+}
+
+// Deleted counts n files the user deleted with r
+func (d *Detector) Deleted(r *http.Request, userId int64, n int64) {
+	if d == nil || userId < 0 || n <= 0 {
+		return
+	}
+
+	now := time.Now()
+	w, ok := d.window(userId, now)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	w.deleted += n
+	deleted, t := w.deleted, w.thresholds
+	d.mu.Unlock()
+
+	if t.DeletesPerHour <= 0 || deleted <= t.DeletesPerHour {
+		return
+	}
+	d.raise(r, Alert{
+		Kind:   MassDelete,
+		UserId: userId,
+		Detail: fmt.Sprintf("deleted %d files this hour", deleted),
+		At:     now,
+	})
+}
+
+// LoggedIn checks the country the user logged in from with r
+func (d *Detector) LoggedIn(r *http.Request, userId int64) {
+	if d == nil || d.cfg.CountryHeader == "" {
+		return
+	}
+
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(d.cfg.CountryHeader)))
+	if country == "" {
+		return
+	}
+
+	now := time.Now()
+	isNew, err := d.db.AddLoginCountry(userId, country, db_access.Time(now))
+	if err != nil {
+		d.log.Error("Could not record login country", slogext.Error(err), slog.Int64("user-id", userId))
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	w, ok := d.window(userId, now)
+	if !ok || !w.thresholds.NewCountries {
+		return
+	}
+	d.raise(r, Alert{
+		Kind:   NewCountry,
+		UserId: userId,
+		Detail: "logged in from " + country + " for the first time",
+		At:     now,
+	})
+}
+
+// window returns the counters of the user for the hour of now, loading them on the first call in it
+func (d *Detector) window(userId int64, now time.Time) (*window, bool) {
+	hour := now.Truncate(time.Hour)
+
+	d.mu.Lock()
+	if !hour.Equal(d.hour) {
+		d.hour = hour
+		d.users = make(map[int64]*window)
+	}
+	w, ok := d.users[userId]
+	d.mu.Unlock()
+	if ok {
+		return w, true
+	}
+
+	w, err := d.load(userId, now)
+	if err != nil {
+		d.log.Error("Could not load user for anomaly detection", slogext.Error(err), slog.Int64("user-id", userId))
+		return nil, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// another request of the user may have loaded it meanwhile
+	if loaded, ok := d.users[userId]; ok {
+		return loaded, true
+	}
+	if hour.Equal(d.hour) {
+		d.users[userId] = w
+	}
+	return w, true
+}
+
+func (d *Detector) load(userId int64, now time.Time) (*window, error) {
+	const op = "anomaly.Detector.load"
+
+	w := &window{thresholds: d.cfg.Defaults}
+
+	overrides, err := d.db.GetAlertThresholds(userId)
+	var nre db_access.NoRowsError
+	if err == nil {
+		w.thresholds = w.thresholds.With(overrides)
+	} else if !errors.As(err, &nre) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// traffic is counted per calendar month in UTC
+	now = now.UTC()
+	since := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+	var downloaded int64
+	for _, month := range []time.Time{since, now} {
+		usage, err := d.db.GetUsage(userId, db_access.Time(month))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		downloaded += usage.DownloadedBytes
+	}
+	w.usual = float64(downloaded) / now.Sub(since).Hours()
+
+	return w, nil
+}
+
+// raise records the alert and queues it for the notifiers, unless the user had one of its kind within the cooldown
+func (d *Detector) raise(r *http.Request, alert Alert) {
+	key := raisedKey{userId: alert.UserId, kind: alert.Kind}
+
+	d.mu.Lock()
+	if last, ok := d.raised[key]; ok && alert.At.Sub(last) < d.cfg.Cooldown {
+		d.mu.Unlock()
+		return
+	}
+	// alerts past their cooldown don't hold back any other
+	for k, at := range d.raised {
+		if alert.At.Sub(at) >= d.cfg.Cooldown {
+			delete(d.raised, k)
+		}
+	}
+	d.raised[key] = alert.At
+	d.mu.Unlock()
+
+	alert.RemoteAddr = r.RemoteAddr
+	alert.RequestId = middleware.GetReqID(r.Context())
+
+	log := d.log.With(slog.String("kind", string(alert.Kind)), slog.Int64("user-id", alert.UserId))
+	log.Warn("Unusual activity", slog.String("detail", alert.Detail))
+
+	event := db_access.AuditEvent{
+		At:         db_access.Time(alert.At),
+		UserId:     alert.UserId,
+		Method:     "ALERT",
+		Path:       "anomaly/" + string(alert.Kind),
+		RemoteAddr: alert.RemoteAddr,
+		RequestId:  alert.RequestId,
+	}
+	if err := d.db.AddAuditEvent(&event); err != nil {
+		log.Error("Could not record alert", slogext.Error(err))
+	}
+
+	if len(d.cfg.Notifiers) == 0 {
+		return
+	}
+	select {
+	case d.alerts <- alert:
+	default:
+		log.Error("Too many alerts queued, dropped one")
+	}
+}
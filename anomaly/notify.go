@@ -0,0 +1,81 @@
+package anomaly
+
+import (
+	"bytes"
+	"cloud-storage/events"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Webhook POSTs alerts as json to URL, signed like the deliveries of storage events when Secret is set,
+// see events.SignatureHeader
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func (h Webhook) Notify(ctx context.Context, alert Alert) error {
+	const op = "anomaly.Webhook.Notify"
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("%s: json.Marshal: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: http.NewRequest: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Secret != "" {
+		req.Header.Set(events.SignatureHeader, events.Sign(h.Secret, body))
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %w", op, events.WebhookError{Status: resp.StatusCode})
+	}
+
+	return nil
+}
+
+// Mail sends alerts through the SMTP server at Addr; Auth may be nil for servers that take mail without it
+type Mail struct {
+	Addr string
+	From string
+	To   []string
+	Auth smtp.Auth
+}
+
+func (m Mail) Notify(ctx context.Context, alert Alert) error {
+	const op = "anomaly.Mail.Notify"
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&msg, "Subject: Unusual activity of user %d: %s\r\n", alert.UserId, alert.Kind)
+	fmt.Fprintf(&msg, "Date: %s\r\n", alert.At.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\n", alert.Detail)
+	fmt.Fprintf(&msg, "User: %d\r\nAddress: %s\r\nRequest: %s\r\n", alert.UserId, alert.RemoteAddr, alert.RequestId)
+
+	// smtp.SendMail takes no context, so a stuck server holds the alerts behind this one until it gives up
+	if err := smtp.SendMail(m.Addr, m.Auth, m.From, m.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
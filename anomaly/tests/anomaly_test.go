@@ -0,0 +1,132 @@
+package anomaly_test
+
+import (
+	"cloud-storage/anomaly"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/events"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const userId = int64(7)
+
+func expectAlert(db *db_access_mocks.DbAccess, kind anomaly.Kind) {
+	db.EXPECT().AddAuditEvent(mock.MatchedBy(func(e *db_access.AuditEvent) bool {
+		return e.UserId == userId && e.Method == "ALERT" && e.Path == "anomaly/"+string(kind)
+	})).Return(nil).Once()
+}
+
+func expectUser(db *db_access_mocks.DbAccess, overrides db_access.AlertThresholds, downloaded int64) {
+	if overrides.UserId == 0 {
+		db.EXPECT().GetAlertThresholds(userId).Return(db_access.AlertThresholds{}, db_access.NoRowsError{}).Once()
+	} else {
+		db.EXPECT().GetAlertThresholds(userId).Return(overrides, nil).Once()
+	}
+	db.EXPECT().GetUsage(userId, mock.Anything).Return(db_access.Usage{DownloadedBytes: downloaded}, nil).Twice()
+}
+
+func TestDeleted(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	expectUser(db, db_access.AlertThresholds{}, 0)
+	expectAlert(db, anomaly.MassDelete)
+
+	d := anomaly.New(db, anomaly.Config{
+		Defaults: anomaly.Thresholds{DeletesPerHour: 3},
+		Cooldown: time.Hour,
+	}, slogext.NewDiscardLogger())
+
+	r := httptest.NewRequest("POST", "/files/batch", nil)
+	d.Deleted(r, userId, 2)
+	d.Deleted(r, userId, 2)
+	// the cooldown keeps a second alert back
+	d.Deleted(r, userId, 10)
+}
+
+func TestDownloaded(t *testing.T) {
+	received := make(chan anomaly.Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, events.Sign("secret", body), r.Header.Get(events.SignatureHeader))
+
+		var alert anomaly.Alert
+		assert.NoError(t, json.Unmarshal(body, &alert))
+		received <- alert
+	}))
+	defer server.Close()
+
+	factor := 2.0
+	db := db_access_mocks.NewDbAccess(t)
+	// a lot of traffic makes for a high usual volume
+	expectUser(db, db_access.AlertThresholds{UserId: userId, DownloadFactor: &factor}, 1<<38)
+	expectAlert(db, anomaly.DownloadVolume)
+
+	d := anomaly.New(db, anomaly.Config{
+		Defaults:  anomaly.Thresholds{DownloadFactor: 100, MinDownloadBytes: 1 << 20},
+		Cooldown:  time.Hour,
+		Notifiers: []anomaly.Notifier{anomaly.Webhook{URL: server.URL, Secret: "secret", Client: server.Client()}},
+	}, slogext.NewDiscardLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	r := httptest.NewRequest("GET", "/download", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	// 256 GiB over the at least 28 days of this month and the one before make an hour of 0.4 GiB at most
+	d.Downloaded(r, userId, 1<<29)
+	d.Downloaded(r, userId, 1<<31)
+
+	select {
+	case alert := <-received:
+		assert.Equal(t, anomaly.DownloadVolume, alert.Kind)
+		assert.Equal(t, userId, alert.UserId)
+		assert.Equal(t, "192.0.2.1:1234", alert.RemoteAddr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook got no alert")
+	}
+}
+
+func TestLoggedIn(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().AddLoginCountry(userId, "DE", mock.Anything).Return(false, nil).Once()
+	db.EXPECT().AddLoginCountry(userId, "NZ", mock.Anything).Return(true, nil).Once()
+	expectUser(db, db_access.AlertThresholds{}, 0)
+	expectAlert(db, anomaly.NewCountry)
+
+	d := anomaly.New(db, anomaly.Config{
+		Defaults:      anomaly.Thresholds{NewCountries: true},
+		CountryHeader: "CF-IPCountry",
+		Cooldown:      time.Hour,
+	}, slogext.NewDiscardLogger())
+
+	login := func(country string) {
+		r := httptest.NewRequest("POST", "/auth/login", nil)
+		if country != "" {
+			r.Header.Set("CF-IPCountry", country)
+		}
+		d.LoggedIn(r, userId)
+	}
+	login("de")
+	login("NZ")
+	// logins without the header aren't checked
+	login("")
+}
+
+func TestThresholds_With(t *testing.T) {
+	factor, off := 3.0, false
+	defaults := anomaly.Thresholds{DownloadFactor: 10, MinDownloadBytes: 5, DeletesPerHour: 100, NewCountries: true}
+
+	got := defaults.With(db_access.AlertThresholds{DownloadFactor: &factor, NewCountries: &off})
+	require.Equal(t, anomaly.Thresholds{DownloadFactor: 3, MinDownloadBytes: 5, DeletesPerHour: 100}, got)
+	assert.Equal(t, defaults, defaults.With(db_access.AlertThresholds{}))
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"cloud-storage/anomaly"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type AlertThresholdsRequest struct {
+	// nil fields keep the defaults of the config file
+	DownloadFactor   *float64 `json:"download_factor"`
+	MinDownloadBytes *int64   `json:"min_download_bytes"`
+	DeletesPerHour   *int64   `json:"deletes_per_hour"`
+	NewCountries     *bool    `json:"new_countries"`
+}
+
+type AlertThresholdsInfo struct {
+	DownloadFactor   float64 `json:"download_factor"`
+	MinDownloadBytes int64   `json:"min_download_bytes"`
+	DeletesPerHour   int64   `json:"deletes_per_hour"`
+	NewCountries     bool    `json:"new_countries"`
+}
+
+type AlertThresholdsResponse struct {
+	// Overrides are the thresholds set for the user, Effective what applies with the defaults
+	Overrides AlertThresholdsRequest `json:"overrides"`
+	Effective AlertThresholdsInfo    `json:"effective"`
+	ErrorHolder
+}
+
+// WatchDownloads counts the bytes of successful downloads towards the download volume of the user
+func WatchDownloads(detector *anomaly.Detector) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				if status := ww.Status(); status == 0 || status >= 200 && status <= 299 {
+					detector.Downloaded(r, auth.UserId(r.Context()), int64(ww.BytesWritten()))
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// alertThresholdsUser reads the user id of /users/{id}/alert-thresholds
+func alertThresholdsUser(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int64, bool) {
+	userId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		errorMsg := "Invalid user id"
+		log.Error(errorMsg, slogext.Error(err))
+		writeParamError(w, InvalidContentFormat, "id", errorMsg, http.StatusBadRequest)
+		return 0, false
+	}
+	return userId, true
+}
+
+// AdminAlertThresholds shows the thresholds of unusual activity of the user {id};
+// it has to be mounted behind auth.Admin
+func AdminAlertThresholds(db db_access.AnomalyRepo, detector *anomaly.Detector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminAlertThresholds"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId, ok := alertThresholdsUser(w, r, log)
+		if !ok {
+			return
+		}
+
+		overrides, err := db.GetAlertThresholds(userId)
+		var nre db_access.NoRowsError
+		if err != nil && !errors.As(err, &nre) {
+			log.Error("Could not get alert thresholds from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		effective := detector.Defaults().With(overrides)
+		resp := AlertThresholdsResponse{
+			Overrides: AlertThresholdsRequest{
+				DownloadFactor:   overrides.DownloadFactor,
+				MinDownloadBytes: overrides.MinDownloadBytes,
+				DeletesPerHour:   overrides.DeletesPerHour,
+				NewCountries:     overrides.NewCountries,
+			},
+			Effective: AlertThresholdsInfo{
+				DownloadFactor:   effective.DownloadFactor,
+				MinDownloadBytes: effective.MinDownloadBytes,
+				DeletesPerHour:   effective.DeletesPerHour,
+				NewCountries:     effective.NewCountries,
+			},
+		}
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AdminAlertThresholdsSet replaces the thresholds of the user {id}; it has to be mounted behind auth.Admin
+func AdminAlertThresholdsSet(db db_access.DbAccess, detector *anomaly.Detector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminAlertThresholdsSet"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId, ok := alertThresholdsUser(w, r, log)
+		if !ok {
+			return
+		}
+
+		var req AlertThresholdsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		if req.DownloadFactor != nil && *req.DownloadFactor < 0 {
+			errorMsg := "download_factor is not in valid range"
+			log.Error(errorMsg, slog.Float64("download_factor", *req.DownloadFactor))
+			writeParamError(w, ParameterOutOfRange, "download_factor", errorMsg, http.StatusUnprocessableEntity)
+			return
+		}
+		for param, value := range map[string]*int64{
+			"min_download_bytes": req.MinDownloadBytes,
+			"deletes_per_hour":   req.DeletesPerHour,
+		} {
+			if value != nil && *value < 0 {
+				errorMsg := param + " is not in valid range"
+				log.Error(errorMsg, slog.Int64(param, *value))
+				writeParamError(w, ParameterOutOfRange, param, errorMsg, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		user := db_access.User{Id: userId}
+		var nre db_access.NoRowsError
+		if err := db.GetUser(&user); errors.As(err, &nre) {
+			errorMsg := "User not found"
+			log.Error(errorMsg, slog.Int64("user-id", userId))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get user from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		err := db.SetAlertThresholds(db_access.AlertThresholds{
+			UserId:           userId,
+			DownloadFactor:   req.DownloadFactor,
+			MinDownloadBytes: req.MinDownloadBytes,
+			DeletesPerHour:   req.DeletesPerHour,
+			NewCountries:     req.NewCountries,
+		})
+		if err != nil {
+			log.Error("Could not save alert thresholds to db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		detector.Forget(userId)
+
+		log.Info("Alert thresholds set", slog.Int64("user-id", userId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminAlertThresholdsDelete puts the user {id} back on the default thresholds; it has to be mounted behind auth.Admin
+func AdminAlertThresholdsDelete(db db_access.AnomalyRepo, detector *anomaly.Detector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminAlertThresholdsDelete"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId, ok := alertThresholdsUser(w, r, log)
+		if !ok {
+			return
+		}
+
+		var nre db_access.NoRowsError
+		if err := db.DeleteAlertThresholds(userId); errors.As(err, &nre) {
+			errorMsg := "User has no alert thresholds of their own"
+			log.Error(errorMsg, slog.Int64("user-id", userId))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not delete alert thresholds from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		detector.Forget(userId)
+
+		log.Info("Alert thresholds deleted", slog.Int64("user-id", userId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"cloud-storage/audit"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const auditSignatureHeader = "X-Audit-Signature"
+
+// longest time range a single export may cover; exports are built in memory to be signed
+const maxAuditRange = 31 * 24 * time.Hour
+
+type AuditKeyResponse struct {
+	// base64 encoded ed25519 public key that verifies X-Audit-Signature
+	PublicKey string `json:"public_key"`
+	ErrorHolder
+}
+
+// Audit records every request that may change something once it is answered; reads are left out.
+// Requests that pass it before auth.Auth are recorded with user id -1.
+func Audit(db db_access.AuditRepo) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "api.Audit"
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				// the route pattern keeps ids and file names out of the log
+				path := r.URL.Path
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					path = rctx.RoutePattern()
+				}
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				event := db_access.AuditEvent{
+					At:         db_access.Time(time.Now()),
+					UserId:     auth.UserId(r.Context()),
+					Method:     r.Method,
+					Path:       path,
+					Status:     status,
+					RemoteAddr: r.RemoteAddr,
+					RequestId:  middleware.GetReqID(r.Context()),
+				}
+				if err := db.AddAuditEvent(&event); err != nil {
+					log := slogext.LogWithOp(op, r.Context())
+					log.Error("Could not record audit event", slogext.Error(err), slog.String("path", path))
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// parseUnixParam reads a unix time in seconds from the query; a missing one gives def
+func parseUnixParam(w http.ResponseWriter, r *http.Request, log *slog.Logger, name string, def time.Time) (time.Time, bool) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return def, true
+	}
+
+	seconds, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		errorMsg := name + " has to be a unix time in seconds"
+		log.Error(errorMsg, slogext.Error(err))
+		writeParamError(w, InvalidContentFormat, name, errorMsg, http.StatusBadRequest)
+		return time.Time{}, false
+	}
+
+	return time.Unix(seconds, 0), true
+}
+
+// AuditExport writes the audit events between ?from and ?to, unix seconds defaulting to the last day,
+// as ?format=jsonl or csv. X-Audit-Signature holds the base64 ed25519 signature of the body.
+// It has to be mounted behind auth.Admin.
+func AuditExport(db db_access.AuditRepo, signer *audit.Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AuditExport"
+		log := slogext.LogWithOp(op, r.Context())
+
+		now := time.Now()
+		to, ok := parseUnixParam(w, r, log, "to", now)
+		if !ok {
+			return
+		}
+		from, ok := parseUnixParam(w, r, log, "from", to.Add(-24*time.Hour))
+		if !ok {
+			return
+		}
+
+		if !from.Before(to) || to.Sub(from) > maxAuditRange {
+			errorMsg := "from has to be before to and at most 31 days apart"
+			log.Error(errorMsg, slog.Time("from", from), slog.Time("to", to))
+			writeParamError(w, ParameterOutOfRange, "from", errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		format := audit.Format(r.URL.Query().Get("format"))
+		if format == "" {
+			format = audit.JSONL
+		}
+		if format != audit.JSONL && format != audit.CSV {
+			errorMsg := "format has to be jsonl or csv"
+			log.Error(errorMsg, slog.String("format", string(format)))
+			writeParamError(w, InvalidContentFormat, "format", errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		events, err := db.GetAuditEvents(db_access.Time(from), db_access.Time(to))
+		if err != nil {
+			log.Error("Could not get audit events from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		// the signature goes into a header, so the whole body has to exist first
+		var body bytes.Buffer
+		if err := audit.Write(&body, format, events); err != nil {
+			log.Error("Could not encode audit events", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set(auditSignatureHeader, signer.Sign(body.Bytes()))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%d-%d.%s"`, from.Unix(), to.Unix(), format))
+		if _, err := w.Write(body.Bytes()); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AuditKey hands out the public key that verifies audit exports
+func AuditKey(signer *audit.Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AuditKey"
+		log := slogext.LogWithOp(op, r.Context())
+
+		resp := AuditKeyResponse{PublicKey: base64.StdEncoding.EncodeToString(signer.PublicKey())}
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"cloud-storage/db_access"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ifMatchHeader holds the ETags of the files an upload may replace. The ETag of a file is its quoted id:
+// every new version of a file gets an id of its own, so the id tells versions apart.
+const ifMatchHeader = "If-Match"
+
+func fileETag(id string) string {
+	return `"` + id + `"`
+}
+
+// ifNoneMatchHeader holds the ETags of the listings a client has already
+const ifNoneMatchHeader = "If-None-Match"
+
+// collectionETag is the ETag of the listings of the files of a user as of a collection version.
+// It is weak, as the same version may be listed in another encoding, and names the user,
+// as versions of different users are unrelated.
+func collectionETag(userId int64, version int64) string {
+	return fmt.Sprintf(`W/"%d.%d"`, userId, version)
+}
+
+// parseIfMatch returns the ETags of If-Match, or nil without the header
+func parseIfMatch(r *http.Request) []string {
+	return parseETags(r, ifMatchHeader)
+}
+
+// notModified is the weak comparison of If-None-Match with the ETag of what would be sent
+func notModified(r *http.Request, etag string) bool {
+	for _, tag := range parseETags(r, ifNoneMatchHeader) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseETags(r *http.Request, header string) []string {
+	values := r.Header.Values(header)
+	if len(values) == 0 {
+		return nil
+	}
+
+	tags := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// matchesETag is the strong comparison of If-Match: weak tags never match, * matches any file
+func matchesETag(tags []string, files []db_access.File) bool {
+	for _, tag := range tags {
+		for _, file := range files {
+			if tag == "*" || tag == fileETag(file.GeneratedName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nameLocks keeps conditional uploads of a name from passing their check at the same time,
+// which would let the later one replace the earlier one unseen
+var nameLocks = keyedMutex{locks: make(map[string]*keyedLock)}
+
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// lock returns the function that unlocks the name of the user again
+func (km *keyedMutex) lock(userId int64, name string) func() {
+	key := fmt.Sprintf("%d\x00%s", userId, name)
+
+	km.mu.Lock()
+	l, ok := km.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		km.locks[key] = l
+	}
+	l.waiters++
+	km.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		km.mu.Lock()
+		if l.waiters--; l.waiters == 0 {
+			delete(km.locks, key)
+		}
+		km.mu.Unlock()
+	}
+}
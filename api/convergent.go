@@ -0,0 +1,158 @@
+package api
+
+import (
+	dbaccess "cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// errNoContentBlob tells saveConvergent that the content has to be stored before the file can reference it
+var errNoContentBlob = errors.New("no blob of the content")
+
+// saveConvergent is save with convergent encryption. The content is spooled encrypted with a DEC as usual
+// while it is hashed, as its key depends on all of it. The file then shares the blob of the same content
+// if there is one, whoever uploaded it; otherwise the spooled content is encrypted again with the content key
+// into a blob of its own. The blob is in place before any row references it.
+func (s *UploadService) saveConvergent(
+	ctx context.Context,
+	log *slog.Logger,
+	meta UploadMeta,
+	content io.Reader,
+	addRow func(repos dbaccess.DbAccess, generatedName string, size int64, blobName string) error,
+) (string, int64, error) {
+	spool, err := storage.CreateTemp(s.cfg.StorageDir, s.cfg.Durability)
+	if err != nil {
+		return "", 0, contentError(err, meta.Size < 0)
+	}
+	defer func() {
+		if err := spool.Discard(); err != nil {
+			log.Error("Could not remove spooled upload from disk", slogext.Error(err))
+		}
+	}()
+
+	hash := sha256.New()
+	var limited io.Reader = newLimitedReader(content, meta.MaxSize)
+	if meta.Size >= 0 {
+		limited = newLimitedReader(content, meta.Size)
+	}
+	counted := &countingReader{r: io.TeeReader(limited, hash)}
+	if err := s.c.EncryptAndCopyFor(meta.OwnerId, spool, counted); err != nil {
+		return "", 0, contentError(err, meta.Size < 0)
+	}
+	if meta.Size < 0 && counted.n == 0 {
+		return "", 0, contentError(emptyFileError{}, true)
+	}
+	size := counted.n
+
+	key, err := s.cfg.Convergent.DeriveContentKey(hash.Sum(nil))
+	if err != nil {
+		return "", 0, fmt.Errorf("derive content key: %w", err)
+	}
+
+	// written is the blob stored by this upload, if it had to store one
+	var written string
+	removeWritten := func() {
+		if written == "" {
+			return
+		}
+		if _, ok := storage.ContentChecksum(written); ok {
+			// another upload of the content may have committed and referenced the same blob meanwhile;
+			// repair removes it if it stays orphaned
+			return
+		}
+		if err := storage.Remove(s.cfg.StorageDir, s.cfg.Durability, written); err != nil {
+			log.Error("Could not remove unused content blob", slogext.Error(err), slog.String("blob", written))
+		}
+	}
+
+	for {
+		var blobName string
+		strId, err := addWithUniqueName(s.cfg.TimeOrderedIds, func(generatedName string) error {
+			return s.db.WithTx(ctx, func(repos dbaccess.DbAccess) error {
+				existing, err := repos.GetConvergentBlob(key.Id)
+				var nre dbaccess.NoRowsError
+				if err == nil {
+					blobName = existing.BlobName
+					return addRow(repos, generatedName, size, existing.BlobName)
+				} else if !errors.As(err, &nre) {
+					return err
+				} else if written == "" {
+					return errNoContentBlob
+				}
+
+				blobName = written
+				if err := addRow(repos, generatedName, size, written); err != nil {
+					return err
+				}
+				return repos.AddConvergentBlob(dbaccess.ConvergentBlob{ContentId: key.Id, BlobName: written, Key: key.Wrapped})
+			})
+		})
+
+		var uce dbaccess.UniqueConstraintError
+		switch {
+		case errors.Is(err, errNoContentBlob):
+			if written, err = s.writeContentBlob(spool, key); err != nil {
+				return "", 0, fmt.Errorf("store content blob: %w", err)
+			}
+			continue
+		case errors.As(err, &uce) && uce.Table == "convergentBlobs":
+			// the same content was just stored by another upload, whose blob the next attempt finds
+			continue
+		case err != nil:
+			removeWritten()
+			return "", 0, fmt.Errorf("save file info: %w", err)
+		}
+
+		if blobName != written {
+			removeWritten()
+		} else {
+			log.Info("Stored new content blob", slog.String("blob", written))
+		}
+		return strId, size, nil
+	}
+}
+
+// writeContentBlob encrypts the spooled content again with the content key and stores it under a new name,
+// or its content address with the content-addressed layout
+func (s *UploadService) writeContentBlob(spool *storage.TempFile, key encryption.ContentKey) (string, error) {
+	src, err := spool.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	blob, err := storage.CreateTemp(s.cfg.StorageDir, s.cfg.Durability)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Discard()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.c.DecryptAndCopy(pw, src))
+	}()
+
+	err = s.cfg.Convergent.EncryptWithContentKey(blob, pr, key)
+	// stops the decryption if encryption gave up early
+	pr.CloseWithError(err)
+	if err != nil {
+		return "", err
+	}
+
+	name := storage.NewFileId(s.cfg.TimeOrderedIds)
+	if s.cfg.Layout == storage.LayoutContentAddressed {
+		name = blob.ContentAddress()
+	}
+	if err := blob.CommitAs(name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
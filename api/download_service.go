@@ -0,0 +1,191 @@
+package api
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/filecache"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// ErrFileNotFound is returned for files that don't exist and for files of other users alike,
+// so that their ids can't be probed
+var ErrFileNotFound = errors.New("file not found")
+
+// ownedFile is where every download and file operation checks that the file belongs to userId
+func ownedFile(db db_access.FileRepo, userId int64, id string) (db_access.File, error) {
+	const op = "api.ownedFile"
+
+	if !storage.ValidFileId(id) {
+		return db_access.File{}, ErrFileNotFound
+	}
+
+	file, err := db.GetFile(id)
+	var nre db_access.NoRowsError
+	if errors.As(err, &nre) || (err == nil && file.OwnerId != userId) {
+		return db_access.File{}, ErrFileNotFound
+	} else if err != nil {
+		return db_access.File{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return file, nil
+}
+
+// writeDownloadError answers with why a file could not be opened
+func writeDownloadError(w http.ResponseWriter, log *slog.Logger, err error) {
+	if errors.Is(err, ErrFileNotFound) {
+		errorMsg := "No file with provided id was found"
+		log.Error(errorMsg, slogext.Error(err))
+		writeError(w, NotFound, errorMsg, http.StatusNotFound)
+		return
+	}
+
+	log.Error("Could not open file", slogext.Error(err))
+	writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+}
+
+// DownloadService opens files for reading whatever protocol they go out by, so that every front end
+// checks ownership the same way
+type DownloadService struct {
+	db    db_access.FileRepo
+	c     encryption.Crypter
+	blobs *blobstore.Store
+	// nil unless set by WithCache
+	files *filecache.Cache
+}
+
+func NewDownloadService(db db_access.FileRepo, c encryption.Crypter, blobs *blobstore.Store) *DownloadService {
+	return &DownloadService{
+		db:    db,
+		c:     c,
+		blobs: blobs,
+	}
+}
+
+// WithCache makes Open look files up in files first; the db of s must invalidate it, see filecache.Wrap
+func (s *DownloadService) WithCache(files *filecache.Cache) *DownloadService {
+	s.files = files
+	return s
+}
+
+// Open opens the file id of userId; see ErrFileNotFound
+func (s *DownloadService) Open(ctx context.Context, userId int64, id string) (*Download, error) {
+	const op = "api.DownloadService.Open"
+
+	entry, err := s.files.Load(id, func() (filecache.Entry, error) {
+		file, err := ownedFile(s.db, userId, id)
+		if err != nil {
+			return filecache.Entry{}, err
+		}
+
+		fileName, err := s.c.DecryptFileName(file.FileName)
+		if err != nil {
+			return filecache.Entry{}, fmt.Errorf("decrypt file name: %w", err)
+		}
+
+		return filecache.Entry{File: file, FileName: fileName}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// the entry may have been loaded for its owner
+	if entry.File.OwnerId != userId {
+		return nil, fmt.Errorf("%s: %w", op, ErrFileNotFound)
+	}
+
+	d, err := s.open(ctx, entry.File, entry.FileName)
+	if err != nil {
+		// in case the blob moved without the cache hearing of it
+		s.files.Invalidate(id)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return d, nil
+}
+
+// OpenFile opens a file that was looked up some other way than by id
+func (s *DownloadService) OpenFile(ctx context.Context, userId int64, file db_access.File) (*Download, error) {
+	const op = "api.DownloadService.OpenFile"
+
+	if file.OwnerId != userId {
+		return nil, fmt.Errorf("%s: %w", op, ErrFileNotFound)
+	}
+
+	fileName, err := s.c.DecryptFileName(file.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: decrypt file name: %w", op, err)
+	}
+
+	d, err := s.open(ctx, file, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return d, nil
+}
+
+func (s *DownloadService) open(ctx context.Context, file db_access.File, fileName string) (*Download, error) {
+	const op = "api.DownloadService.open"
+
+	blob, err := s.blobs.Open(ctx, file.Backend, file.BlobName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: open blob %s of backend %q: %w", op, file.BlobName, file.Backend, err)
+	}
+
+	return &Download{
+		File:     file,
+		FileName: fileName,
+		c:        s.c,
+		blob:     blob,
+	}, nil
+}
+
+// Download is an open file. It decrypts as it is read; WriteTo, which io.Copy prefers, does so without
+// the goroutine that Read needs. Close it once done.
+type Download struct {
+	File db_access.File
+	// FileName is decrypted
+	FileName string
+
+	c    encryption.Crypter
+	blob io.ReadCloser
+	pr   *io.PipeReader
+	done chan struct{}
+}
+
+// WriteTo decrypts the whole file into w; it is only meant to be called once, instead of Read
+func (d *Download) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := d.c.DecryptAndCopy(cw, d.blob)
+	return cw.n, err
+}
+
+func (d *Download) Read(p []byte) (int, error) {
+	if d.pr == nil {
+		pr, pw := io.Pipe()
+		d.pr, d.done = pr, make(chan struct{})
+		go func() {
+			defer close(d.done)
+			pw.CloseWithError(d.c.DecryptAndCopy(pw, d.blob))
+		}()
+	}
+
+	return d.pr.Read(p)
+}
+
+func (d *Download) Close() error {
+	if d.pr != nil {
+		// the blob is only closed once nothing reads it anymore
+		d.pr.Close()
+		<-d.done
+	}
+
+	return d.blob.Close()
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type FileExpiryRequest struct {
+	// unix seconds; 0 keeps the file until deleted
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type NotificationInfo struct {
+	Id   int64  `json:"id"`
+	Kind string `json:"kind"`
+	// the id of the export for export notifications
+	FileId string `json:"file_id"`
+	// empty if the name could not be decrypted anymore
+	FileName  string `json:"file_name,omitempty"`
+	At        int64  `json:"at,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type NotificationsResponse struct {
+	Notifications []NotificationInfo `json:"notifications"`
+	ErrorHolder
+}
+
+func unixOrZero(t db_access.Time) int64 {
+	if time.Time(t).IsZero() {
+		return 0
+	}
+	return time.Time(t).Unix()
+}
+
+func checkExpiry(w http.ResponseWriter, log *slog.Logger, param string, unix int64) (db_access.Time, bool) {
+	var v validate.Validator
+	expiresAt := validateExpiry(&v, param, unix)
+	return expiresAt, requireValid(w, log, &v)
+}
+
+// validateExpiry checks that the expiry, unless it is 0, is in the future
+func validateExpiry(v *validate.Validator, param string, unix int64) db_access.Time {
+	if unix == 0 {
+		return db_access.Time{}
+	}
+
+	expiresAt := time.Unix(unix, 0)
+	if !v.Check(expiresAt.After(time.Now()), param, validate.OutOfRange, "Expiry must be in the future") {
+		return db_access.Time{}
+	}
+
+	return db_access.Time(expiresAt)
+}
+
+func validateUploadExpiry(v *validate.Validator, r *http.Request) db_access.Time {
+	param := r.URL.Query().Get("expires_at")
+	if param == "" {
+		return db_access.Time{}
+	}
+
+	unix, err := strconv.ParseInt(param, 10, 64)
+	if !v.Check(err == nil && unix > 0, "expires_at", validate.Malformed, "expires_at must be a unix timestamp") {
+		return db_access.Time{}
+	}
+
+	return validateExpiry(v, "expires_at", unix)
+}
+
+func FileExpiry(db db_access.FileRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileExpiry"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req FileExpiryRequest
+		if !decodeFileOpRequest(w, r, log, &req) {
+			return
+		}
+
+		expiresAt, ok := checkExpiry(w, log, "expires_at", req.ExpiresAt)
+		if !ok {
+			return
+		}
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		err := db.SetFileExpiry(file.GeneratedName, expiresAt)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "No file with provided id was found"
+			log.Error(errorMsg, slog.String("generated-name", file.GeneratedName))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not set file expiry", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func Notifications(db db_access.NotificationRepo, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.Notifications"
+		log := slogext.LogWithOp(op, r.Context())
+
+		notifications, err := db.GetNotifications(auth.UserId(r.Context()))
+		if err != nil {
+			log.Error("Could not get notifications from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := NotificationsResponse{Notifications: make([]NotificationInfo, 0, len(notifications))}
+		for _, n := range notifications {
+			// one broken name should not hide the other notifications
+			var fileName string
+			if n.FileName != "" {
+				fileName, err = c.DecryptFileName(n.FileName)
+				if err != nil {
+					log.Warn("Could not decrypt file name of notification", slogext.Error(err), slog.Int64("notification-id", n.Id))
+					fileName = ""
+				}
+			}
+
+			resp.Notifications = append(resp.Notifications, NotificationInfo{
+				Id:        n.Id,
+				Kind:      string(n.Kind),
+				FileId:    n.FileId,
+				FileName:  fileName,
+				At:        unixOrZero(n.At),
+				CreatedAt: unixOrZero(n.CreationTime),
+			})
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/export"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func ExportStart(e *export.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ExportStart"
+		log := slogext.LogWithOp(op, r.Context())
+
+		exp, err := e.Start(auth.UserId(r.Context()))
+		if err != nil {
+			log.Error("Could not start export", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Started export", slog.String("export-id", exp.Id))
+
+		resp := ExportResponse{
+			Id:     exp.Id,
+			Status: string(exp.Status),
+		}
+		if err := writeResponse(w, resp, http.StatusAccepted); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func ExportStatus(db db_access.ExportRepo, e *export.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ExportStatus"
+		log := slogext.LogWithOp(op, r.Context())
+
+		exp, err := db.GetExport(chi.URLParam(r, "id"))
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) || (err == nil && exp.OwnerId != auth.UserId(r.Context())) {
+			errorMsg := "No export with provided id was found"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get export from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := ExportResponse{
+			Id:     exp.Id,
+			Status: string(exp.Status),
+		}
+
+		switch exp.Status {
+		case db_access.ExportReady:
+			resp.DownloadUrl = e.Link(exp)
+			resp.ExpiresAt = time.Time(exp.ExpiresAt).Unix()
+		case db_access.ExportFailed:
+			addError(&resp.ErrorHolder, InternalApiError, exp.Error)
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// ExportDownload is authorized by the signed link alone, so it must be routed outside of auth.Auth
+func ExportDownload(db db_access.ExportRepo, e *export.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ExportDownload"
+		log := slogext.LogWithOp(op, r.Context())
+
+		id := chi.URLParam(r, "id")
+		query := r.URL.Query()
+
+		err := e.VerifyLink(id, query.Get("expires"), query.Get("signature"))
+		if err != nil {
+			errorMsg := "Invalid or expired link"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidLink, errorMsg, http.StatusForbidden)
+			return
+		}
+
+		exp, err := db.GetExport(id)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) || (err == nil && exp.Status != db_access.ExportReady) {
+			errorMsg := "No export with provided id was found"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get export from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, exp.Id))
+
+		if err := e.WriteArchive(w, exp.Id); err != nil {
+			log.Error("Could not write export", slogext.Error(err))
+			return
+		}
+	}
+}
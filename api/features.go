@@ -0,0 +1,74 @@
+package api
+
+import (
+	"cloud-storage/features"
+	slogext "cloud-storage/utils/slogExt"
+	"fmt"
+	"net/http"
+)
+
+// feature flags consulted by the api
+const (
+	// presigned links to files
+	FeatureSharing = "sharing"
+	// uploads of a raw request body through PUT /files
+	FeatureRawUploads = "raw-uploads"
+	FeatureBatch      = "batch"
+	FeatureImport     = "import"
+	FeatureExport     = "export"
+	// uploads of the changes to a file through /files/{id}/signature and /files/{id}/delta
+	FeatureDeltaSync = "delta-sync"
+	// comment threads on files
+	FeatureComments = "comments"
+)
+
+// FeatureDefaults are the flags the api knows about and their state when the flags file doesn't mention them
+var FeatureDefaults = map[string]bool{
+	FeatureSharing:    true,
+	FeatureRawUploads: true,
+	FeatureBatch:      true,
+	FeatureImport:     true,
+	FeatureExport:     true,
+	FeatureDeltaSync:  true,
+	FeatureComments:   true,
+}
+
+type FeaturesResponse struct {
+	Features map[string]bool `json:"features"`
+	ErrorHolder
+}
+
+// RequireFeature answers with 404 while the flag is off, so that a disabled feature looks like it isn't there.
+// The flag is checked on every request, so reloading the flags takes effect right away.
+func RequireFeature(flags *features.Flags, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "api.RequireFeature"
+
+			if !flags.Enabled(name) {
+				log := slogext.LogWithOp(op, r.Context())
+
+				errorMsg := fmt.Sprintf("Feature %s is disabled", name)
+				log.Info(errorMsg)
+				if err := writeError(w, FeatureDisabled, errorMsg, http.StatusNotFound); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Features lists the flags so that clients can hide what is disabled
+func Features(flags *features.Flags) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.Features"
+		log := slogext.LogWithOp(op, r.Context())
+
+		if err := writeResponse(w, FeaturesResponse{Features: flags.All()}, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"cloud-storage/anomaly"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/hls"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	maxBatchOperations = 1000
+	maxBatchRequestLen = 1 << 20
+	maxTagsPerFile     = 32
+	maxTagLen          = 64
+)
+
+type BatchOperation struct {
+	Op     string   `json:"op"`
+	Id     string   `json:"id"`
+	Folder string   `json:"folder,omitempty"`
+	Name   string   `json:"name,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// LockToken is the token of the lock the client holds on the files a move renames or replaces
+	LockToken string `json:"lock_token,omitempty"`
+}
+
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+type BatchResult struct {
+	Id       string `json:"id"`
+	Status   int    `json:"status"`
+	FileName string `json:"file_name,omitempty"`
+	ErrorHolder
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results,omitempty"`
+	ErrorHolder
+}
+
+// FileBatch applies every operation on its own; a failed operation doesn't affect the others
+// and is reported in its entry of the result array.
+// Deletes count against the mass delete threshold of detector, which may be nil.
+func FileBatch(db db_access.DbAccess, c encryption.Crypter, blobs *blobstore.Store, names DuplicateNames, detector *anomaly.Detector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileBatch"
+		log := slogext.LogWithOp(op, r.Context())
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBatchRequestLen)
+
+		var req BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+			errorMsg := fmt.Sprintf("operations must contain from 1 to %d entries", maxBatchOperations)
+			log.Error(errorMsg, slog.Int("count", len(req.Operations)))
+			writeParamError(w, ParameterOutOfRange, "operations", errorMsg, http.StatusUnprocessableEntity)
+			return
+		}
+
+		userId := auth.UserId(r.Context())
+		resp := BatchResponse{Results: make([]BatchResult, 0, len(req.Operations))}
+		var deleted int64
+		for _, operation := range req.Operations {
+			result := applyBatchOperation(r.Context(), db, c, blobs, names, userId, operation, log)
+			resp.Results = append(resp.Results, result)
+			if operation.Op == "delete" && result.Status == http.StatusOK {
+				deleted++
+			}
+		}
+		detector.Deleted(r, userId, deleted)
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func batchError(result BatchResult, apiErr ApiError, status int) BatchResult {
+	result.Status = status
+	result.Errors = append(result.Errors, apiErr)
+	return result
+}
+
+func applyBatchOperation(
+	ctx context.Context,
+	db db_access.DbAccess,
+	c encryption.Crypter,
+	blobs *blobstore.Store,
+	names DuplicateNames,
+	userId int64,
+	operation BatchOperation,
+	log *slog.Logger,
+) BatchResult {
+	result := BatchResult{Id: operation.Id, Status: http.StatusOK}
+	log = log.With(slog.String("batch-op", operation.Op), slog.String("generated-name", operation.Id))
+
+	switch operation.Op {
+	case "delete", "move", "tag":
+	default:
+		return batchError(result, ApiError{Code: InvalidContentFormat, ParamName: "op", Description: "op must be one of delete, move, tag"}, http.StatusUnprocessableEntity)
+	}
+
+	if !storage.ValidFileId(operation.Id) {
+		log.Error("Invalid file id")
+		return batchError(result, ApiError{Code: NotFound, Description: "No file with provided id was found"}, http.StatusNotFound)
+	}
+
+	file, err := db.GetFile(operation.Id)
+	var nre db_access.NoRowsError
+	if errors.As(err, &nre) || (err == nil && file.OwnerId != userId) {
+		return batchError(result, ApiError{Code: NotFound, Description: "No file with provided id was found"}, http.StatusNotFound)
+	} else if err != nil {
+		log.Error("Could not get file from db", slogext.Error(err))
+		return batchError(result, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable)
+	}
+
+	switch operation.Op {
+	case "delete":
+		err := removeFile(ctx, db, blobs, file.GeneratedName, log)
+		if errors.As(err, &nre) {
+			return batchError(result, ApiError{Code: NotFound, Description: "No file with provided id was found"}, http.StatusNotFound)
+		} else if err != nil {
+			log.Error("Could not delete file", slogext.Error(err))
+			return batchError(result, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable)
+		}
+
+	case "move":
+		if apiErr, status := checkDestination(operation.Folder, operation.Name); status != 0 {
+			return batchError(result, apiErr, status)
+		}
+
+		folderId, apiErr, status := destinationFolder(log, db, c, userId, operation.Folder, file.FolderId)
+		if status != 0 {
+			return batchError(result, apiErr, status)
+		}
+
+		if operation.Name == "" && folderId == file.FolderId {
+			break
+		}
+
+		// a file that keeps its name needs it to be free in the folder it goes to
+		name := operation.Name
+		if name == "" {
+			if name, err = c.DecryptFileName(file.FileName); err != nil {
+				log.Error("Could not decrypt file name", slogext.Error(err))
+				return batchError(result, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable)
+			}
+		}
+		current := name
+
+		name, replaced, apiErr, status := names.resolve(log, db, c, userId, folderId, name, file.GeneratedName)
+		if status != 0 {
+			return batchError(result, apiErr, status)
+		}
+
+		if apiErr, status := checkUnlocked(log, db, operation.LockToken, append([]db_access.File{file}, replaced...)...); status != 0 {
+			return batchError(result, apiErr, status)
+		}
+
+		encName := file.FileName
+		if operation.Name != "" || name != current {
+			if encName, err = c.EncryptFileName(name); err != nil {
+				log.Error("Could not encrypt file name", slogext.Error(err))
+				return batchError(result, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable)
+			}
+		}
+
+		err = moveFile(ctx, db, file, encName, folderId)
+		if errors.As(err, &nre) {
+			return batchError(result, ApiError{Code: NotFound, Description: "No file with provided id was found"}, http.StatusNotFound)
+		} else if err != nil {
+			log.Error("Could not move file", slogext.Error(err))
+			return batchError(result, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable)
+		}
+		result.FileName = name
+		if encName != file.FileName {
+			indexFileName(log, db, c, file.GeneratedName, name)
+		}
+		removeReplaced(ctx, db, blobs, replaced, log)
+
+	case "tag":
+		if len(operation.Tags) == 0 || len(operation.Tags) > maxTagsPerFile {
+			return batchError(result, ApiError{
+				Code:        ParameterOutOfRange,
+				ParamName:   "tags",
+				Description: fmt.Sprintf("tags must contain from 1 to %d entries", maxTagsPerFile),
+			}, http.StatusUnprocessableEntity)
+		}
+
+		for _, tag := range operation.Tags {
+			if tag == "" || len(tag) > maxTagLen {
+				return batchError(result, ApiError{
+					Code:        ParameterOutOfRange,
+					ParamName:   "tags",
+					Description: fmt.Sprintf("tags must be from 1 to %d bytes long", maxTagLen),
+				}, http.StatusUnprocessableEntity)
+			}
+		}
+
+		err := db.AddFileTags(file.GeneratedName, operation.Tags)
+		if errors.As(err, &nre) {
+			return batchError(result, ApiError{Code: NotFound, Description: "No file with provided id was found"}, http.StatusNotFound)
+		} else if err != nil {
+			log.Error("Could not tag file", slogext.Error(err))
+			return batchError(result, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable)
+		}
+	}
+
+	return result
+}
+
+// removeFile deletes the file row, then its blob and stream if no other file references them
+func removeFile(ctx context.Context, db db_access.FileRepo, blobs *blobstore.Store, generatedName string, log *slog.Logger) error {
+	blob, orphaned, err := db.DeleteFile(generatedName)
+	if err != nil {
+		return err
+	}
+
+	// the row is gone, so a leftover blob is only wasted space and not worth failing the operation
+	if orphaned {
+		if err := blobs.Remove(ctx, blob.Backend, blob.Name); err != nil {
+			log.Error("Could not remove blob of deleted file", slogext.Error(err), slog.String("blob", blob.Name))
+		}
+
+		if err := hls.Remove(blobs.Dir(), blob.Name); err != nil {
+			log.Error("Could not remove stream of deleted file", slogext.Error(err), slog.String("blob", blob.Name))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"cloud-storage/access"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const maxPathLen = 4096
+
+// FileByPath streams the user's file found at the path query parameter, e.g. /photos/2024/beach.jpg
+// for a file uploaded into folders.
+func FileByPath(db db_access.DbAccess, c encryption.Crypter, blobs *blobstore.Store, accesses *access.Recorder, chunkSize int) http.HandlerFunc {
+	ds := NewDownloadService(db, c, blobs)
+	fs := newFileStreamer(chunkSize)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileByPath"
+		log := slogext.LogWithOp(op, r.Context())
+
+		filePath := r.URL.Query().Get("path")
+		if !strings.HasPrefix(filePath, "/") || len(filePath) > maxPathLen {
+			errorMsg := "path must be absolute and at most 4096 bytes long"
+			log.Error(errorMsg, slog.Int("path-len", len(filePath)))
+			writeParamError(w, InvalidContentFormat, "path", errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		folder, name := path.Split(path.Clean(filePath))
+		if name == "" {
+			errorMsg := "path must point to a file"
+			log.Error(errorMsg)
+			writeParamError(w, InvalidContentFormat, "path", errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		var folders []string
+		if folder = strings.Trim(folder, "/"); folder != "" {
+			folders = strings.Split(folder, "/")
+		}
+		folderId, ok, err := findFolder(db, c, auth.UserId(r.Context()), folders)
+		if err != nil {
+			log.Error("Could not look up folder", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		if !ok {
+			errorMsg := "No file at provided path"
+			log.Error(errorMsg, slog.String("reason", "no such folder"))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		}
+
+		found, err := filesNamed(log, db, c, auth.UserId(r.Context()), folderId, name, "")
+		if err != nil {
+			log.Error("Could not look up files by name", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		switch len(found) {
+		case 0:
+			errorMsg := "No file at provided path"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		case 1:
+		default:
+			errorMsg := "Several files share this path; download them by id"
+			log.Error(errorMsg, slog.Int("count", len(found)))
+			writeError(w, AmbiguousPath, errorMsg, http.StatusConflict)
+			return
+		}
+
+		d, err := ds.OpenFile(r.Context(), auth.UserId(r.Context()), found[0])
+		if err != nil {
+			writeDownloadError(w, log, err)
+			return
+		}
+		defer d.Close()
+
+		n := fs.stream(w, log, d)
+		recordTraffic(db, log, auth.UserId(r.Context()), 0, n)
+		recordAccess(accesses, d.File.GeneratedName, n)
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"bytes"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxCommentLen        = 4096
+	maxCommentRequestLen = 2 * maxCommentLen
+)
+
+type CommentRequest struct {
+	// Body may be left out, e.g. for a bare acknowledgement in a thread
+	Body string `json:"body"`
+	// ReplyTo is the id of the comment on the same file this one answers
+	ReplyTo int64 `json:"reply_to"`
+}
+
+type CommentInfo struct {
+	Id        int64  `json:"id"`
+	AuthorId  int64  `json:"author_id"`
+	ReplyTo   int64  `json:"reply_to,omitempty"`
+	Body      string `json:"body,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type CommentResponse struct {
+	CommentInfo
+	ErrorHolder
+}
+
+type CommentsResponse struct {
+	Comments []CommentInfo `json:"comments"`
+	ErrorHolder
+}
+
+// encryptText encrypts a short text of the user the way their file contents are, so that it needs no keys of its own
+func encryptText(c encryption.Crypter, userId int64, text string) (string, error) {
+	var buf bytes.Buffer
+	if err := c.EncryptAndCopyFor(userId, &buf, strings.NewReader(text)); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decryptText(c encryption.Crypter, ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decode: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.DecryptAndCopy(&buf, bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func commentInfo(comment db_access.Comment, body string) CommentInfo {
+	return CommentInfo{
+		Id:        comment.Id,
+		AuthorId:  comment.AuthorId,
+		ReplyTo:   comment.ReplyTo,
+		Body:      body,
+		CreatedAt: unixOrZero(comment.CreatedAt),
+	}
+}
+
+// FileComments lists the comments on the file {id}, oldest first; replies name the comment they answer
+func FileComments(db db_access.DbAccess, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileComments"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		comments, err := db.GetComments(file.GeneratedName)
+		if err != nil {
+			log.Error("Could not get comments from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := CommentsResponse{Comments: make([]CommentInfo, 0, len(comments))}
+		for _, comment := range comments {
+			var body string
+			if comment.Body != "" {
+				// one broken body should not hide the rest of the thread
+				if body, err = decryptText(c, comment.Body); err != nil {
+					log.Warn("Could not decrypt comment", slogext.Error(err), slog.Int64("comment-id", comment.Id))
+				}
+			}
+			resp.Comments = append(resp.Comments, commentInfo(comment, body))
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// FileCommentAdd comments on the file {id}, or replies to a comment on it
+func FileCommentAdd(db db_access.DbAccess, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileCommentAdd"
+		log := slogext.LogWithOp(op, r.Context())
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxCommentRequestLen)
+
+		var req CommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		var v validate.Validator
+		v.MaxLen("body", req.Body, maxCommentLen)
+		v.UTF8("body", req.Body)
+		v.Check(req.ReplyTo >= 0, "reply_to", validate.OutOfRange, "reply_to must be the id of a comment")
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		if req.ReplyTo != 0 {
+			parent, err := db.GetComment(req.ReplyTo)
+			var nre db_access.NoRowsError
+			if errors.As(err, &nre) || (err == nil && parent.GeneratedName != file.GeneratedName) {
+				errorMsg := "reply_to must be the id of a comment on the file"
+				log.Error(errorMsg, slog.Int64("reply-to", req.ReplyTo))
+				writeParamError(w, ParameterOutOfRange, "reply_to", errorMsg, http.StatusUnprocessableEntity)
+				return
+			} else if err != nil {
+				log.Error("Could not get comment from db", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		comment := db_access.Comment{
+			GeneratedName: file.GeneratedName,
+			AuthorId:      auth.UserId(r.Context()),
+			ReplyTo:       req.ReplyTo,
+			CreatedAt:     db_access.Time(time.Now()),
+		}
+		if req.Body != "" {
+			encBody, err := encryptText(c, comment.AuthorId, req.Body)
+			if err != nil {
+				log.Error("Could not encrypt comment", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+			comment.Body = encBody
+		}
+
+		if err := db.AddComment(&comment); err != nil {
+			log.Error("Could not save comment to db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Added comment", slog.String("generated-name", file.GeneratedName), slog.Int64("comment-id", comment.Id))
+		if err := writeResponse(w, CommentResponse{CommentInfo: commentInfo(comment, req.Body)}, http.StatusCreated); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// FileCommentDelete removes the comment {commentId} on the file {id} along with the replies to it.
+// Only the owner can reach the comments on a file, and may remove any of them.
+func FileCommentDelete(db db_access.DbAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileCommentDelete"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		errorMsg := "No comment with provided id was found"
+		id, err := strconv.ParseInt(chi.URLParam(r, "commentId"), 10, 64)
+		if err != nil {
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		}
+
+		comment, err := db.GetComment(id)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) || (err == nil && comment.GeneratedName != file.GeneratedName) {
+			log.Error(errorMsg, slog.Int64("comment-id", id))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get comment from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		err = db.RemoveComment(id)
+		if errors.As(err, &nre) {
+			log.Error(errorMsg, slog.Int64("comment-id", id))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not remove comment", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Removed comment", slog.String("generated-name", file.GeneratedName), slog.Int64("comment-id", id))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
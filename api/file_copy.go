@@ -0,0 +1,474 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxFileNameLen = encryption.MaxFileNameLen
+	// room for a folder of maxPathLen and a name, escaped
+	maxFileOpRequestLen = 4 * (maxPathLen + maxFileNameLen)
+)
+
+type FileCopyRequest struct {
+	// Folder is the path of an existing folder like /photos/2024, the folder of the source if empty
+	Folder string `json:"folder"`
+	Name   string `json:"name"`
+	// Share makes the copy reference the blob of the source instead of re-encrypting it
+	Share bool `json:"share"`
+}
+
+type FileMoveRequest struct {
+	// Folder is the path of an existing folder, the file stays in its folder if empty
+	Folder string `json:"folder"`
+	Name   string `json:"name"`
+}
+
+// checkDestination returns a non-zero status when the destination of a copy or move is malformed;
+// whether its folder exists is up to destinationFolder
+func checkDestination(folder string, name string) (ApiError, int) {
+	if len(folder) > maxPathLen {
+		return ApiError{
+			Code:        ParameterOutOfRange,
+			ParamName:   "folder",
+			Description: "folder must be at most 4096 bytes long",
+		}, http.StatusUnprocessableEntity
+	}
+	for _, segment := range splitFolder(folder) {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsRune(segment, 0) || len(segment) > maxFileNameLen {
+			return ApiError{
+				Code:        ParameterOutOfRange,
+				ParamName:   "folder",
+				Description: "folder must be a path like /photos/2024, without empty, . or .. parts, and at most 255 bytes per part",
+			}, http.StatusUnprocessableEntity
+		}
+	}
+
+	if name != "" && (len(name) > maxFileNameLen || strings.ContainsAny(name, "/\x00") || name == "." || name == "..") {
+		return ApiError{
+			Code:        ParameterOutOfRange,
+			ParamName:   "name",
+			Description: "name must be at most 255 bytes long and must not contain slashes",
+		}, http.StatusUnprocessableEntity
+	}
+
+	return ApiError{}, 0
+}
+
+func validateDestination(w http.ResponseWriter, log *slog.Logger, folder string, name string) bool {
+	if apiErr, status := checkDestination(folder, name); status != 0 {
+		log.Error(apiErr.Description, slog.String("folder", folder), slog.Int("name-len", len(name)))
+		writeParamError(w, apiErr.Code, apiErr.ParamName, apiErr.Description, status)
+		return false
+	}
+
+	return true
+}
+
+// splitFolder splits a folder path like /photos/2024 into the names of its folders; / is the root
+func splitFolder(folder string) []string {
+	if folder = strings.Trim(folder, "/"); folder == "" {
+		return nil
+	}
+	return strings.Split(folder, "/")
+}
+
+// destinationFolder returns the id of the existing folder a copy or move goes into, current if no folder is given;
+// a non-zero status tells why there is none. Folders are only made by uploads.
+func destinationFolder(log *slog.Logger, db db_access.FolderRepo, c encryption.Crypter, userId int64, folder string, current int64) (int64, ApiError, int) {
+	if folder == "" {
+		return current, ApiError{}, 0
+	}
+
+	id, ok, err := findFolder(db, c, userId, splitFolder(folder))
+	if err != nil {
+		log.Error("Could not look up folder", slogext.Error(err))
+		return 0, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable
+	}
+	if !ok {
+		errorMsg := "Destination folder does not exist"
+		log.Error(errorMsg, slog.Int("folder-len", len(folder)))
+		return 0, ApiError{Code: NotFound, ParamName: "folder", Description: errorMsg}, http.StatusNotFound
+	}
+
+	return id, ApiError{}, 0
+}
+
+// resolveDestinationFolder is destinationFolder for the user of the request; it writes an error response and returns false on failure
+func resolveDestinationFolder(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	db db_access.FolderRepo,
+	c encryption.Crypter,
+	folder string,
+	current int64,
+) (int64, bool) {
+	id, apiErr, status := destinationFolder(log, db, c, auth.UserId(r.Context()), folder, current)
+	if status != 0 {
+		writeParamError(w, apiErr.Code, apiErr.ParamName, apiErr.Description, status)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func decodeFileOpRequest(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFileOpRequestLen)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		errorMsg := "Invalid json"
+		log.Error(errorMsg, slogext.Error(err))
+		writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// getOwnedFile answers 404 for files of other users so their ids can't be probed
+func getOwnedFile(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.FileRepo) (db_access.File, bool) {
+	file, err := ownedFile(db, auth.UserId(r.Context()), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDownloadError(w, log, err)
+		return db_access.File{}, false
+	}
+
+	return file, true
+}
+
+// addWithUniqueName regenerates uuid in case of duplicate
+func addWithUniqueName(timeOrdered bool, add func(generatedName string) error) (string, error) {
+	for {
+		strId := storage.NewFileId(timeOrdered)
+
+		err := add(strId)
+		var uce db_access.UniqueConstraintError
+		if errors.As(err, &uce) && uce.Column == "generatedName" {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		return strId, nil
+	}
+}
+
+func FileCopy(db db_access.DbAccess, c encryption.Crypter, uploadConfig UploadConfig, blobs *blobstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileCopy"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req FileCopyRequest
+		if !decodeFileOpRequest(w, r, log, &req) || !validateDestination(w, log, req.Folder, req.Name) {
+			return
+		}
+
+		src, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		// without a folder the copy goes next to its source
+		folderId, ok := resolveDestinationFolder(w, r, log, db, c, req.Folder, src.FolderId)
+		if !ok {
+			return
+		}
+
+		name, encName, ok := destinationName(w, log, c, src, req.Name)
+		if !ok {
+			return
+		}
+
+		cfg, ok := uploadConfig.forUser(w, r, log)
+		if !ok {
+			return
+		}
+
+		// without a new name the copy takes the one of its source, which is taken by the source itself
+		claimed, replaced, ok := cfg.DuplicateNames.claimName(w, r, log, db, c, folderId, name, "")
+		if !ok || !requireUnlocked(w, r, log, db, replaced...) {
+			return
+		}
+		if claimed != name {
+			if name, encName, ok = destinationName(w, log, c, src, claimed); !ok {
+				return
+			}
+		}
+
+		// shared copies count against the quota too, as they do in usage
+		used, ok := requireQuota(w, r, log, db, cfg.Quota, src.Size)
+		if !ok {
+			return
+		}
+		warnings := cfg.Quota.warnings(used + src.Size)
+
+		if req.Share {
+			strId, err := addCopy(r.Context(), db, cfg.TimeOrderedIds, src, encName, folderId, true)
+			if err != nil {
+				log.Error("Could not save file info to a db", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+
+			log.Info("Copied file", slog.String("source", src.GeneratedName), slog.String("generated-name", strId), slog.Bool("shared", true))
+			indexFileName(log, db, c, strId, name)
+			removeReplaced(r.Context(), db, blobs, replaced, log)
+			addStorageWarnings(w, warnings)
+			writeResponse(w, UploadResponse{Id: strId, FileName: name, Warnings: warnings}, http.StatusCreated)
+			return
+		}
+
+		blob, err := blobs.Open(r.Context(), src.Backend, src.BlobName)
+		if err != nil {
+			log.Error("Could not open source blob", slogext.Error(err), slog.String("blob", src.BlobName), slog.String("backend", src.Backend))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		defer blob.Close()
+
+		// the copy is always written to the storage dir, wherever the source lives
+		if err := cfg.Space.Require(blob.Size); err != nil {
+			var ise storage.InsufficientSpaceError
+			if errors.As(err, &ise) {
+				errorMsg := "Not enough free space to store the file"
+				log.Error(errorMsg, slog.Uint64("free", ise.Free), slog.Uint64("required", ise.Required))
+				writeError(w, InsufficientStorage, errorMsg, http.StatusInsufficientStorage)
+			} else {
+				log.Error("Could not check free space", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			}
+			return
+		}
+
+		strId, err := addCopy(r.Context(), db, cfg.TimeOrderedIds, src, encName, folderId, false)
+		if err != nil {
+			log.Error("Could not save file info to a db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := reencryptBlob(c, cfg, blob, src.OwnerId, strId); err != nil {
+			log.Error("Could not copy blob", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+
+			if err := db.RemoveFile(strId); err != nil {
+				log.Error("Could not remove incomplete file info from db", slogext.Error(err), slog.String("generated-name", strId))
+			}
+			return
+		}
+
+		log.Info("Copied file", slog.String("source", src.GeneratedName), slog.String("generated-name", strId), slog.Bool("shared", false))
+		indexFileName(log, db, c, strId, name)
+		removeReplaced(r.Context(), db, blobs, replaced, log)
+		addStorageWarnings(w, warnings)
+		writeResponse(w, UploadResponse{Id: strId, FileName: name, Warnings: warnings}, http.StatusCreated)
+	}
+}
+
+// addCopy adds the row of a copy of src in the folder, which either shares the blob of src or gets one of its own,
+// along with the metadata and tags of src. Both stay sealed as they are, since the copy has the same owner.
+func addCopy(ctx context.Context, db db_access.DbAccess, timeOrdered bool, src db_access.File, encName string, folderId int64, shared bool) (string, error) {
+	const op = "api.addCopy"
+
+	return addWithUniqueName(timeOrdered, func(generatedName string) error {
+		return db.WithTx(ctx, func(repos db_access.DbAccess) error {
+			var err error
+			if shared {
+				err = repos.AddFileCopy(generatedName, encName, src.OwnerId, src.BlobName, src.Size)
+			} else {
+				err = repos.AddFile(generatedName, encName, src.OwnerId, src.Size)
+			}
+			if err != nil {
+				return err
+			}
+
+			if folderId != 0 {
+				if err := repos.SetFileFolder(generatedName, folderId); err != nil {
+					return fmt.Errorf("%s: set folder: %w", op, err)
+				}
+			}
+
+			data, err := repos.GetFileMetadata(src.GeneratedName)
+			if err != nil {
+				return fmt.Errorf("%s: get metadata: %w", op, err)
+			}
+			if data != "" {
+				terms, err := repos.GetFileMetadataTerms(src.GeneratedName)
+				if err != nil {
+					return fmt.Errorf("%s: get metadata terms: %w", op, err)
+				}
+				if err := repos.SetFileMetadata(generatedName, src.OwnerId, data, terms); err != nil {
+					return fmt.Errorf("%s: set metadata: %w", op, err)
+				}
+			}
+
+			tags, err := repos.GetFileTags(src.GeneratedName)
+			if err != nil {
+				return fmt.Errorf("%s: get tags: %w", op, err)
+			}
+			if len(tags) > 0 {
+				if err := repos.AddFileTags(generatedName, tags); err != nil {
+					return fmt.Errorf("%s: add tags: %w", op, err)
+				}
+			}
+
+			return nil
+		})
+	})
+}
+
+// destinationName returns the plaintext and encrypted name of a copy or move target
+func destinationName(w http.ResponseWriter, log *slog.Logger, c encryption.Crypter, src db_access.File, newName string) (string, string, bool) {
+	if newName == "" {
+		name, err := c.DecryptFileName(src.FileName)
+		if err != nil {
+			log.Error("Could not decrypt file name", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return "", "", false
+		}
+		return name, src.FileName, true
+	}
+
+	encName, err := c.EncryptFileName(newName)
+	if err != nil {
+		log.Error("Could not encrypt file name", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return "", "", false
+	}
+
+	return newName, encName, true
+}
+
+// reencryptBlob writes a copy of the src blob under a fresh key of the owner, so the copy doesn't depend on the source
+func reencryptBlob(c encryption.Crypter, cfg UploadConfig, src io.Reader, ownerId int64, generatedName string) error {
+	const op = "api.reencryptBlob"
+
+	dest, err := storage.CreateTemp(cfg.StorageDir, cfg.Durability)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer dest.Discard()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.DecryptAndCopy(pw, src))
+	}()
+
+	err = c.EncryptAndCopyFor(ownerId, dest, pr)
+	// unblocks the decrypting side if encryption stopped reading early
+	pr.CloseWithError(errors.New("copy stopped"))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := dest.CommitAs(generatedName); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func FileMove(db db_access.DbAccess, c encryption.Crypter, names DuplicateNames, blobs *blobstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileMove"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req FileMoveRequest
+		if !decodeFileOpRequest(w, r, log, &req) || !validateDestination(w, log, req.Folder, req.Name) {
+			return
+		}
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		folderId, ok := resolveDestinationFolder(w, r, log, db, c, req.Folder, file.FolderId)
+		if !ok {
+			return
+		}
+
+		var replaced []db_access.File
+		if req.Name != "" || folderId != file.FolderId {
+			// a file that keeps its name needs it to be free in the folder it goes to
+			name := req.Name
+			if name == "" {
+				if name, _, ok = destinationName(w, log, c, file, ""); !ok {
+					return
+				}
+			}
+
+			var claimed string
+			if claimed, replaced, ok = names.claimName(w, r, log, db, c, folderId, name, file.GeneratedName); !ok {
+				return
+			}
+			if req.Name != "" || claimed != name {
+				req.Name = claimed
+			}
+
+			if !requireUnlocked(w, r, log, db, append([]db_access.File{file}, replaced...)...) {
+				return
+			}
+		}
+
+		name, encName, ok := destinationName(w, log, c, file, req.Name)
+		if !ok {
+			return
+		}
+
+		err := moveFile(r.Context(), db, file, encName, folderId)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "No file with provided id was found"
+			log.Error(errorMsg, slog.String("generated-name", file.GeneratedName))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not move file", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		if encName != file.FileName {
+			indexFileName(log, db, c, file.GeneratedName, name)
+		}
+
+		removeReplaced(r.Context(), db, blobs, replaced, log)
+		writeResponse(w, UploadResponse{Id: file.GeneratedName, FileName: name}, http.StatusOK)
+	}
+}
+
+// moveFile gives the file the encrypted name and puts it into the folder in one step; either may be what it is already
+func moveFile(ctx context.Context, db db_access.DbAccess, file db_access.File, encName string, folderId int64) error {
+	if encName == file.FileName && folderId == file.FolderId {
+		return nil
+	}
+
+	return db.WithTx(ctx, func(repos db_access.DbAccess) error {
+		if encName != file.FileName {
+			if err := repos.RenameFile(file.GeneratedName, encName); err != nil {
+				return err
+			}
+		}
+
+		if folderId != file.FolderId {
+			return repos.SetFileFolder(file.GeneratedName, folderId)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/delta"
+	"cloud-storage/encryption"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type SignatureResponse struct {
+	// Id is the version of the file the signature is of; deltas against it replace it with a new one
+	Id string `json:"id,omitempty"`
+	delta.Signature
+	ErrorHolder
+}
+
+// blockSize reads the block_size parameter; on failure it writes an error response
+func blockSize(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int, bool) {
+	param := r.URL.Query().Get("block_size")
+	if param == "" {
+		return delta.DefaultBlockSize, true
+	}
+
+	size, err := strconv.Atoi(param)
+	if err != nil || size < delta.MinBlockSize || size > delta.MaxBlockSize {
+		errorMsg := fmt.Sprintf("block_size must be from %d to %d", delta.MinBlockSize, delta.MaxBlockSize)
+		log.Error(errorMsg, slog.String("block_size", param))
+		writeParamError(w, ParameterOutOfRange, "block_size", errorMsg, http.StatusUnprocessableEntity)
+		return 0, false
+	}
+
+	return size, true
+}
+
+// decryptedBlob is the plaintext of a blob being decrypted
+type decryptedBlob struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// Close stops decryption and waits for it to let go of the blob
+func (b decryptedBlob) Close() error {
+	err := b.PipeReader.Close()
+	<-b.done
+	return err
+}
+
+// decryptBlob streams the plaintext of the file
+func decryptBlob(r *http.Request, c encryption.Crypter, blobs *blobstore.Store, file db_access.File) (io.ReadCloser, error) {
+	blob, err := blobs.Open(r.Context(), file.Backend, file.BlobName)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer blob.Close()
+		pw.CloseWithError(c.DecryptAndCopy(pw, blob))
+	}()
+
+	return decryptedBlob{PipeReader: pr, done: done}, nil
+}
+
+// FileSignature returns the checksums of the blocks of the file {id} that FileDelta takes changes against
+func FileSignature(db db_access.FileRepo, c encryption.Crypter, blobs *blobstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileSignature"
+		log := slogext.LogWithOp(op, r.Context())
+
+		size, ok := blockSize(w, r, log)
+		if !ok {
+			return
+		}
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		plain, err := decryptBlob(r, c, blobs, file)
+		if err != nil {
+			log.Error("Could not open blob", slogext.Error(err), slog.String("blob", file.BlobName), slog.String("backend", file.Backend))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		defer plain.Close()
+
+		sig, err := delta.Sign(plain, size)
+		if err != nil {
+			log.Error("Could not sign file", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := writeResponse(w, SignatureResponse{Id: file.GeneratedName, Signature: sig}, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// limitedWriter fails with tooBigFileError once more than n bytes were written
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.n {
+		n, err := lw.w.Write(p[:lw.n])
+		lw.n -= int64(n)
+		if err == nil {
+			err = tooBigFileError{}
+		}
+		return n, err
+	}
+
+	n, err := lw.w.Write(p)
+	lw.n -= int64(n)
+	return n, err
+}
+
+// FileDelta makes a new version of the file {id} out of a delta against it, sent as the body in the format
+// of delta.Writer with the block size the signature was taken with. The new version is stored as a file of its own
+// under a new id, and the old one is removed; there are no versions kept.
+func FileDelta(db db_access.DbAccess, c encryption.Crypter, uploadConfig UploadConfig, blobs *blobstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileDelta"
+		log := slogext.LogWithOp(op, r.Context())
+
+		size, ok := blockSize(w, r, log)
+		if !ok {
+			return
+		}
+
+		base, ok := getOwnedFile(w, r, log, db)
+		if !ok || !requireUnlocked(w, r, log, db, base) {
+			return
+		}
+
+		cfg, ok := uploadConfig.forUser(w, r, log)
+		if !ok {
+			return
+		}
+
+		name, err := c.DecryptFileName(base.FileName)
+		if err != nil {
+			log.Error("Could not decrypt file name", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		// the new version is usually about as big as the old one
+		if !requireSpace(w, log, cfg.Space, base.Size) {
+			return
+		}
+
+		// whatever part of the body was read counts as traffic, even if the upload fails
+		received := &countingReader{r: http.MaxBytesReader(w, r.Body, cfg.multipartLimit())}
+		defer func() { recordTraffic(db, log, auth.UserId(r.Context()), received.n, 0) }()
+
+		dest, err := storage.CreateTemp(cfg.StorageDir, cfg.Durability)
+		if err != nil {
+			log.Error("Could not create file", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		defer func() {
+			if err := dest.Discard(); err != nil {
+				log.Error("Could not remove incomplete file from disk", slogext.Error(err))
+			}
+		}()
+
+		fileSize, err := applyDelta(r, c, blobs, base, size, received, dest, cfg.MaxUploadSize)
+		if err != nil {
+			log.Error("Could not apply delta", slogext.Error(err))
+
+			var mbe *http.MaxBytesError
+			if errors.As(err, &tooBigFileError{}) || errors.As(err, &mbe) {
+				writeError(w, TooBigContentSize, "File size exceeds max upload size", http.StatusRequestEntityTooLarge)
+			} else if errors.Is(err, delta.ErrInvalidDelta) {
+				writeError(w, InvalidContentFormat, err.Error(), http.StatusUnprocessableEntity)
+			} else {
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			}
+			return
+		}
+
+		if fileSize == 0 {
+			errorMsg := "Delta makes an empty file"
+			log.Error(errorMsg)
+			writeError(w, ParameterOutOfRange, errorMsg, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// the old version goes once the new one is in place
+		used, ok := requireQuota(w, r, log, db, cfg.Quota, max(fileSize-base.Size, 0))
+		if !ok {
+			return
+		}
+		warnings := cfg.Quota.warnings(used + max(fileSize-base.Size, 0))
+
+		expiresAt := cfg.applyRetention(base.ExpiresAt)
+		strId, err := addWithUniqueName(cfg.TimeOrderedIds, func(generatedName string) error {
+			return db.WithTx(r.Context(), func(repos db_access.DbAccess) error {
+				if err := repos.AddFile(generatedName, base.FileName, base.OwnerId, fileSize); err != nil {
+					return err
+				}
+
+				if !time.Time(expiresAt).IsZero() {
+					return repos.SetFileExpiry(generatedName, expiresAt)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			log.Error("Could not save file info to a db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := dest.CommitAs(strId); err != nil {
+			log.Error("Could not save file to disk", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+
+			if err := db.RemoveFile(strId); err != nil {
+				log.Error("Could not remove incomplete file info from db", slogext.Error(err), slog.String("generated-name", strId))
+			}
+			return
+		}
+
+		log.Info(
+			"File updated from delta",
+			slog.String("base", base.GeneratedName),
+			slog.String("generated-name", strId),
+			slog.Int64("received", received.n),
+			slog.Int64("size", fileSize),
+		)
+		indexFileName(log, db, c, strId, name)
+		removeReplaced(r.Context(), db, blobs, []db_access.File{base}, log)
+
+		resp := UploadResponse{Id: strId, FileName: name, ExpiresAt: unixOrZero(expiresAt), Warnings: warnings}
+		addStorageWarnings(w, warnings)
+		if err := writeResponse(w, resp, http.StatusCreated); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// applyDelta encrypts into dest the file that the delta read from body makes of base, and returns its size
+func applyDelta(
+	r *http.Request,
+	c encryption.Crypter,
+	blobs *blobstore.Store,
+	base db_access.File,
+	blockSize int,
+	body io.Reader,
+	dest io.Writer,
+	maxSize int64,
+) (int64, error) {
+	const op = "api.applyDelta"
+
+	plain, err := decryptBlob(r, c, blobs, base)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	// the delta may not need the end of base
+	defer plain.Close()
+
+	pr, pw := io.Pipe()
+	encrypted := make(chan error, 1)
+	go func() {
+		err := c.EncryptAndCopyFor(base.OwnerId, dest, pr)
+		// unblocks Apply if encryption stopped reading early
+		pr.CloseWithError(errors.New("encryption stopped"))
+		encrypted <- err
+	}()
+
+	n, err := delta.Apply(&limitedWriter{w: pw, n: maxSize}, plain, blockSize, body)
+	pw.CloseWithError(err)
+	if encErr := <-encrypted; err == nil && encErr != nil {
+		err = encErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
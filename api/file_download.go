@@ -1,17 +1,27 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"cloud-storage/access"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
 	"cloud-storage/db_access"
 	"cloud-storage/encryption"
+	"cloud-storage/utils/disposition"
 	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
 	"encoding/json"
-	"errors"
+	"io"
 	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type FileRequest struct {
@@ -20,7 +30,10 @@ type FileRequest struct {
 
 const maxContentLen = 512
 
-func FileDownload(db db_access.DbAccess, c encryption.Crypter, storageDir string) http.HandlerFunc {
+func FileDownload(db db_access.DbAccess, c encryption.Crypter, blobs *blobstore.Store, accesses *access.Recorder, chunkSize int) http.HandlerFunc {
+	ds := NewDownloadService(db, c, blobs)
+	fs := newFileStreamer(chunkSize)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "api.FileDownload"
 		log := slogext.LogWithOp(op, r.Context())
@@ -61,53 +74,157 @@ func FileDownload(db db_access.DbAccess, c encryption.Crypter, storageDir string
 			return
 		}
 		
-		encryptedFilename, err := db.GetFile(req.Id)
-		var nre db_access.NoRowsError
-		if errors.As(err, &nre) {
-			errorMsg := "No file with provided id was found"
-			log.Error(errorMsg, slogext.Error(err))
-			writeError(w, NotFound, errorMsg, http.StatusNotFound)
-			return
-		} else if err != nil {
-			errorMsg := "Could not get file from db"
-			log.Error(errorMsg, slogext.Error(err))
-			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		var v validate.Validator
+		v.Required("id", req.Id)
+		if !requireValid(w, log, &v) {
 			return
 		}
-		
-		fileName, err := c.DecryptFileName(encryptedFilename)
-		if err != nil {
-			log.Error("Could not decrypt file name", slogext.Error(err))
-			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
-			return
-		}
-		
-		path := filepath.Join(storageDir, req.Id)
-		file, err := os.Open(path)
-		if err != nil {
-			log.Error("Could not open file", slogext.Error(err), slog.String("path", path))
-			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
-			return
-		}
-		defer file.Close()
-		
-		form := multipart.NewWriter(w)
-		defer form.Close()
 
-		w.Header().Set("Content-Type", form.FormDataContentType())
-		
-		part, err := form.CreateFormFile("file", fileName)
+		d, err := ds.Open(r.Context(), auth.UserId(r.Context()), req.Id)
 		if err != nil {
-			log.Error("Could not create form file", slogext.Error(err))
-			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			writeDownloadError(w, log, err)
 			return
 		}
-		
-		err = c.DecryptAndCopy(part, file)
+		defer d.Close()
+
+		n := fs.stream(w, log, d)
+		recordTraffic(db, log, auth.UserId(r.Context()), 0, n)
+		recordAccess(accesses, d.File.GeneratedName, n)
+	}
+}
+
+// FileGet is FileDownload for clients that can't send a body with GET, such as browsers
+func FileGet(db db_access.DbAccess, c encryption.Crypter, blobs *blobstore.Store, accesses *access.Recorder, chunkSize int) http.HandlerFunc {
+	ds := NewDownloadService(db, c, blobs)
+	fs := newFileStreamer(chunkSize)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileGet"
+		log := slogext.LogWithOp(op, r.Context())
+
+		d, err := ds.Open(r.Context(), auth.UserId(r.Context()), chi.URLParam(r, "id"))
 		if err != nil {
-			log.Error("Decrypt and copy error", slogext.Error(err))
-			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			writeDownloadError(w, log, err)
 			return
 		}
+		defer d.Close()
+
+		n := fs.stream(w, log, d)
+		recordTraffic(db, log, auth.UserId(r.Context()), 0, n)
+		recordAccess(accesses, d.File.GeneratedName, n)
+	}
+}
+
+// fileStreamer decrypts downloads straight into the response. Writes go through a pooled
+// buffer of one encryption chunk, so every decrypted chunk and the small
+// multipart framing writes around it reach the connection in a single write.
+type fileStreamer struct {
+	writers *sync.Pool
+}
+
+func newFileStreamer(chunkSize int) fileStreamer {
+	return fileStreamer{
+		writers: &sync.Pool{
+			New: func() any {
+				return bufio.NewWriterSize(nil, chunkSize)
+			},
+		},
+	}
+}
+
+// stream writes the download as a single-part multipart form and reports errors to the client itself;
+// it returns how many plaintext bytes went out
+func (fs fileStreamer) stream(w http.ResponseWriter, log *slog.Logger, d *Download) int64 {
+	bw := fs.writers.Get().(*bufio.Writer)
+	bw.Reset(w)
+	defer func() {
+		bw.Reset(nil)
+		fs.writers.Put(bw)
+	}()
+
+	form := multipart.NewWriter(bw)
+
+	w.Header().Set("Content-Type", form.FormDataContentType())
+	w.Header().Set("ETag", fileETag(d.File.GeneratedName))
+	
+	part, err := form.CreateFormFile("file", d.FileName)
+	if err != nil {
+		log.Error("Could not create form file", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return 0
+	}
+
+	n, err := d.WriteTo(part)
+	if err != nil {
+		log.Error("Decrypt and copy error", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return n
 	}
+
+	if err := form.Close(); err != nil {
+		log.Error("Could not close form", slogext.Error(err))
+		return n
+	}
+
+	if err := bw.Flush(); err != nil {
+		log.Error("Could not flush response", slogext.Error(err))
+	}
+
+	return n
+}
+
+// streamRaw writes the download as the response body, for clients like <img src> or wget
+// that can't unpack a multipart form
+func (fs fileStreamer) streamRaw(w http.ResponseWriter, log *slog.Logger, d *Download) int64 {
+	bw := fs.writers.Get().(*bufio.Writer)
+	bw.Reset(w)
+	defer func() {
+		bw.Reset(nil)
+		fs.writers.Put(bw)
+	}()
+
+	contentType := mime.TypeByExtension(filepath.Ext(d.FileName))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// media is shown in place, anything else that a browser could run is saved instead
+	dispositionType := "attachment"
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediaType, "image/") && mediaType != "image/svg+xml" ||
+		strings.HasPrefix(mediaType, "video/") ||
+		strings.HasPrefix(mediaType, "audio/") ||
+		mediaType == "text/plain" {
+		dispositionType = "inline"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fileETag(d.File.GeneratedName))
+	w.Header().Set("Content-Disposition", disposition.Format(dispositionType, d.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+
+	n, err := d.WriteTo(bw)
+	if err != nil {
+		log.Error("Decrypt and copy error", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return n
+	}
+
+	if err := bw.Flush(); err != nil {
+		log.Error("Could not flush response", slogext.Error(err))
+	}
+
+	return n
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
 }
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FileExists answers 200 if the user has the file and 404 otherwise, without a body either way,
+// so sync clients can check many files without fetching their metadata. It serves HEAD of the file as well.
+func FileExists(db db_access.FileRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileExists"
+		log := slogext.LogWithOp(op, r.Context())
+
+		id := chi.URLParam(r, "id")
+		_, err := ownedFile(db, auth.UserId(r.Context()), id)
+		if errors.Is(err, ErrFileNotFound) {
+			log.Debug("No file with provided id was found", slog.String("generated-name", id))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get file from db", slogext.Error(err))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/importer"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	maxFromUrlLen        = 2048
+	maxFromUrlRequestLen = 4096
+)
+
+type FromUrlRequest struct {
+	Url string `json:"url"`
+	// FileName defaults to the last segment of the url path
+	FileName string `json:"file_name"`
+}
+
+// FileFromUrl queues fetching the url into a file of the user. The fetch runs as an import
+// of a single file, whose progress GET /api/jobs/{id} reports.
+func FileFromUrl(imp *importer.Importer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileFromUrl"
+		log := slogext.LogWithOp(op, r.Context())
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxFromUrlRequestLen)
+
+		var req FromUrlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		var v validate.Validator
+		if v.Required("url", req.Url) {
+			v.MaxLen("url", req.Url, maxFromUrlLen)
+		}
+		validateFileName(&v, "file_name", req.FileName)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		started, err := imp.Start(auth.UserId(r.Context()), importer.SourceSpec{
+			Type: importer.SourceUrl,
+			Url:  &importer.UrlSpec{Url: req.Url, FileName: req.FileName},
+		})
+		var ise importer.InvalidSourceError
+		if errors.As(err, &ise) {
+			log.Error("Invalid url to fetch", slogext.Error(err))
+			writeParamError(w, ParameterOutOfRange, "url", ise.Reason, http.StatusUnprocessableEntity)
+			return
+		} else if errors.Is(err, importer.ErrQueueFull) {
+			log.Error("Import queue is full")
+			writeError(w, TooManyRequests, "Too many imports in progress; try again later", http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			log.Error("Could not start fetching url", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Queued fetching url", slog.String("import-id", started.Id))
+
+		if err := writeResponse(w, importResponse(started), http.StatusAccepted); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
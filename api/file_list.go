@@ -0,0 +1,96 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"cmp"
+	"log/slog"
+	"net/http"
+)
+
+// FileList lists the files of the user; meta.<key>=<value> query parameters keep those whose metadata has all the pairs.
+// Clients polling it get 304 with the ETag of the listing they have until the files of the user change.
+func FileList(db db_access.DbAccess, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileList"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId := auth.UserId(r.Context())
+		terms, err := metadataFilter(c, userId, r.URL.Query())
+		if err != nil {
+			log.Error("Could not hash metadata filter", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		// read before the files, so that a change in between makes the next request list them again
+		version, err := db.GetCollectionVersion(userId)
+		if err != nil {
+			log.Error("Could not get collection version from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		etag := collectionETag(userId, version)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if notModified(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var files []db_access.File
+		if terms != nil {
+			files, err = db.GetFilesByMetadata(userId, terms)
+		} else {
+			files, err = db.GetUserFiles(userId)
+		}
+		if err != nil {
+			log.Error("Could not get user files from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp, ok := fileListResponse(w, log, c, files)
+		if !ok {
+			return
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// fileListResponse decrypts the names of files; on failure it writes the error and returns false
+func fileListResponse(w http.ResponseWriter, log *slog.Logger, c encryption.Crypter, files []db_access.File) (FileListResponse, bool) {
+	ciphertexts := make([]string, 0, len(files))
+	for _, file := range files {
+		ciphertexts = append(ciphertexts, file.FileName)
+	}
+
+	fileNames, err := c.DecryptFileNames(ciphertexts)
+	if err != nil {
+		log.Error("Could not decrypt file names", slogext.Error(err), slog.Int("files", len(files)))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return FileListResponse{}, false
+	}
+
+	resp := FileListResponse{Files: make([]FileInfo, 0, len(files))}
+	for i, file := range files {
+		resp.Files = append(resp.Files, FileInfo{
+			Id:            file.GeneratedName,
+			FileName:      fileNames[i],
+			ExpiresAt:     unixOrZero(file.ExpiresAt),
+			Tier:          cmp.Or(file.Backend, blobstore.Local),
+			LastAccessAt:  unixOrZero(file.LastAccess),
+			DownloadCount: file.DownloadCount,
+			FolderId:      file.FolderId,
+		})
+	}
+
+	return resp, true
+}
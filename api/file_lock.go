@@ -0,0 +1,237 @@
+package api
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// lockTokenHeader carries the token of the lock a client holds on the file it saves over or renames
+const lockTokenHeader = "X-Lock-Token"
+
+const maxLockHolderLen = 256
+
+// LockConfig bounds how long a lock lasts without being refreshed, so that a client going away
+// doesn't keep the file locked for good
+type LockConfig struct {
+	// TimeToLive is used when the client doesn't ask for one
+	TimeToLive    time.Duration
+	MaxTimeToLive time.Duration
+}
+
+type LockRequest struct {
+	// zero means the server default
+	TimeToLive int64 `json:"ttl_seconds"`
+	// Holder tells the clients turned away by the lock who holds it, e.g. "Word on laptop"
+	Holder string `json:"holder"`
+	// Steal takes over a lock held by another client
+	Steal bool `json:"steal"`
+}
+
+type LockResponse struct {
+	// Token is only given to the holder of the lock
+	Token     string `json:"token,omitempty"`
+	Holder    string `json:"holder,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	ErrorHolder
+}
+
+func lockToken(r *http.Request) string {
+	return r.Header.Get(lockTokenHeader)
+}
+
+func lockActive(lock db_access.FileLock) bool {
+	return time.Now().Before(time.Time(lock.ExpiresAt))
+}
+
+func lockedError(lock db_access.FileLock) ApiError {
+	return ApiError{
+		Code:        FileLocked,
+		ParamName:   lockTokenHeader,
+		Description: fmt.Sprintf("The file is locked by %q until %s", lock.Holder, time.Time(lock.ExpiresAt).UTC().Format(time.RFC3339)),
+	}
+}
+
+// writeLocked turns a client away from a lock held by another one, telling it by whom and for how long
+func writeLocked(w http.ResponseWriter, log *slog.Logger, lock db_access.FileLock) {
+	apiErr := lockedError(lock)
+	log.Error(apiErr.Description)
+
+	resp := LockResponse{Holder: lock.Holder, ExpiresAt: unixOrZero(lock.ExpiresAt)}
+	resp.Errors = append(resp.Errors, apiErr)
+	if err := writeResponse(w, resp, http.StatusLocked); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+}
+
+// checkUnlocked fails with FileLocked unless each of the files is either not locked or locked with token.
+// Expired locks don't count.
+func checkUnlocked(log *slog.Logger, db db_access.LockRepo, token string, files ...db_access.File) (ApiError, int) {
+	for _, file := range files {
+		lock, err := db.GetFileLock(file.GeneratedName)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			continue
+		} else if err != nil {
+			log.Error("Could not get file lock from db", slogext.Error(err), slog.String("generated-name", file.GeneratedName))
+			return ApiError{Code: InternalApiError}, http.StatusServiceUnavailable
+		}
+
+		if lockActive(lock) && lock.Token != token {
+			return lockedError(lock), http.StatusLocked
+		}
+	}
+
+	return ApiError{}, 0
+}
+
+// requireUnlocked is checkUnlocked for the lock token of the request; it writes an error response and returns false on failure
+func requireUnlocked(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.LockRepo, files ...db_access.File) bool {
+	apiErr, status := checkUnlocked(log, db, lockToken(r), files...)
+	if status == 0 {
+		return true
+	}
+
+	if apiErr.Description != "" {
+		log.Error(apiErr.Description)
+	}
+	if err := writeParamError(w, apiErr.Code, apiErr.ParamName, apiErr.Description, status); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+	return false
+}
+
+// FileLock locks the file {id} against being saved over or renamed by clients without the token it answers with.
+// The holder of the lock refreshes it by locking again with the token in X-Lock-Token. A lock held by another client
+// is turned down with 423 unless the request steals it; expired locks are taken over silently.
+func FileLock(db db_access.DbAccess, cfg LockConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileLock"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req LockRequest
+		if r.ContentLength != 0 && !decodeFileOpRequest(w, r, log, &req) {
+			return
+		}
+
+		var v validate.Validator
+		v.Range("ttl_seconds", req.TimeToLive, 0, int64(cfg.MaxTimeToLive/time.Second))
+		v.MaxLen("holder", req.Holder, maxLockHolderLen)
+		v.UTF8("holder", req.Holder)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		ttl := time.Duration(req.TimeToLive) * time.Second
+		if ttl == 0 {
+			ttl = cfg.TimeToLive
+		}
+		now := time.Now()
+
+		var lock, held db_access.FileLock
+		err := db.WithTx(r.Context(), func(repos db_access.DbAccess) error {
+			current, err := repos.GetFileLock(file.GeneratedName)
+			var nre db_access.NoRowsError
+			if err != nil && !errors.As(err, &nre) {
+				return err
+			}
+
+			lock = db_access.FileLock{
+				GeneratedName: file.GeneratedName,
+				Token:         uuid.New().String(),
+				Holder:        req.Holder,
+				CreatedAt:     db_access.Time(now),
+				ExpiresAt:     db_access.Time(now.Add(ttl)),
+			}
+
+			if err == nil && lockActive(current) {
+				switch {
+				case current.Token == lockToken(r):
+					// a refresh keeps the token, so the holder can go on using it
+					lock.Token, lock.CreatedAt = current.Token, current.CreatedAt
+					if lock.Holder == "" {
+						lock.Holder = current.Holder
+					}
+				case !req.Steal:
+					held = current
+					return nil
+				default:
+					log.Info("Stealing file lock", slog.String("generated-name", file.GeneratedName), slog.String("previous-holder", current.Holder))
+				}
+			}
+
+			return repos.SetFileLock(lock)
+		})
+		if err != nil {
+			log.Error("Could not lock file", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if held.Token != "" {
+			writeLocked(w, log, held)
+			return
+		}
+
+		resp := LockResponse{Token: lock.Token, Holder: lock.Holder, ExpiresAt: unixOrZero(lock.ExpiresAt)}
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// FileUnlock releases the lock of the file {id}, which takes its token in X-Lock-Token.
+// Files without a lock, or with an expired one, are unlocked already.
+func FileUnlock(db db_access.DbAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileUnlock"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		var held db_access.FileLock
+		err := db.WithTx(r.Context(), func(repos db_access.DbAccess) error {
+			current, err := repos.GetFileLock(file.GeneratedName)
+			var nre db_access.NoRowsError
+			if errors.As(err, &nre) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if lockActive(current) && current.Token != lockToken(r) {
+				held = current
+				return nil
+			}
+
+			return repos.RemoveFileLock(file.GeneratedName)
+		})
+		if err != nil {
+			log.Error("Could not unlock file", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if held.Token != "" {
+			writeLocked(w, log, held)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DuplicateNames is what happens when a file is given the name of another file of the same owner
+// in the same folder. Names are compared across the files of the owner sharing the name index,
+// which spares them from being decrypted one by one.
+// Uploads check names in the transaction adding the file, so that concurrent ones can't end up with
+// the same name; copies and moves check them beforehand, so they still may.
+// The zero value behaves as AllowDuplicates.
+type DuplicateNames string
+
+const (
+	AllowDuplicates DuplicateNames = "allow"
+	// RejectDuplicates answers 409
+	RejectDuplicates DuplicateNames = "reject"
+	// RenameDuplicates names the file "name (1).ext", "name (2).ext"... whichever is free first
+	RenameDuplicates DuplicateNames = "rename"
+	// OverwriteDuplicates deletes the files of that name once the new one has it; there are no
+	// versions kept, so the new file takes their place under its own id
+	OverwriteDuplicates DuplicateNames = "overwrite"
+)
+
+func (d *DuplicateNames) UnmarshalText(text []byte) error {
+	switch v := DuplicateNames(text); v {
+	case AllowDuplicates, RejectDuplicates, RenameDuplicates, OverwriteDuplicates:
+		*d = v
+		return nil
+	case "":
+		*d = AllowDuplicates
+		return nil
+	}
+
+	return fmt.Errorf("unknown duplicate names policy %q; expected one of allow, reject, rename, overwrite", text)
+}
+
+// resolve applies d to name, which a file of the user in the folder is about to get; except is the id
+// of that file if it exists already. It returns the name to give the file and the files to remove once
+// it has it, or a non-zero status.
+func (d DuplicateNames) resolve(
+	log *slog.Logger,
+	db db_access.FileRepo,
+	c encryption.Crypter,
+	userId int64,
+	folderId int64,
+	name string,
+	except string,
+) (string, []db_access.File, ApiError, int) {
+	if d == "" || d == AllowDuplicates {
+		return name, nil, ApiError{}, 0
+	}
+
+	taken, err := filesNamed(log, db, c, userId, folderId, name, except)
+	if err != nil {
+		log.Error("Could not look up files by name", slogext.Error(err))
+		return "", nil, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable
+	}
+
+	if len(taken) == 0 {
+		return name, nil, ApiError{}, 0
+	}
+
+	switch d {
+	case RejectDuplicates:
+		return "", nil, ApiError{Code: FileNameTaken, Description: "A file with this name already exists"}, http.StatusConflict
+	case RenameDuplicates:
+		for i := 1; ; i++ {
+			candidate := numberedName(name, i)
+			taken, err := filesNamed(log, db, c, userId, folderId, candidate, except)
+			if err != nil {
+				log.Error("Could not look up files by name", slogext.Error(err))
+				return "", nil, ApiError{Code: InternalApiError}, http.StatusServiceUnavailable
+			}
+
+			if len(taken) == 0 {
+				return candidate, nil, ApiError{}, 0
+			}
+		}
+	}
+
+	return name, taken, ApiError{}, 0
+}
+
+// filesNamed returns the files of the user in the folder named exactly name, besides except. Only names
+// sharing its index or without one get decrypted, and the missing indexes are recorded on the way.
+func filesNamed(
+	log *slog.Logger,
+	db db_access.FileRepo,
+	c encryption.Crypter,
+	userId int64,
+	folderId int64,
+	name string,
+	except string,
+) ([]db_access.File, error) {
+	const op = "api.filesNamed"
+
+	index, err := c.FileNameIndex(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	files, err := db.GetFilesByNameIndex(userId, index)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the index is the same for names differing in case, so the names themselves are compared too
+	var found []db_access.File
+	for _, file := range files {
+		if file.GeneratedName == except || file.FolderId != folderId {
+			continue
+		}
+
+		fileName, err := c.DecryptFileName(file.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, file.GeneratedName, err)
+		}
+
+		if file.NameIndex == "" {
+			indexFileName(log, db, c, file.GeneratedName, fileName)
+		}
+
+		if fileName == name {
+			found = append(found, file)
+		}
+	}
+
+	return found, nil
+}
+
+// indexFileName records the name index of a file that was given name; a file without one is only
+// slower to find, so failures are just logged
+func indexFileName(log *slog.Logger, db db_access.FileRepo, c encryption.Crypter, generatedName string, name string) {
+	index, err := c.FileNameIndex(name)
+	if err == nil {
+		err = db.SetFileNameIndex(generatedName, index)
+	}
+
+	var nre db_access.NoRowsError
+	if err != nil && !errors.As(err, &nre) {
+		log.Warn("Could not index file name", slogext.Error(err), slog.String("generated-name", generatedName))
+	}
+}
+
+// claimName is resolve for the user of the request; it writes an error response and returns false on failure
+func (d DuplicateNames) claimName(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	db db_access.FileRepo,
+	c encryption.Crypter,
+	folderId int64,
+	name string,
+	except string,
+) (string, []db_access.File, bool) {
+	name, replaced, apiErr, status := d.resolve(log, db, c, auth.UserId(r.Context()), folderId, name, except)
+	if status != 0 {
+		if apiErr.Description != "" {
+			log.Error(apiErr.Description, slog.String("policy", string(d)))
+		}
+
+		if err := writeError(w, apiErr.Code, apiErr.Description, status); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return "", nil, false
+	}
+
+	return name, replaced, true
+}
+
+// numberedName turns report.txt into report (i).txt; the base is cut short where the number
+// would not fit into maxFileNameLen otherwise
+func numberedName(name string, i int) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if base == "" || len(ext) > maxFileNameLen/2 {
+		base, ext = name, ""
+	}
+
+	suffix := fmt.Sprintf(" (%d)", i)
+	if room := maxFileNameLen - len(suffix) - len(ext); len(base) > room {
+		// cutting may split a multibyte character, whose remains are dropped
+		base = strings.ToValidUTF8(base[:room], "")
+	}
+
+	return base + suffix + ext
+}
+
+// removeReplaced deletes the files overwritten by a file taking their name; the request succeeded already,
+// so failures are only logged
+func removeReplaced(ctx context.Context, db db_access.FileRepo, blobs *blobstore.Store, replaced []db_access.File, log *slog.Logger) {
+	for _, file := range replaced {
+		err := removeFile(ctx, db, blobs, file.GeneratedName, log)
+		var nre db_access.NoRowsError
+		if err != nil && !errors.As(err, &nre) {
+			log.Error("Could not remove overwritten file", slogext.Error(err), slog.String("generated-name", file.GeneratedName))
+			continue
+		}
+
+		log.Info("Removed overwritten file", slog.String("generated-name", file.GeneratedName))
+	}
+}
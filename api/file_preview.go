@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/preview"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPreviewSize = 16 << 10
+	maxPreviewSize     = 64 << 10
+	// what http.DetectContentType looks at
+	sniffLen = 512
+)
+
+// FilePreview returns the beginning of a text file, or the first page of a PDF as an image if pdf is set.
+// Rendering PDFs needs tools the server doesn't ship, so without pdf they are reported as unavailable.
+func FilePreview(db db_access.FileRepo, c encryption.Crypter, blobs *blobstore.Store, pdf preview.Renderer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FilePreview"
+		log := slogext.LogWithOp(op, r.Context())
+
+		size := defaultPreviewSize
+		if param := r.URL.Query().Get("bytes"); param != "" {
+			var err error
+			size, err = strconv.Atoi(param)
+			if err != nil || size <= 0 || size > maxPreviewSize {
+				errorMsg := fmt.Sprintf("bytes must be from 1 to %d", maxPreviewSize)
+				log.Error(errorMsg, slog.String("bytes", param))
+				writeParamError(w, ParameterOutOfRange, "bytes", errorMsg, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		d, err := NewDownloadService(db, c, blobs).Open(r.Context(), auth.UserId(r.Context()), chi.URLParam(r, "id"))
+		if err != nil {
+			writeDownloadError(w, log, err)
+			return
+		}
+		// closing stops decryption of the rest
+		defer d.Close()
+
+		// one byte more than the preview tells whether it is truncated
+		head := make([]byte, max(size, sniffLen)+1)
+		n, err := io.ReadFull(d, head)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Error("Decrypt and copy error", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		head = head[:n]
+
+		contentType := http.DetectContentType(head)
+		if contentType == "application/pdf" {
+			writePdfPreview(w, r, log, pdf, io.MultiReader(bytes.NewReader(head), d))
+			return
+		}
+		if !strings.HasPrefix(contentType, "text/") {
+			errorMsg := fmt.Sprintf("Preview is not available for %s files", contentType)
+			log.Info(errorMsg)
+			writeError(w, PreviewUnavailable, errorMsg, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		truncated := len(head) > size
+		text := head[:min(len(head), size)]
+		if truncated {
+			// don't cut a multi-byte character in half
+			for i := 1; i <= utf8.UTFMax && i <= len(text); i++ {
+				if utf8.RuneStart(text[len(text)-i]) {
+					if !utf8.FullRune(text[len(text)-i:]) {
+						text = text[:len(text)-i]
+					}
+					break
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Preview-Truncated", strconv.FormatBool(truncated))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(text); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// writePdfPreview answers with the first page of the PDF read from r
+func writePdfPreview(w http.ResponseWriter, r *http.Request, log *slog.Logger, pdf preview.Renderer, content io.Reader) {
+	if pdf == nil {
+		errorMsg := "Preview of PDF files is not set up on this server"
+		log.Info(errorMsg)
+		writeError(w, PreviewUnavailable, errorMsg, http.StatusNotImplemented)
+		return
+	}
+
+	// the image is kept until rendering is done, so that a failure can still be answered with an error
+	var image bytes.Buffer
+	if err := pdf.RenderFirstPage(r.Context(), content, &image); err != nil {
+		log.Error("Could not render PDF", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", preview.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(image.Len()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := image.WriteTo(w); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	dbaccess "cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+)
+
+const fileNameHeader = "X-File-Name"
+
+// FilePut stores the raw request body as a file; the name comes from X-File-Name
+// (percent-encoded if it is not plain ascii) and the size from Content-Length.
+func FilePut(db dbaccess.DbAccess, uploadConfig UploadConfig, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FilePut"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var v validate.Validator
+		expiresAt := validateUploadExpiry(&v, r)
+		metadata := validateMetadataHeader(&v, r)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		cfg, ok := uploadConfig.forUser(w, r, log)
+		if !ok {
+			return
+		}
+		expiresAt = cfg.applyRetention(expiresAt)
+
+		filename, err := url.PathUnescape(r.Header.Get(fileNameHeader))
+		if err == nil {
+			filename = filepath.Base(filepath.Clean("/" + filename))
+		}
+		if err != nil || filename == "/" || filename == "." {
+			errorMsg := "X-File-Name must hold a file name"
+			log.Error(errorMsg, slog.String("file-name", r.Header.Get(fileNameHeader)))
+
+			if err := writeParamError(w, InvalidContentFormat, fileNameHeader, errorMsg, http.StatusUnprocessableEntity); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		if !requireFileNameLen(w, log, fileNameHeader, filename) {
+			return
+		}
+
+		fileSize := r.ContentLength
+		if fileSize < 0 {
+			errorMsg := "Content-Length is required"
+			log.Error(errorMsg)
+
+			if err := writeParamError(w, InvalidContentFormat, "Content-Length", errorMsg, http.StatusLengthRequired); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		if fileSize > cfg.MaxUploadSize || fileSize == 0 {
+			errorMsg := "Content-Length is not in valid range"
+			log.Error(errorMsg, slog.Int64("content-length", fileSize), slog.Int64("max-upload-size", cfg.MaxUploadSize))
+
+			status := http.StatusUnprocessableEntity
+			if fileSize > cfg.MaxUploadSize {
+				status = http.StatusRequestEntityTooLarge
+			}
+			if err := writeParamError(w, ParameterOutOfRange, "Content-Length", errorMsg, status); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		meta := UploadMeta{
+			OwnerId:     auth.UserId(r.Context()),
+			FileName:    filename,
+			ContentType: r.Header.Get("Content-Type"),
+			Size:        fileSize,
+			MaxSize:     fileSize,
+			ExpiresAt:   expiresAt,
+			IfMatch:     parseIfMatch(r),
+			LockToken:   lockToken(r),
+			Metadata:    metadata,
+		}
+
+		result, err := NewUploadService(db, cfg, c).Upload(r.Context(), meta, r.Body)
+		if err != nil {
+			writeUploadError(w, log, err)
+			return
+		}
+
+		resp := UploadResponse{
+			Id:        result.Id,
+			FileName:  result.FileName,
+			ExpiresAt: unixOrZero(expiresAt),
+			Warnings:  result.Warnings,
+		}
+		w.Header().Set("ETag", fileETag(result.Id))
+		addStorageWarnings(w, resp.Warnings)
+		writeResponse(w, resp, http.StatusCreated)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultRecentFiles = 20
+	maxRecentFiles     = 100
+)
+
+// FileRecent lists the files of the user that were downloaded most recently, latest first
+func FileRecent(db db_access.FileRepo, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileRecent"
+		log := slogext.LogWithOp(op, r.Context())
+
+		limit := defaultRecentFiles
+		if param := r.URL.Query().Get("limit"); param != "" {
+			var err error
+			limit, err = strconv.Atoi(param)
+			if err != nil || limit <= 0 || limit > maxRecentFiles {
+				errorMsg := fmt.Sprintf("limit must be from 1 to %d", maxRecentFiles)
+				log.Error(errorMsg, slog.String("limit", param))
+				writeParamError(w, ParameterOutOfRange, "limit", errorMsg, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		files, err := db.GetRecentFiles(auth.UserId(r.Context()), limit)
+		if err != nil {
+			log.Error("Could not get recent files from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp, ok := fileListResponse(w, log, c, files)
+		if !ok {
+			return
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
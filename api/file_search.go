@@ -0,0 +1,95 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/search"
+	slogext "cloud-storage/utils/slogExt"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const maxSearchQueryLen = 256
+
+const (
+	MatchName    = "name"
+	MatchContent = "content"
+)
+
+// FileSearch finds the files of the user whose names contain the query q, ignoring case, and,
+// if contentSearch is on, the indexed files that have every word of it. Files the indexer hasn't
+// got to yet are only found by name.
+func FileSearch(db db_access.DbAccess, c encryption.Crypter, contentSearch bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileSearch"
+		log := slogext.LogWithOp(op, r.Context())
+
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" || len(query) > maxSearchQueryLen {
+			errorMsg := fmt.Sprintf("q must be from 1 to %d bytes", maxSearchQueryLen)
+			log.Error(errorMsg, slog.Int("length", len(query)))
+			writeParamError(w, ParameterOutOfRange, "q", errorMsg, http.StatusUnprocessableEntity)
+			return
+		}
+
+		userId := auth.UserId(r.Context())
+		files, err := db.GetUserFiles(userId)
+		if err != nil {
+			log.Error("Could not get user files from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		byContent := make(map[string]struct{})
+		if terms := search.Terms(query); contentSearch && len(terms) > 0 {
+			hashes := make([]string, 0, len(terms))
+			for _, term := range terms {
+				hash, err := c.ContentTermIndex(userId, term)
+				if err != nil {
+					log.Error("Could not hash search term", slogext.Error(err))
+					writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+					return
+				}
+				hashes = append(hashes, hash)
+			}
+
+			matches, err := db.GetFilesByTerms(userId, hashes)
+			if err != nil {
+				log.Error("Could not get files by terms from db", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+			for _, file := range matches {
+				byContent[file.GeneratedName] = struct{}{}
+			}
+		}
+
+		list, ok := fileListResponse(w, log, c, files)
+		if !ok {
+			return
+		}
+
+		resp := SearchResponse{Files: make([]SearchResult, 0)}
+		query = strings.ToLower(query)
+		for _, file := range list.Files {
+			var matches []string
+			if strings.Contains(strings.ToLower(file.FileName), query) {
+				matches = append(matches, MatchName)
+			}
+			if _, ok := byContent[file.Id]; ok {
+				matches = append(matches, MatchContent)
+			}
+
+			if len(matches) > 0 {
+				resp.Files = append(resp.Files, SearchResult{FileInfo: file, Matches: matches})
+			}
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
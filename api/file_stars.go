@@ -0,0 +1,77 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// FileStar stars the file {id} for the user; starring a starred file again changes nothing
+func FileStar(db db_access.DbAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileStar"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		if err := db.StarFile(auth.UserId(r.Context()), file.GeneratedName, db_access.Time(time.Now())); err != nil {
+			log.Error("Could not star file", slogext.Error(err), slog.String("generated-name", file.GeneratedName))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// FileUnstar takes the star of the user off the file {id}, if it has one
+func FileUnstar(db db_access.DbAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileUnstar"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		if err := db.UnstarFile(auth.UserId(r.Context()), file.GeneratedName); err != nil {
+			log.Error("Could not unstar file", slogext.Error(err), slog.String("generated-name", file.GeneratedName))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// FileStarred lists the files the user starred, the latest starred first
+func FileStarred(db db_access.StarRepo, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileStarred"
+		log := slogext.LogWithOp(op, r.Context())
+
+		files, err := db.GetStarredFiles(auth.UserId(r.Context()))
+		if err != nil {
+			log.Error("Could not get starred files from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp, ok := fileListResponse(w, log, c, files)
+		if !ok {
+			return
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -1,21 +1,24 @@
 package api
 
 import (
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
 	dbaccess "cloud-storage/db_access"
 	"cloud-storage/encryption"
+	"cloud-storage/policy"
+	"cloud-storage/storage"
 	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"mime"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-
-	"github.com/google/uuid"
+	"time"
 )
 
 func isMultipartForm(r *http.Request) (bool, string) {
@@ -30,11 +33,65 @@ func isMultipartForm(r *http.Request) (bool, string) {
 
 type UploadConfig struct {
 	MaxUploadSize int64
-	StorageDir    string
+	// MultipartOverhead is how many bytes of a multipart upload beyond MaxUploadSize
+	// may go to boundaries, headers and the file-size field
+	MultipartOverhead int64
+	// OptionalFileSize lets multipart uploads with a Content-Length leave out file-size. Space and quota
+	// are then checked against the Content-Length, which the file can't be larger than, and the file
+	// is cut off at MaxUploadSize as it is read. Chunked uploads still have to send file-size.
+	OptionalFileSize bool
+	StorageDir        string
+	Space             storage.Space
+	Durability        storage.Durability
+	// TimeOrderedIds generates UUIDv7 names for new files instead of UUIDv4
+	TimeOrderedIds bool
+	// Layout names the blobs of uploads; the ids of the files are generated either way
+	Layout         storage.Layout
+	Quota          Quota
+	DuplicateNames DuplicateNames
+	// Blobs holds the files removed when DuplicateNames overwrites them
+	Blobs *blobstore.Store
+	// Policies override MaxUploadSize and the quota limit per user when set
+	Policies *policy.Evaluator
+	// Convergent, unless nil, stores uploads of the same content once, see UploadService.saveConvergent
+	Convergent encryption.ConvergentCrypter
+	// what is left of the policies of the user after forUser
+	allowedTypes []string
+	retention    time.Duration
+}
+
+// multipartLimit is the most a multipart upload body may take
+func (cfg UploadConfig) multipartLimit() int64 {
+	if cfg.MultipartOverhead > math.MaxInt64-cfg.MaxUploadSize {
+		return math.MaxInt64
+	}
+	return cfg.MaxUploadSize + cfg.MultipartOverhead
 }
 
-func readNextPart(w http.ResponseWriter, mpReader *multipart.Reader, log *slog.Logger) *multipart.Part {
+// known parts of an upload form; any other part is skipped, as browsers tend to send a few more fields
+const (
+	fileSizePart = "file-size"
+	// relative-path puts the file into folders, see validateRelativePath
+	relativePathPart = "relative-path"
+	filePart         = "file"
+)
+
+// uploadForm is what readUploadForm read of a file of a form
+type uploadForm struct {
+	size         int64
+	relativePath string
+	part         *multipart.Part
+	// empty is set when the form ended before any part of a file
+	empty bool
+}
+
+// readNextPart returns nil at the end of the form; on failure it writes the error and returns false
+func readNextPart(w http.ResponseWriter, mpReader *multipart.Reader, log *slog.Logger) (*multipart.Part, bool) {
 	part, err := mpReader.NextPart()
+	// the form only ends properly with a bare EOF; a wrapped one means the body was cut short
+	if err == io.EOF {
+		return nil, true
+	}
 
 	mbe := &http.MaxBytesError{}
 	if errors.As(err, &mbe) {
@@ -44,17 +101,17 @@ func readNextPart(w http.ResponseWriter, mpReader *multipart.Reader, log *slog.L
 		if err := writeError(w, TooBigContentSize, errorMsg, http.StatusRequestEntityTooLarge); err != nil {
 			log.Error("Could not write response", slogext.Error(err))
 		}
-		return nil
+		return nil, false
 	}
 
-	if errors.Is(err, io.EOF) {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 		errorMsg := "Unexpected end of a multipart form"
 		log.Error(errorMsg)
 
 		if err := writeError(w, UnexpectedEOF, errorMsg, http.StatusUnprocessableEntity); err != nil {
 			log.Error("Could not write response", slogext.Error(err))
 		}
-		return nil
+		return nil, false
 	}
 
 	if err != nil {
@@ -64,207 +121,212 @@ func readNextPart(w http.ResponseWriter, mpReader *multipart.Reader, log *slog.L
 		if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusUnprocessableEntity); err != nil {
 			log.Error("Could not write response", slogext.Error(err))
 		}
-		return nil
+		return nil, false
 	}
 
-	return part
+	return part, true
 }
 
-func FileUpload(db dbaccess.DbAccess, cfg UploadConfig, c encryption.Crypter) http.HandlerFunc {
-	maxUploadSize := cfg.MaxUploadSize
-	storageDir := cfg.StorageDir
-
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "api.FileUpload"
-		log := slogext.LogWithOp(op, r.Context())
+// readUploadForm reads the parts of the form up to the file part, which is returned unread along with
+// the announced size and relative path; both have to come before the file. If sizeOptional, a file
+// without file-size has a size of -1. Invalid and missing parts go to v; it only returns false once
+// it wrote an error about the form itself.
+func readUploadForm(
+	w http.ResponseWriter,
+	mpReader *multipart.Reader,
+	log *slog.Logger,
+	v *validate.Validator,
+	maxUploadSize int64,
+	sizeOptional bool,
+) (uploadForm, bool) {
+	form := uploadForm{empty: true}
+	sized := false
+	for {
+		part, ok := readNextPart(w, mpReader, log)
+		if !ok {
+			return uploadForm{}, false
+		}
 
-		if ok, mediaType := isMultipartForm(r); !ok {
-			errMsg := fmt.Sprintf("Unsupported media type: %s", mediaType)
-			log.Error(errMsg)
+		if part == nil || part.FormName() == filePart {
+			v.Check(sized || sizeOptional, "file_size", validate.Missing, "file-size is not provided")
+			if part == nil {
+				v.Fail("file", validate.Missing, "file is not provided")
+			} else {
+				form.empty = false
+				v.Check(part.FileName() != "", "file", validate.Malformed, "file is not a file part")
+			}
 
-			if err := writeError(w, InvalidContentFormat, errMsg, http.StatusUnsupportedMediaType); err != nil {
-				log.Error("Could not write response", slogext.Error(err))
+			if !sized {
+				form.size = -1
 			}
-			return
+			form.part = part
+			return form, true
 		}
 
-		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
-		mpReader, err := r.MultipartReader()
-		if err != nil {
-			errorMsg := "Invalid multipart form"
-			log.Error(errorMsg, slogext.Error(err))
-
-			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusUnprocessableEntity); err != nil {
-				log.Error("Could not write response", slogext.Error(err))
+		if part.FormName() == relativePathPart {
+			form.empty = false
+			// one byte past the limit is enough to tell it's too long
+			value, err := io.ReadAll(io.LimitReader(part, maxPathLen+1))
+			if err != nil {
+				v.Fail("relative_path", validate.Malformed, "Invalid relative-path")
+				continue
 			}
-			return
+			form.relativePath = string(value)
+			continue
 		}
 
-		// read fileSize
-		part := readNextPart(w, mpReader, log)
-		if part == nil {
-			return
+		if part.FormName() != fileSizePart {
+			log.Debug("Skipping unknown form part", slog.String("name", part.FormName()))
+			continue
 		}
+		form.empty = false
 
-		var fileSize int64
+		value := make([]byte, 8)
 
-		if part.FormName() == "file-size" {
-			value := make([]byte, 8)
+		// a part may hand its content out over several reads
+		sized = true
+		if _, err := io.ReadFull(part, value); err != nil {
+			v.Fail("file_size", validate.Malformed, "Invalid file-size")
+			continue
+		}
 
-			n, err := part.Read(value)
-			if errors.Is(err, io.EOF) && n > 0 {
-				// do nothing
-			} else if err != nil {
-				log.Error("Could not read file-size", slogext.Error(err))
+		form.size = int64(binary.LittleEndian.Uint64(value))
+		log.Debug("Read file-size", slog.Int64("value", form.size))
+		v.Check(form.size > 0 && form.size <= maxUploadSize, "file_size", validate.OutOfRange, "file-size is not in valid range")
+	}
+}
 
-				if err := writeError(w, InvalidContentFormat, "Invalid file-size", http.StatusUnprocessableEntity); err != nil {
-					log.Error("Could not write response", slogext.Error(err))
-				}
-				return
-			}
+// FileUpload stores the file of a multipart form; a relative-path part before it, like
+// photos/2024/beach.jpg, puts it into those folders, which are made as needed, under the last part as its name
+func FileUpload(db dbaccess.DbAccess, uploadConfig UploadConfig, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileUpload"
+		log := slogext.LogWithOp(op, r.Context())
 
-			fileSize = int64(binary.LittleEndian.Uint64(value))
-			log.Debug("Read file-size", slog.Int64("value", fileSize))
+		var v validate.Validator
+		expiresAt := validateUploadExpiry(&v, r)
+		metadata := validateMetadataHeader(&v, r)
 
-			if fileSize > maxUploadSize || fileSize <= 0 {
-				errorMsg := "file-size is not in valid range"
-				log.Error(errorMsg, slog.Int64("file-size", fileSize), slog.Int64("max-upload-size", maxUploadSize))
+		cfg, ok := uploadConfig.forUser(w, r, log)
+		if !ok {
+			return
+		}
+		maxUploadSize := cfg.MaxUploadSize
+		expiresAt = cfg.applyRetention(expiresAt)
 
-				if err := writeParamError(w, ParameterOutOfRange, "file_size", errorMsg, http.StatusUnprocessableEntity); err != nil {
-					log.Error("Could not write response", slogext.Error(err))
-				}
-				return
-			}
-		} else {
-			errorMsg := "file-size is not provided"
-			log.Error(errorMsg)
+		if ok, mediaType := isMultipartForm(r); !ok {
+			errMsg := fmt.Sprintf("Unsupported media type: %s", mediaType)
+			log.Error(errMsg)
 
-			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusUnprocessableEntity); err != nil {
+			if err := writeError(w, InvalidContentFormat, errMsg, http.StatusUnsupportedMediaType); err != nil {
 				log.Error("Could not write response", slogext.Error(err))
 			}
 			return
 		}
 
-		// read an actual file after reading fileSize
-		part = readNextPart(w, mpReader, log)
-		if part == nil {
-			return
-		}
-
-		//TODO: check if file name is too long cause we dont want that to cause problems
-		filename := part.FileName()
-		if filename == "" {
-			errorMsg := "Expected file but found different form part"
-			log.Error(errorMsg)
+		// a body that says it won't fit is turned down before any of it is read
+		bodyLimit := cfg.multipartLimit()
+		if r.ContentLength > bodyLimit {
+			errorMsg := "Content-Length exceeds max upload size"
+			log.Error(errorMsg, slog.Int64("content-length", r.ContentLength), slog.Int64("limit", bodyLimit))
 
-			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusUnprocessableEntity); err != nil {
+			if err := writeError(w, TooBigContentSize, errorMsg, http.StatusRequestEntityTooLarge); err != nil {
 				log.Error("Could not write response", slogext.Error(err))
 			}
 			return
 		}
 
-		encFileName, err := c.EncryptFileName(filename)
+		r.Body = http.MaxBytesReader(w, r.Body, bodyLimit)
+		mpReader, err := r.MultipartReader()
 		if err != nil {
-			log.Error("Could not encrypt file name", slogext.Error(err))
+			errorMsg := "Invalid multipart form"
+			log.Error(errorMsg, slogext.Error(err))
 
-			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusUnprocessableEntity); err != nil {
 				log.Error("Could not write response", slogext.Error(err))
 			}
 			return
 		}
 
-		// this loop regenerates uuid in case of duplicate
-		var strId string
-		for {
-			id := uuid.New()
-			strId = id.String()
-			if strId == "" {
-				panic("Invalid uuid generated")
-			}
-
-			err = db.AddFile(strId, encFileName)
-			if err != nil {
-				var uce dbaccess.UniqueConstraintError
-				if errors.As(err, &uce) && uce.Column == "generatedName" {
-					continue
-				} else {
-					log.Error("Could not save file info to a db", slogext.Error(err))
-
-					if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
-						log.Error("Could not write response", slogext.Error(err))
-					}
-					return
-				}
+		form, ok := readUploadForm(w, mpReader, log, &v, maxUploadSize, cfg.OptionalFileSize && r.ContentLength > 0)
+		if !ok {
+			return
+		}
+		fileName, folders := "", []string(nil)
+		if form.part != nil {
+			fileName = form.part.FileName()
+			if form.relativePath != "" {
+				folders, fileName = validateRelativePath(&v, "relative_path", form.relativePath)
 			}
+			validateFileName(&v, "file", fileName)
+		}
+		if !requireValid(w, log, &v) {
+			return
+		}
 
-			path := filepath.Join(storageDir, strId)
-			err = func() error {
-				path, err = filepath.Abs(path)
-				if err != nil {
-					return err
-				}
-
-				file, err := os.Create(path)
-				if err != nil {
-					return err
-				}
-				defer file.Close()
-
-				lr := newLimitedReader(part, fileSize)
-				err = c.EncryptAndCopy(file, lr)
-				if err != nil {
-					return err
-				}
-
-				return nil
-			}()
+		folderId, err := newFolderMaker(db, c, auth.UserId(r.Context())).make(log, folders)
+		if err != nil {
+			log.Error("Could not make folders", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
 
-			if err != nil {
-				log.Error("Could not save file to disk", slogext.Error(err))
-				var tbfe tooBigFileError
-				if errors.As(err, &tbfe) {
-					if err := writeError(w, TooBigContentSize, tbfe.Error(), http.StatusRequestEntityTooLarge); err != nil {
-						log.Error("Could not write response", slogext.Error(err))
-					}
-				} else {
-					if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
-						log.Error("Could not write response", slogext.Error(err))
-					}
-				}
-
-				err := db.RemoveFile(strId)
-				if err != nil {
-					log.Error(
-						"Could not remove incomplete file info from db",
-						slogext.Error(err),
-						slog.String("generated-name", strId),
-					)
-				}
-
-				err = os.Remove(path)
-				if err != nil {
-					log.Error(
-						"Could not remove incomplete file from disk",
-						slogext.Error(err),
-						slog.String("generated-name", strId),
-					)
-				}
-
-				return
-			}
+		// without file-size, the body is the most the file can take
+		meta := UploadMeta{
+			OwnerId:     auth.UserId(r.Context()),
+			FileName:    fileName,
+			ContentType: form.part.Header.Get("Content-Type"),
+			Size:        form.size,
+			MaxSize:     min(r.ContentLength, maxUploadSize),
+			ExpiresAt:   expiresAt,
+			IfMatch:     parseIfMatch(r),
+			LockToken:   lockToken(r),
+			Metadata:    metadata,
+			FolderId:    folderId,
+		}
 
-			// we're done saving file
-			break
+		result, err := NewUploadService(db, cfg, c).Upload(r.Context(), meta, form.part)
+		if err != nil {
+			writeUploadError(w, log, err)
+			return
 		}
 
 		resp := UploadResponse{
-			Id:       strId,
-			FileName: filename,
+			Id:        result.Id,
+			FileName:  result.FileName,
+			ExpiresAt: unixOrZero(expiresAt),
+			Warnings:  result.Warnings,
+		}
+		if form.relativePath != "" {
+			resp.FilePath = filePath(folders, result.FileName)
 		}
+		w.Header().Set("ETag", fileETag(result.Id))
+		addStorageWarnings(w, resp.Warnings)
 		writeResponse(w, resp, http.StatusCreated)
 	}
 }
 
+// requireFileNameLen writes an error response and returns false if name is longer than encrypted names may hold
+func requireFileNameLen(w http.ResponseWriter, log *slog.Logger, param string, name string) bool {
+	var v validate.Validator
+	validateFileName(&v, param, name)
+	return requireValid(w, log, &v)
+}
+
+func validateFileName(v *validate.Validator, param string, name string) {
+	v.MaxLen(param, name, maxFileNameLen)
+}
+
+// requireSpace writes an error response and returns false if size bytes would not fit into the storage
+func requireSpace(w http.ResponseWriter, log *slog.Logger, space storage.Space, size int64) bool {
+	if err := checkSpace(space, size); err != nil {
+		writeUploadError(w, log, err)
+		return false
+	}
+
+	return true
+}
+
 type limitedReader struct {
 	reader  io.Reader
 	remaing int64
@@ -279,7 +341,13 @@ func newLimitedReader(reader io.Reader, limit int64) *limitedReader {
 
 func (lr *limitedReader) Read(p []byte) (n int, err error) {
 	if lr.remaing <= 0 {
-		return 0, tooBigFileError{}
+		// the content may end right at the limit without having said so yet
+		var probe [1]byte
+		n, err := lr.reader.Read(probe[:])
+		if n > 0 {
+			return 0, tooBigFileError{}
+		}
+		return 0, err
 	}
 	if int64(len(p)) > lr.remaing {
 		p = p[0:lr.remaing]
@@ -289,8 +357,46 @@ func (lr *limitedReader) Read(p []byte) (n int, err error) {
 	return
 }
 
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
 type tooBigFileError struct{}
 
 func (tooBigFileError) Error() string {
 	return "File size exceeds user provided size"
 }
+
+type emptyFileError struct{}
+
+func (emptyFileError) Error() string {
+	return "file must not be empty"
+}
+
+type contentTooShortError struct{}
+
+func (contentTooShortError) Error() string {
+	return "Content ended before the announced size"
+}
+
+// exactReader fails with contentTooShortError if r ends before size bytes
+type exactReader struct {
+	reader  io.Reader
+	remaining int64
+}
+
+func (er *exactReader) Read(p []byte) (n int, err error) {
+	n, err = er.reader.Read(p)
+	er.remaining -= int64(n)
+	if er.remaining > 0 && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+		return n, contentTooShortError{}
+	}
+	return n, err
+}
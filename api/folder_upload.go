@@ -0,0 +1,137 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	dbaccess "cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// most files a single folder upload may carry
+const maxFolderUploadFiles = 1000
+
+type FolderUploadResponse struct {
+	Files []UploadResponse `json:"files,omitempty"`
+	ErrorHolder
+}
+
+// filePath is the absolute path of a file named name in the folders
+func filePath(folders []string, name string) string {
+	return "/" + strings.Join(append(folders[:len(folders):len(folders)], name), "/")
+}
+
+// FolderUpload stores every file of a multipart form, as browsers send a directory picked by the user:
+// each file part comes after its relative-path, and its file-size unless that is optional, see FileUpload.
+// The folders are made as needed. Each file is checked and stored on its own, so the files before
+// one that fails stay stored.
+func FolderUpload(db dbaccess.DbAccess, uploadConfig UploadConfig, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FolderUpload"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var v validate.Validator
+		expiresAt := validateUploadExpiry(&v, r)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		cfg, ok := uploadConfig.forUser(w, r, log)
+		if !ok {
+			return
+		}
+		maxUploadSize := cfg.MaxUploadSize
+		expiresAt = cfg.applyRetention(expiresAt)
+
+		if ok, mediaType := isMultipartForm(r); !ok {
+			errMsg := fmt.Sprintf("Unsupported media type: %s", mediaType)
+			log.Error(errMsg)
+			writeError(w, InvalidContentFormat, errMsg, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		// every file is held to the limits of single uploads as it is read, the body only to their sum
+		bodyLimit := cfg.multipartLimit()
+		if bodyLimit > math.MaxInt64/maxFolderUploadFiles {
+			bodyLimit = math.MaxInt64
+		} else {
+			bodyLimit *= maxFolderUploadFiles
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, bodyLimit)
+		mpReader, err := r.MultipartReader()
+		if err != nil {
+			errorMsg := "Invalid multipart form"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusUnprocessableEntity)
+			return
+		}
+
+		userId := auth.UserId(r.Context())
+		folders := newFolderMaker(db, c, userId)
+		service := NewUploadService(db, cfg, c)
+		var resp FolderUploadResponse
+		for {
+			var v validate.Validator
+			form, ok := readUploadForm(w, mpReader, log, &v, maxUploadSize, cfg.OptionalFileSize && r.ContentLength > 0)
+			if !ok {
+				return
+			}
+			if form.empty && len(resp.Files) > 0 {
+				break
+			}
+
+			var names []string
+			var fileName string
+			if v.Required("relative_path", form.relativePath) {
+				names, fileName = validateRelativePath(&v, "relative_path", form.relativePath)
+				validateFileName(&v, "relative_path", fileName)
+			}
+			v.Check(len(resp.Files) < maxFolderUploadFiles, "file", validate.OutOfRange, fmt.Sprintf("at most %d files may be uploaded at once", maxFolderUploadFiles))
+			if !requireValid(w, log, &v) {
+				return
+			}
+
+			folderId, err := folders.make(log, names)
+			if err != nil {
+				log.Error("Could not make folders", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+
+			meta := UploadMeta{
+				OwnerId:     userId,
+				FileName:    fileName,
+				ContentType: form.part.Header.Get("Content-Type"),
+				Size:        form.size,
+				MaxSize:     min(r.ContentLength, maxUploadSize),
+				ExpiresAt:   expiresAt,
+				FolderId:    folderId,
+			}
+			result, err := service.Upload(r.Context(), meta, form.part)
+			if err != nil {
+				log.Error("Folder upload stopped", slog.Int("stored-files", len(resp.Files)))
+				writeUploadError(w, log, err)
+				return
+			}
+
+			resp.Files = append(resp.Files, UploadResponse{
+				Id:        result.Id,
+				FileName:  result.FileName,
+				FilePath:  filePath(names, result.FileName),
+				ExpiresAt: unixOrZero(expiresAt),
+				Warnings:  result.Warnings,
+			})
+		}
+
+		log.Info("Uploaded folder", slog.Int("files", len(resp.Files)))
+		addStorageWarnings(w, resp.Files[len(resp.Files)-1].Warnings)
+		if err := writeResponse(w, resp, http.StatusCreated); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
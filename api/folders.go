@@ -0,0 +1,177 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// deepest a relative path may nest its file
+const maxFolderDepth = 64
+
+type FolderInfo struct {
+	Id int64 `json:"id"`
+	// ParentId is 0 for folders in the root
+	ParentId int64  `json:"parent_id"`
+	Name     string `json:"name"`
+}
+
+type FolderListResponse struct {
+	Folders []FolderInfo `json:"folders"`
+	ErrorHolder
+}
+
+// validateRelativePath checks a path like the webkitRelativePath of browsers, photos/2024/beach.jpg,
+// and splits it into its folders and the name of the file
+func validateRelativePath(v *validate.Validator, param string, relativePath string) ([]string, string) {
+	if !v.MaxLen(param, relativePath, maxPathLen) {
+		return nil, ""
+	}
+
+	segments := strings.Split(relativePath, "/")
+	if !v.Check(len(segments) <= maxFolderDepth+1, param, validate.OutOfRange, fmt.Sprintf("%s must nest at most %d folders", param, maxFolderDepth)) {
+		return nil, ""
+	}
+	for _, segment := range segments {
+		ok := segment != "" && segment != "." && segment != ".." && !strings.ContainsRune(segment, 0) && len(segment) <= maxFileNameLen
+		if !v.Check(ok, param, validate.Malformed, param+" must be relative, without empty, . or .. parts, and at most 255 bytes per part") {
+			return nil, ""
+		}
+	}
+
+	return segments[:len(segments)-1], segments[len(segments)-1]
+}
+
+// folderNamed returns the folder of the user in the parent named exactly name, if there is one;
+// see filesNamed for why names are compared after the index
+func folderNamed(db db_access.FolderRepo, c encryption.Crypter, userId int64, parentId int64, name string) (db_access.Folder, bool, error) {
+	const op = "api.folderNamed"
+
+	index, err := c.FileNameIndex(name)
+	if err != nil {
+		return db_access.Folder{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	folders, err := db.GetFoldersByNameIndex(userId, parentId, index)
+	if err != nil {
+		return db_access.Folder{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, folder := range folders {
+		folderName, err := c.DecryptFileName(folder.Name)
+		if err != nil {
+			return db_access.Folder{}, false, fmt.Errorf("%s: %d: %w", op, folder.Id, err)
+		}
+		if folderName == name {
+			return folder, true, nil
+		}
+	}
+
+	return db_access.Folder{}, false, nil
+}
+
+// findFolder returns the id of the folder of the user at the path of folder names, or false if
+// there is none; the root is 0
+func findFolder(db db_access.FolderRepo, c encryption.Crypter, userId int64, names []string) (int64, bool, error) {
+	var id int64
+	for _, name := range names {
+		folder, ok, err := folderNamed(db, c, userId, id, name)
+		if err != nil || !ok {
+			return 0, false, err
+		}
+		id = folder.Id
+	}
+
+	return id, true, nil
+}
+
+// folderMaker makes the folders of the uploads of a request, remembering the ones it went through
+type folderMaker struct {
+	db     db_access.FolderRepo
+	c      encryption.Crypter
+	userId int64
+	// ids of the folders by their path
+	known map[string]int64
+}
+
+func newFolderMaker(db db_access.FolderRepo, c encryption.Crypter, userId int64) *folderMaker {
+	return &folderMaker{db: db, c: c, userId: userId, known: make(map[string]int64)}
+}
+
+// make returns the id of the folder at the path of folder names, adding the ones missing
+func (m *folderMaker) make(log *slog.Logger, names []string) (int64, error) {
+	const op = "api.folderMaker.make"
+
+	var id int64
+	for i, name := range names {
+		key := strings.Join(names[:i+1], "/")
+		if known, ok := m.known[key]; ok {
+			id = known
+			continue
+		}
+
+		folder, ok, err := folderNamed(m.db, m.c, m.userId, id, name)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !ok {
+			folder = db_access.Folder{OwnerId: m.userId, ParentId: id}
+			if folder.Name, err = m.c.EncryptFileName(name); err != nil {
+				return 0, fmt.Errorf("%s: encrypt folder name: %w", op, err)
+			}
+			if folder.NameIndex, err = m.c.FileNameIndex(name); err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+			if err := m.db.AddFolder(&folder); err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+			log.Info("Added folder", slog.Int64("folder-id", folder.Id), slog.Int64("parent-id", id))
+		}
+
+		id = folder.Id
+		m.known[key] = id
+	}
+
+	return id, nil
+}
+
+// Folders lists the folders of the user; files tell theirs by folder_id
+func Folders(db db_access.FolderRepo, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.Folders"
+		log := slogext.LogWithOp(op, r.Context())
+
+		folders, err := db.GetFolders(auth.UserId(r.Context()))
+		if err != nil {
+			log.Error("Could not get folders from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		ciphertexts := make([]string, 0, len(folders))
+		for _, folder := range folders {
+			ciphertexts = append(ciphertexts, folder.Name)
+		}
+		names, err := c.DecryptFileNames(ciphertexts)
+		if err != nil {
+			log.Error("Could not decrypt folder names", slogext.Error(err), slog.Int("folders", len(folders)))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := FolderListResponse{Folders: make([]FolderInfo, 0, len(folders))}
+		for i, folder := range folders {
+			resp.Folders = append(resp.Folders, FolderInfo{Id: folder.Id, ParentId: folder.ParentId, Name: names[i]})
+		}
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	slogext "cloud-storage/utils/slogExt"
+	"net/http"
+)
+
+type ReadinessCheck struct {
+	Name  string
+	Check func() error
+}
+
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// Ready reports 503 until every check passes, so load balancers stop routing to the instance
+func Ready(checks ...ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.Ready"
+		log := slogext.LogWithOp(op, r.Context())
+
+		resp := ReadinessResponse{Status: "ready"}
+		for _, check := range checks {
+			if err := check.Check(); err != nil {
+				if resp.Failed == nil {
+					resp.Failed = make(map[string]string)
+				}
+				resp.Failed[check.Name] = err.Error()
+			}
+		}
+
+		status := http.StatusOK
+		if resp.Failed != nil {
+			resp.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+
+		if err := writeResponse(w, resp, status); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/hls"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func hlsResponse(r *http.Request, status hls.Status) HLSResponse {
+	resp := HLSResponse{Status: string(status)}
+	if status == hls.StatusReady {
+		resp.PlaylistUrl = r.URL.Path + "/" + hls.PlaylistName
+	}
+	return resp
+}
+
+func HLSStart(db db_access.FileRepo, s *hls.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.HLSStart"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		status := s.Start(file)
+
+		code := http.StatusAccepted
+		if status == hls.StatusReady {
+			code = http.StatusOK
+		}
+
+		if err := writeResponse(w, hlsResponse(r, status), code); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func HLSStatus(db db_access.FileRepo, s *hls.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.HLSStatus"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		status := s.Status(file)
+		if status == hls.StatusNone {
+			errorMsg := "File was not transcoded"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		}
+
+		if err := writeResponse(w, hlsResponse(r, status), http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func HLSPlaylist(db db_access.FileRepo, s *hls.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.HLSPlaylist"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		if s.Status(file) != hls.StatusReady {
+			errorMsg := "Stream is not ready"
+			log.Error(errorMsg)
+			writeError(w, StreamNotReady, errorMsg, http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		err := s.WritePlaylist(w, file, "segments/")
+		if errors.Is(err, hls.ErrNotReady) {
+			// removed between the status check and opening the playlist
+			log.Error("Stream is not ready", slogext.Error(err))
+			writeError(w, StreamNotReady, "Stream is not ready", http.StatusConflict)
+		} else if err != nil {
+			log.Error("Could not write playlist", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+func HLSSegment(db db_access.FileRepo, s *hls.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.HLSSegment"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "video/mp2t")
+		err := s.WriteSegment(w, file, chi.URLParam(r, "segment"))
+		if errors.Is(err, hls.ErrUnknownSegment) {
+			errorMsg := "No such segment"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+		} else if err != nil {
+			log.Error("Could not write segment", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		}
+	}
+}
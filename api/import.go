@@ -0,0 +1,97 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/importer"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// credentials and urls of a source comfortably fit into this
+const maxImportRequestLen = 4096
+
+func ImportStart(imp *importer.Importer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ImportStart"
+		log := slogext.LogWithOp(op, r.Context())
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportRequestLen)
+
+		var spec importer.SourceSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		started, err := imp.Start(auth.UserId(r.Context()), spec)
+		var ise importer.InvalidSourceError
+		if errors.As(err, &ise) {
+			log.Error("Invalid import source", slogext.Error(err))
+			writeParamError(w, InvalidContentFormat, "source", ise.Reason, http.StatusUnprocessableEntity)
+			return
+		} else if errors.Is(err, importer.ErrQueueFull) {
+			log.Error("Import queue is full")
+			writeError(w, TooManyRequests, "Too many imports in progress; try again later", http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			log.Error("Could not start import", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Queued import", slog.String("import-id", started.Id), slog.String("source", started.Source))
+
+		if err := writeResponse(w, importResponse(started), http.StatusAccepted); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func ImportStatus(db db_access.ImportRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ImportStatus"
+		log := slogext.LogWithOp(op, r.Context())
+
+		imp, err := db.GetImport(chi.URLParam(r, "id"))
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) || (err == nil && imp.OwnerId != auth.UserId(r.Context())) {
+			errorMsg := "No import with provided id was found"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get import from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := importResponse(imp)
+		if imp.Error != "" {
+			addError(&resp.ErrorHolder, InternalApiError, imp.Error)
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func importResponse(imp db_access.Import) ImportResponse {
+	return ImportResponse{
+		Id:            imp.Id,
+		Source:        imp.Source,
+		Status:        string(imp.Status),
+		TotalFiles:    imp.TotalFiles,
+		ImportedFiles: imp.ImportedFiles,
+		FailedFiles:   imp.FailedFiles,
+		ImportedBytes: imp.ImportedBytes,
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	dbaccess "cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/importer"
+	"cloud-storage/utils/validate"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ImportStore stores the files of imports through UploadService, so that they go through
+// the same checks and policies as the uploads of their owners
+type ImportStore struct {
+	db  dbaccess.DbAccess
+	cfg UploadConfig
+	c   encryption.Crypter
+}
+
+func NewImportStore(db dbaccess.DbAccess, cfg UploadConfig, c encryption.Crypter) *ImportStore {
+	return &ImportStore{
+		db:  db,
+		cfg: cfg,
+		c:   c,
+	}
+}
+
+// Store turns the files an upload would be turned down for into importer.RejectedError
+func (s *ImportStore) Store(ctx context.Context, file importer.File, content io.Reader) (int64, error) {
+	const op = "api.ImportStore.Store"
+
+	cfg := s.cfg
+	if cfg.Policies != nil {
+		limits, err := cfg.Policies.For(file.OwnerId)
+		if err != nil {
+			return 0, fmt.Errorf("%s: get policies: %w", op, err)
+		}
+		cfg = cfg.withLimits(limits)
+	}
+
+	var v validate.Validator
+	validateFileName(&v, "file_name", file.Name)
+	if !v.Valid() {
+		return 0, fmt.Errorf("%s: %w", op, importer.RejectedError{Reason: v.Failures[0].Message})
+	}
+
+	maxSize := min(file.MaxSize, cfg.MaxUploadSize)
+	if file.Size > maxSize {
+		return 0, fmt.Errorf("%s: %w", op, importer.RejectedError{Reason: "File exceeds max upload size"})
+	}
+
+	result, err := NewUploadService(s.db, cfg, s.c).Upload(ctx, UploadMeta{
+		OwnerId:   file.OwnerId,
+		FileName:  file.Name,
+		Size:      file.Size,
+		MaxSize:   maxSize,
+		ExpiresAt: cfg.applyRetention(dbaccess.Time{}),
+	}, content)
+	var ue UploadError
+	if errors.As(err, &ue) {
+		return 0, fmt.Errorf("%s: %w", op, importer.RejectedError{Reason: ue.Description})
+	} else if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result.Size, nil
+}
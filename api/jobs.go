@@ -0,0 +1,63 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// JobStatus answers with the state of the job {id} of the user
+func JobStatus(db db_access.JobRepo) http.HandlerFunc {
+	return jobStatus("api.JobStatus", db, false)
+}
+
+// AdminJobStatus answers with the state of the job {id} of any user or of the server
+func AdminJobStatus(db db_access.JobRepo) http.HandlerFunc {
+	return jobStatus("api.AdminJobStatus", db, true)
+}
+
+func jobStatus(op string, db db_access.JobRepo, anyOwner bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := slogext.LogWithOp(op, r.Context())
+
+		job, err := db.GetJob(chi.URLParam(r, "id"))
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) || (err == nil && !anyOwner && job.OwnerId != auth.UserId(r.Context())) {
+			errorMsg := "No job with provided id was found"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get job from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := writeResponse(w, jobResponse(job), http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func jobResponse(job db_access.Job) JobResponse {
+	resp := JobResponse{
+		Id:        job.Id,
+		Type:      job.Type,
+		Status:    string(job.Status),
+		Progress:  JobProgress{Done: job.Done, Total: job.Total},
+		CreatedAt: unixOrZero(job.CreationTime),
+		UpdatedAt: unixOrZero(job.UpdateTime),
+	}
+	if job.Result != "" {
+		resp.Result = json.RawMessage(job.Result)
+	}
+	if job.Error != "" {
+		addError(&resp.ErrorHolder, InternalApiError, job.Error)
+	}
+	return resp
+}
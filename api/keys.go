@@ -0,0 +1,109 @@
+package api
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/jobs"
+	"cloud-storage/keys"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type KeyInfo struct {
+	Id        int64 `json:"id"`
+	CreatedAt int64 `json:"created_at"`
+	// the user whose contents the key encrypts, omitted for a shared key
+	OwnerId int64 `json:"owner_id,omitempty"`
+	// omitted for the key in use
+	RetiredAt  int64 `json:"retired_at,omitempty"`
+	References int   `json:"references"`
+	Prunable   bool  `json:"prunable"`
+	// version of the vault transit key the key is wrapped with
+	VaultKeyVersion int64 `json:"vault_key_version"`
+}
+
+type KeyVersionInfo struct {
+	VaultKeyVersion int64 `json:"vault_key_version"`
+	Names           int64 `json:"file_names"`
+}
+
+type KeysResponse struct {
+	Keys     []KeyInfo `json:"keys"`
+	Scanned  int       `json:"scanned_files"`
+	Prunable int       `json:"prunable"`
+	// file names wrapped with each version of the vault transit key
+	NameKeyVersions []KeyVersionInfo `json:"name_key_versions"`
+	ErrorHolder
+}
+
+// AdminKeys reports which data encryption keys are still in use; it has to be mounted behind auth.Admin.
+// Every blob is read to find out, so it takes a while on large storages.
+func AdminKeys(p *keys.Pruner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminKeys"
+		log := slogext.LogWithOp(op, r.Context())
+
+		report, err := p.Check(r.Context(), time.Now())
+		if err != nil {
+			log.Error("Could not check keys", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := KeysResponse{
+			Keys:            make([]KeyInfo, 0, len(report.Keys)),
+			Scanned:         report.Scanned,
+			Prunable:        len(report.Prunable()),
+			NameKeyVersions: make([]KeyVersionInfo, 0, len(report.NameKeyVersions)),
+		}
+		for _, key := range report.Keys {
+			resp.Keys = append(resp.Keys, KeyInfo{
+				Id:              int64(key.Id),
+				CreatedAt:       key.CreationTime.Unix(),
+				OwnerId:         key.OwnerId,
+				RetiredAt:       unixOrZero(db_access.Time(key.RetiredAt)),
+				References:      key.References,
+				Prunable:        key.Prunable,
+				VaultKeyVersion: key.KeyVersion,
+			})
+		}
+		for _, count := range report.NameKeyVersions {
+			resp.NameKeyVersions = append(resp.NameKeyVersions, KeyVersionInfo{
+				VaultKeyVersion: count.KeyVersion,
+				Names:           count.Count,
+			})
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AdminKeysRewrap starts a job that rewraps the keys and file names wrapped with older versions
+// of the vault transit key; it has to be mounted behind auth.Admin
+func AdminKeysRewrap(pool *jobs.Pool, rw *keys.Rewrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminKeysRewrap"
+		log := slogext.LogWithOp(op, r.Context())
+
+		job, err := pool.Submit(db_access.Job{Type: jobs.TypeRewrap}, rw.Run)
+		if errors.Is(err, jobs.ErrQueueFull) {
+			log.Error("Job queue is full")
+			writeError(w, TooManyRequests, "Too many jobs in progress; try again later", http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			log.Error("Could not start rewrap", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Queued rewrap", slog.String("job-id", job.Id))
+
+		if err := writeResponse(w, jobResponse(job), http.StatusAccepted); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"cloud-storage/maintenance"
+	slogext "cloud-storage/utils/slogExt"
+	"log/slog"
+	"net/http"
+)
+
+type MaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+type MaintenanceResponse struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+	// unix seconds, omitted while disabled
+	Since int64 `json:"since,omitempty"`
+	ErrorHolder
+}
+
+// RejectInMaintenance answers 503 while maintenance mode is on; it wraps the routes that write,
+// everything else keeps working
+func RejectInMaintenance(mode *maintenance.Mode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "api.RejectInMaintenance"
+
+			status := mode.Status()
+			if status.Enabled {
+				log := slogext.LogWithOp(op, r.Context())
+
+				errorMsg := "The service is read-only for maintenance; try again later"
+				if status.Reason != "" {
+					errorMsg += ": " + status.Reason
+				}
+				log.Info("Rejected write during maintenance")
+				if err := writeError(w, UnderMaintenance, errorMsg, http.StatusServiceUnavailable); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeMaintenance(w http.ResponseWriter, log *slog.Logger, status maintenance.Status) {
+	resp := MaintenanceResponse{
+		Enabled: status.Enabled,
+		Reason:  status.Reason,
+	}
+	if status.Enabled {
+		resp.Since = status.Since.Unix()
+	}
+
+	if err := writeResponse(w, resp, http.StatusOK); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+}
+
+// MaintenanceStatus has to be mounted behind auth.Admin
+func MaintenanceStatus(mode *maintenance.Mode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.MaintenanceStatus"
+		log := slogext.LogWithOp(op, r.Context())
+
+		writeMaintenance(w, log, mode.Status())
+	}
+}
+
+// MaintenanceSet turns maintenance mode on or off; it has to be mounted behind auth.Admin
+func MaintenanceSet(mode *maintenance.Mode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.MaintenanceSet"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req MaintenanceRequest
+		if !decodeFileOpRequest(w, r, log, &req) {
+			return
+		}
+
+		if req.Enabled {
+			mode.Enable(req.Reason)
+			log.Warn("Maintenance mode enabled", slog.String("reason", req.Reason))
+		} else {
+			mode.Disable()
+			log.Warn("Maintenance mode disabled")
+		}
+
+		writeMaintenance(w, log, mode.Status())
+	}
+}
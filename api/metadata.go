@@ -0,0 +1,261 @@
+package api
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// metadataHeader holds the metadata of an upload as a JSON object of strings, percent-encoded
+// if it is not plain ascii
+const metadataHeader = "X-File-Metadata"
+
+// metadataQueryPrefix starts the query parameters of listings that filter by metadata, e.g. meta.album=trip
+const metadataQueryPrefix = "meta."
+
+const (
+	maxMetadataPairs    = 32
+	maxMetadataKeyLen   = 64
+	maxMetadataValueLen = 256
+	maxMetadataLen      = 4096
+	// a patch may remove every pair while it sets as many new ones
+	maxMetadataRequestLen = 2 * maxMetadataLen
+)
+
+type MetadataRequest struct {
+	// Metadata sets the keys it has to their values; null removes a key
+	Metadata map[string]*string `json:"metadata"`
+}
+
+type MetadataResponse struct {
+	Metadata map[string]string `json:"metadata"`
+	ErrorHolder
+}
+
+// validateMetadata checks the pairs against the limits a file is held to
+func validateMetadata(v *validate.Validator, param string, metadata map[string]string) {
+	v.Check(len(metadata) <= maxMetadataPairs, param, validate.OutOfRange, fmt.Sprintf("%s must have at most %d keys", param, maxMetadataPairs))
+
+	size := 0
+	for key, value := range metadata {
+		size += len(key) + len(value)
+
+		if !v.Check(key != "" && len(key) <= maxMetadataKeyLen, param, validate.OutOfRange, fmt.Sprintf("%s keys must be from 1 to %d bytes long", param, maxMetadataKeyLen)) ||
+			!v.Check(len(value) <= maxMetadataValueLen, param, validate.OutOfRange, fmt.Sprintf("%s values must be at most %d bytes long", param, maxMetadataValueLen)) ||
+			!v.Check(utf8.ValidString(key) && utf8.ValidString(value), param, validate.Malformed, fmt.Sprintf("%s must be valid UTF-8", param)) {
+			return
+		}
+	}
+
+	v.Check(size <= maxMetadataLen, param, validate.OutOfRange, fmt.Sprintf("%s must take at most %d bytes", param, maxMetadataLen))
+}
+
+// validateMetadataHeader reads the optional metadata of an upload
+func validateMetadataHeader(v *validate.Validator, r *http.Request) map[string]string {
+	header := r.Header.Get(metadataHeader)
+	if header == "" {
+		return nil
+	}
+
+	var metadata map[string]string
+	decoded, err := url.PathUnescape(header)
+	if err == nil {
+		err = json.Unmarshal([]byte(decoded), &metadata)
+	}
+	if !v.Check(err == nil, metadataHeader, validate.Malformed, metadataHeader+" must hold a JSON object of strings") {
+		return nil
+	}
+
+	validateMetadata(v, metadataHeader, metadata)
+	return metadata
+}
+
+// metadataTerm is what a pair is found by; content terms never hold a NUL, so the two don't mix
+func metadataTerm(c encryption.Crypter, ownerId int64, key string, value string) (string, error) {
+	return c.ContentTermIndex(ownerId, "meta\x00"+key+"\x00"+value)
+}
+
+// sealMetadata encrypts the metadata of a file of the owner and hashes its pairs for filtering;
+// empty metadata gives an empty document
+func sealMetadata(c encryption.Crypter, ownerId int64, metadata map[string]string) (string, []string, error) {
+	if len(metadata) == 0 {
+		return "", nil, nil
+	}
+
+	doc, err := json.Marshal(metadata)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	data, err := encryptText(c, ownerId, string(doc))
+	if err != nil {
+		return "", nil, fmt.Errorf("encrypt metadata: %w", err)
+	}
+
+	terms := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		term, err := metadataTerm(c, ownerId, key, value)
+		if err != nil {
+			return "", nil, fmt.Errorf("hash metadata: %w", err)
+		}
+		terms = append(terms, term)
+	}
+
+	return data, terms, nil
+}
+
+func openMetadata(c encryption.Crypter, data string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if data == "" {
+		return metadata, nil
+	}
+
+	doc, err := decryptText(c, data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt metadata: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(doc), &metadata); err != nil {
+		return nil, fmt.Errorf("unmarshal metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// metadataFilter returns the terms of the meta.* query parameters of a listing, nil if it has none.
+// Every parameter has to match, so one given twice with different values matches nothing.
+func metadataFilter(c encryption.Crypter, userId int64, query url.Values) ([]string, error) {
+	var terms []string
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, metadataQueryPrefix)
+		if !ok {
+			continue
+		}
+
+		for _, value := range values {
+			term, err := metadataTerm(c, userId, key, value)
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, term)
+		}
+	}
+
+	return terms, nil
+}
+
+// FileMetadata answers with the metadata of the file {id}
+func FileMetadata(db db_access.DbAccess, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileMetadata"
+		log := slogext.LogWithOp(op, r.Context())
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		data, err := db.GetFileMetadata(file.GeneratedName)
+		if err != nil {
+			log.Error("Could not get file metadata from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		metadata, err := openMetadata(c, data)
+		if err != nil {
+			log.Error("Could not read file metadata", slogext.Error(err), slog.String("generated-name", file.GeneratedName))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := writeResponse(w, MetadataResponse{Metadata: metadata}, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// FileMetadataPatch merges the request into the metadata of the file {id} and answers with the result
+func FileMetadataPatch(db db_access.DbAccess, c encryption.Crypter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileMetadataPatch"
+		log := slogext.LogWithOp(op, r.Context())
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxMetadataRequestLen)
+
+		var req MetadataRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		var metadata map[string]string
+		var invalid validate.Validator
+		err := db.WithTx(r.Context(), func(repos db_access.DbAccess) error {
+			data, err := repos.GetFileMetadata(file.GeneratedName)
+			if err != nil {
+				return err
+			}
+
+			if metadata, err = openMetadata(c, data); err != nil {
+				return err
+			}
+
+			for key, value := range req.Metadata {
+				if value == nil {
+					delete(metadata, key)
+				} else {
+					metadata[key] = *value
+				}
+			}
+
+			var v validate.Validator
+			if validateMetadata(&v, "metadata", metadata); !v.Valid() {
+				invalid = v
+				return nil
+			}
+
+			data, terms, err := sealMetadata(c, file.OwnerId, metadata)
+			if err != nil {
+				return err
+			}
+			return repos.SetFileMetadata(file.GeneratedName, file.OwnerId, data, terms)
+		})
+
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "No file with provided id was found"
+			log.Error(errorMsg, slog.String("generated-name", file.GeneratedName))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not set file metadata", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if !requireValid(w, log, &invalid) {
+			return
+		}
+
+		log.Info("Set file metadata", slog.String("generated-name", file.GeneratedName))
+		if err := writeResponse(w, MetadataResponse{Metadata: metadata}, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/tiering"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type MigrationRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// unix seconds; files created before it are moved, 0 moves all of them
+	CreatedBefore int64 `json:"created_before"`
+}
+
+func MigrationStart(m *tiering.Migrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.MigrationStart"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req MigrationRequest
+		if !decodeFileOpRequest(w, r, log, &req) {
+			return
+		}
+
+		if req.CreatedBefore < 0 {
+			errorMsg := "created_before must be a unix timestamp"
+			log.Error(errorMsg, slog.Int64("created_before", req.CreatedBefore))
+			writeParamError(w, ParameterOutOfRange, "created_before", errorMsg, http.StatusUnprocessableEntity)
+			return
+		}
+
+		createdBefore := time.Now()
+		if req.CreatedBefore > 0 {
+			createdBefore = time.Unix(req.CreatedBefore, 0)
+		}
+
+		started, err := m.Start(req.From, req.To, createdBefore)
+		var ime tiering.InvalidMigrationError
+		if errors.As(err, &ime) {
+			log.Error("Invalid migration", slogext.Error(err))
+			writeError(w, InvalidContentFormat, ime.Reason, http.StatusUnprocessableEntity)
+			return
+		} else if errors.Is(err, tiering.ErrQueueFull) {
+			log.Error("Migration queue is full")
+			writeError(w, TooManyRequests, "Too many migrations in progress; try again later", http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			log.Error("Could not start migration", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Queued migration", slog.String("migration-id", started.Id), slog.String("from", started.FromBackend), slog.String("to", started.ToBackend))
+
+		if err := writeResponse(w, migrationResponse(started), http.StatusAccepted); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func MigrationStatus(db db_access.MigrationRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.MigrationStatus"
+		log := slogext.LogWithOp(op, r.Context())
+
+		migration, err := db.GetMigration(chi.URLParam(r, "id"))
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "No migration with provided id was found"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get migration from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := migrationResponse(migration)
+		if migration.Error != "" {
+			addError(&resp.ErrorHolder, InternalApiError, migration.Error)
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+func migrationResponse(m db_access.Migration) MigrationResponse {
+	return MigrationResponse{
+		Id:            m.Id,
+		From:          m.FromBackend,
+		To:            m.ToBackend,
+		CreatedBefore: unixOrZero(m.CreatedBefore),
+		Status:        string(m.Status),
+		TotalBlobs:    m.TotalBlobs,
+		MovedBlobs:    m.MovedBlobs,
+		FailedBlobs:   m.FailedBlobs,
+		MovedBytes:    m.MovedBytes,
+	}
+}
@@ -0,0 +1,285 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/ipfilter"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// most networks a single rule set may list
+const maxNetworks = 100
+
+type NetworkRulesRequest struct {
+	// networks in CIDR notation or single addresses; with allow empty any network not denied is allowed
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+type NetworkRulesInfo struct {
+	Scope   string   `json:"scope"`
+	Subject string   `json:"subject"`
+	Allow   []string `json:"allow"`
+	Deny    []string `json:"deny"`
+}
+
+type NetworkRulesListResponse struct {
+	Rules []NetworkRulesInfo `json:"rules"`
+	ErrorHolder
+}
+
+// denyNetwork records the denial in the audit log and answers 403; level names the rules that denied it
+func denyNetwork(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.AuditRepo, userId int64, level string) {
+	event := db_access.AuditEvent{
+		At:         db_access.Time(time.Now()),
+		UserId:     userId,
+		Method:     "DENY",
+		Path:       "network/" + level,
+		Status:     http.StatusForbidden,
+		RemoteAddr: r.RemoteAddr,
+		RequestId:  middleware.GetReqID(r.Context()),
+	}
+	if err := db.AddAuditEvent(&event); err != nil {
+		log.Error("Could not record audit event", slogext.Error(err))
+	}
+
+	errorMsg := "Requests from this network are not allowed"
+	log.Info(errorMsg, slog.String("remote-addr", r.RemoteAddr), slog.String("rules", level))
+	writeError(w, NetworkDenied, errorMsg, http.StatusForbidden)
+}
+
+// FilterNetwork answers 403 to clients the global rules deny; it goes before auth, so that they can't
+// even try to log in
+func FilterNetwork(db db_access.AuditRepo, rules ipfilter.Rules) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if rules.Empty() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "api.FilterNetwork"
+
+			if !rules.Permits(r.RemoteAddr) {
+				denyNetwork(w, r, slogext.LogWithOp(op, r.Context()), db, 0, "global")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// permitUserNetwork checks the client against the rules of the user and of the device,
+// answering 403 itself when they deny it
+func permitUserNetwork(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.DbAccess, userId int64, deviceId string) bool {
+	stored, err := db.GetRequestNetworkRules(userId, deviceId)
+	if err != nil {
+		log.Error("Could not get network rules from db", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return false
+	}
+
+	for _, s := range stored {
+		rules, err := ipfilter.FromDb(s)
+		if err != nil {
+			// rules are checked when they are set, so this is a broken row; failing closed keeps it safe
+			log.Error("Invalid network rules in db", slogext.Error(err), slog.String("scope", string(s.Scope)), slog.String("subject", s.Subject))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return false
+		}
+
+		if !rules.Permits(r.RemoteAddr) {
+			denyNetwork(w, r, log, db, userId, string(s.Scope))
+			return false
+		}
+	}
+	return true
+}
+
+// FilterUserNetwork answers 403 to clients the rules of the user, or of the device of the session, deny;
+// it has to run after auth.Auth
+func FilterUserNetwork(db db_access.DbAccess) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "api.FilterUserNetwork"
+			log := slogext.LogWithOp(op, r.Context())
+
+			if !permitUserNetwork(w, r, log, db, auth.UserId(r.Context()), auth.DeviceId(r.Context())) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LoginNetworkFilter turns down logins and refreshes from networks the rules of the user,
+// or of the refreshed device, deny; it is handed to auth.AuthData.FilterLogins
+type LoginNetworkFilter struct {
+	db db_access.DbAccess
+}
+
+func NewLoginNetworkFilter(db db_access.DbAccess) *LoginNetworkFilter {
+	return &LoginNetworkFilter{db: db}
+}
+
+func (f *LoginNetworkFilter) AllowLogin(w http.ResponseWriter, r *http.Request, userId int64, deviceId string) bool {
+	const op = "api.LoginNetworkFilter.AllowLogin"
+	return permitUserNetwork(w, r, slogext.LogWithOp(op, r.Context()), f.db, userId, deviceId)
+}
+
+// networkRulesTarget reads /{scope}/{subject} of the network rules routes, which have to exist
+func networkRulesTarget(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.DbAccess) (db_access.NetworkScope, string, bool) {
+	scope := db_access.NetworkScope(chi.URLParam(r, "scope"))
+	subject := chi.URLParam(r, "subject")
+
+	switch scope {
+	case db_access.NetworkUser:
+		userId, err := strconv.ParseInt(subject, 10, 64)
+		if err != nil {
+			errorMsg := "User rules are set by user id"
+			log.Error(errorMsg, slog.String("subject", subject))
+			writeParamError(w, InvalidContentFormat, "subject", errorMsg, http.StatusBadRequest)
+			return "", "", false
+		}
+
+		if !requireUser(w, log, db, userId) {
+			return "", "", false
+		}
+		return scope, subject, true
+	case db_access.NetworkDevice:
+		_, err := db.GetDevice(subject)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "Device not found"
+			log.Error(errorMsg, slog.String("device-id", subject))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return "", "", false
+		} else if err != nil {
+			log.Error("Could not get device from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return "", "", false
+		}
+		return scope, subject, true
+	}
+
+	errorMsg := "Network rules are set for user/{id} or device/{id}"
+	log.Error(errorMsg, slog.String("scope", string(scope)), slog.String("subject", subject))
+	writeParamError(w, ParameterOutOfRange, "scope", errorMsg, http.StatusUnprocessableEntity)
+	return "", "", false
+}
+
+// AdminNetworkRules lists the network rules of users and devices; it has to be mounted behind auth.Admin
+func AdminNetworkRules(db db_access.NetworkRuleRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminNetworkRules"
+		log := slogext.LogWithOp(op, r.Context())
+
+		stored, err := db.ListNetworkRules()
+		if err != nil {
+			log.Error("Could not get network rules from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := NetworkRulesListResponse{Rules: make([]NetworkRulesInfo, 0, len(stored))}
+		for _, s := range stored {
+			resp.Rules = append(resp.Rules, NetworkRulesInfo{
+				Scope:   string(s.Scope),
+				Subject: s.Subject,
+				Allow:   append([]string{}, s.Allow...),
+				Deny:    append([]string{}, s.Deny...),
+			})
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AdminNetworkRulesSet replaces the network rules of /{scope}/{subject}; it has to be mounted behind auth.Admin
+func AdminNetworkRulesSet(db db_access.DbAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminNetworkRulesSet"
+		log := slogext.LogWithOp(op, r.Context())
+
+		scope, subject, ok := networkRulesTarget(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		var req NetworkRulesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		if len(req.Allow)+len(req.Deny) > maxNetworks {
+			errorMsg := "allow and deny may list " + strconv.Itoa(maxNetworks) + " networks at most"
+			log.Error(errorMsg)
+			writeParamError(w, ParameterOutOfRange, "allow", errorMsg, http.StatusUnprocessableEntity)
+			return
+		}
+		rules, err := ipfilter.Parse(req.Allow, req.Deny)
+		if err != nil {
+			log.Error("Invalid network", slogext.Error(err))
+			writeParamError(w, InvalidContentFormat, "allow", err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		// stored the way they parse, so that the list shows what is checked
+		stored := db_access.NetworkRules{Scope: scope, Subject: subject}
+		for _, prefix := range rules.Allow {
+			stored.Allow = append(stored.Allow, prefix.String())
+		}
+		for _, prefix := range rules.Deny {
+			stored.Deny = append(stored.Deny, prefix.String())
+		}
+		if err := db.SetNetworkRules(stored); err != nil {
+			log.Error("Could not save network rules to db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Network rules set", slog.String("scope", string(scope)), slog.String("subject", subject))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminNetworkRulesDelete removes the network rules of /{scope}/{subject}; it has to be mounted behind auth.Admin
+func AdminNetworkRulesDelete(db db_access.NetworkRuleRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminNetworkRulesDelete"
+		log := slogext.LogWithOp(op, r.Context())
+
+		scope := db_access.NetworkScope(chi.URLParam(r, "scope"))
+		subject := chi.URLParam(r, "subject")
+
+		var nre db_access.NoRowsError
+		if err := db.DeleteNetworkRules(scope, subject); errors.As(err, &nre) {
+			errorMsg := "No network rules found"
+			log.Error(errorMsg, slog.String("scope", string(scope)), slog.String("subject", subject))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not delete network rules from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Network rules deleted", slog.String("scope", string(scope)), slog.String("subject", subject))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
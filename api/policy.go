@@ -0,0 +1,413 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	"cloud-storage/policy"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// uploadSizeLimitHeader asks for a bigger max upload size than usual for one upload,
+// which policies grant with max_file_size_override_bytes
+const uploadSizeLimitHeader = "X-Upload-Size-Limit"
+
+type PolicyRequest struct {
+	// fields left out are inherited from the wider scope
+	QuotaBytes       *int64   `json:"quota_bytes"`
+	MaxFileSizeBytes *int64   `json:"max_file_size_bytes"`
+	AllowedTypes     []string `json:"allowed_types"`
+	RetentionSeconds *int64   `json:"retention_seconds"`
+	// how far clients may raise max_file_size_bytes with the X-Upload-Size-Limit header
+	MaxFileSizeOverrideBytes *int64 `json:"max_file_size_override_bytes"`
+}
+
+type PolicyInfo struct {
+	Scope   string `json:"scope"`
+	Subject string `json:"subject,omitempty"`
+	PolicyRequest
+}
+
+type PolicyListResponse struct {
+	Policies []PolicyInfo `json:"policies"`
+	ErrorHolder
+}
+
+// LimitsResponse is what applies to a user once every policy is taken into account; zero means no limit
+type LimitsResponse struct {
+	QuotaBytes       int64    `json:"quota_bytes"`
+	MaxFileSizeBytes int64    `json:"max_file_size_bytes"`
+	AllowedTypes     []string `json:"allowed_types,omitempty"`
+	RetentionSeconds int64    `json:"retention_seconds"`
+	// 0 when the user may not raise max_file_size_bytes
+	MaxFileSizeOverrideBytes int64 `json:"max_file_size_override_bytes"`
+	ErrorHolder
+}
+
+type UserOrgRequest struct {
+	// empty to take the user out of their org
+	Org string `json:"org"`
+}
+
+// forUser narrows cfg down to the policies of the user making the request.
+// On failure it writes an error response.
+func (cfg UploadConfig) forUser(w http.ResponseWriter, r *http.Request, log *slog.Logger) (UploadConfig, bool) {
+	if cfg.Policies == nil {
+		return cfg.raiseUploadSize(w, r, log, 0)
+	}
+
+	limits, err := cfg.Policies.For(auth.UserId(r.Context()))
+	if err != nil {
+		log.Error("Could not get policies from db", slogext.Error(err))
+
+		if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return UploadConfig{}, false
+	}
+
+	return cfg.withLimits(limits).raiseUploadSize(w, r, log, limits.MaxFileSizeOverride)
+}
+
+// withLimits narrows cfg down to the policies of a user
+func (cfg UploadConfig) withLimits(limits policy.Limits) UploadConfig {
+	cfg.MaxUploadSize = limits.MaxFileSize
+	if cfg.MaxUploadSize <= 0 {
+		cfg.MaxUploadSize = math.MaxInt64
+	}
+	cfg.Quota.Limit = limits.Quota
+	cfg.allowedTypes = limits.AllowedTypes
+	cfg.retention = limits.Retention
+
+	return cfg
+}
+
+// raiseUploadSize lets the request take up to the size in its uploadSizeLimitHeader instead of MaxUploadSize,
+// as long as it is no more than override. On failure it writes an error response.
+func (cfg UploadConfig) raiseUploadSize(w http.ResponseWriter, r *http.Request, log *slog.Logger, override int64) (UploadConfig, bool) {
+	header := r.Header.Get(uploadSizeLimitHeader)
+	if header == "" {
+		return cfg, true
+	}
+
+	requested, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || requested <= 0 {
+		errorMsg := "Invalid upload size limit"
+		log.Error(errorMsg, slog.String("upload-size-limit", header))
+
+		if err := writeParamError(w, InvalidContentFormat, uploadSizeLimitHeader, errorMsg, http.StatusBadRequest); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return UploadConfig{}, false
+	}
+
+	if requested <= cfg.MaxUploadSize {
+		return cfg, true
+	}
+
+	if requested > override {
+		errorMsg := "Upload size limit is more than the policies allow"
+		log.Error(errorMsg, slog.Int64("upload-size-limit", requested), slog.Int64("max-override", override))
+
+		if err := writeParamError(w, ParameterOutOfRange, uploadSizeLimitHeader, errorMsg, http.StatusForbidden); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return UploadConfig{}, false
+	}
+
+	log.Info("Upload size limit raised", slog.Int64("upload-size-limit", requested), slog.Int64("max-upload-size", cfg.MaxUploadSize))
+	cfg.MaxUploadSize = requested
+	return cfg, true
+}
+
+// applyRetention brings expiresAt forward to the end of the retention period, files without expiry included
+func (cfg UploadConfig) applyRetention(expiresAt db_access.Time) db_access.Time {
+	if cfg.retention <= 0 {
+		return expiresAt
+	}
+
+	latest := time.Now().Add(cfg.retention)
+	if time.Time(expiresAt).IsZero() || time.Time(expiresAt).After(latest) {
+		return db_access.Time(latest)
+	}
+	return expiresAt
+}
+
+// checkType fails with an UploadError if the policies don't allow files of mediaType
+func (cfg UploadConfig) checkType(mediaType string) error {
+	if (policy.Limits{AllowedTypes: cfg.allowedTypes}).Allows(mediaType) {
+		return nil
+	}
+
+	return UploadError{
+		ApiError: ApiError{Code: FileTypeNotAllowed, Description: fmt.Sprintf("Files of type %s are not allowed", mediaType)},
+		Status:   http.StatusUnsupportedMediaType,
+	}
+}
+
+func newPolicyInfo(p db_access.Policy) PolicyInfo {
+	info := PolicyInfo{
+		Scope:   string(p.Scope),
+		Subject: p.Subject,
+		PolicyRequest: PolicyRequest{
+			QuotaBytes:               p.Quota,
+			MaxFileSizeBytes:         p.MaxFileSize,
+			AllowedTypes:             p.AllowedTypes,
+			MaxFileSizeOverrideBytes: p.MaxFileSizeOverride,
+		},
+	}
+	if p.Retention != nil {
+		seconds := int64(*p.Retention / time.Second)
+		info.RetentionSeconds = &seconds
+	}
+	return info
+}
+
+// policyTarget reads the scope and subject of the policy routes; users have to exist,
+// orgs exist as soon as a policy or a user names them
+func policyTarget(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.UserRepo) (db_access.PolicyScope, string, bool) {
+	scope := db_access.PolicyScope(chi.URLParam(r, "scope"))
+	subject := chi.URLParam(r, "subject")
+
+	switch {
+	case scope == db_access.GlobalScope && subject == "":
+		return scope, subject, true
+	case scope == db_access.OrgScope && subject != "":
+		return scope, subject, true
+	case scope == db_access.UserScope && subject != "":
+		userId, err := strconv.ParseInt(subject, 10, 64)
+		if err != nil {
+			errorMsg := "User policies are set by user id"
+			log.Error(errorMsg, slog.String("subject", subject))
+			writeParamError(w, InvalidContentFormat, "subject", errorMsg, http.StatusBadRequest)
+			return "", "", false
+		}
+
+		if !requireUser(w, log, db, userId) {
+			return "", "", false
+		}
+		return scope, subject, true
+	}
+
+	errorMsg := "Policies are set for global, org/{name} or user/{id}"
+	log.Error(errorMsg, slog.String("scope", string(scope)), slog.String("subject", subject))
+	writeParamError(w, InvalidContentFormat, "scope", errorMsg, http.StatusNotFound)
+	return "", "", false
+}
+
+// requireUser writes an error response and returns false if there is no user with the id
+func requireUser(w http.ResponseWriter, log *slog.Logger, db db_access.UserRepo, userId int64) bool {
+	var nre db_access.NoRowsError
+	if err := db.GetUser(&db_access.User{Id: userId}); errors.As(err, &nre) {
+		errorMsg := "User not found"
+		log.Error(errorMsg, slog.Int64("user-id", userId))
+		writeError(w, NotFound, errorMsg, http.StatusNotFound)
+		return false
+	} else if err != nil {
+		log.Error("Could not get user from db", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// AdminPolicies lists every policy, the widest scopes first; it has to be mounted behind auth.Admin
+func AdminPolicies(db db_access.PolicyRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminPolicies"
+		log := slogext.LogWithOp(op, r.Context())
+
+		policies, err := db.ListPolicies()
+		if err != nil {
+			log.Error("Could not get policies from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := PolicyListResponse{Policies: make([]PolicyInfo, 0, len(policies))}
+		for _, p := range policies {
+			resp.Policies = append(resp.Policies, newPolicyInfo(p))
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AdminPolicySet replaces the policy of /{scope} or /{scope}/{subject}; it has to be mounted behind auth.Admin
+func AdminPolicySet(db db_access.DbAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminPolicySet"
+		log := slogext.LogWithOp(op, r.Context())
+
+		scope, subject, ok := policyTarget(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		var req PolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		for param, value := range map[string]*int64{
+			"quota_bytes":                  req.QuotaBytes,
+			"max_file_size_bytes":          req.MaxFileSizeBytes,
+			"retention_seconds":            req.RetentionSeconds,
+			"max_file_size_override_bytes": req.MaxFileSizeOverrideBytes,
+		} {
+			if value != nil && (*value < 0 || param == "retention_seconds" && *value > math.MaxInt64/int64(time.Second)) {
+				errorMsg := param + " is not in valid range"
+				log.Error(errorMsg, slog.Int64(param, *value))
+				writeParamError(w, ParameterOutOfRange, param, errorMsg, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		for _, t := range req.AllowedTypes {
+			if err := policy.ValidType(t); err != nil {
+				log.Error("Invalid allowed type", slogext.Error(err))
+				writeParamError(w, InvalidContentFormat, "allowed_types", err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		p := db_access.Policy{
+			Scope:               scope,
+			Subject:             subject,
+			Quota:               req.QuotaBytes,
+			MaxFileSize:         req.MaxFileSizeBytes,
+			MaxFileSizeOverride: req.MaxFileSizeOverrideBytes,
+			AllowedTypes:        req.AllowedTypes,
+		}
+		if req.RetentionSeconds != nil {
+			retention := time.Duration(*req.RetentionSeconds) * time.Second
+			p.Retention = &retention
+		}
+
+		if err := db.SetPolicy(p); err != nil {
+			log.Error("Could not save policy to db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Policy set", slog.String("scope", string(scope)), slog.String("subject", subject))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminPolicyDelete removes a policy, so its scope inherits everything again; it has to be mounted behind auth.Admin
+func AdminPolicyDelete(db db_access.DbAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminPolicyDelete"
+		log := slogext.LogWithOp(op, r.Context())
+
+		scope, subject, ok := policyTarget(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		var nre db_access.NoRowsError
+		if err := db.DeletePolicy(scope, subject); errors.As(err, &nre) {
+			errorMsg := "Policy not found"
+			log.Error(errorMsg, slog.String("scope", string(scope)), slog.String("subject", subject))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not delete policy from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminUserLimits shows the limits that apply to the user {id}; it has to be mounted behind auth.Admin
+func AdminUserLimits(db db_access.UserRepo, policies *policy.Evaluator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminUserLimits"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			errorMsg := "Invalid user id"
+			log.Error(errorMsg, slogext.Error(err))
+			writeParamError(w, InvalidContentFormat, "id", errorMsg, http.StatusBadRequest)
+			return
+		}
+		if !requireUser(w, log, db, userId) {
+			return
+		}
+
+		limits, err := policies.For(userId)
+		if err != nil {
+			log.Error("Could not get policies from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := LimitsResponse{
+			QuotaBytes:               limits.Quota,
+			MaxFileSizeBytes:         limits.MaxFileSize,
+			AllowedTypes:             limits.AllowedTypes,
+			RetentionSeconds:         int64(limits.Retention / time.Second),
+			MaxFileSizeOverrideBytes: limits.MaxFileSizeOverride,
+		}
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AdminUserOrg moves the user {id} into an org, whose policy then applies to them;
+// it has to be mounted behind auth.Admin
+func AdminUserOrg(db db_access.UserRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminUserOrg"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			errorMsg := "Invalid user id"
+			log.Error(errorMsg, slogext.Error(err))
+			writeParamError(w, InvalidContentFormat, "id", errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		var req UserOrgRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		var nre db_access.NoRowsError
+		if err := db.SetUserOrg(userId, req.Org); errors.As(err, &nre) {
+			errorMsg := "User not found"
+			log.Error(errorMsg, slog.Int64("user-id", userId))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not save org to db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("User moved to org", slog.Int64("user-id", userId), slog.String("org", req.Org))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
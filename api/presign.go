@@ -0,0 +1,417 @@
+package api
+
+import (
+	"cloud-storage/access"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/filecache"
+	"cloud-storage/geoip"
+	"cloud-storage/ipfilter"
+	"cloud-storage/presign"
+	"cloud-storage/utils/realip"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// most countries a single link may be restricted to
+const maxLinkCountries = 50
+
+// bcrypt ignores what comes after
+const maxLinkPasswordLen = 72
+
+type PresignRequest struct {
+	// zero means the server default
+	TimeToLive int64 `json:"ttl_seconds"`
+	OneTime    bool  `json:"one_time"`
+	// Countries and Networks keep the link to clients of the listed ISO 3166 countries and networks,
+	// in CIDR notation or single addresses; empty ones don't restrict it
+	Countries []string `json:"countries"`
+	Networks  []string `json:"networks"`
+	// Password, if set, has to be sent as the password of basic auth to use the link
+	Password string `json:"password"`
+	// MaxDownloads disables the link after that many downloads; zero means no limit
+	MaxDownloads int64 `json:"max_downloads"`
+}
+
+type PresignResponse struct {
+	Url       string `json:"url,omitempty"`
+	LinkId    string `json:"link_id,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Protected bool   `json:"protected,omitempty"`
+	ErrorHolder
+}
+
+type ShareStatsResponse struct {
+	LinkId        string `json:"link_id,omitempty"`
+	FileId        string `json:"file_id,omitempty"`
+	ExpiresAt     int64  `json:"expires_at,omitempty"`
+	Downloads     int64  `json:"downloads"`
+	UniqueClients int    `json:"unique_clients"`
+	LastAccess    int64  `json:"last_access,omitempty"`
+	MaxDownloads  int64  `json:"max_downloads,omitempty"`
+	Protected     bool   `json:"protected,omitempty"`
+	Disabled      bool   `json:"disabled,omitempty"`
+	ErrorHolder
+}
+
+// restriction parses the restriction of req; countries need geo to be checked against
+func (req PresignRequest) restriction(w http.ResponseWriter, log *slog.Logger, geo geoip.Provider) (presign.Restriction, bool) {
+	var restrict presign.Restriction
+
+	if len(req.Countries) > maxLinkCountries {
+		log.Error("Too many countries", slog.Int("countries", len(req.Countries)))
+		writeParamError(w, ParameterOutOfRange, "countries", fmt.Sprintf("at most %d countries are allowed", maxLinkCountries), http.StatusUnprocessableEntity)
+		return restrict, false
+	}
+	if len(req.Countries) != 0 && geo == nil {
+		errorMsg := "Links can't be restricted by country without a GeoIP database"
+		log.Error(errorMsg)
+		writeParamError(w, ParameterOutOfRange, "countries", errorMsg, http.StatusUnprocessableEntity)
+		return restrict, false
+	}
+	for _, country := range req.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !geoip.ValidCountry(country) {
+			log.Error("Invalid country", slog.String("country", country))
+			writeParamError(w, ParameterOutOfRange, "countries", "countries must be ISO 3166 alpha-2 codes", http.StatusUnprocessableEntity)
+			return restrict, false
+		}
+		if !slices.Contains(restrict.Countries, country) {
+			restrict.Countries = append(restrict.Countries, country)
+		}
+	}
+
+	if len(req.Networks) > maxNetworks {
+		log.Error("Too many networks", slog.Int("networks", len(req.Networks)))
+		writeParamError(w, ParameterOutOfRange, "networks", fmt.Sprintf("at most %d networks are allowed", maxNetworks), http.StatusUnprocessableEntity)
+		return restrict, false
+	}
+	networks, err := realip.ParseProxies(req.Networks)
+	if err != nil {
+		log.Error("Invalid network", slogext.Error(err))
+		writeParamError(w, ParameterOutOfRange, "networks", err.Error(), http.StatusUnprocessableEntity)
+		return restrict, false
+	}
+	restrict.Networks = networks
+
+	return restrict, true
+}
+
+// FilePresign hands out a link to the raw content of a file that needs no Authorization header;
+// geo, which may be nil, tells the countries of clients for links restricted to some
+func FilePresign(db db_access.FileRepo, s *presign.Signer, geo geoip.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FilePresign"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req PresignRequest
+		if r.ContentLength != 0 && !decodeFileOpRequest(w, r, log, &req) {
+			return
+		}
+
+		restrict, ok := req.restriction(w, log, geo)
+		if !ok {
+			return
+		}
+		if len(req.Password) > maxLinkPasswordLen {
+			log.Error("Password too long", slog.Int("length", len(req.Password)))
+			writeParamError(w, ParameterOutOfRange, "password", fmt.Sprintf("must be at most %d bytes long", maxLinkPasswordLen), http.StatusUnprocessableEntity)
+			return
+		}
+		if req.MaxDownloads < 0 {
+			log.Error("Negative download limit", slog.Int64("max-downloads", req.MaxDownloads))
+			writeParamError(w, ParameterOutOfRange, "max_downloads", "must not be negative", http.StatusUnprocessableEntity)
+			return
+		}
+
+		file, ok := getOwnedFile(w, r, log, db)
+		if !ok {
+			return
+		}
+
+		link, err := s.Sign(file.GeneratedName, file.OwnerId, time.Duration(req.TimeToLive)*time.Second, req.OneTime, restrict)
+		var ttle presign.TimeToLiveError
+		if errors.As(err, &ttle) {
+			log.Error("Invalid time to live", slog.Int64("ttl-seconds", req.TimeToLive))
+			writeParamError(w, ParameterOutOfRange, "ttl_seconds", ttle.Error(), http.StatusUnprocessableEntity)
+			return
+		} else if err != nil {
+			log.Error("Could not sign link", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+		if req.Password != "" {
+			if err := s.Protect(link, req.Password); err != nil {
+				log.Error("Could not protect link", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		if req.MaxDownloads > 0 {
+			if err := s.LimitDownloads(link, req.MaxDownloads); err != nil {
+				log.Error("Could not limit downloads of link", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		resp := PresignResponse{
+			Url:       fmt.Sprintf("/api/presigned/files/%s?%s", file.GeneratedName, link.Query.Encode()),
+			LinkId:    link.Id,
+			ExpiresAt: link.ExpiresAt.Unix(),
+			Protected: req.Password != "",
+		}
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// denyLink records the denied use of a link in the audit log, under the owner of the link, and answers with status
+func denyLink(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	db db_access.AuditRepo,
+	grant presign.Grant,
+	status int,
+	code ApiErrorCode,
+	errorMsg string,
+) {
+	fileId := chi.URLParam(r, "id")
+	event := db_access.AuditEvent{
+		At:         db_access.Time(time.Now()),
+		UserId:     grant.OwnerId,
+		Method:     "DENY",
+		Path:       "presigned/" + fileId + "/" + grant.LinkId,
+		Status:     status,
+		RemoteAddr: r.RemoteAddr,
+		RequestId:  middleware.GetReqID(r.Context()),
+	}
+	if err := db.AddAuditEvent(&event); err != nil {
+		log.Error("Could not record audit event", slogext.Error(err))
+	}
+
+	log.Info(errorMsg,
+		slog.String("file-id", fileId),
+		slog.String("link-id", grant.LinkId),
+		slog.String("remote-addr", r.RemoteAddr),
+	)
+	writeError(w, code, errorMsg, status)
+}
+
+// clientHost returns RemoteAddr of r without its port
+func clientHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// clientCountry returns the country of the client of r, or "" if it can't be told
+func clientCountry(r *http.Request, log *slog.Logger, geo geoip.Provider) string {
+	if geo == nil {
+		return ""
+	}
+
+	addr, err := netip.ParseAddr(clientHost(r))
+	if err != nil {
+		return ""
+	}
+
+	country, err := geo.Country(addr)
+	if err != nil && !errors.Is(err, geoip.ErrUnknown) {
+		log.Error("Could not look up country", slogext.Error(err))
+	}
+	return country
+}
+
+type presignCtx string
+
+// linkUse holds the func that counts the download through the presigned link of the request
+const linkUse presignCtx = "presigned link use"
+
+// useLink counts the download through the presigned link of the request, if it came with one;
+// handlers behind PresignedAuth call it once the file is open, so that failed downloads don't use up the link
+func useLink(ctx context.Context) error {
+	use, ok := ctx.Value(linkUse).(func() error)
+	if !ok {
+		return nil
+	}
+	return use()
+}
+
+// PresignedAuth lets requests through that carry a valid presigned link for the {id} route param
+// and acts as the user who signed it. Restricted links are only let through from their countries,
+// as geo tells them, and networks; clients of unknown countries are denied. Protected links take
+// their password as the one of basic auth, with guessRate wrong guesses per second allowed on each
+// in bursts of guessBurst; a guessRate that is not positive disables the limit. The handler has to
+// call useLink before it serves the file.
+func PresignedAuth(
+	s *presign.Signer,
+	db db_access.AuditRepo,
+	geo geoip.Provider,
+	guessRate float64,
+	guessBurst int,
+) func(http.Handler) http.Handler {
+	var guesses *rateLimiter
+	if guessRate > 0 {
+		guesses = newRateLimiter(guessRate, guessBurst)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "api.PresignedAuth"
+			log := slogext.LogWithOp(op, r.Context())
+
+			query := r.URL.Query()
+			_, password, _ := r.BasicAuth()
+			linkId := query.Get("link")
+			// the token is taken before the password is compared, so concurrent guesses can't all get past
+			// the limit at once; only wrong passwords keep it
+			guessing := guesses != nil && password != "" && s.Protected(linkId)
+			if guessing {
+				if ok, wait := guesses.take(linkId, time.Now()); !ok {
+					log.Error("Too many wrong passwords", slog.String("link-id", linkId))
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+					writeError(w, TooManyRequests, "Too many wrong passwords; try again later", http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			grant, err := s.Verify(chi.URLParam(r, "id"), query, password)
+			var pe presign.PasswordError
+			if guessing && !errors.As(err, &pe) {
+				guesses.giveBack(linkId, time.Now())
+			}
+			if errors.As(err, &pe) {
+				// makes browsers ask for the password
+				w.Header().Set("WWW-Authenticate", `Basic realm="shared file", charset="UTF-8"`)
+				if pe.Missing {
+					errorMsg := "This link needs a password"
+					log.Info(errorMsg, slog.String("link-id", grant.LinkId))
+					writeError(w, PasswordRequired, errorMsg, http.StatusUnauthorized)
+					return
+				}
+
+				denyLink(w, r, log, db, grant, http.StatusUnauthorized, PasswordRequired, "Wrong password")
+				return
+			} else if err != nil {
+				errorMsg := "Invalid or expired link"
+				log.Error(errorMsg, slogext.Error(err))
+				writeError(w, InvalidLink, errorMsg, http.StatusForbidden)
+				return
+			}
+
+			networks := ipfilter.Rules{Allow: grant.Restriction.Networks}
+			if !networks.Permits(r.RemoteAddr) {
+				denyLink(w, r, log, db, grant, http.StatusForbidden, NetworkDenied, "This link can't be used from this network")
+				return
+			}
+			if len(grant.Restriction.Countries) != 0 {
+				country := clientCountry(r, log, geo)
+				if country == "" {
+					denyLink(w, r, log, db, grant, http.StatusForbidden, CountryDenied, "This link is restricted to some countries and the one of this client is unknown")
+					return
+				}
+				if !slices.Contains(grant.Restriction.Countries, country) {
+					denyLink(w, r, log, db, grant, http.StatusForbidden, CountryDenied, "This link can't be used from "+country)
+					return
+				}
+			}
+
+			// the download is only counted once the file is open, see useLink
+			client := clientHost(r)
+			ctx := context.WithValue(r.Context(), auth.AuthUserId, grant.OwnerId)
+			ctx = context.WithValue(ctx, linkUse, func() error { return s.Use(grant, client) })
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FileRaw serves the decrypted content of a file as the response body. Presigned links may be
+// shared widely, so files are looked up in files, which may be nil, before the db.
+func FileRaw(
+	db db_access.DbAccess,
+	c encryption.Crypter,
+	blobs *blobstore.Store,
+	files *filecache.Cache,
+	accesses *access.Recorder,
+	chunkSize int,
+) http.HandlerFunc {
+	ds := NewDownloadService(db, c, blobs).WithCache(files)
+	fs := newFileStreamer(chunkSize)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.FileRaw"
+		log := slogext.LogWithOp(op, r.Context())
+
+		d, err := ds.Open(r.Context(), auth.UserId(r.Context()), chi.URLParam(r, "id"))
+		if err != nil {
+			writeDownloadError(w, log, err)
+			return
+		}
+		defer d.Close()
+
+		if err := useLink(r.Context()); err != nil {
+			errorMsg := "Invalid or expired link"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidLink, errorMsg, http.StatusForbidden)
+			return
+		}
+
+		n := fs.streamRaw(w, log, d)
+		recordTraffic(db, log, auth.UserId(r.Context()), 0, n)
+		recordAccess(accesses, d.File.GeneratedName, n)
+	}
+}
+
+// ShareStats tells the owner of a presigned link, the {id} route param, how it was used
+func ShareStats(s *presign.Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ShareStats"
+		log := slogext.LogWithOp(op, r.Context())
+
+		linkId := chi.URLParam(r, "id")
+		stats, err := s.Stats(linkId, auth.UserId(r.Context()))
+		if err != nil {
+			errorMsg := "No link with provided id was found"
+			log.Error(errorMsg, slog.String("link-id", linkId))
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		}
+
+		resp := ShareStatsResponse{
+			LinkId:        linkId,
+			FileId:        stats.FileId,
+			ExpiresAt:     stats.ExpiresAt.Unix(),
+			Downloads:     stats.Downloads,
+			UniqueClients: stats.UniqueClients,
+			MaxDownloads:  stats.MaxDownloads,
+			Protected:     stats.Protected,
+			Disabled:      stats.Disabled(),
+		}
+		if !stats.LastAccess.IsZero() {
+			resp.LastAccess = stats.LastAccess.Unix()
+		}
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxConsentPurposeLen = 64
+	maxConsentVersionLen = 64
+)
+
+type ConsentRequest struct {
+	// Version of the document consented to, such as the date of the terms
+	Version string `json:"version"`
+}
+
+type ConsentInfo struct {
+	Purpose string `json:"purpose"`
+	// Version is empty for a withdrawal
+	Version string `json:"version,omitempty"`
+	At      int64  `json:"at"`
+}
+
+type ConsentsResponse struct {
+	// Consents holds every consent given and withdrawn, oldest first
+	Consents []ConsentInfo `json:"consents"`
+}
+
+type DeletionInfo struct {
+	UserId      int64 `json:"user_id"`
+	RequestedAt int64 `json:"requested_at"`
+	Deadline    int64 `json:"deadline"`
+	CompletedAt int64 `json:"completed_at,omitempty"`
+	// Overdue is set for pending requests past their deadline
+	Overdue bool `json:"overdue,omitempty"`
+}
+
+type DeletionRequestsResponse struct {
+	Requests []DeletionInfo `json:"requests"`
+}
+
+func deletionInfo(req db_access.DeletionRequest, now time.Time) DeletionInfo {
+	info := DeletionInfo{
+		UserId:      req.UserId,
+		RequestedAt: time.Time(req.RequestedAt).Unix(),
+		Deadline:    time.Time(req.Deadline).Unix(),
+	}
+	if completedAt := time.Time(req.CompletedAt); !completedAt.IsZero() {
+		info.CompletedAt = completedAt.Unix()
+	} else {
+		info.Overdue = now.After(time.Time(req.Deadline))
+	}
+	return info
+}
+
+// Consents lists the record of consents of the user
+func Consents(db db_access.PrivacyRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.Consents"
+		log := slogext.LogWithOp(op, r.Context())
+
+		consents, err := db.GetConsents(auth.UserId(r.Context()))
+		if err != nil {
+			log.Error("Could not get consents from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := ConsentsResponse{Consents: make([]ConsentInfo, 0, len(consents))}
+		for _, consent := range consents {
+			resp.Consents = append(resp.Consents, ConsentInfo{
+				Purpose: consent.Purpose,
+				Version: consent.Version,
+				At:      time.Time(consent.At).Unix(),
+			})
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// addConsent records version for the purpose {purpose}; an empty version records its withdrawal
+func addConsent(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.PrivacyRepo, version string) {
+	purpose := chi.URLParam(r, "purpose")
+
+	var v validate.Validator
+	if v.Required("purpose", purpose) {
+		v.MaxLen("purpose", purpose, maxConsentPurposeLen)
+	}
+	if r.Method != http.MethodDelete && v.Required("version", version) {
+		v.MaxLen("version", version, maxConsentVersionLen)
+	}
+	if !requireValid(w, log, &v) {
+		return
+	}
+
+	consent := db_access.Consent{
+		UserId:  auth.UserId(r.Context()),
+		Purpose: purpose,
+		Version: version,
+		At:      db_access.Time(time.Now()),
+	}
+	if err := db.AddConsent(consent); err != nil {
+		log.Error("Could not save consent to db", slogext.Error(err))
+		writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+		return
+	}
+
+	log.Info("Recorded consent", slog.String("purpose", purpose), slog.String("version", version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ConsentGive records that the user consents to the version of the purpose {purpose} in the body
+func ConsentGive(db db_access.PrivacyRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ConsentGive"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req ConsentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+			writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest)
+			return
+		}
+
+		addConsent(w, r, log, db, req.Version)
+	}
+}
+
+// ConsentWithdraw records that the user withdraws their consent to the purpose {purpose}
+func ConsentWithdraw(db db_access.PrivacyRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.ConsentWithdraw"
+		log := slogext.LogWithOp(op, r.Context())
+
+		addConsent(w, r, log, db, "")
+	}
+}
+
+// AccountDeletion requests the account of the user to be erased, which is due within deadline;
+// privacy.Eraser carries it out
+func AccountDeletion(db db_access.PrivacyRepo, deadline time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AccountDeletion"
+		log := slogext.LogWithOp(op, r.Context())
+
+		now := time.Now()
+		req := db_access.DeletionRequest{
+			UserId:      auth.UserId(r.Context()),
+			RequestedAt: db_access.Time(now),
+			Deadline:    db_access.Time(now.Add(deadline)),
+		}
+		var uce db_access.UniqueConstraintError
+		if err := db.AddDeletionRequest(req); errors.As(err, &uce) {
+			errorMsg := "Account deletion was requested already"
+			log.Error(errorMsg)
+			writeError(w, DeletionRequested, errorMsg, http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Error("Could not save deletion request to db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Info("Account deletion requested", slog.Time("deadline", time.Time(req.Deadline)))
+		if err := writeResponse(w, deletionInfo(req, now), http.StatusAccepted); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AccountDeletionStatus tells the user when their request for erasure is due or was completed
+func AccountDeletionStatus(db db_access.PrivacyRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AccountDeletionStatus"
+		log := slogext.LogWithOp(op, r.Context())
+
+		req, err := db.GetDeletionRequest(auth.UserId(r.Context()))
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "Account deletion was not requested"
+			log.Error(errorMsg)
+			writeError(w, NotFound, errorMsg, http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error("Could not get deletion request from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := writeResponse(w, deletionInfo(req, time.Now()), http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// AdminDeletionRequests lists the pending requests for erasure, the earliest deadline first
+func AdminDeletionRequests(db db_access.PrivacyRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminDeletionRequests"
+		log := slogext.LogWithOp(op, r.Context())
+
+		requests, err := db.GetPendingDeletionRequests()
+		if err != nil {
+			log.Error("Could not get deletion requests from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		now := time.Now()
+		resp := DeletionRequestsResponse{Requests: make([]DeletionInfo, 0, len(requests))}
+		for _, req := range requests {
+			resp.Requests = append(resp.Requests, deletionInfo(req, now))
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"cloud-storage/utils/problem"
+	"net/http"
+)
+
+type problemType struct {
+	name  string
+	title string
+}
+
+var problemTypes = map[ApiErrorCode]problemType{
+	InternalApiError:     {"internal-error", "Internal error"},
+	InvalidContentFormat: {"invalid-content-format", "Invalid content format"},
+	UnexpectedEOF:        {"unexpected-eof", "Unexpected end of content"},
+	TooBigContentSize:    {"content-too-big", "Content too big"},
+	ParameterOutOfRange:  {"parameter-out-of-range", "Parameter out of range"},
+	NotFound:             {"not-found", "Not found"},
+	InvalidLink:          {"invalid-link", "Invalid link"},
+	TooManyRequests:      {"too-many-requests", "Too many requests"},
+	InsufficientStorage:  {"insufficient-storage", "Insufficient storage"},
+	AmbiguousPath:        {"ambiguous-path", "Ambiguous path"},
+	PreviewUnavailable:   {"preview-unavailable", "Preview unavailable"},
+	StreamNotReady:       {"stream-not-ready", "Stream not ready"},
+	QuotaExceeded:        {"quota-exceeded", "Quota exceeded"},
+	FeatureDisabled:      {"feature-disabled", "Feature disabled"},
+	UnderMaintenance:     {"under-maintenance", "Under maintenance"},
+	TrafficCapExceeded:   {"traffic-cap-exceeded", "Traffic cap exceeded"},
+	FileTypeNotAllowed:   {"file-type-not-allowed", "File type not allowed"},
+	FileNameTaken:        {"file-name-taken", "File name taken"},
+	PreconditionFailed:   {"precondition-failed", "Precondition failed"},
+	FileLocked:           {"file-locked", "File locked"},
+	DeletionRequested:    {"deletion-requested", "Deletion requested"},
+	NetworkDenied:        {"network-denied", "Network denied"},
+	CountryDenied:        {"country-denied", "Country denied"},
+	PasswordRequired:     {"password-required", "Password required"},
+}
+
+func (code ApiErrorCode) problemType() problemType {
+	if t, ok := problemTypes[code]; ok {
+		return t
+	}
+	return problemType{name: "unknown", title: "Unknown error"}
+}
+
+// ProblemTypeURI is the RFC 7807 type of errors with the given code
+func ProblemTypeURI(code ApiErrorCode) string {
+	return problem.TypeURI(code.problemType().name)
+}
+
+type errorCarrier interface {
+	apiErrors() []ApiError
+	requestId() string
+}
+
+func (holder ErrorHolder) apiErrors() []ApiError {
+	return holder.Errors
+}
+
+func (holder ErrorHolder) requestId() string {
+	return holder.RequestId
+}
+
+func writeProblem(w http.ResponseWriter, errs []ApiError, requestId string, status int) error {
+	first := errs[0]
+	details := problem.Details{
+		Type:      ProblemTypeURI(first.Code),
+		Title:     first.Code.problemType().title,
+		Detail:    first.Description,
+		Code:      int(first.Code),
+		RequestId: requestId,
+	}
+
+	// a single error without a parameter is fully described by the top level members
+	if len(errs) > 1 || first.ParamName != "" {
+		for _, err := range errs {
+			details.Errors = append(details.Errors, problem.Param{
+				Code:   int(err.Code),
+				Type:   ProblemTypeURI(err.Code),
+				Name:   err.ParamName,
+				Detail: err.Description,
+			})
+		}
+	}
+
+	return problem.Write(w, details, status)
+}
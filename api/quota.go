@@ -0,0 +1,91 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const storageWarningHeader = "X-Storage-Warning"
+
+// Quota limits the bytes each user may store; a zero Limit means no limit
+type Quota struct {
+	Limit int64
+	// WarnAt is the share of Limit past which responses carry storage warnings
+	WarnAt float64
+}
+
+type QuotaResponse struct {
+	Unlimited bool `json:"unlimited,omitempty"`
+	// zero for unlimited quotas
+	LimitBytes     int64    `json:"limit_bytes"`
+	UsedBytes      int64    `json:"used_bytes"`
+	RemainingBytes int64    `json:"remaining_bytes"`
+	Warnings       []string `json:"warnings,omitempty"`
+	ErrorHolder
+}
+
+// warnings describes how close used bytes are to the limit once they pass WarnAt
+func (q Quota) warnings(used int64) []string {
+	if q.Limit <= 0 || float64(used) < q.WarnAt*float64(q.Limit) {
+		return nil
+	}
+
+	if used >= q.Limit {
+		return []string{fmt.Sprintf("Storage quota is used up: %d of %d bytes", used, q.Limit)}
+	}
+
+	return []string{fmt.Sprintf("Storage quota is %d%% used: %d of %d bytes", used*100/q.Limit, used, q.Limit)}
+}
+
+// addStorageWarnings mirrors warnings into response headers; call it before the status is written
+func addStorageWarnings(w http.ResponseWriter, warnings []string) {
+	for _, warning := range warnings {
+		w.Header().Add(storageWarningHeader, warning)
+	}
+}
+
+// requireQuota is checkQuota for the user of the request; it writes an error response and returns false on failure
+func requireQuota(w http.ResponseWriter, r *http.Request, log *slog.Logger, db db_access.UsageRepo, quota Quota, size int64) (int64, bool) {
+	used, err := checkQuota(db, quota, auth.UserId(r.Context()), size)
+	if err != nil {
+		writeUploadError(w, log, err)
+		return 0, false
+	}
+
+	return used, true
+}
+
+// QuotaStatus reports how much of the storage quota the user has left
+func QuotaStatus(db db_access.UsageRepo, quota Quota) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.QuotaStatus"
+		log := slogext.LogWithOp(op, r.Context())
+
+		usage, err := db.GetUsage(auth.UserId(r.Context()), db_access.Time(time.Now()))
+		if err != nil {
+			log.Error("Could not get usage from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := QuotaResponse{
+			Unlimited: quota.Limit <= 0,
+			UsedBytes: usage.StoredBytes,
+			Warnings:  quota.warnings(usage.StoredBytes),
+		}
+		if !resp.Unlimited {
+			resp.LimitBytes = quota.Limit
+			resp.RemainingBytes = max(quota.Limit-usage.StoredBytes, 0)
+		}
+
+		addStorageWarnings(w, resp.Warnings)
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
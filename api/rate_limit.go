@@ -0,0 +1,116 @@
+package api
+
+import (
+	slogext "cloud-storage/utils/slogExt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxTrackedClients bounds the memory of RateLimit; buckets that refilled are dropped past it
+const maxTrackedClients = 10000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rate    float64
+	burst   float64
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*bucket),
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+	}
+}
+
+// refill returns the bucket of the client, tokens added up to now; l.mu must be held
+func (l *rateLimiter) refill(client string, now time.Time) *bucket {
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxTrackedClients {
+			l.sweep(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	return b
+}
+
+// take spends a token of the client and otherwise returns how long until the next one
+func (l *rateLimiter) take(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(client, now)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// giveBack returns a token taken for a request that turned out not to count against the client
+func (l *rateLimiter) giveBack(client string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(client, now)
+	b.tokens = min(l.burst, b.tokens+1)
+}
+
+// sweep forgets clients whose buckets are full again; l.mu must be held
+func (l *rateLimiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// RateLimit allows each client rate requests per second with bursts of up to burst requests.
+// Clients are told apart by RemoteAddr, so it belongs behind realip.Middleware.
+// A rate that is not positive disables the limit.
+func RateLimit(rate float64, burst int) func(http.Handler) http.Handler {
+	if rate <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	l := newRateLimiter(rate, burst)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(client); err == nil {
+				client = host
+			}
+
+			ok, wait := l.take(client, time.Now())
+			if !ok {
+				const op = "api.RateLimit"
+				log := slogext.LogWithOp(op, r.Context())
+
+				log.Error("Too many requests", slog.String("client", client))
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeError(w, TooManyRequests, "Too many requests; try again later", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
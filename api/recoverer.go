@@ -0,0 +1,60 @@
+package api
+
+import (
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var panics = expvar.NewInt("http_panics")
+
+// Recoverer turns a panicking handler into an InternalApiError response carrying the request id,
+// so that the client can report it and it can be found next to the logged stack.
+// Unlike middleware.Recoverer it answers in the api error format instead of a bare 500.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+
+			// the server uses it to abort a response on purpose
+			if err, ok := rvr.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rvr)
+			}
+
+			const op = "api.Recoverer"
+			log := slogext.LogWithOp(op, r.Context())
+
+			panics.Add(1)
+			log.Error(
+				"Handler panicked",
+				slog.String("panic", fmt.Sprint(rvr)),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			// too late for an error response; cutting the connection at least tells the client something broke
+			if ww.Status() != 0 {
+				panic(http.ErrAbortHandler)
+			}
+
+			resp := UploadResponse{}
+			resp.RequestId = middleware.GetReqID(r.Context())
+			addError(&resp.ErrorHolder, InternalApiError, "Internal error")
+			if err := writeResponse(ww, resp, http.StatusInternalServerError); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/scheduler"
+	slogext "cloud-storage/utils/slogExt"
+	"net/http"
+)
+
+type TaskStatus struct {
+	Name string `json:"name"`
+	// seconds, zero for disabled tasks
+	Interval int64 `json:"interval"`
+	Enabled  bool  `json:"enabled"`
+	Running  bool  `json:"running"`
+	Runs     int   `json:"runs"`
+	Failures int   `json:"failures"`
+	// unix seconds, omitted until it happens
+	LastStart   int64  `json:"last_start,omitempty"`
+	LastEnd     int64  `json:"last_end,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+	LastSkipped int64  `json:"last_skipped,omitempty"`
+	Next        int64  `json:"next,omitempty"`
+}
+
+type ScheduleResponse struct {
+	Tasks []TaskStatus `json:"tasks"`
+	ErrorHolder
+}
+
+// AdminSchedule lists the maintenance tasks and how their last runs went; it has to be mounted behind auth.Admin
+func AdminSchedule(s *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminSchedule"
+		log := slogext.LogWithOp(op, r.Context())
+
+		statuses := s.Status()
+		resp := ScheduleResponse{Tasks: make([]TaskStatus, 0, len(statuses))}
+		for _, st := range statuses {
+			resp.Tasks = append(resp.Tasks, TaskStatus{
+				Name:        st.Name,
+				Interval:    int64(st.Interval.Seconds()),
+				Enabled:     st.Enabled,
+				Running:     st.Running,
+				Runs:        st.Runs,
+				Failures:    st.Failures,
+				LastStart:   unixOrZero(db_access.Time(st.LastStart)),
+				LastEnd:     unixOrZero(db_access.Time(st.LastEnd)),
+				LastError:   st.LastError,
+				LastSkipped: unixOrZero(db_access.Time(st.LastSkipped)),
+				Next:        unixOrZero(db_access.Time(st.Next)),
+			})
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"cloud-storage/anomaly"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchDownloads(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetAlertThresholds(fileOwnerId).Return(db_access.AlertThresholds{}, db_access.NoRowsError{}).Once()
+	db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{}, nil).Twice()
+	db.EXPECT().AddAuditEvent(mock.MatchedBy(func(e *db_access.AuditEvent) bool {
+		return e.Path == "anomaly/"+string(anomaly.DownloadVolume)
+	})).Return(nil).Once()
+
+	detector := anomaly.New(db, anomaly.Config{
+		Defaults: anomaly.Thresholds{DownloadFactor: 10, MinDownloadBytes: 10},
+		Cooldown: time.Hour,
+	}, slogext.NewDiscardLogger())
+	handler := api.WatchDownloads(detector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("missing") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("0123456789"))
+	}))
+
+	download := func(target string) {
+		r := httptest.NewRequest("GET", target, nil)
+		r = r.WithContext(context.WithValue(r.Context(), auth.AuthUserId, fileOwnerId))
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	// error bodies aren't downloads
+	download("/download?missing")
+	download("/download")
+	download("/download")
+}
+
+func TestAdminAlertThresholds(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		if user.Id != 3 {
+			return db_access.NoRowsError{}
+		}
+		return nil
+	})
+	db.EXPECT().SetAlertThresholds(mock.MatchedBy(func(t db_access.AlertThresholds) bool {
+		return t.UserId == 3 && t.DeletesPerHour != nil && *t.DeletesPerHour == 50 && t.DownloadFactor == nil
+	})).Return(nil).Once()
+	deletes := int64(50)
+	db.EXPECT().GetAlertThresholds(int64(3)).Return(db_access.AlertThresholds{UserId: 3, DeletesPerHour: &deletes}, nil).Once()
+	db.EXPECT().DeleteAlertThresholds(int64(3)).Return(nil).Once()
+
+	detector := anomaly.New(db, anomaly.Config{
+		Defaults: anomaly.Thresholds{DownloadFactor: 10, DeletesPerHour: 500},
+	}, slogext.NewDiscardLogger())
+
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Get("/users/{id}/alert-thresholds", api.AdminAlertThresholds(db, detector))
+	r.Put("/users/{id}/alert-thresholds", api.AdminAlertThresholdsSet(db, detector))
+	r.Delete("/users/{id}/alert-thresholds", api.AdminAlertThresholdsDelete(db, detector))
+	serve := func(method string, target string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serve("PUT", "/users/3/alert-thresholds", `{"deletes_per_hour": -1}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve("PUT", "/users/4/alert-thresholds", `{"deletes_per_hour": 50}`).Code)
+	assert.Equal(t, http.StatusNoContent, serve("PUT", "/users/3/alert-thresholds", `{"deletes_per_hour": 50}`).Code)
+
+	w := serve("GET", "/users/3/alert-thresholds", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.AlertThresholdsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, &deletes, resp.Overrides.DeletesPerHour)
+	assert.Nil(t, resp.Overrides.DownloadFactor)
+	assert.Equal(t, api.AlertThresholdsInfo{DownloadFactor: 10, DeletesPerHour: 50}, resp.Effective)
+
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/users/3/alert-thresholds", "").Code)
+}
@@ -0,0 +1,145 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/audit"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+
+	var recorded []db_access.AuditEvent
+	db.EXPECT().AddAuditEvent(mock.Anything).RunAndReturn(func(e *db_access.AuditEvent) error {
+		recorded = append(recorded, *e)
+		return nil
+	})
+
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.AuthUserId, fileOwnerId)))
+		})
+	})
+	r.Use(api.Audit(db))
+	r.Post("/files/{id}/move", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/files/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Put("/files", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("POST", "/files/secret-id/move", nil),
+		httptest.NewRequest("GET", "/files/secret-id", nil),
+		httptest.NewRequest("PUT", "/files", strings.NewReader("content")),
+	} {
+		req.RemoteAddr = "10.0.0.1:5000"
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// reads are not recorded
+	require.Equal(t, 2, len(recorded))
+
+	assert.Equal(t, fileOwnerId, recorded[0].UserId)
+	assert.Equal(t, "POST", recorded[0].Method)
+	assert.Equal(t, "/files/{id}/move", recorded[0].Path)
+	assert.Equal(t, http.StatusNotFound, recorded[0].Status)
+	assert.Equal(t, "10.0.0.1:5000", recorded[0].RemoteAddr)
+	assert.WithinDuration(t, time.Now(), time.Time(recorded[0].At), time.Minute)
+
+	assert.Equal(t, "PUT", recorded[1].Method)
+	assert.Equal(t, "/files", recorded[1].Path)
+	assert.Equal(t, http.StatusOK, recorded[1].Status)
+}
+
+func newAuditSigner(t *testing.T) *audit.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return audit.NewSigner(key)
+}
+
+func TestAuditExport(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	signer := newAuditSigner(t)
+
+	from := time.Unix(1714521600, 0)
+	to := time.Unix(1714608000, 0)
+	db.EXPECT().GetAuditEvents(db_access.Time(from), db_access.Time(to)).Return([]db_access.AuditEvent{
+		{Id: 1, At: db_access.Time(from), UserId: 2, Method: "POST", Path: "/api/upload", Status: 201},
+	}, nil).Twice()
+
+	for _, format := range []string{"jsonl", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/?from=1714521600&to=1714608000&format="+format, nil)
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+			w := httptest.NewRecorder()
+			api.AuditExport(db, signer).ServeHTTP(w, r)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "/api/upload")
+			assert.NoError(t, audit.Verify(signer.PublicKey(), w.Body.Bytes(), w.Header().Get("X-Audit-Signature")))
+		})
+	}
+}
+
+func TestAuditExport_InvalidParams(t *testing.T) {
+	for _, query := range []string{
+		"from=yesterday",
+		"from=1714608000&to=1714521600",
+		"from=1700000000&to=1714608000",
+		"format=xml",
+	} {
+		t.Run(query, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+
+			r := httptest.NewRequest("GET", "/?"+query, nil)
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+			w := httptest.NewRecorder()
+			api.AuditExport(db, newAuditSigner(t)).ServeHTTP(w, r)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, 1, len(resp.Errors))
+		})
+	}
+}
+
+func TestAuditKey(t *testing.T) {
+	signer := newAuditSigner(t)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	api.AuditKey(signer).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.AuditKeyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	key, err := base64.StdEncoding.DecodeString(resp.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(signer.PublicKey()), key)
+}
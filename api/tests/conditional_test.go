@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePut_IfMatch(t *testing.T) {
+	current := db_access.File{GeneratedName: sourceFile.GeneratedName, FileName: "enc:a.txt", OwnerId: fileOwnerId}
+
+	testCases := []struct {
+		name         string
+		ifMatch      string
+		files        []db_access.File
+		expectedCode int
+	}{
+		{name: "Current version", ifMatch: `"other", "` + current.GeneratedName + `"`, files: []db_access.File{current}, expectedCode: http.StatusCreated},
+		{name: "Any version", ifMatch: "*", files: []db_access.File{current}, expectedCode: http.StatusCreated},
+		{name: "Stale version", ifMatch: `"0190a5c2-0000-7000-8000-000000000000"`, files: []db_access.File{current}, expectedCode: http.StatusPreconditionFailed},
+		{name: "Weak tag", ifMatch: `W/"` + current.GeneratedName + `"`, files: []db_access.File{current}, expectedCode: http.StatusPreconditionFailed},
+		{name: "Removed meanwhile", ifMatch: "*", expectedCode: http.StatusPreconditionFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			db.EXPECT().GetFilesByNameIndex(fileOwnerId, "idx:a.txt").Return(tc.files, nil).Once()
+			c.EXPECT().DecryptFileName("enc:a.txt").Return("a.txt", nil).Maybe()
+			if tc.expectedCode == http.StatusCreated {
+				c.EXPECT().EncryptFileName("a.txt").Return("enc:a.txt", nil).Once()
+				db.EXPECT().AddFile(mock.Anything, "enc:a.txt", fileOwnerId, int64(3)).Return(nil).Once()
+				c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+					_, err := io.Copy(w, r)
+					return err
+				}).Once()
+				db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(3), int64(0)).Return(nil).Once()
+			}
+
+			r := httptest.NewRequest("PUT", "/", strings.NewReader("new"))
+			r.Header.Set("X-File-Name", "a.txt")
+			r.Header.Set("If-Match", tc.ifMatch)
+			r = r.WithContext(context.WithValue(context.Background(), auth.AuthUserId, fileOwnerId))
+			w := serveFilePut(t, db, c, t.TempDir(), r)
+			require.Equal(t, tc.expectedCode, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if tc.expectedCode == http.StatusCreated {
+				assert.Equal(t, `"`+resp.Id+`"`, w.Header().Get("ETag"))
+				return
+			}
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, api.PreconditionFailed, resp.Errors[0].Code)
+		})
+	}
+}
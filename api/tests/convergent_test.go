@@ -0,0 +1,168 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/encryption"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePut_Convergent(t *testing.T) {
+	contentHash := sha256.Sum256([]byte("raw content"))
+	key := encryption.ContentKey{Id: "content-id", Wrapped: "wrapped-key"}
+
+	testCases := []struct {
+		name string
+		// the blob already storing the content, if any
+		existing string
+	}{
+		{name: "New content"},
+		{name: "Stored content", existing: "shared-blob"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			cc := encryption_mocks.NewConvergentCrypter(t)
+			dir := t.TempDir()
+
+			expectTx(db)
+			expectNameIndex(db, c)
+			expectPlainCrypter(c)
+			c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Once()
+			cc.EXPECT().DeriveContentKey(contentHash[:]).Return(key, nil).Once()
+
+			var blobName string
+			if tc.existing != "" {
+				db.EXPECT().GetConvergentBlob(key.Id).Return(db_access.ConvergentBlob{ContentId: key.Id, BlobName: tc.existing}, nil).Once()
+				blobName = tc.existing
+			} else {
+				db.EXPECT().GetConvergentBlob(key.Id).Return(db_access.ConvergentBlob{}, db_access.NoRowsError{Table: "convergentBlobs"}).Twice()
+				cc.EXPECT().EncryptWithContentKey(mock.Anything, mock.Anything, key).RunAndReturn(func(w io.Writer, r io.Reader, _ encryption.ContentKey) error {
+					_, err := io.Copy(w, io.MultiReader(strings.NewReader("convergent:"), r))
+					return err
+				}).Once()
+				db.EXPECT().AddConvergentBlob(mock.Anything).RunAndReturn(func(blob db_access.ConvergentBlob) error {
+					assert.Equal(t, key.Id, blob.ContentId)
+					assert.Equal(t, key.Wrapped, blob.Key)
+					blobName = blob.BlobName
+					return nil
+				}).Once()
+			}
+
+			var generatedName string
+			db.EXPECT().AddFileCopy(mock.Anything, "enc:a", mock.Anything, mock.Anything, int64(11)).RunAndReturn(
+				func(name string, _ string, _ int64, blob string, _ int64) error {
+					generatedName = name
+					if tc.existing != "" {
+						assert.Equal(t, tc.existing, blob)
+					}
+					return nil
+				},
+			).Once()
+
+			cfg := api.UploadConfig{
+				MaxUploadSize: 16,
+				StorageDir:    dir,
+				Space:         storage.Space{Dir: dir},
+				Convergent:    cc,
+			}
+
+			r := httptest.NewRequest("PUT", "/", strings.NewReader("raw content"))
+			r.Header.Set("X-File-Name", "a.txt")
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+			w := httptest.NewRecorder()
+			api.FilePut(db, cfg, c).ServeHTTP(w, r)
+			require.Equal(t, http.StatusCreated, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, generatedName, resp.Id)
+
+			// the file has no blob of its own, and a new blob holds the content under its content key
+			_, err := os.Stat(filepath.Join(dir, generatedName))
+			assert.ErrorIs(t, err, os.ErrNotExist)
+			if tc.existing == "" {
+				content, err := os.ReadFile(filepath.Join(dir, blobName))
+				require.NoError(t, err)
+				assert.Equal(t, "convergent:raw content", string(content))
+			}
+
+			// nothing is left of the spooled content
+			entries, err := os.ReadDir(filepath.Join(dir, storage.TempDirName))
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestFilePut_ConvergentRace(t *testing.T) {
+	contentHash := sha256.Sum256([]byte("raw content"))
+	key := encryption.ContentKey{Id: "content-id", Wrapped: "wrapped-key"}
+
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	cc := encryption_mocks.NewConvergentCrypter(t)
+	dir := t.TempDir()
+
+	expectTx(db)
+	expectNameIndex(db, c)
+	expectPlainCrypter(c)
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Twice()
+	cc.EXPECT().DeriveContentKey(contentHash[:]).Return(key, nil).Once()
+	cc.EXPECT().EncryptWithContentKey(mock.Anything, mock.Anything, key).RunAndReturn(func(w io.Writer, r io.Reader, _ encryption.ContentKey) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	// another upload of the same content stores its blob first, after this one wrote its own
+	db.EXPECT().GetConvergentBlob(key.Id).Return(db_access.ConvergentBlob{}, db_access.NoRowsError{Table: "convergentBlobs"}).Twice()
+	db.EXPECT().AddConvergentBlob(mock.Anything).Return(db_access.UniqueConstraintError{Table: "convergentBlobs", Column: "contentId"}).Once()
+	db.EXPECT().GetConvergentBlob(key.Id).Return(db_access.ConvergentBlob{ContentId: key.Id, BlobName: "raced-blob"}, nil).Once()
+
+	var blobs []string
+	db.EXPECT().AddFileCopy(mock.Anything, "enc:a", mock.Anything, mock.Anything, int64(11)).RunAndReturn(
+		func(_ string, _ string, _ int64, blob string, _ int64) error {
+			blobs = append(blobs, blob)
+			return nil
+		},
+	).Twice()
+
+	cfg := api.UploadConfig{
+		MaxUploadSize: 16,
+		StorageDir:    dir,
+		Space:         storage.Space{Dir: dir},
+		Convergent:    cc,
+	}
+
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("raw content"))
+	r.Header.Set("X-File-Name", "a.txt")
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+	w := httptest.NewRecorder()
+	api.FilePut(db, cfg, c).ServeHTTP(w, r)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// the file ends up sharing the blob of the other upload, and the one written for it is gone
+	require.Len(t, blobs, 2)
+	assert.Equal(t, "raced-blob", blobs[1])
+	_, err := os.Stat(filepath.Join(dir, blobs[0]))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,123 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCSRF_CookieSessions(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// the db only knows the user, so any request that got to upload or delete would fail the test
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		*user = db_access.User{Id: fileOwnerId, Name: "alice", PasswordHash: hash}
+		return nil
+	})
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	a := auth.NewAuthData(db, time.Hour)
+	a.UseCookies(auth.CookieConfig{Name: "session", Secure: true, SameSite: http.SameSiteStrictMode})
+
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Post("/auth/session", auth.SessionLogin(a))
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Auth(a))
+		r.Post("/upload", api.FileUpload(db, api.UploadConfig{MaxUploadSize: 1024, StorageDir: dir, Space: storage.Space{Dir: dir}}, c))
+		r.Post("/files/batch", api.FileBatch(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil), api.AllowDuplicates, nil))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/auth/session", strings.NewReader(`{"name": "alice", "password": "secret"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	var resp auth.AuthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	upload := func() *http.Request {
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		field, err := form.CreateFormFile("file", "evil.txt")
+		require.NoError(t, err)
+		field.Write([]byte("planted"))
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return req
+	}
+	deletion := func() *http.Request {
+		body := `{"operations":[{"op":"delete","id":"01900000-0000-7000-8000-000000000001"}]}`
+		req := httptest.NewRequest("POST", "/files/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	testCases := []struct {
+		name    string
+		headers map[string]string
+	}{
+		// what a form or a script on another site can send along with the cookie of the user
+		{name: "No token", headers: map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}},
+		{name: "No token, old browser", headers: map[string]string{}},
+		{name: "Forged token", headers: map[string]string{auth.CSRFHeader: "forged"}},
+		// the token doesn't help a page of another site that somehow got it
+		{name: "Cross-site with token", headers: map[string]string{auth.CSRFHeader: resp.CSRFToken, "Sec-Fetch-Site": "cross-site"}},
+		{name: "Same-site with token", headers: map[string]string{auth.CSRFHeader: resp.CSRFToken, "Sec-Fetch-Site": "same-site"}},
+		{name: "Other origin with token", headers: map[string]string{auth.CSRFHeader: resp.CSRFToken, "Origin": "https://evil.example"}},
+	}
+
+	for _, tc := range testCases {
+		for _, newRequest := range []func() *http.Request{upload, deletion} {
+			req := newRequest()
+			t.Run(tc.name+" "+req.URL.Path, func(t *testing.T) {
+				for _, cookie := range cookies {
+					req.AddCookie(cookie)
+				}
+				for name, value := range tc.headers {
+					req.Header.Set(name, value)
+				}
+
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				assert.Equal(t, http.StatusForbidden, w.Code)
+			})
+		}
+	}
+
+	// while our own pages get through
+	db.EXPECT().GetFile("01900000-0000-7000-8000-000000000001").Return(db_access.File{}, db_access.NoRowsError{}).Once()
+	req := deletion()
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	req.Header.Set(auth.CSRFHeader, resp.CSRFToken)
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	req.Header.Set("Origin", "http://"+req.Host)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadService_Open(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+	ds := api.NewDownloadService(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil))
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Times(2)
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, io.MultiReader(strings.NewReader("decrypted "), r))
+		return err
+	}).Once()
+
+	d, err := ds.Open(context.Background(), fileOwnerId, sourceFile.GeneratedName)
+	require.NoError(t, err)
+	assert.Equal(t, "report.txt", d.FileName)
+
+	content, err := io.ReadAll(d)
+	require.NoError(t, err)
+	assert.Equal(t, "decrypted enc", string(content))
+	assert.NoError(t, d.Close())
+
+	// files of other users can't be told apart from missing ones
+	_, err = ds.Open(context.Background(), fileOwnerId+1, sourceFile.GeneratedName)
+	assert.ErrorIs(t, err, api.ErrFileNotFound)
+	_, err = ds.Open(context.Background(), fileOwnerId, "../.journal")
+	assert.ErrorIs(t, err, api.ErrFileNotFound)
+	_, err = ds.OpenFile(context.Background(), fileOwnerId+1, sourceFile)
+	assert.ErrorIs(t, err, api.ErrFileNotFound)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(db_access.File{}, errors.New("db is down")).Once()
+	_, err = ds.Open(context.Background(), fileOwnerId, sourceFile.GeneratedName)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, api.ErrFileNotFound)
+}
+
+func TestDownload_CloseWhileReading(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+	ds := api.NewDownloadService(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil))
+
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		for {
+			if _, err := w.Write([]byte("chunk")); err != nil {
+				return err
+			}
+		}
+	}).Once()
+
+	d, err := ds.OpenFile(context.Background(), fileOwnerId, sourceFile)
+	require.NoError(t, err)
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(d, buf)
+	require.NoError(t, err)
+	assert.NoError(t, d.Close())
+}
@@ -0,0 +1,128 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileExpiry(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	db.EXPECT().SetFileExpiry(sourceFile.GeneratedName, db_access.Time(time.Unix(expiresAt, 0))).Return(nil).Once()
+
+	w, _ := serveFileOpStatus(t, api.FileExpiry(db), fmt.Sprintf(`{"expires_at":%d}`, expiresAt))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	db.EXPECT().SetFileExpiry(sourceFile.GeneratedName, db_access.Time{}).Return(nil).Once()
+
+	w, _ = serveFileOpStatus(t, api.FileExpiry(db), `{"expires_at":0}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w, resp := serveFileOpStatus(t, api.FileExpiry(db), `{"expires_at":1000}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, api.ParameterOutOfRange, resp.Errors[0].Code)
+}
+
+// serveFileOpStatus is serveFileOp for handlers that may answer without a body
+func serveFileOpStatus(t *testing.T, h http.HandlerFunc, body string) (*httptest.ResponseRecorder, api.UploadResponse) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var resp api.UploadResponse
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func TestFilePut_InvalidExpiry(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	r := httptest.NewRequest("PUT", "/?expires_at=1000", strings.NewReader("content"))
+	r.Header.Set("X-File-Name", "a.txt")
+	w := serveFilePut(t, db, c, t.TempDir(), r)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	r = httptest.NewRequest("PUT", "/?expires_at=soon", strings.NewReader("content"))
+	r.Header.Set("X-File-Name", "a.txt")
+	w = serveFilePut(t, db, c, t.TempDir(), r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFilePut_Expiry(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Once()
+	db.EXPECT().AddFile(mock.Anything, "enc:a", mock.Anything, int64(7)).Return(nil).Once()
+	// the row is rolled back with the expiry by the db
+	db.EXPECT().SetFileExpiry(mock.Anything, db_access.Time(time.Unix(expiresAt, 0))).Return(errors.New("db is gone")).Once()
+
+	r := httptest.NewRequest("PUT", fmt.Sprintf("/?expires_at=%d", expiresAt), strings.NewReader("content"))
+	r.Header.Set("X-File-Name", "a.txt")
+	w := serveFilePut(t, db, c, t.TempDir(), r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestNotifications(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	at := time.Unix(1700000000, 0)
+	db.EXPECT().GetNotifications(fileOwnerId).Return([]db_access.Notification{
+		// export notifications have no file name to decrypt
+		{Id: 3, Kind: db_access.NotificationExportReady, FileId: "export", At: db_access.Time(at), CreationTime: db_access.Time(at)},
+		{Id: 2, Kind: db_access.NotificationFileExpired, FileId: "a", FileName: "enc:a.txt", At: db_access.Time(at), CreationTime: db_access.Time(at)},
+		{Id: 1, Kind: db_access.NotificationFileExpiring, FileId: "b", FileName: "broken", At: db_access.Time(at), CreationTime: db_access.Time(at)},
+	}, nil).Once()
+	c.EXPECT().DecryptFileName("enc:a.txt").Return("a.txt", nil).Once()
+	c.EXPECT().DecryptFileName("broken").Return("", errors.New("bad ciphertext")).Once()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	api.Notifications(db, c).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.NotificationsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []api.NotificationInfo{
+		{Id: 3, Kind: "export-ready", FileId: "export", At: at.Unix(), CreatedAt: at.Unix()},
+		{Id: 2, Kind: "file-expired", FileId: "a", FileName: "a.txt", At: at.Unix(), CreatedAt: at.Unix()},
+		{Id: 1, Kind: "file-expiring", FileId: "b", At: at.Unix(), CreatedAt: at.Unix()},
+	}, resp.Notifications)
+}
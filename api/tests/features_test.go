@@ -0,0 +1,45 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/features"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireFeature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"sharing": false}`), 0o600))
+
+	flags, err := features.New(path, api.FeatureDefaults, slogext.NewDiscardLogger())
+	require.NoError(t, err)
+
+	send := func(name string) *httptest.ResponseRecorder {
+		h := api.RequireFeature(flags, name)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		r := httptest.NewRequest("POST", "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send(api.FeatureImport).Code)
+
+	w := send(api.FeatureSharing)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	require.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, api.FeatureDisabled, resp.Errors[0].Code)
+}
@@ -0,0 +1,120 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileBatch(t *testing.T) {
+	id := func(n int) string {
+		return fmt.Sprintf("01900000-0000-7000-8000-%012d", n)
+	}
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, id(1)), []byte("blob"), 0o600))
+
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	expectNoLocks(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+	expectFolder(db, c, 3)
+
+	owned := func(id string) db_access.File {
+		return db_access.File{GeneratedName: id, FileName: "enc", OwnerId: fileOwnerId, BlobName: id}
+	}
+
+	db.EXPECT().GetFile(id(1)).Return(owned(id(1)), nil).Once()
+	db.EXPECT().DeleteFile(id(1)).Return(db_access.Blob{Name: id(1), Backend: blobstore.Local}, true, nil).Once()
+
+	db.EXPECT().GetFile(id(2)).Return(owned(id(2)), nil).Once()
+	c.EXPECT().EncryptFileName("b.txt").Return("enc:b.txt", nil).Once()
+	db.EXPECT().RenameFile(id(2), "enc:b.txt").Return(nil).Once()
+
+	db.EXPECT().GetFile(id(3)).Return(owned(id(3)), nil).Times(2)
+	db.EXPECT().AddFileTags(id(3), []string{"work", "2024"}).Return(nil).Once()
+
+	db.EXPECT().GetFile(id(4)).Return(db_access.File{GeneratedName: id(4), OwnerId: fileOwnerId + 1}, nil).Once()
+	db.EXPECT().GetFile(id(5)).Return(db_access.File{}, db_access.NoRowsError{}).Once()
+
+	db.EXPECT().GetFile(id(6)).Return(owned(id(6)), nil).Once()
+	c.EXPECT().DecryptFileName("enc").Return("c.txt", nil).Once()
+	db.EXPECT().SetFileFolder(id(6), int64(3)).Return(nil).Once()
+
+	body := fmt.Sprintf(`{"operations":[
+		{"op":"delete","id":%[1]q},
+		{"op":"move","id":%[2]q,"name":"b.txt"},
+		{"op":"tag","id":%[3]q,"tags":["work","2024"]},
+		{"op":"tag","id":%[3]q,"tags":[]},
+		{"op":"delete","id":%[4]q},
+		{"op":"delete","id":%[5]q},
+		{"op":"chmod","id":%[1]q},
+		{"op":"delete","id":"../%[1]s"},
+		{"op":"move","id":%[6]q,"folder":"/docs"}
+	]}`, id(1), id(2), id(3), id(4), id(5), id(6))
+
+	r, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+	assert.NoError(t, err)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	api.FileBatch(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil), api.AllowDuplicates, nil).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var resp api.BatchResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+
+	statuses := make([]int, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []int{
+		http.StatusOK,
+		http.StatusOK,
+		http.StatusOK,
+		http.StatusUnprocessableEntity,
+		http.StatusNotFound,
+		http.StatusNotFound,
+		http.StatusUnprocessableEntity,
+		http.StatusNotFound,
+		http.StatusOK,
+	}, statuses)
+	assert.Equal(t, "b.txt", resp.Results[1].FileName)
+	assert.Equal(t, "c.txt", resp.Results[8].FileName)
+	assert.Equal(t, api.ParameterOutOfRange, resp.Results[3].Errors[0].Code)
+	assert.Equal(t, api.InvalidContentFormat, resp.Results[6].Errors[0].Code)
+
+	_, err = os.Stat(filepath.Join(dir, id(1)))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileBatch_TooManyOperations(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	r, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"operations":[]}`))
+	assert.NoError(t, err)
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	api.FileBatch(db, c, blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil), api.AllowDuplicates, nil).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+}
@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"cloud-storage/access"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileByPath(t *testing.T) {
+	const userId = int64(7)
+
+	files := []db_access.File{
+		{GeneratedName: "id-1", FileName: "enc:report.txt", OwnerId: userId, BlobName: "id-1"},
+		{GeneratedName: "id-2", FileName: "enc:notes.txt", OwnerId: userId, BlobName: "id-2"},
+		{GeneratedName: "id-3", FileName: "enc:notes.txt", OwnerId: userId, BlobName: "id-2"},
+		{GeneratedName: "id-4", FileName: "enc:report.txt", OwnerId: userId, BlobName: "id-1", FolderId: 3},
+	}
+	folder := db_access.Folder{Id: 3, OwnerId: userId, Name: "enc:folder", NameIndex: "idx:folder"}
+
+	testCases := []struct {
+		name         string
+		path         string
+		listsFiles   bool
+		expectedCode int
+		expectedErr  api.ApiErrorCode
+	}{
+		{name: "Found", path: "/report.txt", listsFiles: true, expectedCode: http.StatusOK},
+		{name: "Found with redundant slashes", path: "//report.txt", listsFiles: true, expectedCode: http.StatusOK},
+		{name: "Not found", path: "/missing.txt", listsFiles: true, expectedCode: http.StatusNotFound, expectedErr: api.NotFound},
+		{name: "Ambiguous", path: "/notes.txt", listsFiles: true, expectedCode: http.StatusConflict, expectedErr: api.AmbiguousPath},
+		{name: "Nested", path: "/folder/report.txt", listsFiles: true, expectedCode: http.StatusOK},
+		{name: "No such folder", path: "/other/report.txt", expectedCode: http.StatusNotFound, expectedErr: api.NotFound},
+		{name: "Relative", path: "report.txt", expectedCode: http.StatusBadRequest, expectedErr: api.InvalidContentFormat},
+		{name: "Folder", path: "/", expectedCode: http.StatusBadRequest, expectedErr: api.InvalidContentFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "id-1"), []byte("encrypted"), 0o600))
+
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			expectNameIndex(db, c)
+			c.EXPECT().DecryptFileName(mock.Anything).RunAndReturn(func(ciphertext string) (string, error) {
+				return strings.TrimPrefix(ciphertext, "enc:"), nil
+			}).Maybe()
+			db.EXPECT().GetFoldersByNameIndex(userId, int64(0), mock.Anything).RunAndReturn(func(_ int64, _ int64, index string) ([]db_access.Folder, error) {
+				if index != folder.NameIndex {
+					return nil, nil
+				}
+				return []db_access.Folder{folder}, nil
+			}).Maybe()
+
+			if tc.listsFiles {
+				// none of the files were indexed yet, so every one of them is a candidate
+				db.EXPECT().GetFilesByNameIndex(userId, mock.Anything).Return(files, nil).Once()
+			}
+
+			if tc.expectedCode == http.StatusOK {
+				c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+					_, err := w.Write([]byte("report"))
+					return err
+				}).Once()
+				db.EXPECT().AddTraffic(userId, mock.Anything, int64(0), int64(len("report"))).Return(nil).Once()
+			}
+
+			h := api.FileByPath(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil), access.New(db, slogext.NewDiscardLogger()), 16)
+
+			r, err := http.NewRequest("GET", "/?path="+url.QueryEscape(tc.path), nil)
+			assert.NoError(t, err)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, userId))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+
+			if tc.expectedCode != http.StatusOK {
+				var resp api.DownloadResponse
+				assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+				assert.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, tc.expectedErr, resp.Errors[0].Code)
+				return
+			}
+
+			_, params, err := mime.ParseMediaType(w.Result().Header.Get("Content-Type"))
+			assert.NoError(t, err)
+
+			part, err := multipart.NewReader(w.Result().Body, params["boundary"]).NextPart()
+			assert.NoError(t, err)
+			assert.Equal(t, "report.txt", part.FileName())
+
+			content, err := io.ReadAll(part)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("report"), content)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func serveOnFile(h http.HandlerFunc, commentId string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+	routeCtx.URLParams.Add("commentId", commentId)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestFileCommentAdd(t *testing.T) {
+	otherFilesComment := db_access.Comment{Id: 3, GeneratedName: "0190a5c2-0000-7000-8000-000000000000"}
+	parent := db_access.Comment{Id: 1, GeneratedName: sourceFile.GeneratedName}
+
+	testCases := []struct {
+		name         string
+		body         string
+		parent       *db_access.Comment
+		expectedCode int
+	}{
+		{name: "Comment", body: `{"body":"Looks good"}`, expectedCode: http.StatusCreated},
+		{name: "Reply", body: `{"body":"Thanks","reply_to":1}`, parent: &parent, expectedCode: http.StatusCreated},
+		{name: "Without body", body: `{"reply_to":1}`, parent: &parent, expectedCode: http.StatusCreated},
+		{name: "Reply to another file", body: `{"body":"x","reply_to":3}`, parent: &otherFilesComment, expectedCode: http.StatusUnprocessableEntity},
+		{name: "Invalid json", body: `{`, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			expectPlainCrypter(c)
+
+			if tc.expectedCode != http.StatusBadRequest {
+				db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+			}
+			if tc.parent != nil {
+				db.EXPECT().GetComment(tc.parent.Id).Return(*tc.parent, nil).Once()
+			}
+
+			var added db_access.Comment
+			if tc.expectedCode == http.StatusCreated {
+				db.EXPECT().AddComment(mock.Anything).RunAndReturn(func(comment *db_access.Comment) error {
+					comment.Id = 2
+					added = *comment
+					return nil
+				}).Once()
+			}
+
+			w := serveOnFile(api.FileCommentAdd(db, c), "", tc.body)
+			require.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode != http.StatusCreated {
+				return
+			}
+
+			var req api.CommentRequest
+			require.NoError(t, json.Unmarshal([]byte(tc.body), &req))
+
+			var resp api.CommentResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, int64(2), resp.Id)
+			assert.Equal(t, fileOwnerId, resp.AuthorId)
+			assert.Equal(t, req.ReplyTo, resp.ReplyTo)
+			assert.Equal(t, req.Body, resp.Body)
+
+			// the body is stored encrypted, which the plain crypter leaves readable after decoding
+			assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(req.Body)), added.Body)
+			assert.Equal(t, sourceFile.GeneratedName, added.GeneratedName)
+		})
+	}
+}
+
+func TestFileComments(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	expectPlainCrypter(c)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	db.EXPECT().GetComments(sourceFile.GeneratedName).Return([]db_access.Comment{
+		{Id: 1, AuthorId: fileOwnerId, Body: base64.StdEncoding.EncodeToString([]byte("Looks good"))},
+		{Id: 2, AuthorId: fileOwnerId, ReplyTo: 1},
+	}, nil).Once()
+
+	w := serveOnFile(api.FileComments(db, c), "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.CommentsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []api.CommentInfo{
+		{Id: 1, AuthorId: fileOwnerId, Body: "Looks good"},
+		{Id: 2, AuthorId: fileOwnerId, ReplyTo: 1},
+	}, resp.Comments)
+}
+
+func TestFileCommentDelete(t *testing.T) {
+	testCases := []struct {
+		name         string
+		commentId    string
+		comment      *db_access.Comment
+		expectedCode int
+	}{
+		{"Comment on the file", "1", &db_access.Comment{Id: 1, GeneratedName: sourceFile.GeneratedName}, http.StatusNoContent},
+		{"Comment on another file", "1", &db_access.Comment{Id: 1, GeneratedName: "other"}, http.StatusNotFound},
+		{"Invalid id", "one", nil, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+			if tc.comment != nil {
+				db.EXPECT().GetComment(tc.comment.Id).Return(*tc.comment, nil).Once()
+			}
+			if tc.expectedCode == http.StatusNoContent {
+				db.EXPECT().RemoveComment(tc.comment.Id).Return(nil).Once()
+			}
+
+			w := serveOnFile(api.FileCommentDelete(db), tc.commentId, "")
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
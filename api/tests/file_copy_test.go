@@ -0,0 +1,285 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const fileOwnerId = int64(7)
+
+var sourceFile = db_access.File{
+	GeneratedName: "0190a5c2-7d4e-7b3a-9f1e-3c2b1a0d9e8f",
+	FileName:      "enc:report.txt",
+	OwnerId:       fileOwnerId,
+	BlobName:      "0190a5c2-7d4e-7b3a-9f1e-3c2b1a0d9e8f",
+	Size:          12,
+}
+
+// expectFolder lets the user of sourceFile have a folder named docs in the root
+func expectFolder(db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter, id int64) {
+	docs := db_access.Folder{Id: id, OwnerId: fileOwnerId, Name: "enc:docs", NameIndex: "idx:docs"}
+	db.EXPECT().GetFoldersByNameIndex(fileOwnerId, int64(0), mock.Anything).RunAndReturn(func(_ int64, _ int64, index string) ([]db_access.Folder, error) {
+		if index == docs.NameIndex {
+			return []db_access.Folder{docs}, nil
+		}
+		return nil, nil
+	}).Maybe()
+	c.EXPECT().DecryptFileName(docs.Name).Return("docs", nil).Maybe()
+}
+
+// expectSourceExtras lets sourceFile have the sealed metadata with its terms and the tags
+func expectSourceExtras(db *db_access_mocks.DbAccess, data string, terms []string, tags []string) {
+	db.EXPECT().GetFileMetadata(sourceFile.GeneratedName).Return(data, nil)
+	db.EXPECT().GetFileMetadataTerms(sourceFile.GeneratedName).Return(terms, nil).Maybe()
+	db.EXPECT().GetFileTags(sourceFile.GeneratedName).Return(tags, nil)
+}
+
+func serveFileOp(t *testing.T, h http.HandlerFunc, userId int64, body string) (*httptest.ResponseRecorder, api.UploadResponse) {
+	r, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+	assert.NoError(t, err)
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, userId))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var resp api.UploadResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	return w, resp
+}
+
+func TestFileCopy_Shared(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	c.EXPECT().EncryptFileName("copy.txt").Return("enc:copy.txt", nil).Once()
+
+	var generatedName string
+	db.EXPECT().AddFileCopy(mock.Anything, "enc:copy.txt", fileOwnerId, sourceFile.BlobName, sourceFile.Size).RunAndReturn(
+		func(name string, _ string, _ int64, _ string, _ int64) error {
+			generatedName = name
+			return nil
+		},
+	).Once()
+
+	// the copy gets the metadata and tags of its source as they are
+	expectSourceExtras(db, "enc:meta", []string{"term:album"}, []string{"work"})
+	db.EXPECT().SetFileMetadata(mock.Anything, fileOwnerId, "enc:meta", []string{"term:album"}).RunAndReturn(
+		func(name string, _ int64, _ string, _ []string) error {
+			assert.Equal(t, generatedName, name)
+			return nil
+		},
+	).Once()
+	db.EXPECT().AddFileTags(mock.Anything, []string{"work"}).RunAndReturn(func(name string, _ []string) error {
+		assert.Equal(t, generatedName, name)
+		return nil
+	}).Once()
+
+	h := api.FileCopy(db, c, api.UploadConfig{StorageDir: t.TempDir()}, nil)
+	w, resp := serveFileOp(t, h, fileOwnerId, `{"name":"copy.txt","share":true}`)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	assert.Equal(t, generatedName, resp.Id)
+	assert.Equal(t, "copy.txt", resp.FileName)
+}
+
+func TestFileCopy_Reencrypted(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("ciphertext 1"), 0o600))
+
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+
+	var generatedName string
+	db.EXPECT().AddFile(mock.Anything, sourceFile.FileName, fileOwnerId, sourceFile.Size).RunAndReturn(
+		func(name string, _ string, _ int64, _ int64) error {
+			generatedName = name
+			return nil
+		},
+	).Once()
+	expectSourceExtras(db, "", nil, nil)
+
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		ciphertext, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("ciphertext 1"), ciphertext)
+
+		_, err = w.Write([]byte("plaintext"))
+		return err
+	}).Once()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		plaintext, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("plaintext"), plaintext)
+
+		_, err = w.Write([]byte("ciphertext 2"))
+		return err
+	}).Once()
+
+	cfg := api.UploadConfig{StorageDir: dir, Space: storage.Space{Dir: dir}}
+	w, resp := serveFileOp(t, api.FileCopy(db, c, cfg, blobstore.NewStore(dir, storage.DurabilityNone, nil)), fileOwnerId, `{}`)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	assert.Equal(t, generatedName, resp.Id)
+	assert.Equal(t, "report.txt", resp.FileName)
+
+	content, err := os.ReadFile(filepath.Join(dir, generatedName))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ciphertext 2"), content)
+}
+
+func TestFileCopy_IntoFolder(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+	expectFolder(db, c, 3)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	c.EXPECT().EncryptFileName("copy.txt").Return("enc:copy.txt", nil).Once()
+
+	var generatedName string
+	db.EXPECT().AddFileCopy(mock.Anything, "enc:copy.txt", fileOwnerId, sourceFile.BlobName, sourceFile.Size).RunAndReturn(
+		func(name string, _ string, _ int64, _ string, _ int64) error {
+			generatedName = name
+			return nil
+		},
+	).Once()
+	db.EXPECT().SetFileFolder(mock.Anything, int64(3)).RunAndReturn(func(name string, _ int64) error {
+		assert.Equal(t, generatedName, name)
+		return nil
+	}).Once()
+	expectSourceExtras(db, "", nil, nil)
+
+	h := api.FileCopy(db, c, api.UploadConfig{StorageDir: t.TempDir()}, nil)
+	w, resp := serveFileOp(t, h, fileOwnerId, `{"folder":"/docs/","name":"copy.txt","share":true}`)
+
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	assert.Equal(t, generatedName, resp.Id)
+}
+
+func TestFileCopy_InvalidDestination(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedErr  api.ApiErrorCode
+	}{
+		{name: "Unknown folder", body: `{"folder":"/photos"}`, expectedCode: http.StatusNotFound, expectedErr: api.NotFound},
+		{name: "Dot dot in folder", body: `{"folder":"/docs/.."}`, expectedCode: http.StatusUnprocessableEntity, expectedErr: api.ParameterOutOfRange},
+		{name: "Slash in name", body: `{"name":"a/b"}`, expectedCode: http.StatusUnprocessableEntity, expectedErr: api.ParameterOutOfRange},
+		{name: "Invalid json", body: `{`, expectedCode: http.StatusBadRequest, expectedErr: api.InvalidContentFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Maybe()
+			expectNameIndex(db, c)
+			expectFolder(db, c, 3)
+
+			w, resp := serveFileOp(t, api.FileCopy(db, c, api.UploadConfig{}, nil), fileOwnerId, tc.body)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+			assert.Equal(t, 1, len(resp.Errors))
+			assert.Equal(t, tc.expectedErr, resp.Errors[0].Code)
+		})
+	}
+}
+
+func TestFileMove(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	expectNoLocks(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	c.EXPECT().EncryptFileName("renamed.txt").Return("enc:renamed.txt", nil).Once()
+	db.EXPECT().RenameFile(sourceFile.GeneratedName, "enc:renamed.txt").Return(nil).Once()
+
+	w, resp := serveFileOp(t, api.FileMove(db, c, api.AllowDuplicates, nil), fileOwnerId, `{"folder":"/","name":"renamed.txt"}`)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, sourceFile.GeneratedName, resp.Id)
+	assert.Equal(t, "renamed.txt", resp.FileName)
+}
+
+func TestFileMove_IntoFolder(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		fileName string
+	}{
+		{name: "Keeps its name", body: `{"folder":"/docs"}`, fileName: "report.txt"},
+		{name: "Renamed", body: `{"folder":"/docs","name":"renamed.txt"}`, fileName: "renamed.txt"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			expectTx(db)
+			expectNoLocks(db)
+			c := encryption_mocks.NewCrypter(t)
+			expectUserFiles(db, c)
+			expectFolder(db, c, 3)
+
+			db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+			c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Maybe()
+			if tc.fileName != "report.txt" {
+				c.EXPECT().EncryptFileName(tc.fileName).Return("enc:"+tc.fileName, nil).Once()
+				db.EXPECT().RenameFile(sourceFile.GeneratedName, "enc:"+tc.fileName).Return(nil).Once()
+			}
+			db.EXPECT().SetFileFolder(sourceFile.GeneratedName, int64(3)).Return(nil).Once()
+
+			w, resp := serveFileOp(t, api.FileMove(db, c, api.RenameDuplicates, nil), fileOwnerId, tc.body)
+
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+			assert.Equal(t, tc.fileName, resp.FileName)
+		})
+	}
+}
+
+func TestFileMove_OtherUsersFile(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+
+	w, resp := serveFileOp(t, api.FileMove(db, c, api.AllowDuplicates, nil), fileOwnerId+1, `{"name":"mine.txt"}`)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	assert.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, api.NotFound, resp.Errors[0].Code)
+}
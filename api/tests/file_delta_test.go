@@ -0,0 +1,180 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/delta"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// plaintext of sourceFile in blocks of delta.MinBlockSize
+var sourceBlocks = strings.Repeat("a", delta.MinBlockSize) + strings.Repeat("b", delta.MinBlockSize) + "cc"
+
+// expectPlainCrypter makes c store contents as they are
+func expectPlainCrypter(c *encryption_mocks.Crypter) {
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Maybe()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Maybe()
+}
+
+func serveDelta(h http.HandlerFunc, method string, query string, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/?"+query, bytes.NewReader(body))
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestFileSignature(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte(sourceBlocks), 0o600))
+
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	expectPlainCrypter(c)
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+
+	h := api.FileSignature(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil))
+	w := serveDelta(h, "GET", "block_size=512", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.SignatureResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, sourceFile.GeneratedName, resp.Id)
+	assert.Equal(t, delta.MinBlockSize, resp.BlockSize)
+	assert.Equal(t, int64(len(sourceBlocks)), resp.Size)
+	require.Len(t, resp.Blocks, 3)
+	assert.Equal(t, delta.Weak([]byte("cc")), resp.Blocks[2].Weak)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serveDelta(h, "GET", "block_size=1", nil).Code)
+}
+
+func TestFileDelta(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte(sourceBlocks), 0o600))
+
+	db := db_access_mocks.NewDbAccess(t)
+	expectNoLocks(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectPlainCrypter(c)
+	expectNameIndex(db, c)
+	expectTx(db)
+
+	const expected = "new " + "cc"
+	var generatedName string
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+	db.EXPECT().AddFile(mock.Anything, sourceFile.FileName, fileOwnerId, int64(len(expected))).RunAndReturn(
+		func(name string, _ string, _ int64, _ int64) error {
+			generatedName = name
+			return nil
+		},
+	).Once()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, mock.Anything, int64(0)).Return(nil).Once()
+	db.EXPECT().DeleteFile(sourceFile.GeneratedName).Return(db_access.Blob{Name: sourceFile.BlobName}, true, nil).Once()
+
+	var body bytes.Buffer
+	d := delta.NewWriter(&body)
+	require.NoError(t, d.Literal([]byte("new ")))
+	require.NoError(t, d.Copy(2, 1))
+	require.NoError(t, d.Flush())
+
+	cfg := api.UploadConfig{MaxUploadSize: 1024, StorageDir: dir, Space: storage.Space{Dir: dir}}
+	blobs := blobstore.NewStore(dir, storage.DurabilityNone, nil)
+	w := serveDelta(api.FileDelta(db, c, cfg, blobs), "PUT", "block_size=512", body.Bytes())
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, generatedName, resp.Id)
+	assert.Equal(t, "report.txt", resp.FileName)
+
+	content, err := os.ReadFile(filepath.Join(dir, generatedName))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+
+	_, err = os.Stat(filepath.Join(dir, sourceFile.BlobName))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileDelta_Errors(t *testing.T) {
+	encode := func(ops func(d *delta.Writer)) []byte {
+		var body bytes.Buffer
+		d := delta.NewWriter(&body)
+		ops(d)
+		d.Flush()
+		return body.Bytes()
+	}
+
+	testCases := []struct {
+		name   string
+		body   []byte
+		status int
+		code   api.ApiErrorCode
+	}{
+		{"Invalid delta", encode(func(d *delta.Writer) { d.Copy(3, 1) }), http.StatusUnprocessableEntity, api.InvalidContentFormat},
+		{"Too big", encode(func(d *delta.Writer) { d.Copy(0, 2) }), http.StatusRequestEntityTooLarge, api.TooBigContentSize},
+		{"Empty", nil, http.StatusUnprocessableEntity, api.ParameterOutOfRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte(sourceBlocks), 0o600))
+
+			db := db_access_mocks.NewDbAccess(t)
+			expectNoLocks(db)
+			c := encryption_mocks.NewCrypter(t)
+			expectPlainCrypter(c)
+			db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+			c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+			db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, mock.Anything, int64(0)).Return(nil).Maybe()
+
+			cfg := api.UploadConfig{MaxUploadSize: 600, StorageDir: dir, Space: storage.Space{Dir: dir}}
+			blobs := blobstore.NewStore(dir, storage.DurabilityNone, nil)
+			w := serveDelta(api.FileDelta(db, c, cfg, blobs), "PUT", "block_size=512", tc.body)
+			require.Equal(t, tc.status, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tc.code, resp.Errors[0].Code)
+
+			// the stored version is left as it was
+			content, err := os.ReadFile(filepath.Join(dir, sourceFile.BlobName))
+			require.NoError(t, err)
+			assert.Equal(t, sourceBlocks, string(content))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/access"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileDownload(t *testing.T) {
+	const chunkSize = 16
+
+	testCases := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "Smaller than chunk", content: []byte("short")},
+		{name: "Several chunks", content: bytes.Repeat([]byte("0123456789"), 10)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			id := "6f1c2d3e-4a5b-4c6d-8e7f-9a0b1c2d3e4f"
+			encryptedContent := []byte("encrypted")
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, id), encryptedContent, 0o600))
+
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			db.EXPECT().GetFile(id).Return(db_access.File{GeneratedName: id, FileName: "encrypted name", OwnerId: fileOwnerId, BlobName: id}, nil).Once()
+			c.EXPECT().DecryptFileName("encrypted name").Return("name.txt", nil).Once()
+			c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+				ciphertext, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				assert.Equal(t, encryptedContent, ciphertext)
+
+				// mimic the provider writing one chunk at a time
+				for chunk := range slices.Chunk(tc.content, chunkSize) {
+					if _, err := w.Write(chunk); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Once()
+			db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(0), int64(len(tc.content))).Return(nil).Once()
+
+			accesses := access.New(db, slogext.NewDiscardLogger())
+			h := api.FileDownload(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil), accesses, chunkSize)
+
+			body := `{"id":"` + id + `"}`
+			r, err := http.NewRequest("GET", "/", bytes.NewBufferString(body))
+			assert.NoError(t, err)
+			r.Header.Add("Content-Type", "application/json")
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+			_, params, err := mime.ParseMediaType(w.Result().Header.Get("Content-Type"))
+			assert.NoError(t, err)
+
+			form := multipart.NewReader(w.Result().Body, params["boundary"])
+			part, err := form.NextPart()
+			assert.NoError(t, err)
+			assert.Equal(t, "name.txt", part.FileName())
+
+			content, err := io.ReadAll(part)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.content, content)
+
+			_, err = form.NextPart()
+			assert.ErrorIs(t, err, io.EOF)
+
+			db.EXPECT().RecordFileAccesses(mock.Anything).RunAndReturn(func(accesses []db_access.FileAccess) error {
+				assert.Equal(t, 1, len(accesses))
+				assert.Equal(t, id, accesses[0].GeneratedName)
+				assert.Equal(t, int64(1), accesses[0].Count)
+				return nil
+			}).Once()
+			assert.NoError(t, accesses.Flush())
+		})
+	}
+}
+
+func TestFileDownload_InvalidId(t *testing.T) {
+	for _, id := range []string{"../.journal", "6F1C2D3E-4A5B-4C6D-8E7F-9A0B1C2D3E4F"} {
+		t.Run(id, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			h := api.FileDownload(db, encryption_mocks.NewCrypter(t), blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil), nil, 16)
+
+			r, err := http.NewRequest("GET", "/", bytes.NewBufferString(`{"id":"`+id+`"}`))
+			assert.NoError(t, err)
+			r.Header.Add("Content-Type", "application/json")
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestFileDownload_MissingId(t *testing.T) {
+	h := api.FileDownload(db_access_mocks.NewDbAccess(t), encryption_mocks.NewCrypter(t), blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil), nil, 16)
+
+	r, err := http.NewRequest("GET", "/", bytes.NewBufferString(`{}`))
+	assert.NoError(t, err)
+	r.Header.Add("Content-Type", "application/json")
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+
+	var resp api.UploadResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	assert.Equal(t, []api.ApiError{{Code: api.InvalidContentFormat, ParamName: "id", Description: "id is required"}}, resp.Errors)
+}
@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileExists(t *testing.T) {
+	otherFile := sourceFile
+	otherFile.OwnerId = fileOwnerId + 1
+
+	testCases := []struct {
+		name         string
+		method       string
+		id           string
+		file         db_access.File
+		err          error
+		expectedCode int
+	}{
+		{name: "Exists", method: "GET", id: sourceFile.GeneratedName, file: sourceFile, expectedCode: http.StatusOK},
+		{name: "Head", method: "HEAD", id: sourceFile.GeneratedName, file: sourceFile, expectedCode: http.StatusOK},
+		{name: "Missing", method: "GET", id: sourceFile.GeneratedName, err: db_access.NoRowsError{}, expectedCode: http.StatusNotFound},
+		{name: "Other user", method: "HEAD", id: sourceFile.GeneratedName, file: otherFile, expectedCode: http.StatusNotFound},
+		{name: "Invalid id", method: "GET", id: "not-a-uuid", expectedCode: http.StatusNotFound},
+		{name: "Db error", method: "GET", id: sourceFile.GeneratedName, err: errors.New("db is down"), expectedCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			if tc.file.GeneratedName != "" || tc.err != nil {
+				db.EXPECT().GetFile(tc.id).Return(tc.file, tc.err).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.AuthUserId, fileOwnerId)))
+				})
+			})
+			r.Head("/files/{id}", api.FileExists(db))
+			r.Get("/files/{id}/exists", api.FileExists(db))
+
+			path := "/files/" + tc.id
+			if tc.method == "GET" {
+				path += "/exists"
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tc.method, path, nil))
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Empty(t, w.Body.Bytes())
+		})
+	}
+}
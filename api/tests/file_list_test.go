@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileList(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	db.EXPECT().GetCollectionVersion(fileOwnerId).Return(4, nil).Once()
+	db.EXPECT().GetUserFiles(fileOwnerId).Return([]db_access.File{
+		{GeneratedName: "a", FileName: "enc:a.txt", OwnerId: fileOwnerId},
+		{GeneratedName: "b", FileName: "enc:b.txt", OwnerId: fileOwnerId, Backend: "cold", LastAccess: db_access.Time(time.Unix(1700000000, 0))},
+	}, nil).Once()
+	c.EXPECT().DecryptFileNames([]string{"enc:a.txt", "enc:b.txt"}).Return([]string{"a.txt", "b.txt"}, nil).Once()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	api.FileList(db, c).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, `W/"7.4"`, w.Header().Get("ETag"))
+
+	var resp api.FileListResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	assert.Equal(t, []api.FileInfo{
+		{Id: "a", FileName: "a.txt", Tier: "local"},
+		{Id: "b", FileName: "b.txt", Tier: "cold", LastAccessAt: 1700000000},
+	}, resp.Files)
+}
+
+func TestFileList_NotModified(t *testing.T) {
+	cases := []struct {
+		name         string
+		ifNoneMatch  string
+		expectedCode int
+	}{
+		{name: "Same version", ifNoneMatch: `W/"7.4"`, expectedCode: http.StatusNotModified},
+		{name: "Strong tag", ifNoneMatch: `"7.4"`, expectedCode: http.StatusNotModified},
+		{name: "One of many", ifNoneMatch: `W/"7.2", W/"7.4"`, expectedCode: http.StatusNotModified},
+		{name: "Any", ifNoneMatch: `*`, expectedCode: http.StatusNotModified},
+		{name: "Older version", ifNoneMatch: `W/"7.3"`, expectedCode: http.StatusOK},
+		{name: "Other user", ifNoneMatch: `W/"8.4"`, expectedCode: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			db.EXPECT().GetCollectionVersion(fileOwnerId).Return(4, nil).Once()
+			if tc.expectedCode == http.StatusOK {
+				db.EXPECT().GetUserFiles(fileOwnerId).Return([]db_access.File{}, nil).Once()
+				c.EXPECT().DecryptFileNames([]string{}).Return([]string{}, nil).Once()
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("If-None-Match", tc.ifNoneMatch)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.FileList(db, c).ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, `W/"7.4"`, w.Header().Get("ETag"))
+			if tc.expectedCode == http.StatusNotModified {
+				assert.Zero(t, w.Body.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func expectNoLocks(db *db_access_mocks.DbAccess) {
+	db.EXPECT().GetFileLock(mock.Anything).Return(db_access.FileLock{}, db_access.NoRowsError{Table: "fileLocks"}).Maybe()
+}
+
+func serveLock(t *testing.T, h http.HandlerFunc, token string, body string) (*httptest.ResponseRecorder, api.LockResponse) {
+	r := httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+	if token != "" {
+		r.Header.Set("X-Lock-Token", token)
+	}
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var resp api.LockResponse
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func TestFileLock(t *testing.T) {
+	held := db_access.FileLock{
+		GeneratedName: sourceFile.GeneratedName,
+		Token:         "held-token",
+		Holder:        "Word on laptop",
+		CreatedAt:     db_access.Time(time.Now().Add(-time.Minute)),
+		ExpiresAt:     db_access.Time(time.Now().Add(time.Minute)),
+	}
+	expired := held
+	expired.ExpiresAt = db_access.Time(time.Now().Add(-time.Second))
+
+	testCases := []struct {
+		name         string
+		current      *db_access.FileLock
+		token        string
+		body         string
+		expectedCode int
+		// the token the response should carry; "new" for a fresh one
+		expectedToken  string
+		expectedHolder string
+	}{
+		{name: "Not locked", body: `{"holder":"Excel"}`, expectedCode: http.StatusOK, expectedToken: "new", expectedHolder: "Excel"},
+		{name: "Expired lock", current: &expired, body: `{"holder":"Excel"}`, expectedCode: http.StatusOK, expectedToken: "new", expectedHolder: "Excel"},
+		{name: "Refresh", current: &held, token: held.Token, expectedCode: http.StatusOK, expectedToken: held.Token, expectedHolder: held.Holder},
+		{name: "Held by another client", current: &held, token: "other", body: `{"holder":"Excel"}`, expectedCode: http.StatusLocked, expectedHolder: held.Holder},
+		{name: "Steal", current: &held, body: `{"holder":"Excel","steal":true}`, expectedCode: http.StatusOK, expectedToken: "new", expectedHolder: "Excel"},
+		{name: "Too long", body: `{"ttl_seconds":7200}`, expectedCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			expectTx(db)
+
+			if tc.expectedCode != http.StatusUnprocessableEntity {
+				db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+				if tc.current != nil {
+					db.EXPECT().GetFileLock(sourceFile.GeneratedName).Return(*tc.current, nil).Once()
+				} else {
+					expectNoLocks(db)
+				}
+			}
+
+			var stored db_access.FileLock
+			if tc.expectedCode == http.StatusOK {
+				db.EXPECT().SetFileLock(mock.Anything).RunAndReturn(func(lock db_access.FileLock) error {
+					stored = lock
+					return nil
+				}).Once()
+			}
+
+			cfg := api.LockConfig{TimeToLive: time.Minute, MaxTimeToLive: time.Hour}
+			w, resp := serveLock(t, api.FileLock(db, cfg), tc.token, tc.body)
+			require.Equal(t, tc.expectedCode, w.Code)
+
+			switch tc.expectedCode {
+			case http.StatusOK:
+				assert.Equal(t, stored.Token, resp.Token)
+				if tc.expectedToken == "new" {
+					assert.NotEmpty(t, resp.Token)
+					assert.NotEqual(t, held.Token, resp.Token)
+				} else {
+					assert.Equal(t, tc.expectedToken, resp.Token)
+				}
+				assert.Equal(t, tc.expectedHolder, resp.Holder)
+				assert.InDelta(t, time.Now().Add(time.Minute).Unix(), resp.ExpiresAt, 2)
+			case http.StatusLocked:
+				// the token stays with its holder
+				assert.Empty(t, resp.Token)
+				assert.Equal(t, tc.expectedHolder, resp.Holder)
+				require.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, api.FileLocked, resp.Errors[0].Code)
+			}
+		})
+	}
+}
+
+func TestFileUnlock(t *testing.T) {
+	held := db_access.FileLock{
+		GeneratedName: sourceFile.GeneratedName,
+		Token:         "held-token",
+		Holder:        "Word on laptop",
+		ExpiresAt:     db_access.Time(time.Now().Add(time.Minute)),
+	}
+
+	testCases := []struct {
+		name         string
+		token        string
+		expectedCode int
+	}{
+		{"Holder", held.Token, http.StatusNoContent},
+		{"Another client", "other", http.StatusLocked},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			expectTx(db)
+			db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+			db.EXPECT().GetFileLock(sourceFile.GeneratedName).Return(held, nil).Once()
+			if tc.expectedCode == http.StatusNoContent {
+				db.EXPECT().RemoveFileLock(sourceFile.GeneratedName).Return(nil).Once()
+			}
+
+			w, _ := serveLock(t, api.FileUnlock(db), tc.token, "")
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestFileMove_Locked(t *testing.T) {
+	held := db_access.FileLock{
+		GeneratedName: sourceFile.GeneratedName,
+		Token:         "held-token",
+		Holder:        "Word on laptop",
+		ExpiresAt:     db_access.Time(time.Now().Add(time.Minute)),
+	}
+
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	db.EXPECT().GetFileLock(sourceFile.GeneratedName).Return(held, nil).Once()
+
+	w, resp := serveFileOp(t, api.FileMove(db, c, api.AllowDuplicates, nil), fileOwnerId, `{"name":"renamed.txt"}`)
+
+	assert.Equal(t, http.StatusLocked, w.Result().StatusCode)
+	require.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, api.FileLocked, resp.Errors[0].Code)
+}
+
+func TestFilePut_OverwriteLocked(t *testing.T) {
+	testCases := []struct {
+		name         string
+		token        string
+		expectedCode int
+	}{
+		{"Without token", "", http.StatusLocked},
+		{"Holder", "held-token", http.StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			dir := t.TempDir()
+
+			expectUserFiles(db, c, "a.txt")
+			expectTx(db)
+			// checked before the content is read and again in the transaction adding the file
+			lock := db.EXPECT().GetFileLock("id:a.txt").Return(db_access.FileLock{
+				GeneratedName: "id:a.txt",
+				Token:         "held-token",
+				ExpiresAt:     db_access.Time(time.Now().Add(time.Minute)),
+			}, nil).Once()
+
+			if tc.expectedCode == http.StatusCreated {
+				lock.Twice()
+				c.EXPECT().EncryptFileName("a.txt").Return("enc:new", nil).Once()
+				db.EXPECT().AddFile(mock.Anything, "enc:new", fileOwnerId, int64(3)).Return(nil).Once()
+				db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(3), int64(0)).Return(nil).Once()
+				c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+					_, err := io.Copy(w, r)
+					return err
+				}).Once()
+				db.EXPECT().DeleteFile("id:a.txt").Return(db_access.Blob{}, false, nil).Once()
+			}
+
+			cfg := api.UploadConfig{
+				MaxUploadSize:  16,
+				StorageDir:     dir,
+				Space:          storage.Space{Dir: dir},
+				DuplicateNames: api.OverwriteDuplicates,
+			}
+
+			r := httptest.NewRequest("PUT", "/", strings.NewReader("new"))
+			r.Header.Set("X-File-Name", "a.txt")
+			r.Header.Set("X-Lock-Token", tc.token)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+			w := httptest.NewRecorder()
+			api.FilePut(db, cfg, c).ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// expectUserFiles makes the user own a file of every name, encrypted as "enc:" and the name
+// and indexed as by expectNameIndex
+func expectUserFiles(db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter, names ...string) {
+	expectNameIndex(db, c)
+
+	files := make([]db_access.File, 0, len(names))
+	for _, name := range names {
+		files = append(files, db_access.File{
+			GeneratedName: "id:" + name,
+			FileName:      "enc:" + name,
+			OwnerId:       fileOwnerId,
+			BlobName:      "id:" + name,
+			NameIndex:     "idx:" + strings.ToLower(name),
+		})
+		c.EXPECT().DecryptFileName("enc:"+name).Return(name, nil).Maybe()
+	}
+
+	db.EXPECT().GetFilesByNameIndex(fileOwnerId, mock.Anything).RunAndReturn(func(_ int64, index string) ([]db_access.File, error) {
+		var found []db_access.File
+		for _, file := range files {
+			if file.NameIndex == index {
+				found = append(found, file)
+			}
+		}
+		return found, nil
+	})
+}
+
+// expectNameIndex lets files get indexed by their lowercased name
+func expectNameIndex(db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter) {
+	c.EXPECT().FileNameIndex(mock.Anything).RunAndReturn(func(name string) (string, error) {
+		return "idx:" + strings.ToLower(name), nil
+	}).Maybe()
+	db.EXPECT().SetFileNameIndex(mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+func TestDuplicateNames_UnmarshalText(t *testing.T) {
+	var d api.DuplicateNames
+	require.NoError(t, d.UnmarshalText([]byte("")))
+	assert.Equal(t, api.AllowDuplicates, d)
+	require.NoError(t, d.UnmarshalText([]byte("overwrite")))
+	assert.Equal(t, api.OverwriteDuplicates, d)
+	assert.Error(t, d.UnmarshalText([]byte("version")))
+}
+
+func TestFilePut_DuplicateNames(t *testing.T) {
+	// 254 bytes, so that the number only fits in place of two and a half multibyte characters
+	long := strings.Repeat("ж", 125) + ".txt"
+
+	testCases := []struct {
+		name     string
+		policy   api.DuplicateNames
+		fileName string
+		existing []string
+		stored   string
+	}{
+		{"Rename", api.RenameDuplicates, "a.txt", []string{"a.txt", "a (1).txt", "b.txt"}, "a (2).txt"},
+		{"Rename without extension", api.RenameDuplicates, ".bashrc", []string{".bashrc"}, ".bashrc (1)"},
+		{"Rename free name", api.RenameDuplicates, "a.txt", []string{"A.txt"}, "a.txt"},
+		{"Rename cuts the name short", api.RenameDuplicates, long, []string{long}, strings.Repeat("ж", 123) + " (1).txt"},
+		{"Overwrite", api.OverwriteDuplicates, "a.txt", []string{"a.txt", "b.txt"}, "a.txt"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			expectNoLocks(db)
+			c := encryption_mocks.NewCrypter(t)
+			dir := t.TempDir()
+
+			expectUserFiles(db, c, tc.existing...)
+			expectTx(db)
+			c.EXPECT().EncryptFileName(tc.stored).Return("enc:new", nil).Once()
+			db.EXPECT().AddFile(mock.Anything, "enc:new", fileOwnerId, int64(3)).Return(nil).Once()
+			db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(3), int64(0)).Return(nil).Once()
+			c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+				_, err := io.Copy(w, r)
+				return err
+			}).Once()
+
+			if tc.policy == api.OverwriteDuplicates {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "id:a.txt"), []byte("old"), 0o600))
+				db.EXPECT().DeleteFile("id:a.txt").Return(db_access.Blob{Name: "id:a.txt"}, true, nil).Once()
+			}
+
+			cfg := api.UploadConfig{
+				MaxUploadSize:  16,
+				StorageDir:     dir,
+				Space:          storage.Space{Dir: dir},
+				DuplicateNames: tc.policy,
+				Blobs:          blobstore.NewStore(dir, storage.DurabilityNone, nil),
+			}
+
+			r := httptest.NewRequest("PUT", "/", strings.NewReader("new"))
+			r.Header.Set("X-File-Name", tc.fileName)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+			w := httptest.NewRecorder()
+			api.FilePut(db, cfg, c).ServeHTTP(w, r)
+			require.Equal(t, http.StatusCreated, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.stored, resp.FileName)
+
+			_, err := os.Stat(filepath.Join(dir, "id:a.txt"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestFilePut_DuplicateNameRejected(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	expectUserFiles(db, c, "a.txt")
+
+	cfg := api.UploadConfig{
+		MaxUploadSize:  16,
+		StorageDir:     dir,
+		Space:          storage.Space{Dir: dir},
+		DuplicateNames: api.RejectDuplicates,
+	}
+
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("new"))
+	r.Header.Set("X-File-Name", "a.txt")
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+	w := httptest.NewRecorder()
+	api.FilePut(db, cfg, c).ServeHTTP(w, r)
+	require.Equal(t, http.StatusConflict, w.Code)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, api.FileNameTaken, resp.Errors[0].Code)
+}
+
+func TestFileMove_DuplicateNames(t *testing.T) {
+	t.Run("Own name is not taken", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		expectNoLocks(db)
+		c := encryption_mocks.NewCrypter(t)
+
+		db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+		expectNameIndex(db, c)
+		db.EXPECT().GetFilesByNameIndex(fileOwnerId, "idx:report.txt").Return([]db_access.File{sourceFile}, nil).Once()
+		c.EXPECT().EncryptFileName("report.txt").Return("enc:report.txt", nil).Once()
+
+		w, resp := serveFileOp(t, api.FileMove(db, c, api.RejectDuplicates, nil), fileOwnerId, `{"name":"report.txt"}`)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, "report.txt", resp.FileName)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		c := encryption_mocks.NewCrypter(t)
+
+		db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+		expectUserFiles(db, c, "taken.txt")
+
+		w, resp := serveFileOp(t, api.FileMove(db, c, api.RejectDuplicates, nil), fileOwnerId, `{"name":"taken.txt"}`)
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, api.FileNameTaken, resp.Errors[0].Code)
+	})
+
+	t.Run("Name without index", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		c := encryption_mocks.NewCrypter(t)
+
+		legacy := db_access.File{GeneratedName: "old", FileName: "enc:Taken.txt", OwnerId: fileOwnerId}
+		db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+		// once to look it up and once more to index the legacy file
+		c.EXPECT().FileNameIndex("Taken.txt").Return("idx:taken.txt", nil).Times(2)
+		db.EXPECT().GetFilesByNameIndex(fileOwnerId, "idx:taken.txt").Return([]db_access.File{legacy}, nil).Once()
+		c.EXPECT().DecryptFileName("enc:Taken.txt").Return("Taken.txt", nil).Once()
+		db.EXPECT().SetFileNameIndex("old", "idx:taken.txt").Return(nil).Once()
+
+		w, resp := serveFileOp(t, api.FileMove(db, c, api.RejectDuplicates, nil), fileOwnerId, `{"name":"Taken.txt"}`)
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, api.FileNameTaken, resp.Errors[0].Code)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		expectTx(db)
+		expectNoLocks(db)
+		c := encryption_mocks.NewCrypter(t)
+
+		db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+		expectUserFiles(db, c, "taken.txt")
+		c.EXPECT().EncryptFileName("taken.txt").Return("enc:moved", nil).Once()
+		db.EXPECT().RenameFile(sourceFile.GeneratedName, "enc:moved").Return(nil).Once()
+		db.EXPECT().DeleteFile("id:taken.txt").Return(db_access.Blob{Name: "id:taken.txt"}, false, nil).Once()
+
+		blobs := blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil)
+		w, resp := serveFileOp(t, api.FileMove(db, c, api.OverwriteDuplicates, blobs), fileOwnerId, `{"name":"taken.txt"}`)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, sourceFile.GeneratedName, resp.Id)
+		assert.Equal(t, "taken.txt", resp.FileName)
+	})
+}
+
+func TestUploadService_NameTakenMeanwhile(t *testing.T) {
+	ctx := context.WithValue(context.Background(), slogext.Log, slogext.NewDiscardLogger())
+
+	testCases := []struct {
+		name   string
+		policy api.DuplicateNames
+		stored string
+	}{
+		{"Reject", api.RejectDuplicates, ""},
+		{"Rename", api.RenameDuplicates, "a (1).txt"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			dir := t.TempDir()
+			expectNameIndex(db, c)
+			db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, mock.Anything, int64(0)).Return(nil).Maybe()
+			c.EXPECT().DecryptFileName("enc:a.txt").Return("a.txt", nil).Maybe()
+
+			// another upload gets the name after it was checked, and before the transaction of this one
+			var inTx bool
+			db.EXPECT().WithTx(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, fn func(db_access.DbAccess) error) error {
+				inTx = true
+				defer func() { inTx = false }()
+				return fn(db)
+			})
+			db.EXPECT().GetFilesByNameIndex(fileOwnerId, mock.Anything).RunAndReturn(func(_ int64, index string) ([]db_access.File, error) {
+				if !inTx || index != "idx:a.txt" {
+					return nil, nil
+				}
+				return []db_access.File{{GeneratedName: "other", FileName: "enc:a.txt", OwnerId: fileOwnerId, NameIndex: index}}, nil
+			})
+
+			if tc.stored != "" {
+				c.EXPECT().EncryptFileName(tc.stored).Return("enc:new", nil).Once()
+				db.EXPECT().AddFile(mock.Anything, "enc:new", fileOwnerId, int64(3)).Return(nil).Once()
+				c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+					_, err := io.Copy(w, r)
+					return err
+				}).Once()
+			}
+
+			s := api.NewUploadService(db, api.UploadConfig{
+				MaxUploadSize:  16,
+				StorageDir:     dir,
+				Space:          storage.Space{Dir: dir},
+				DuplicateNames: tc.policy,
+			}, c)
+			result, err := s.Upload(ctx, api.UploadMeta{OwnerId: fileOwnerId, FileName: "a.txt", Size: 3, MaxSize: 16}, strings.NewReader("new"))
+
+			if tc.stored == "" {
+				var ue api.UploadError
+				require.ErrorAs(t, err, &ue)
+				assert.Equal(t, http.StatusConflict, ue.Status)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.stored, result.FileName)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/preview"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFilePreview(t *testing.T) {
+	testCases := []struct {
+		name              string
+		content           string
+		query             string
+		expectedCode      int
+		expectedBody      string
+		expectedTruncated string
+	}{
+		{name: "Short text", content: "hello", expectedCode: http.StatusOK, expectedBody: "hello", expectedTruncated: "false"},
+		{name: "Truncated text", content: "hello world", query: "?bytes=5", expectedCode: http.StatusOK, expectedBody: "hello", expectedTruncated: "true"},
+		// "é" takes two bytes, so the limit falls in the middle of it
+		{name: "Truncated inside a rune", content: "café au lait", query: "?bytes=4", expectedCode: http.StatusOK, expectedBody: "caf", expectedTruncated: "true"},
+		{name: "Pdf without renderer", content: "%PDF-1.7\n...", expectedCode: http.StatusNotImplemented},
+		{name: "Binary", content: "\x00\x01\x02", expectedCode: http.StatusUnsupportedMediaType},
+		{name: "Invalid size", content: "hello", query: "?bytes=0", expectedCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("encrypted"), 0o600))
+
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			if tc.expectedCode != http.StatusUnprocessableEntity {
+				db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+				c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+				c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, _ io.Reader) error {
+					// plaintext arrives one byte at a time so the preview has to stop decryption itself
+					for i := range len(tc.content) {
+						if _, err := w.Write([]byte{tc.content[i]}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}).Once()
+			}
+
+			r, err := http.NewRequest("GET", "/"+tc.query, nil)
+			assert.NoError(t, err)
+			routeCtx := chi.NewRouteContext()
+			routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+			ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.FilePreview(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil), nil).ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+
+			body := readResponseBody(t, w)
+			if tc.expectedCode != http.StatusOK {
+				var resp api.DownloadResponse
+				assert.NoError(t, json.Unmarshal(body, &resp))
+				assert.Equal(t, 1, len(resp.Errors))
+				return
+			}
+
+			assert.True(t, strings.HasPrefix(w.Result().Header.Get("Content-Type"), "text/plain"))
+			assert.Equal(t, tc.expectedTruncated, w.Result().Header.Get("X-Preview-Truncated"))
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
+
+// fakeRenderer "renders" the first line of the PDF it is given
+type fakeRenderer struct {
+	err error
+}
+
+func (f fakeRenderer) RenderFirstPage(_ context.Context, r io.Reader, w io.Writer) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	first, _, _ := strings.Cut(string(content), "\n")
+	_, err = fmt.Fprintf(w, "png of %s, %d bytes", first, len(content))
+	return err
+}
+
+func TestFilePreview_Pdf(t *testing.T) {
+	// longer than the preview, which the renderer gets all of nonetheless
+	content := "%PDF-1.7\n" + strings.Repeat("x", 100<<10)
+
+	testCases := []struct {
+		name         string
+		renderer     fakeRenderer
+		expectedCode int
+	}{
+		{name: "Rendered", expectedCode: http.StatusOK},
+		{name: "Render error", renderer: fakeRenderer{err: errors.New("broken pdf")}, expectedCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("encrypted"), 0o600))
+
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+			c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.pdf", nil).Once()
+			c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, _ io.Reader) error {
+				_, err := io.WriteString(w, content)
+				return err
+			}).Once()
+
+			r, err := http.NewRequest("GET", "/?bytes=16", nil)
+			assert.NoError(t, err)
+			routeCtx := chi.NewRouteContext()
+			routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+			ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.FilePreview(db, c, blobstore.NewStore(dir, storage.DurabilityNone, nil), tc.renderer).ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+
+			if tc.expectedCode == http.StatusOK {
+				assert.Equal(t, preview.ContentType, w.Result().Header.Get("Content-Type"))
+				assert.Equal(t, fmt.Sprintf("png of %%PDF-1.7, %d bytes", len(content)), string(readResponseBody(t, w)))
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func serveFilePut(t *testing.T, db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter, dir string, r *http.Request) *httptest.ResponseRecorder {
+	cfg := api.UploadConfig{
+		MaxUploadSize: 16,
+		StorageDir:    dir,
+		Space:         storage.Space{Dir: dir},
+	}
+
+	expectTx(db)
+	expectNameIndex(db, c)
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+	w := httptest.NewRecorder()
+	api.FilePut(db, cfg, c).ServeHTTP(w, r)
+	return w
+}
+
+func TestFilePut(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	var generatedName string
+	c.EXPECT().EncryptFileName("отчёт.txt").Return("enc:report", nil).Once()
+	db.EXPECT().AddFile(mock.Anything, "enc:report", mock.Anything, int64(11)).Return(nil).Once().Run(func(args mock.Arguments) {
+		generatedName = args.Get(0).(string)
+	})
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("raw content"))
+	r.Header.Set("X-File-Name", "dir%2F%D0%BE%D1%82%D1%87%D1%91%D1%82.txt")
+	w := serveFilePut(t, db, c, dir, r)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, generatedName, resp.Id)
+	assert.Equal(t, "отчёт.txt", resp.FileName)
+
+	content, err := os.ReadFile(filepath.Join(dir, generatedName))
+	require.NoError(t, err)
+	assert.Equal(t, "raw content", string(content))
+}
+
+func TestFilePut_Errors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		body   io.Reader
+		length int64
+		status int
+		code   api.ApiErrorCode
+	}{
+		{"No file name", "", strings.NewReader("x"), 1, http.StatusUnprocessableEntity, api.InvalidContentFormat},
+		{"Bad escaping", "%zz", strings.NewReader("x"), 1, http.StatusUnprocessableEntity, api.InvalidContentFormat},
+		{"Unknown length", "a.txt", strings.NewReader("x"), -1, http.StatusLengthRequired, api.InvalidContentFormat},
+		{"Empty body", "a.txt", http.NoBody, 0, http.StatusUnprocessableEntity, api.ParameterOutOfRange},
+		{"Too big", "a.txt", bytes.NewReader(make([]byte, 17)), 17, http.StatusRequestEntityTooLarge, api.ParameterOutOfRange},
+		{"Name too long", strings.Repeat("a", 256), strings.NewReader("x"), 1, http.StatusUnprocessableEntity, api.ParameterOutOfRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/", tc.body)
+			r.ContentLength = tc.length
+			if tc.header != "" {
+				r.Header.Set("X-File-Name", tc.header)
+			}
+
+			w := serveFilePut(t, db_access_mocks.NewDbAccess(t), encryption_mocks.NewCrypter(t), t.TempDir(), r)
+			require.Equal(t, tc.status, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tc.code, resp.Errors[0].Code)
+		})
+	}
+}
+
+func TestFilePut_ShortBody(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Once()
+	db.EXPECT().AddFile(mock.Anything, "enc:a", mock.Anything, int64(10)).Return(nil).Once()
+	db.EXPECT().RemoveFile(mock.Anything).Return(nil).Once()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	r := httptest.NewRequest("PUT", "/", io.MultiReader(strings.NewReader("abc"), iotestErrReader{io.ErrUnexpectedEOF}))
+	r.ContentLength = 10
+	r.Header.Set("X-File-Name", "a.txt")
+
+	w := serveFilePut(t, db, c, t.TempDir(), r)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, api.UnexpectedEOF, resp.Errors[0].Code)
+}
+
+type iotestErrReader struct {
+	err error
+}
+
+func (r iotestErrReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestFilePut_IdCollision(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	var names []string
+	expectNameIndex(db, c)
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Times(2)
+	db.EXPECT().AddFile(mock.Anything, "enc:a", mock.Anything, int64(11)).RunAndReturn(func(name string, _ string, _ int64, _ int64) error {
+		names = append(names, name)
+		if len(names) == 1 {
+			return db_access.UniqueConstraintError{Table: "files", Column: "generatedName"}
+		}
+		return nil
+	}).Times(2)
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("raw content"))
+	r.Header.Set("X-File-Name", "a.txt")
+	w := serveFilePut(t, db, c, dir, r)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, names, 2)
+	assert.NotEqual(t, names[0], names[1])
+	assert.Equal(t, names[1], resp.Id)
+
+	content, err := os.ReadFile(filepath.Join(dir, resp.Id))
+	require.NoError(t, err)
+	assert.Equal(t, "raw content", string(content))
+}
@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRecent(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedLimit int
+		expectedCode  int
+	}{
+		{name: "Default limit", query: "", expectedLimit: 20, expectedCode: http.StatusOK},
+		{name: "Custom limit", query: "?limit=5", expectedLimit: 5, expectedCode: http.StatusOK},
+		{name: "Zero limit", query: "?limit=0", expectedCode: http.StatusUnprocessableEntity},
+		{name: "Limit too large", query: "?limit=101", expectedCode: http.StatusUnprocessableEntity},
+		{name: "Not a number", query: "?limit=ten", expectedCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			if tc.expectedCode == http.StatusOK {
+				db.EXPECT().GetRecentFiles(fileOwnerId, tc.expectedLimit).Return([]db_access.File{
+					{GeneratedName: "a", FileName: "enc:a.txt", OwnerId: fileOwnerId, LastAccess: db_access.Time(time.Unix(1700000000, 0)), DownloadCount: 3},
+				}, nil).Once()
+				c.EXPECT().DecryptFileNames([]string{"enc:a.txt"}).Return([]string{"a.txt"}, nil).Once()
+			}
+
+			r := httptest.NewRequest("GET", "/"+tc.query, nil)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.FileRecent(db, c).ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+
+			var resp api.FileListResponse
+			assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+			if tc.expectedCode != http.StatusOK {
+				assert.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, api.ParameterOutOfRange, resp.Errors[0].Code)
+				return
+			}
+
+			assert.Equal(t, []api.FileInfo{
+				{Id: "a", FileName: "a.txt", Tier: "local", LastAccessAt: 1700000000, DownloadCount: 3},
+			}, resp.Files)
+		})
+	}
+}
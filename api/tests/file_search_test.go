@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileSearch(t *testing.T) {
+	files := []db_access.File{
+		{GeneratedName: "a", FileName: "enc:Invoice March.pdf", OwnerId: fileOwnerId},
+		{GeneratedName: "b", FileName: "enc:notes.md", OwnerId: fileOwnerId},
+		{GeneratedName: "c", FileName: "enc:photo.jpg", OwnerId: fileOwnerId},
+	}
+
+	testCases := []struct {
+		name            string
+		query           string
+		contentSearch   bool
+		expectedTerms   []string
+		contentMatches  []db_access.File
+		expectedCode    int
+		expectedMatches map[string][]string
+	}{
+		{
+			name:            "Name only",
+			query:           "invoice",
+			expectedCode:    http.StatusOK,
+			expectedMatches: map[string][]string{"a": {api.MatchName}},
+		},
+		{
+			name:            "Name and content",
+			query:           "Invoice",
+			contentSearch:   true,
+			expectedTerms:   []string{"h:invoice"},
+			contentMatches:  []db_access.File{files[0], files[1]},
+			expectedCode:    http.StatusOK,
+			expectedMatches: map[string][]string{"a": {api.MatchName, api.MatchContent}, "b": {api.MatchContent}},
+		},
+		{
+			name:            "Every word",
+			query:           "march invoice",
+			contentSearch:   true,
+			expectedTerms:   []string{"h:march", "h:invoice"},
+			expectedCode:    http.StatusOK,
+			expectedMatches: map[string][]string{},
+		},
+		{
+			name:            "No words",
+			query:           "#",
+			contentSearch:   true,
+			expectedCode:    http.StatusOK,
+			expectedMatches: map[string][]string{},
+		},
+		{name: "Empty query", query: " ", expectedCode: http.StatusUnprocessableEntity},
+		{name: "Query too long", query: strings.Repeat("a", 257), expectedCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			if tc.expectedCode == http.StatusOK {
+				db.EXPECT().GetUserFiles(fileOwnerId).Return(files, nil).Once()
+				c.EXPECT().DecryptFileNames(mock.Anything).RunAndReturn(func(ciphertexts []string) ([]string, error) {
+					names := make([]string, 0, len(ciphertexts))
+					for _, ciphertext := range ciphertexts {
+						names = append(names, strings.TrimPrefix(ciphertext, "enc:"))
+					}
+					return names, nil
+				}).Once()
+			}
+			if len(tc.expectedTerms) > 0 {
+				c.EXPECT().ContentTermIndex(fileOwnerId, mock.Anything).RunAndReturn(func(_ int64, term string) (string, error) {
+					return "h:" + term, nil
+				}).Times(len(tc.expectedTerms))
+				db.EXPECT().GetFilesByTerms(fileOwnerId, tc.expectedTerms).Return(tc.contentMatches, nil).Once()
+			}
+
+			r := httptest.NewRequest("GET", "/?q="+url.QueryEscape(tc.query), nil)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.FileSearch(db, c, tc.contentSearch).ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+
+			var resp api.SearchResponse
+			assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+			if tc.expectedCode != http.StatusOK {
+				assert.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, api.ParameterOutOfRange, resp.Errors[0].Code)
+				return
+			}
+
+			matches := make(map[string][]string)
+			for _, file := range resp.Files {
+				matches[file.Id] = file.Matches
+			}
+			assert.Equal(t, tc.expectedMatches, matches)
+		})
+	}
+}
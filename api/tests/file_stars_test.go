@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStar(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Twice()
+	db.EXPECT().StarFile(fileOwnerId, sourceFile.GeneratedName, mock.Anything).Return(nil).Once()
+	db.EXPECT().UnstarFile(fileOwnerId, sourceFile.GeneratedName).Return(nil).Once()
+
+	w := serveOnFile(api.FileStar(db), "", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serveOnFile(api.FileUnstar(db), "", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestFileStar_OtherUsersFile(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+
+	w, _ := serveFileOp(t, api.FileStar(db), fileOwnerId+1, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFileStarred(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	db.EXPECT().GetStarredFiles(fileOwnerId).Return([]db_access.File{sourceFile}, nil).Once()
+	c.EXPECT().DecryptFileNames([]string{sourceFile.FileName}).Return([]string{"report.txt"}, nil).Once()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+	w := httptest.NewRecorder()
+	api.FileStarred(db, c).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.FileListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Files, 1)
+	assert.Equal(t, sourceFile.GeneratedName, resp.Files[0].Id)
+	assert.Equal(t, "report.txt", resp.Files[0].FileName)
+}
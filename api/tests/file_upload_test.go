@@ -3,19 +3,23 @@ package api_test
 import (
 	"bytes"
 	"cloud-storage/api"
+	"cloud-storage/db_access"
 	db_access_mocks "cloud-storage/db_access/mocks"
 	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
 	slogext "cloud-storage/utils/slogExt"
 	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -78,7 +82,9 @@ func TestFileUpload(t *testing.T) {
 			encryptedContent := []byte("encrypted: " + string(tc.content))
 
 			db := db_access_mocks.NewDbAccess(t)
+			expectTx(db)
 			c := encryption_mocks.NewCrypter(t)
+			expectNameIndex(db, c)
 
 			tc.cfg(t, db, c, encryptedFileName, &generatedFileName, expectedFileName, encryptedContent, tc.content)
 
@@ -113,6 +119,7 @@ func TestFileUpload(t *testing.T) {
 			cfg := api.UploadConfig{
 				MaxUploadSize: int64(tc.uploadSize),
 				StorageDir:    dir,
+				Space:         storage.Space{Dir: dir},
 			}
 			h := api.FileUpload(db, cfg, c)
 
@@ -199,6 +206,396 @@ func TestFileUpload_ErrorOnInvalidMultipartForm(t *testing.T) {
 	}
 }
 
+func TestFileUpload_InsufficientStorage(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	dir := t.TempDir()
+	cfg := api.UploadConfig{
+		MaxUploadSize: 1024,
+		StorageDir:    dir,
+		Space:         storage.Space{Dir: dir, Reserve: math.MaxUint64 / 2},
+	}
+	h := api.FileUpload(db, cfg, c)
+
+	formBuf := bytes.NewBuffer(make([]byte, 0))
+	form := multipart.NewWriter(formBuf)
+
+	field, err := form.CreateFormField("file-size")
+	assert.NoError(t, err)
+	contentLenBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(contentLenBytes, 10)
+	field.Write(contentLenBytes)
+
+	file, err := form.CreateFormFile("file", "name.txt")
+	assert.NoError(t, err)
+	file.Write([]byte("1234567890"))
+
+	assert.NoError(t, form.Close())
+
+	r, err := http.NewRequest("POST", "/", formBuf)
+	assert.NoError(t, err)
+	r.Header.Add("Content-Type", form.FormDataContentType())
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusInsufficientStorage, w.Result().StatusCode)
+
+	var resp api.UploadResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	assert.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, api.InsufficientStorage, resp.Errors[0].Code)
+}
+
+func TestFileUpload_IdCollision(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+
+	var names []string
+	// the name is encrypted in the transaction adding the row, so once per try
+	c.EXPECT().EncryptFileName("name.txt").Return("enc:name.txt", nil).Times(3)
+	db.EXPECT().AddFile(mock.Anything, "enc:name.txt", mock.Anything, int64(10)).RunAndReturn(func(name string, _ string, _ int64, _ int64) error {
+		names = append(names, name)
+		if len(names) < 3 {
+			return db_access.UniqueConstraintError{Table: "files", Column: "generatedName"}
+		}
+		return nil
+	}).Times(3)
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	dir := t.TempDir()
+	cfg := api.UploadConfig{
+		MaxUploadSize: 1024,
+		StorageDir:    dir,
+		Space:         storage.Space{Dir: dir},
+	}
+	h := api.FileUpload(db, cfg, c)
+
+	formBuf := bytes.NewBuffer(make([]byte, 0))
+	form := multipart.NewWriter(formBuf)
+
+	field, err := form.CreateFormField("file-size")
+	assert.NoError(t, err)
+	contentLenBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(contentLenBytes, 10)
+	field.Write(contentLenBytes)
+
+	file, err := form.CreateFormFile("file", "name.txt")
+	assert.NoError(t, err)
+	file.Write([]byte("1234567890"))
+
+	assert.NoError(t, form.Close())
+
+	r, err := http.NewRequest("POST", "/", formBuf)
+	assert.NoError(t, err)
+	r.Header.Add("Content-Type", form.FormDataContentType())
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+	var resp api.UploadResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	assert.Equal(t, names[2], resp.Id)
+
+	// the part was read once, after the id was reserved, so the collisions cost none of it
+	content, err := os.ReadFile(filepath.Join(dir, resp.Id))
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890", string(content))
+}
+
+func TestFileUpload_UnknownParts(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	c := encryption_mocks.NewCrypter(t)
+	expectNameIndex(db, c)
+
+	c.EXPECT().EncryptFileName("name.txt").Return("enc:name.txt", nil).Once()
+	db.EXPECT().AddFile(mock.Anything, "enc:name.txt", mock.Anything, int64(10)).Return(nil).Once()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	dir := t.TempDir()
+	cfg := api.UploadConfig{
+		MaxUploadSize:     1024,
+		MultipartOverhead: 1024,
+		StorageDir:        dir,
+		Space:             storage.Space{Dir: dir},
+	}
+	h := api.FileUpload(db, cfg, c)
+
+	formBuf := bytes.NewBuffer(make([]byte, 0))
+	form := multipart.NewWriter(formBuf)
+
+	// fields a browser form may send along with the ones that matter
+	assert.NoError(t, form.WriteField("description", "a file"))
+	field, err := form.CreateFormField("file-size")
+	assert.NoError(t, err)
+	field.Write(binary.LittleEndian.AppendUint64(nil, 10))
+	other, err := form.CreateFormFile("thumbnail", "thumb.png")
+	assert.NoError(t, err)
+	other.Write([]byte("png"))
+	file, err := form.CreateFormFile("file", "name.txt")
+	assert.NoError(t, err)
+	file.Write([]byte("1234567890"))
+	assert.NoError(t, form.WriteField("submit", "Upload"))
+
+	assert.NoError(t, form.Close())
+
+	r, err := http.NewRequest("POST", "/", formBuf)
+	assert.NoError(t, err)
+	r.Header.Add("Content-Type", form.FormDataContentType())
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+	var resp api.UploadResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	content, err := os.ReadFile(filepath.Join(dir, resp.Id))
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890", string(content))
+}
+
+func TestFileUpload_MissingParts(t *testing.T) {
+	testCases := []struct {
+		name           string
+		fileSize       bool
+		file           bool
+		expectedParams []string
+	}{
+		{name: "No parts", expectedParams: []string{"file_size", "file"}},
+		{name: "No file-size", file: true, expectedParams: []string{"file_size"}},
+		{name: "No file", fileSize: true, expectedParams: []string{"file"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := api.UploadConfig{MaxUploadSize: 1024, MultipartOverhead: 1024}
+			h := api.FileUpload(db_access_mocks.NewDbAccess(t), cfg, encryption_mocks.NewCrypter(t))
+
+			formBuf := bytes.NewBuffer(make([]byte, 0))
+			form := multipart.NewWriter(formBuf)
+			assert.NoError(t, form.WriteField("description", "a file"))
+			if tc.fileSize {
+				field, err := form.CreateFormField("file-size")
+				assert.NoError(t, err)
+				field.Write(binary.LittleEndian.AppendUint64(nil, 10))
+			}
+			if tc.file {
+				file, err := form.CreateFormFile("file", "name.txt")
+				assert.NoError(t, err)
+				file.Write([]byte("1234567890"))
+			}
+			assert.NoError(t, form.Close())
+
+			r, err := http.NewRequest("POST", "/", formBuf)
+			assert.NoError(t, err)
+			r.Header.Add("Content-Type", form.FormDataContentType())
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+
+			var resp api.UploadResponse
+			assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+			params := make([]string, 0, len(resp.Errors))
+			for _, e := range resp.Errors {
+				assert.Equal(t, api.InvalidContentFormat, e.Code)
+				params = append(params, e.ParamName)
+			}
+			assert.Equal(t, tc.expectedParams, params)
+		})
+	}
+}
+
+func TestFileUpload_AllFailures(t *testing.T) {
+	cfg := api.UploadConfig{MaxUploadSize: 8, MultipartOverhead: 4096}
+	h := api.FileUpload(db_access_mocks.NewDbAccess(t), cfg, encryption_mocks.NewCrypter(t))
+
+	formBuf := bytes.NewBuffer(make([]byte, 0))
+	form := multipart.NewWriter(formBuf)
+	field, err := form.CreateFormField("file-size")
+	assert.NoError(t, err)
+	field.Write(binary.LittleEndian.AppendUint64(nil, 10))
+	file, err := form.CreateFormFile("file", strings.Repeat("a", 1024)+".txt")
+	assert.NoError(t, err)
+	file.Write([]byte("1234567890"))
+	assert.NoError(t, form.Close())
+
+	r, err := http.NewRequest("POST", "/?expires_at=1000", formBuf)
+	assert.NoError(t, err)
+	r.Header.Add("Content-Type", form.FormDataContentType())
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+
+	var resp api.UploadResponse
+	assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	params := make([]string, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		assert.Equal(t, api.ParameterOutOfRange, e.Code)
+		params = append(params, e.ParamName)
+	}
+	assert.Equal(t, []string{"expires_at", "file_size", "file"}, params)
+}
+
+func TestFileUpload_OptionalFileSize(t *testing.T) {
+	testCases := []struct {
+		name          string
+		content       string
+		chunked       bool
+		expectedCode  int
+		expectedParam string
+	}{
+		{name: "Size from content", content: "1234567890", expectedCode: http.StatusCreated},
+		{name: "Larger than max upload size", content: "1234567890123456", expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "Empty file", content: "", expectedCode: http.StatusUnprocessableEntity, expectedParam: "file"},
+		{name: "Without Content-Length", content: "1234567890", chunked: true, expectedCode: http.StatusUnprocessableEntity, expectedParam: "file_size"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			expectTx(db)
+			c := encryption_mocks.NewCrypter(t)
+			expectNameIndex(db, c)
+
+			if !tc.chunked {
+				c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+					_, err := io.Copy(w, r)
+					return err
+				}).Once()
+			}
+			if tc.expectedCode == http.StatusCreated {
+				// the row is only added once the size is known
+				c.EXPECT().EncryptFileName("name.txt").Return("enc:name.txt", nil).Once()
+				db.EXPECT().AddFile(mock.Anything, "enc:name.txt", mock.Anything, int64(len(tc.content))).Return(nil).Once()
+			}
+
+			dir := t.TempDir()
+			cfg := api.UploadConfig{
+				MaxUploadSize:     12,
+				MultipartOverhead: 1024,
+				OptionalFileSize:  true,
+				StorageDir:        dir,
+				Space:             storage.Space{Dir: dir},
+			}
+			h := api.FileUpload(db, cfg, c)
+
+			formBuf := bytes.NewBuffer(make([]byte, 0))
+			form := multipart.NewWriter(formBuf)
+			file, err := form.CreateFormFile("file", "name.txt")
+			assert.NoError(t, err)
+			file.Write([]byte(tc.content))
+			assert.NoError(t, form.Close())
+
+			r, err := http.NewRequest("POST", "/", formBuf)
+			assert.NoError(t, err)
+			if tc.chunked {
+				r.ContentLength = -1
+			}
+			r.Header.Add("Content-Type", form.FormDataContentType())
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+
+			var resp api.UploadResponse
+			assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+			if tc.expectedCode == http.StatusCreated {
+				content, err := os.ReadFile(filepath.Join(dir, resp.Id))
+				assert.NoError(t, err)
+				assert.Equal(t, tc.content, string(content))
+				return
+			}
+
+			assert.Equal(t, 1, len(resp.Errors))
+			assert.Equal(t, tc.expectedParam, resp.Errors[0].ParamName)
+			entries, err := os.ReadDir(dir)
+			assert.NoError(t, err)
+			for _, entry := range entries {
+				assert.True(t, entry.IsDir(), "blob %s was kept", entry.Name())
+			}
+		})
+	}
+}
+
+// unreadBody fails the test if the handler reads any of it
+type unreadBody struct {
+	t *testing.T
+}
+
+func (b unreadBody) Read([]byte) (int, error) {
+	b.t.Error("body was read")
+	return 0, io.EOF
+}
+
+func TestFileUpload_ContentLengthTooBig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		contentLength int64
+		overhead      int64
+		maxUploadSize int64
+		status        int
+	}{
+		{"Over the limit", 2049, 1024, 1024, http.StatusRequestEntityTooLarge},
+		{"Over the limit without overhead", 1025, 0, 1024, http.StatusRequestEntityTooLarge},
+		{"Overhead does not overflow", math.MaxInt64, math.MaxInt64, math.MaxInt64, http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+
+			dir := t.TempDir()
+			cfg := api.UploadConfig{
+				MaxUploadSize:     tc.maxUploadSize,
+				MultipartOverhead: tc.overhead,
+				StorageDir:        dir,
+				Space:             storage.Space{Dir: dir},
+			}
+			h := api.FileUpload(db, cfg, c)
+
+			var body io.Reader = unreadBody{t}
+			if tc.status != http.StatusRequestEntityTooLarge {
+				body = bytes.NewReader(nil)
+			}
+			r, err := http.NewRequest("POST", "/", body)
+			assert.NoError(t, err)
+			r.ContentLength = tc.contentLength
+			r.Header.Add("Content-Type", "multipart/form-data; boundary=x")
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tc.status, w.Result().StatusCode)
+
+			if tc.status == http.StatusRequestEntityTooLarge {
+				var resp api.UploadResponse
+				assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+				assert.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, api.TooBigContentSize, resp.Errors[0].Code)
+			}
+		})
+	}
+}
+
 func bodyInvalidContentType(_ *testing.T) (io.Reader, string) {
 	return bytes.NewReader(make([]byte, 0)), ""
 }
@@ -213,6 +610,10 @@ func bodyTooBigFileSize(t *testing.T) (io.Reader, string) {
 	binary.LittleEndian.PutUint64(contentLenBytes, 1024)
 	field.Write(contentLenBytes)
 
+	file, err := form.CreateFormFile("file", "name.txt")
+	assert.NoError(t, err)
+	file.Write([]byte("content"))
+
 	assert.NoError(t, form.Close())
 
 	return formBuf, form.FormDataContentType()
@@ -229,6 +630,10 @@ func bodyNegativeFileSize(t *testing.T) (io.Reader, string) {
 	binary.LittleEndian.PutUint64(contentLenBytes, uint64(size))
 	field.Write(contentLenBytes)
 
+	file, err := form.CreateFormFile("file", "name.txt")
+	assert.NoError(t, err)
+	file.Write([]byte("content"))
+
 	assert.NoError(t, form.Close())
 
 	return formBuf, form.FormDataContentType()
@@ -270,6 +675,13 @@ func readResponseBody(t *testing.T, w *httptest.ResponseRecorder) []byte {
 	return buf.Bytes()
 }
 
+// expectTx runs the transactions of db on db itself, as if every call was committed right away
+func expectTx(db *db_access_mocks.DbAccess) {
+	db.EXPECT().WithTx(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, fn func(db_access.DbAccess) error) error {
+		return fn(db)
+	}).Maybe()
+}
+
 func assertResponseHappyPath(
 	t *testing.T,
 	w *httptest.ResponseRecorder,
@@ -313,18 +725,18 @@ func cfgHappyPath(
 	encryptedContent []byte,
 	content []byte,
 ) {
-	db.EXPECT().AddFile(mock.Anything, encryptedFileName).Return(nil).Once().Run(func(args mock.Arguments) {
+	db.EXPECT().AddFile(mock.Anything, encryptedFileName, mock.Anything, int64(len(content))).Return(nil).Once().Run(func(args mock.Arguments) {
 		*generatedFileName = args.Get(0).(string)
 	})
 
 	c.EXPECT().EncryptFileName(expectedFileName).Return(encryptedFileName, nil).Once()
-	c.EXPECT().EncryptAndCopy(mock.Anything, mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
-		w := args.Get(0).(io.Writer)
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+		w := args.Get(1).(io.Writer)
 		n, err := w.Write(encryptedContent)
 		assert.NoError(t, err)
 		assert.Equal(t, len(encryptedContent), n)
 
-		r := args.Get(1).(io.Reader)
+		r := args.Get(2).(io.Reader)
 		buf := bytes.NewBuffer(make([]byte, 0))
 		_, err = buf.ReadFrom(r)
 		assert.NoError(t, err)
@@ -342,7 +754,7 @@ func cfgUserLiedAboutContentSize(
 	encryptedContent []byte,
 	_ []byte,
 ) {
-	db.EXPECT().AddFile(mock.Anything, encryptedFileName).Return(nil).Once().Run(func(args mock.Arguments) {
+	db.EXPECT().AddFile(mock.Anything, encryptedFileName, mock.Anything, mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
 		*generatedFileName = args.Get(0).(string)
 	})
 	db.EXPECT().RemoveFile(mock.MatchedBy(func(generatedName string) bool {
@@ -350,7 +762,7 @@ func cfgUserLiedAboutContentSize(
 	})).Return(nil).Once()
 
 	c.EXPECT().EncryptFileName(expectedFileName).Return(encryptedFileName, nil).Once()
-	c.EXPECT().EncryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
 		_, err := w.Write(encryptedContent)
 		assert.NoError(t, err)
 
@@ -360,3 +772,77 @@ func cfgUserLiedAboutContentSize(
 		return err
 	}).Once()
 }
+
+// FuzzFileUpload throws malformed multipart bodies at the upload handler; it has to answer every one of them
+// without panicking and only accept a file whose content matches the announced file-size
+func FuzzFileUpload(f *testing.F) {
+	const boundary = "fuzzboundary"
+
+	form := func(fileSize uint64, content []byte) []byte {
+		formBuf := bytes.NewBuffer(make([]byte, 0))
+		form := multipart.NewWriter(formBuf)
+		form.SetBoundary(boundary)
+
+		field, _ := form.CreateFormField("file-size")
+		field.Write(binary.LittleEndian.AppendUint64(nil, fileSize))
+
+		file, _ := form.CreateFormFile("file", "name.txt")
+		file.Write(content)
+
+		form.Close()
+		return formBuf.Bytes()
+	}
+
+	valid := form(10, []byte("1234567890"))
+	f.Add(valid)
+	f.Add(form(5, []byte("1234567890")))
+	f.Add(form(20, []byte("1234567890")))
+	f.Add(form(0, nil))
+	f.Add(valid[:len(valid)/2])
+	f.Add(bytes.Replace(valid, []byte("file-size"), []byte("file-name"), 1))
+	f.Add([]byte("--" + boundary + "\r\nContent-Disposition: form-data; name=\"file-size\"\r\n\r\n\x0a\r\n--" + boundary + "--\r\n"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		db := db_access_mocks.NewDbAccess(t)
+		expectTx(db)
+		c := encryption_mocks.NewCrypter(t)
+		expectNameIndex(db, c)
+
+		var announced, copied int64
+		db.EXPECT().AddFile(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(func(_ string, _ string, _ int64, size int64) { announced = size }).
+			Return(nil).Maybe()
+		db.EXPECT().RemoveFile(mock.Anything).Return(nil).Maybe()
+		c.EXPECT().EncryptFileName(mock.Anything).Return("encrypted", nil).Maybe()
+		c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+			n, err := io.Copy(w, r)
+			copied = n
+			return err
+		}).Maybe()
+
+		dir := t.TempDir()
+		cfg := api.UploadConfig{
+			MaxUploadSize: 1024,
+			StorageDir:    dir,
+			Space:         storage.Space{Dir: dir},
+		}
+		h := api.FileUpload(db, cfg, c)
+
+		r, err := http.NewRequest("POST", "/", bytes.NewReader(body))
+		assert.NoError(t, err)
+		r.Header.Add("Content-Type", "multipart/form-data; boundary="+boundary)
+		r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Result().StatusCode == http.StatusCreated {
+			assert.Equal(t, announced, copied)
+		} else {
+			var resp api.UploadResponse
+			assert.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+			assert.NotEmpty(t, resp.Errors)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// folderFile is a file of a folder upload form
+type folderFile struct {
+	relativePath string
+	content      string
+}
+
+func folderForm(t *testing.T, files ...folderFile) (*bytes.Buffer, string) {
+	formBuf := bytes.NewBuffer(make([]byte, 0))
+	form := multipart.NewWriter(formBuf)
+	for _, f := range files {
+		if f.relativePath != "" {
+			require.NoError(t, form.WriteField("relative-path", f.relativePath))
+		}
+		field, err := form.CreateFormField("file-size")
+		require.NoError(t, err)
+		field.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(f.content))))
+		file, err := form.CreateFormFile("file", filepath.Base(f.relativePath)+".upload")
+		require.NoError(t, err)
+		file.Write([]byte(f.content))
+	}
+	require.NoError(t, form.Close())
+	return formBuf, form.FormDataContentType()
+}
+
+// expectFolderStore keeps the files and folders added to db in memory, files by the folder they were put into
+func expectFolderStore(t *testing.T, db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter) (map[int64]db_access.Folder, map[string]int64) {
+	folders := make(map[int64]db_access.Folder)
+	fileFolders := make(map[string]int64)
+
+	expectTx(db)
+	expectNameIndex(db, c)
+	c.EXPECT().EncryptFileName(mock.Anything).RunAndReturn(func(name string) (string, error) {
+		return "enc:" + name, nil
+	})
+	c.EXPECT().DecryptFileName(mock.Anything).RunAndReturn(func(ciphertext string) (string, error) {
+		return strings.TrimPrefix(ciphertext, "enc:"), nil
+	}).Maybe()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+	db.EXPECT().AddFile(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	db.EXPECT().AddTraffic(int64(1), mock.Anything, mock.Anything, int64(0)).Return(nil)
+	db.EXPECT().GetFoldersByNameIndex(int64(1), mock.Anything, mock.Anything).RunAndReturn(func(_ int64, parentId int64, index string) ([]db_access.Folder, error) {
+		var found []db_access.Folder
+		for _, folder := range folders {
+			if folder.ParentId == parentId && folder.NameIndex == index {
+				found = append(found, folder)
+			}
+		}
+		return found, nil
+	})
+	db.EXPECT().AddFolder(mock.Anything).RunAndReturn(func(folder *db_access.Folder) error {
+		assert.Equal(t, int64(1), folder.OwnerId)
+		folder.Id = int64(len(folders) + 1)
+		folders[folder.Id] = *folder
+		return nil
+	})
+	db.EXPECT().SetFileFolder(mock.Anything, mock.Anything).RunAndReturn(func(generatedName string, folderId int64) error {
+		fileFolders[generatedName] = folderId
+		return nil
+	})
+
+	return folders, fileFolders
+}
+
+func postForm(h http.Handler, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Add("Content-Type", contentType)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, int64(1)))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func folderUploadConfig(dir string) api.UploadConfig {
+	return api.UploadConfig{
+		MaxUploadSize:     1024,
+		MultipartOverhead: 1024,
+		StorageDir:        dir,
+		Space:             storage.Space{Dir: dir},
+	}
+}
+
+func TestFileUpload_RelativePath(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	folders, fileFolders := expectFolderStore(t, db, c)
+
+	dir := t.TempDir()
+	body, contentType := folderForm(t, folderFile{relativePath: "photos/2024/beach.jpg", content: "sand"})
+	w := postForm(api.FileUpload(db, folderUploadConfig(dir), c), body, contentType)
+	require.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	assert.Equal(t, "beach.jpg", resp.FileName)
+	assert.Equal(t, "/photos/2024/beach.jpg", resp.FilePath)
+
+	// the name comes from the path, the folders are made from the root down
+	assert.Equal(t, map[int64]db_access.Folder{
+		1: {Id: 1, OwnerId: 1, ParentId: 0, Name: "enc:photos", NameIndex: "idx:photos"},
+		2: {Id: 2, OwnerId: 1, ParentId: 1, Name: "enc:2024", NameIndex: "idx:2024"},
+	}, folders)
+	assert.Equal(t, map[string]int64{resp.Id: 2}, fileFolders)
+
+	content, err := os.ReadFile(filepath.Join(dir, resp.Id))
+	require.NoError(t, err)
+	assert.Equal(t, "sand", string(content))
+}
+
+func TestFileUpload_InvalidRelativePath(t *testing.T) {
+	testCases := []struct {
+		relativePath string
+		expectedCode int
+	}{
+		{relativePath: "/photos/beach.jpg", expectedCode: http.StatusBadRequest},
+		{relativePath: "photos//beach.jpg", expectedCode: http.StatusBadRequest},
+		{relativePath: "photos/../beach.jpg", expectedCode: http.StatusBadRequest},
+		{relativePath: "photos/./beach.jpg", expectedCode: http.StatusBadRequest},
+		{relativePath: "photos/", expectedCode: http.StatusBadRequest},
+		{relativePath: strings.Repeat("a", 256) + "/beach.jpg", expectedCode: http.StatusBadRequest},
+		{relativePath: strings.Repeat("a/", 65) + "beach.jpg", expectedCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.relativePath, func(t *testing.T) {
+			h := api.FileUpload(db_access_mocks.NewDbAccess(t), folderUploadConfig(t.TempDir()), encryption_mocks.NewCrypter(t))
+
+			body, contentType := folderForm(t, folderFile{relativePath: tc.relativePath, content: "sand"})
+			w := postForm(h, body, contentType)
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+			require.Equal(t, 1, len(resp.Errors))
+			assert.Equal(t, "relative_path", resp.Errors[0].ParamName)
+		})
+	}
+}
+
+func TestFolderUpload(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	folders, fileFolders := expectFolderStore(t, db, c)
+
+	dir := t.TempDir()
+	body, contentType := folderForm(t,
+		folderFile{relativePath: "album/a.txt", content: "first"},
+		folderFile{relativePath: "album/2024/b.txt", content: "second"},
+		folderFile{relativePath: "album/c.txt", content: "third"},
+	)
+	w := postForm(api.FolderUpload(db, folderUploadConfig(dir), c), body, contentType)
+	require.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+	var resp api.FolderUploadResponse
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	require.Equal(t, 3, len(resp.Files))
+
+	paths := make([]string, 0, len(resp.Files))
+	for _, file := range resp.Files {
+		paths = append(paths, file.FilePath)
+	}
+	assert.Equal(t, []string{"/album/a.txt", "/album/2024/b.txt", "/album/c.txt"}, paths)
+
+	// album is made once and shared by the files in it
+	assert.Equal(t, 2, len(folders))
+	assert.Equal(t, map[string]int64{resp.Files[0].Id: 1, resp.Files[1].Id: 2, resp.Files[2].Id: 1}, fileFolders)
+
+	content, err := os.ReadFile(filepath.Join(dir, resp.Files[1].Id))
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(content))
+}
+
+func TestFolderUpload_MissingRelativePath(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	expectFolderStore(t, db, c)
+
+	body, contentType := folderForm(t,
+		folderFile{relativePath: "album/a.txt", content: "first"},
+		folderFile{content: "second"},
+	)
+	w := postForm(api.FolderUpload(db, folderUploadConfig(t.TempDir()), c), body, contentType)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+
+	var resp api.FolderUploadResponse
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	require.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, "relative_path", resp.Errors[0].ParamName)
+}
+
+func TestFolderUpload_Empty(t *testing.T) {
+	h := api.FolderUpload(db_access_mocks.NewDbAccess(t), folderUploadConfig(t.TempDir()), encryption_mocks.NewCrypter(t))
+
+	body, contentType := folderForm(t)
+	w := postForm(h, body, contentType)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+}
+
+func TestFolders(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	db.EXPECT().GetFolders(int64(1)).Return([]db_access.Folder{
+		{Id: 1, OwnerId: 1, Name: "enc:photos"},
+		{Id: 2, OwnerId: 1, ParentId: 1, Name: "enc:2024"},
+	}, nil).Once()
+	c.EXPECT().DecryptFileNames([]string{"enc:photos", "enc:2024"}).Return([]string{"photos", "2024"}, nil).Once()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, int64(1)))
+	w := httptest.NewRecorder()
+	api.Folders(db, c).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var resp api.FolderListResponse
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	assert.Equal(t, []api.FolderInfo{
+		{Id: 1, ParentId: 0, Name: "photos"},
+		{Id: 2, ParentId: 1, Name: "2024"},
+	}, resp.Folders)
+}
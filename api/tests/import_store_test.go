@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/importer"
+	"cloud-storage/policy"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportStore_Store(t *testing.T) {
+	ctx := context.WithValue(context.Background(), slogext.Log, slogext.NewDiscardLogger())
+
+	testCases := []struct {
+		name    string
+		file    importer.File
+		content string
+		stored  bool
+	}{
+		{name: "Stored", file: importer.File{Name: "a.png", Size: 5, MaxSize: 16}, content: "image", stored: true},
+		{name: "Size unknown", file: importer.File{Name: "a.png", Size: -1, MaxSize: 16}, content: "image", stored: true},
+		{name: "Long name", file: importer.File{Name: strings.Repeat("a", 1024) + ".png", Size: 5, MaxSize: 16}, content: "image"},
+		{name: "Type not allowed", file: importer.File{Name: "a.txt", Size: 4, MaxSize: 16}, content: "text"},
+		{name: "Bigger than the user may upload", file: importer.File{Name: "a.png", Size: 9, MaxSize: 16}, content: "big image"},
+		{name: "Content beyond the user's max size", file: importer.File{Name: "a.png", Size: -1, MaxSize: 16}, content: "big image"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			db.EXPECT().GetUserPolicies(mock.Anything).Return([]db_access.Policy{
+				{Scope: db_access.GlobalScope, AllowedTypes: []string{"image/*"}},
+				{Scope: db_access.UserScope, Subject: "1", MaxFileSize: ptr(int64(8))},
+			}, nil).Once()
+			expectTx(db)
+			expectNameIndex(db, c)
+			c.EXPECT().EncryptFileName(tc.file.Name).Return("enc:a", nil).Maybe()
+			c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+				_, err := io.Copy(w, r)
+				return err
+			}).Maybe()
+			db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, mock.Anything, int64(0)).Return(nil).Maybe()
+			if tc.stored {
+				db.EXPECT().AddFile(mock.Anything, "enc:a", fileOwnerId, int64(len(tc.content))).Return(nil).Once()
+			}
+
+			dir := t.TempDir()
+			store := api.NewImportStore(db, api.UploadConfig{
+				MaxUploadSize: 16,
+				StorageDir:    dir,
+				Space:         storage.Space{Dir: dir},
+				Policies:      policy.NewEvaluator(db, policy.Limits{MaxFileSize: 16}),
+			}, c)
+
+			tc.file.OwnerId = fileOwnerId
+			size, err := store.Store(ctx, tc.file, strings.NewReader(tc.content))
+			if tc.stored {
+				require.NoError(t, err)
+				assert.Equal(t, int64(len(tc.content)), size)
+				return
+			}
+
+			var re importer.RejectedError
+			require.ErrorAs(t, err, &re)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/jobs"
+	"cloud-storage/keys"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobStatus(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	finished := db_access.Job{
+		Id:           "job",
+		Type:         "export",
+		OwnerId:      fileOwnerId,
+		Status:       db_access.JobFinished,
+		Done:         2,
+		Total:        2,
+		Result:       `{"download":"/api/export/job/download"}`,
+		CreationTime: db_access.Time(created),
+		UpdateTime:   db_access.Time(created.Add(time.Minute)),
+	}
+	failed := db_access.Job{Id: "job", Type: "import", OwnerId: fileOwnerId, Status: db_access.JobFailed, Error: "Could not list source"}
+	server := db_access.Job{Id: "job", Type: "migration", Status: db_access.JobRunning, Done: 1, Total: 4}
+
+	serve := func(h http.HandlerFunc) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", "job")
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+		ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+		r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Finished", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		db.EXPECT().GetJob("job").Return(finished, nil).Once()
+
+		w := serve(api.JobStatus(db))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{
+			"id": "job",
+			"type": "export",
+			"status": "finished",
+			"progress": {"done": 2, "total": 2},
+			"result": {"download": "/api/export/job/download"},
+			"created_at": 1700000000,
+			"updated_at": 1700000060
+		}`, w.Body.String())
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		db.EXPECT().GetJob("job").Return(failed, nil).Once()
+
+		w := serve(api.JobStatus(db))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp api.JobResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "failed", resp.Status)
+		assert.Nil(t, resp.Result)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "Could not list source", resp.Errors[0].Description)
+	})
+
+	t.Run("Job of someone else", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		db.EXPECT().GetJob("job").Return(server, nil).Once()
+
+		assert.Equal(t, http.StatusNotFound, serve(api.JobStatus(db)).Code)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		db.EXPECT().GetJob("job").Return(server, nil).Once()
+
+		w := serve(api.AdminJobStatus(db))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp api.JobResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, api.JobProgress{Done: 1, Total: 4}, resp.Progress)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		db.EXPECT().GetJob("job").Return(db_access.Job{}, db_access.NoRowsError{Table: "jobs"}).Once()
+
+		assert.Equal(t, http.StatusNotFound, serve(api.JobStatus(db)).Code)
+	})
+}
+
+func TestAdminKeysRewrap(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	var saved db_access.Job
+	db.EXPECT().AddJob(mock.Anything).RunAndReturn(func(job *db_access.Job) error {
+		saved = *job
+		return nil
+	}).Once()
+
+	// without Run the job stays queued
+	pool := jobs.New(db, jobs.Config{}, slogext.NewDiscardLogger())
+	rw := keys.NewRewrapper(db, encryption_mocks.NewRewrapService(t), slogext.NewDiscardLogger())
+
+	r := httptest.NewRequest("POST", "/api/admin/keys/rewrap", nil)
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+	w := httptest.NewRecorder()
+	api.AdminKeysRewrap(pool, rw).ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var resp api.JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, saved.Id, resp.Id)
+	assert.Equal(t, jobs.TypeRewrap, resp.Type)
+	assert.Equal(t, "queued", resp.Status)
+	assert.Zero(t, saved.OwnerId)
+}
@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePut_ContentAddressed(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	expectTx(db)
+	expectNameIndex(db, c)
+	expectPlainCrypter(c)
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Twice()
+
+	sum := sha256.Sum256([]byte("content"))
+	blobName := storage.ContentAddress(hex.EncodeToString(sum[:]))
+
+	var generatedNames []string
+	db.EXPECT().AddFileCopy(mock.Anything, "enc:a", mock.Anything, blobName, int64(7)).RunAndReturn(
+		func(name string, _ string, _ int64, _ string, _ int64) error {
+			// the row is in place before the blob
+			_, err := os.Stat(filepath.Join(dir, blobName))
+			assert.Equal(t, len(generatedNames) == 0, os.IsNotExist(err))
+
+			generatedNames = append(generatedNames, name)
+			return nil
+		},
+	).Twice()
+
+	cfg := api.UploadConfig{
+		MaxUploadSize: 16,
+		StorageDir:    dir,
+		Space:         storage.Space{Dir: dir},
+		Layout:        storage.LayoutContentAddressed,
+	}
+
+	// both uploads of the same ciphertext end up in one blob
+	for range 2 {
+		r := httptest.NewRequest("PUT", "/", strings.NewReader("content"))
+		r.Header.Set("X-File-Name", "a.txt")
+		r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+		w := httptest.NewRecorder()
+		api.FilePut(db, cfg, c).ServeHTTP(w, r)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var resp api.UploadResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, generatedNames[len(generatedNames)-1], resp.Id)
+	}
+	assert.NotEqual(t, generatedNames[0], generatedNames[1])
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var blobs []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			blobs = append(blobs, entry.Name())
+		}
+	}
+	assert.Equal(t, []string{blobName}, blobs)
+
+	content, err := os.ReadFile(filepath.Join(dir, blobName))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
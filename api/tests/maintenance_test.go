@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/maintenance"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance(t *testing.T) {
+	mode := maintenance.New(false, "")
+	write := api.RejectInMaintenance(mode)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func(h http.Handler, method string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, serve(write, "POST", "").Code)
+
+	w := serve(api.MaintenanceSet(mode), "PUT", `{"enabled": true, "reason": "db backup"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status api.MaintenanceResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(t, status.Enabled)
+	assert.Equal(t, "db backup", status.Reason)
+	assert.NotZero(t, status.Since)
+
+	w = serve(write, "POST", "")
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, api.UnderMaintenance, resp.Errors[0].Code)
+	assert.Contains(t, resp.Errors[0].Description, "db backup")
+
+	w = serve(api.MaintenanceSet(mode), "PUT", `{"enabled": false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(api.MaintenanceStatus(mode), "GET", "")
+	status = api.MaintenanceResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.False(t, status.Enabled)
+	assert.Zero(t, status.Since)
+
+	assert.Equal(t, http.StatusCreated, serve(write, "POST", "").Code)
+}
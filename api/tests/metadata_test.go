@@ -0,0 +1,190 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func expectMetadataTerms(c *encryption_mocks.Crypter) {
+	c.EXPECT().ContentTermIndex(fileOwnerId, mock.Anything).RunAndReturn(func(_ int64, term string) (string, error) {
+		return "h:" + strings.ReplaceAll(term, "\x00", "/"), nil
+	}).Maybe()
+}
+
+func sealedMetadata(t *testing.T, metadata map[string]string) string {
+	doc, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(doc)
+}
+
+func TestFileMetadataPatch(t *testing.T) {
+	testCases := []struct {
+		name         string
+		current      map[string]string
+		body         string
+		expectedCode int
+		expected     map[string]string
+		// hashed pairs the metadata is stored with
+		expectedTerms []string
+	}{
+		{
+			name:          "Set",
+			body:          `{"metadata":{"album":"trip"}}`,
+			expectedCode:  http.StatusOK,
+			expected:      map[string]string{"album": "trip"},
+			expectedTerms: []string{"h:meta/album/trip"},
+		},
+		{
+			name:          "Merge",
+			current:       map[string]string{"album": "trip", "source": "phone"},
+			body:          `{"metadata":{"source":null,"rating":"5"}}`,
+			expectedCode:  http.StatusOK,
+			expected:      map[string]string{"album": "trip", "rating": "5"},
+			expectedTerms: []string{"h:meta/album/trip", "h:meta/rating/5"},
+		},
+		{
+			name:         "Remove all",
+			current:      map[string]string{"album": "trip"},
+			body:         `{"metadata":{"album":null}}`,
+			expectedCode: http.StatusOK,
+			expected:     map[string]string{},
+		},
+		{
+			name:         "Value too long",
+			body:         `{"metadata":{"album":"` + strings.Repeat("a", 257) + `"}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Empty key",
+			body:         `{"metadata":{"":"x"}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			c := encryption_mocks.NewCrypter(t)
+			expectTx(db)
+			expectPlainCrypter(c)
+			expectMetadataTerms(c)
+
+			current := ""
+			if tc.current != nil {
+				current = sealedMetadata(t, tc.current)
+			}
+			db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+			db.EXPECT().GetFileMetadata(sourceFile.GeneratedName).Return(current, nil).Once()
+
+			if tc.expectedCode == http.StatusOK {
+				expectedData := ""
+				if len(tc.expected) > 0 {
+					expectedData = sealedMetadata(t, tc.expected)
+				}
+				db.EXPECT().SetFileMetadata(sourceFile.GeneratedName, fileOwnerId, expectedData, mock.Anything).RunAndReturn(
+					func(_ string, _ int64, _ string, terms []string) error {
+						assert.ElementsMatch(t, tc.expectedTerms, terms)
+						return nil
+					},
+				).Once()
+			}
+
+			r := httptest.NewRequest("PATCH", "/", bytes.NewBufferString(tc.body))
+			routeCtx := chi.NewRouteContext()
+			routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+			ctx = context.WithValue(ctx, slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.FileMetadataPatch(db, c).ServeHTTP(w, r)
+			require.Equal(t, tc.expectedCode, w.Code)
+
+			var resp api.MetadataResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if tc.expectedCode == http.StatusOK {
+				assert.Equal(t, tc.expected, resp.Metadata)
+			} else {
+				require.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, api.ParameterOutOfRange, resp.Errors[0].Code)
+			}
+		})
+	}
+}
+
+func TestFileList_MetadataFilter(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	expectMetadataTerms(c)
+
+	db.EXPECT().GetCollectionVersion(fileOwnerId).Return(1, nil).Once()
+	db.EXPECT().GetFilesByMetadata(fileOwnerId, mock.Anything).RunAndReturn(func(_ int64, terms []string) ([]db_access.File, error) {
+		assert.ElementsMatch(t, []string{"h:meta/album/trip", "h:meta/source/phone"}, terms)
+		return []db_access.File{sourceFile}, nil
+	}).Once()
+	c.EXPECT().DecryptFileNames([]string{sourceFile.FileName}).Return([]string{"report.txt"}, nil).Once()
+
+	r := httptest.NewRequest("GET", "/?meta.album=trip&meta.source=phone&limit=5", nil)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+	w := httptest.NewRecorder()
+	api.FileList(db, c).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.FileListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Files, 1)
+	assert.Equal(t, sourceFile.GeneratedName, resp.Files[0].Id)
+}
+
+func TestFilePut_Metadata(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	expectMetadataTerms(c)
+
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a.txt", nil).Once()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Twice()
+	db.EXPECT().AddFile(mock.Anything, "enc:a.txt", fileOwnerId, int64(3)).Return(nil).Once()
+	db.EXPECT().SetFileMetadata(mock.Anything, fileOwnerId, sealedMetadata(t, map[string]string{"album": "поездка"}), []string{"h:meta/album/поездка"}).Return(nil).Once()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(3), int64(0)).Return(nil).Once()
+
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("new"))
+	r.Header.Set("X-File-Name", "a.txt")
+	r.Header.Set("X-File-Metadata", `{"album":"%D0%BF%D0%BE%D0%B5%D0%B7%D0%B4%D0%BA%D0%B0"}`)
+	r = r.WithContext(context.WithValue(r.Context(), auth.AuthUserId, fileOwnerId))
+	w := serveFilePut(t, db, c, dir, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestFilePut_InvalidMetadata(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("new"))
+	r.Header.Set("X-File-Name", "a.txt")
+	r.Header.Set("X-File-Metadata", `["album"]`)
+	w := serveFilePut(t, db, c, t.TempDir(), r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,130 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/ipfilter"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+func expectDenial(db *db_access_mocks.DbAccess, level string) {
+	db.EXPECT().AddAuditEvent(mock.MatchedBy(func(e *db_access.AuditEvent) bool {
+		return e.Method == "DENY" && e.Path == "network/"+level && e.Status == http.StatusForbidden
+	})).Return(nil).Once()
+}
+
+func TestFilterNetwork(t *testing.T) {
+	rules, err := ipfilter.Parse([]string{"10.0.0.0/8"}, nil)
+	require.NoError(t, err)
+
+	db := db_access_mocks.NewDbAccess(t)
+	expectDenial(db, "global")
+	handler := api.FilterNetwork(db, rules)(okHandler)
+
+	for addr, status := range map[string]int{
+		"10.1.2.3:1234":    http.StatusNoContent,
+		"198.51.100.1:443": http.StatusForbidden,
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = addr
+		r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, status, w.Code, addr)
+	}
+}
+
+func TestFilterUserNetwork(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetRequestNetworkRules(fileOwnerId, "").Return(nil, nil).Once()
+	// an upload key of the office, while the user may use any network
+	db.EXPECT().GetRequestNetworkRules(fileOwnerId, "uploader").Return([]db_access.NetworkRules{
+		{Scope: db_access.NetworkDevice, Subject: "uploader", Allow: []string{"10.0.0.0/8"}},
+	}, nil).Twice()
+	expectDenial(db, "device")
+	handler := api.FilterUserNetwork(db)(okHandler)
+
+	serve := func(deviceId string, addr string) int {
+		r := httptest.NewRequest("POST", "/upload", nil)
+		r.RemoteAddr = addr
+		ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+		ctx = context.WithValue(ctx, auth.AuthUserId, fileOwnerId)
+		if deviceId != "" {
+			ctx = context.WithValue(ctx, auth.AuthDeviceId, deviceId)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve("", "198.51.100.1:443"))
+	assert.Equal(t, http.StatusNoContent, serve("uploader", "10.1.2.3:443"))
+	assert.Equal(t, http.StatusForbidden, serve("uploader", "198.51.100.1:443"))
+}
+
+func TestLoginNetworkFilter(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetRequestNetworkRules(fileOwnerId, "").Return([]db_access.NetworkRules{
+		{Scope: db_access.NetworkUser, Subject: "1", Allow: []string{"10.0.0.0/8"}},
+	}, nil).Twice()
+	db.EXPECT().AddAuditEvent(mock.MatchedBy(func(e *db_access.AuditEvent) bool {
+		return e.UserId == fileOwnerId && e.Path == "network/user" && e.Status == http.StatusForbidden
+	})).Return(nil).Once()
+	filter := api.NewLoginNetworkFilter(db)
+
+	allow := func(addr string) (bool, int) {
+		r := httptest.NewRequest("POST", "/auth/login", nil)
+		r.RemoteAddr = addr
+		r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+		w := httptest.NewRecorder()
+		return filter.AllowLogin(w, r, fileOwnerId, ""), w.Code
+	}
+
+	allowed, _ := allow("10.1.2.3:443")
+	assert.True(t, allowed)
+	allowed, status := allow("198.51.100.1:443")
+	assert.False(t, allowed)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestAdminNetworkRulesSet(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).Return(nil)
+	db.EXPECT().GetDevice("missing").Return(db_access.Device{}, db_access.NoRowsError{}).Once()
+	db.EXPECT().SetNetworkRules(db_access.NetworkRules{
+		Scope:   db_access.NetworkUser,
+		Subject: "3",
+		Allow:   []string{"10.0.0.0/8", "192.0.2.1/32"},
+	}).Return(nil).Once()
+
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Put("/network-rules/{scope}/{subject}", api.AdminNetworkRulesSet(db))
+	serve := func(target string, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("PUT", target, strings.NewReader(body)))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serve("/network-rules/user/3", `{"allow": ["office"]}`))
+	assert.Equal(t, http.StatusNotFound, serve("/network-rules/device/missing", `{}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, serve("/network-rules/org/acme", `{}`))
+	// networks are stored the way they are checked
+	assert.Equal(t, http.StatusNoContent, serve("/network-rules/user/3", `{"allow": ["10.1.2.3/8", "192.0.2.1"]}`))
+}
@@ -0,0 +1,228 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/api"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/policy"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestFilePut_Policies(t *testing.T) {
+	testCases := []struct {
+		name   string
+		file   string
+		size   int
+		status int
+		code   api.ApiErrorCode
+	}{
+		{"Bigger than the user may upload", "a.png", 5, http.StatusRequestEntityTooLarge, api.ParameterOutOfRange},
+		{"Type not allowed", "a.txt", 3, http.StatusUnsupportedMediaType, api.FileTypeNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			db.EXPECT().GetUserPolicies(mock.Anything).Return([]db_access.Policy{
+				{Scope: db_access.GlobalScope, MaxFileSize: ptr(int64(8)), AllowedTypes: []string{"image/*"}},
+				{Scope: db_access.UserScope, Subject: "1", MaxFileSize: ptr(int64(4))},
+			}, nil).Once()
+
+			dir := t.TempDir()
+			cfg := api.UploadConfig{
+				MaxUploadSize: 16,
+				StorageDir:    dir,
+				Space:         storage.Space{Dir: dir},
+				Policies:      policy.NewEvaluator(db, policy.Limits{MaxFileSize: 16}),
+			}
+
+			r := httptest.NewRequest("PUT", "/", bytes.NewReader(make([]byte, tc.size)))
+			r.Header.Set("X-File-Name", tc.file)
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+			w := httptest.NewRecorder()
+			api.FilePut(db, cfg, encryption_mocks.NewCrypter(t)).ServeHTTP(w, r)
+			require.Equal(t, tc.status, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tc.code, resp.Errors[0].Code)
+		})
+	}
+}
+
+func TestFilePut_UploadSizeLimit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		limit    string
+		override *int64
+		size     int
+		status   int
+		code     api.ApiErrorCode
+	}{
+		{"Without header", "", ptr(int64(16)), 6, http.StatusRequestEntityTooLarge, api.ParameterOutOfRange},
+		{"Raised", "8", ptr(int64(16)), 6, http.StatusUnsupportedMediaType, api.FileTypeNotAllowed},
+		{"Raised but still too big", "8", ptr(int64(16)), 10, http.StatusRequestEntityTooLarge, api.ParameterOutOfRange},
+		{"Below max", "2", nil, 3, http.StatusUnsupportedMediaType, api.FileTypeNotAllowed},
+		{"Not granted", "8", nil, 6, http.StatusForbidden, api.ParameterOutOfRange},
+		{"More than granted", "32", ptr(int64(16)), 6, http.StatusForbidden, api.ParameterOutOfRange},
+		{"Invalid", "lots", ptr(int64(16)), 6, http.StatusBadRequest, api.InvalidContentFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			db.EXPECT().GetUserPolicies(mock.Anything).Return([]db_access.Policy{
+				{Scope: db_access.GlobalScope, AllowedTypes: []string{"image/*"}},
+				{Scope: db_access.UserScope, Subject: "1", MaxFileSizeOverride: tc.override},
+			}, nil).Once()
+
+			dir := t.TempDir()
+			cfg := api.UploadConfig{
+				MaxUploadSize: 4,
+				StorageDir:    dir,
+				Space:         storage.Space{Dir: dir},
+				Policies:      policy.NewEvaluator(db, policy.Limits{MaxFileSize: 4}),
+			}
+
+			r := httptest.NewRequest("PUT", "/", bytes.NewReader(make([]byte, tc.size)))
+			r.Header.Set("X-File-Name", "backup.tar")
+			if tc.limit != "" {
+				r.Header.Set("X-Upload-Size-Limit", tc.limit)
+			}
+			r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+			w := httptest.NewRecorder()
+			api.FilePut(db, cfg, encryption_mocks.NewCrypter(t)).ServeHTTP(w, r)
+			require.Equal(t, tc.status, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tc.code, resp.Errors[0].Code)
+		})
+	}
+}
+
+func newPolicyRouter(db *db_access_mocks.DbAccess) http.Handler {
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Get("/policies", api.AdminPolicies(db))
+	r.Put("/policies/{scope}", api.AdminPolicySet(db))
+	r.Put("/policies/{scope}/{subject}", api.AdminPolicySet(db))
+	r.Delete("/policies/{scope}/{subject}", api.AdminPolicyDelete(db))
+	r.Get("/users/{id}/limits", api.AdminUserLimits(db, policy.NewEvaluator(db, policy.Limits{MaxFileSize: 1024})))
+	r.Put("/users/{id}/org", api.AdminUserOrg(db))
+	return r
+}
+
+func servePolicy(h http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestAdminPolicySet(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().SetPolicy(db_access.Policy{
+		Scope:               db_access.OrgScope,
+		Subject:             "acme",
+		Quota:               ptr(int64(1 << 30)),
+		AllowedTypes:        []string{"image/*", "application/pdf"},
+		Retention:           ptr(90 * 24 * time.Hour),
+		MaxFileSizeOverride: ptr(int64(1 << 40)),
+	}).Return(nil).Once()
+	db.EXPECT().GetUser(mock.Anything).Return(db_access.NoRowsError{}).Once()
+
+	h := newPolicyRouter(db)
+
+	w := servePolicy(h, "PUT", "/policies/org/acme", `{"quota_bytes": 1073741824, "allowed_types": ["image/*", "application/pdf"], "retention_seconds": 7776000, "max_file_size_override_bytes": 1099511627776}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	testCases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"Global with subject", "/policies/global/acme", `{}`, http.StatusNotFound},
+		{"Org without name", "/policies/org", `{}`, http.StatusNotFound},
+		{"Unknown scope", "/policies/team/acme", `{}`, http.StatusNotFound},
+		{"User by name", "/policies/user/alice", `{}`, http.StatusBadRequest},
+		{"Unknown user", "/policies/user/42", `{}`, http.StatusNotFound},
+		{"Negative quota", "/policies/global", `{"quota_bytes": -1}`, http.StatusUnprocessableEntity},
+		{"Negative override", "/policies/global", `{"max_file_size_override_bytes": -1}`, http.StatusUnprocessableEntity},
+		{"Invalid type", "/policies/global", `{"allowed_types": ["image"]}`, http.StatusUnprocessableEntity},
+		{"Invalid json", "/policies/global", `{`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := servePolicy(h, "PUT", tc.path, tc.body)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestAdminPolicies(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().ListPolicies().Return([]db_access.Policy{
+		{Scope: db_access.GlobalScope, MaxFileSize: ptr(int64(100))},
+		{Scope: db_access.UserScope, Subject: "7", Retention: ptr(time.Hour)},
+	}, nil).Once()
+	db.EXPECT().DeletePolicy(db_access.UserScope, "7").Return(nil).Once()
+	db.EXPECT().DeletePolicy(db_access.OrgScope, "gone").Return(db_access.NoRowsError{}).Once()
+	db.EXPECT().GetUser(mock.Anything).Return(nil).Once()
+
+	h := newPolicyRouter(db)
+
+	w := servePolicy(h, "GET", "/policies", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.PolicyListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Policies, 2)
+	assert.Equal(t, int64(100), *resp.Policies[0].MaxFileSizeBytes)
+	assert.Nil(t, resp.Policies[0].QuotaBytes)
+	assert.Equal(t, int64(3600), *resp.Policies[1].RetentionSeconds)
+
+	assert.Equal(t, http.StatusNoContent, servePolicy(h, "DELETE", "/policies/user/7", "").Code)
+	assert.Equal(t, http.StatusNotFound, servePolicy(h, "DELETE", "/policies/org/gone", "").Code)
+}
+
+func TestAdminUserLimits(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).Return(nil).Once()
+	db.EXPECT().GetUserPolicies(int64(7)).Return([]db_access.Policy{
+		{Scope: db_access.GlobalScope, Quota: ptr(int64(500)), AllowedTypes: []string{"text/plain"}},
+		{Scope: db_access.OrgScope, Subject: "acme", Quota: ptr(int64(1000)), AllowedTypes: []string{}, MaxFileSizeOverride: ptr(int64(4096))},
+	}, nil).Once()
+	db.EXPECT().SetUserOrg(int64(7), "acme").Return(nil).Once()
+
+	h := newPolicyRouter(db)
+
+	w := servePolicy(h, "GET", "/users/7/limits", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.LimitsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, api.LimitsResponse{QuotaBytes: 1000, MaxFileSizeBytes: 1024, MaxFileSizeOverrideBytes: 4096}, resp)
+
+	assert.Equal(t, http.StatusNoContent, servePolicy(h, "PUT", "/users/7/org", `{"org": "acme"}`).Code)
+}
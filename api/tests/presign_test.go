@@ -0,0 +1,425 @@
+package api_test
+
+import (
+	"bytes"
+	"cloud-storage/access"
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/filecache"
+	"cloud-storage/geoip"
+	"cloud-storage/presign"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newPresignRouter(t *testing.T, db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter, dir string, files *filecache.Cache) http.Handler {
+	return newGeoPresignRouter(t, db, c, dir, files, nil)
+}
+
+func newGeoPresignRouter(
+	t *testing.T,
+	db *db_access_mocks.DbAccess,
+	c *encryption_mocks.Crypter,
+	dir string,
+	files *filecache.Cache,
+	geo geoip.Provider,
+) http.Handler {
+	s, err := presign.New(presign.Config{TimeToLive: time.Minute, MaxTimeToLive: time.Hour})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	owner := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.AuthUserId, fileOwnerId)))
+		})
+	}
+	r.With(owner).Post("/api/files/{id}/presign", api.FilePresign(db, s, geo))
+	r.With(owner).Get("/api/shares/{id}/stats", api.ShareStats(s))
+	r.With(api.PresignedAuth(s, db, geo, 1, 2)).Get("/api/presigned/files/{id}", api.FileRaw(
+		db,
+		c,
+		blobstore.NewStore(dir, storage.DurabilityNone, nil),
+		files,
+		access.New(db, slogext.NewDiscardLogger()),
+		64,
+	))
+
+	return r
+}
+
+func presignFile(t *testing.T, h http.Handler, body string) (int, api.PresignResponse) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/api/files/"+sourceFile.GeneratedName+"/presign", strings.NewReader(body)))
+
+	var resp api.PresignResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestFilePresign(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil)
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, io.MultiReader(strings.NewReader("decrypted "), r))
+		return err
+	}).Once()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(0), int64(len("decrypted enc"))).Return(nil).Once()
+
+	h := newPresignRouter(t, db, c, dir, nil)
+
+	status, resp := presignFile(t, h, `{"one_time": true}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Greater(t, resp.ExpiresAt, time.Now().Unix())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", resp.Url, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "decrypted enc", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `inline; filename=report.txt`, w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", resp.Url, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestFilePresign_FailedDownloadNotCounted(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil)
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Twice()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(0), int64(len("enc"))).Return(nil).Once()
+
+	h := newPresignRouter(t, db, c, dir, nil)
+	status, resp := presignFile(t, h, `{"one_time": true, "max_downloads": 1}`)
+	require.Equal(t, http.StatusOK, status)
+
+	// the blob can't be opened yet, which leaves the link as it was
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", resp.Url, nil))
+	assert.NotEqual(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, http.StatusForbidden, w.Code)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", resp.Url, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "enc", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", resp.Url, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestFilePresign_Errors(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	h := newPresignRouter(t, db, encryption_mocks.NewCrypter(t), t.TempDir(), nil)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Once()
+	status, resp := presignFile(t, h, `{"ttl_seconds": 7200}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, api.ParameterOutOfRange, resp.Errors[0].Code)
+
+	otherFile := sourceFile
+	otherFile.OwnerId = fileOwnerId + 1
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(otherFile, nil).Once()
+	status, _ = presignFile(t, h, "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api/presigned/files/src?user=7&expires=9999999999&signature=00", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.True(t, bytes.Contains(w.Body.Bytes(), []byte("Invalid or expired link")))
+}
+
+func TestFilePresign_Cached(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+	files := filecache.New(10)
+
+	// once to presign and once for the first download only
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Twice()
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Times(3)
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(0), int64(len("enc"))).Return(nil).Times(3)
+
+	h := newPresignRouter(t, db, c, dir, files)
+
+	status, resp := presignFile(t, h, "")
+	require.Equal(t, http.StatusOK, status)
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", resp.Url, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `inline; filename=report.txt`, w.Header().Get("Content-Disposition"))
+	}
+
+	assert.Equal(t, filecache.Stats{Hits: 2, Misses: 1, Size: 1}, files.Stats())
+}
+
+func TestFilePresign_Restricted(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+	// requests of httptest come from 192.0.2.1
+	geo, err := geoip.ReadTable(strings.NewReader("192.0.2.0/24,DE\n"))
+	require.NoError(t, err)
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil)
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(0), int64(len("enc"))).Return(nil).Once()
+
+	h := newGeoPresignRouter(t, db, c, dir, nil, geo)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	status, resp := presignFile(t, h, `{"countries": ["de", "AT"], "networks": ["192.0.2.0/24"]}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, get(resp.Url).Code)
+
+	testCases := []struct {
+		body string
+		code api.ApiErrorCode
+	}{
+		{body: `{"countries": ["FR"]}`, code: api.CountryDenied},
+		{body: `{"networks": ["10.0.0.0/8"]}`, code: api.NetworkDenied},
+		{body: `{"one_time": true, "countries": ["FR"]}`, code: api.CountryDenied},
+		{body: `{"one_time": true, "networks": ["10.0.0.0/8"]}`, code: api.NetworkDenied},
+	}
+	for _, tc := range testCases {
+		status, resp := presignFile(t, h, tc.body)
+		require.Equal(t, http.StatusOK, status)
+
+		db.EXPECT().AddAuditEvent(mock.MatchedBy(func(e *db_access.AuditEvent) bool {
+			return e.Method == "DENY" && e.UserId == fileOwnerId &&
+				e.Path == "presigned/"+sourceFile.GeneratedName+"/"+resp.LinkId
+		})).Return(nil).Twice()
+
+		// a denied use doesn't use up a one-time link, so it is denied for the same reason again
+		for range 2 {
+			w := get(resp.Url)
+			assert.Equal(t, http.StatusForbidden, w.Code, tc.body)
+			var errResp api.PresignResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			require.Len(t, errResp.Errors, 1)
+			assert.Equal(t, tc.code, errResp.Errors[0].Code, tc.body)
+		}
+	}
+}
+
+func TestFilePresign_InvalidRestriction(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	geo, err := geoip.ReadTable(strings.NewReader(""))
+	require.NoError(t, err)
+
+	for _, body := range []string{`{"countries": ["Germany"]}`, `{"networks": ["office"]}`} {
+		status, _ := presignFile(t, newGeoPresignRouter(t, db, encryption_mocks.NewCrypter(t), t.TempDir(), nil, geo), body)
+		assert.Equal(t, http.StatusUnprocessableEntity, status, body)
+	}
+
+	// countries can't be checked without a GeoIP table
+	status, resp := presignFile(t, newPresignRouter(t, db, encryption_mocks.NewCrypter(t), t.TempDir(), nil), `{"countries": ["DE"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "countries", resp.Errors[0].ParamName)
+}
+
+func TestFilePresign_Password(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil)
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Once()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(0), int64(len("enc"))).Return(nil).Once()
+
+	// wrong guesses are limited to bursts of two
+	h := newPresignRouter(t, db, c, dir, nil)
+
+	get := func(url string, password string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		if password != "" {
+			r.SetBasicAuth("", password)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	status, resp := presignFile(t, h, `{"password": "secret"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, resp.Protected)
+
+	w := get(resp.Url, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+
+	db.EXPECT().AddAuditEvent(mock.MatchedBy(func(e *db_access.AuditEvent) bool {
+		return e.Method == "DENY" && e.Status == http.StatusUnauthorized &&
+			e.Path == "presigned/"+sourceFile.GeneratedName+"/"+resp.LinkId
+	})).Return(nil).Twice()
+	assert.Equal(t, http.StatusUnauthorized, get(resp.Url, "guess").Code)
+	assert.Equal(t, http.StatusUnauthorized, get(resp.Url, "guess").Code)
+
+	w = get(resp.Url, "secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// the limit is per link
+	status, resp = presignFile(t, h, `{"password": "secret"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, get(resp.Url, "secret").Code)
+}
+
+func TestFilePresign_ConcurrentGuesses(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil)
+	db.EXPECT().AddAuditEvent(mock.Anything).Return(nil).Maybe()
+	h := newPresignRouter(t, db, encryption_mocks.NewCrypter(t), t.TempDir(), nil)
+
+	status, resp := presignFile(t, h, `{"password": "secret"}`)
+	require.Equal(t, http.StatusOK, status)
+
+	const guesses = 10
+	var wg sync.WaitGroup
+	codes := make(chan int, guesses)
+	for range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest("GET", resp.Url, nil)
+			r.SetBasicAuth("", "guess")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	// guesses in flight together still only get the burst of two through to the password,
+	// and maybe one more if a token came back while they ran
+	compared := 0
+	for code := range codes {
+		if code == http.StatusUnauthorized {
+			compared++
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, code)
+		}
+	}
+	assert.GreaterOrEqual(t, compared, 2)
+	assert.LessOrEqual(t, compared, 3)
+}
+
+func TestShareStats(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, sourceFile.BlobName), []byte("enc"), 0o600))
+
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil)
+	c.EXPECT().DecryptFileName(sourceFile.FileName).Return("report.txt", nil).Twice()
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Twice()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(0), int64(len("enc"))).Return(nil).Twice()
+
+	h := newPresignRouter(t, db, c, dir, nil)
+
+	status, resp := presignFile(t, h, `{"max_downloads": 2}`)
+	require.Equal(t, http.StatusOK, status)
+
+	stats := func(linkId string) (int, api.ShareStatsResponse) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/api/shares/"+linkId+"/stats", nil))
+		var resp api.ShareStatsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	status, before := stats(resp.LinkId)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, api.ShareStatsResponse{
+		LinkId:       resp.LinkId,
+		FileId:       sourceFile.GeneratedName,
+		ExpiresAt:    resp.ExpiresAt,
+		MaxDownloads: 2,
+	}, before)
+
+	for _, addr := range []string{"192.0.2.1:1000", "192.0.2.1:2000", "192.0.2.2:1000"} {
+		r := httptest.NewRequest("GET", resp.Url, nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if addr == "192.0.2.2:1000" {
+			// past the limit of two downloads
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		} else {
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+	}
+
+	status, after := stats(resp.LinkId)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(2), after.Downloads)
+	assert.Equal(t, 1, after.UniqueClients)
+	assert.True(t, after.Disabled)
+	assert.InDelta(t, time.Now().Unix(), after.LastAccess, 2)
+
+	status, _ = stats("unknown")
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func servePrivacy(db *db_access_mocks.DbAccess, method string, path string, body io.Reader) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId)))
+		})
+	})
+	r.Get("/consents", api.Consents(db))
+	r.Put("/consents/{purpose}", api.ConsentGive(db))
+	r.Delete("/consents/{purpose}", api.ConsentWithdraw(db))
+	r.Post("/account/deletion", api.AccountDeletion(db, time.Hour))
+	r.Get("/account/deletion", api.AccountDeletionStatus(db))
+	r.Get("/admin/deletion-requests", api.AdminDeletionRequests(db))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, body))
+	return w
+}
+
+func TestConsents(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().AddConsent(mock.MatchedBy(func(c db_access.Consent) bool {
+		return c.UserId == fileOwnerId && c.Purpose == "terms" && c.Version == "2024-01"
+	})).Return(nil).Once()
+	db.EXPECT().AddConsent(mock.MatchedBy(func(c db_access.Consent) bool {
+		return c.Purpose == "terms" && c.Version == ""
+	})).Return(nil).Once()
+	db.EXPECT().GetConsents(fileOwnerId).Return([]db_access.Consent{
+		{UserId: fileOwnerId, Purpose: "terms", Version: "2024-01", At: db_access.Time(time.Unix(1, 0))},
+		{UserId: fileOwnerId, Purpose: "terms", At: db_access.Time(time.Unix(2, 0))},
+	}, nil).Once()
+
+	w := servePrivacy(db, "PUT", "/consents/terms", strings.NewReader(`{"version": "2024-01"}`))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// giving consent takes a version
+	w = servePrivacy(db, "PUT", "/consents/terms", strings.NewReader(`{}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = servePrivacy(db, "DELETE", "/consents/terms", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = servePrivacy(db, "GET", "/consents", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.ConsentsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []api.ConsentInfo{
+		{Purpose: "terms", Version: "2024-01", At: 1},
+		{Purpose: "terms", At: 2},
+	}, resp.Consents)
+}
+
+func TestAccountDeletion(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().AddDeletionRequest(mock.MatchedBy(func(req db_access.DeletionRequest) bool {
+		return req.UserId == fileOwnerId && time.Time(req.Deadline).Sub(time.Time(req.RequestedAt)) == time.Hour
+	})).Return(nil).Once()
+	db.EXPECT().AddDeletionRequest(mock.Anything).Return(db_access.UniqueConstraintError{}).Once()
+	db.EXPECT().GetDeletionRequest(fileOwnerId).Return(db_access.DeletionRequest{}, db_access.NoRowsError{}).Once()
+
+	w := servePrivacy(db, "GET", "/account/deletion", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = servePrivacy(db, "POST", "/account/deletion", nil)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var info api.DeletionInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, fileOwnerId, info.UserId)
+	assert.False(t, info.Overdue)
+
+	w = servePrivacy(db, "POST", "/account/deletion", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestAdminDeletionRequests(t *testing.T) {
+	now := time.Now()
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetPendingDeletionRequests().Return([]db_access.DeletionRequest{
+		{UserId: 1, RequestedAt: db_access.Time(now.Add(-48 * time.Hour)), Deadline: db_access.Time(now.Add(-time.Hour))},
+		{UserId: 2, RequestedAt: db_access.Time(now), Deadline: db_access.Time(now.Add(time.Hour))},
+	}, nil).Once()
+
+	w := servePrivacy(db, "GET", "/admin/deletion-requests", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.DeletionRequestsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Requests, 2)
+	assert.True(t, resp.Requests[0].Overdue)
+	assert.False(t, resp.Requests[1].Overdue)
+}
@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	"cloud-storage/utils/problem"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveInvalidPreview(t *testing.T, accept string) *httptest.ResponseRecorder {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	db.EXPECT().GetFile(sourceFile.GeneratedName).Return(sourceFile, nil).Maybe()
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", sourceFile.GeneratedName)
+
+	r := httptest.NewRequest("GET", "/api/files/file/preview?bytes=-1", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	problem.Negotiate(api.FilePreview(db, c, blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil), nil)).ServeHTTP(w, r)
+	return w
+}
+
+func TestProblemDetails(t *testing.T) {
+	w := serveInvalidPreview(t, "application/json, application/problem+json;q=0.9")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var details problem.Details
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+	assert.Equal(t, api.ProblemTypeURI(api.ParameterOutOfRange), details.Type)
+	assert.Equal(t, http.StatusUnprocessableEntity, details.Status)
+	assert.Equal(t, "/api/files/file/preview", details.Instance)
+	assert.Equal(t, int(api.ParameterOutOfRange), details.Code)
+	require.Len(t, details.Errors, 1)
+	assert.Equal(t, "bytes", details.Errors[0].Name)
+}
+
+func TestProblemDetailsLegacyByDefault(t *testing.T) {
+	for _, accept := range []string{"", "application/json", "application/problem+json;q=0"} {
+		w := serveInvalidPreview(t, accept)
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.NotEqual(t, problem.ContentType, w.Header().Get("Content-Type"), accept)
+
+		var resp api.UploadResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), accept)
+		require.Len(t, resp.Errors, 1, accept)
+		assert.Equal(t, api.ParameterOutOfRange, resp.Errors[0].Code, accept)
+	}
+}
@@ -0,0 +1,143 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		quota    api.Quota
+		used     int64
+		expected api.QuotaResponse
+		warns    bool
+	}{
+		{
+			name:     "Unlimited",
+			used:     1000,
+			expected: api.QuotaResponse{Unlimited: true, UsedBytes: 1000},
+		},
+		{
+			name:     "Below warning",
+			quota:    api.Quota{Limit: 1000, WarnAt: 0.9},
+			used:     100,
+			expected: api.QuotaResponse{LimitBytes: 1000, UsedBytes: 100, RemainingBytes: 900},
+		},
+		{
+			name:     "Past warning",
+			quota:    api.Quota{Limit: 1000, WarnAt: 0.9},
+			used:     950,
+			expected: api.QuotaResponse{LimitBytes: 1000, UsedBytes: 950, RemainingBytes: 50},
+			warns:    true,
+		},
+		{
+			name:     "Over limit",
+			quota:    api.Quota{Limit: 1000, WarnAt: 0.9},
+			used:     1200,
+			expected: api.QuotaResponse{LimitBytes: 1000, UsedBytes: 1200},
+			warns:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{StoredBytes: tc.used}, nil).Once()
+
+			r := httptest.NewRequest("GET", "/", nil)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.QuotaStatus(db, tc.quota).ServeHTTP(w, r)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp api.QuotaResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.warns, len(resp.Warnings) == 1)
+			assert.Equal(t, resp.Warnings, w.Header().Values("X-Storage-Warning"))
+
+			resp.Warnings = nil
+			assert.Equal(t, tc.expected, resp)
+		})
+	}
+}
+
+func TestFilePut_Quota(t *testing.T) {
+	testCases := []struct {
+		name   string
+		used   int64
+		status int
+		warns  bool
+	}{
+		{name: "Plenty left", used: 10, status: http.StatusCreated},
+		{name: "Close to the limit", used: 85, status: http.StatusCreated, warns: true},
+		{name: "Exactly at the limit", used: 89, status: http.StatusCreated, warns: true},
+		{name: "Over the limit", used: 90, status: http.StatusInsufficientStorage, warns: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			expectTx(db)
+			c := encryption_mocks.NewCrypter(t)
+			expectNameIndex(db, c)
+			dir := t.TempDir()
+
+			db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{StoredBytes: tc.used}, nil).Once()
+			if tc.status == http.StatusCreated {
+				c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Once()
+				db.EXPECT().AddFile(mock.Anything, "enc:a", fileOwnerId, int64(11)).Return(nil).Once()
+				c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+					_, err := io.Copy(w, r)
+					return err
+				}).Once()
+				db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(11), int64(0)).Return(nil).Maybe()
+			}
+
+			cfg := api.UploadConfig{
+				MaxUploadSize: 16,
+				StorageDir:    dir,
+				Space:         storage.Space{Dir: dir},
+				Quota:         api.Quota{Limit: 100, WarnAt: 0.9},
+			}
+
+			r := httptest.NewRequest("PUT", "/", strings.NewReader("raw content"))
+			r.Header.Set("X-File-Name", "a.txt")
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			api.FilePut(db, cfg, c).ServeHTTP(w, r)
+			require.Equal(t, tc.status, w.Code)
+
+			var resp api.UploadResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.warns, len(w.Header().Values("X-Storage-Warning")) == 1)
+
+			if tc.status != http.StatusCreated {
+				require.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, api.QuotaExceeded, resp.Errors[0].Code)
+				return
+			}
+			assert.Equal(t, w.Header().Values("X-Storage-Warning"), resp.Warnings)
+		})
+	}
+}
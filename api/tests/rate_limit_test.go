@@ -0,0 +1,53 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	h := api.RateLimit(0.001, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send("198.51.100.1:1000").Code)
+	// the port doesn't make another client
+	assert.Equal(t, http.StatusOK, send("198.51.100.1:2000").Code)
+
+	w := send("198.51.100.1:3000")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	require.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, api.TooManyRequests, resp.Errors[0].Code)
+
+	assert.Equal(t, http.StatusOK, send("198.51.100.2:1000").Code)
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	h := api.RateLimit(0, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for range 10 {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
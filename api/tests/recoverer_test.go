@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/utils/problem"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func servePanic(t *testing.T, accept string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, middleware.RequestIDKey, "req-1"))
+
+	w := httptest.NewRecorder()
+	problem.Negotiate(api.Recoverer(h)).ServeHTTP(w, r)
+	return w
+}
+
+func panicCount(t *testing.T) int {
+	t.Helper()
+
+	n, err := strconv.Atoi(expvar.Get("http_panics").String())
+	require.NoError(t, err)
+	return n
+}
+
+func TestRecoverer(t *testing.T) {
+	before := panicCount(t)
+
+	w := servePanic(t, "", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var resp api.UploadResponse
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &resp))
+	require.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, api.InternalApiError, resp.Errors[0].Code)
+	assert.Equal(t, "req-1", resp.RequestId)
+	assert.Equal(t, before+1, panicCount(t))
+}
+
+func TestRecoverer_Problem(t *testing.T) {
+	w := servePanic(t, problem.ContentType, func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var details problem.Details
+	require.NoError(t, json.Unmarshal(readResponseBody(t, w), &details))
+	assert.Equal(t, api.ProblemTypeURI(api.InternalApiError), details.Type)
+	assert.Equal(t, "req-1", details.RequestId)
+}
+
+func TestRecoverer_AfterHeader(t *testing.T) {
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		servePanic(t, "", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			panic("boom")
+		})
+	})
+}
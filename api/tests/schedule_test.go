@@ -0,0 +1,57 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/scheduler"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminSchedule(t *testing.T) {
+	s := scheduler.New(scheduler.Config{}, slogext.NewDiscardLogger())
+	s.Add(scheduler.Task{Name: "orphan-gc", Interval: time.Millisecond, Run: func(ctx context.Context, now time.Time) error {
+		return errors.New("permission denied")
+	}})
+	s.Add(scheduler.Task{Name: "dec-prune"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	require.Eventually(t, func() bool {
+		return s.Status()[0].Runs > 0
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+
+	r := httptest.NewRequest("GET", "/api/admin/schedule", nil)
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+	w := httptest.NewRecorder()
+	api.AdminSchedule(s).ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.ScheduleResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Tasks, 2)
+
+	gc := resp.Tasks[0]
+	assert.Equal(t, "orphan-gc", gc.Name)
+	assert.True(t, gc.Enabled)
+	assert.Positive(t, gc.Runs)
+	assert.Equal(t, gc.Runs, gc.Failures)
+	assert.Equal(t, "permission denied", gc.LastError)
+	assert.NotZero(t, gc.LastStart)
+
+	prune := resp.Tasks[1]
+	assert.Equal(t, "dec-prune", prune.Name)
+	assert.False(t, prune.Enabled)
+	assert.Zero(t, prune.Interval)
+	assert.Zero(t, prune.Next)
+}
@@ -0,0 +1,196 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireTraffic(t *testing.T) {
+	testCases := []struct {
+		name      string
+		caps      api.TrafficCaps
+		direction api.TrafficDirection
+		usage     db_access.Usage
+		status    int
+	}{
+		{
+			name:      "Below the upload cap",
+			caps:      api.TrafficCaps{Upload: 100},
+			direction: api.UploadTraffic,
+			usage:     db_access.Usage{UploadedBytes: 99, DownloadedBytes: 1000},
+			status:    http.StatusOK,
+		},
+		{
+			name:      "Upload cap used up",
+			caps:      api.TrafficCaps{Upload: 100},
+			direction: api.UploadTraffic,
+			usage:     db_access.Usage{UploadedBytes: 100},
+			status:    http.StatusTooManyRequests,
+		},
+		{
+			name:      "Below the download cap",
+			caps:      api.TrafficCaps{Upload: 10, Download: 100},
+			direction: api.DownloadTraffic,
+			usage:     db_access.Usage{UploadedBytes: 1000, DownloadedBytes: 50},
+			status:    http.StatusOK,
+		},
+		{
+			name:      "Download cap used up",
+			caps:      api.TrafficCaps{Download: 100},
+			direction: api.DownloadTraffic,
+			usage:     db_access.Usage{DownloadedBytes: 150},
+			status:    http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(tc.usage, nil).Once()
+
+			h := api.RequireTraffic(db, tc.caps, tc.direction)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest("GET", "/", nil)
+			ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+			r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, tc.status, w.Code)
+
+			if tc.status != http.StatusOK {
+				var resp api.UploadResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				require.Equal(t, 1, len(resp.Errors))
+				assert.Equal(t, api.TrafficCapExceeded, resp.Errors[0].Code)
+			}
+		})
+	}
+}
+
+func TestRequireTraffic_NoCap(t *testing.T) {
+	// without a cap the counters are not even looked at
+	db := db_access_mocks.NewDbAccess(t)
+
+	called := false
+	h := api.RequireTraffic(db, api.TrafficCaps{Upload: 100}, api.DownloadTraffic)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.True(t, called)
+}
+
+func TestUsage_Caps(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{UploadedBytes: 5}, nil).Once()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	api.Usage(db, api.TrafficCaps{Upload: 100, Download: 200}).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.UsageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(5), resp.UploadedBytes)
+	assert.Equal(t, int64(100), resp.UploadCapBytes)
+	assert.Equal(t, int64(200), resp.DownloadCapBytes)
+}
+
+func TestAdminTraffic(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	db.EXPECT().GetTrafficByUser(db_access.Time(may)).Return([]db_access.UserTraffic{
+		{OwnerId: 2, Name: "bob", UploadedBytes: 70, DownloadedBytes: 30},
+		{OwnerId: 1, Name: "alice", DownloadedBytes: 5},
+	}, nil).Once()
+
+	r := httptest.NewRequest("GET", "/?month=2024-05", nil)
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	api.AdminTraffic(db).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp api.TrafficReportResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, api.TrafficReportResponse{
+		Month: "2024-05",
+		Users: []api.UserTrafficResponse{
+			{UserId: 2, Name: "bob", UploadedBytes: 70, DownloadedBytes: 30},
+			{UserId: 1, Name: "alice", DownloadedBytes: 5},
+		},
+	}, resp)
+}
+
+func TestAdminTraffic_InvalidMonth(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+
+	r := httptest.NewRequest("GET", "/?month=May", nil)
+	r = r.WithContext(context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger()))
+
+	w := httptest.NewRecorder()
+	api.AdminTraffic(db).ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp api.TrafficReportResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, len(resp.Errors))
+	assert.Equal(t, "month", resp.Errors[0].ParamName)
+}
+
+func TestFilePut_CountsPartialTraffic(t *testing.T) {
+	// an upload that breaks off still used the bandwidth it got through
+	db := db_access_mocks.NewDbAccess(t)
+	expectTx(db)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	expectNameIndex(db, c)
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Once()
+	db.EXPECT().AddFile(mock.Anything, "enc:a", fileOwnerId, int64(20)).Return(nil).Once()
+	db.EXPECT().RemoveFile(mock.Anything).Return(nil).Once()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, int64(11), int64(0)).Return(nil).Once()
+
+	cfg := api.UploadConfig{
+		MaxUploadSize: 32,
+		StorageDir:    dir,
+		Space:         storage.Space{Dir: dir},
+	}
+
+	r := httptest.NewRequest("PUT", "/", strings.NewReader("raw content"))
+	r.ContentLength = 20
+	r.Header.Set("X-File-Name", "a.txt")
+	ctx := context.WithValue(r.Context(), slogext.Log, slogext.NewDiscardLogger())
+	r = r.WithContext(context.WithValue(ctx, auth.AuthUserId, fileOwnerId))
+
+	w := httptest.NewRecorder()
+	api.FilePut(db, cfg, c).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
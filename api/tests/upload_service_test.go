@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newUploadService(t *testing.T, db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter, dir string) *api.UploadService {
+	expectTx(db)
+	expectNameIndex(db, c)
+	db.EXPECT().AddTraffic(fileOwnerId, mock.Anything, mock.Anything, int64(0)).Return(nil).Maybe()
+	return api.NewUploadService(db, api.UploadConfig{
+		MaxUploadSize: 16,
+		StorageDir:    dir,
+		Space:         storage.Space{Dir: dir},
+		Quota:         api.Quota{Limit: 32, WarnAt: 0.5},
+	}, c)
+}
+
+func TestUploadService_Upload(t *testing.T) {
+	ctx := context.WithValue(context.Background(), slogext.Log, slogext.NewDiscardLogger())
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+
+	db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{StoredBytes: 10}, nil).Once()
+	c.EXPECT().EncryptFileName("a.txt").Return("enc:a", nil).Once()
+	db.EXPECT().AddFile(mock.Anything, "enc:a", fileOwnerId, int64(7)).Return(nil).Once()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	// the size is only known once the content is read
+	meta := api.UploadMeta{OwnerId: fileOwnerId, FileName: "a.txt", Size: -1, MaxSize: 16}
+	result, err := newUploadService(t, db, c, dir).Upload(ctx, meta, strings.NewReader("content"))
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", result.FileName)
+	assert.Equal(t, int64(7), result.Size)
+	assert.Len(t, result.Warnings, 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, result.Id))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestUploadService_Errors(t *testing.T) {
+	ctx := context.WithValue(context.Background(), slogext.Log, slogext.NewDiscardLogger())
+
+	t.Run("Quota exceeded", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{StoredBytes: 30}, nil).Once()
+
+		meta := api.UploadMeta{OwnerId: fileOwnerId, FileName: "a.txt", Size: 3}
+		_, err := newUploadService(t, db, encryption_mocks.NewCrypter(t), t.TempDir()).Upload(ctx, meta, strings.NewReader("abc"))
+
+		var ue api.UploadError
+		require.ErrorAs(t, err, &ue)
+		assert.Equal(t, api.QuotaExceeded, ue.Code)
+		assert.Equal(t, http.StatusInsufficientStorage, ue.Status)
+		assert.NotEmpty(t, ue.Warnings)
+	})
+
+	t.Run("Content beyond max size", func(t *testing.T) {
+		db := db_access_mocks.NewDbAccess(t)
+		c := encryption_mocks.NewCrypter(t)
+		db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{}, nil).Once()
+		c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+			_, err := io.Copy(w, r)
+			return err
+		}).Once()
+
+		meta := api.UploadMeta{OwnerId: fileOwnerId, FileName: "a.txt", Size: -1, MaxSize: 4}
+		_, err := newUploadService(t, db, c, t.TempDir()).Upload(ctx, meta, strings.NewReader("too long"))
+
+		var ue api.UploadError
+		require.ErrorAs(t, err, &ue)
+		assert.Equal(t, api.TooBigContentSize, ue.Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, ue.Status)
+	})
+}
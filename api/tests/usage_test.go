@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newUsageRouter(db *db_access_mocks.DbAccess, userId int64) http.Handler {
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.AuthUserId, userId)))
+		})
+	})
+
+	r.Get("/usage", api.Usage(db, api.TrafficCaps{}))
+	r.With(auth.Admin(db, []string{"root"})).Get("/admin/usage", api.AdminUsage(db))
+
+	return r
+}
+
+func getUsage(t *testing.T, h http.Handler, path string) (int, api.UsageResponse) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	var resp api.UsageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestUsage(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUsage(fileOwnerId, mock.Anything).Return(db_access.Usage{
+		Users:           1,
+		Files:           3,
+		StoredBytes:     300,
+		UploadedBytes:   200,
+		DownloadedBytes: 100,
+	}, nil).Once()
+
+	status, resp := getUsage(t, newUsageRouter(db, fileOwnerId), "/usage")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, api.UsageResponse{
+		Files:           3,
+		StoredBytes:     300,
+		Month:           time.Now().UTC().Format("2006-01"),
+		UploadedBytes:   200,
+		DownloadedBytes: 100,
+	}, resp)
+}
+
+func TestAdminUsage(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		user.Name = map[int64]string{1: "root", 2: "alice"}[user.Id]
+		return nil
+	})
+	db.EXPECT().GetTotalUsage(mock.Anything).Return(db_access.Usage{Users: 2, Files: 5}, nil).Once()
+
+	status, resp := getUsage(t, newUsageRouter(db, 1), "/admin/usage")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(2), resp.Users)
+	assert.Equal(t, int64(5), resp.Files)
+
+	status, _ = getUsage(t, newUsageRouter(db, 2), "/admin/usage")
+	assert.Equal(t, http.StatusForbidden, status)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TrafficCaps limit the bytes each user may transfer per calendar month in UTC; zero means no cap
+type TrafficCaps struct {
+	Upload   int64
+	Download int64
+}
+
+type TrafficDirection int
+
+const (
+	UploadTraffic TrafficDirection = iota
+	DownloadTraffic
+)
+
+type TrafficReportResponse struct {
+	// calendar month in UTC, e.g. 2024-05
+	Month string                `json:"month"`
+	Users []UserTrafficResponse `json:"users"`
+	ErrorHolder
+}
+
+type UserTrafficResponse struct {
+	UserId          int64  `json:"user_id"`
+	Name            string `json:"name"`
+	UploadedBytes   int64  `json:"uploaded_bytes"`
+	DownloadedBytes int64  `json:"downloaded_bytes"`
+}
+
+// RequireTraffic answers 429 once the user has used up the monthly cap of the direction.
+// The cap is checked before a transfer starts, so the transfer that crosses it still completes.
+func RequireTraffic(db db_access.UsageRepo, caps TrafficCaps, direction TrafficDirection) func(http.Handler) http.Handler {
+	limit, name := caps.Upload, "upload"
+	if direction == DownloadTraffic {
+		limit, name = caps.Download, "download"
+	}
+
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "api.RequireTraffic"
+			log := slogext.LogWithOp(op, r.Context())
+
+			usage, err := db.GetUsage(auth.UserId(r.Context()), db_access.Time(time.Now()))
+			if err != nil {
+				log.Error("Could not get usage from db", slogext.Error(err))
+				writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+				return
+			}
+
+			used := usage.UploadedBytes
+			if direction == DownloadTraffic {
+				used = usage.DownloadedBytes
+			}
+
+			if used >= limit {
+				errorMsg := fmt.Sprintf("Monthly %s traffic cap reached: %d of %d bytes used", name, used, limit)
+				log.Info(errorMsg)
+				if err := writeError(w, TrafficCapExceeded, errorMsg, http.StatusTooManyRequests); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminTraffic lists the traffic of every user in the month given as ?month=2024-05, the current one by default;
+// it has to be mounted behind auth.Admin
+func AdminTraffic(db db_access.UsageRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminTraffic"
+		log := slogext.LogWithOp(op, r.Context())
+
+		month := time.Now().UTC()
+		if param := r.URL.Query().Get("month"); param != "" {
+			parsed, err := time.Parse("2006-01", param)
+			if err != nil {
+				errorMsg := "month has to look like 2024-05"
+				log.Error(errorMsg, slogext.Error(err))
+				writeParamError(w, InvalidContentFormat, "month", errorMsg, http.StatusBadRequest)
+				return
+			}
+			month = parsed
+		}
+
+		traffic, err := db.GetTrafficByUser(db_access.Time(month))
+		if err != nil {
+			log.Error("Could not get traffic from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := TrafficReportResponse{
+			Month: month.Format("2006-01"),
+			Users: make([]UserTrafficResponse, 0, len(traffic)),
+		}
+		for _, t := range traffic {
+			resp.Users = append(resp.Users, UserTrafficResponse{
+				UserId:          t.OwnerId,
+				Name:            t.Name,
+				UploadedBytes:   t.UploadedBytes,
+				DownloadedBytes: t.DownloadedBytes,
+			})
+		}
+
+		if err := writeResponse(w, resp, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
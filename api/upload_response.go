@@ -1,6 +1,7 @@
 package api
 
 import (
+	"cloud-storage/utils/problem"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +11,38 @@ type UploadResponse struct {
 	Id       string     `json:"id,omitempty"`
 	FileName string     `json:"file_name,omitempty"`
 	FilePath string     `json:"file_path,omitempty"`
+	// unix seconds, omitted for files without expiry
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+	// set when the user is close to their storage quota
+	Warnings []string `json:"warnings,omitempty"`
+	ErrorHolder
+}
+
+type FileInfo struct {
+	Id        string `json:"id"`
+	FileName  string `json:"file_name"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	// Tier is the storage backend holding the file
+	Tier          string `json:"tier"`
+	LastAccessAt  int64  `json:"last_access_at,omitempty"`
+	DownloadCount int64  `json:"download_count"`
+	// FolderId is the folder the file is in, see Folders; omitted for the root
+	FolderId int64 `json:"folder_id,omitempty"`
+}
+
+type FileListResponse struct {
+	Files []FileInfo `json:"files"`
+	ErrorHolder
+}
+
+type SearchResult struct {
+	FileInfo
+	// Matches says what matched the query: the name, the content or both
+	Matches []string `json:"matches"`
+}
+
+type SearchResponse struct {
+	Files []SearchResult `json:"files"`
 	ErrorHolder
 }
 
@@ -17,6 +50,62 @@ type DownloadResponse struct {
 	ErrorHolder
 }
 
+type ExportResponse struct {
+	Id          string `json:"id,omitempty"`
+	Status      string `json:"status,omitempty"`
+	DownloadUrl string `json:"download_url,omitempty"`
+	ExpiresAt   int64  `json:"expires_at,omitempty"`
+	ErrorHolder
+}
+
+type ImportResponse struct {
+	Id            string `json:"id,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Status        string `json:"status,omitempty"`
+	TotalFiles    int64  `json:"total_files"`
+	ImportedFiles int64  `json:"imported_files"`
+	FailedFiles   int64  `json:"failed_files"`
+	ImportedBytes int64  `json:"imported_bytes"`
+	ErrorHolder
+}
+
+type MigrationResponse struct {
+	Id            string `json:"id,omitempty"`
+	From          string `json:"from,omitempty"`
+	To            string `json:"to,omitempty"`
+	CreatedBefore int64  `json:"created_before,omitempty"`
+	Status        string `json:"status,omitempty"`
+	TotalBlobs    int64  `json:"total_blobs"`
+	MovedBlobs    int64  `json:"moved_blobs"`
+	FailedBlobs   int64  `json:"failed_blobs"`
+	MovedBytes    int64  `json:"moved_bytes"`
+	ErrorHolder
+}
+
+type JobProgress struct {
+	Done int64 `json:"done"`
+	// Total is 0 until the job knows how much work it has
+	Total int64 `json:"total"`
+}
+
+type JobResponse struct {
+	Id       string      `json:"id,omitempty"`
+	Type     string      `json:"type,omitempty"`
+	Status   string      `json:"status,omitempty"`
+	Progress JobProgress `json:"progress"`
+	// Result depends on the type of the job and is only set once it finished
+	Result    json.RawMessage `json:"result,omitempty"`
+	CreatedAt int64           `json:"created_at,omitempty"`
+	UpdatedAt int64           `json:"updated_at,omitempty"`
+	ErrorHolder
+}
+
+type HLSResponse struct {
+	Status      string `json:"status,omitempty"`
+	PlaylistUrl string `json:"playlist_url,omitempty"`
+	ErrorHolder
+}
+
 type ApiErrorCode int
 
 type ApiError struct {
@@ -27,6 +116,8 @@ type ApiError struct {
 
 type ErrorHolder struct {
 	Errors []ApiError `json:"errors,omitempty"`
+	// set for unexpected failures, so that they can be found in the logs
+	RequestId string `json:"request_id,omitempty"`
 }
 
 const (
@@ -37,6 +128,24 @@ const (
 	TooBigContentSize
 	ParameterOutOfRange
 	NotFound
+	InvalidLink
+	TooManyRequests
+	InsufficientStorage
+	AmbiguousPath
+	PreviewUnavailable
+	StreamNotReady
+	QuotaExceeded
+	FeatureDisabled
+	UnderMaintenance
+	TrafficCapExceeded
+	FileTypeNotAllowed
+	FileNameTaken
+	PreconditionFailed
+	FileLocked
+	DeletionRequested
+	NetworkDenied
+	CountryDenied
+	PasswordRequired
 )
 
 func addError(r *ErrorHolder, code ApiErrorCode, description string) {
@@ -57,6 +166,15 @@ func addParamError(r *ErrorHolder, code ApiErrorCode, param string, description
 func writeResponse(w http.ResponseWriter, resp any, status int) error {
 	const op = "api.writeResponse"
 
+	if carrier, ok := resp.(errorCarrier); ok && status >= 400 && problem.Requested(w) {
+		if errs := carrier.apiErrors(); len(errs) > 0 {
+			if err := writeProblem(w, errs, carrier.requestId(), status); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			return nil
+		}
+	}
+
 	body, err := json.Marshal(resp)
 	if err != nil {
 		return fmt.Errorf("%s: json.Marshal: %w", op, err)
@@ -0,0 +1,409 @@
+package api
+
+import (
+	dbaccess "cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/policy"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// UploadMeta describes a file about to be uploaded
+type UploadMeta struct {
+	OwnerId  int64
+	FileName string
+	// the type the client gave the file, if any; policies check it along with the extension
+	ContentType string
+	// Size is -1 if it isn't known up front, in which case the content may take up to MaxSize bytes
+	Size      int64
+	MaxSize   int64
+	ExpiresAt dbaccess.Time
+	// IfMatch, unless nil, lists the ETags one of which the file now holding the name must have;
+	// see ifMatchHeader
+	IfMatch []string
+	// LockToken is the token of the lock the client holds on the files the upload replaces, if any
+	LockToken string
+	// Metadata is stored with the file, see validateMetadata
+	Metadata map[string]string
+	// FolderId is the folder the file goes into, 0 for the root
+	FolderId int64
+}
+
+type UploadResult struct {
+	Id string
+	// FileName differs from the one asked for if DuplicateNames renamed the file
+	FileName string
+	Size     int64
+	// Warnings tell how close the owner is to their quota
+	Warnings []string
+}
+
+// UploadError is an upload turned down for a reason the client can do something about;
+// Status is what an http front end answers with
+type UploadError struct {
+	ApiError
+	Status int
+	// Warnings tell how close the owner is to their quota
+	Warnings []string
+}
+
+func (err UploadError) Error() string {
+	return err.Description
+}
+
+// UploadService stores uploaded files whatever protocol they came in by. Front ends parse their
+// requests into UploadMeta and turn the errors of Upload into their own responses.
+type UploadService struct {
+	db  dbaccess.DbAccess
+	cfg UploadConfig
+	c   encryption.Crypter
+}
+
+// NewUploadService takes cfg as it applies to the owners of the files, see UploadConfig.forUser
+func NewUploadService(db dbaccess.DbAccess, cfg UploadConfig, c encryption.Crypter) *UploadService {
+	return &UploadService{
+		db:  db,
+		cfg: cfg,
+		c:   c,
+	}
+}
+
+// Upload checks meta against free space, quota and policies, then stores the content under a new generated name.
+// The content must end right at meta.Size if it is known.
+func (s *UploadService) Upload(ctx context.Context, meta UploadMeta, content io.Reader) (UploadResult, error) {
+	const op = "api.UploadService.Upload"
+	log := slogext.LogWithOp(op, ctx)
+
+	required := meta.Size
+	if meta.Size < 0 {
+		required = meta.MaxSize
+	}
+
+	if err := checkSpace(s.cfg.Space, required); err != nil {
+		return UploadResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	used, err := checkQuota(s.db, s.cfg.Quota, meta.OwnerId, required)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.cfg.checkType(policy.MediaType(meta.FileName, meta.ContentType)); err != nil {
+		return UploadResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if meta.IfMatch != nil {
+		// held until the replaced files are gone, so the next conditional upload of the name sees this one
+		unlock := nameLocks.lock(meta.OwnerId, meta.FileName)
+		defer unlock()
+
+		current, err := filesNamed(log, s.db, s.c, meta.OwnerId, meta.FolderId, meta.FileName, "")
+		if err != nil {
+			return UploadResult{}, fmt.Errorf("%s: look up files by name: %w", op, err)
+		}
+
+		if !matchesETag(meta.IfMatch, current) {
+			return UploadResult{}, fmt.Errorf("%s: %w", op, UploadError{
+				ApiError: ApiError{
+					Code:        PreconditionFailed,
+					ParamName:   ifMatchHeader,
+					Description: "The file was changed or removed since the version in If-Match",
+				},
+				Status: http.StatusPreconditionFailed,
+			})
+		}
+	}
+
+	// a taken name is turned down before any of the content is read; save claims the name again
+	// in the transaction adding the file, so that concurrent uploads can't both get it
+	if _, _, err := s.resolveName(log, s.db, meta); err != nil {
+		return UploadResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if meta.Size >= 0 {
+		content = &exactReader{reader: content, remaining: meta.Size}
+	}
+
+	saved, err := s.save(ctx, log, meta, content)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	removeReplaced(ctx, s.db, s.cfg.Blobs, saved.replaced, log)
+
+	return UploadResult{
+		Id:       saved.id,
+		FileName: saved.name,
+		Size:     saved.size,
+		Warnings: s.cfg.Quota.warnings(used + saved.size),
+	}, nil
+}
+
+// resolveName applies DuplicateNames to the name of the upload; it fails with an UploadError if the name
+// is taken, or if the files it replaces are locked by someone else
+func (s *UploadService) resolveName(log *slog.Logger, db dbaccess.DbAccess, meta UploadMeta) (string, []dbaccess.File, error) {
+	name, replaced, apiErr, status := s.cfg.DuplicateNames.resolve(log, db, s.c, meta.OwnerId, meta.FolderId, meta.FileName, "")
+	if status != 0 {
+		return "", nil, UploadError{ApiError: apiErr, Status: status}
+	}
+
+	if apiErr, status := checkUnlocked(log, db, meta.LockToken, replaced...); status != 0 {
+		return "", nil, UploadError{ApiError: apiErr, Status: status}
+	}
+
+	return name, replaced, nil
+}
+
+// savedFile is what save stored of an upload
+type savedFile struct {
+	id   string
+	name string
+	size int64
+	// replaced are the files that had the name, to be removed now that the upload has it
+	replaced []dbaccess.File
+}
+
+// save stores the content under a new generated name, expiring at meta.ExpiresAt unless it is zero
+func (s *UploadService) save(ctx context.Context, log *slog.Logger, meta UploadMeta, content io.Reader) (savedFile, error) {
+	var saved savedFile
+	metadata, terms, err := sealMetadata(s.c, meta.OwnerId, meta.Metadata)
+	if err != nil {
+		return savedFile{}, err
+	}
+
+	// whatever part of the content was read counts as traffic, even if the upload fails
+	received := &countingReader{r: content}
+	defer func() { recordTraffic(s.db, log, meta.OwnerId, received.n, 0) }()
+
+	// the row only shows up with its expiry, so retention never sees it as kept forever;
+	// an empty blobName gives the file a blob of its own
+	// the name is resolved and indexed in the same transaction, so that an upload running meanwhile either
+	// sees the row or fails to commit and runs again
+	addRow := func(repos dbaccess.DbAccess, generatedName string, size int64, blobName string) error {
+		filename, replaced, err := s.resolveName(log, repos, meta)
+		if err != nil {
+			return err
+		}
+		saved.name, saved.replaced = filename, replaced
+
+		encFileName, err := s.c.EncryptFileName(filename)
+		if err != nil {
+			return fmt.Errorf("encrypt file name: %w", err)
+		}
+		index, err := s.c.FileNameIndex(filename)
+		if err != nil {
+			return fmt.Errorf("index file name: %w", err)
+		}
+
+		if blobName == "" {
+			err = repos.AddFile(generatedName, encFileName, meta.OwnerId, size)
+		} else {
+			err = repos.AddFileCopy(generatedName, encFileName, meta.OwnerId, blobName, size)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := repos.SetFileNameIndex(generatedName, index); err != nil {
+			return err
+		}
+
+		if metadata != "" {
+			if err := repos.SetFileMetadata(generatedName, meta.OwnerId, metadata, terms); err != nil {
+				return err
+			}
+		}
+
+		if meta.FolderId != 0 {
+			if err := repos.SetFileFolder(generatedName, meta.FolderId); err != nil {
+				return err
+			}
+		}
+
+		if !time.Time(meta.ExpiresAt).IsZero() {
+			return repos.SetFileExpiry(generatedName, meta.ExpiresAt)
+		}
+		return nil
+	}
+
+	if s.cfg.Convergent != nil {
+		saved.id, saved.size, err = s.saveConvergent(ctx, log, meta, received, addRow)
+		if err != nil {
+			return savedFile{}, err
+		}
+		return saved, nil
+	}
+
+	reserve := func(size int64, blobName string) (string, error) {
+		return addWithUniqueName(s.cfg.TimeOrderedIds, func(generatedName string) error {
+			return s.db.WithTx(ctx, func(repos dbaccess.DbAccess) error {
+				return addRow(repos, generatedName, size, blobName)
+			})
+		})
+	}
+
+	// with a known size, the id is reserved with its row before any of the content is read; either way
+	// a collision can be retried without losing data, as the content waits in the temp file otherwise.
+	// A content-addressed blob is only named once it is written, so its row has to wait until then.
+	contentAddressed := s.cfg.Layout == storage.LayoutContentAddressed
+	fileSize := meta.Size
+	var strId string
+	if fileSize >= 0 && !contentAddressed {
+		strId, err = reserve(fileSize, "")
+		if err != nil {
+			return savedFile{}, fmt.Errorf("save file info: %w", err)
+		}
+	}
+
+	err = func() error {
+		// the blob only shows up under its name once fully written
+		file, err := storage.CreateTemp(s.cfg.StorageDir, s.cfg.Durability)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := file.Discard(); err != nil {
+				log.Error("Could not remove incomplete file from disk", slogext.Error(err))
+			}
+		}()
+
+		var limited io.Reader = newLimitedReader(received, meta.MaxSize)
+		if fileSize >= 0 {
+			limited = newLimitedReader(received, fileSize)
+		}
+		if err := s.c.EncryptAndCopyFor(meta.OwnerId, file, limited); err != nil {
+			return err
+		}
+
+		if fileSize < 0 {
+			if received.n == 0 {
+				return emptyFileError{}
+			}
+			fileSize = received.n
+		}
+
+		if !contentAddressed {
+			if strId == "" {
+				if strId, err = reserve(fileSize, ""); err != nil {
+					return fmt.Errorf("save file info: %w", err)
+				}
+			}
+			return file.CommitAs(strId)
+		}
+
+		// the row goes first, so that a file of the same blob being deleted meanwhile leaves it in place;
+		// committing over a blob already there changes nothing, as it has the same content
+		blobName := file.ContentAddress()
+		if strId, err = reserve(fileSize, blobName); err != nil {
+			return fmt.Errorf("save file info: %w", err)
+		}
+		return file.CommitAs(blobName)
+	}()
+
+	if err != nil {
+		if strId != "" {
+			if err := s.db.RemoveFile(strId); err != nil {
+				log.Error(
+					"Could not remove incomplete file info from db",
+					slogext.Error(err),
+					slog.String("generated-name", strId),
+				)
+			}
+		}
+
+		return savedFile{}, contentError(err, meta.Size < 0)
+	}
+
+	saved.id, saved.size = strId, fileSize
+	return saved, nil
+}
+
+// contentError tells the errors of reading content that are the fault of the client from the rest
+func contentError(err error, sizeUnknown bool) error {
+	if errors.As(err, &tooBigFileError{}) && sizeUnknown {
+		return UploadError{ApiError: ApiError{Code: TooBigContentSize, Description: "File exceeds max upload size"}, Status: http.StatusRequestEntityTooLarge}
+	} else if errors.As(err, &tooBigFileError{}) {
+		return UploadError{ApiError: ApiError{Code: TooBigContentSize, Description: tooBigFileError{}.Error()}, Status: http.StatusRequestEntityTooLarge}
+	} else if errors.As(err, &contentTooShortError{}) {
+		return UploadError{ApiError: ApiError{Code: UnexpectedEOF, Description: contentTooShortError{}.Error()}, Status: http.StatusUnprocessableEntity}
+	} else if mbe := (&http.MaxBytesError{}); errors.As(err, &mbe) {
+		return UploadError{ApiError: ApiError{Code: TooBigContentSize, Description: "File exceeds max upload size"}, Status: http.StatusRequestEntityTooLarge}
+	} else if errors.As(err, &emptyFileError{}) {
+		return UploadError{ApiError: ApiError{Code: ParameterOutOfRange, ParamName: "file", Description: emptyFileError{}.Error()}, Status: http.StatusUnprocessableEntity}
+	}
+
+	return fmt.Errorf("save file: %w", err)
+}
+
+// checkSpace fails with an UploadError if size bytes would not fit into the storage
+func checkSpace(space storage.Space, size int64) error {
+	err := space.Require(size)
+	var ise storage.InsufficientSpaceError
+	if errors.As(err, &ise) {
+		return UploadError{
+			ApiError: ApiError{
+				Code:        InsufficientStorage,
+				Description: fmt.Sprintf("Not enough free space to store the file: %d bytes required, %d free", ise.Required, ise.Free),
+			},
+			Status: http.StatusInsufficientStorage,
+		}
+	} else if err != nil {
+		return fmt.Errorf("check free space: %w", err)
+	}
+
+	return nil
+}
+
+// checkQuota fails with an UploadError if size more bytes would exceed the quota of the user.
+// Otherwise it returns the bytes the user stores so far. Concurrent uploads may overshoot the limit together;
+// the quota is meant to keep usage in check, not to be exact.
+func checkQuota(db dbaccess.UsageRepo, quota Quota, userId int64, size int64) (int64, error) {
+	if quota.Limit <= 0 {
+		return 0, nil
+	}
+
+	usage, err := db.GetUsage(userId, dbaccess.Time(time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("get usage: %w", err)
+	}
+
+	if usage.StoredBytes+size > quota.Limit {
+		return 0, UploadError{
+			ApiError: ApiError{
+				Code:        QuotaExceeded,
+				Description: fmt.Sprintf("Storage quota exceeded: %d of %d bytes used", usage.StoredBytes, quota.Limit),
+			},
+			Status:   http.StatusInsufficientStorage,
+			Warnings: quota.warnings(usage.StoredBytes),
+		}
+	}
+
+	return usage.StoredBytes, nil
+}
+
+// writeUploadError answers with what went wrong in an upload
+func writeUploadError(w http.ResponseWriter, log *slog.Logger, err error) {
+	var ue UploadError
+	if !errors.As(err, &ue) {
+		log.Error("Could not store file", slogext.Error(err))
+
+		if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return
+	}
+
+	if ue.Description != "" {
+		log.Error(ue.Description)
+	}
+	addStorageWarnings(w, ue.Warnings)
+	if err := writeParamError(w, ue.Code, ue.ParamName, ue.Description, ue.Status); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+}
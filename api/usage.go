@@ -0,0 +1,101 @@
+package api
+
+import (
+	"cloud-storage/access"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type UsageResponse struct {
+	// only set for the admin view
+	Users       int64 `json:"users,omitempty"`
+	Files       int64 `json:"files"`
+	StoredBytes int64 `json:"stored_bytes"`
+	// calendar month in UTC that the traffic counters belong to, e.g. 2024-05
+	Month           string `json:"month"`
+	UploadedBytes   int64  `json:"uploaded_bytes"`
+	DownloadedBytes int64  `json:"downloaded_bytes"`
+	// monthly traffic caps, omitted when there is none
+	UploadCapBytes   int64 `json:"upload_cap_bytes,omitempty"`
+	DownloadCapBytes int64 `json:"download_cap_bytes,omitempty"`
+	ErrorHolder
+}
+
+// recordTraffic only logs failures; losing a counter update is no reason to fail a transfer
+func recordTraffic(db db_access.UsageRepo, log *slog.Logger, userId int64, uploaded int64, downloaded int64) {
+	if userId < 0 || uploaded == 0 && downloaded == 0 {
+		return
+	}
+
+	if err := db.AddTraffic(userId, db_access.Time(time.Now()), uploaded, downloaded); err != nil {
+		log.Error("Could not record traffic", slogext.Error(err), slog.Int64("user-id", userId))
+	}
+}
+
+// recordAccess keeps the last access and download count of files up to date; the recorder batches the writes
+func recordAccess(accesses *access.Recorder, generatedName string, downloaded int64) {
+	if downloaded == 0 {
+		return
+	}
+
+	accesses.Record(generatedName, time.Now())
+}
+
+func writeUsage(w http.ResponseWriter, log *slog.Logger, usage db_access.Usage, caps TrafficCaps, now time.Time) {
+	resp := UsageResponse{
+		Users:            usage.Users,
+		Files:            usage.Files,
+		StoredBytes:      usage.StoredBytes,
+		Month:            now.UTC().Format("2006-01"),
+		UploadedBytes:    usage.UploadedBytes,
+		DownloadedBytes:  usage.DownloadedBytes,
+		UploadCapBytes:   max(caps.Upload, 0),
+		DownloadCapBytes: max(caps.Download, 0),
+	}
+
+	if err := writeResponse(w, resp, http.StatusOK); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+}
+
+func Usage(db db_access.UsageRepo, caps TrafficCaps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.Usage"
+		log := slogext.LogWithOp(op, r.Context())
+
+		now := time.Now()
+		usage, err := db.GetUsage(auth.UserId(r.Context()), db_access.Time(now))
+		if err != nil {
+			log.Error("Could not get usage from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		// the user count is only meaningful for the aggregate
+		usage.Users = 0
+		writeUsage(w, log, usage, caps, now)
+	}
+}
+
+// AdminUsage sums the counters of all users; it has to be mounted behind auth.Admin
+func AdminUsage(db db_access.UsageRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "api.AdminUsage"
+		log := slogext.LogWithOp(op, r.Context())
+
+		now := time.Now()
+		usage, err := db.GetTotalUsage(db_access.Time(now))
+		if err != nil {
+			log.Error("Could not get total usage from db", slogext.Error(err))
+			writeError(w, InternalApiError, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		// caps apply to each user, not to the sum
+		writeUsage(w, log, usage, TrafficCaps{}, now)
+	}
+}
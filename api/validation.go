@@ -0,0 +1,32 @@
+package api
+
+import (
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"log/slog"
+	"net/http"
+)
+
+var validationCodes = map[validate.Kind]ApiErrorCode{
+	validate.Missing:    InvalidContentFormat,
+	validate.OutOfRange: ParameterOutOfRange,
+	validate.Malformed:  InvalidContentFormat,
+}
+
+// requireValid writes every failure of v in one response and returns false, unless there are none
+func requireValid(w http.ResponseWriter, log *slog.Logger, v *validate.Validator) bool {
+	if v.Valid() {
+		return true
+	}
+
+	var resp UploadResponse
+	for _, failure := range v.Failures {
+		addParamError(&resp.ErrorHolder, validationCodes[failure.Kind], failure.Param, failure.Message)
+	}
+
+	log.Error("Invalid request", slog.Any("errors", resp.Errors))
+	if err := writeResponse(w, resp, v.Status()); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+	return false
+}
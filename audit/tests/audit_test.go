@@ -0,0 +1,119 @@
+package audit_test
+
+import (
+	"bytes"
+	"cloud-storage/audit"
+	"cloud-storage/db_access"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var events = []db_access.AuditEvent{
+	{
+		Id:         1,
+		At:         db_access.Time(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		UserId:     3,
+		Method:     "POST",
+		Path:       "/api/upload",
+		Status:     201,
+		RemoteAddr: "10.0.0.1:5000",
+		RequestId:  "host/abc-000001",
+	},
+	{
+		Id:         2,
+		At:         db_access.Time(time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)),
+		UserId:     -1,
+		Method:     "POST",
+		Path:       "/api/auth/login",
+		Status:     401,
+		RemoteAddr: "10.0.0.2:5000",
+		RequestId:  `id, with "quotes"`,
+	},
+}
+
+func TestWrite_JSONL(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, audit.Write(&buf, audit.JSONL, events))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Equal(t, 2, len(lines))
+
+	var first audit.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, audit.Event{
+		Id:         1,
+		At:         "2024-05-01T12:00:00Z",
+		UserId:     3,
+		Method:     "POST",
+		Path:       "/api/upload",
+		Status:     201,
+		RemoteAddr: "10.0.0.1:5000",
+		RequestId:  "host/abc-000001",
+	}, first)
+}
+
+func TestWrite_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, audit.Write(&buf, audit.CSV, events))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "at", "user_id", "method", "path", "status", "remote_addr", "request_id"},
+		{"1", "2024-05-01T12:00:00Z", "3", "POST", "/api/upload", "201", "10.0.0.1:5000", "host/abc-000001"},
+		{"2", "2024-05-01T12:00:01Z", "-1", "POST", "/api/auth/login", "401", "10.0.0.2:5000", `id, with "quotes"`},
+	}, records)
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	assert.Error(t, audit.Write(&bytes.Buffer{}, audit.Format("xml"), events))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := audit.NewSigner(key)
+
+	data := []byte("some export\n")
+	signature := signer.Sign(data)
+	assert.NoError(t, audit.Verify(signer.PublicKey(), data, signature))
+
+	assert.ErrorIs(t, audit.Verify(signer.PublicKey(), []byte("some export!\n"), signature), audit.ErrInvalidSignature)
+	assert.ErrorIs(t, audit.Verify(signer.PublicKey(), data, "not base64"), audit.ErrInvalidSignature)
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	assert.ErrorIs(t, audit.Verify(otherKey, data, signature), audit.ErrInvalidSignature)
+}
+
+func TestLoadSigner(t *testing.T) {
+	dir := t.TempDir()
+
+	seed := make([]byte, ed25519.SeedSize)
+	rand.Read(seed)
+	path := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(seed)+"\n"), 0o600))
+
+	signer, err := audit.LoadSigner(path)
+	require.NoError(t, err)
+	assert.Equal(t, ed25519.NewKeyFromSeed(seed).Public(), signer.PublicKey())
+
+	short := filepath.Join(dir, "short")
+	require.NoError(t, os.WriteFile(short, []byte(base64.StdEncoding.EncodeToString(seed[:16])), 0o600))
+	_, err = audit.LoadSigner(short)
+	assert.Error(t, err)
+
+	_, err = audit.LoadSigner(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// Admin lets through users whose name is in admins or who hold RoleAdmin; it has to run after Auth
+func Admin(db db_access.UserRepo, admins []string) func(http.Handler) http.Handler {
+	// names are kept normalized, the config may hold them as they were registered
+	normalized := make([]string, 0, len(admins))
+	for _, name := range admins {
+		normalized = append(normalized, NormalizeName(name))
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "auth.Admin"
+			log := slogext.LogWithOp(op, r.Context())
+
+			user := db_access.User{Id: UserId(r.Context())}
+			if err := db.GetUser(&user); err != nil {
+				log.Error("Could not get user from db", slogext.Error(err), slog.Int64("user-id", user.Id))
+
+				if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+
+			if !slices.Contains(normalized, user.Name) && !slices.Contains(user.Roles, RoleAdmin) {
+				errorMsg := "Admin rights required"
+				log.Error(errorMsg, slog.Int64("user-id", user.Id))
+
+				if err := writeError(w, NotAdmin, errorMsg, http.StatusForbidden); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
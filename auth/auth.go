@@ -3,6 +3,7 @@ package auth
 import (
 	"cloud-storage/db_access"
 	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
 	"context"
 	"crypto/rand"
 	"encoding/json"
@@ -16,29 +17,78 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Repo is where AuthData finds users and their devices
+type Repo interface {
+	db_access.UserRepo
+	db_access.DeviceRepo
+}
+
 type AuthData struct {
-	db              db_access.DbAccess
+	db              Repo
 	tokenKey        []byte
 	tokenTimeToLive time.Duration
+	// checks passwords in place of the users table when set
+	directory Directory
+	// how Register answers a taken name
+	registrations Registrations
+	tokens        TokenConfig
+	// sessions of browsers are kept in cookies when set
+	cookies *cookies
+	logins  LoginWatcher
+	filter  LoginFilter
+	// compared with the password of users that don't exist, see newDummyHash
+	dummyHash []byte
 }
 
 const hMACKeySize = 32
 
 type Claims struct {
 	UserId int64 `json:"user_id"`
+	// TokenVersion is the one of the user when the token was issued
+	TokenVersion int64 `json:"token_version"`
+	// DeviceId is set on tokens issued for the refresh token of a device
+	DeviceId string `json:"device_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewAuthData(db db_access.DbAccess, tokenTTL time.Duration) *AuthData {
+func NewAuthData(db Repo, tokenTTL time.Duration) *AuthData {
 	key := make([]byte, hMACKeySize)
 	rand.Read(key)
 	return &AuthData{
-		db:       db,
-		tokenKey: key,
+		db:              db,
+		tokenKey:        key,
 		tokenTimeToLive: tokenTTL,
+		tokens:          defaultTokenConfig,
+		dummyHash:       newDummyHash(),
 	}
 }
 
+// UseDirectory makes Login check passwords against d; users it knows are added on their first login
+func (a *AuthData) UseDirectory(d Directory) {
+	a.directory = d
+}
+
+// LoginWatcher is told of every login that succeeds, such as anomaly.Detector
+type LoginWatcher interface {
+	LoggedIn(r *http.Request, userId int64)
+}
+
+// WatchLogins hands every successful login to w
+func (a *AuthData) WatchLogins(w LoginWatcher) {
+	a.logins = w
+}
+
+// LoginFilter may turn down logins and refreshes with valid credentials, such as by the network
+// of the client; it writes the response itself when it does
+type LoginFilter interface {
+	AllowLogin(w http.ResponseWriter, r *http.Request, userId int64, deviceId string) bool
+}
+
+// FilterLogins has f decide on every login and refresh once the credentials are checked
+func (a *AuthData) FilterLogins(f LoginFilter) {
+	a.filter = f
+}
+
 type AuthCtx string
 
 const AuthUserId AuthCtx = "auth user id"
@@ -49,8 +99,24 @@ func Auth(a *AuthData) func(http.Handler) http.Handler {
 			const op = "auth.Auth"
 			log := slogext.LogWithOp(op, r.Context())
 
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
+			// API clients send their token, browsers may keep it in a cookie
+			var sessionToken string
+			fromCookie := false
+			if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+				sessionTokenData := strings.Split(authHeader, " ")
+				if len(sessionTokenData) != 2 || sessionTokenData[0] != "Bearer" {
+					errorMsg := "Invalid authorization scheme"
+					log.Error(errorMsg)
+
+					if err := writeError(w, InvalidSessionToken, errorMsg, http.StatusUnauthorized); err != nil {
+						log.Error("Could not write response", slogext.Error(err))
+					}
+					return
+				}
+				sessionToken = sessionTokenData[1]
+			} else if cookie, ok := a.sessionCookie(r); ok {
+				sessionToken, fromCookie = cookie, true
+			} else {
 				errorMsg := "No Authorization header provided"
 				log.Error(errorMsg)
 
@@ -60,10 +126,10 @@ func Auth(a *AuthData) func(http.Handler) http.Handler {
 				return
 			}
 
-			sessionTokenData := strings.Split(authHeader, " ")
-			if len(sessionTokenData) != 2 || sessionTokenData[0] != "Bearer" {
-				errorMsg := "Invalid authorization scheme"
-				log.Error(errorMsg)
+			claims, err := a.parseToken(sessionToken)
+			if err != nil {
+				errorMsg := "Invalid session token"
+				log.Error(errorMsg, slogext.Error(err))
 
 				if err := writeError(w, InvalidSessionToken, errorMsg, http.StatusUnauthorized); err != nil {
 					log.Error("Could not write response", slogext.Error(err))
@@ -71,18 +137,16 @@ func Auth(a *AuthData) func(http.Handler) http.Handler {
 				return
 			}
 
-			token, err := jwt.ParseWithClaims(
-				sessionTokenData[1],
-				&Claims{},
-				func(t *jwt.Token) (any, error) {
-					return a.tokenKey, nil
-				},
-				jwt.WithExpirationRequired(),
-				jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
-			)
-			if err != nil {
+			if revoked, err := a.revoked(claims); err != nil {
+				log.Error("Could not get user from db", slogext.Error(err), slog.Int64("user-id", claims.UserId))
+
+				if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			} else if revoked {
 				errorMsg := "Invalid session token"
-				log.Error(errorMsg, slogext.Error(err))
+				log.Error(errorMsg, slog.String("reason", "revoked"), slog.Int64("user-id", claims.UserId))
 
 				if err := writeError(w, InvalidSessionToken, errorMsg, http.StatusUnauthorized); err != nil {
 					log.Error("Could not write response", slogext.Error(err))
@@ -90,18 +154,22 @@ func Auth(a *AuthData) func(http.Handler) http.Handler {
 				return
 			}
 
-			claims, ok := token.Claims.(*Claims)
-			if !ok {
-				errorMsg := "Invalid session token"
-				log.Error(errorMsg, slogext.Error(errors.New("Invalid Claims type")))
+			// a cookie comes along with requests other sites make, a header only with those of our pages
+			if fromCookie && !a.validCSRF(r, sessionToken) {
+				errorMsg := "Invalid CSRF token"
+				log.Error(errorMsg, slog.Int64("user-id", claims.UserId))
 
-				if err := writeError(w, InvalidSessionToken, errorMsg, http.StatusUnauthorized); err != nil {
+				if err := writeError(w, InvalidCSRFToken, errorMsg, http.StatusForbidden); err != nil {
 					log.Error("Could not write response", slogext.Error(err))
 				}
 				return
 			}
 
-			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), AuthUserId, claims.UserId)))
+			ctx := context.WithValue(r.Context(), AuthUserId, claims.UserId)
+			if claims.DeviceId != "" {
+				ctx = context.WithValue(ctx, AuthDeviceId, claims.DeviceId)
+			}
+			h.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -131,6 +199,13 @@ func Register(a *AuthData) http.HandlerFunc {
 			return
 		}
 
+		req.Name = NormalizeName(req.Name)
+		var v validate.Validator
+		req.validate(&v, true)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
 		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
 			errorMsg := "Bad password"
@@ -147,11 +222,17 @@ func Register(a *AuthData) http.HandlerFunc {
 			PasswordHash: hash,
 		}
 		var uce db_access.UniqueConstraintError
-		if err := a.db.AddUser(&user); errors.As(err, &uce) {
+		if err := a.db.AddUser(&user); errors.As(err, &uce) && a.registrations == HideTakenNames {
+			// answered as a new name, which was hashed for all the same
+			log.Error("Name already used", slog.String("name", user.Name))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		} else if errors.As(err, &uce) {
+			// a registration of the same name that got there first lands here as well
 			errorMsg := "Name already used"
-			log.Error(errorMsg)
+			log.Error(errorMsg, slog.String("constraint", uce.Table+"."+uce.Column))
 
-			if err := writeError(w, InvalidCredentials, errorMsg, http.StatusConflict); err != nil {
+			if err := writeParamError(w, NameTaken, "name", errorMsg, http.StatusConflict); err != nil {
 				log.Error("Could not write response", slogext.Error(err))
 			}
 			return
@@ -175,73 +256,108 @@ func Login(a *AuthData) http.HandlerFunc {
 		const op = "auth.Login"
 		log := slogext.LogWithOp(op, r.Context())
 
-		decoder := json.NewDecoder(r.Body)
-
-		var req AuthRequest
-		if err := decoder.Decode(&req); err != nil {
-			errorMsg := "Invalid json"
-			log.Error(errorMsg, slogext.Error(err))
-
-			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest); err != nil {
-				log.Error("Could not write response", slogext.Error(err))
-			}
+		token, ok := issueToken(a, w, r, log)
+		if !ok {
 			return
 		}
 
-		var user db_access.User
-		user.Name = req.Name
+		resp := AuthResponse{
+			SessionToken: token,
+		}
+		if err := resp.write(w, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
 
-		var nre db_access.NoRowsError
-		if err := a.db.GetUser(&user); errors.As(err, &nre) {
-			errorMsg := "Invalid credentials"
-			log.Error(errorMsg)
+// issueToken checks the credentials in the body of r and returns a new session token of their user;
+// it writes the response itself when it fails
+func issueToken(a *AuthData, w http.ResponseWriter, r *http.Request, log *slog.Logger) (string, bool) {
+	decoder := json.NewDecoder(r.Body)
 
-			if err := writeError(w, InvalidCredentials, errorMsg, http.StatusUnauthorized); err != nil {
-				log.Error("Could not write response", slogext.Error(err))
-			}
-			return
-		} else if err != nil {
-			log.Error("Database error", slogext.Error(err))
+	var req AuthRequest
+	if err := decoder.Decode(&req); err != nil {
+		errorMsg := "Invalid json"
+		log.Error(errorMsg, slogext.Error(err))
 
-			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
-				log.Error("Could not write response", slogext.Error(err))
-			}
-			return
+		if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
 		}
+		return "", false
+	}
 
-		if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(req.Password)); err != nil {
-			errorMsg := "Invalid credentials"
-			log.Error(errorMsg, slogext.Error(err))
+	var v validate.Validator
+	req.validate(&v, false)
+	if !requireValid(w, log, &v) {
+		return "", false
+	}
 
-			if err := writeError(w, InvalidCredentials, errorMsg, http.StatusUnauthorized); err != nil {
-				log.Error("Could not write response", slogext.Error(err))
-			}
-			return
-		}
+	checkCredentials := localLogin
+	if a.directory != nil {
+		checkCredentials = directoryLogin
+	}
+	user, ok := checkCredentials(a, w, r, req)
+	if !ok {
+		return "", false
+	}
+	if a.filter != nil && !a.filter.AllowLogin(w, r, user.Id, "") {
+		return "", false
+	}
+
+	token, err := a.newToken(user, "")
+	if err != nil {
+		log.Error("JWT creation error", slogext.Error(err))
 
-		now := time.Now()
-		claims := Claims{
-			user.Id,
-			jwt.RegisteredClaims{
-				IssuedAt:  jwt.NewNumericDate(now),
-				ExpiresAt: jwt.NewNumericDate(now.Add(a.tokenTimeToLive)),
-			},
+		if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
 		}
-		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.tokenKey)
-		if err != nil {
-			log.Error("JWT creation error", slogext.Error(err))
+		return "", false
+	}
 
-			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
-				log.Error("Could not write response", slogext.Error(err))
-			}
-			return
+	if a.logins != nil {
+		a.logins.LoggedIn(r, user.Id)
+	}
+	return token, true
+}
+
+// localLogin checks the password against the hash in the users table
+func localLogin(a *AuthData, w http.ResponseWriter, r *http.Request, req AuthRequest) (db_access.User, bool) {
+	const op = "auth.localLogin"
+	log := slogext.LogWithOp(op, r.Context())
+
+	var user db_access.User
+	user.Name = NormalizeName(req.Name)
+
+	var nre db_access.NoRowsError
+	if err := a.db.GetUser(&user); errors.As(err, &nre) {
+		// answered as a wrong password, after as long
+		bcrypt.CompareHashAndPassword(a.dummyHash, []byte(req.Password))
+
+		errorMsg := "Invalid credentials"
+		log.Error(errorMsg, slog.String("name", req.Name))
+
+		if err := writeError(w, InvalidCredentials, errorMsg, http.StatusUnauthorized); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
 		}
+		return db_access.User{}, false
+	} else if err != nil {
+		log.Error("Database error", slogext.Error(err))
 
-		resp := AuthResponse{
-			SessionToken: token,
+		if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
 		}
-		if err := resp.write(w, http.StatusOK); err != nil {
+		return db_access.User{}, false
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(req.Password)); err != nil {
+		errorMsg := "Invalid credentials"
+		log.Error(errorMsg, slogext.Error(err))
+
+		if err := writeError(w, InvalidCredentials, errorMsg, http.StatusUnauthorized); err != nil {
 			log.Error("Could not write response", slogext.Error(err))
 		}
+		return db_access.User{}, false
 	}
+
+	return user, true
 }
@@ -1,6 +1,25 @@
 package auth
 
+import "cloud-storage/utils/validate"
+
+const (
+	maxNameLen = 64
+	// bcrypt refuses longer passwords
+	maxPasswordLen = 72
+)
+
 type AuthRequest struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
 }
+
+// validate checks what any request needs; registering also holds new names, which have to be
+// normalized already, to validateName and keeps passwords within limits
+func (req AuthRequest) validate(v *validate.Validator, register bool) {
+	if v.Required("name", req.Name) && register {
+		validateName(v, "name", req.Name)
+	}
+	if v.Required("password", req.Password) && register {
+		v.MaxLen("password", req.Password, maxPasswordLen)
+	}
+}
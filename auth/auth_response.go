@@ -1,13 +1,18 @@
 package auth
 
 import (
+	"cloud-storage/utils/problem"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 )
 
 type AuthResponse struct {
 	SessionToken string      `json:"session_token,omitempty"`
+	CSRFToken    string      `json:"csrf_token,omitempty"`
 	Errors       []AuthError `json:"errors,omitempty"`
 }
 
@@ -20,10 +25,17 @@ const (
 	NoSessionToken
 	InvalidSessionToken
 	InvalidCredentials
+	NotAdmin
+	UntrustedProxy
+	ParameterOutOfRange
+	InvalidCSRFToken
+	NotFound
+	NameTaken
 )
 
 type AuthError struct {
 	Code        AuthErrorCode `json:"code"`
+	ParamName   string        `json:"parameter_name,omitempty"`
 	Description string        `json:"description,omitempty"`
 }
 
@@ -37,6 +49,13 @@ func (r *AuthResponse) addError(err AuthErrorCode, description string) {
 func (r AuthResponse) write(w http.ResponseWriter, statusCode int) error {
 	const op = "auth.AuthResponse.write"
 
+	if len(r.Errors) > 0 && statusCode >= 400 && problem.Requested(w) {
+		if err := writeProblem(w, r.Errors, statusCode); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+
 	body, err := json.Marshal(r)
 	if err != nil {
 		return fmt.Errorf("%s: json.Marshal: %w", op, err)
@@ -62,3 +81,43 @@ func writeError(w http.ResponseWriter, err AuthErrorCode, description string, st
 
 	return nil
 }
+
+// writeParamError is writeError for an error about one parameter of the request
+func writeParamError(w http.ResponseWriter, err AuthErrorCode, param string, description string, statusCode int) error {
+	const op = "auth.writeParamError"
+
+	resp := AuthResponse{Errors: []AuthError{{Code: err, ParamName: param, Description: description}}}
+	if err := resp.write(w, statusCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var validationCodes = map[validate.Kind]AuthErrorCode{
+	validate.Missing:    InvalidContentFormat,
+	validate.OutOfRange: ParameterOutOfRange,
+	validate.Malformed:  InvalidContentFormat,
+}
+
+// requireValid writes every failure of v in one response and returns false, unless there are none
+func requireValid(w http.ResponseWriter, log *slog.Logger, v *validate.Validator) bool {
+	if v.Valid() {
+		return true
+	}
+
+	var resp AuthResponse
+	for _, failure := range v.Failures {
+		resp.Errors = append(resp.Errors, AuthError{
+			Code:        validationCodes[failure.Kind],
+			ParamName:   failure.Param,
+			Description: failure.Message,
+		})
+	}
+
+	log.Error("Invalid request", slog.Any("errors", resp.Errors))
+	if err := resp.write(w, v.Status()); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+	return false
+}
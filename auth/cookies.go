@@ -0,0 +1,100 @@
+package auth
+
+import (
+	slogext "cloud-storage/utils/slogExt"
+	"crypto/rand"
+	"net/http"
+)
+
+// CSRFHeader has to carry the CSRF token on requests that write and come with a session cookie
+const CSRFHeader = "X-CSRF-Token"
+
+// CookieConfig lets browsers keep their session token in a cookie instead of handing it over themselves
+type CookieConfig struct {
+	// Name of the session cookie; the CSRF cookie is named after it with a -csrf suffix
+	Name string
+	// Secure keeps the cookies to https, which only plain http in development does without
+	Secure   bool
+	SameSite http.SameSite
+}
+
+type cookies struct {
+	CookieConfig
+	// csrfKey binds CSRF tokens to their session token
+	csrfKey []byte
+}
+
+// UseCookies makes Auth take session tokens from the cookie SessionLogin sets when there is
+// no Authorization header; requests made with it that write have to send the CSRF token in CSRFHeader
+func (a *AuthData) UseCookies(cfg CookieConfig) {
+	key := make([]byte, hMACKeySize)
+	rand.Read(key)
+	a.cookies = &cookies{CookieConfig: cfg, csrfKey: key}
+}
+
+func (a *AuthData) sessionCookie(r *http.Request) (string, bool) {
+	if a.cookies == nil {
+		return "", false
+	}
+
+	cookie, err := r.Cookie(a.cookies.Name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// setCookies sets the session cookie, which scripts can't read, and the CSRF cookie, which the pages
+// of the web UI read to send its value back in CSRFHeader; maxAge of -1 removes them
+func (a *AuthData) setCookies(w http.ResponseWriter, sessionToken string, csrfToken string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.cookies.Name,
+		Value:    sessionToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   a.cookies.Secure,
+		SameSite: a.cookies.SameSite,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.cookies.Name + "-csrf",
+		Value:    csrfToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   a.cookies.Secure,
+		SameSite: a.cookies.SameSite,
+	})
+}
+
+// SessionLogin is Login for browsers: the session token goes into a cookie instead of the body,
+// which holds the CSRF token instead
+func SessionLogin(a *AuthData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.SessionLogin"
+		log := slogext.LogWithOp(op, r.Context())
+
+		token, ok := issueToken(a, w, r, log)
+		if !ok {
+			return
+		}
+
+		csrfToken := a.csrfToken(token)
+		a.setCookies(w, token, csrfToken, int(a.tokenTimeToLive.Seconds()))
+
+		resp := AuthResponse{
+			CSRFToken: csrfToken,
+		}
+		if err := resp.write(w, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// SessionLogout removes the cookies of SessionLogin; the token they held stays valid until
+// it expires or the sessions of the user are revoked
+func SessionLogout(a *AuthData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.setCookies(w, "", "", -1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	slogext "cloud-storage/utils/slogExt"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// Requests that come with a session cookie are kept to our own pages in two ways: those that write
+// have to send back the CSRF token, which other sites can't read, and browsers that tell where
+// a request comes from, through Sec-Fetch-Site or Origin, mustn't say it's another site.
+
+func (a *AuthData) csrfToken(sessionToken string) string {
+	mac := hmac.New(sha256.New, a.cookies.csrfKey)
+	mac.Write([]byte(sessionToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validCSRF lets through requests that don't write, which other sites can make but not read the answers of
+func (a *AuthData) validCSRF(r *http.Request, sessionToken string) bool {
+	if safeMethod(r.Method) {
+		return true
+	}
+
+	return !crossSite(r) && hmac.Equal([]byte(r.Header.Get(CSRFHeader)), []byte(a.csrfToken(sessionToken)))
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// crossSite reports whether the browser says r comes from a page of another origin;
+// API clients send neither header, so they pass
+func crossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		// same-site too, another subdomain may well be someone else's
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// RejectCrossSite answers 403 to requests that write from pages of other sites; it guards
+// the routes that set session cookies, which have no session to check a CSRF token against
+func RejectCrossSite(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.RejectCrossSite"
+
+		if !safeMethod(r.Method) && crossSite(r) {
+			log := slogext.LogWithOp(op, r.Context())
+			errorMsg := "Cross-site request"
+			log.Error(errorMsg, slog.String("origin", r.Header.Get("Origin")), slog.String("fetch-site", r.Header.Get("Sec-Fetch-Site")))
+
+			if err := writeError(w, InvalidCSRFToken, errorMsg, http.StatusForbidden); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDeviceNameLen     = 64
+	maxDevicePlatformLen = 32
+)
+
+// AuthDeviceId holds the device the session token of a request was issued for, if any
+const AuthDeviceId AuthCtx = "auth device id"
+
+func DeviceId(ctx context.Context) string {
+	deviceId, _ := ctx.Value(AuthDeviceId).(string)
+	return deviceId
+}
+
+type DeviceRequest struct {
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+}
+
+func (req DeviceRequest) validate(v *validate.Validator) {
+	if v.Required("name", req.Name) {
+		v.MaxLen("name", req.Name, maxDeviceNameLen)
+	}
+	v.MaxLen("platform", req.Platform, maxDevicePlatformLen)
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type DeviceInfo struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Platform  string `json:"platform,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	LastUsed  int64  `json:"last_used"`
+	// Current is set for the device the request was made from
+	Current bool `json:"current,omitempty"`
+}
+
+type DeviceResponse struct {
+	Device DeviceInfo `json:"device"`
+	// RefreshToken is only ever handed out once, when the device is registered
+	RefreshToken string `json:"refresh_token"`
+	SessionToken string `json:"session_token"`
+}
+
+type SessionsResponse struct {
+	Devices []DeviceInfo `json:"devices"`
+}
+
+func deviceInfo(device db_access.Device, currentId string) DeviceInfo {
+	return DeviceInfo{
+		Id:        device.Id,
+		Name:      device.Name,
+		Platform:  device.Platform,
+		CreatedAt: time.Time(device.CreatedAt).Unix(),
+		LastUsed:  time.Time(device.LastUsed).Unix(),
+		Current:   device.Id == currentId,
+	}
+}
+
+func refreshHash(refreshToken string) []byte {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hash[:]
+}
+
+// writeJSON writes body, or an internal error if it can't be encoded
+func writeJSON(w http.ResponseWriter, log *slog.Logger, body any, statusCode int) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		log.Error("Could not encode response", slogext.Error(err))
+
+		if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(data); err != nil {
+		log.Error("Could not write response", slogext.Error(err))
+	}
+}
+
+// RegisterDevice gives the device a refresh token of its own, along with a first session token;
+// it has to run after Auth
+func RegisterDevice(a *AuthData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.RegisterDevice"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req DeviceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+
+			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		var v validate.Validator
+		req.validate(&v)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		user := db_access.User{Id: UserId(r.Context())}
+		if err := a.db.GetUser(&user); err != nil {
+			log.Error("Could not get user from db", slogext.Error(err), slog.Int64("user-id", user.Id))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		refreshToken := rand.Text()
+		now := db_access.Time(time.Now())
+		device := db_access.Device{
+			Id:          uuid.New().String(),
+			OwnerId:     user.Id,
+			Name:        req.Name,
+			Platform:    req.Platform,
+			RefreshHash: refreshHash(refreshToken),
+			CreatedAt:   now,
+			LastUsed:    now,
+		}
+		if err := a.db.AddDevice(device); err != nil {
+			log.Error("Database error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		token, err := a.newToken(user, device.Id)
+		if err != nil {
+			log.Error("JWT creation error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		log.Info("Registered device", slog.String("device-id", device.Id), slog.Int64("user-id", user.Id))
+		writeJSON(w, log, DeviceResponse{
+			Device:       deviceInfo(device, device.Id),
+			RefreshToken: refreshToken,
+			SessionToken: token,
+		}, http.StatusCreated)
+	}
+}
+
+// Refresh exchanges the refresh token of a device for a new session token of that device
+func Refresh(a *AuthData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.Refresh"
+		log := slogext.LogWithOp(op, r.Context())
+
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+
+			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		var v validate.Validator
+		v.Required("refresh_token", req.RefreshToken)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		device, err := a.db.GetDeviceByRefreshHash(refreshHash(req.RefreshToken))
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "Invalid refresh token"
+			log.Error(errorMsg)
+
+			if err := writeError(w, InvalidCredentials, errorMsg, http.StatusUnauthorized); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		user := db_access.User{Id: device.OwnerId}
+		if err == nil {
+			err = a.db.GetUser(&user)
+		}
+		if err == nil && a.filter != nil && !a.filter.AllowLogin(w, r, user.Id, device.Id) {
+			return
+		}
+		if err == nil {
+			err = a.db.TouchDevice(device.Id, db_access.Time(time.Now()))
+		}
+		if err != nil {
+			log.Error("Database error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		token, err := a.newToken(user, device.Id)
+		if err != nil {
+			log.Error("JWT creation error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		resp := AuthResponse{
+			SessionToken: token,
+		}
+		if err := resp.write(w, http.StatusOK); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+	}
+}
+
+// Sessions lists the devices of the user; it has to run after Auth
+func Sessions(a *AuthData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.Sessions"
+		log := slogext.LogWithOp(op, r.Context())
+
+		devices, err := a.db.GetDevices(UserId(r.Context()))
+		if err != nil {
+			log.Error("Database error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		resp := SessionsResponse{Devices: make([]DeviceInfo, 0, len(devices))}
+		for _, device := range devices {
+			resp.Devices = append(resp.Devices, deviceInfo(device, DeviceId(r.Context())))
+		}
+		writeJSON(w, log, resp, http.StatusOK)
+	}
+}
+
+// RevokeDevice ends the sessions of one device of the user, leaving the others be; it has to run after Auth
+func RevokeDevice(a *AuthData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.RevokeDevice"
+		log := slogext.LogWithOp(op, r.Context())
+
+		deviceId := chi.URLParam(r, "id")
+		err := a.db.RemoveDevice(UserId(r.Context()), deviceId)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			errorMsg := "No device with provided id was found"
+			log.Error(errorMsg, slog.String("device-id", deviceId))
+
+			if err := writeError(w, NotFound, errorMsg, http.StatusNotFound); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		} else if err != nil {
+			log.Error("Database error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		log.Info("Revoked device", slog.String("device-id", deviceId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
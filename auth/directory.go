@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// RoleAdmin grants what being listed in admin-users does
+const RoleAdmin = "admin"
+
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
+// Identity is a user as an external directory knows it
+type Identity struct {
+	// the canonical name, which may differ in case from the one logged in with
+	Name  string
+	Roles []string
+}
+
+// Directory checks credentials against an external user directory such as LDAP.
+// Authenticate returns ErrInvalidCredentials for unknown users and wrong passwords alike.
+type Directory interface {
+	Authenticate(ctx context.Context, name string, password string) (Identity, error)
+}
+
+// directoryLogin checks the password against the directory and brings the local user row,
+// with the roles the directory grants, up to date
+func directoryLogin(a *AuthData, w http.ResponseWriter, r *http.Request, req AuthRequest) (db_access.User, bool) {
+	const op = "auth.directoryLogin"
+	log := slogext.LogWithOp(op, r.Context())
+
+	identity, err := a.directory.Authenticate(r.Context(), req.Name, req.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		errorMsg := "Invalid credentials"
+		log.Error(errorMsg, slog.String("name", req.Name))
+
+		if err := writeError(w, InvalidCredentials, errorMsg, http.StatusUnauthorized); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return db_access.User{}, false
+	} else if err != nil {
+		log.Error("Directory error", slogext.Error(err))
+
+		if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return db_access.User{}, false
+	}
+
+	user, created, err := provisionUser(a.db, identity.Name)
+	if err == nil && !slices.Equal(user.Roles, identity.Roles) {
+		err = a.db.SetUserRoles(user.Id, identity.Roles)
+		user.Roles = identity.Roles
+	}
+	if err != nil {
+		log.Error("Database error", slogext.Error(err))
+
+		if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+			log.Error("Could not write response", slogext.Error(err))
+		}
+		return db_access.User{}, false
+	}
+	if created {
+		log.Info("Provisioned new user", slog.String("name", user.Name))
+	}
+
+	return user, true
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Registrations is how Register answers a name that is taken already.
+// The zero value behaves as RevealTakenNames.
+type Registrations string
+
+const (
+	// RevealTakenNames answers 409, which tells anyone whether a user of that name exists
+	RevealTakenNames Registrations = "reveal"
+	// HideTakenNames answers 204 for taken names as for new ones, so that names can't be probed;
+	// whoever registered a taken name finds out when their password doesn't log them in
+	HideTakenNames Registrations = "hide"
+)
+
+func (reg *Registrations) UnmarshalText(text []byte) error {
+	switch v := Registrations(text); v {
+	case RevealTakenNames, HideTakenNames:
+		*reg = v
+		return nil
+	case "":
+		*reg = RevealTakenNames
+		return nil
+	}
+
+	return fmt.Errorf("unknown registrations mode %q; expected one of reveal, hide", text)
+}
+
+// SetRegistrations sets how Register answers a name that is taken
+func (a *AuthData) SetRegistrations(reg Registrations) {
+	a.registrations = reg
+}
+
+// newDummyHash makes the hash compared with the password of a user that doesn't exist, so that
+// logging in as one takes as long as with a wrong password; it has the cost Register hashes with
+func newDummyHash() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte(rand.Text()), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+}
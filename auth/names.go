@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/utils/validate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const minNameLen = 3
+
+// nonNFC holds the letters NFC replaces with others, and the conjoining jamo it composes into
+// Hangul syllables. Names are kept to letters NFC leaves as they are and can't hold combining marks,
+// so that every name is in NFC without having to compose it.
+var nonNFC = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x0958, Hi: 0x095f, Stride: 1},
+		{Lo: 0x09dc, Hi: 0x09dd, Stride: 1},
+		{Lo: 0x09df, Hi: 0x09df, Stride: 1},
+		{Lo: 0x0a33, Hi: 0x0a36, Stride: 3},
+		{Lo: 0x0a59, Hi: 0x0a5b, Stride: 1},
+		{Lo: 0x0a5e, Hi: 0x0a5e, Stride: 1},
+		{Lo: 0x0b5c, Hi: 0x0b5d, Stride: 1},
+		{Lo: 0x0f43, Hi: 0x0f4d, Stride: 10},
+		{Lo: 0x0f52, Hi: 0x0f5c, Stride: 5},
+		{Lo: 0x0f69, Hi: 0x0f69, Stride: 1},
+		{Lo: 0x1100, Hi: 0x11ff, Stride: 1},
+		{Lo: 0x1f71, Hi: 0x1f7d, Stride: 2},
+		{Lo: 0x1fbb, Hi: 0x1fbb, Stride: 1},
+		{Lo: 0x1fbe, Hi: 0x1fbe, Stride: 1},
+		{Lo: 0x1fc9, Hi: 0x1fcb, Stride: 2},
+		{Lo: 0x1fd3, Hi: 0x1fdb, Stride: 8},
+		{Lo: 0x1fe3, Hi: 0x1feb, Stride: 8},
+		{Lo: 0x1ff9, Hi: 0x1ffb, Stride: 2},
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1},
+		{Lo: 0xd7b0, Hi: 0xd7ff, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfb1d, Hi: 0xfb4f, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x2f800, Hi: 0x2fa1f, Stride: 1},
+	},
+}
+
+// NormalizeName is the form names are kept and looked up in: without surrounding space and
+// case folded, so that Alice and alice are one user
+func NormalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		// through upper case, so that letters with several lower cases, like σ and ς, fold into one
+		return unicode.ToLower(unicode.ToUpper(r))
+	}, strings.TrimSpace(name))
+}
+
+// validateName checks a normalized name against what new names may hold: letters, digits and . _ - @,
+// starting with a letter or digit
+func validateName(v *validate.Validator, param string, name string) {
+	if !v.MaxLen(param, name, maxNameLen) {
+		return
+	}
+	if !v.Check(utf8.RuneCountInString(name) >= minNameLen, param, validate.OutOfRange, fmt.Sprintf("%s must be at least %d characters long", param, minNameLen)) {
+		return
+	}
+
+	for i, r := range name {
+		ok := (unicode.IsLetter(r) || unicode.IsDigit(r)) && !unicode.Is(nonNFC, r)
+		if i > 0 {
+			ok = ok || strings.ContainsRune(".-_@", r)
+		}
+		if !v.Check(ok, param, validate.Malformed, param+" may only hold letters, digits and . _ - @, and has to start with a letter or digit") {
+			return
+		}
+	}
+}
+
+// NormalizeNames renames the users whose names were kept before they were normalized, which has to
+// happen before user names are made unique in any case. Users that already hold their normalized name
+// keep it; the others, by id, get theirs if it is free and otherwise one suffixed with their id,
+// like alice-7, which an admin can change with AdminRenameUser.
+func NormalizeNames(db db_access.UserRepo, log *slog.Logger) (int, error) {
+	const op = "auth.NormalizeNames"
+
+	users, err := db.GetUsers()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	taken := make(map[string]bool, len(users))
+	for _, user := range users {
+		if NormalizeName(user.Name) == user.Name {
+			taken[user.Name] = true
+		}
+	}
+
+	renamed := 0
+	for _, user := range users {
+		name := NormalizeName(user.Name)
+		if name == user.Name {
+			continue
+		}
+
+		if taken[name] {
+			name = freeName(taken, name, user.Id)
+			log.Warn(
+				"User name is taken once normalized, renamed",
+				slog.Int64("user-id", user.Id),
+				slog.String("name", user.Name),
+				slog.String("new-name", name),
+			)
+		}
+		taken[name] = true
+
+		var uce db_access.UniqueConstraintError
+		if err := db.RenameUser(user.Id, name); errors.As(err, &uce) {
+			// only a user added meanwhile can hold it
+			log.Warn("Could not normalize user name, it is taken", slog.Int64("user-id", user.Id), slog.String("name", name))
+			continue
+		} else if err != nil {
+			return renamed, fmt.Errorf("%s: %d: %w", op, user.Id, err)
+		}
+		renamed++
+	}
+
+	return renamed, nil
+}
+
+// freeName suffixes name with the user id, and a counter if that is taken too, cutting the name
+// short so that it fits into maxNameLen
+func freeName(taken map[string]bool, name string, userId int64) string {
+	for i := 1; ; i++ {
+		suffix := fmt.Sprintf("-%d", userId)
+		if i > 1 {
+			suffix += fmt.Sprintf("-%d", i)
+		}
+
+		base := name
+		if room := maxNameLen - len(suffix); len(base) > room {
+			// cutting may split a multibyte character, whose remains are dropped
+			base = strings.ToValidUTF8(base[:room], "")
+		}
+		if candidate := base + suffix; !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+type RenameRequest struct {
+	Name string `json:"name"`
+}
+
+// AdminRenameUser gives the user of the {id} route param a new name, held to the policy of registered
+// ones; it has to be mounted behind Admin
+func AdminRenameUser(db db_access.UserRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.AdminRenameUser"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			errorMsg := "Invalid user id"
+			log.Error(errorMsg, slogext.Error(err))
+
+			if err := writeParamError(w, InvalidContentFormat, "id", errorMsg, http.StatusBadRequest); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		var req RenameRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			errorMsg := "Invalid json"
+			log.Error(errorMsg, slogext.Error(err))
+
+			if err := writeError(w, InvalidContentFormat, errorMsg, http.StatusBadRequest); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		name := NormalizeName(req.Name)
+		var v validate.Validator
+		validateName(&v, "name", name)
+		if !requireValid(w, log, &v) {
+			return
+		}
+
+		var uce db_access.UniqueConstraintError
+		var nre db_access.NoRowsError
+		if err := db.RenameUser(userId, name); errors.As(err, &uce) {
+			errorMsg := "Name already used"
+			log.Error(errorMsg, slog.String("name", name))
+
+			if err := writeParamError(w, NameTaken, "name", errorMsg, http.StatusConflict); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		} else if errors.As(err, &nre) {
+			errorMsg := "User not found"
+			log.Error(errorMsg, slog.Int64("user-id", userId))
+
+			if err := writeError(w, NotFound, errorMsg, http.StatusNotFound); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		} else if err != nil {
+			log.Error("Database error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		log.Info("User renamed", slog.Int64("user-id", userId), slog.String("name", name))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
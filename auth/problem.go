@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"cloud-storage/utils/problem"
+	"net/http"
+)
+
+type problemType struct {
+	name  string
+	title string
+}
+
+var problemTypes = map[AuthErrorCode]problemType{
+	InternalApiError:     {"internal-error", "Internal error"},
+	InvalidContentFormat: {"invalid-content-format", "Invalid content format"},
+	NoSessionToken:       {"no-session-token", "No session token"},
+	InvalidSessionToken:  {"invalid-session-token", "Invalid session token"},
+	InvalidCredentials:   {"invalid-credentials", "Invalid credentials"},
+	NotAdmin:             {"not-admin", "Admin rights required"},
+	UntrustedProxy:       {"untrusted-proxy", "Untrusted proxy"},
+	ParameterOutOfRange:  {"parameter-out-of-range", "Parameter out of range"},
+	InvalidCSRFToken:     {"invalid-csrf-token", "Invalid CSRF token"},
+	NotFound:             {"not-found", "Not found"},
+	NameTaken:            {"name-taken", "Name taken"},
+}
+
+func (code AuthErrorCode) problemType() problemType {
+	if t, ok := problemTypes[code]; ok {
+		return t
+	}
+	return problemType{name: "unknown", title: "Unknown error"}
+}
+
+func writeProblem(w http.ResponseWriter, errs []AuthError, statusCode int) error {
+	first := errs[0]
+	details := problem.Details{
+		Type:   problem.TypeURI(first.Code.problemType().name),
+		Title:  first.Code.problemType().title,
+		Detail: first.Description,
+		Code:   int(first.Code),
+	}
+
+	// a single error without a parameter is fully described by the top level members
+	if len(errs) > 1 || first.ParamName != "" {
+		for _, err := range errs {
+			details.Errors = append(details.Errors, problem.Param{
+				Code:   int(err.Code),
+				Type:   problem.TypeURI(err.Code.problemType().name),
+				Name:   err.ParamName,
+				Detail: err.Description,
+			})
+		}
+	}
+
+	return problem.Write(w, details, statusCode)
+}
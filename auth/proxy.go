@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/utils/realip"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const DefaultIdentityHeader = "X-Forwarded-User"
+
+// ProxyConfig delegates authentication to a fronting proxy such as oauth2-proxy or Cloudflare Access,
+// which puts the name of the signed in user into Header
+type ProxyConfig struct {
+	Header string
+	// networks the proxy connects from; anyone else could set the header themselves
+	Trusted realip.Proxies
+}
+
+// ProxyAuth takes the user from the identity header instead of a session token and creates users
+// it has not seen before. It has to run after realip.Middleware, which keeps the address of the peer.
+func ProxyAuth(db db_access.UserRepo, cfg ProxyConfig) func(http.Handler) http.Handler {
+	header := cfg.Header
+	if header == "" {
+		header = DefaultIdentityHeader
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "auth.ProxyAuth"
+			log := slogext.LogWithOp(op, r.Context())
+
+			peer := realip.PeerAddr(r)
+			if !cfg.Trusted.Trusts(peer) {
+				errorMsg := "Request did not come through the authenticating proxy"
+				log.Error(errorMsg, slog.String("peer", peer))
+
+				if err := writeError(w, UntrustedProxy, errorMsg, http.StatusUnauthorized); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+
+			name := strings.TrimSpace(r.Header.Get(header))
+			if name == "" {
+				errorMsg := "No " + header + " header provided"
+				log.Error(errorMsg)
+
+				if err := writeError(w, NoSessionToken, errorMsg, http.StatusUnauthorized); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+
+			user, created, err := provisionUser(db, name)
+			if err != nil {
+				log.Error("Could not get user from db", slogext.Error(err), slog.String("name", name))
+
+				if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+					log.Error("Could not write response", slogext.Error(err))
+				}
+				return
+			}
+			if created {
+				log.Info("Provisioned new user", slog.String("name", user.Name))
+			}
+
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), AuthUserId, user.Id)))
+		})
+	}
+}
+
+// provisionUser finds the user by normalized name and adds one without a password if there is none,
+// so such users can only ever sign in through the proxy
+func provisionUser(db db_access.UserRepo, name string) (user db_access.User, created bool, err error) {
+	const op = "auth.provisionUser"
+
+	name = NormalizeName(name)
+	user = db_access.User{Name: name}
+	var nre db_access.NoRowsError
+	if err := db.GetUser(&user); err == nil {
+		return user, false, nil
+	} else if !errors.As(err, &nre) {
+		return db_access.User{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user = db_access.User{Name: name}
+	var uce db_access.UniqueConstraintError
+	if err := db.AddUser(&user); errors.As(err, &uce) {
+		// a concurrent first request of the same user got there first
+		user = db_access.User{Name: name}
+		if err := db.GetUser(&user); err != nil {
+			return db_access.User{}, false, fmt.Errorf("%s: %w", op, err)
+		}
+		return user, false, nil
+	} else if err != nil {
+		return db_access.User{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, true, nil
+}
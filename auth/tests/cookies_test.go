@@ -0,0 +1,122 @@
+package auth_test
+
+import (
+	"cloud-storage/auth"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withCookies(r http.Handler, method string, path string, cookies []*http.Cookie, csrfToken string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if csrfToken != "" {
+		req.Header.Set(auth.CSRFHeader, csrfToken)
+	}
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestSessionCookies(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	a := auth.NewAuthData(db, time.Hour)
+	a.UseCookies(auth.CookieConfig{Name: "session", Secure: true, SameSite: http.SameSiteStrictMode})
+	var version int64
+	r := newSessionRouter(t, a, db, &version)
+	r.Post("/session", auth.SessionLogin(a))
+	r.Delete("/session", auth.SessionLogout(a))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/session", strings.NewReader(`{"name": "alice", "password": "secret"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp auth.AuthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.SessionToken)
+	require.NotEmpty(t, resp.CSRFToken)
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 2)
+	session, csrf := cookies[0], cookies[1]
+	assert.Equal(t, "session", session.Name)
+	assert.True(t, session.HttpOnly)
+	assert.True(t, session.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, session.SameSite)
+	assert.Equal(t, 3600, session.MaxAge)
+	assert.Equal(t, "session-csrf", csrf.Name)
+	assert.False(t, csrf.HttpOnly)
+	assert.Equal(t, resp.CSRFToken, csrf.Value)
+
+	// reads only need the cookie, writes the CSRF token as well
+	assert.Equal(t, http.StatusNoContent, withCookies(r, "GET", "/me", cookies, ""))
+	assert.Equal(t, http.StatusForbidden, withCookies(r, "POST", "/sessions/revoke", cookies, ""))
+	assert.Equal(t, http.StatusForbidden, withCookies(r, "POST", "/sessions/revoke", cookies, "forged"))
+	assert.Equal(t, http.StatusNoContent, withCookies(r, "POST", "/sessions/revoke", cookies, resp.CSRFToken))
+	assert.Equal(t, http.StatusUnauthorized, withCookies(r, "GET", "/me", cookies, ""))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/session", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	for _, cookie := range w.Result().Cookies() {
+		assert.Negative(t, cookie.MaxAge)
+	}
+}
+
+func TestSessionCookies_Disabled(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	a := auth.NewAuthData(db, time.Hour)
+	var version int64
+	r := newSessionRouter(t, a, db, &version)
+
+	// Bearer tokens are taken as before, cookies are ignored
+	token := sessionToken(t, r)
+	assert.Equal(t, http.StatusNoContent, withToken(r, "POST", "/sessions/revoke", token))
+	assert.Equal(t, http.StatusUnauthorized, withCookies(r, "GET", "/me", []*http.Cookie{{Name: "session", Value: token}}, ""))
+}
+
+func TestRejectCrossSite(t *testing.T) {
+	h := chi.NewRouter()
+	h.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	h.With(auth.RejectCrossSite).HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	testCases := []struct {
+		method         string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{method: "POST", headers: map[string]string{}, expectedStatus: http.StatusNoContent},
+		{method: "POST", headers: map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"}, expectedStatus: http.StatusNoContent},
+		{method: "POST", headers: map[string]string{"Sec-Fetch-Site": "none"}, expectedStatus: http.StatusNoContent},
+		// logging a victim in as the attacker is as bad as acting as the victim
+		{method: "POST", headers: map[string]string{"Sec-Fetch-Site": "cross-site"}, expectedStatus: http.StatusForbidden},
+		{method: "DELETE", headers: map[string]string{"Sec-Fetch-Site": "same-site"}, expectedStatus: http.StatusForbidden},
+		{method: "POST", headers: map[string]string{"Origin": "https://evil.example"}, expectedStatus: http.StatusForbidden},
+		{method: "POST", headers: map[string]string{"Origin": "null"}, expectedStatus: http.StatusForbidden},
+		{method: "GET", headers: map[string]string{"Sec-Fetch-Site": "cross-site"}, expectedStatus: http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, "http://example.com/session", nil)
+		for name, value := range tc.headers {
+			req.Header.Set(name, value)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, tc.expectedStatus, w.Code, "%s %v", tc.method, tc.headers)
+	}
+}
@@ -0,0 +1,117 @@
+package auth_test
+
+import (
+	"bytes"
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// expectDevices keeps the devices of the db mock in a map
+func expectDevices(db *db_access_mocks.DbAccess) map[string]db_access.Device {
+	devices := make(map[string]db_access.Device)
+	db.EXPECT().AddDevice(mock.Anything).RunAndReturn(func(device db_access.Device) error {
+		devices[device.Id] = device
+		return nil
+	}).Maybe()
+	db.EXPECT().GetDevice(mock.Anything).RunAndReturn(func(id string) (db_access.Device, error) {
+		device, ok := devices[id]
+		if !ok {
+			return db_access.Device{}, db_access.NoRowsError{}
+		}
+		return device, nil
+	}).Maybe()
+	db.EXPECT().GetDeviceByRefreshHash(mock.Anything).RunAndReturn(func(hash []byte) (db_access.Device, error) {
+		for _, device := range devices {
+			if bytes.Equal(device.RefreshHash, hash) {
+				return device, nil
+			}
+		}
+		return db_access.Device{}, db_access.NoRowsError{}
+	}).Maybe()
+	db.EXPECT().TouchDevice(mock.Anything, mock.Anything).Return(nil).Maybe()
+	db.EXPECT().GetDevices(int64(1)).RunAndReturn(func(ownerId int64) ([]db_access.Device, error) {
+		var owned []db_access.Device
+		for _, device := range devices {
+			owned = append(owned, device)
+		}
+		return owned, nil
+	}).Maybe()
+	db.EXPECT().RemoveDevice(int64(1), mock.Anything).RunAndReturn(func(ownerId int64, id string) error {
+		if _, ok := devices[id]; !ok {
+			return db_access.NoRowsError{}
+		}
+		delete(devices, id)
+		return nil
+	}).Maybe()
+	return devices
+}
+
+func sendJSON(r http.Handler, method string, path string, token string, body string, resp any) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	r.ServeHTTP(w, req)
+	if resp != nil {
+		json.Unmarshal(w.Body.Bytes(), resp)
+	}
+	return w.Code
+}
+
+func TestDevices(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	a := auth.NewAuthData(db, time.Hour)
+	var version int64
+	r := newSessionRouter(t, a, db, &version)
+	expectDevices(db)
+	r.Post("/refresh", auth.Refresh(a))
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Auth(a))
+		r.Post("/devices", auth.RegisterDevice(a))
+		r.Get("/sessions", auth.Sessions(a))
+		r.Delete("/sessions/{id}", auth.RevokeDevice(a))
+	})
+
+	login := sessionToken(t, r)
+	register := func(name string) auth.DeviceResponse {
+		var resp auth.DeviceResponse
+		require.Equal(t, http.StatusCreated, sendJSON(r, "POST", "/devices", login, `{"name": "`+name+`", "platform": "ios"}`, &resp))
+		require.NotEmpty(t, resp.RefreshToken)
+		return resp
+	}
+	phone, laptop := register("Phone"), register("Laptop")
+
+	var refreshed auth.AuthResponse
+	require.Equal(t, http.StatusOK, sendJSON(r, "POST", "/refresh", "", `{"refresh_token": "`+phone.RefreshToken+`"}`, &refreshed))
+	assert.Equal(t, http.StatusNoContent, withToken(r, "GET", "/me", refreshed.SessionToken))
+
+	var sessions auth.SessionsResponse
+	require.Equal(t, http.StatusOK, sendJSON(r, "GET", "/sessions", refreshed.SessionToken, "", &sessions))
+	require.Len(t, sessions.Devices, 2)
+	for _, device := range sessions.Devices {
+		assert.Equal(t, device.Id == phone.Device.Id, device.Current, device.Name)
+	}
+
+	// losing the phone ends its sessions and refresh token, but not those of the others
+	assert.Equal(t, http.StatusNoContent, sendJSON(r, "DELETE", "/sessions/"+phone.Device.Id, laptop.SessionToken, "", nil))
+	assert.Equal(t, http.StatusUnauthorized, withToken(r, "GET", "/me", refreshed.SessionToken))
+	assert.Equal(t, http.StatusUnauthorized, withToken(r, "GET", "/me", phone.SessionToken))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(r, "POST", "/refresh", "", `{"refresh_token": "`+phone.RefreshToken+`"}`, nil))
+	assert.Equal(t, http.StatusNoContent, withToken(r, "GET", "/me", laptop.SessionToken))
+	assert.Equal(t, http.StatusNoContent, withToken(r, "GET", "/me", login))
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(r, "DELETE", "/sessions/"+phone.Device.Id, login, "", nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(r, "POST", "/devices", login, `{"platform": "ios"}`, nil))
+}
@@ -0,0 +1,120 @@
+package auth_test
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type stubDirectory map[string]auth.Identity
+
+func (d stubDirectory) Authenticate(ctx context.Context, name string, password string) (auth.Identity, error) {
+	if password == "down" {
+		return auth.Identity{}, errors.New("connection refused")
+	}
+	identity, ok := d[name]
+	if !ok || password != "secret" {
+		return auth.Identity{}, auth.ErrInvalidCredentials
+	}
+	return identity, nil
+}
+
+func login(t *testing.T, db *db_access_mocks.DbAccess, name string, password string) (int, auth.AuthResponse) {
+	a := auth.NewAuthData(db, time.Hour)
+	a.UseDirectory(stubDirectory{
+		"alice": {Name: "alice", Roles: []string{auth.RoleAdmin}},
+		"bob":   {Name: "bob"},
+	})
+
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Post("/login", auth.Login(a))
+
+	w := httptest.NewRecorder()
+	body := `{"name": "` + name + `", "password": "` + password + `"}`
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/login", strings.NewReader(body)))
+
+	var resp auth.AuthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestDirectoryLoginProvisionsUser(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).Return(db_access.NoRowsError{}).Once()
+	db.EXPECT().AddUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		user.Id = 3
+		return nil
+	}).Once()
+	db.EXPECT().SetUserRoles(int64(3), []string{auth.RoleAdmin}).Return(nil).Once()
+
+	status, resp := login(t, db, "alice", "secret")
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp.SessionToken)
+}
+
+func TestDirectoryLoginKeepsRoles(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		user.Id = 4
+		return nil
+	}).Once()
+
+	// roles match the directory already, so nothing is written
+	status, _ := login(t, db, "bob", "secret")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestDirectoryLoginRejects(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+
+	status, resp := login(t, db, "alice", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, auth.InvalidCredentials, resp.Errors[0].Code)
+
+	status, _ = login(t, db, "alice", "down")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestAdminRole(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		user.Name = "carol"
+		if user.Id == 1 {
+			user.Roles = []string{auth.RoleAdmin}
+		}
+		return nil
+	})
+
+	for userId, expected := range map[int64]int{1: http.StatusNoContent, 2: http.StatusForbidden} {
+		r := chi.NewRouter()
+		r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.AuthUserId, userId)))
+			})
+		})
+		r.With(auth.Admin(db, nil)).Get("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, expected, w.Code, "user %d", userId)
+	}
+}
@@ -0,0 +1,168 @@
+package auth_test
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newAuthRouter(a *auth.AuthData) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Post("/register", auth.Register(a))
+	r.Post("/login", auth.Login(a))
+	return r
+}
+
+func post(r http.Handler, path string, name string, password string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body := `{"name": "` + name + `", "password": "` + password + `"}`
+	r.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+	return w
+}
+
+func TestLocalLogin_UnknownUser(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		if user.Name != "alice" {
+			return db_access.NoRowsError{}
+		}
+		user.Id = 1
+		user.PasswordHash = hash
+		return nil
+	})
+	r := newAuthRouter(auth.NewAuthData(db, time.Hour))
+
+	unknown := post(r, "/login", "mallory", "secret")
+	wrong := post(r, "/login", "alice", "guess")
+
+	// an unknown user can't be told from a wrong password
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	assert.Equal(t, wrong.Code, unknown.Code)
+	assert.Equal(t, wrong.Body.String(), unknown.Body.String())
+
+	assert.Equal(t, http.StatusOK, post(r, "/login", "alice", "secret").Code)
+}
+
+func TestRegister_TakenName(t *testing.T) {
+	testCases := []struct {
+		registrations  auth.Registrations
+		expectedStatus int
+	}{
+		{registrations: "", expectedStatus: http.StatusConflict},
+		{registrations: auth.RevealTakenNames, expectedStatus: http.StatusConflict},
+		{registrations: auth.HideTakenNames, expectedStatus: http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.registrations), func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			db.EXPECT().AddUser(mock.Anything).Return(db_access.UniqueConstraintError{Table: "users", Column: "name"}).Once()
+			a := auth.NewAuthData(db, time.Hour)
+			a.SetRegistrations(tc.registrations)
+
+			w := post(newAuthRouter(a), "/register", "alice", "secret")
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusConflict {
+				return
+			}
+
+			var resp auth.AuthResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, []auth.AuthError{{Code: auth.NameTaken, ParamName: "name", Description: "Name already used"}}, resp.Errors)
+		})
+	}
+}
+
+func TestRegistrations_UnmarshalText(t *testing.T) {
+	var reg auth.Registrations
+	require.NoError(t, reg.UnmarshalText([]byte("hide")))
+	assert.Equal(t, auth.HideTakenNames, reg)
+	require.NoError(t, reg.UnmarshalText(nil))
+	assert.Equal(t, auth.RevealTakenNames, reg)
+	assert.Error(t, reg.UnmarshalText([]byte("silent")))
+}
+
+type loginWatcher []int64
+
+func (w *loginWatcher) LoggedIn(r *http.Request, userId int64) {
+	*w = append(*w, userId)
+}
+
+func TestWatchLogins(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		user.Id = 1
+		user.PasswordHash = hash
+		return nil
+	})
+	a := auth.NewAuthData(db, time.Hour)
+	var watcher loginWatcher
+	a.WatchLogins(&watcher)
+	r := newAuthRouter(a)
+
+	assert.Equal(t, http.StatusUnauthorized, post(r, "/login", "alice", "guess").Code)
+	assert.Equal(t, http.StatusOK, post(r, "/login", "alice", "secret").Code)
+	assert.Equal(t, loginWatcher{1}, watcher)
+}
+
+// loginFilter turns down the devices in denied, and logins without one when "" is in it
+type loginFilter struct {
+	denied map[string]bool
+	asked  []string
+}
+
+func (f *loginFilter) AllowLogin(w http.ResponseWriter, r *http.Request, userId int64, deviceId string) bool {
+	f.asked = append(f.asked, deviceId)
+	if f.denied[deviceId] {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func TestFilterLogins(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	a := auth.NewAuthData(db, time.Hour)
+	var version int64
+	r := newSessionRouter(t, a, db, &version)
+	expectDevices(db)
+	r.Post("/refresh", auth.Refresh(a))
+	r.With(auth.Auth(a)).Post("/devices", auth.RegisterDevice(a))
+
+	var phone auth.DeviceResponse
+	require.Equal(t, http.StatusCreated, sendJSON(r, "POST", "/devices", sessionToken(t, r), `{"name": "Phone", "platform": "ios"}`, &phone))
+
+	filter := loginFilter{denied: map[string]bool{phone.Device.Id: true}}
+	a.FilterLogins(&filter)
+
+	// wrong credentials are turned down before the filter is asked
+	assert.Equal(t, http.StatusUnauthorized, post(r, "/login", "alice", "guess").Code)
+	assert.Empty(t, filter.asked)
+
+	assert.Equal(t, http.StatusOK, post(r, "/login", "alice", "secret").Code)
+	assert.Equal(t, http.StatusForbidden, sendJSON(r, "POST", "/refresh", "", `{"refresh_token": "`+phone.RefreshToken+`"}`, nil))
+	assert.Equal(t, []string{"", phone.Device.Id}, filter.asked)
+
+	filter.denied[""] = true
+	assert.Equal(t, http.StatusForbidden, post(r, "/login", "alice", "secret").Code)
+}
@@ -0,0 +1,138 @@
+package auth_test
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNormalizeName(t *testing.T) {
+	testCases := map[string]string{
+		"alice":       "alice",
+		"  Alice\t":   "alice",
+		"ALICE":       "alice",
+		"Jürgen":      "jürgen",
+		"σοφος":       "σοφοσ",
+		"ΣΟΦΟΣ":       "σοφοσ",
+		"\u212Aelvin": "kelvin",
+	}
+
+	for name, expected := range testCases {
+		assert.Equal(t, expected, auth.NormalizeName(name), name)
+	}
+}
+
+func TestRegister_InvalidName(t *testing.T) {
+	testCases := []struct {
+		name           string
+		expectedStatus int
+		expectedCode   auth.AuthErrorCode
+	}{
+		{name: "al", expectedStatus: http.StatusUnprocessableEntity, expectedCode: auth.ParameterOutOfRange},
+		{name: "ali ce", expectedStatus: http.StatusBadRequest, expectedCode: auth.InvalidContentFormat},
+		{name: "-alice", expectedStatus: http.StatusBadRequest, expectedCode: auth.InvalidContentFormat},
+		{name: "alice!", expectedStatus: http.StatusBadRequest, expectedCode: auth.InvalidContentFormat},
+		// decomposed, which NFC would compose into é
+		{name: "e\u0301lise", expectedStatus: http.StatusBadRequest, expectedCode: auth.InvalidContentFormat},
+		// conjoining jamo, which NFC would compose into a syllable
+		{name: "\u1100\u1161bc", expectedStatus: http.StatusBadRequest, expectedCode: auth.InvalidContentFormat},
+		// a compatibility ideograph, which NFC would replace
+		{name: "\uf900ab", expectedStatus: http.StatusBadRequest, expectedCode: auth.InvalidContentFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := auth.NewAuthData(db_access_mocks.NewDbAccess(t), time.Hour)
+
+			w := post(newAuthRouter(a), "/register", tc.name, "secret")
+			assert.Equal(t, tc.expectedStatus, w.Code)
+
+			var resp auth.AuthResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, 1, len(resp.Errors))
+			assert.Equal(t, "name", resp.Errors[0].ParamName)
+			assert.Equal(t, tc.expectedCode, resp.Errors[0].Code)
+		})
+	}
+}
+
+func TestRegister_NormalizesName(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().AddUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		assert.Equal(t, "jürgen", user.Name)
+		return nil
+	}).Once()
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		if user.Name != "jürgen" {
+			return db_access.NoRowsError{}
+		}
+		user.Id = 1
+		user.PasswordHash = hash
+		return nil
+	})
+	r := newAuthRouter(auth.NewAuthData(db, time.Hour))
+
+	assert.Equal(t, http.StatusNoContent, post(r, "/register", " Jürgen ", "secret").Code)
+	// logging in takes the name in any case
+	assert.Equal(t, http.StatusOK, post(r, "/login", "JÜRGEN", "secret").Code)
+	assert.Equal(t, http.StatusOK, post(r, "/login", "jürgen", "secret").Code)
+}
+
+func TestNormalizeNames(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUsers().Return([]db_access.User{
+		{Id: 1, Name: "Alice"},
+		{Id: 2, Name: "Bob"},
+		{Id: 3, Name: " carol"},
+		{Id: 4, Name: "Carol"},
+		{Id: 5, Name: "alice"},
+		{Id: 6, Name: "carol-4"},
+	}, nil).Once()
+	// alice is kept by the user that has it already, carol goes to the first to ask for it
+	db.EXPECT().RenameUser(int64(1), "alice-1").Return(nil).Once()
+	db.EXPECT().RenameUser(int64(2), "bob").Return(nil).Once()
+	db.EXPECT().RenameUser(int64(3), "carol").Return(nil).Once()
+	db.EXPECT().RenameUser(int64(4), "carol-4-2").Return(nil).Once()
+
+	n, err := auth.NormalizeNames(db, slogext.NewDiscardLogger())
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+}
+
+func TestAdminRenameUser(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().RenameUser(int64(1), "alice").Return(nil).Once()
+	db.EXPECT().RenameUser(int64(1), "bob").Return(db_access.UniqueConstraintError{Table: "users", Column: "name"}).Once()
+	db.EXPECT().RenameUser(int64(2), "carol").Return(db_access.NoRowsError{Table: "users"}).Once()
+
+	r := chi.NewRouter()
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Put("/users/{id}/name", auth.AdminRenameUser(db))
+	rename := func(id string, name string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("PUT", "/users/"+id+"/name", strings.NewReader(`{"name": "`+name+`"}`)))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, rename("1", " Alice"))
+	assert.Equal(t, http.StatusConflict, rename("1", "bob"))
+	assert.Equal(t, http.StatusNotFound, rename("2", "carol"))
+	assert.Equal(t, http.StatusUnprocessableEntity, rename("1", "-x"))
+	assert.Equal(t, http.StatusBadRequest, rename("x", "dave"))
+}
@@ -0,0 +1,102 @@
+package auth_test
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/utils/realip"
+	slogext "cloud-storage/utils/slogExt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newProxyRouter(t *testing.T, db *db_access_mocks.DbAccess, seen *int64) http.Handler {
+	trusted, err := realip.ParseProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(realip.Middleware(trusted))
+	r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+	r.Use(auth.ProxyAuth(db, auth.ProxyConfig{Trusted: trusted}))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		*seen = auth.UserId(r.Context())
+	})
+
+	return r
+}
+
+func proxyRequest(h http.Handler, remoteAddr string, user string) int {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = remoteAddr
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if user != "" {
+		r.Header.Set(auth.DefaultIdentityHeader, user)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestProxyAuthKnownUser(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		assert.Equal(t, "alice", user.Name)
+		user.Id = 7
+		return nil
+	}).Once()
+
+	var seen int64
+	status := proxyRequest(newProxyRouter(t, db, &seen), "10.0.0.2:1234", "alice")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(7), seen)
+}
+
+func TestProxyAuthProvisionsUser(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).Return(db_access.NoRowsError{}).Once()
+	db.EXPECT().AddUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		assert.Equal(t, "bob", user.Name)
+		assert.Nil(t, user.PasswordHash)
+		user.Id = 8
+		return nil
+	}).Once()
+
+	var seen int64
+	status := proxyRequest(newProxyRouter(t, db, &seen), "10.0.0.2:1234", "bob")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(8), seen)
+}
+
+func TestProxyAuthProvisionRace(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetUser(mock.Anything).Return(db_access.NoRowsError{}).Once()
+	db.EXPECT().AddUser(mock.Anything).Return(db_access.UniqueConstraintError{}).Once()
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		user.Id = 9
+		return nil
+	}).Once()
+
+	var seen int64
+	status := proxyRequest(newProxyRouter(t, db, &seen), "10.0.0.2:1234", "carol")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(9), seen)
+}
+
+func TestProxyAuthRejects(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+
+	var seen int64 = -1
+	h := newProxyRouter(t, db, &seen)
+
+	// the header is only believed from the proxy itself, whatever X-Forwarded-For says
+	assert.Equal(t, http.StatusUnauthorized, proxyRequest(h, "203.0.113.7:1234", "alice"))
+	assert.Equal(t, http.StatusUnauthorized, proxyRequest(h, "10.0.0.2:1234", ""))
+	assert.Equal(t, int64(-1), seen)
+}
@@ -0,0 +1,98 @@
+package auth_test
+
+import (
+	"cloud-storage/auth"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newSessionRouter serves /login and, behind Auth, /me and /sessions/revoke for alice,
+// whose token version the db keeps in version
+func newSessionRouter(t *testing.T, a *auth.AuthData, db *db_access_mocks.DbAccess, version *int64) *chi.Mux {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	db.EXPECT().GetUser(mock.Anything).RunAndReturn(func(user *db_access.User) error {
+		if user.Name != "alice" && user.Id != 1 {
+			return db_access.NoRowsError{}
+		}
+		*user = db_access.User{Id: 1, Name: "alice", PasswordHash: hash, TokenVersion: *version}
+		return nil
+	}).Maybe()
+	db.EXPECT().RevokeUserTokens(int64(1)).RunAndReturn(func(userId int64) error {
+		*version++
+		return nil
+	}).Maybe()
+
+	r := newAuthRouter(a)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Auth(a))
+		r.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		r.Post("/sessions/revoke", auth.RevokeSessions(a))
+	})
+	return r
+}
+
+func sessionToken(t *testing.T, r http.Handler) string {
+	w := post(r, "/login", "alice", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp auth.AuthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.SessionToken
+}
+
+func withToken(r http.Handler, method string, path string, token string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRevokeSessions(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	a := auth.NewAuthData(db, time.Hour)
+	var version int64
+	r := newSessionRouter(t, a, db, &version)
+
+	first, second := sessionToken(t, r), sessionToken(t, r)
+	assert.Equal(t, http.StatusNoContent, withToken(r, "GET", "/me", first))
+
+	// revoking ends every session of the user, not only the one it was called with
+	assert.Equal(t, http.StatusNoContent, withToken(r, "POST", "/sessions/revoke", first))
+	assert.Equal(t, http.StatusUnauthorized, withToken(r, "GET", "/me", first))
+	assert.Equal(t, http.StatusUnauthorized, withToken(r, "GET", "/me", second))
+
+	assert.Equal(t, http.StatusNoContent, withToken(r, "GET", "/me", sessionToken(t, r)))
+}
+
+func TestTokenConfig(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	a := auth.NewAuthData(db, time.Hour)
+	a.SetTokenConfig(auth.TokenConfig{Issuer: "storage-1", Audience: "api", Leeway: time.Minute})
+	var version int64
+	r := newSessionRouter(t, a, db, &version)
+
+	token := sessionToken(t, r)
+	assert.Equal(t, http.StatusNoContent, withToken(r, "GET", "/me", token))
+
+	// tokens are only taken for the issuer and audience they were issued for
+	a.SetTokenConfig(auth.TokenConfig{Issuer: "storage-1", Audience: "admin"})
+	assert.Equal(t, http.StatusUnauthorized, withToken(r, "GET", "/me", token))
+	a.SetTokenConfig(auth.TokenConfig{Issuer: "storage-2", Audience: "api"})
+	assert.Equal(t, http.StatusUnauthorized, withToken(r, "GET", "/me", token))
+}
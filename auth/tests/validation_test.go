@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"cloud-storage/auth"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	slogext "cloud-storage/utils/slogExt"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthValidation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		body           string
+		expectedErrors []auth.AuthError
+	}{
+		{
+			name: "Register empty",
+			path: "/register",
+			body: `{}`,
+			expectedErrors: []auth.AuthError{
+				{Code: auth.InvalidContentFormat, ParamName: "name", Description: "name is required"},
+				{Code: auth.InvalidContentFormat, ParamName: "password", Description: "password is required"},
+			},
+		},
+		{
+			name: "Register too long",
+			path: "/register",
+			body: `{"name": "` + strings.Repeat("n", 65) + `", "password": "` + strings.Repeat("p", 73) + `"}`,
+			expectedErrors: []auth.AuthError{
+				{Code: auth.ParameterOutOfRange, ParamName: "name", Description: "name must be at most 64 bytes long"},
+				{Code: auth.ParameterOutOfRange, ParamName: "password", Description: "password must be at most 72 bytes long"},
+			},
+		},
+		{
+			name: "Login empty",
+			path: "/login",
+			body: `{"name": "alice"}`,
+			expectedErrors: []auth.AuthError{
+				{Code: auth.InvalidContentFormat, ParamName: "password", Description: "password is required"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := auth.NewAuthData(db_access_mocks.NewDbAccess(t), time.Hour)
+
+			r := chi.NewRouter()
+			r.Use(slogext.Logger(slogext.NewDiscardLogger()))
+			r.Post("/register", auth.Register(a))
+			r.Post("/login", auth.Login(a))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body)))
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+			var resp auth.AuthResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.expectedErrors, resp.Errors)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenConfig is what session tokens are issued for and checked against
+type TokenConfig struct {
+	Issuer   string
+	Audience string
+	// Leeway is how far the clocks of the servers taking a token may be off from the one that issued it
+	Leeway time.Duration
+}
+
+var defaultTokenConfig = TokenConfig{Issuer: "cloud-storage", Audience: "cloud-storage"}
+
+// SetTokenConfig replaces the issuer and audience of session tokens, which ends the sessions
+// issued for the ones before
+func (a *AuthData) SetTokenConfig(cfg TokenConfig) {
+	a.tokens = cfg
+}
+
+// newToken returns a session token of user, valid from now on for tokenTimeToLive;
+// deviceId is empty unless it is issued for a device
+func (a *AuthData) newToken(user db_access.User, deviceId string) (string, error) {
+	const op = "auth.AuthData.newToken"
+
+	now := time.Now()
+	claims := Claims{
+		UserId:       user.Id,
+		TokenVersion: user.TokenVersion,
+		DeviceId:     deviceId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    a.tokens.Issuer,
+			Audience:  jwt.ClaimStrings{a.tokens.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.tokenTimeToLive)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.tokenKey)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// parseToken returns the claims of a token that is valid now; see revoked for its version
+func (a *AuthData) parseToken(sessionToken string) (*Claims, error) {
+	const op = "auth.AuthData.parseToken"
+
+	token, err := jwt.ParseWithClaims(
+		sessionToken,
+		&Claims{},
+		func(t *jwt.Token) (any, error) {
+			return a.tokenKey, nil
+		},
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithIssuer(a.tokens.Issuer),
+		jwt.WithAudience(a.tokens.Audience),
+		jwt.WithLeeway(a.tokens.Leeway),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, fmt.Errorf("%s: invalid claims type", op)
+	}
+
+	return claims, nil
+}
+
+// revoked reports whether the user of claims is gone or has revoked the tokens of its version,
+// or of its device
+func (a *AuthData) revoked(claims *Claims) (bool, error) {
+	const op = "auth.AuthData.revoked"
+
+	user := db_access.User{Id: claims.UserId}
+	var nre db_access.NoRowsError
+	if err := a.db.GetUser(&user); errors.As(err, &nre) {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.TokenVersion != claims.TokenVersion {
+		return true, nil
+	}
+	if claims.DeviceId == "" {
+		return false, nil
+	}
+
+	device, err := a.db.GetDevice(claims.DeviceId)
+	if errors.As(err, &nre) {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return device.OwnerId != claims.UserId, nil
+}
+
+// RevokeSessions ends every session of the user, the one it is called with included; it has to run after Auth
+func RevokeSessions(a *AuthData) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "auth.RevokeSessions"
+		log := slogext.LogWithOp(op, r.Context())
+
+		userId := UserId(r.Context())
+		if err := a.db.RevokeUserTokens(userId); err != nil {
+			log.Error("Database error", slogext.Error(err))
+
+			if err := writeError(w, InternalApiError, "", http.StatusServiceUnavailable); err != nil {
+				log.Error("Could not write response", slogext.Error(err))
+			}
+			return
+		}
+
+		log.Info("Revoked all sessions", slog.Int64("user-id", userId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
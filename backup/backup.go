@@ -0,0 +1,318 @@
+package backup
+
+import (
+	"cloud-storage/db_access/sqlite"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	manifestFileName = "manifest.json"
+	dbFileName       = "db.sqlite"
+	blobsDirName     = "blobs"
+)
+
+const manifestVersion = 1
+
+type FileEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Db        FileEntry   `json:"db"`
+	Blobs     []FileEntry `json:"blobs"`
+}
+
+type MissingBlobsError struct {
+	Names []string
+}
+
+func (err MissingBlobsError) Error() string {
+	return fmt.Sprintf("%d blobs referenced by the db are missing", len(err.Names))
+}
+
+type ManifestMismatchError struct {
+	Name   string
+	Reason string
+}
+
+func (err ManifestMismatchError) Error() string {
+	return fmt.Sprintf("%s does not match the manifest: %s", err.Name, err.Reason)
+}
+
+// Create snapshots the db at dbPath and every blob it references into outDir
+// and writes a manifest describing the snapshot.
+func Create(ctx context.Context, dbPath string, storageDir string, outDir string) (*Manifest, error) {
+	const op = "backup.Create"
+
+	if err := os.MkdirAll(filepath.Join(outDir, blobsDirName), 0o700); err != nil {
+		return nil, fmt.Errorf("%s: os.MkdirAll: %w", op, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, manifestFileName)); err == nil {
+		return nil, fmt.Errorf("%s: %s already contains a backup", op, outDir)
+	}
+
+	snapshotPath := filepath.Join(outDir, dbFileName)
+	if err := sqlite.Backup(ctx, dbPath, snapshotPath); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	manifest := Manifest{
+		Version:   manifestVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var err error
+	manifest.Db, err = describeFile(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the snapshot is the source of truth for which blobs we need
+	names, err := blobNames(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var missing []string
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entry, err := copyFile(filepath.Join(storageDir, name), filepath.Join(outDir, blobsDirName, name))
+		if errors.Is(err, os.ErrNotExist) {
+			missing = append(missing, name)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entry.Name = name
+		manifest.Blobs = append(manifest.Blobs, entry)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s: %w", op, MissingBlobsError{Names: missing})
+	}
+
+	if err := writeManifest(filepath.Join(outDir, manifestFileName), &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &manifest, nil
+}
+
+// Verify checks that the backup in dir is complete: the db snapshot and every blob
+// match their manifest entries, and every file row of the snapshot has a blob.
+func Verify(dir string) (*Manifest, error) {
+	const op = "backup.Verify"
+
+	manifest, err := readManifest(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("%s: unsupported manifest version %d", op, manifest.Version)
+	}
+
+	if err := verifyFile(filepath.Join(dir, dbFileName), manifest.Db); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	listed := make(map[string]struct{}, len(manifest.Blobs))
+	for _, blob := range manifest.Blobs {
+		if filepath.Base(blob.Name) != blob.Name {
+			return nil, fmt.Errorf("%s: %w", op, ManifestMismatchError{Name: blob.Name, Reason: "invalid blob name"})
+		}
+
+		if err := verifyFile(filepath.Join(dir, blobsDirName, blob.Name), blob); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		listed[blob.Name] = struct{}{}
+	}
+
+	names, err := blobNames(filepath.Join(dir, dbFileName))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var missing []string
+	for _, name := range names {
+		if _, ok := listed[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s: %w", op, MissingBlobsError{Names: missing})
+	}
+
+	return manifest, nil
+}
+
+// Restore verifies the backup in dir and then restores the db into dbPath and blobs into storageDir.
+// Existing db is only replaced when force is set.
+func Restore(ctx context.Context, dir string, dbPath string, storageDir string, force bool) (*Manifest, error) {
+	const op = "backup.Restore"
+
+	manifest, err := Verify(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := os.Stat(dbPath); err == nil && !force {
+		return nil, fmt.Errorf("%s: db %s already exists", op, dbPath)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: os.Stat: %w", op, err)
+	}
+
+	if err := os.MkdirAll(storageDir, 0o700); err != nil {
+		return nil, fmt.Errorf("%s: os.MkdirAll: %w", op, err)
+	}
+
+	// blobs go first so that a restored db never references a blob that is not there yet
+	for _, blob := range manifest.Blobs {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entry, err := copyFile(filepath.Join(dir, blobsDirName, blob.Name), filepath.Join(storageDir, blob.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if entry.Sha256 != blob.Sha256 {
+			return nil, fmt.Errorf("%s: %w", op, ManifestMismatchError{Name: blob.Name, Reason: "checksum changed while restoring"})
+		}
+	}
+
+	if err := sqlite.Backup(ctx, filepath.Join(dir, dbFileName), dbPath); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return manifest, nil
+}
+
+func blobNames(dbPath string) ([]string, error) {
+	// snapshot must stay byte-for-byte identical to what the manifest describes
+	db, err := sqlite.New("file:" + dbPath + "?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := db.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	return db.ListBlobNames()
+}
+
+func describeFile(path string) (FileEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, file)
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("hash %s: %w", path, err)
+	}
+
+	return FileEntry{
+		Name:   filepath.Base(path),
+		Size:   n,
+		Sha256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func verifyFile(path string, expected FileEntry) error {
+	actual, err := describeFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ManifestMismatchError{Name: expected.Name, Reason: "file is missing"}
+	} else if err != nil {
+		return err
+	}
+
+	if actual.Size != expected.Size {
+		return ManifestMismatchError{Name: expected.Name, Reason: "size differs"}
+	}
+
+	if actual.Sha256 != expected.Sha256 {
+		return ManifestMismatchError{Name: expected.Name, Reason: "checksum differs"}
+	}
+
+	return nil
+}
+
+func copyFile(srcPath string, destPath string) (FileEntry, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer src.Close()
+
+	dest, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("create %s: %w", destPath, err)
+	}
+	defer dest.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(dest, hash), src)
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("copy %s: %w", srcPath, err)
+	}
+
+	if err := dest.Sync(); err != nil {
+		return FileEntry{}, fmt.Errorf("sync %s: %w", destPath, err)
+	}
+
+	return FileEntry{
+		Name:   filepath.Base(destPath),
+		Size:   n,
+		Sha256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func writeManifest(path string, manifest *Manifest) error {
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	return nil
+}
+
+func readManifest(path string) (*Manifest, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return &manifest, nil
+}
@@ -0,0 +1,88 @@
+package backup_test
+
+import (
+	"cloud-storage/backup"
+	"cloud-storage/db_access/sqlite"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupRestore(t *testing.T) {
+	root := t.TempDir()
+	dbPath := filepath.Join(root, "db.sqlite")
+	storageDir := filepath.Join(root, "files")
+	backupDir := filepath.Join(root, "backup")
+	assert.NoError(t, os.Mkdir(storageDir, 0o700))
+
+	db, err := sqlite.New(dbPath)
+	assert.NoError(t, err)
+	assert.NoError(t, db.AddFile("blob-1", "name-1", 1, 0))
+	assert.NoError(t, os.WriteFile(filepath.Join(storageDir, "blob-1"), []byte("content"), 0o600))
+
+	manifest, err := backup.Create(context.Background(), dbPath, storageDir, backupDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(manifest.Blobs))
+
+	_, err = backup.Verify(backupDir)
+	assert.NoError(t, err)
+
+	restoreRoot := t.TempDir()
+	restoredDb := filepath.Join(restoreRoot, "db.sqlite")
+	restoredStorage := filepath.Join(restoreRoot, "files")
+
+	_, err = backup.Restore(context.Background(), backupDir, restoredDb, restoredStorage, false)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(restoredStorage, "blob-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("content"), content)
+
+	restored, err := sqlite.New(restoredDb)
+	assert.NoError(t, err)
+	file, err := restored.GetFile("blob-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "name-1", file.FileName)
+}
+
+func TestVerify_CorruptedBlob(t *testing.T) {
+	root := t.TempDir()
+	dbPath := filepath.Join(root, "db.sqlite")
+	storageDir := filepath.Join(root, "files")
+	backupDir := filepath.Join(root, "backup")
+	assert.NoError(t, os.Mkdir(storageDir, 0o700))
+
+	db, err := sqlite.New(dbPath)
+	assert.NoError(t, err)
+	assert.NoError(t, db.AddFile("blob-1", "name-1", 1, 0))
+	assert.NoError(t, os.WriteFile(filepath.Join(storageDir, "blob-1"), []byte("content"), 0o600))
+
+	_, err = backup.Create(context.Background(), dbPath, storageDir, backupDir)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(backupDir, "blobs", "blob-1"), []byte("c0ntent"), 0o600))
+
+	_, err = backup.Verify(backupDir)
+	var mme backup.ManifestMismatchError
+	assert.ErrorAs(t, err, &mme)
+	assert.Equal(t, "blob-1", mme.Name)
+}
+
+func TestCreate_MissingBlob(t *testing.T) {
+	root := t.TempDir()
+	dbPath := filepath.Join(root, "db.sqlite")
+	storageDir := filepath.Join(root, "files")
+	assert.NoError(t, os.Mkdir(storageDir, 0o700))
+
+	db, err := sqlite.New(dbPath)
+	assert.NoError(t, err)
+	assert.NoError(t, db.AddFile("blob-1", "name-1", 1, 0))
+
+	_, err = backup.Create(context.Background(), dbPath, storageDir, filepath.Join(root, "backup"))
+	var mbe backup.MissingBlobsError
+	assert.ErrorAs(t, err, &mbe)
+	assert.Equal(t, []string{"blob-1"}, mbe.Names)
+}
@@ -0,0 +1,113 @@
+// Package blobstore keeps encrypted blobs on one of several backends. Every file row
+// remembers the backend of its blob, so blobs can move between backends behind the files' backs.
+package blobstore
+
+import (
+	"cloud-storage/storage"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Local is the backend of the storage dir; blobs are always written there first
+const Local = "local"
+
+// Object is an open blob; Size is what the backend stores, not the plaintext size
+type Object struct {
+	io.ReadCloser
+	Size int64
+}
+
+type Backend interface {
+	// Open fails with an error wrapping fs.ErrNotExist if there is no blob with that name
+	Open(ctx context.Context, name string) (Object, error)
+	// Put stores exactly size bytes of r under name, replacing any blob with that name
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// Remove does nothing if there is no blob with that name
+	Remove(ctx context.Context, name string) error
+}
+
+type UnknownBackendError struct {
+	Name string
+}
+
+func (err UnknownBackendError) Error() string {
+	return fmt.Sprintf("unknown storage backend %q", err.Name)
+}
+
+type Store struct {
+	dir      string
+	backends map[string]Backend
+}
+
+// NewStore serves the local backend from dir next to the remote ones; a remote named Local is ignored
+func NewStore(dir string, durability storage.Durability, remotes map[string]Backend) *Store {
+	backends := make(map[string]Backend, len(remotes)+1)
+	for name, backend := range remotes {
+		backends[name] = backend
+	}
+	backends[Local] = NewLocal(dir, durability)
+
+	return &Store{dir: dir, backends: backends}
+}
+
+// Dir is the storage dir, which also holds derived data such as hls streams regardless of the blob backend
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Backend returns the backend with that name; an empty name is the local one,
+// as it is for files stored before backends were recorded
+func (s *Store) Backend(name string) (Backend, error) {
+	if name == "" {
+		name = Local
+	}
+
+	backend, ok := s.backends[name]
+	if !ok {
+		return nil, UnknownBackendError{Name: name}
+	}
+
+	return backend, nil
+}
+
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.backends))
+	for name := range s.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Store) Open(ctx context.Context, backend string, name string) (Object, error) {
+	const op = "blobstore.Store.Open"
+
+	b, err := s.Backend(backend)
+	if err != nil {
+		return Object{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	obj, err := b.Open(ctx, name)
+	if err != nil {
+		return Object{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return obj, nil
+}
+
+func (s *Store) Remove(ctx context.Context, backend string, name string) error {
+	const op = "blobstore.Store.Remove"
+
+	b, err := s.Backend(backend)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := b.Remove(ctx, name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
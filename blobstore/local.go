@@ -0,0 +1,73 @@
+package blobstore
+
+import (
+	"cloud-storage/storage"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+type local struct {
+	dir        string
+	durability storage.Durability
+}
+
+func NewLocal(dir string, durability storage.Durability) Backend {
+	return local{dir: dir, durability: durability}
+}
+
+func (l local) Open(_ context.Context, name string) (Object, error) {
+	const op = "blobstore.local.Open"
+
+	path, err := storage.BlobPath(l.dir, name)
+	if err != nil {
+		return Object{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Object{}, fmt.Errorf("%s: os.Open: %w", op, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return Object{}, fmt.Errorf("%s: file.Stat: %w", op, err)
+	}
+
+	return Object{ReadCloser: file, Size: info.Size()}, nil
+}
+
+func (l local) Put(_ context.Context, name string, r io.Reader, size int64) error {
+	const op = "blobstore.local.Put"
+
+	file, err := storage.CreateTemp(l.dir, l.durability)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Discard()
+
+	n, err := io.Copy(file, io.LimitReader(r, size))
+	if err != nil {
+		return fmt.Errorf("%s: io.Copy: %w", op, err)
+	} else if n != size {
+		return fmt.Errorf("%s: got %d bytes, expected %d: %w", op, n, size, io.ErrUnexpectedEOF)
+	}
+
+	if err := file.CommitAs(name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (l local) Remove(_ context.Context, name string) error {
+	const op = "blobstore.local.Remove"
+
+	if err := storage.Remove(l.dir, l.durability, name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package blobstore
+
+import (
+	"cloud-storage/utils/sigv4"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"access-key"`
+	SecretKey string `json:"secret-key"`
+}
+
+type s3 struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	creds    sigv4.Credentials
+	client   *http.Client
+}
+
+func NewS3(cfg S3Config, client *http.Client) (Backend, error) {
+	const op = "blobstore.NewS3"
+
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("%s: endpoint and bucket are required", op)
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("%s: endpoint must be http or https", op)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &s3{
+		endpoint: endpoint,
+		bucket:   cfg.Bucket,
+		prefix:   cfg.Prefix,
+		creds: sigv4.Credentials{
+			AccessKey: cfg.AccessKey,
+			SecretKey: cfg.SecretKey,
+			Region:    region,
+			Service:   "s3",
+		},
+		client: client,
+	}, nil
+}
+
+// objectUrl uses path-style addressing so that any s3 compatible server works
+func (s *s3) objectUrl(name string) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.bucket + "/" + s.prefix + name
+	u.RawPath = sigv4.UriEncode(u.Path, false)
+	return &u
+}
+
+func (s *s3) do(ctx context.Context, method string, name string, body io.Reader, size int64) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, method, s.objectUrl(name).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	payloadHash := sigv4.EmptyPayloadHash
+	if body != nil {
+		r.ContentLength = size
+		payloadHash = sigv4.UnsignedPayload
+	}
+
+	if s.creds.AccessKey != "" {
+		sigv4.Sign(r, s.creds, payloadHash, time.Now())
+	}
+
+	return s.client.Do(r)
+}
+
+func (s *s3) Open(ctx context.Context, name string) (Object, error) {
+	const op = "blobstore.s3.Open"
+
+	resp, err := s.do(ctx, http.MethodGet, name, nil, 0)
+	if err != nil {
+		return Object{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return Object{ReadCloser: resp.Body, Size: resp.ContentLength}, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return Object{}, fmt.Errorf("%s: %s: %w", op, name, fs.ErrNotExist)
+	}
+
+	resp.Body.Close()
+	return Object{}, fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+}
+
+func (s *s3) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	const op = "blobstore.s3.Put"
+
+	// an empty body only goes out with Content-Length: 0 if it is http.NoBody
+	r = io.LimitReader(r, size)
+	if size == 0 {
+		r = http.NoBody
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, name, r, size)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *s3) Remove(ctx context.Context, name string) error {
+	const op = "blobstore.s3.Remove"
+
+	resp, err := s.do(ctx, http.MethodDelete, name, nil, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+	}
+
+	return nil
+}
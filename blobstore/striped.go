@@ -0,0 +1,607 @@
+package blobstore
+
+import (
+	"bytes"
+	"cloud-storage/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+// shardMagic starts every shard, followed by its index, the shard count, the chunk size and the blob size
+const shardMagic = "CSS1"
+
+const (
+	shardHeaderSize = len(shardMagic) + 1 + 1 + 4 + 8
+	// shardTrailerSize is the sha256 of the header and the body of the shard
+	shardTrailerSize = sha256.Size
+	// StripeChunkSize is how much of a blob each data shard takes per stripe
+	StripeChunkSize = 64 << 10
+	// at most one byte holds a shard index
+	maxShards = 255
+)
+
+// ErrShardsLost means more shards of a blob are missing or corrupt than parity makes up for
+var ErrShardsLost = errors.New("too many shards of the blob are lost")
+
+// ErrCorruptShard means a shard doesn't match its checksum or the other shards of its blob
+var ErrCorruptShard = errors.New("corrupt shard")
+
+// Striped keeps every blob as one shard per dir, each dir being meant to be a disk of its own. The blob is cut into
+// stripes of StripeChunkSize per data shard, and the last shard holds the XOR of the others, so a blob stays readable
+// with any one shard missing or corrupt. Shards are written like blobs in the storage dir, see storage.TempFile.
+type Striped struct {
+	dirs       []string
+	durability storage.Durability
+	chunkSize  int
+}
+
+// NewStriped needs at least three dirs: two for data and one for parity. The order of dirs must not change
+// while they hold blobs, as each shard only fits its place.
+func NewStriped(dirs []string, durability storage.Durability) (*Striped, error) {
+	const op = "blobstore.NewStriped"
+
+	if len(dirs) < 3 || len(dirs) > maxShards {
+		return nil, fmt.Errorf("%s: from 3 to %d dirs are required, got %d", op, maxShards, len(dirs))
+	}
+
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		if seen[dir] {
+			return nil, fmt.Errorf("%s: dir %q is given twice", op, dir)
+		}
+		seen[dir] = true
+	}
+
+	return &Striped{dirs: slices.Clone(dirs), durability: durability, chunkSize: StripeChunkSize}, nil
+}
+
+// Dirs returns the dirs of the shards, the parity shard last
+func (s *Striped) Dirs() []string {
+	return slices.Clone(s.dirs)
+}
+
+type shardHeader struct {
+	index     int
+	count     int
+	chunkSize int
+	size      int64
+}
+
+func (h shardHeader) encode() []byte {
+	buf := make([]byte, 0, shardHeaderSize)
+	buf = append(buf, shardMagic...)
+	buf = append(buf, byte(h.index), byte(h.count))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(h.chunkSize))
+	return binary.LittleEndian.AppendUint64(buf, uint64(h.size))
+}
+
+func decodeShardHeader(buf []byte) (shardHeader, error) {
+	if len(buf) != shardHeaderSize || string(buf[:len(shardMagic)]) != shardMagic {
+		return shardHeader{}, ErrCorruptShard
+	}
+
+	rest := buf[len(shardMagic):]
+	h := shardHeader{
+		index:     int(rest[0]),
+		count:     int(rest[1]),
+		chunkSize: int(binary.LittleEndian.Uint32(rest[2:6])),
+		size:      int64(binary.LittleEndian.Uint64(rest[6:14])),
+	}
+	if h.chunkSize <= 0 || h.chunkSize > 16<<20 || h.size < 0 {
+		return shardHeader{}, ErrCorruptShard
+	}
+
+	return h, nil
+}
+
+// stripes is how many stripes a blob of size bytes takes
+func (s *Striped) stripes(size int64, chunkSize int) int64 {
+	stripeSize := int64(chunkSize) * int64(len(s.dirs)-1)
+	return (size + stripeSize - 1) / stripeSize
+}
+
+func (s *Striped) Put(_ context.Context, name string, r io.Reader, size int64) error {
+	const op = "blobstore.Striped.Put"
+
+	if err := s.put(name, r, size, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// put writes the shards of a blob; only the shards with the indexes in only are replaced unless it is nil
+func (s *Striped) put(name string, r io.Reader, size int64, only []int) error {
+	n := len(s.dirs)
+	files := make([]*storage.TempFile, n)
+	hashes := make([]hash.Hash, n)
+	defer func() {
+		for _, file := range files {
+			if file != nil {
+				file.Discard()
+			}
+		}
+	}()
+
+	for i, dir := range s.dirs {
+		if only != nil && !slices.Contains(only, i) {
+			continue
+		}
+
+		file, err := storage.CreateTemp(dir, s.durability)
+		if err != nil {
+			return err
+		}
+		files[i] = file
+		hashes[i] = sha256.New()
+	}
+
+	write := func(i int, p []byte) error {
+		if files[i] == nil {
+			return nil
+		}
+		hashes[i].Write(p)
+		_, err := files[i].Write(p)
+		return err
+	}
+
+	for i := range s.dirs {
+		header := shardHeader{index: i, count: n, chunkSize: s.chunkSize, size: size}
+		if err := write(i, header.encode()); err != nil {
+			return err
+		}
+	}
+
+	data := n - 1
+	stripe := make([]byte, s.chunkSize*data)
+	parity := make([]byte, s.chunkSize)
+	remaining := size
+	for range s.stripes(size, s.chunkSize) {
+		clear(stripe)
+		want := min(int64(len(stripe)), remaining)
+		if _, err := io.ReadFull(r, stripe[:want]); err != nil {
+			return fmt.Errorf("read blob: %w", err)
+		}
+		remaining -= want
+
+		clear(parity)
+		for i := range data {
+			chunk := stripe[i*s.chunkSize : (i+1)*s.chunkSize]
+			xorInto(parity, chunk)
+			if err := write(i, chunk); err != nil {
+				return err
+			}
+		}
+		if err := write(data, parity); err != nil {
+			return err
+		}
+	}
+
+	for i := range s.dirs {
+		if files[i] == nil {
+			continue
+		}
+		if err := write(i, hashes[i].Sum(nil)); err != nil {
+			return err
+		}
+	}
+
+	// once a shard is in place, the blob is readable from it and the ones before; one failing commit
+	// leaves a blob that is readable, but scrub rebuilds it
+	var errs []error
+	for i, file := range files {
+		if file == nil {
+			continue
+		}
+		if err := file.CommitAs(name); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func xorInto(dst []byte, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
+
+// shard is an open shard whose header was read
+type shard struct {
+	file   *os.File
+	header shardHeader
+	hash   hash.Hash
+}
+
+// openShards opens the shards of a blob except those in skip. Shards that are missing or don't fit
+// the others come back nil, as long as there are no more of them than parity makes up for.
+func (s *Striped) openShards(name string, skip []int) ([]*shard, shardHeader, error) {
+	shards := make([]*shard, len(s.dirs))
+	closeAll := func() {
+		for _, sh := range shards {
+			if sh != nil {
+				sh.file.Close()
+			}
+		}
+	}
+
+	missing := 0
+	for i, dir := range s.dirs {
+		if slices.Contains(skip, i) {
+			continue
+		}
+
+		path, err := storage.BlobPath(dir, name)
+		if err != nil {
+			return nil, shardHeader{}, err
+		}
+
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing++
+			continue
+		} else if err != nil {
+			closeAll()
+			return nil, shardHeader{}, fmt.Errorf("shard %d: os.Open: %w", i, err)
+		}
+
+		buf := make([]byte, shardHeaderSize)
+		if _, err := io.ReadFull(file, buf); err != nil {
+			file.Close()
+			continue
+		}
+
+		header, err := decodeShardHeader(buf)
+		if err != nil || header.index != i || header.count != len(s.dirs) {
+			file.Close()
+			continue
+		}
+
+		h := sha256.New()
+		h.Write(buf)
+		shards[i] = &shard{file: file, header: header, hash: h}
+	}
+
+	if missing == len(s.dirs)-len(skip) {
+		return nil, shardHeader{}, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+
+	// the header most shards agree on is the one of the blob
+	var header shardHeader
+	votes := 0
+	for _, sh := range shards {
+		if sh == nil {
+			continue
+		}
+
+		count := 0
+		for _, other := range shards {
+			if other != nil && other.header.size == sh.header.size && other.header.chunkSize == sh.header.chunkSize {
+				count++
+			}
+		}
+		if count > votes {
+			header, votes = sh.header, count
+		}
+	}
+
+	present := 0
+	for i, sh := range shards {
+		if sh == nil {
+			continue
+		}
+		if sh.header.size != header.size || sh.header.chunkSize != header.chunkSize {
+			sh.file.Close()
+			shards[i] = nil
+			continue
+		}
+		present++
+	}
+
+	if present < len(s.dirs)-1 {
+		closeAll()
+		return nil, shardHeader{}, fmt.Errorf("%s: %w", name, ErrShardsLost)
+	}
+
+	return shards, header, nil
+}
+
+func (s *Striped) Open(_ context.Context, name string) (Object, error) {
+	const op = "blobstore.Striped.Open"
+
+	r, size, err := s.open(name, nil)
+	if err != nil {
+		return Object{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Object{ReadCloser: r, Size: size}, nil
+}
+
+func (s *Striped) open(name string, skip []int) (*stripedReader, int64, error) {
+	shards, header, err := s.openShards(name, skip)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &stripedReader{
+		shards:    shards,
+		chunkSize: header.chunkSize,
+		stripes:   s.stripes(header.size, header.chunkSize),
+		remaining: header.size,
+	}, header.size, nil
+}
+
+// stripedReader reads a blob stripe by stripe, making up for a missing shard with parity.
+// The checksums of the shards are checked once all stripes are read.
+type stripedReader struct {
+	shards    []*shard
+	chunkSize int
+	stripes   int64
+	remaining int64
+	buf       []byte
+	err       error
+}
+
+func (r *stripedReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next reads the next stripe into buf, or checks the trailers after the last one and returns io.EOF
+func (r *stripedReader) next() error {
+	if r.stripes == 0 {
+		for i, sh := range r.shards {
+			if sh == nil {
+				continue
+			}
+
+			trailer := make([]byte, shardTrailerSize)
+			if _, err := io.ReadFull(sh.file, trailer); err != nil || !bytes.Equal(trailer, sh.hash.Sum(nil)) {
+				return fmt.Errorf("shard %d: %w", i, ErrCorruptShard)
+			}
+		}
+		return io.EOF
+	}
+	r.stripes--
+
+	n := len(r.shards)
+	chunks := make([][]byte, n)
+	lost := -1
+	for i, sh := range r.shards {
+		if sh == nil {
+			lost = i
+			continue
+		}
+
+		chunk := make([]byte, r.chunkSize)
+		if _, err := io.ReadFull(sh.file, chunk); err != nil {
+			return fmt.Errorf("shard %d: %w: %w", i, ErrCorruptShard, err)
+		}
+		sh.hash.Write(chunk)
+		chunks[i] = chunk
+	}
+
+	if lost >= 0 {
+		rebuilt := make([]byte, r.chunkSize)
+		for i, chunk := range chunks {
+			if i != lost {
+				xorInto(rebuilt, chunk)
+			}
+		}
+		chunks[lost] = rebuilt
+	}
+
+	stripe := make([]byte, 0, r.chunkSize*(n-1))
+	for _, chunk := range chunks[:n-1] {
+		stripe = append(stripe, chunk...)
+	}
+
+	// only the last stripe is padded
+	want := min(int64(len(stripe)), r.remaining)
+	r.remaining -= want
+	r.buf = stripe[:want]
+	return nil
+}
+
+func (r *stripedReader) Close() error {
+	var errs []error
+	for _, sh := range r.shards {
+		if sh != nil {
+			errs = append(errs, sh.file.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Striped) Remove(_ context.Context, name string) error {
+	const op = "blobstore.Striped.Remove"
+
+	var errs []error
+	for _, dir := range s.dirs {
+		errs = append(errs, storage.Remove(dir, s.durability, name))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+type ScrubReport struct {
+	Checked int
+	// Damaged blobs have a missing or corrupt shard; they are rebuilt if the scrub was asked to
+	Damaged []string
+	Rebuilt []string
+	// Lost blobs have more shards missing or corrupt than parity makes up for; only a backup brings them back
+	Lost []string
+}
+
+// Scrub reads every shard of every blob in the dirs and checks it against its checksum. With rebuild,
+// the bad shards of damaged blobs are written anew from the others. It must not run while the dirs are written to.
+func (s *Striped) Scrub(rebuild bool) (ScrubReport, error) {
+	const op = "blobstore.Striped.Scrub"
+
+	names, err := s.blobNames()
+	if err != nil {
+		return ScrubReport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var report ScrubReport
+	for _, name := range names {
+		report.Checked++
+
+		bad, err := s.checkShards(name)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+
+		switch {
+		case len(bad) == 0:
+			continue
+		case len(bad) > 1:
+			report.Lost = append(report.Lost, name)
+			continue
+		}
+
+		report.Damaged = append(report.Damaged, name)
+		if !rebuild {
+			continue
+		}
+
+		if err := s.rebuild(name, bad); errors.Is(err, ErrCorruptShard) || errors.Is(err, ErrShardsLost) {
+			report.Lost = append(report.Lost, name)
+		} else if err != nil {
+			return report, fmt.Errorf("%s: rebuild %s: %w", op, name, err)
+		} else {
+			report.Rebuilt = append(report.Rebuilt, name)
+		}
+	}
+
+	return report, nil
+}
+
+// blobNames lists the blobs any dir has a shard of
+func (s *Striped) blobNames() ([]string, error) {
+	seen := make(map[string]bool)
+	for _, dir := range s.dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			// a replaced disk that is not set up yet; all of its shards need rebuilding
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("os.ReadDir: %w", err)
+		}
+
+		for _, entry := range entries {
+			// dot entries hold the journal and temp files
+			if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+				seen[entry.Name()] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// checkShards returns the indexes of the shards of the blob that are missing or corrupt
+func (s *Striped) checkShards(name string) ([]int, error) {
+	var bad []int
+	var size int64 = -1
+	for i, dir := range s.dirs {
+		path, err := storage.BlobPath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+
+		header, err := verifyShard(path)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrCorruptShard) || errors.Is(err, io.ErrUnexpectedEOF) {
+			bad = append(bad, i)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("shard %d of %s: %w", i, name, err)
+		}
+
+		if header.index != i || header.count != len(s.dirs) || (size >= 0 && header.size != size) {
+			bad = append(bad, i)
+			continue
+		}
+		size = header.size
+	}
+
+	return bad, nil
+}
+
+// verifyShard reads the whole shard and checks it against its trailer
+func verifyShard(path string) (shardHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return shardHeader{}, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, shardHeaderSize)
+	if _, err := io.ReadFull(file, buf); err != nil {
+		return shardHeader{}, ErrCorruptShard
+	}
+	header, err := decodeShardHeader(buf)
+	if err != nil {
+		return shardHeader{}, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return shardHeader{}, err
+	}
+	bodySize := info.Size() - int64(shardHeaderSize) - int64(shardTrailerSize)
+	if bodySize < 0 {
+		return shardHeader{}, ErrCorruptShard
+	}
+
+	h := sha256.New()
+	h.Write(buf)
+	if _, err := io.CopyN(h, file, bodySize); err != nil {
+		return shardHeader{}, err
+	}
+
+	trailer := make([]byte, shardTrailerSize)
+	if _, err := io.ReadFull(file, trailer); err != nil || !bytes.Equal(trailer, h.Sum(nil)) {
+		return shardHeader{}, ErrCorruptShard
+	}
+
+	return header, nil
+}
+
+// rebuild writes the bad shards of a blob anew from the other ones, which checkShards just verified
+func (s *Striped) rebuild(name string, bad []int) error {
+	r, size, err := s.open(name, bad)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return s.put(name, r, size, bad)
+}
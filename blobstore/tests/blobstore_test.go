@@ -0,0 +1,126 @@
+package blobstore_test
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/storage"
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 keeps objects of a single bucket in memory
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("x-amz-content-sha256") != "UNSIGNED-PAYLOAD" || r.ContentLength < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testBackend(t *testing.T, backend blobstore.Backend) {
+	ctx := context.Background()
+
+	_, err := backend.Open(ctx, "blob")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, backend.Put(ctx, "blob", strings.NewReader("ciphertext and more"), 10))
+
+	obj, err := backend.Open(ctx, "blob")
+	require.NoError(t, err)
+	content, err := io.ReadAll(obj)
+	obj.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "ciphertext", string(content))
+	assert.Equal(t, int64(10), obj.Size)
+
+	require.NoError(t, backend.Remove(ctx, "blob"))
+	require.NoError(t, backend.Remove(ctx, "blob"))
+
+	_, err = backend.Open(ctx, "blob")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestLocal(t *testing.T) {
+	backend := blobstore.NewLocal(t.TempDir(), storage.DurabilityNone)
+	testBackend(t, backend)
+
+	err := backend.Put(context.Background(), "short", strings.NewReader("abc"), 10)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	backend, err := blobstore.NewS3(blobstore.S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "bucket",
+		Prefix:    "blobs/",
+		AccessKey: "key",
+		SecretKey: "secret",
+	}, server.Client())
+	require.NoError(t, err)
+
+	require.NoError(t, backend.Put(context.Background(), "other", strings.NewReader("x"), 1))
+	assert.Contains(t, fake.objects, "/bucket/blobs/other")
+
+	testBackend(t, backend)
+}
+
+func TestStore(t *testing.T) {
+	remote := blobstore.NewLocal(t.TempDir(), storage.DurabilityNone)
+	store := blobstore.NewStore(t.TempDir(), storage.DurabilityNone, map[string]blobstore.Backend{"cold": remote})
+
+	assert.Equal(t, []string{"cold", "local"}, store.Names())
+
+	_, err := store.Backend("")
+	assert.NoError(t, err)
+
+	_, err = store.Backend("hot")
+	assert.ErrorAs(t, err, &blobstore.UnknownBackendError{})
+
+	require.NoError(t, remote.Put(context.Background(), "blob", strings.NewReader("x"), 1))
+
+	_, err = store.Open(context.Background(), blobstore.Local, "blob")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	obj, err := store.Open(context.Background(), "cold", "blob")
+	require.NoError(t, err)
+	obj.Close()
+}
@@ -0,0 +1,116 @@
+package blobstore_test
+
+import (
+	"bytes"
+	"cloud-storage/blobstore"
+	"cloud-storage/storage"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStriped(t *testing.T, disks int) (*blobstore.Striped, []string) {
+	dirs := make([]string, disks)
+	for i := range dirs {
+		dirs[i] = t.TempDir()
+	}
+
+	backend, err := blobstore.NewStriped(dirs, storage.DurabilityNone)
+	require.NoError(t, err)
+	return backend, dirs
+}
+
+func readBlob(t *testing.T, backend blobstore.Backend, name string) ([]byte, error) {
+	obj, err := backend.Open(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(obj)
+}
+
+func TestStriped(t *testing.T) {
+	backend, _ := newStriped(t, 3)
+	testBackend(t, backend)
+
+	_, err := blobstore.NewStriped([]string{"a", "b"}, storage.DurabilityNone)
+	assert.Error(t, err)
+	_, err = blobstore.NewStriped([]string{"a", "b", "a"}, storage.DurabilityNone)
+	assert.Error(t, err)
+}
+
+func TestStriped_OneDiskLost(t *testing.T) {
+	// a few stripes with a short last one
+	content := make([]byte, 5*blobstore.StripeChunkSize+123)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	for lost := range 4 {
+		backend, dirs := newStriped(t, 4)
+		require.NoError(t, backend.Put(context.Background(), "blob", bytes.NewReader(content), int64(len(content))))
+		require.NoError(t, os.Remove(filepath.Join(dirs[lost], "blob")))
+
+		got, err := readBlob(t, backend, "blob")
+		require.NoError(t, err, "disk %d lost", lost)
+		assert.True(t, bytes.Equal(content, got), "disk %d lost", lost)
+	}
+
+	backend, dirs := newStriped(t, 3)
+	require.NoError(t, backend.Put(context.Background(), "blob", bytes.NewReader(content), int64(len(content))))
+	require.NoError(t, os.Remove(filepath.Join(dirs[0], "blob")))
+	require.NoError(t, os.Remove(filepath.Join(dirs[2], "blob")))
+
+	_, err = readBlob(t, backend, "blob")
+	assert.ErrorIs(t, err, blobstore.ErrShardsLost)
+}
+
+func TestStriped_Scrub(t *testing.T) {
+	backend, dirs := newStriped(t, 3)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("encrypted "), blobstore.StripeChunkSize/4)
+	for _, name := range []string{"healthy", "missing-shard", "corrupt-shard", "lost"} {
+		require.NoError(t, backend.Put(ctx, name, bytes.NewReader(content), int64(len(content))))
+	}
+
+	require.NoError(t, os.Remove(filepath.Join(dirs[1], "missing-shard")))
+
+	corrupt := filepath.Join(dirs[2], "corrupt-shard")
+	shard, err := os.ReadFile(corrupt)
+	require.NoError(t, err)
+	shard[len(shard)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(corrupt, shard, 0o600))
+
+	require.NoError(t, os.Remove(filepath.Join(dirs[0], "lost")))
+	require.NoError(t, os.Remove(filepath.Join(dirs[1], "lost")))
+
+	report, err := backend.Scrub(false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Checked)
+	assert.Equal(t, []string{"corrupt-shard", "missing-shard"}, report.Damaged)
+	assert.Empty(t, report.Rebuilt)
+	assert.Equal(t, []string{"lost"}, report.Lost)
+
+	report, err = backend.Scrub(true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"corrupt-shard", "missing-shard"}, report.Rebuilt)
+
+	report, err = backend.Scrub(false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Damaged)
+
+	// rebuilt blobs are whole again, with every shard in place
+	for _, name := range []string{"corrupt-shard", "missing-shard"} {
+		require.NoError(t, os.Remove(filepath.Join(dirs[0], name)))
+		got, err := readBlob(t, backend, name)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(content, got))
+	}
+}
@@ -0,0 +1,397 @@
+// Package client is a typed client for the cloud-storage http api.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokens that expire sooner than this are refreshed before use
+const tokenRefreshMargin = 30 * time.Second
+
+type Client struct {
+	baseUrl string
+	http    *http.Client
+
+	mu        sync.Mutex
+	name      string
+	password  string
+	token     string
+	expiresAt time.Time
+}
+
+// ErrorDetail mirrors a single entry of the errors array of api responses
+type ErrorDetail struct {
+	Code        int    `json:"code"`
+	ParamName   string `json:"parameter_name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Error is returned for every non-2xx response
+type Error struct {
+	StatusCode int
+	Details    []ErrorDetail
+}
+
+func (err *Error) Error() string {
+	descriptions := make([]string, 0, len(err.Details))
+	for _, detail := range err.Details {
+		if detail.Description != "" {
+			descriptions = append(descriptions, detail.Description)
+		}
+	}
+
+	if len(descriptions) == 0 {
+		return fmt.Sprintf("cloud-storage: %s", http.StatusText(err.StatusCode))
+	}
+	return fmt.Sprintf("cloud-storage: %s: %s", http.StatusText(err.StatusCode), strings.Join(descriptions, "; "))
+}
+
+type File struct {
+	Id       string `json:"id"`
+	FileName string `json:"file_name"`
+}
+
+// New returns a client for the server at baseUrl, e.g. "https://storage.example.com".
+// httpClient may be nil to use http.DefaultClient.
+func New(baseUrl string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		http:    httpClient,
+	}
+}
+
+func (c *Client) url(path string) string {
+	return c.baseUrl + "/api" + path
+}
+
+func (c *Client) Register(ctx context.Context, name string, password string) error {
+	const op = "client.Client.Register"
+
+	resp, err := c.doJSON(ctx, "POST", "/auth/register", map[string]string{"name": name, "password": password}, false)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Login obtains a session token and remembers the credentials to get a new one once it expires
+func (c *Client) Login(ctx context.Context, name string, password string) error {
+	const op = "client.Client.Login"
+
+	c.mu.Lock()
+	c.name, c.password = name, password
+	c.mu.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Client) refresh(ctx context.Context) error {
+	c.mu.Lock()
+	credentials := map[string]string{"name": c.name, "password": c.password}
+	c.mu.Unlock()
+
+	resp, err := c.doJSON(ctx, "POST", "/auth/login", credentials, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		SessionToken string `json:"session_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decode login response: %w", err)
+	}
+
+	c.mu.Lock()
+	c.token = body.SessionToken
+	c.expiresAt = tokenExpiry(body.SessionToken)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// tokenExpiry reads the exp claim; the signature is the server's business
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ExpiresAt == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+// authorize sets a token that is valid for a while yet, logging in again if needed
+func (c *Client) authorize(ctx context.Context, req *http.Request) error {
+	c.mu.Lock()
+	token, expiresAt, canRefresh := c.token, c.expiresAt, c.name != ""
+	c.mu.Unlock()
+
+	if canRefresh && (token == "" || (!expiresAt.IsZero() && time.Until(expiresAt) < tokenRefreshMargin)) {
+		if err := c.refresh(ctx); err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		token = c.token
+		c.mu.Unlock()
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
+
+func (c *Client) invalidateToken() {
+	c.mu.Lock()
+	c.token = ""
+	c.mu.Unlock()
+}
+
+// do sends the request and turns non-2xx responses into *Error.
+// Requests with a replayable body are retried once with a fresh token on 401.
+func (c *Client) do(ctx context.Context, newRequest func() (*http.Request, error), authorized bool) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+
+		if authorized {
+			if err := c.authorize(ctx, req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		apiErr := readError(resp)
+		if authorized && resp.StatusCode == http.StatusUnauthorized && attempt == 0 && req.GetBody != nil {
+			c.invalidateToken()
+			continue
+		}
+
+		return nil, apiErr
+	}
+}
+
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Errors []ErrorDetail `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil {
+		apiErr.Details = body.Errors
+	}
+
+	return apiErr
+}
+
+func (c *Client) doJSON(ctx context.Context, method string, path string, body any, authorized bool) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal: %w", err)
+		}
+	}
+
+	return c.do(ctx, func() (*http.Request, error) {
+		var req *http.Request
+		var err error
+		if payload != nil {
+			req, err = http.NewRequest(method, c.url(path), bytes.NewReader(payload))
+			if req != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+		} else {
+			req, err = http.NewRequest(method, c.url(path), http.NoBody)
+			if req != nil {
+				// lets do retry requests without a body
+				req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+			}
+		}
+		return req, err
+	}, authorized)
+}
+
+func (c *Client) List(ctx context.Context) ([]File, error) {
+	const op = "client.Client.List"
+
+	resp, err := c.doJSON(ctx, "GET", "/files", nil, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Files []File `json:"files"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: decode response: %w", op, err)
+	}
+
+	return body.Files, nil
+}
+
+// Upload streams size bytes of r as a file named fileName. The server needs the exact size
+// up front and rejects uploads that turn out bigger.
+func (c *Client) Upload(ctx context.Context, fileName string, r io.Reader, size int64) (File, error) {
+	const op = "client.Client.Upload"
+
+	if size <= 0 {
+		return File{}, fmt.Errorf("%s: size must be positive", op)
+	}
+
+	var contentType string
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		pr, pw := io.Pipe()
+		form := multipart.NewWriter(pw)
+		contentType = form.FormDataContentType()
+
+		go func() {
+			pw.CloseWithError(writeUploadForm(form, fileName, r, size))
+		}()
+
+		req, err := http.NewRequest("POST", c.url("/upload"), pr)
+		if err != nil {
+			pr.Close()
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		return req, nil
+	}, true)
+	if err != nil {
+		return File{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	var file File
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return File{}, fmt.Errorf("%s: decode response: %w", op, err)
+	}
+
+	return file, nil
+}
+
+func writeUploadForm(form *multipart.Writer, fileName string, r io.Reader, size int64) error {
+	// file-size is a raw little endian int64 and has to come before the file
+	field, err := form.CreateFormField("file-size")
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(field, binary.LittleEndian, size); err != nil {
+		return err
+	}
+
+	part, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(part, r, size); err != nil {
+		return err
+	}
+
+	return form.Close()
+}
+
+// Download writes the content of the file with the given id into w and returns its name
+func (c *Client) Download(ctx context.Context, id string, w io.Writer) (string, error) {
+	const op = "client.Client.Download"
+
+	resp, err := c.doJSON(ctx, "GET", "/files/"+url.PathEscape(id), nil, true)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("%s: mime.ParseMediaType: %w", op, err)
+	}
+
+	part, err := multipart.NewReader(resp.Body, params["boundary"]).NextPart()
+	if err != nil {
+		return "", fmt.Errorf("%s: read part: %w", op, err)
+	}
+
+	if _, err := io.Copy(w, part); err != nil {
+		return "", fmt.Errorf("%s: copy: %w", op, err)
+	}
+
+	return part.FileName(), nil
+}
+
+// Share returns a link anyone can download the file with the given id by, without logging in,
+// until it expires after the default time of the server
+func (c *Client) Share(ctx context.Context, id string) (string, error) {
+	const op = "client.Client.Share"
+
+	resp, err := c.doJSON(ctx, "POST", "/files/"+url.PathEscape(id)+"/presign", nil, true)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Url string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%s: decode response: %w", op, err)
+	}
+	if body.Url == "" {
+		return "", fmt.Errorf("%s: no url in response", op)
+	}
+
+	// the server answers with a path of its own
+	return c.baseUrl + body.Url, nil
+}
@@ -0,0 +1,205 @@
+package client_test
+
+import (
+	"bytes"
+	"cloud-storage/client"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeServer struct {
+	logins   atomic.Int32
+	tokenTTL time.Duration
+	// the next authorized request is rejected as if the token had been revoked
+	reject atomic.Bool
+	files  map[string][]byte
+}
+
+func fakeToken(expiresAt time.Time, n int32) string {
+	payload, _ := json.Marshal(map[string]any{"exp": expiresAt.Unix(), "n": n})
+	return "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+func (s *fakeServer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var creds map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&creds))
+		if creds["name"] != "user" || creds["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		n := s.logins.Add(1)
+		json.NewEncoder(w).Encode(map[string]string{"session_token": fakeToken(time.Now().Add(s.tokenTTL), n)})
+	})
+
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer header.") || s.reject.Swap(false) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+
+	mux.HandleFunc("GET /api/files", authorized(func(w http.ResponseWriter, r *http.Request) {
+		files := make([]client.File, 0, len(s.files))
+		for id := range s.files {
+			files = append(files, client.File{Id: id, FileName: id + ".txt"})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"files": files})
+	}))
+
+	mux.HandleFunc("POST /api/upload", authorized(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		require.NoError(t, err)
+
+		part, err := reader.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, "file-size", part.FormName())
+		var size int64
+		require.NoError(t, binary.Read(part, binary.LittleEndian, &size))
+
+		part, err = reader.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, "file", part.FormName())
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		assert.Equal(t, size, int64(len(content)))
+
+		id := fmt.Sprintf("f%d", len(s.files))
+		s.files[id] = content
+		json.NewEncoder(w).Encode(client.File{Id: id, FileName: part.FileName()})
+	}))
+
+	mux.HandleFunc("GET /api/files/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := s.files[r.PathValue("id")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []client.ErrorDetail{{Code: 6, Description: "File not found"}}})
+			return
+		}
+
+		form := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", form.FormDataContentType())
+		part, _ := form.CreateFormFile("file", r.PathValue("id")+".txt")
+		part.Write(content)
+		form.Close()
+	}))
+
+	mux.HandleFunc("POST /api/files/{id}/presign", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := s.files[r.PathValue("id")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []client.ErrorDetail{{Code: 6, Description: "File not found"}}})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"url":        "/api/presigned/files/" + r.PathValue("id") + "?sig=abc",
+			"link_id":    "link",
+			"expires_at": time.Now().Add(time.Hour).Unix(),
+		})
+	}))
+
+	return mux
+}
+
+func newTestClient(t *testing.T, tokenTTL time.Duration) (*client.Client, *fakeServer) {
+	c, s, _ := newTestServer(t, tokenTTL)
+	return c, s
+}
+
+func newTestServer(t *testing.T, tokenTTL time.Duration) (*client.Client, *fakeServer, *httptest.Server) {
+	s := &fakeServer{tokenTTL: tokenTTL, files: map[string][]byte{}}
+	server := httptest.NewServer(s.handler(t))
+	t.Cleanup(server.Close)
+
+	c := client.New(server.URL, server.Client())
+	require.NoError(t, c.Login(context.Background(), "user", "secret"))
+
+	return c, s, server
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	c, _ := newTestClient(t, time.Hour)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("cloud-storage"), 1000)
+	file, err := c.Upload(ctx, "notes.txt", bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	assert.Equal(t, "notes.txt", file.FileName)
+
+	files, err := c.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []client.File{{Id: file.Id, FileName: file.Id + ".txt"}}, files)
+
+	var downloaded bytes.Buffer
+	name, err := c.Download(ctx, file.Id, &downloaded)
+	require.NoError(t, err)
+	assert.Equal(t, file.Id+".txt", name)
+	assert.Equal(t, content, downloaded.Bytes())
+}
+
+func TestClientShare(t *testing.T) {
+	c, _, server := newTestServer(t, time.Hour)
+	ctx := context.Background()
+
+	file, err := c.Upload(ctx, "notes.txt", strings.NewReader("notes"), 5)
+	require.NoError(t, err)
+
+	link, err := c.Share(ctx, file.Id)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/api/presigned/files/"+file.Id+"?sig=abc", link)
+}
+
+func TestClientErrors(t *testing.T) {
+	c, _ := newTestClient(t, time.Hour)
+
+	_, err := c.Download(context.Background(), "missing", io.Discard)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, []client.ErrorDetail{{Code: 6, Description: "File not found"}}, apiErr.Details)
+
+	err = c.Login(context.Background(), "user", "wrong")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	_, err = c.Share(context.Background(), "missing")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestClientRefreshesExpiringToken(t *testing.T) {
+	c, s := newTestClient(t, time.Second)
+
+	_, err := c.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), s.logins.Load())
+}
+
+func TestClientRetriesRejectedToken(t *testing.T) {
+	c, s := newTestClient(t, time.Hour)
+
+	s.reject.Store(true)
+	_, err := c.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), s.logins.Load())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"cloud-storage/backup"
+	"cloud-storage/config"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+)
+
+const usage = `usage: backup <command> [flags]
+
+commands:
+  create  -out <dir>             snapshot the db and blobs into dir
+  verify  -dir <dir>             check a backup against its manifest
+  restore -dir <dir> [-force]    restore a verified backup into configured paths
+
+db and storage paths are read from the config file pointed to by CONFIG_PATH
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	dir := flags.String("dir", "", "backup directory")
+	out := flags.String("out", "", "directory to write a new backup into")
+	force := flags.Bool("force", false, "replace an existing db on restore")
+	flags.Parse(os.Args[2:])
+
+	switch os.Args[1] {
+	case "create":
+		if *out == "" {
+			log.Fatal("-out is required")
+		}
+
+		appConfig := config.MustLoad()
+		manifest, err := backup.Create(ctx, appConfig.DbPath, appConfig.FileStoragePath, *out)
+		var mbe backup.MissingBlobsError
+		if errors.As(err, &mbe) {
+			for _, name := range mbe.Names {
+				log.Printf("missing blob: %s", name)
+			}
+		}
+		if err != nil {
+			log.Fatalf("Backup failed: %s", err)
+		}
+
+		log.Printf("Backup written to %s: %d blobs", *out, len(manifest.Blobs))
+	case "verify":
+		if *dir == "" {
+			log.Fatal("-dir is required")
+		}
+
+		manifest, err := backup.Verify(*dir)
+		if err != nil {
+			log.Fatalf("Backup is not valid: %s", err)
+		}
+
+		log.Printf("Backup from %s is valid: %d blobs", manifest.CreatedAt, len(manifest.Blobs))
+	case "restore":
+		if *dir == "" {
+			log.Fatal("-dir is required")
+		}
+
+		appConfig := config.MustLoad()
+		manifest, err := backup.Restore(ctx, *dir, appConfig.DbPath, appConfig.FileStoragePath, *force)
+		if err != nil {
+			log.Fatalf("Restore failed: %s", err)
+		}
+
+		log.Printf("Restored backup from %s: %d blobs", manifest.CreatedAt, len(manifest.Blobs))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"cloud-storage/client"
+	"cloud-storage/loadgen"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const usage = `usage: loadgen -url <url> -user <name> -password <password> [flags]
+
+uploads and downloads files against a running server from concurrent workers
+and prints latency percentiles and throughput per operation
+
+  -url         base url of the server, e.g. http://localhost:8080
+  -user        account to run as
+  -password    password of the account
+  -register    register the account before the run
+  -c           number of concurrent workers (default 8)
+  -d           how long to run (default 30s)
+  -sizes       comma separated upload sizes with k, m or g suffixes (default 4k,256k,4m)
+  -downloads   share of operations that download an uploaded file (default 0.5)
+
+uploaded files are left on the server
+`
+
+func main() {
+	flags := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	url := flags.String("url", "", "base url of the server")
+	user := flags.String("user", "", "account to run as")
+	password := flags.String("password", "", "password of the account")
+	register := flags.Bool("register", false, "register the account before the run")
+	concurrency := flags.Int("c", 8, "number of concurrent workers")
+	duration := flags.Duration("d", 30*time.Second, "how long to run")
+	sizes := flags.String("sizes", "4k,256k,4m", "comma separated upload sizes")
+	downloads := flags.Float64("downloads", 0.5, "share of operations that download an uploaded file")
+	flags.Parse(os.Args[1:])
+
+	if *url == "" || *user == "" || *password == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	fileSizes, err := loadgen.ParseSizes(*sizes)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := client.New(*url, nil)
+	if *register {
+		if err := c.Register(ctx, *user, *password); err != nil {
+			log.Fatalf("Could not register: %s", err)
+		}
+	}
+	if err := c.Login(ctx, *user, *password); err != nil {
+		log.Fatalf("Could not log in: %s", err)
+	}
+
+	log.Printf("Running %d workers for %s", *concurrency, *duration)
+	report, err := loadgen.Run(ctx, c, loadgen.Config{
+		Concurrency:   *concurrency,
+		Duration:      *duration,
+		FileSizes:     fileSizes,
+		DownloadRatio: *downloads,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%-9s %8s %7s %10s %10s %10s %10s %10s\n", "op", "count", "errors", "MB/s", "p50", "p90", "p99", "max")
+	for _, op := range []loadgen.Op{loadgen.OpUpload, loadgen.OpDownload} {
+		s, ok := report.Ops[op]
+		if !ok {
+			continue
+		}
+		fmt.Printf(
+			"%-9s %8d %7d %10.2f %10s %10s %10s %10s\n",
+			op, s.Count, s.Errors, s.Throughput(report.Elapsed)/(1<<20),
+			s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond),
+			s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond),
+		)
+	}
+
+	if report.FirstError != nil {
+		log.Printf("First error: %s", report.FirstError)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"cloud-storage/config"
+	"cloud-storage/db_access/sqlite"
+	"cloud-storage/repair"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = `usage: repair [-fix]
+
+cross-checks the storage journal, the db and the storage dir after a crash;
+the server must be stopped while it runs
+
+  -fix    remove orphaned and abandoned blobs
+
+files whose blobs are missing or corrupt are only reported; restore them from a backup.
+db and storage paths are read from the config file pointed to by CONFIG_PATH
+`
+
+func main() {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	fix := flags.Bool("fix", false, "remove orphaned and abandoned blobs")
+	flags.Parse(os.Args[1:])
+
+	appConfig := config.MustLoad()
+	db, err := sqlite.New(appConfig.DbPath)
+	if err != nil {
+		log.Fatalf("Could not load the db: %s", err)
+	}
+
+	report, err := repair.Check(db, appConfig.FileStoragePath, appConfig.Durability, *fix)
+	if err != nil {
+		log.Fatalf("Repair failed: %s", err)
+	}
+
+	log.Printf(
+		"Journal: %d unfinished writes verified, %d unfinished removals finished",
+		len(report.Recovery.Verified),
+		len(report.Recovery.Removed),
+	)
+	for _, name := range report.Missing {
+		log.Printf("missing blob: %s", name)
+	}
+	for _, name := range report.Corrupt {
+		log.Printf("corrupt blob: %s", name)
+	}
+
+	action := "found"
+	if *fix {
+		action = "removed"
+	}
+	for _, name := range report.Orphaned {
+		log.Printf("orphaned blob %s: %s", action, name)
+	}
+	for _, name := range report.Abandoned {
+		log.Printf("abandoned blob %s: %s", action, name)
+	}
+
+	if report.Damaged() {
+		log.Fatalf("%d blobs referenced by the db are missing or corrupt", len(report.Missing)+len(report.Corrupt))
+	}
+
+	if leftovers := len(report.Orphaned) + len(report.Abandoned); leftovers > 0 && !*fix {
+		log.Printf("Run with -fix to remove %d unreferenced blobs", leftovers)
+		return
+	}
+
+	log.Printf("Storage is consistent")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/config"
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"cloud-storage/storage"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+)
+
+const usage = `usage: scrub [-rebuild] [backend...]
+
+verifies every shard of the striped backends against its checksum and
+looks for blobs the db references but no shard is left of;
+the server must be stopped while it runs
+
+  -rebuild    write missing and corrupt shards anew from the others
+
+to replace a failed disk, mount the new one at the dir of the old one and
+run with -rebuild. without backends, all striped backends are scrubbed.
+db and backends are read from the config file pointed to by CONFIG_PATH
+`
+
+func main() {
+	flags := flag.NewFlagSet("scrub", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	rebuild := flags.Bool("rebuild", false, "write missing and corrupt shards anew")
+	flags.Parse(os.Args[1:])
+
+	appConfig := config.MustLoad()
+	db, err := sqlite.New(appConfig.DbPath)
+	if err != nil {
+		log.Fatalf("Could not load the db: %s", err)
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		for name := range appConfig.StripedBackends {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	if len(names) == 0 {
+		log.Printf("No striped backends are configured")
+		return
+	}
+
+	lost := 0
+	for _, name := range names {
+		backend, err := appConfig.StripedBackend(name)
+		if err != nil {
+			log.Fatalf("Backend %s: %s", name, err)
+		}
+
+		n, err := scrub(db, name, backend, appConfig.Durability, *rebuild)
+		if err != nil {
+			log.Fatalf("Scrubbing %s failed: %s", name, err)
+		}
+		lost += n
+	}
+
+	if lost > 0 {
+		log.Fatalf("%d blobs are lost; restore them from a backup", lost)
+	}
+}
+
+// scrub checks one backend and returns how many blobs it lost
+func scrub(db db_access.FileRepo, name string, backend *blobstore.Striped, durability storage.Durability, rebuild bool) (int, error) {
+	// like the storage dir at startup, leftovers of writes interrupted by a crash go first
+	for _, dir := range backend.Dirs() {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			log.Printf("%s: dir %s is missing", name, dir)
+			continue
+		}
+
+		if _, err := storage.SweepTemp(dir); err != nil {
+			return 0, err
+		}
+		if _, err := storage.Recover(dir, durability); err != nil {
+			return 0, err
+		}
+	}
+
+	report, err := backend.Scrub(rebuild)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, blob := range report.Damaged {
+		log.Printf("%s: damaged blob: %s", name, blob)
+	}
+	for _, blob := range report.Rebuilt {
+		log.Printf("%s: rebuilt blob: %s", name, blob)
+	}
+	for _, blob := range report.Lost {
+		log.Printf("%s: lost blob: %s", name, blob)
+	}
+
+	// blobs without any shard left don't show up in the dirs at all
+	blobs, err := db.GetBlobs(name, db_access.BlobFilter{})
+	if err != nil {
+		return 0, err
+	}
+	missing := 0
+	for _, blob := range blobs {
+		obj, err := backend.Open(context.Background(), blob.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("%s: missing blob: %s", name, blob.Name)
+			missing++
+			continue
+		} else if err != nil {
+			// damaged blobs are reported by the scrub already
+			continue
+		}
+		obj.Close()
+	}
+
+	log.Printf("%s: %d blobs checked, %d damaged, %d rebuilt", name, report.Checked, len(report.Damaged), len(report.Rebuilt))
+	if !rebuild && len(report.Damaged) > 0 {
+		log.Printf("%s: run with -rebuild to restore the damaged blobs", name)
+	}
+
+	return len(report.Lost) + missing, nil
+}
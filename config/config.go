@@ -1,8 +1,38 @@
 package config
 
 import (
+	"cloud-storage/anomaly"
 	"cloud-storage/api"
+	"cloud-storage/auth"
+	"cloud-storage/blobstore"
+	"cloud-storage/encryption"
+	"cloud-storage/events"
+	"cloud-storage/export"
+	"cloud-storage/geoip"
+	"cloud-storage/hls"
+	"cloud-storage/importer"
+	"cloud-storage/ipfilter"
+	"cloud-storage/jobs"
+	"cloud-storage/keys"
+	"cloud-storage/ldap"
+	"cloud-storage/maintenance"
+	"cloud-storage/policy"
+	"cloud-storage/presign"
+	"cloud-storage/privacy"
+	"cloud-storage/replication"
+	"cloud-storage/retention"
+	"cloud-storage/scheduler"
+	"cloud-storage/search"
+	"cloud-storage/storage"
+	"cloud-storage/tiering"
+	"cloud-storage/utils/realip"
+	"errors"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
 	"os"
 	"time"
 
@@ -15,6 +45,14 @@ const (
 	EnvDev   string = "dev"
 )
 
+// middlewareProfiles are the api middleware chains of environments that don't list their own;
+// the names are registered in main
+var middlewareProfiles = map[string][]string{
+	EnvLocal: {"request-id", "logger", "drain", "problem", "recoverer"},
+	EnvDev:   {"request-id", "logger", "drain", "problem", "recoverer", "rate-limit"},
+	EnvProd:  {"request-id", "logger", "drain", "problem", "recoverer", "rate-limit", "security-headers"},
+}
+
 type Duration time.Duration
 
 func (d *Duration) UnmarshalText(text []byte) error {
@@ -24,20 +62,302 @@ func (d *Duration) UnmarshalText(text []byte) error {
 }
 
 type AppConfig struct {
-	Environment       string   `json:"environment" env-default:"prod"`
-	DbPath            string   `json:"db-path" env-required:"true"`
-	MaxUploadSize     int64    `json:"max-upload-size" env-default:"1024"`
-	FileStoragePath   string   `json:"file-storage-path" env-required:"true"`
-	DecRotationPeriod Duration `json:"dec-rotation-period" env-required:"true"`
-	TokenTimeToLive   Duration `json:"token_time_to_live" env-default:"1h"`
+	Environment       string `json:"environment" env-default:"prod"`
+	DbPath            string `json:"db-path" env-required:"true"`
+	MaxUploadSize     int64  `json:"max-upload-size" env-default:"1024"`
+	MultipartOverhead int64  `json:"multipart-overhead" env-default:"16384"`
+	OptionalFileSize  bool   `json:"optional-file-size" env-default:"false"`
+	ChunkSize         int    `json:"encryption-chunk-size" env-default:"65536"`
+	EncryptionWorkers int    `json:"encryption-workers" env-default:"0"`
+	// see NameDecryptionConfig
+	NameDecryption NameDecryptionConfig `json:"name-decryption"`
+	// FileCacheSize is how many files presigned downloads keep in memory; with 0 none are
+	FileCacheSize int `json:"file-cache-size" env-default:"10000"`
+	// may start with ~; relative paths are relative to the working dir
+	FileStoragePath string          `json:"file-storage-path" env-required:"true"`
+	StorageDirMode  storage.DirMode `json:"storage-dir-mode" env-default:"0700"`
+	StorageReserve  uint64          `json:"storage-reserve" env-default:"67108864"`
+	FileIdsV7       bool            `json:"file-ids-v7" env-default:"false"`
+	// ConvergentEncryption stores identical uploads of any users once; see encryption/convergent.go
+	// for what that gives away. It needs CONVERGENT_KEY_NAME to name a convergent vault transit key.
+	ConvergentEncryption bool `json:"convergent-encryption" env-default:"false"`
+	// one of random, content-addressed; see storage.Layout
+	BlobLayout storage.Layout `json:"blob-layout" env-default:"random"`
+	// one of none, fdatasync, fsync; see storage.Durability
+	Durability        storage.Durability `json:"durability" env-default:"fsync"`
+	DecRotationPeriod Duration           `json:"dec-rotation-period" env-required:"true"`
+	TokenTimeToLive   Duration           `json:"token_time_to_live" env-default:"1h"`
+	ExportLinkTTL     Duration           `json:"export-link-ttl" env-default:"24h"`
+	ExportCleanup     Duration           `json:"export-cleanup-interval" env-default:"1h"`
+	PresignTTL        Duration           `json:"presign-ttl" env-default:"15m"`
+	PresignMaxTTL     Duration           `json:"presign-max-ttl" env-default:"24h"`
+	// wrong passwords per second allowed on each protected presigned link, in bursts of PresignGuessBurst
+	PresignGuessRate  float64            `json:"presign-guess-rate" env-default:"0.1"`
+	PresignGuessBurst int                `json:"presign-guess-burst" env-default:"5"`
+	LockTTL           Duration           `json:"lock-ttl" env-default:"15m"`
+	LockMaxTTL        Duration           `json:"lock-max-ttl" env-default:"1h"`
+	RetentionInterval Duration           `json:"retention-interval" env-default:"1m"`
+	ExpiryNotice      Duration           `json:"expiry-notice" env-default:"24h"`
+	NotificationTTL   Duration           `json:"notification-ttl" env-default:"720h"`
+	DuplicateNames    api.DuplicateNames `json:"duplicate-names" env-default:"allow"`
+	ImportLocalRoots  []string           `json:"import-local-roots"`
+	JobWorkers        int                `json:"job-workers" env-default:"2"`
+	FromUrlHosts      []string           `json:"from-url-hosts"`
+	FromUrlTimeout    Duration           `json:"from-url-timeout" env-default:"10m"`
+	AdminUsers        []string           `json:"admin-users"`
+	S3Backends        S3Backends         `json:"s3-backends"`
+	StripedBackends   StripedBackends    `json:"striped-backends"`
+	TieringRules      []TieringRule      `json:"tiering-rules"`
+	TieringInterval   Duration           `json:"tiering-interval" env-default:"1h"`
+	AccessFlush       Duration           `json:"access-flush-interval" env-default:"10s"`
+	UserQuota         int64              `json:"user-quota" env-default:"0"`
+	QuotaWarning      float64            `json:"quota-warning" env-default:"0.9"`
+	UploadCap         int64              `json:"monthly-upload-cap" env-default:"0"`
+	DownloadCap       int64              `json:"monthly-download-cap" env-default:"0"`
+	Middlewares       []string           `json:"middlewares"`
+	RateLimit         float64            `json:"rate-limit" env-default:"20"`
+	RateBurst         int                `json:"rate-burst" env-default:"40"`
+	FeatureFlags      string             `json:"feature-flags"`
+	FeatureReload     Duration           `json:"feature-flags-reload-interval" env-default:"0s"`
+	DecPruneAfter     Duration           `json:"dec-prune-after" env-default:"720h"`
+	DecPruneInterval  Duration           `json:"dec-prune-interval" env-default:"0s"`
+	Maintenance       bool               `json:"maintenance" env-default:"false"`
+	Schedule          ScheduleConfig     `json:"schedule"`
+	AuditSigningKey   string             `json:"audit-signing-key"`
+	ProxyAuth         ProxyAuthConfig    `json:"proxy-auth"`
+	LDAP              LDAPConfig         `json:"ldap"`
+	HLS               HLSConfig          `json:"hls"`
+	Preview           PreviewConfig      `json:"preview"`
+	Replication       ReplicationConfig  `json:"replication"`
+	Webhooks          Webhooks           `json:"webhooks"`
+	EventsInterval    Duration           `json:"events-interval" env-default:"5s"`
+	NATS              NATSConfig         `json:"nats"`
+	ContentIndex      ContentIndexConfig `json:"content-index"`
+	// one of reveal, hide; whether registering a taken name says so, see auth.Registrations
+	Registrations auth.Registrations `json:"registrations" env-default:"reveal"`
+	Tokens        TokensConfig       `json:"tokens"`
+	Cookies       CookiesConfig      `json:"cookies"`
+	Privacy       PrivacyConfig      `json:"privacy"`
+	Anomalies     AnomalyConfig      `json:"anomalies"`
+	Networks      NetworksConfig     `json:"networks"`
+	GeoIP         GeoIPConfig        `json:"geoip"`
 	HTTPConfig
 }
 
+// S3Backends names the remote blob backends next to the storage dir; file rows refer to them by name,
+// so a backend must not be renamed or dropped while it still holds blobs
+type S3Backends map[string]blobstore.S3Config
+
+// StripedBackends names local backends that stripe blobs with parity over several dirs, see blobstore.Striped.
+// Like S3Backends they are filled by tiering rules, and a backend must not be renamed or dropped while it holds blobs.
+type StripedBackends map[string]StripedConfig
+
+type StripedConfig struct {
+	// Dirs should each be on a disk of its own, the last one taking parity; their order must not change.
+	// They may start with ~ like the storage dir.
+	Dirs []string `json:"dirs"`
+}
+
+// TieringRule is tiering.Rule as written in the config file; backends are "local" or a name from S3Backends
+type TieringRule struct {
+	From           string   `json:"from"`
+	To             string   `json:"to"`
+	MinAge         Duration `json:"min-age"`
+	IdleFor        Duration `json:"idle-for"`
+	AccessedWithin Duration `json:"accessed-within"`
+	MinSize        int64    `json:"min-size"`
+	MaxSize        int64    `json:"max-size"`
+}
+
+// ScheduleConfig sets up the maintenance tasks next to retention-interval, export-cleanup-interval
+// and dec-prune-interval, which the retention, export-cleanup and dec-prune tasks run at.
+// A task with a zero interval doesn't run; Tasks turns off any other by name, e.g. {"orphan-gc": false}.
+type ScheduleConfig struct {
+	// Jitter delays every run by up to this fraction of the interval of its task
+	Jitter float64         `json:"jitter" env-default:"0.1"`
+	Tasks  map[string]bool `json:"tasks"`
+	// OrphanGC removes blobs of the storage dir that no file references once they are OrphanMinAge old;
+	// uploads may store a blob shortly before the file row, so it must be longer than any upload takes
+	OrphanGCInterval Duration `json:"orphan-gc-interval" env-default:"24h"`
+	OrphanMinAge     Duration `json:"orphan-min-age" env-default:"24h"`
+	UsageInterval    Duration `json:"usage-recalculation-interval" env-default:"24h"`
+	// AuditRetention is how long audit events are kept; with 0 they are kept for good
+	AuditRetention Duration `json:"audit-retention" env-default:"0s"`
+	AuditInterval  Duration `json:"audit-retention-interval" env-default:"24h"`
+	// Rewrap checks for DECs and file names wrapped with older versions of the vault transit key
+	// and starts a job rewrapping them; the vault token needs to read the key and use its rewrap endpoint
+	RewrapInterval Duration `json:"rewrap-interval" env-default:"1h"`
+}
+
+// NameDecryptionConfig tunes how listings decrypt file names; names still wrapped by vault
+// go to it BatchSize at a time, so a batch must fit the request size vault accepts
+type NameDecryptionConfig struct {
+	Workers   int `json:"workers" env-default:"4"`
+	BatchSize int `json:"batch-size" env-default:"100"`
+	// CacheSize is how many decrypted names are kept in memory; with 0 none are
+	CacheSize int `json:"cache-size" env-default:"10000"`
+}
+
+// HLSConfig gates in-browser streaming; transcoding needs ffmpeg on the host
+type HLSConfig struct {
+	Enabled         bool   `json:"enabled" env-default:"false"`
+	FFmpegPath      string `json:"ffmpeg-path" env-default:"ffmpeg"`
+	SegmentDuration int    `json:"segment-duration" env-default:"6"`
+}
+
+// PreviewConfig lets previews render the first page of PDFs, which needs pdftoppm of poppler on the host;
+// without it they are answered with 501
+type PreviewConfig struct {
+	PDF          bool     `json:"pdf" env-default:"false"`
+	PdftoppmPath string   `json:"pdftoppm-path" env-default:"pdftoppm"`
+	Resolution   int      `json:"resolution" env-default:"72"`
+	Timeout      Duration `json:"timeout" env-default:"30s"`
+}
+
+// ReplicationConfig copies blobs and file rows to a backend from S3Backends for disaster recovery
+// once target is set; the target should be in another region than the backends holding the blobs
+type ReplicationConfig struct {
+	Target            string   `json:"target"`
+	Interval          Duration `json:"interval" env-default:"1m"`
+	ReconcileInterval Duration `json:"reconcile-interval" env-default:"24h"`
+}
+
+// Webhooks get storage events POSTed to them, see events.Webhook. A webhook keeps its place in the change feed
+// by its name, so events that it missed while it was down are delivered once it is back.
+type Webhooks map[string]WebhookConfig
+
+type WebhookConfig struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// NATSConfig publishes storage events to subject.<event type>, such as cloud-storage.file.created, once url is set
+type NATSConfig struct {
+	URL     string        `json:"url"`
+	Subject string        `json:"subject" env-default:"cloud-storage"`
+	Schema  events.Schema `json:"schema" env-default:"native"`
+	// Source names the server in cloudevents messages
+	Source string `json:"source" env-default:"cloud-storage"`
+}
+
+// ContentIndexConfig indexes the words of text, markdown, pdf and docx files, so that search finds files by them
+type ContentIndexConfig struct {
+	Enabled  bool     `json:"enabled" env-default:"false"`
+	Interval Duration `json:"interval" env-default:"1m"`
+	// MaxFileSize is in bytes; larger files are only found by name
+	MaxFileSize int64 `json:"max-file-size" env-default:"16777216"`
+}
+
+// ProxyAuthConfig replaces session tokens with the identity header of a fronting SSO proxy;
+// registration and login are turned off while it is enabled
+type ProxyAuthConfig struct {
+	Enabled bool   `json:"enabled" env-default:"false"`
+	Header  string `json:"header" env-default:"X-Forwarded-User"`
+	// addresses or CIDR networks the proxy connects from, trusted-proxies when empty
+	TrustedNetworks []string `json:"trusted-networks"`
+}
+
+// PrivacyConfig sets when requests for erasure are due, a month by default as under GDPR, and how often the account-erasure task carries them out;
+// copies of the files in backups and replicas can't be decrypted once the erasure has shredded the keys of the user
+type PrivacyConfig struct {
+	DeletionDeadline Duration `json:"deletion-deadline" env-default:"720h"`
+	ErasureInterval  Duration `json:"erasure-interval" env-default:"1h"`
+}
+
+// AnomalyConfig sets what activity raises alerts by default, see anomaly.Thresholds; admins set other thresholds
+// for single users. Alerts are recorded as audit events and, once set up, POSTed to the webhook and mailed.
+type AnomalyConfig struct {
+	DownloadFactor   float64 `json:"download-factor" env-default:"10"`
+	MinDownloadBytes int64   `json:"min-download-bytes" env-default:"1073741824"`
+	DeletesPerHour   int64   `json:"deletes-per-hour" env-default:"500"`
+	NewCountries     bool    `json:"new-countries" env-default:"true"`
+	// header a fronting proxy puts the country of the client in, e.g. CF-IPCountry; logins aren't checked without it
+	CountryHeader string        `json:"country-header"`
+	Cooldown      Duration      `json:"cooldown" env-default:"1h"`
+	Webhook       WebhookConfig `json:"webhook"`
+	Mail          MailConfig    `json:"mail"`
+}
+
+// NetworksConfig lists the networks clients may use the api from, in CIDR notation or single addresses,
+// before logging in; with allow empty any network not denied may. Admins set rules of users and devices on top.
+type NetworksConfig struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// GeoIPConfig points to a table of networks and their countries, see geoip.ReadTable;
+// presigned links can't be restricted by country without one
+type GeoIPConfig struct {
+	Table string `json:"table"`
+}
+
+// MailConfig sends mail through the SMTP server at addr, host:port, once it is set
+type MailConfig struct {
+	Addr     string   `json:"addr"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+}
+
+// TokensConfig is what session tokens are issued for; leeway allows for the clocks of servers
+// sharing tokens being off from each other
+type TokensConfig struct {
+	Issuer   string   `json:"issuer" env-default:"cloud-storage"`
+	Audience string   `json:"audience" env-default:"cloud-storage"`
+	Leeway   Duration `json:"leeway" env-default:"30s"`
+}
+
+// CookiesConfig lets browsers log in through /api/auth/session, which keeps the session token
+// in an HttpOnly cookie; API clients keep using Bearer tokens
+type CookiesConfig struct {
+	Enabled bool   `json:"enabled" env-default:"false"`
+	Name    string `json:"name" env-default:"session"`
+	// only to be turned off for plain http in development
+	Secure bool `json:"secure" env-default:"true"`
+	// one of strict, lax
+	SameSite string `json:"same-site" env-default:"strict"`
+}
+
+// LDAPConfig checks logins against a directory when url is set; registration is turned off then.
+// Users are searched for with the service account, then bound as to check their password.
+type LDAPConfig struct {
+	URL            string `json:"url"`
+	BindDN         string `json:"bind-dn"`
+	BindPassword   string `json:"bind-password"`
+	BaseDN         string `json:"base-dn"`
+	UserAttribute  string `json:"user-attribute" env-default:"uid"`
+	GroupAttribute string `json:"group-attribute" env-default:"memberOf"`
+	// group DN to role; the admin role grants what admin-users does
+	GroupRoles map[string]string `json:"group-roles"`
+	Timeout    Duration          `json:"timeout" env-default:"10s"`
+}
+
 type HTTPConfig struct {
 	Address      string   `json:"address" env-default:"0.0.0.0:8080"`
 	WriteTimeout Duration `json:"write-timeout" env-default:"0s"`
 	IdleTimeout  Duration `json:"idle-timeout" env-default:"30s"`
 	ReadTimout   Duration `json:"read-timeout" env-default:"0s"`
+	// admin, health, metrics and pprof endpoints; keep it off the public network
+	ManagementAddress string `json:"management-address" env-default:"127.0.0.1:9090"`
+	// addresses or CIDR networks of reverse proxies whose X-Forwarded-For and X-Real-IP are believed
+	TrustedProxies []string `json:"trusted-proxies"`
+	// the go defaults accept megabyte headers and clients that never finish sending them
+	ReadHeaderTimeout Duration `json:"read-header-timeout" env-default:"10s"`
+	MaxHeaderBytes    int      `json:"max-header-bytes" env-default:"65536"`
+	// TLS, and with it HTTP/2 for browsers, is served when both files are set
+	TLSCertFile string      `json:"tls-cert-file"`
+	TLSKeyFile  string      `json:"tls-key-file"`
+	HTTP2       HTTP2Config `json:"http2"`
+}
+
+type HTTP2Config struct {
+	// accept HTTP/2 without TLS from clients that know the server speaks it, such as a load balancer
+	Cleartext            bool `json:"cleartext" env-default:"false"`
+	MaxConcurrentStreams int  `json:"max-concurrent-streams" env-default:"100"`
+	// flow control windows, which bound the upload data a single stream and a whole connection may have in flight
+	MaxReceiveBufferPerStream     int `json:"max-receive-buffer-per-stream" env-default:"1048576"`
+	MaxReceiveBufferPerConnection int `json:"max-receive-buffer-per-connection" env-default:"3145728"`
 }
 
 const configPathEnvVarName = "CONFIG_PATH"
@@ -58,12 +378,390 @@ func MustLoad() *AppConfig {
 		log.Fatalf("Could not read config file: %s", err)
 	}
 
+	storagePath, err := storage.ExpandPath(appConfig.FileStoragePath)
+	if err != nil {
+		log.Fatalf("Invalid file-storage-path: %s", err)
+	}
+	appConfig.FileStoragePath = storagePath
+
 	return &appConfig
 }
 
-func (cfg *AppConfig) UploadConfig() api.UploadConfig {
-	return api.UploadConfig{
-		MaxUploadSize: cfg.MaxUploadSize,
-		StorageDir:    cfg.FileStoragePath,
+// UploadConfig dedups uploads through convergent unless convergent encryption is disabled
+func (cfg *AppConfig) UploadConfig(policies *policy.Evaluator, blobs *blobstore.Store, convergent encryption.ConvergentCrypter) api.UploadConfig {
+	uploadConfig := api.UploadConfig{
+		MaxUploadSize:     cfg.MaxUploadSize,
+		MultipartOverhead: cfg.MultipartOverhead,
+		OptionalFileSize:  cfg.OptionalFileSize,
+		StorageDir:        cfg.FileStoragePath,
+		Space:             cfg.StorageSpace(),
+		Durability:        cfg.Durability,
+		TimeOrderedIds:    cfg.FileIdsV7,
+		Layout:            cfg.BlobLayout,
+		Quota:             cfg.Quota(),
+		DuplicateNames:    cfg.DuplicateNames,
+		Blobs:             blobs,
+		Policies:          policies,
+	}
+	if cfg.ConvergentEncryption {
+		uploadConfig.Convergent = convergent
+	}
+
+	return uploadConfig
+}
+
+// PolicyDefaults are the limits of users without any policy
+func (cfg *AppConfig) PolicyDefaults() policy.Limits {
+	return policy.Limits{
+		Quota:       cfg.UserQuota,
+		MaxFileSize: cfg.MaxUploadSize,
+	}
+}
+
+func (cfg *AppConfig) Quota() api.Quota {
+	return api.Quota{
+		Limit:  cfg.UserQuota,
+		WarnAt: cfg.QuotaWarning,
+	}
+}
+
+func (cfg *AppConfig) TrafficCaps() api.TrafficCaps {
+	return api.TrafficCaps{
+		Upload:   cfg.UploadCap,
+		Download: cfg.DownloadCap,
+	}
+}
+
+func (cfg *AppConfig) ProxyAuthConfig() (auth.ProxyConfig, error) {
+	networks := cfg.ProxyAuth.TrustedNetworks
+	if len(networks) == 0 {
+		networks = cfg.TrustedProxies
+	}
+
+	trusted, err := realip.ParseProxies(networks)
+	if err != nil {
+		return auth.ProxyConfig{}, err
+	}
+	if len(trusted) == 0 {
+		return auth.ProxyConfig{}, errors.New("proxy auth needs trusted networks")
+	}
+
+	return auth.ProxyConfig{
+		Header:  cfg.ProxyAuth.Header,
+		Trusted: trusted,
+	}, nil
+}
+
+func (cfg *AppConfig) TokenConfig() auth.TokenConfig {
+	return auth.TokenConfig{
+		Issuer:   cfg.Tokens.Issuer,
+		Audience: cfg.Tokens.Audience,
+		Leeway:   time.Duration(cfg.Tokens.Leeway),
+	}
+}
+
+func (cfg *AppConfig) CookieConfig() (auth.CookieConfig, error) {
+	sameSite := map[string]http.SameSite{
+		"strict": http.SameSiteStrictMode,
+		"lax":    http.SameSiteLaxMode,
+	}
+	mode, ok := sameSite[cfg.Cookies.SameSite]
+	if !ok {
+		return auth.CookieConfig{}, fmt.Errorf("unknown same-site mode %q; expected one of strict, lax", cfg.Cookies.SameSite)
+	}
+
+	return auth.CookieConfig{
+		Name:     cfg.Cookies.Name,
+		Secure:   cfg.Cookies.Secure,
+		SameSite: mode,
+	}, nil
+}
+
+func (cfg *AppConfig) LDAPDirectory() *ldap.Directory {
+	return ldap.NewDirectory(ldap.Config{
+		URL:            cfg.LDAP.URL,
+		BindDN:         cfg.LDAP.BindDN,
+		BindPassword:   cfg.LDAP.BindPassword,
+		BaseDN:         cfg.LDAP.BaseDN,
+		UserAttribute:  cfg.LDAP.UserAttribute,
+		GroupAttribute: cfg.LDAP.GroupAttribute,
+		GroupRoles:     cfg.LDAP.GroupRoles,
+		Timeout:        time.Duration(cfg.LDAP.Timeout),
+	})
+}
+
+// MiddlewareNames is the api middleware chain, outermost first
+func (cfg *AppConfig) MiddlewareNames() []string {
+	if len(cfg.Middlewares) > 0 {
+		return cfg.Middlewares
+	}
+	return middlewareProfiles[cfg.Environment]
+}
+
+func (cfg *AppConfig) StorageSpace() storage.Space {
+	return storage.Space{
+		Dir:     cfg.FileStoragePath,
+		Reserve: cfg.StorageReserve,
+	}
+}
+
+func (cfg *AppConfig) BlobStore() (*blobstore.Store, error) {
+	remotes := make(map[string]blobstore.Backend, len(cfg.S3Backends))
+	for name, s3Config := range cfg.S3Backends {
+		if name == blobstore.Local {
+			return nil, fmt.Errorf("storage backend name %q is reserved", name)
+		}
+
+		backend, err := blobstore.NewS3(s3Config, &http.Client{})
+		if err != nil {
+			return nil, fmt.Errorf("storage backend %q: %w", name, err)
+		}
+		remotes[name] = backend
+	}
+
+	for name := range cfg.StripedBackends {
+		if _, ok := remotes[name]; ok || name == blobstore.Local {
+			return nil, fmt.Errorf("storage backend name %q is taken", name)
+		}
+
+		backend, err := cfg.StripedBackend(name)
+		if err != nil {
+			return nil, fmt.Errorf("storage backend %q: %w", name, err)
+		}
+		remotes[name] = backend
+	}
+
+	return blobstore.NewStore(cfg.FileStoragePath, cfg.Durability, remotes), nil
+}
+
+// StripedBackend sets up the backend with that name from StripedBackends
+func (cfg *AppConfig) StripedBackend(name string) (*blobstore.Striped, error) {
+	striped, ok := cfg.StripedBackends[name]
+	if !ok {
+		return nil, blobstore.UnknownBackendError{Name: name}
+	}
+
+	dirs := make([]string, 0, len(striped.Dirs))
+	for _, dir := range striped.Dirs {
+		expanded, err := storage.ExpandPath(dir)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, expanded)
+	}
+
+	return blobstore.NewStriped(dirs, cfg.Durability)
+}
+
+func (cfg *AppConfig) TieringConfig(pool *jobs.Pool) tiering.Config {
+	rules := make([]tiering.Rule, 0, len(cfg.TieringRules))
+	for _, rule := range cfg.TieringRules {
+		rules = append(rules, tiering.Rule{
+			From:           rule.From,
+			To:             rule.To,
+			MinAge:         time.Duration(rule.MinAge),
+			IdleFor:        time.Duration(rule.IdleFor),
+			AccessedWithin: time.Duration(rule.AccessedWithin),
+			MinSize:        rule.MinSize,
+			MaxSize:        rule.MaxSize,
+		})
+	}
+
+	return tiering.Config{
+		Rules:    rules,
+		Interval: time.Duration(cfg.TieringInterval),
+		Jobs:     pool,
+	}
+}
+
+func (cfg *AppConfig) ExportConfig(blobs *blobstore.Store, pool *jobs.Pool) export.Config {
+	return export.Config{
+		Blobs:          blobs,
+		LinkTimeToLive: time.Duration(cfg.ExportLinkTTL),
+		Jobs:           pool,
+	}
+}
+
+func (cfg *AppConfig) PresignConfig() presign.Config {
+	return presign.Config{
+		TimeToLive:    time.Duration(cfg.PresignTTL),
+		MaxTimeToLive: time.Duration(cfg.PresignMaxTTL),
+	}
+}
+
+func (cfg *AppConfig) LockConfig() api.LockConfig {
+	return api.LockConfig{
+		TimeToLive:    time.Duration(cfg.LockTTL),
+		MaxTimeToLive: time.Duration(cfg.LockMaxTTL),
+	}
+}
+
+func (cfg *AppConfig) RetentionConfig(blobs *blobstore.Store) retention.Config {
+	return retention.Config{
+		Blobs:           blobs,
+		NotifyBefore:    time.Duration(cfg.ExpiryNotice),
+		NotificationTTL: time.Duration(cfg.NotificationTTL),
+	}
+}
+
+func (cfg *AppConfig) PrivacyConfig(blobs *blobstore.Store, exports privacy.ExportRemover, keys encryption.KeyShredder) privacy.Config {
+	return privacy.Config{
+		Blobs:   blobs,
+		Exports: exports,
+		Keys:    keys,
+	}
+}
+
+func (cfg *AppConfig) AnomalyConfig() (anomaly.Config, error) {
+	c := cfg.Anomalies
+	anomalyConfig := anomaly.Config{
+		Defaults: anomaly.Thresholds{
+			DownloadFactor:   c.DownloadFactor,
+			MinDownloadBytes: c.MinDownloadBytes,
+			DeletesPerHour:   c.DeletesPerHour,
+			NewCountries:     c.NewCountries,
+		},
+		CountryHeader: c.CountryHeader,
+		Cooldown:      time.Duration(c.Cooldown),
+	}
+
+	if c.Webhook.URL != "" {
+		u, err := url.Parse(c.Webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return anomaly.Config{}, errors.New("anomalies webhook: url must be an absolute http or https url")
+		}
+
+		anomalyConfig.Notifiers = append(anomalyConfig.Notifiers, anomaly.Webhook{
+			URL:    c.Webhook.URL,
+			Secret: c.Webhook.Secret,
+			Client: &http.Client{Timeout: 30 * time.Second},
+		})
+	}
+
+	if c.Mail.Addr != "" {
+		host, _, err := net.SplitHostPort(c.Mail.Addr)
+		if err != nil {
+			return anomaly.Config{}, fmt.Errorf("anomalies mail: addr must be host:port: %w", err)
+		}
+		if c.Mail.From == "" || len(c.Mail.To) == 0 {
+			return anomaly.Config{}, errors.New("anomalies mail: from and to must be set")
+		}
+
+		mail := anomaly.Mail{Addr: c.Mail.Addr, From: c.Mail.From, To: c.Mail.To}
+		if c.Mail.Username != "" {
+			mail.Auth = smtp.PlainAuth("", c.Mail.Username, c.Mail.Password, host)
+		}
+		anomalyConfig.Notifiers = append(anomalyConfig.Notifiers, mail)
+	}
+
+	return anomalyConfig, nil
+}
+
+func (cfg *AppConfig) NetworkRules() (ipfilter.Rules, error) {
+	return ipfilter.Parse(cfg.Networks.Allow, cfg.Networks.Deny)
+}
+
+// GeoIPProvider returns nil when no table is configured
+func (cfg *AppConfig) GeoIPProvider() (geoip.Provider, error) {
+	if cfg.GeoIP.Table == "" {
+		return nil, nil
+	}
+
+	table, err := geoip.LoadTable(cfg.GeoIP.Table)
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func (cfg *AppConfig) HLSServiceConfig(blobs *blobstore.Store) hls.Config {
+	return hls.Config{
+		Blobs:      blobs,
+		Durability: cfg.Durability,
+	}
+}
+
+func (cfg *AppConfig) ReplicationConfig() replication.Config {
+	return replication.Config{
+		Target:            cfg.Replication.Target,
+		Interval:          time.Duration(cfg.Replication.Interval),
+		ReconcileInterval: time.Duration(cfg.Replication.ReconcileInterval),
+	}
+}
+
+func (cfg *AppConfig) ContentIndexConfig() search.Config {
+	return search.Config{
+		Interval:    time.Duration(cfg.ContentIndex.Interval),
+		MaxFileSize: cfg.ContentIndex.MaxFileSize,
+	}
+}
+
+func (cfg *AppConfig) EventPublishers() (map[string]events.Publisher, error) {
+	publishers := make(map[string]events.Publisher, len(cfg.Webhooks))
+	for name, webhook := range cfg.Webhooks {
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook %q: url must be an absolute http or https url", name)
+		}
+
+		publishers[name] = events.Webhook{
+			URL:    webhook.URL,
+			Secret: webhook.Secret,
+			Client: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+
+	// NATS keeps its place in the feed under the name of its own, which webhooks can't take
+	if cfg.NATS.URL != "" {
+		if _, ok := publishers["nats"]; ok {
+			return nil, errors.New(`webhook name "nats" is reserved`)
+		}
+
+		nats, err := events.NewNATS(cfg.NATS.URL, cfg.NATS.Subject, cfg.NATS.Schema, cfg.NATS.Source)
+		if err != nil {
+			return nil, err
+		}
+		publishers["nats"] = nats
+	}
+
+	return publishers, nil
+}
+
+func (cfg *AppConfig) KeysConfig(blobs *blobstore.Store) keys.Config {
+	return keys.Config{
+		Blobs:      blobs,
+		RetiredFor: time.Duration(cfg.DecPruneAfter),
+	}
+}
+
+func (cfg *AppConfig) SchedulerConfig(mode *maintenance.Mode) scheduler.Config {
+	return scheduler.Config{
+		Jitter:      cfg.Schedule.Jitter,
+		Maintenance: mode,
+	}
+}
+
+// TaskInterval returns how often the named maintenance task runs, zero if it is off
+func (cfg *AppConfig) TaskInterval(name string, interval Duration) time.Duration {
+	if enabled, ok := cfg.Schedule.Tasks[name]; ok && !enabled {
+		return 0
+	}
+	return time.Duration(interval)
+}
+
+func (cfg *AppConfig) JobsConfig() jobs.Config {
+	return jobs.Config{
+		Workers: cfg.JobWorkers,
+	}
+}
+
+func (cfg *AppConfig) ImportConfig(store importer.Store, pool *jobs.Pool) importer.Config {
+	return importer.Config{
+		Store:       store,
+		MaxFileSize: cfg.MaxUploadSize,
+		LocalRoots:  cfg.ImportLocalRoots,
+		UrlHosts:    cfg.FromUrlHosts,
+		UrlTimeout:  time.Duration(cfg.FromUrlTimeout),
+		Jobs:        pool,
 	}
 }
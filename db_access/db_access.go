@@ -1,6 +1,7 @@
 package db_access
 
 import (
+	"context"
 	"database/sql/driver"
 	"fmt"
 	"strings"
@@ -16,6 +17,19 @@ func (err UniqueConstraintError) Error() string {
 	return strings.Join([]string{"unique constraint violation: ", err.Table, ".", err.Column}, "")
 }
 
+// UserNamesConflictError lists the names of users that differ only in case, one group per name
+type UserNamesConflictError struct {
+	Names [][]string
+}
+
+func (err UserNamesConflictError) Error() string {
+	groups := make([]string, 0, len(err.Names))
+	for _, names := range err.Names {
+		groups = append(groups, strings.Join(names, ", "))
+	}
+	return "user names differ only in case: " + strings.Join(groups, "; ")
+}
+
 type NoRowsError struct {
 	Table string
 }
@@ -27,6 +41,10 @@ func (err NoRowsError) Error() string {
 type Time time.Time
 
 func (t Time) Value() (driver.Value, error) {
+	// zero time is stored as NULL so that Scan gives it back unchanged
+	if time.Time(t).IsZero() {
+		return nil, nil
+	}
 	return time.Time(t).Unix(), nil
 }
 
@@ -52,23 +70,748 @@ type DEC struct {
 	Id           DecId
 	Value        string
 	CreationTime Time
+	// KeyVersion is the version of the vault transit key Value is wrapped with
+	KeyVersion int64
+	// OwnerId is the user whose contents alone the key encrypts, 0 for the keys file names are sealed with
+	OwnerId int64
+}
+
+// WrappedName is the encrypted name of a file as stored
+type WrappedName struct {
+	GeneratedName string
+	FileName      string
+}
+
+// KeyVersionCount counts what is wrapped with one version of the vault transit key
+type KeyVersionCount struct {
+	KeyVersion int64
+	Count      int64
 }
 
 type User struct {
 	Id int64
 	Name string
 	PasswordHash []byte
+	// roles granted by an external directory, e.g. "admin"
+	Roles []string
+	// empty for users outside of any org
+	Org string
+	// session tokens carry it and are only taken while it is unchanged
+	TokenVersion int64
 }
 
-type DbAccess interface {
-	AddFile(generatedName string, filename string) error
+// Device is a client a user registered, which gets session tokens for its refresh token
+// until it is revoked
+type Device struct {
+	Id       string
+	OwnerId  int64
+	Name     string
+	Platform string
+	// RefreshHash is the SHA-256 of the refresh token, which isn't kept itself
+	RefreshHash []byte
+	CreatedAt   Time
+	// LastUsed is when the refresh token was last exchanged
+	LastUsed Time
+}
+
+type File struct {
+	GeneratedName string
+	FileName      string
+	OwnerId       int64
+	// BlobName names the blob in the storage dir; copies share the blob of their source,
+	// so a blob is referenced by every file row with its name
+	BlobName string
+	// Backend holds the blob; all files sharing a blob agree on it
+	Backend string
+	// LastAccess is when the file was last downloaded, zero if never
+	LastAccess    Time
+	DownloadCount int64
+	// Size is the plaintext size, 0 for files stored before sizes were recorded
+	Size int64
+	// ExpiresAt is zero for files that are kept until deleted
+	ExpiresAt Time
+	// NameIndex is the keyed hash of the lowercased name, empty until it is computed
+	NameIndex string
+	// FolderId is the folder the file is in, 0 for the root
+	FolderId int64
+}
+
+// Folder is a folder of a user, made when files are uploaded into it
+type Folder struct {
+	Id      int64
+	OwnerId int64
+	// ParentId is 0 for folders in the root
+	ParentId int64
+	// Name is encrypted like the names of files, see File.NameIndex for NameIndex
+	Name      string
+	NameIndex string
+}
+
+// Consent records a user agreeing to a version of the document of a purpose, e.g. "terms",
+// or withdrawing their consent to it; records are only ever added
+type Consent struct {
+	UserId  int64
+	Purpose string
+	// Version is empty on records of a withdrawal
+	Version string
+	At      Time
+}
+
+// DeletionRequest asks for the account of a user to be erased
+type DeletionRequest struct {
+	UserId      int64
+	RequestedAt Time
+	// Deadline is when the erasure is due by law
+	Deadline Time
+	// CompletedAt is zero while the request is pending
+	CompletedAt Time
+}
+
+// Blob is what file rows sharing a blob have in common
+type Blob struct {
+	Name    string
+	Backend string
+	// Size is the plaintext size of the files referencing the blob
+	Size int64
+}
+
+// FileAccess sums up the downloads of a file since the previous one was recorded
+type FileAccess struct {
+	GeneratedName string
+	At            Time
+	Count         int64
+}
+
+// BlobFilter selects blobs by the files referencing them: a blob is as old as its oldest file
+// and was accessed when any of its files was. Zero fields don't filter.
+type BlobFilter struct {
+	CreatedBefore  Time
+	AccessedBefore Time
+	AccessedAfter  Time
+	MinSize        int64
+	MaxSize        int64
+}
+
+type NotificationKind string
+
+const (
+	NotificationFileExpiring NotificationKind = "file-expiring"
+	NotificationFileExpired  NotificationKind = "file-expired"
+	// export notifications hold the id of the export as FileId and no FileName
+	NotificationExportReady  NotificationKind = "export-ready"
+	NotificationExportFailed NotificationKind = "export-failed"
+)
+
+type Notification struct {
+	Id      int64
+	OwnerId int64
+	Kind    NotificationKind
+	FileId  string
+	// FileName is encrypted like files.fileName, the file itself may be gone by the time it is read
+	FileName     string
+	At           Time
+	CreationTime Time
+}
+
+// Usage holds the counters of one user, or of everyone for the admin view;
+// traffic counters are those of a single calendar month in UTC
+type Usage struct {
+	Users           int64
+	Files           int64
+	StoredBytes     int64
+	UploadedBytes   int64
+	DownloadedBytes int64
+}
+
+// UserTraffic is the traffic of one user in a single calendar month in UTC
+type UserTraffic struct {
+	OwnerId         int64
+	Name            string
+	UploadedBytes   int64
+	DownloadedBytes int64
+}
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+)
+
+type Export struct {
+	Id           string
+	OwnerId      int64
+	Status       ExportStatus
+	Error        string
+	CreationTime Time
+	ExpiresAt    Time
+}
+
+type ImportStatus string
+
+const (
+	ImportQueued   ImportStatus = "queued"
+	ImportRunning  ImportStatus = "running"
+	ImportFinished ImportStatus = "finished"
+	ImportFailed   ImportStatus = "failed"
+)
+
+type Import struct {
+	Id            string
+	OwnerId       int64
+	Source        string
+	Status        ImportStatus
+	TotalFiles    int64
+	ImportedFiles int64
+	FailedFiles   int64
+	ImportedBytes int64
+	Error         string
+	CreationTime  Time
+}
+
+type MigrationStatus string
+
+const (
+	MigrationQueued   MigrationStatus = "queued"
+	MigrationRunning  MigrationStatus = "running"
+	MigrationFinished MigrationStatus = "finished"
+	MigrationFailed   MigrationStatus = "failed"
+)
+
+type JobStatus string
+
+const (
+	JobQueued   JobStatus = "queued"
+	JobRunning  JobStatus = "running"
+	JobFinished JobStatus = "finished"
+	JobFailed   JobStatus = "failed"
+)
+
+// Job is a long operation as the jobs api reports it, whatever kind it is
+type Job struct {
+	Id   string
+	Type string
+	// OwnerId is 0 for jobs of the server, such as migrations
+	OwnerId int64
+	Status  JobStatus
+	// Done counts the units of work done out of Total, in whatever unit the type of job uses;
+	// Total is 0 until it is known
+	Done  int64
+	Total int64
+	// Result is a JSON document, empty until the job finishes
+	Result       string
+	Error        string
+	CreationTime Time
+	UpdateTime   Time
+}
+
+// Migration moves the blobs of files created before CreatedBefore from one backend to another
+type Migration struct {
+	Id            string
+	FromBackend   string
+	ToBackend     string
+	CreatedBefore Time
+	Status        MigrationStatus
+	TotalBlobs    int64
+	MovedBlobs    int64
+	FailedBlobs   int64
+	MovedBytes    int64
+	Error         string
+	CreationTime  Time
+}
+
+// AuditEvent records a request that changed something, or tried to. Alerts of the anomaly detector
+// are recorded as events too, with method ALERT and the kind of the alert in the path, e.g. anomaly/mass-delete,
+// as are requests from denied networks, with method DENY and the rules that denied them, e.g. network/user,
+// and denied uses of restricted presigned links and wrong passwords of protected ones, with method DENY and
+// presigned/<file id>/<link id> as the path.
+type AuditEvent struct {
+	Id int64
+	At Time
+	// -1 for requests made without logging in
+	UserId     int64
+	Method     string
+	Path       string
+	Status     int
+	RemoteAddr string
+	RequestId  string
+}
+
+type ChangeKind string
+
+const (
+	// ChangeCreated is a new file row; its blob may still be being written
+	ChangeCreated ChangeKind = "created"
+	// ChangeUpdated is a file whose name, expiry or backend changed; the blob itself never does
+	ChangeUpdated ChangeKind = "updated"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change is an entry of the feed of file row changes
+type Change struct {
+	Seq           int64
+	Kind          ChangeKind
+	GeneratedName string
+	// BlobName is the blob of the file; for deletions it is empty unless no file references the blob anymore
+	BlobName string
+	// OwnerId and Size are those of the file as of the change
+	OwnerId int64
+	Size    int64
+	At      Time
+}
+
+// ReplicatedBlob is a blob kept on a replication target
+type ReplicatedBlob struct {
+	Name string
+	// Referenced is false once every file of the blob is gone
+	Referenced bool
+}
+
+// FileLock keeps other clients from saving over a file being edited
+type FileLock struct {
+	GeneratedName string
+	// Token is what the holder proves the lock is theirs by
+	Token string
+	// Holder names who holds the lock, as shown to the clients it turns away
+	Holder    string
+	CreatedAt Time
+	ExpiresAt Time
+}
+
+// Comment is a remark on a file; a reply to another comment continues its thread
+type Comment struct {
+	Id            int64
+	GeneratedName string
+	AuthorId      int64
+	// ReplyTo is the id of the comment this one answers, 0 for one starting a thread
+	ReplyTo int64
+	// Body is encrypted; it is empty for comments without one
+	Body      string
+	CreatedAt Time
+}
+
+type PolicyScope string
+
+const (
+	GlobalScope PolicyScope = "global"
+	OrgScope    PolicyScope = "org"
+	UserScope   PolicyScope = "user"
+)
+
+// Policy overrides storage limits for everyone in its scope; nil fields are inherited from the wider scope
+type Policy struct {
+	Scope PolicyScope
+	// org name or user id, empty for the global scope
+	Subject string
+	// bytes a user may store, 0 for no limit
+	Quota       *int64
+	MaxFileSize *int64
+	// MaxFileSizeOverride is how big an upload may get beyond MaxFileSize when the client asks for it,
+	// such as backup tooling pushing archives; 0 lets nobody in the scope ask
+	MaxFileSizeOverride *int64
+	// media types such as image/png or image/*; */* allows everything
+	AllowedTypes []string
+	// longest time files are kept, 0 to keep them until deleted
+	Retention *time.Duration
+}
+
+type NetworkScope string
+
+const (
+	NetworkUser   NetworkScope = "user"
+	NetworkDevice NetworkScope = "device"
+)
+
+// NetworkRules limit the networks a user, or one of their devices, may use the api from
+type NetworkRules struct {
+	Scope NetworkScope
+	// user id or device id
+	Subject string
+	// networks in CIDR notation or single addresses; with Allow empty any network not denied is allowed
+	Allow []string
+	Deny  []string
+}
+
+// AlertThresholds override the thresholds of the anomaly detector for a user; nil fields keep the defaults
+type AlertThresholds struct {
+	UserId int64
+	// DownloadFactor is how many times the usual hourly download volume an hour may see; 0 turns the check off
+	DownloadFactor *float64
+	// MinDownloadBytes is the volume below which an hour of downloads is never unusual
+	MinDownloadBytes *int64
+	// DeletesPerHour is how many files may be deleted in an hour; 0 turns the check off
+	DeletesPerHour *int64
+	NewCountries   *bool
+}
+
+// FileRepo keeps file rows and the blobs they reference
+type FileRepo interface {
+	AddFile(generatedName string, filename string, ownerId int64, size int64) error
+	AddFileCopy(generatedName string, filename string, ownerId int64, blobName string, size int64) error
+	// RenameFile clears the name index, which no longer matches
+	RenameFile(generatedName string, filename string) error
 	RemoveFile(generatedName string) error
-	GetFile(generatedName string) (filename string, err error)
-	
+	// DeleteFile removes the file with its tags and reports whether no other file references its blob anymore
+	DeleteFile(generatedName string) (blob Blob, orphaned bool, err error)
+	GetFile(generatedName string) (File, error)
+	GetUserFiles(ownerId int64) ([]File, error)
+	// ListBlobNames returns the blobs kept in the storage dir itself
+	ListBlobNames() ([]string, error)
+	GetBlobs(backend string, filter BlobFilter) ([]Blob, error)
+	// SetBlobBackend moves every file of the blob from one backend to the other;
+	// it reports false if none was left on the first, e.g. because they were deleted meanwhile
+	SetBlobBackend(blobName string, from string, to string) (bool, error)
+	AddFileTags(generatedName string, tags []string) error
+	GetFileTags(generatedName string) ([]string, error)
+	// RecordFileAccesses applies many downloads at once; files that are gone are skipped
+	RecordFileAccesses(accesses []FileAccess) error
+	// GetRecentFiles returns the files of the user that were downloaded, most recent first
+	GetRecentFiles(ownerId int64, limit int) ([]File, error)
+	// SetFileExpiry with a zero time keeps the file until deleted; owners get notified again about a new expiry
+	SetFileExpiry(generatedName string, expiresAt Time) error
+	GetExpiredFiles(now Time) ([]File, error)
+	// GetFilesToNotify returns files expiring before t whose owners have not been told yet
+	GetFilesToNotify(t Time) ([]File, error)
+	MarkExpiryNotified(generatedName string) error
+	SetFileNameIndex(generatedName string, index string) error
+	// GetFilesByNameIndex returns the files of the owner with that name index, and those that have none yet
+	GetFilesByNameIndex(ownerId int64, index string) ([]File, error)
+	// GetCollectionVersion returns a number that moves on with every change to the files of the owner
+	// or to their metadata, 0 for owners whose files never changed
+	GetCollectionVersion(ownerId int64) (int64, error)
+}
+
+// KeyRepo keeps the data encryption keys
+type KeyRepo interface {
 	GetDEC(id DecId) (DEC, error)
+	// GetNewestDEC returns the newest of the keys owned by no user
 	GetNewestDEC() (DEC, error)
+	// GetNewestUserDEC returns the newest key of the user
+	GetNewestUserDEC(ownerId int64) (DEC, error)
 	AddDEC(dec *DEC) error
-	
+	// GetDECs returns every key, oldest first
+	GetDECs() ([]DEC, error)
+	// RemoveDEC makes whatever is still encrypted with the key unreadable for good
+	RemoveDEC(id DecId) error
+	// RemoveUserDECs removes every key of the user like RemoveDEC and returns their ids
+	RemoveUserDECs(ownerId int64) ([]DecId, error)
+	// GetIndexKey returns the wrapped key of the file name index
+	GetIndexKey() (string, error)
+	// AddIndexKey stores the wrapped key unless there is one already, and returns the one kept
+	AddIndexKey(value string) (string, error)
+	// GetContentKey returns the wrapped key of the convergently encrypted blob of the content
+	GetContentKey(contentId string) (string, error)
+	// RewrapDEC replaces the wrapped value of a key, which stays the same key
+	RewrapDEC(id DecId, value string, keyVersion int64) error
+	// GetNamesBelowKeyVersion returns up to limit file names wrapped with a vault key version below version,
+	// ordered by and starting after the generated name after
+	GetNamesBelowKeyVersion(version int64, after string, limit int) ([]WrappedName, error)
+	// RewrapFileName replaces the encrypted name of a file unless it changed since it was old;
+	// the file keeps its name, so nothing else about it changes
+	RewrapFileName(generatedName string, old string, new string) error
+	// CountNamesByKeyVersion counts the file names wrapped with each vault key version, oldest first
+	CountNamesByKeyVersion() ([]KeyVersionCount, error)
+	// CountNamesByDEC counts the file names sealed with each DEC; names still wrapped by vault aren't counted
+	CountNamesByDEC() (map[DecId]int, error)
+}
+
+type UserRepo interface {
 	GetUser(user *User) error
+	// AddUser fails with UniqueConstraintError on users.name if the name is taken, in any case
 	AddUser(user *User) error
+	// SetUserRoles replaces the roles of the user
+	SetUserRoles(userId int64, roles []string) error
+	// SetUserOrg moves the user into org, or out of any with an empty one
+	SetUserOrg(userId int64, org string) error
+	// RevokeUserTokens bumps the token version of the user, which ends all of their sessions,
+	// and removes their devices
+	RevokeUserTokens(userId int64) error
+	// GetUsers lists every user by id, without their password hashes
+	GetUsers() ([]User, error)
+	// RenameUser fails with UniqueConstraintError if the name is taken
+	RenameUser(userId int64, name string) error
+	// UniqueUserNames makes names that differ only in case taken as one; it fails with
+	// UserNamesConflictError while some users have such names
+	UniqueUserNames() error
+}
+
+// AnomalyRepo keeps what the anomaly detector knows of users beyond the usage counters
+type AnomalyRepo interface {
+	// SetAlertThresholds adds the thresholds of the user or replaces them
+	SetAlertThresholds(t AlertThresholds) error
+	// DeleteAlertThresholds fails with NoRowsError if the user has none
+	DeleteAlertThresholds(userId int64) error
+	// GetAlertThresholds fails with NoRowsError if the user has none
+	GetAlertThresholds(userId int64) (AlertThresholds, error)
+	// AddLoginCountry records that the user logged in from the country, an ISO 3166 code, and reports
+	// whether it is new to a user who had logged in from others before
+	AddLoginCountry(userId int64, country string, at Time) (bool, error)
+}
+
+// NetworkRuleRepo keeps the network rules of users and devices, at most one per scope and subject
+type NetworkRuleRepo interface {
+	// SetNetworkRules adds the rules or replaces the ones of the same scope and subject
+	SetNetworkRules(rules NetworkRules) error
+	DeleteNetworkRules(scope NetworkScope, subject string) error
+	// ListNetworkRules returns the rules of users first, then those of devices
+	ListNetworkRules() ([]NetworkRules, error)
+	// GetRequestNetworkRules returns the rules of the user and of the device, which may be empty, the user first
+	// and leaving out those that don't exist
+	GetRequestNetworkRules(userId int64, deviceId string) ([]NetworkRules, error)
+}
+
+// FolderRepo keeps the folders of users; names are checked before folders are added, so concurrent
+// requests may add two of the same name, as with files
+type FolderRepo interface {
+	// AddFolder sets the id of the folder it adds
+	AddFolder(folder *Folder) error
+	// GetFoldersByNameIndex returns the folders of the owner in the parent with that name index
+	GetFoldersByNameIndex(ownerId int64, parentId int64, index string) ([]Folder, error)
+	GetFolders(ownerId int64) ([]Folder, error)
+	SetFileFolder(generatedName string, folderId int64) error
+}
+
+// PrivacyRepo keeps the records of consent and the requests for erasure, which outlive the accounts they are about
+type PrivacyRepo interface {
+	AddConsent(consent Consent) error
+	// GetConsents returns the consent records of the user, oldest first
+	GetConsents(userId int64) ([]Consent, error)
+	// AddDeletionRequest fails with UniqueConstraintError if the user has requested it before
+	AddDeletionRequest(req DeletionRequest) error
+	// GetDeletionRequest fails with NoRowsError if the user hasn't requested it
+	GetDeletionRequest(userId int64) (DeletionRequest, error)
+	// GetPendingDeletionRequests returns the requests not completed yet, the earliest deadline first
+	GetPendingDeletionRequests() ([]DeletionRequest, error)
+	// EraseUser takes the name, password, roles and org off the user row, which stays for what refers
+	// to it, revokes their sessions, removes their devices, notifications, imports, stars, alert thresholds,
+	// login countries, network rules, usage and traffic and clears their comments. It doesn't touch their files,
+	// exports or keys, which have to be deleted along with their blobs before.
+	EraseUser(userId int64) error
+	CompleteDeletionRequest(userId int64, at Time) error
+}
+
+// DeviceRepo keeps the devices of users; devices go with their user's sessions, see RevokeUserTokens
+type DeviceRepo interface {
+	AddDevice(device Device) error
+	// GetDevice fails with NoRowsError for devices that don't exist or were removed
+	GetDevice(id string) (Device, error)
+	// GetDeviceByRefreshHash fails with NoRowsError if no device has the refresh token
+	GetDeviceByRefreshHash(hash []byte) (Device, error)
+	// GetDevices returns the devices of the user, the latest registered first
+	GetDevices(ownerId int64) ([]Device, error)
+	TouchDevice(id string, at Time) error
+	// RemoveDevice fails with NoRowsError if the user has no such device
+	RemoveDevice(ownerId int64, id string) error
+}
+
+// UsageRepo keeps the usage counters; file counts and stored bytes are kept up to date
+// by the db itself as files come and go
+type UsageRepo interface {
+	AddTraffic(ownerId int64, at Time, uploaded int64, downloaded int64) error
+	GetUsage(ownerId int64, month Time) (Usage, error)
+	GetTotalUsage(month Time) (Usage, error)
+	// GetTrafficByUser returns the traffic of every user who had any in the month, busiest first
+	GetTrafficByUser(month Time) ([]UserTraffic, error)
+	// RecalculateUsage recounts the files and stored bytes of every user, returning how many were off
+	RecalculateUsage() (int64, error)
+}
+
+type NotificationRepo interface {
+	AddNotification(n *Notification) error
+	GetNotifications(ownerId int64) ([]Notification, error)
+	RemoveNotificationsBefore(t Time) error
+}
+
+type ExportRepo interface {
+	AddExport(export *Export) error
+	UpdateExport(export *Export) error
+	GetExport(id string) (Export, error)
+	GetExpiredExports(now Time) ([]Export, error)
+	GetUserExports(ownerId int64) ([]Export, error)
+	RemoveExport(id string) error
+	// FailUnfinishedExports fails pending and running exports, expiring them at expiresAt
+	FailUnfinishedExports(reason string, expiresAt Time) error
+}
+
+type ImportRepo interface {
+	AddImport(imp *Import) error
+	UpdateImport(imp *Import) error
+	GetImport(id string) (Import, error)
+	FailUnfinishedImports(reason string) error
+}
+
+type MigrationRepo interface {
+	AddMigration(m *Migration) error
+	UpdateMigration(m *Migration) error
+	GetMigration(id string) (Migration, error)
+	FailUnfinishedMigrations(reason string) error
+}
+
+type JobRepo interface {
+	AddJob(job *Job) error
+	UpdateJob(job *Job) error
+	GetJob(id string) (Job, error)
+	FailUnfinishedJobs(reason string) error
+}
+
+// AuditRepo keeps the audit log; events are never changed once added, only dropped past retention
+type AuditRepo interface {
+	AddAuditEvent(e *AuditEvent) error
+	// GetAuditEvents returns the events from from up to but excluding to, oldest first
+	GetAuditEvents(from Time, to Time) ([]AuditEvent, error)
+	// RemoveAuditEventsBefore removes the events before t, returning how many
+	RemoveAuditEventsBefore(t Time) (int64, error)
+}
+
+// PolicyRepo keeps storage policies, at most one per scope and subject
+type PolicyRepo interface {
+	// SetPolicy adds the policy or replaces the one of the same scope and subject
+	SetPolicy(p Policy) error
+	DeletePolicy(scope PolicyScope, subject string) error
+	// ListPolicies returns every policy, the widest scopes first
+	ListPolicies() ([]Policy, error)
+	// GetUserPolicies returns the policies that apply to the user: the global one, the one of their org
+	// and their own, widest first and leaving out those that don't exist
+	GetUserPolicies(userId int64) ([]Policy, error)
+}
+
+// ChangeRepo is the feed of file row changes. The db records changes itself, in the transaction making them,
+// while any consumer has a cursor on the feed; a consumer reading up to a change sets its cursor past it.
+type ChangeRepo interface {
+	// GetChanges returns up to limit changes after seq, oldest first
+	GetChanges(after int64, limit int) ([]Change, error)
+	// GetChangeCursor fails with NoRowsError for consumers that never set theirs
+	GetChangeCursor(consumer string) (int64, error)
+	SetChangeCursor(consumer string, seq int64) error
+	// KeepChangeCursors removes the cursors of consumers other than these, so changes aren't kept for them
+	KeepChangeCursors(consumers []string) error
+	// RemoveReadChanges drops the changes every consumer has read
+	RemoveReadChanges() error
+}
+
+// ReplicationRepo keeps what was replicated to which target
+type ReplicationRepo interface {
+	// GetFiles returns up to limit files with ids after after, ordered by id
+	GetFiles(after string, limit int) ([]File, error)
+	// GetUnreplicatedBlobs returns up to limit blobs named after after that were not replicated to the target yet
+	GetUnreplicatedBlobs(target string, after string, limit int) ([]Blob, error)
+	// GetReplicatedBlobs returns the blobs replicated to the target, and whether any file still references each
+	GetReplicatedBlobs(target string) ([]ReplicatedBlob, error)
+	MarkBlobReplicated(target string, blobName string) error
+	UnmarkBlobReplicated(target string, blobName string) error
+}
+
+// ContentRepo keeps the index of file contents: for every file, the keyed hashes of the words in it
+type ContentRepo interface {
+	// GetUnindexedFiles returns up to limit files with ids after after that weren't indexed yet
+	GetUnindexedFiles(after string, limit int) ([]File, error)
+	// SetFileTerms replaces the terms of the file; a file without any still counts as indexed
+	SetFileTerms(generatedName string, ownerId int64, terms []string) error
+	// GetFilesByTerms returns the files of the owner that have all of the terms
+	GetFilesByTerms(ownerId int64, terms []string) ([]File, error)
+}
+
+// LockRepo keeps at most one lock per file; locks go with their files
+type LockRepo interface {
+	// GetFileLock fails with NoRowsError for files without a lock. Expired locks are still returned,
+	// it is up to the caller to ignore them.
+	GetFileLock(generatedName string) (FileLock, error)
+	// SetFileLock adds the lock or replaces the one the file has
+	SetFileLock(lock FileLock) error
+	RemoveFileLock(generatedName string) error
+}
+
+// CommentRepo keeps the comments on files; comments go with their files
+type CommentRepo interface {
+	// AddComment sets the id of c
+	AddComment(c *Comment) error
+	// GetComment fails with NoRowsError for comments that don't exist
+	GetComment(id int64) (Comment, error)
+	// GetComments returns the comments on the file, oldest first
+	GetComments(generatedName string) ([]Comment, error)
+	// RemoveComment removes the comment along with the replies to it
+	RemoveComment(id int64) error
+}
+
+// StarRepo keeps the files each user starred; stars go with their files
+type StarRepo interface {
+	// StarFile stars the file for the user; starring it again keeps the time it was first starred
+	StarFile(userId int64, generatedName string, at Time) error
+	UnstarFile(userId int64, generatedName string) error
+	// GetStarredFiles returns the files the user starred, the latest starred first
+	GetStarredFiles(userId int64) ([]File, error)
+}
+
+// MetadataRepo keeps the custom metadata of files: an encrypted document per file along with
+// the keyed hashes of its pairs, which listings are filtered by. Metadata goes with its file.
+type MetadataRepo interface {
+	// SetFileMetadata replaces the metadata of the file, or removes it if data is empty;
+	// it fails with NoRowsError for files that don't exist
+	SetFileMetadata(generatedName string, ownerId int64, data string, terms []string) error
+	// GetFileMetadata returns an empty string for files without metadata
+	GetFileMetadata(generatedName string) (string, error)
+	// GetFileMetadataTerms returns the terms the metadata of the file is found by
+	GetFileMetadataTerms(generatedName string) ([]string, error)
+	// GetFilesByMetadata returns the files of the owner whose metadata has all of the terms
+	GetFilesByMetadata(ownerId int64, terms []string) ([]File, error)
+}
+
+// ConvergentBlob is a blob encrypted with a key derived from its content, which every upload
+// of the same content shares, whoever it comes from
+type ConvergentBlob struct {
+	// ContentId is derived from the content the way its key is, so it can't be told from the content without the key service
+	ContentId string
+	BlobName  string
+	// Key is the wrapped content key
+	Key string
+}
+
+// ConvergentRepo finds convergently encrypted blobs by their content. The row of a blob goes
+// along with the last file referencing it.
+type ConvergentRepo interface {
+	// GetConvergentBlob fails with NoRowsError if the content has no blob
+	GetConvergentBlob(contentId string) (ConvergentBlob, error)
+	// AddConvergentBlob fails with UniqueConstraintError if the content has a blob already
+	AddConvergentBlob(blob ConvergentBlob) error
+}
+
+// Transactor makes multi-step operations atomic
+type Transactor interface {
+	// WithTx runs fn with repos bound to a single transaction, which is committed if fn returns nil
+	// and rolled back otherwise. fn must make its calls through repos; a WithTx on them joins the transaction.
+	// fn may run again after a rollback if the db was busy, so it must be idempotent: it must not have
+	// effects outside of repos, whose writes the rollback undoes.
+	WithTx(ctx context.Context, fn func(repos DbAccess) error) error
+}
+
+// DbAccess is the whole db. Code that needs a single repo should take just that one,
+// so that new repos don't change its constructor and tests can mock what it uses.
+type DbAccess interface {
+	FileRepo
+	KeyRepo
+	UserRepo
+	DeviceRepo
+	PrivacyRepo
+	AnomalyRepo
+	NetworkRuleRepo
+	FolderRepo
+	UsageRepo
+	NotificationRepo
+	ExportRepo
+	ImportRepo
+	MigrationRepo
+	JobRepo
+	AuditRepo
+	PolicyRepo
+	ChangeRepo
+	ReplicationRepo
+	ContentRepo
+	LockRepo
+	CommentRepo
+	StarRepo
+	MetadataRepo
+	ConvergentRepo
+	Transactor
 }
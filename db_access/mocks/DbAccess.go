@@ -4,6 +4,7 @@ package db_access_mocks
 
 import (
 	db_access "cloud-storage/db_access"
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -21,17 +22,6414 @@ func (_m *DbAccess) EXPECT() *DbAccess_Expecter {
 	return &DbAccess_Expecter{mock: &_m.Mock}
 }
 
+// AddAuditEvent provides a mock function with given fields: e
+func (_m *DbAccess) AddAuditEvent(e *db_access.AuditEvent) error {
+	ret := _m.Called(e)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddAuditEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.AuditEvent) error); ok {
+		r0 = rf(e)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddAuditEvent_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddAuditEvent'
+type DbAccess_AddAuditEvent_Call struct {
+	*mock.Call
+}
+
+// AddAuditEvent is a helper method to define mock.On call
+//   - e *db_access.AuditEvent
+func (_e *DbAccess_Expecter) AddAuditEvent(e interface{}) *DbAccess_AddAuditEvent_Call {
+	return &DbAccess_AddAuditEvent_Call{Call: _e.mock.On("AddAuditEvent", e)}
+}
+
+func (_c *DbAccess_AddAuditEvent_Call) Run(run func(e *db_access.AuditEvent)) *DbAccess_AddAuditEvent_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.AuditEvent))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddAuditEvent_Call) Return(_a0 error) *DbAccess_AddAuditEvent_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddAuditEvent_Call) RunAndReturn(run func(*db_access.AuditEvent) error) *DbAccess_AddAuditEvent_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddComment provides a mock function with given fields: c
+func (_m *DbAccess) AddComment(c *db_access.Comment) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddComment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Comment) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddComment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddComment'
+type DbAccess_AddComment_Call struct {
+	*mock.Call
+}
+
+// AddComment is a helper method to define mock.On call
+//   - c *db_access.Comment
+func (_e *DbAccess_Expecter) AddComment(c interface{}) *DbAccess_AddComment_Call {
+	return &DbAccess_AddComment_Call{Call: _e.mock.On("AddComment", c)}
+}
+
+func (_c *DbAccess_AddComment_Call) Run(run func(c *db_access.Comment)) *DbAccess_AddComment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Comment))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddComment_Call) Return(_a0 error) *DbAccess_AddComment_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddComment_Call) RunAndReturn(run func(*db_access.Comment) error) *DbAccess_AddComment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddConsent provides a mock function with given fields: consent
+func (_m *DbAccess) AddConsent(consent db_access.Consent) error {
+	ret := _m.Called(consent)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddConsent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.Consent) error); ok {
+		r0 = rf(consent)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddConsent_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddConsent'
+type DbAccess_AddConsent_Call struct {
+	*mock.Call
+}
+
+// AddConsent is a helper method to define mock.On call
+//   - consent db_access.Consent
+func (_e *DbAccess_Expecter) AddConsent(consent interface{}) *DbAccess_AddConsent_Call {
+	return &DbAccess_AddConsent_Call{Call: _e.mock.On("AddConsent", consent)}
+}
+
+func (_c *DbAccess_AddConsent_Call) Run(run func(consent db_access.Consent)) *DbAccess_AddConsent_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Consent))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddConsent_Call) Return(_a0 error) *DbAccess_AddConsent_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddConsent_Call) RunAndReturn(run func(db_access.Consent) error) *DbAccess_AddConsent_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddConvergentBlob provides a mock function with given fields: blob
+func (_m *DbAccess) AddConvergentBlob(blob db_access.ConvergentBlob) error {
+	ret := _m.Called(blob)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddConvergentBlob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.ConvergentBlob) error); ok {
+		r0 = rf(blob)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddConvergentBlob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddConvergentBlob'
+type DbAccess_AddConvergentBlob_Call struct {
+	*mock.Call
+}
+
+// AddConvergentBlob is a helper method to define mock.On call
+//   - blob db_access.ConvergentBlob
+func (_e *DbAccess_Expecter) AddConvergentBlob(blob interface{}) *DbAccess_AddConvergentBlob_Call {
+	return &DbAccess_AddConvergentBlob_Call{Call: _e.mock.On("AddConvergentBlob", blob)}
+}
+
+func (_c *DbAccess_AddConvergentBlob_Call) Run(run func(blob db_access.ConvergentBlob)) *DbAccess_AddConvergentBlob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.ConvergentBlob))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddConvergentBlob_Call) Return(_a0 error) *DbAccess_AddConvergentBlob_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddConvergentBlob_Call) RunAndReturn(run func(db_access.ConvergentBlob) error) *DbAccess_AddConvergentBlob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // AddDEC provides a mock function with given fields: dec
 func (_m *DbAccess) AddDEC(dec *db_access.DEC) error {
 	ret := _m.Called(dec)
 
 	if len(ret) == 0 {
-		panic("no return value specified for AddDEC")
+		panic("no return value specified for AddDEC")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.DEC) error); ok {
+		r0 = rf(dec)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddDEC'
+type DbAccess_AddDEC_Call struct {
+	*mock.Call
+}
+
+// AddDEC is a helper method to define mock.On call
+//   - dec *db_access.DEC
+func (_e *DbAccess_Expecter) AddDEC(dec interface{}) *DbAccess_AddDEC_Call {
+	return &DbAccess_AddDEC_Call{Call: _e.mock.On("AddDEC", dec)}
+}
+
+func (_c *DbAccess_AddDEC_Call) Run(run func(dec *db_access.DEC)) *DbAccess_AddDEC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.DEC))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddDEC_Call) Return(_a0 error) *DbAccess_AddDEC_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddDEC_Call) RunAndReturn(run func(*db_access.DEC) error) *DbAccess_AddDEC_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddDeletionRequest provides a mock function with given fields: req
+func (_m *DbAccess) AddDeletionRequest(req db_access.DeletionRequest) error {
+	ret := _m.Called(req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDeletionRequest")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.DeletionRequest) error); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddDeletionRequest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddDeletionRequest'
+type DbAccess_AddDeletionRequest_Call struct {
+	*mock.Call
+}
+
+// AddDeletionRequest is a helper method to define mock.On call
+//   - req db_access.DeletionRequest
+func (_e *DbAccess_Expecter) AddDeletionRequest(req interface{}) *DbAccess_AddDeletionRequest_Call {
+	return &DbAccess_AddDeletionRequest_Call{Call: _e.mock.On("AddDeletionRequest", req)}
+}
+
+func (_c *DbAccess_AddDeletionRequest_Call) Run(run func(req db_access.DeletionRequest)) *DbAccess_AddDeletionRequest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.DeletionRequest))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddDeletionRequest_Call) Return(_a0 error) *DbAccess_AddDeletionRequest_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddDeletionRequest_Call) RunAndReturn(run func(db_access.DeletionRequest) error) *DbAccess_AddDeletionRequest_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddDevice provides a mock function with given fields: device
+func (_m *DbAccess) AddDevice(device db_access.Device) error {
+	ret := _m.Called(device)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDevice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.Device) error); ok {
+		r0 = rf(device)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddDevice_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddDevice'
+type DbAccess_AddDevice_Call struct {
+	*mock.Call
+}
+
+// AddDevice is a helper method to define mock.On call
+//   - device db_access.Device
+func (_e *DbAccess_Expecter) AddDevice(device interface{}) *DbAccess_AddDevice_Call {
+	return &DbAccess_AddDevice_Call{Call: _e.mock.On("AddDevice", device)}
+}
+
+func (_c *DbAccess_AddDevice_Call) Run(run func(device db_access.Device)) *DbAccess_AddDevice_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Device))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddDevice_Call) Return(_a0 error) *DbAccess_AddDevice_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddDevice_Call) RunAndReturn(run func(db_access.Device) error) *DbAccess_AddDevice_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddExport provides a mock function with given fields: export
+func (_m *DbAccess) AddExport(export *db_access.Export) error {
+	ret := _m.Called(export)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddExport")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Export) error); ok {
+		r0 = rf(export)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddExport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddExport'
+type DbAccess_AddExport_Call struct {
+	*mock.Call
+}
+
+// AddExport is a helper method to define mock.On call
+//   - export *db_access.Export
+func (_e *DbAccess_Expecter) AddExport(export interface{}) *DbAccess_AddExport_Call {
+	return &DbAccess_AddExport_Call{Call: _e.mock.On("AddExport", export)}
+}
+
+func (_c *DbAccess_AddExport_Call) Run(run func(export *db_access.Export)) *DbAccess_AddExport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Export))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddExport_Call) Return(_a0 error) *DbAccess_AddExport_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddExport_Call) RunAndReturn(run func(*db_access.Export) error) *DbAccess_AddExport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddFile provides a mock function with given fields: generatedName, filename, ownerId, size
+func (_m *DbAccess) AddFile(generatedName string, filename string, ownerId int64, size int64) error {
+	ret := _m.Called(generatedName, filename, ownerId, size)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, int64, int64) error); ok {
+		r0 = rf(generatedName, filename, ownerId, size)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddFile'
+type DbAccess_AddFile_Call struct {
+	*mock.Call
+}
+
+// AddFile is a helper method to define mock.On call
+//   - generatedName string
+//   - filename string
+//   - ownerId int64
+//   - size int64
+func (_e *DbAccess_Expecter) AddFile(generatedName interface{}, filename interface{}, ownerId interface{}, size interface{}) *DbAccess_AddFile_Call {
+	return &DbAccess_AddFile_Call{Call: _e.mock.On("AddFile", generatedName, filename, ownerId, size)}
+}
+
+func (_c *DbAccess_AddFile_Call) Run(run func(generatedName string, filename string, ownerId int64, size int64)) *DbAccess_AddFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(int64), args[3].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddFile_Call) Return(_a0 error) *DbAccess_AddFile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddFile_Call) RunAndReturn(run func(string, string, int64, int64) error) *DbAccess_AddFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddFileCopy provides a mock function with given fields: generatedName, filename, ownerId, blobName, size
+func (_m *DbAccess) AddFileCopy(generatedName string, filename string, ownerId int64, blobName string, size int64) error {
+	ret := _m.Called(generatedName, filename, ownerId, blobName, size)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddFileCopy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, int64, string, int64) error); ok {
+		r0 = rf(generatedName, filename, ownerId, blobName, size)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddFileCopy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddFileCopy'
+type DbAccess_AddFileCopy_Call struct {
+	*mock.Call
+}
+
+// AddFileCopy is a helper method to define mock.On call
+//   - generatedName string
+//   - filename string
+//   - ownerId int64
+//   - blobName string
+//   - size int64
+func (_e *DbAccess_Expecter) AddFileCopy(generatedName interface{}, filename interface{}, ownerId interface{}, blobName interface{}, size interface{}) *DbAccess_AddFileCopy_Call {
+	return &DbAccess_AddFileCopy_Call{Call: _e.mock.On("AddFileCopy", generatedName, filename, ownerId, blobName, size)}
+}
+
+func (_c *DbAccess_AddFileCopy_Call) Run(run func(generatedName string, filename string, ownerId int64, blobName string, size int64)) *DbAccess_AddFileCopy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(int64), args[3].(string), args[4].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddFileCopy_Call) Return(_a0 error) *DbAccess_AddFileCopy_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddFileCopy_Call) RunAndReturn(run func(string, string, int64, string, int64) error) *DbAccess_AddFileCopy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddFileTags provides a mock function with given fields: generatedName, tags
+func (_m *DbAccess) AddFileTags(generatedName string, tags []string) error {
+	ret := _m.Called(generatedName, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddFileTags")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
+		r0 = rf(generatedName, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddFileTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddFileTags'
+type DbAccess_AddFileTags_Call struct {
+	*mock.Call
+}
+
+// AddFileTags is a helper method to define mock.On call
+//   - generatedName string
+//   - tags []string
+func (_e *DbAccess_Expecter) AddFileTags(generatedName interface{}, tags interface{}) *DbAccess_AddFileTags_Call {
+	return &DbAccess_AddFileTags_Call{Call: _e.mock.On("AddFileTags", generatedName, tags)}
+}
+
+func (_c *DbAccess_AddFileTags_Call) Run(run func(generatedName string, tags []string)) *DbAccess_AddFileTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddFileTags_Call) Return(_a0 error) *DbAccess_AddFileTags_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddFileTags_Call) RunAndReturn(run func(string, []string) error) *DbAccess_AddFileTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddFolder provides a mock function with given fields: folder
+func (_m *DbAccess) AddFolder(folder *db_access.Folder) error {
+	ret := _m.Called(folder)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddFolder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Folder) error); ok {
+		r0 = rf(folder)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddFolder_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddFolder'
+type DbAccess_AddFolder_Call struct {
+	*mock.Call
+}
+
+// AddFolder is a helper method to define mock.On call
+//   - folder *db_access.Folder
+func (_e *DbAccess_Expecter) AddFolder(folder interface{}) *DbAccess_AddFolder_Call {
+	return &DbAccess_AddFolder_Call{Call: _e.mock.On("AddFolder", folder)}
+}
+
+func (_c *DbAccess_AddFolder_Call) Run(run func(folder *db_access.Folder)) *DbAccess_AddFolder_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Folder))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddFolder_Call) Return(_a0 error) *DbAccess_AddFolder_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddFolder_Call) RunAndReturn(run func(*db_access.Folder) error) *DbAccess_AddFolder_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddImport provides a mock function with given fields: imp
+func (_m *DbAccess) AddImport(imp *db_access.Import) error {
+	ret := _m.Called(imp)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddImport")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Import) error); ok {
+		r0 = rf(imp)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddImport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddImport'
+type DbAccess_AddImport_Call struct {
+	*mock.Call
+}
+
+// AddImport is a helper method to define mock.On call
+//   - imp *db_access.Import
+func (_e *DbAccess_Expecter) AddImport(imp interface{}) *DbAccess_AddImport_Call {
+	return &DbAccess_AddImport_Call{Call: _e.mock.On("AddImport", imp)}
+}
+
+func (_c *DbAccess_AddImport_Call) Run(run func(imp *db_access.Import)) *DbAccess_AddImport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Import))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddImport_Call) Return(_a0 error) *DbAccess_AddImport_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddImport_Call) RunAndReturn(run func(*db_access.Import) error) *DbAccess_AddImport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddIndexKey provides a mock function with given fields: value
+func (_m *DbAccess) AddIndexKey(value string) (string, error) {
+	ret := _m.Called(value)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddIndexKey")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(value)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(value)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(value)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_AddIndexKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddIndexKey'
+type DbAccess_AddIndexKey_Call struct {
+	*mock.Call
+}
+
+// AddIndexKey is a helper method to define mock.On call
+//   - value string
+func (_e *DbAccess_Expecter) AddIndexKey(value interface{}) *DbAccess_AddIndexKey_Call {
+	return &DbAccess_AddIndexKey_Call{Call: _e.mock.On("AddIndexKey", value)}
+}
+
+func (_c *DbAccess_AddIndexKey_Call) Run(run func(value string)) *DbAccess_AddIndexKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddIndexKey_Call) Return(_a0 string, _a1 error) *DbAccess_AddIndexKey_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_AddIndexKey_Call) RunAndReturn(run func(string) (string, error)) *DbAccess_AddIndexKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddJob provides a mock function with given fields: job
+func (_m *DbAccess) AddJob(job *db_access.Job) error {
+	ret := _m.Called(job)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Job) error); ok {
+		r0 = rf(job)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddJob'
+type DbAccess_AddJob_Call struct {
+	*mock.Call
+}
+
+// AddJob is a helper method to define mock.On call
+//   - job *db_access.Job
+func (_e *DbAccess_Expecter) AddJob(job interface{}) *DbAccess_AddJob_Call {
+	return &DbAccess_AddJob_Call{Call: _e.mock.On("AddJob", job)}
+}
+
+func (_c *DbAccess_AddJob_Call) Run(run func(job *db_access.Job)) *DbAccess_AddJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Job))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddJob_Call) Return(_a0 error) *DbAccess_AddJob_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddJob_Call) RunAndReturn(run func(*db_access.Job) error) *DbAccess_AddJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddLoginCountry provides a mock function with given fields: userId, country, at
+func (_m *DbAccess) AddLoginCountry(userId int64, country string, at db_access.Time) (bool, error) {
+	ret := _m.Called(userId, country, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddLoginCountry")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string, db_access.Time) (bool, error)); ok {
+		return rf(userId, country, at)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string, db_access.Time) bool); ok {
+		r0 = rf(userId, country, at)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string, db_access.Time) error); ok {
+		r1 = rf(userId, country, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_AddLoginCountry_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddLoginCountry'
+type DbAccess_AddLoginCountry_Call struct {
+	*mock.Call
+}
+
+// AddLoginCountry is a helper method to define mock.On call
+//   - userId int64
+//   - country string
+//   - at db_access.Time
+func (_e *DbAccess_Expecter) AddLoginCountry(userId interface{}, country interface{}, at interface{}) *DbAccess_AddLoginCountry_Call {
+	return &DbAccess_AddLoginCountry_Call{Call: _e.mock.On("AddLoginCountry", userId, country, at)}
+}
+
+func (_c *DbAccess_AddLoginCountry_Call) Run(run func(userId int64, country string, at db_access.Time)) *DbAccess_AddLoginCountry_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string), args[2].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddLoginCountry_Call) Return(_a0 bool, _a1 error) *DbAccess_AddLoginCountry_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_AddLoginCountry_Call) RunAndReturn(run func(int64, string, db_access.Time) (bool, error)) *DbAccess_AddLoginCountry_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddMigration provides a mock function with given fields: m
+func (_m *DbAccess) AddMigration(m *db_access.Migration) error {
+	ret := _m.Called(m)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddMigration")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Migration) error); ok {
+		r0 = rf(m)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddMigration_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddMigration'
+type DbAccess_AddMigration_Call struct {
+	*mock.Call
+}
+
+// AddMigration is a helper method to define mock.On call
+//   - m *db_access.Migration
+func (_e *DbAccess_Expecter) AddMigration(m interface{}) *DbAccess_AddMigration_Call {
+	return &DbAccess_AddMigration_Call{Call: _e.mock.On("AddMigration", m)}
+}
+
+func (_c *DbAccess_AddMigration_Call) Run(run func(m *db_access.Migration)) *DbAccess_AddMigration_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Migration))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddMigration_Call) Return(_a0 error) *DbAccess_AddMigration_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddMigration_Call) RunAndReturn(run func(*db_access.Migration) error) *DbAccess_AddMigration_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddNotification provides a mock function with given fields: n
+func (_m *DbAccess) AddNotification(n *db_access.Notification) error {
+	ret := _m.Called(n)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddNotification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Notification) error); ok {
+		r0 = rf(n)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddNotification_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddNotification'
+type DbAccess_AddNotification_Call struct {
+	*mock.Call
+}
+
+// AddNotification is a helper method to define mock.On call
+//   - n *db_access.Notification
+func (_e *DbAccess_Expecter) AddNotification(n interface{}) *DbAccess_AddNotification_Call {
+	return &DbAccess_AddNotification_Call{Call: _e.mock.On("AddNotification", n)}
+}
+
+func (_c *DbAccess_AddNotification_Call) Run(run func(n *db_access.Notification)) *DbAccess_AddNotification_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Notification))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddNotification_Call) Return(_a0 error) *DbAccess_AddNotification_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddNotification_Call) RunAndReturn(run func(*db_access.Notification) error) *DbAccess_AddNotification_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddTraffic provides a mock function with given fields: ownerId, at, uploaded, downloaded
+func (_m *DbAccess) AddTraffic(ownerId int64, at db_access.Time, uploaded int64, downloaded int64) error {
+	ret := _m.Called(ownerId, at, uploaded, downloaded)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddTraffic")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, db_access.Time, int64, int64) error); ok {
+		r0 = rf(ownerId, at, uploaded, downloaded)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddTraffic_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddTraffic'
+type DbAccess_AddTraffic_Call struct {
+	*mock.Call
+}
+
+// AddTraffic is a helper method to define mock.On call
+//   - ownerId int64
+//   - at db_access.Time
+//   - uploaded int64
+//   - downloaded int64
+func (_e *DbAccess_Expecter) AddTraffic(ownerId interface{}, at interface{}, uploaded interface{}, downloaded interface{}) *DbAccess_AddTraffic_Call {
+	return &DbAccess_AddTraffic_Call{Call: _e.mock.On("AddTraffic", ownerId, at, uploaded, downloaded)}
+}
+
+func (_c *DbAccess_AddTraffic_Call) Run(run func(ownerId int64, at db_access.Time, uploaded int64, downloaded int64)) *DbAccess_AddTraffic_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(db_access.Time), args[2].(int64), args[3].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddTraffic_Call) Return(_a0 error) *DbAccess_AddTraffic_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddTraffic_Call) RunAndReturn(run func(int64, db_access.Time, int64, int64) error) *DbAccess_AddTraffic_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddUser provides a mock function with given fields: user
+func (_m *DbAccess) AddUser(user *db_access.User) error {
+	ret := _m.Called(user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.User) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_AddUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddUser'
+type DbAccess_AddUser_Call struct {
+	*mock.Call
+}
+
+// AddUser is a helper method to define mock.On call
+//   - user *db_access.User
+func (_e *DbAccess_Expecter) AddUser(user interface{}) *DbAccess_AddUser_Call {
+	return &DbAccess_AddUser_Call{Call: _e.mock.On("AddUser", user)}
+}
+
+func (_c *DbAccess_AddUser_Call) Run(run func(user *db_access.User)) *DbAccess_AddUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.User))
+	})
+	return _c
+}
+
+func (_c *DbAccess_AddUser_Call) Return(_a0 error) *DbAccess_AddUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_AddUser_Call) RunAndReturn(run func(*db_access.User) error) *DbAccess_AddUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CompleteDeletionRequest provides a mock function with given fields: userId, at
+func (_m *DbAccess) CompleteDeletionRequest(userId int64, at db_access.Time) error {
+	ret := _m.Called(userId, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteDeletionRequest")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, db_access.Time) error); ok {
+		r0 = rf(userId, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_CompleteDeletionRequest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompleteDeletionRequest'
+type DbAccess_CompleteDeletionRequest_Call struct {
+	*mock.Call
+}
+
+// CompleteDeletionRequest is a helper method to define mock.On call
+//   - userId int64
+//   - at db_access.Time
+func (_e *DbAccess_Expecter) CompleteDeletionRequest(userId interface{}, at interface{}) *DbAccess_CompleteDeletionRequest_Call {
+	return &DbAccess_CompleteDeletionRequest_Call{Call: _e.mock.On("CompleteDeletionRequest", userId, at)}
+}
+
+func (_c *DbAccess_CompleteDeletionRequest_Call) Run(run func(userId int64, at db_access.Time)) *DbAccess_CompleteDeletionRequest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_CompleteDeletionRequest_Call) Return(_a0 error) *DbAccess_CompleteDeletionRequest_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_CompleteDeletionRequest_Call) RunAndReturn(run func(int64, db_access.Time) error) *DbAccess_CompleteDeletionRequest_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CountNamesByDEC provides a mock function with no fields
+func (_m *DbAccess) CountNamesByDEC() (map[db_access.DecId]int, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountNamesByDEC")
+	}
+
+	var r0 map[db_access.DecId]int
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (map[db_access.DecId]int, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() map[db_access.DecId]int); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[db_access.DecId]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_CountNamesByDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountNamesByDEC'
+type DbAccess_CountNamesByDEC_Call struct {
+	*mock.Call
+}
+
+// CountNamesByDEC is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) CountNamesByDEC() *DbAccess_CountNamesByDEC_Call {
+	return &DbAccess_CountNamesByDEC_Call{Call: _e.mock.On("CountNamesByDEC")}
+}
+
+func (_c *DbAccess_CountNamesByDEC_Call) Run(run func()) *DbAccess_CountNamesByDEC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_CountNamesByDEC_Call) Return(_a0 map[db_access.DecId]int, _a1 error) *DbAccess_CountNamesByDEC_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_CountNamesByDEC_Call) RunAndReturn(run func() (map[db_access.DecId]int, error)) *DbAccess_CountNamesByDEC_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CountNamesByKeyVersion provides a mock function with no fields
+func (_m *DbAccess) CountNamesByKeyVersion() ([]db_access.KeyVersionCount, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountNamesByKeyVersion")
+	}
+
+	var r0 []db_access.KeyVersionCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]db_access.KeyVersionCount, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []db_access.KeyVersionCount); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.KeyVersionCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_CountNamesByKeyVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountNamesByKeyVersion'
+type DbAccess_CountNamesByKeyVersion_Call struct {
+	*mock.Call
+}
+
+// CountNamesByKeyVersion is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) CountNamesByKeyVersion() *DbAccess_CountNamesByKeyVersion_Call {
+	return &DbAccess_CountNamesByKeyVersion_Call{Call: _e.mock.On("CountNamesByKeyVersion")}
+}
+
+func (_c *DbAccess_CountNamesByKeyVersion_Call) Run(run func()) *DbAccess_CountNamesByKeyVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_CountNamesByKeyVersion_Call) Return(_a0 []db_access.KeyVersionCount, _a1 error) *DbAccess_CountNamesByKeyVersion_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_CountNamesByKeyVersion_Call) RunAndReturn(run func() ([]db_access.KeyVersionCount, error)) *DbAccess_CountNamesByKeyVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteAlertThresholds provides a mock function with given fields: userId
+func (_m *DbAccess) DeleteAlertThresholds(userId int64) error {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAlertThresholds")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_DeleteAlertThresholds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAlertThresholds'
+type DbAccess_DeleteAlertThresholds_Call struct {
+	*mock.Call
+}
+
+// DeleteAlertThresholds is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) DeleteAlertThresholds(userId interface{}) *DbAccess_DeleteAlertThresholds_Call {
+	return &DbAccess_DeleteAlertThresholds_Call{Call: _e.mock.On("DeleteAlertThresholds", userId)}
+}
+
+func (_c *DbAccess_DeleteAlertThresholds_Call) Run(run func(userId int64)) *DbAccess_DeleteAlertThresholds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_DeleteAlertThresholds_Call) Return(_a0 error) *DbAccess_DeleteAlertThresholds_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_DeleteAlertThresholds_Call) RunAndReturn(run func(int64) error) *DbAccess_DeleteAlertThresholds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteFile provides a mock function with given fields: generatedName
+func (_m *DbAccess) DeleteFile(generatedName string) (db_access.Blob, bool, error) {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFile")
+	}
+
+	var r0 db_access.Blob
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.Blob, bool, error)); ok {
+		return rf(generatedName)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.Blob); ok {
+		r0 = rf(generatedName)
+	} else {
+		r0 = ret.Get(0).(db_access.Blob)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(generatedName)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(generatedName)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// DbAccess_DeleteFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteFile'
+type DbAccess_DeleteFile_Call struct {
+	*mock.Call
+}
+
+// DeleteFile is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) DeleteFile(generatedName interface{}) *DbAccess_DeleteFile_Call {
+	return &DbAccess_DeleteFile_Call{Call: _e.mock.On("DeleteFile", generatedName)}
+}
+
+func (_c *DbAccess_DeleteFile_Call) Run(run func(generatedName string)) *DbAccess_DeleteFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_DeleteFile_Call) Return(blob db_access.Blob, orphaned bool, err error) *DbAccess_DeleteFile_Call {
+	_c.Call.Return(blob, orphaned, err)
+	return _c
+}
+
+func (_c *DbAccess_DeleteFile_Call) RunAndReturn(run func(string) (db_access.Blob, bool, error)) *DbAccess_DeleteFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteNetworkRules provides a mock function with given fields: scope, subject
+func (_m *DbAccess) DeleteNetworkRules(scope db_access.NetworkScope, subject string) error {
+	ret := _m.Called(scope, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteNetworkRules")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.NetworkScope, string) error); ok {
+		r0 = rf(scope, subject)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_DeleteNetworkRules_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteNetworkRules'
+type DbAccess_DeleteNetworkRules_Call struct {
+	*mock.Call
+}
+
+// DeleteNetworkRules is a helper method to define mock.On call
+//   - scope db_access.NetworkScope
+//   - subject string
+func (_e *DbAccess_Expecter) DeleteNetworkRules(scope interface{}, subject interface{}) *DbAccess_DeleteNetworkRules_Call {
+	return &DbAccess_DeleteNetworkRules_Call{Call: _e.mock.On("DeleteNetworkRules", scope, subject)}
+}
+
+func (_c *DbAccess_DeleteNetworkRules_Call) Run(run func(scope db_access.NetworkScope, subject string)) *DbAccess_DeleteNetworkRules_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.NetworkScope), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_DeleteNetworkRules_Call) Return(_a0 error) *DbAccess_DeleteNetworkRules_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_DeleteNetworkRules_Call) RunAndReturn(run func(db_access.NetworkScope, string) error) *DbAccess_DeleteNetworkRules_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeletePolicy provides a mock function with given fields: scope, subject
+func (_m *DbAccess) DeletePolicy(scope db_access.PolicyScope, subject string) error {
+	ret := _m.Called(scope, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePolicy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.PolicyScope, string) error); ok {
+		r0 = rf(scope, subject)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_DeletePolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeletePolicy'
+type DbAccess_DeletePolicy_Call struct {
+	*mock.Call
+}
+
+// DeletePolicy is a helper method to define mock.On call
+//   - scope db_access.PolicyScope
+//   - subject string
+func (_e *DbAccess_Expecter) DeletePolicy(scope interface{}, subject interface{}) *DbAccess_DeletePolicy_Call {
+	return &DbAccess_DeletePolicy_Call{Call: _e.mock.On("DeletePolicy", scope, subject)}
+}
+
+func (_c *DbAccess_DeletePolicy_Call) Run(run func(scope db_access.PolicyScope, subject string)) *DbAccess_DeletePolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.PolicyScope), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_DeletePolicy_Call) Return(_a0 error) *DbAccess_DeletePolicy_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_DeletePolicy_Call) RunAndReturn(run func(db_access.PolicyScope, string) error) *DbAccess_DeletePolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EraseUser provides a mock function with given fields: userId
+func (_m *DbAccess) EraseUser(userId int64) error {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EraseUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_EraseUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EraseUser'
+type DbAccess_EraseUser_Call struct {
+	*mock.Call
+}
+
+// EraseUser is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) EraseUser(userId interface{}) *DbAccess_EraseUser_Call {
+	return &DbAccess_EraseUser_Call{Call: _e.mock.On("EraseUser", userId)}
+}
+
+func (_c *DbAccess_EraseUser_Call) Run(run func(userId int64)) *DbAccess_EraseUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_EraseUser_Call) Return(_a0 error) *DbAccess_EraseUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_EraseUser_Call) RunAndReturn(run func(int64) error) *DbAccess_EraseUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FailUnfinishedExports provides a mock function with given fields: reason, expiresAt
+func (_m *DbAccess) FailUnfinishedExports(reason string, expiresAt db_access.Time) error {
+	ret := _m.Called(reason, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailUnfinishedExports")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, db_access.Time) error); ok {
+		r0 = rf(reason, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_FailUnfinishedExports_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FailUnfinishedExports'
+type DbAccess_FailUnfinishedExports_Call struct {
+	*mock.Call
+}
+
+// FailUnfinishedExports is a helper method to define mock.On call
+//   - reason string
+//   - expiresAt db_access.Time
+func (_e *DbAccess_Expecter) FailUnfinishedExports(reason interface{}, expiresAt interface{}) *DbAccess_FailUnfinishedExports_Call {
+	return &DbAccess_FailUnfinishedExports_Call{Call: _e.mock.On("FailUnfinishedExports", reason, expiresAt)}
+}
+
+func (_c *DbAccess_FailUnfinishedExports_Call) Run(run func(reason string, expiresAt db_access.Time)) *DbAccess_FailUnfinishedExports_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedExports_Call) Return(_a0 error) *DbAccess_FailUnfinishedExports_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedExports_Call) RunAndReturn(run func(string, db_access.Time) error) *DbAccess_FailUnfinishedExports_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FailUnfinishedImports provides a mock function with given fields: reason
+func (_m *DbAccess) FailUnfinishedImports(reason string) error {
+	ret := _m.Called(reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailUnfinishedImports")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_FailUnfinishedImports_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FailUnfinishedImports'
+type DbAccess_FailUnfinishedImports_Call struct {
+	*mock.Call
+}
+
+// FailUnfinishedImports is a helper method to define mock.On call
+//   - reason string
+func (_e *DbAccess_Expecter) FailUnfinishedImports(reason interface{}) *DbAccess_FailUnfinishedImports_Call {
+	return &DbAccess_FailUnfinishedImports_Call{Call: _e.mock.On("FailUnfinishedImports", reason)}
+}
+
+func (_c *DbAccess_FailUnfinishedImports_Call) Run(run func(reason string)) *DbAccess_FailUnfinishedImports_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedImports_Call) Return(_a0 error) *DbAccess_FailUnfinishedImports_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedImports_Call) RunAndReturn(run func(string) error) *DbAccess_FailUnfinishedImports_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FailUnfinishedJobs provides a mock function with given fields: reason
+func (_m *DbAccess) FailUnfinishedJobs(reason string) error {
+	ret := _m.Called(reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailUnfinishedJobs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_FailUnfinishedJobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FailUnfinishedJobs'
+type DbAccess_FailUnfinishedJobs_Call struct {
+	*mock.Call
+}
+
+// FailUnfinishedJobs is a helper method to define mock.On call
+//   - reason string
+func (_e *DbAccess_Expecter) FailUnfinishedJobs(reason interface{}) *DbAccess_FailUnfinishedJobs_Call {
+	return &DbAccess_FailUnfinishedJobs_Call{Call: _e.mock.On("FailUnfinishedJobs", reason)}
+}
+
+func (_c *DbAccess_FailUnfinishedJobs_Call) Run(run func(reason string)) *DbAccess_FailUnfinishedJobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedJobs_Call) Return(_a0 error) *DbAccess_FailUnfinishedJobs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedJobs_Call) RunAndReturn(run func(string) error) *DbAccess_FailUnfinishedJobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FailUnfinishedMigrations provides a mock function with given fields: reason
+func (_m *DbAccess) FailUnfinishedMigrations(reason string) error {
+	ret := _m.Called(reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailUnfinishedMigrations")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_FailUnfinishedMigrations_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FailUnfinishedMigrations'
+type DbAccess_FailUnfinishedMigrations_Call struct {
+	*mock.Call
+}
+
+// FailUnfinishedMigrations is a helper method to define mock.On call
+//   - reason string
+func (_e *DbAccess_Expecter) FailUnfinishedMigrations(reason interface{}) *DbAccess_FailUnfinishedMigrations_Call {
+	return &DbAccess_FailUnfinishedMigrations_Call{Call: _e.mock.On("FailUnfinishedMigrations", reason)}
+}
+
+func (_c *DbAccess_FailUnfinishedMigrations_Call) Run(run func(reason string)) *DbAccess_FailUnfinishedMigrations_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedMigrations_Call) Return(_a0 error) *DbAccess_FailUnfinishedMigrations_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_FailUnfinishedMigrations_Call) RunAndReturn(run func(string) error) *DbAccess_FailUnfinishedMigrations_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAlertThresholds provides a mock function with given fields: userId
+func (_m *DbAccess) GetAlertThresholds(userId int64) (db_access.AlertThresholds, error) {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAlertThresholds")
+	}
+
+	var r0 db_access.AlertThresholds
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (db_access.AlertThresholds, error)); ok {
+		return rf(userId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) db_access.AlertThresholds); ok {
+		r0 = rf(userId)
+	} else {
+		r0 = ret.Get(0).(db_access.AlertThresholds)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetAlertThresholds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAlertThresholds'
+type DbAccess_GetAlertThresholds_Call struct {
+	*mock.Call
+}
+
+// GetAlertThresholds is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) GetAlertThresholds(userId interface{}) *DbAccess_GetAlertThresholds_Call {
+	return &DbAccess_GetAlertThresholds_Call{Call: _e.mock.On("GetAlertThresholds", userId)}
+}
+
+func (_c *DbAccess_GetAlertThresholds_Call) Run(run func(userId int64)) *DbAccess_GetAlertThresholds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetAlertThresholds_Call) Return(_a0 db_access.AlertThresholds, _a1 error) *DbAccess_GetAlertThresholds_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetAlertThresholds_Call) RunAndReturn(run func(int64) (db_access.AlertThresholds, error)) *DbAccess_GetAlertThresholds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAuditEvents provides a mock function with given fields: from, to
+func (_m *DbAccess) GetAuditEvents(from db_access.Time, to db_access.Time) ([]db_access.AuditEvent, error) {
+	ret := _m.Called(from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuditEvents")
+	}
+
+	var r0 []db_access.AuditEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.Time, db_access.Time) ([]db_access.AuditEvent, error)); ok {
+		return rf(from, to)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.Time, db_access.Time) []db_access.AuditEvent); ok {
+		r0 = rf(from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.AuditEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.Time, db_access.Time) error); ok {
+		r1 = rf(from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetAuditEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAuditEvents'
+type DbAccess_GetAuditEvents_Call struct {
+	*mock.Call
+}
+
+// GetAuditEvents is a helper method to define mock.On call
+//   - from db_access.Time
+//   - to db_access.Time
+func (_e *DbAccess_Expecter) GetAuditEvents(from interface{}, to interface{}) *DbAccess_GetAuditEvents_Call {
+	return &DbAccess_GetAuditEvents_Call{Call: _e.mock.On("GetAuditEvents", from, to)}
+}
+
+func (_c *DbAccess_GetAuditEvents_Call) Run(run func(from db_access.Time, to db_access.Time)) *DbAccess_GetAuditEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time), args[1].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetAuditEvents_Call) Return(_a0 []db_access.AuditEvent, _a1 error) *DbAccess_GetAuditEvents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetAuditEvents_Call) RunAndReturn(run func(db_access.Time, db_access.Time) ([]db_access.AuditEvent, error)) *DbAccess_GetAuditEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetBlobs provides a mock function with given fields: backend, filter
+func (_m *DbAccess) GetBlobs(backend string, filter db_access.BlobFilter) ([]db_access.Blob, error) {
+	ret := _m.Called(backend, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBlobs")
+	}
+
+	var r0 []db_access.Blob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, db_access.BlobFilter) ([]db_access.Blob, error)); ok {
+		return rf(backend, filter)
+	}
+	if rf, ok := ret.Get(0).(func(string, db_access.BlobFilter) []db_access.Blob); ok {
+		r0 = rf(backend, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Blob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, db_access.BlobFilter) error); ok {
+		r1 = rf(backend, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetBlobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetBlobs'
+type DbAccess_GetBlobs_Call struct {
+	*mock.Call
+}
+
+// GetBlobs is a helper method to define mock.On call
+//   - backend string
+//   - filter db_access.BlobFilter
+func (_e *DbAccess_Expecter) GetBlobs(backend interface{}, filter interface{}) *DbAccess_GetBlobs_Call {
+	return &DbAccess_GetBlobs_Call{Call: _e.mock.On("GetBlobs", backend, filter)}
+}
+
+func (_c *DbAccess_GetBlobs_Call) Run(run func(backend string, filter db_access.BlobFilter)) *DbAccess_GetBlobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(db_access.BlobFilter))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetBlobs_Call) Return(_a0 []db_access.Blob, _a1 error) *DbAccess_GetBlobs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetBlobs_Call) RunAndReturn(run func(string, db_access.BlobFilter) ([]db_access.Blob, error)) *DbAccess_GetBlobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetChangeCursor provides a mock function with given fields: consumer
+func (_m *DbAccess) GetChangeCursor(consumer string) (int64, error) {
+	ret := _m.Called(consumer)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChangeCursor")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int64, error)); ok {
+		return rf(consumer)
+	}
+	if rf, ok := ret.Get(0).(func(string) int64); ok {
+		r0 = rf(consumer)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(consumer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetChangeCursor_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetChangeCursor'
+type DbAccess_GetChangeCursor_Call struct {
+	*mock.Call
+}
+
+// GetChangeCursor is a helper method to define mock.On call
+//   - consumer string
+func (_e *DbAccess_Expecter) GetChangeCursor(consumer interface{}) *DbAccess_GetChangeCursor_Call {
+	return &DbAccess_GetChangeCursor_Call{Call: _e.mock.On("GetChangeCursor", consumer)}
+}
+
+func (_c *DbAccess_GetChangeCursor_Call) Run(run func(consumer string)) *DbAccess_GetChangeCursor_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetChangeCursor_Call) Return(_a0 int64, _a1 error) *DbAccess_GetChangeCursor_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetChangeCursor_Call) RunAndReturn(run func(string) (int64, error)) *DbAccess_GetChangeCursor_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetChanges provides a mock function with given fields: after, limit
+func (_m *DbAccess) GetChanges(after int64, limit int) ([]db_access.Change, error) {
+	ret := _m.Called(after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChanges")
+	}
+
+	var r0 []db_access.Change
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, int) ([]db_access.Change, error)); ok {
+		return rf(after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(int64, int) []db_access.Change); ok {
+		r0 = rf(after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Change)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, int) error); ok {
+		r1 = rf(after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetChanges_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetChanges'
+type DbAccess_GetChanges_Call struct {
+	*mock.Call
+}
+
+// GetChanges is a helper method to define mock.On call
+//   - after int64
+//   - limit int
+func (_e *DbAccess_Expecter) GetChanges(after interface{}, limit interface{}) *DbAccess_GetChanges_Call {
+	return &DbAccess_GetChanges_Call{Call: _e.mock.On("GetChanges", after, limit)}
+}
+
+func (_c *DbAccess_GetChanges_Call) Run(run func(after int64, limit int)) *DbAccess_GetChanges_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetChanges_Call) Return(_a0 []db_access.Change, _a1 error) *DbAccess_GetChanges_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetChanges_Call) RunAndReturn(run func(int64, int) ([]db_access.Change, error)) *DbAccess_GetChanges_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetCollectionVersion provides a mock function with given fields: ownerId
+func (_m *DbAccess) GetCollectionVersion(ownerId int64) (int64, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCollectionVersion")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (int64, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) int64); ok {
+		r0 = rf(ownerId)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetCollectionVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCollectionVersion'
+type DbAccess_GetCollectionVersion_Call struct {
+	*mock.Call
+}
+
+// GetCollectionVersion is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) GetCollectionVersion(ownerId interface{}) *DbAccess_GetCollectionVersion_Call {
+	return &DbAccess_GetCollectionVersion_Call{Call: _e.mock.On("GetCollectionVersion", ownerId)}
+}
+
+func (_c *DbAccess_GetCollectionVersion_Call) Run(run func(ownerId int64)) *DbAccess_GetCollectionVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetCollectionVersion_Call) Return(_a0 int64, _a1 error) *DbAccess_GetCollectionVersion_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetCollectionVersion_Call) RunAndReturn(run func(int64) (int64, error)) *DbAccess_GetCollectionVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetComment provides a mock function with given fields: id
+func (_m *DbAccess) GetComment(id int64) (db_access.Comment, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetComment")
+	}
+
+	var r0 db_access.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (db_access.Comment, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(int64) db_access.Comment); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(db_access.Comment)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetComment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetComment'
+type DbAccess_GetComment_Call struct {
+	*mock.Call
+}
+
+// GetComment is a helper method to define mock.On call
+//   - id int64
+func (_e *DbAccess_Expecter) GetComment(id interface{}) *DbAccess_GetComment_Call {
+	return &DbAccess_GetComment_Call{Call: _e.mock.On("GetComment", id)}
+}
+
+func (_c *DbAccess_GetComment_Call) Run(run func(id int64)) *DbAccess_GetComment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetComment_Call) Return(_a0 db_access.Comment, _a1 error) *DbAccess_GetComment_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetComment_Call) RunAndReturn(run func(int64) (db_access.Comment, error)) *DbAccess_GetComment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetComments provides a mock function with given fields: generatedName
+func (_m *DbAccess) GetComments(generatedName string) ([]db_access.Comment, error) {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetComments")
+	}
+
+	var r0 []db_access.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]db_access.Comment, error)); ok {
+		return rf(generatedName)
+	}
+	if rf, ok := ret.Get(0).(func(string) []db_access.Comment); ok {
+		r0 = rf(generatedName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(generatedName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetComments_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetComments'
+type DbAccess_GetComments_Call struct {
+	*mock.Call
+}
+
+// GetComments is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) GetComments(generatedName interface{}) *DbAccess_GetComments_Call {
+	return &DbAccess_GetComments_Call{Call: _e.mock.On("GetComments", generatedName)}
+}
+
+func (_c *DbAccess_GetComments_Call) Run(run func(generatedName string)) *DbAccess_GetComments_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetComments_Call) Return(_a0 []db_access.Comment, _a1 error) *DbAccess_GetComments_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetComments_Call) RunAndReturn(run func(string) ([]db_access.Comment, error)) *DbAccess_GetComments_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetConsents provides a mock function with given fields: userId
+func (_m *DbAccess) GetConsents(userId int64) ([]db_access.Consent, error) {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetConsents")
+	}
+
+	var r0 []db_access.Consent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.Consent, error)); ok {
+		return rf(userId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.Consent); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Consent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetConsents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetConsents'
+type DbAccess_GetConsents_Call struct {
+	*mock.Call
+}
+
+// GetConsents is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) GetConsents(userId interface{}) *DbAccess_GetConsents_Call {
+	return &DbAccess_GetConsents_Call{Call: _e.mock.On("GetConsents", userId)}
+}
+
+func (_c *DbAccess_GetConsents_Call) Run(run func(userId int64)) *DbAccess_GetConsents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetConsents_Call) Return(_a0 []db_access.Consent, _a1 error) *DbAccess_GetConsents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetConsents_Call) RunAndReturn(run func(int64) ([]db_access.Consent, error)) *DbAccess_GetConsents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetContentKey provides a mock function with given fields: contentId
+func (_m *DbAccess) GetContentKey(contentId string) (string, error) {
+	ret := _m.Called(contentId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetContentKey")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(contentId)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(contentId)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(contentId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetContentKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetContentKey'
+type DbAccess_GetContentKey_Call struct {
+	*mock.Call
+}
+
+// GetContentKey is a helper method to define mock.On call
+//   - contentId string
+func (_e *DbAccess_Expecter) GetContentKey(contentId interface{}) *DbAccess_GetContentKey_Call {
+	return &DbAccess_GetContentKey_Call{Call: _e.mock.On("GetContentKey", contentId)}
+}
+
+func (_c *DbAccess_GetContentKey_Call) Run(run func(contentId string)) *DbAccess_GetContentKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetContentKey_Call) Return(_a0 string, _a1 error) *DbAccess_GetContentKey_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetContentKey_Call) RunAndReturn(run func(string) (string, error)) *DbAccess_GetContentKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetConvergentBlob provides a mock function with given fields: contentId
+func (_m *DbAccess) GetConvergentBlob(contentId string) (db_access.ConvergentBlob, error) {
+	ret := _m.Called(contentId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetConvergentBlob")
+	}
+
+	var r0 db_access.ConvergentBlob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.ConvergentBlob, error)); ok {
+		return rf(contentId)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.ConvergentBlob); ok {
+		r0 = rf(contentId)
+	} else {
+		r0 = ret.Get(0).(db_access.ConvergentBlob)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(contentId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetConvergentBlob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetConvergentBlob'
+type DbAccess_GetConvergentBlob_Call struct {
+	*mock.Call
+}
+
+// GetConvergentBlob is a helper method to define mock.On call
+//   - contentId string
+func (_e *DbAccess_Expecter) GetConvergentBlob(contentId interface{}) *DbAccess_GetConvergentBlob_Call {
+	return &DbAccess_GetConvergentBlob_Call{Call: _e.mock.On("GetConvergentBlob", contentId)}
+}
+
+func (_c *DbAccess_GetConvergentBlob_Call) Run(run func(contentId string)) *DbAccess_GetConvergentBlob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetConvergentBlob_Call) Return(_a0 db_access.ConvergentBlob, _a1 error) *DbAccess_GetConvergentBlob_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetConvergentBlob_Call) RunAndReturn(run func(string) (db_access.ConvergentBlob, error)) *DbAccess_GetConvergentBlob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDEC provides a mock function with given fields: id
+func (_m *DbAccess) GetDEC(id db_access.DecId) (db_access.DEC, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDEC")
+	}
+
+	var r0 db_access.DEC
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.DecId) (db_access.DEC, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.DecId) db_access.DEC); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(db_access.DEC)
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.DecId) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDEC'
+type DbAccess_GetDEC_Call struct {
+	*mock.Call
+}
+
+// GetDEC is a helper method to define mock.On call
+//   - id db_access.DecId
+func (_e *DbAccess_Expecter) GetDEC(id interface{}) *DbAccess_GetDEC_Call {
+	return &DbAccess_GetDEC_Call{Call: _e.mock.On("GetDEC", id)}
+}
+
+func (_c *DbAccess_GetDEC_Call) Run(run func(id db_access.DecId)) *DbAccess_GetDEC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.DecId))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetDEC_Call) Return(_a0 db_access.DEC, _a1 error) *DbAccess_GetDEC_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetDEC_Call) RunAndReturn(run func(db_access.DecId) (db_access.DEC, error)) *DbAccess_GetDEC_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDECs provides a mock function with no fields
+func (_m *DbAccess) GetDECs() ([]db_access.DEC, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDECs")
+	}
+
+	var r0 []db_access.DEC
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]db_access.DEC, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []db_access.DEC); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.DEC)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetDECs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDECs'
+type DbAccess_GetDECs_Call struct {
+	*mock.Call
+}
+
+// GetDECs is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) GetDECs() *DbAccess_GetDECs_Call {
+	return &DbAccess_GetDECs_Call{Call: _e.mock.On("GetDECs")}
+}
+
+func (_c *DbAccess_GetDECs_Call) Run(run func()) *DbAccess_GetDECs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetDECs_Call) Return(_a0 []db_access.DEC, _a1 error) *DbAccess_GetDECs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetDECs_Call) RunAndReturn(run func() ([]db_access.DEC, error)) *DbAccess_GetDECs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDeletionRequest provides a mock function with given fields: userId
+func (_m *DbAccess) GetDeletionRequest(userId int64) (db_access.DeletionRequest, error) {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeletionRequest")
+	}
+
+	var r0 db_access.DeletionRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (db_access.DeletionRequest, error)); ok {
+		return rf(userId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) db_access.DeletionRequest); ok {
+		r0 = rf(userId)
+	} else {
+		r0 = ret.Get(0).(db_access.DeletionRequest)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetDeletionRequest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDeletionRequest'
+type DbAccess_GetDeletionRequest_Call struct {
+	*mock.Call
+}
+
+// GetDeletionRequest is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) GetDeletionRequest(userId interface{}) *DbAccess_GetDeletionRequest_Call {
+	return &DbAccess_GetDeletionRequest_Call{Call: _e.mock.On("GetDeletionRequest", userId)}
+}
+
+func (_c *DbAccess_GetDeletionRequest_Call) Run(run func(userId int64)) *DbAccess_GetDeletionRequest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetDeletionRequest_Call) Return(_a0 db_access.DeletionRequest, _a1 error) *DbAccess_GetDeletionRequest_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetDeletionRequest_Call) RunAndReturn(run func(int64) (db_access.DeletionRequest, error)) *DbAccess_GetDeletionRequest_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDevice provides a mock function with given fields: id
+func (_m *DbAccess) GetDevice(id string) (db_access.Device, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDevice")
+	}
+
+	var r0 db_access.Device
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.Device, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.Device); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(db_access.Device)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetDevice_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDevice'
+type DbAccess_GetDevice_Call struct {
+	*mock.Call
+}
+
+// GetDevice is a helper method to define mock.On call
+//   - id string
+func (_e *DbAccess_Expecter) GetDevice(id interface{}) *DbAccess_GetDevice_Call {
+	return &DbAccess_GetDevice_Call{Call: _e.mock.On("GetDevice", id)}
+}
+
+func (_c *DbAccess_GetDevice_Call) Run(run func(id string)) *DbAccess_GetDevice_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetDevice_Call) Return(_a0 db_access.Device, _a1 error) *DbAccess_GetDevice_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetDevice_Call) RunAndReturn(run func(string) (db_access.Device, error)) *DbAccess_GetDevice_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDeviceByRefreshHash provides a mock function with given fields: hash
+func (_m *DbAccess) GetDeviceByRefreshHash(hash []byte) (db_access.Device, error) {
+	ret := _m.Called(hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeviceByRefreshHash")
+	}
+
+	var r0 db_access.Device
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]byte) (db_access.Device, error)); ok {
+		return rf(hash)
+	}
+	if rf, ok := ret.Get(0).(func([]byte) db_access.Device); ok {
+		r0 = rf(hash)
+	} else {
+		r0 = ret.Get(0).(db_access.Device)
+	}
+
+	if rf, ok := ret.Get(1).(func([]byte) error); ok {
+		r1 = rf(hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetDeviceByRefreshHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDeviceByRefreshHash'
+type DbAccess_GetDeviceByRefreshHash_Call struct {
+	*mock.Call
+}
+
+// GetDeviceByRefreshHash is a helper method to define mock.On call
+//   - hash []byte
+func (_e *DbAccess_Expecter) GetDeviceByRefreshHash(hash interface{}) *DbAccess_GetDeviceByRefreshHash_Call {
+	return &DbAccess_GetDeviceByRefreshHash_Call{Call: _e.mock.On("GetDeviceByRefreshHash", hash)}
+}
+
+func (_c *DbAccess_GetDeviceByRefreshHash_Call) Run(run func(hash []byte)) *DbAccess_GetDeviceByRefreshHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]byte))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetDeviceByRefreshHash_Call) Return(_a0 db_access.Device, _a1 error) *DbAccess_GetDeviceByRefreshHash_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetDeviceByRefreshHash_Call) RunAndReturn(run func([]byte) (db_access.Device, error)) *DbAccess_GetDeviceByRefreshHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDevices provides a mock function with given fields: ownerId
+func (_m *DbAccess) GetDevices(ownerId int64) ([]db_access.Device, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDevices")
+	}
+
+	var r0 []db_access.Device
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.Device, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.Device); ok {
+		r0 = rf(ownerId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Device)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetDevices_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDevices'
+type DbAccess_GetDevices_Call struct {
+	*mock.Call
+}
+
+// GetDevices is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) GetDevices(ownerId interface{}) *DbAccess_GetDevices_Call {
+	return &DbAccess_GetDevices_Call{Call: _e.mock.On("GetDevices", ownerId)}
+}
+
+func (_c *DbAccess_GetDevices_Call) Run(run func(ownerId int64)) *DbAccess_GetDevices_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetDevices_Call) Return(_a0 []db_access.Device, _a1 error) *DbAccess_GetDevices_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetDevices_Call) RunAndReturn(run func(int64) ([]db_access.Device, error)) *DbAccess_GetDevices_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetExpiredExports provides a mock function with given fields: now
+func (_m *DbAccess) GetExpiredExports(now db_access.Time) ([]db_access.Export, error) {
+	ret := _m.Called(now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetExpiredExports")
+	}
+
+	var r0 []db_access.Export
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.Time) ([]db_access.Export, error)); ok {
+		return rf(now)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.Time) []db_access.Export); ok {
+		r0 = rf(now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Export)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.Time) error); ok {
+		r1 = rf(now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetExpiredExports_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetExpiredExports'
+type DbAccess_GetExpiredExports_Call struct {
+	*mock.Call
+}
+
+// GetExpiredExports is a helper method to define mock.On call
+//   - now db_access.Time
+func (_e *DbAccess_Expecter) GetExpiredExports(now interface{}) *DbAccess_GetExpiredExports_Call {
+	return &DbAccess_GetExpiredExports_Call{Call: _e.mock.On("GetExpiredExports", now)}
+}
+
+func (_c *DbAccess_GetExpiredExports_Call) Run(run func(now db_access.Time)) *DbAccess_GetExpiredExports_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetExpiredExports_Call) Return(_a0 []db_access.Export, _a1 error) *DbAccess_GetExpiredExports_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetExpiredExports_Call) RunAndReturn(run func(db_access.Time) ([]db_access.Export, error)) *DbAccess_GetExpiredExports_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetExpiredFiles provides a mock function with given fields: now
+func (_m *DbAccess) GetExpiredFiles(now db_access.Time) ([]db_access.File, error) {
+	ret := _m.Called(now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetExpiredFiles")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.Time) ([]db_access.File, error)); ok {
+		return rf(now)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.Time) []db_access.File); ok {
+		r0 = rf(now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.Time) error); ok {
+		r1 = rf(now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetExpiredFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetExpiredFiles'
+type DbAccess_GetExpiredFiles_Call struct {
+	*mock.Call
+}
+
+// GetExpiredFiles is a helper method to define mock.On call
+//   - now db_access.Time
+func (_e *DbAccess_Expecter) GetExpiredFiles(now interface{}) *DbAccess_GetExpiredFiles_Call {
+	return &DbAccess_GetExpiredFiles_Call{Call: _e.mock.On("GetExpiredFiles", now)}
+}
+
+func (_c *DbAccess_GetExpiredFiles_Call) Run(run func(now db_access.Time)) *DbAccess_GetExpiredFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetExpiredFiles_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetExpiredFiles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetExpiredFiles_Call) RunAndReturn(run func(db_access.Time) ([]db_access.File, error)) *DbAccess_GetExpiredFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetExport provides a mock function with given fields: id
+func (_m *DbAccess) GetExport(id string) (db_access.Export, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetExport")
+	}
+
+	var r0 db_access.Export
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.Export, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.Export); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(db_access.Export)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetExport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetExport'
+type DbAccess_GetExport_Call struct {
+	*mock.Call
+}
+
+// GetExport is a helper method to define mock.On call
+//   - id string
+func (_e *DbAccess_Expecter) GetExport(id interface{}) *DbAccess_GetExport_Call {
+	return &DbAccess_GetExport_Call{Call: _e.mock.On("GetExport", id)}
+}
+
+func (_c *DbAccess_GetExport_Call) Run(run func(id string)) *DbAccess_GetExport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetExport_Call) Return(_a0 db_access.Export, _a1 error) *DbAccess_GetExport_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetExport_Call) RunAndReturn(run func(string) (db_access.Export, error)) *DbAccess_GetExport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFile provides a mock function with given fields: generatedName
+func (_m *DbAccess) GetFile(generatedName string) (db_access.File, error) {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFile")
+	}
+
+	var r0 db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.File, error)); ok {
+		return rf(generatedName)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.File); ok {
+		r0 = rf(generatedName)
+	} else {
+		r0 = ret.Get(0).(db_access.File)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(generatedName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFile'
+type DbAccess_GetFile_Call struct {
+	*mock.Call
+}
+
+// GetFile is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) GetFile(generatedName interface{}) *DbAccess_GetFile_Call {
+	return &DbAccess_GetFile_Call{Call: _e.mock.On("GetFile", generatedName)}
+}
+
+func (_c *DbAccess_GetFile_Call) Run(run func(generatedName string)) *DbAccess_GetFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFile_Call) Return(_a0 db_access.File, _a1 error) *DbAccess_GetFile_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFile_Call) RunAndReturn(run func(string) (db_access.File, error)) *DbAccess_GetFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFileLock provides a mock function with given fields: generatedName
+func (_m *DbAccess) GetFileLock(generatedName string) (db_access.FileLock, error) {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFileLock")
+	}
+
+	var r0 db_access.FileLock
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.FileLock, error)); ok {
+		return rf(generatedName)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.FileLock); ok {
+		r0 = rf(generatedName)
+	} else {
+		r0 = ret.Get(0).(db_access.FileLock)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(generatedName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFileLock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileLock'
+type DbAccess_GetFileLock_Call struct {
+	*mock.Call
+}
+
+// GetFileLock is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) GetFileLock(generatedName interface{}) *DbAccess_GetFileLock_Call {
+	return &DbAccess_GetFileLock_Call{Call: _e.mock.On("GetFileLock", generatedName)}
+}
+
+func (_c *DbAccess_GetFileLock_Call) Run(run func(generatedName string)) *DbAccess_GetFileLock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFileLock_Call) Return(_a0 db_access.FileLock, _a1 error) *DbAccess_GetFileLock_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFileLock_Call) RunAndReturn(run func(string) (db_access.FileLock, error)) *DbAccess_GetFileLock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFileMetadata provides a mock function with given fields: generatedName
+func (_m *DbAccess) GetFileMetadata(generatedName string) (string, error) {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFileMetadata")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(generatedName)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(generatedName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(generatedName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFileMetadata_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileMetadata'
+type DbAccess_GetFileMetadata_Call struct {
+	*mock.Call
+}
+
+// GetFileMetadata is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) GetFileMetadata(generatedName interface{}) *DbAccess_GetFileMetadata_Call {
+	return &DbAccess_GetFileMetadata_Call{Call: _e.mock.On("GetFileMetadata", generatedName)}
+}
+
+func (_c *DbAccess_GetFileMetadata_Call) Run(run func(generatedName string)) *DbAccess_GetFileMetadata_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFileMetadata_Call) Return(_a0 string, _a1 error) *DbAccess_GetFileMetadata_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFileMetadata_Call) RunAndReturn(run func(string) (string, error)) *DbAccess_GetFileMetadata_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFileMetadataTerms provides a mock function with given fields: generatedName
+func (_m *DbAccess) GetFileMetadataTerms(generatedName string) ([]string, error) {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFileMetadataTerms")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
+		return rf(generatedName)
+	}
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(generatedName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(generatedName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFileMetadataTerms_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileMetadataTerms'
+type DbAccess_GetFileMetadataTerms_Call struct {
+	*mock.Call
+}
+
+// GetFileMetadataTerms is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) GetFileMetadataTerms(generatedName interface{}) *DbAccess_GetFileMetadataTerms_Call {
+	return &DbAccess_GetFileMetadataTerms_Call{Call: _e.mock.On("GetFileMetadataTerms", generatedName)}
+}
+
+func (_c *DbAccess_GetFileMetadataTerms_Call) Run(run func(generatedName string)) *DbAccess_GetFileMetadataTerms_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFileMetadataTerms_Call) Return(_a0 []string, _a1 error) *DbAccess_GetFileMetadataTerms_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFileMetadataTerms_Call) RunAndReturn(run func(string) ([]string, error)) *DbAccess_GetFileMetadataTerms_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFileTags provides a mock function with given fields: generatedName
+func (_m *DbAccess) GetFileTags(generatedName string) ([]string, error) {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFileTags")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
+		return rf(generatedName)
+	}
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(generatedName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(generatedName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFileTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileTags'
+type DbAccess_GetFileTags_Call struct {
+	*mock.Call
+}
+
+// GetFileTags is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) GetFileTags(generatedName interface{}) *DbAccess_GetFileTags_Call {
+	return &DbAccess_GetFileTags_Call{Call: _e.mock.On("GetFileTags", generatedName)}
+}
+
+func (_c *DbAccess_GetFileTags_Call) Run(run func(generatedName string)) *DbAccess_GetFileTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFileTags_Call) Return(_a0 []string, _a1 error) *DbAccess_GetFileTags_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFileTags_Call) RunAndReturn(run func(string) ([]string, error)) *DbAccess_GetFileTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFiles provides a mock function with given fields: after, limit
+func (_m *DbAccess) GetFiles(after string, limit int) ([]db_access.File, error) {
+	ret := _m.Called(after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFiles")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) ([]db_access.File, error)); ok {
+		return rf(after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) []db_access.File); ok {
+		r0 = rf(after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFiles'
+type DbAccess_GetFiles_Call struct {
+	*mock.Call
+}
+
+// GetFiles is a helper method to define mock.On call
+//   - after string
+//   - limit int
+func (_e *DbAccess_Expecter) GetFiles(after interface{}, limit interface{}) *DbAccess_GetFiles_Call {
+	return &DbAccess_GetFiles_Call{Call: _e.mock.On("GetFiles", after, limit)}
+}
+
+func (_c *DbAccess_GetFiles_Call) Run(run func(after string, limit int)) *DbAccess_GetFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFiles_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetFiles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFiles_Call) RunAndReturn(run func(string, int) ([]db_access.File, error)) *DbAccess_GetFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFilesByMetadata provides a mock function with given fields: ownerId, terms
+func (_m *DbAccess) GetFilesByMetadata(ownerId int64, terms []string) ([]db_access.File, error) {
+	ret := _m.Called(ownerId, terms)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFilesByMetadata")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, []string) ([]db_access.File, error)); ok {
+		return rf(ownerId, terms)
+	}
+	if rf, ok := ret.Get(0).(func(int64, []string) []db_access.File); ok {
+		r0 = rf(ownerId, terms)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, []string) error); ok {
+		r1 = rf(ownerId, terms)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFilesByMetadata_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFilesByMetadata'
+type DbAccess_GetFilesByMetadata_Call struct {
+	*mock.Call
+}
+
+// GetFilesByMetadata is a helper method to define mock.On call
+//   - ownerId int64
+//   - terms []string
+func (_e *DbAccess_Expecter) GetFilesByMetadata(ownerId interface{}, terms interface{}) *DbAccess_GetFilesByMetadata_Call {
+	return &DbAccess_GetFilesByMetadata_Call{Call: _e.mock.On("GetFilesByMetadata", ownerId, terms)}
+}
+
+func (_c *DbAccess_GetFilesByMetadata_Call) Run(run func(ownerId int64, terms []string)) *DbAccess_GetFilesByMetadata_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFilesByMetadata_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetFilesByMetadata_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFilesByMetadata_Call) RunAndReturn(run func(int64, []string) ([]db_access.File, error)) *DbAccess_GetFilesByMetadata_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFilesByNameIndex provides a mock function with given fields: ownerId, index
+func (_m *DbAccess) GetFilesByNameIndex(ownerId int64, index string) ([]db_access.File, error) {
+	ret := _m.Called(ownerId, index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFilesByNameIndex")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string) ([]db_access.File, error)); ok {
+		return rf(ownerId, index)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string) []db_access.File); ok {
+		r0 = rf(ownerId, index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = rf(ownerId, index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFilesByNameIndex_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFilesByNameIndex'
+type DbAccess_GetFilesByNameIndex_Call struct {
+	*mock.Call
+}
+
+// GetFilesByNameIndex is a helper method to define mock.On call
+//   - ownerId int64
+//   - index string
+func (_e *DbAccess_Expecter) GetFilesByNameIndex(ownerId interface{}, index interface{}) *DbAccess_GetFilesByNameIndex_Call {
+	return &DbAccess_GetFilesByNameIndex_Call{Call: _e.mock.On("GetFilesByNameIndex", ownerId, index)}
+}
+
+func (_c *DbAccess_GetFilesByNameIndex_Call) Run(run func(ownerId int64, index string)) *DbAccess_GetFilesByNameIndex_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFilesByNameIndex_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetFilesByNameIndex_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFilesByNameIndex_Call) RunAndReturn(run func(int64, string) ([]db_access.File, error)) *DbAccess_GetFilesByNameIndex_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFilesByTerms provides a mock function with given fields: ownerId, terms
+func (_m *DbAccess) GetFilesByTerms(ownerId int64, terms []string) ([]db_access.File, error) {
+	ret := _m.Called(ownerId, terms)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFilesByTerms")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, []string) ([]db_access.File, error)); ok {
+		return rf(ownerId, terms)
+	}
+	if rf, ok := ret.Get(0).(func(int64, []string) []db_access.File); ok {
+		r0 = rf(ownerId, terms)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, []string) error); ok {
+		r1 = rf(ownerId, terms)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFilesByTerms_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFilesByTerms'
+type DbAccess_GetFilesByTerms_Call struct {
+	*mock.Call
+}
+
+// GetFilesByTerms is a helper method to define mock.On call
+//   - ownerId int64
+//   - terms []string
+func (_e *DbAccess_Expecter) GetFilesByTerms(ownerId interface{}, terms interface{}) *DbAccess_GetFilesByTerms_Call {
+	return &DbAccess_GetFilesByTerms_Call{Call: _e.mock.On("GetFilesByTerms", ownerId, terms)}
+}
+
+func (_c *DbAccess_GetFilesByTerms_Call) Run(run func(ownerId int64, terms []string)) *DbAccess_GetFilesByTerms_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFilesByTerms_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetFilesByTerms_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFilesByTerms_Call) RunAndReturn(run func(int64, []string) ([]db_access.File, error)) *DbAccess_GetFilesByTerms_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFilesToNotify provides a mock function with given fields: t
+func (_m *DbAccess) GetFilesToNotify(t db_access.Time) ([]db_access.File, error) {
+	ret := _m.Called(t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFilesToNotify")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.Time) ([]db_access.File, error)); ok {
+		return rf(t)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.Time) []db_access.File); ok {
+		r0 = rf(t)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.Time) error); ok {
+		r1 = rf(t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFilesToNotify_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFilesToNotify'
+type DbAccess_GetFilesToNotify_Call struct {
+	*mock.Call
+}
+
+// GetFilesToNotify is a helper method to define mock.On call
+//   - t db_access.Time
+func (_e *DbAccess_Expecter) GetFilesToNotify(t interface{}) *DbAccess_GetFilesToNotify_Call {
+	return &DbAccess_GetFilesToNotify_Call{Call: _e.mock.On("GetFilesToNotify", t)}
+}
+
+func (_c *DbAccess_GetFilesToNotify_Call) Run(run func(t db_access.Time)) *DbAccess_GetFilesToNotify_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFilesToNotify_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetFilesToNotify_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFilesToNotify_Call) RunAndReturn(run func(db_access.Time) ([]db_access.File, error)) *DbAccess_GetFilesToNotify_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFolders provides a mock function with given fields: ownerId
+func (_m *DbAccess) GetFolders(ownerId int64) ([]db_access.Folder, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFolders")
+	}
+
+	var r0 []db_access.Folder
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.Folder, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.Folder); ok {
+		r0 = rf(ownerId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Folder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFolders_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFolders'
+type DbAccess_GetFolders_Call struct {
+	*mock.Call
+}
+
+// GetFolders is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) GetFolders(ownerId interface{}) *DbAccess_GetFolders_Call {
+	return &DbAccess_GetFolders_Call{Call: _e.mock.On("GetFolders", ownerId)}
+}
+
+func (_c *DbAccess_GetFolders_Call) Run(run func(ownerId int64)) *DbAccess_GetFolders_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFolders_Call) Return(_a0 []db_access.Folder, _a1 error) *DbAccess_GetFolders_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFolders_Call) RunAndReturn(run func(int64) ([]db_access.Folder, error)) *DbAccess_GetFolders_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFoldersByNameIndex provides a mock function with given fields: ownerId, parentId, index
+func (_m *DbAccess) GetFoldersByNameIndex(ownerId int64, parentId int64, index string) ([]db_access.Folder, error) {
+	ret := _m.Called(ownerId, parentId, index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFoldersByNameIndex")
+	}
+
+	var r0 []db_access.Folder
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, int64, string) ([]db_access.Folder, error)); ok {
+		return rf(ownerId, parentId, index)
+	}
+	if rf, ok := ret.Get(0).(func(int64, int64, string) []db_access.Folder); ok {
+		r0 = rf(ownerId, parentId, index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Folder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, int64, string) error); ok {
+		r1 = rf(ownerId, parentId, index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetFoldersByNameIndex_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFoldersByNameIndex'
+type DbAccess_GetFoldersByNameIndex_Call struct {
+	*mock.Call
+}
+
+// GetFoldersByNameIndex is a helper method to define mock.On call
+//   - ownerId int64
+//   - parentId int64
+//   - index string
+func (_e *DbAccess_Expecter) GetFoldersByNameIndex(ownerId interface{}, parentId interface{}, index interface{}) *DbAccess_GetFoldersByNameIndex_Call {
+	return &DbAccess_GetFoldersByNameIndex_Call{Call: _e.mock.On("GetFoldersByNameIndex", ownerId, parentId, index)}
+}
+
+func (_c *DbAccess_GetFoldersByNameIndex_Call) Run(run func(ownerId int64, parentId int64, index string)) *DbAccess_GetFoldersByNameIndex_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(int64), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetFoldersByNameIndex_Call) Return(_a0 []db_access.Folder, _a1 error) *DbAccess_GetFoldersByNameIndex_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetFoldersByNameIndex_Call) RunAndReturn(run func(int64, int64, string) ([]db_access.Folder, error)) *DbAccess_GetFoldersByNameIndex_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetImport provides a mock function with given fields: id
+func (_m *DbAccess) GetImport(id string) (db_access.Import, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetImport")
+	}
+
+	var r0 db_access.Import
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.Import, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.Import); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(db_access.Import)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetImport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetImport'
+type DbAccess_GetImport_Call struct {
+	*mock.Call
+}
+
+// GetImport is a helper method to define mock.On call
+//   - id string
+func (_e *DbAccess_Expecter) GetImport(id interface{}) *DbAccess_GetImport_Call {
+	return &DbAccess_GetImport_Call{Call: _e.mock.On("GetImport", id)}
+}
+
+func (_c *DbAccess_GetImport_Call) Run(run func(id string)) *DbAccess_GetImport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetImport_Call) Return(_a0 db_access.Import, _a1 error) *DbAccess_GetImport_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetImport_Call) RunAndReturn(run func(string) (db_access.Import, error)) *DbAccess_GetImport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetIndexKey provides a mock function with no fields
+func (_m *DbAccess) GetIndexKey() (string, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIndexKey")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetIndexKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetIndexKey'
+type DbAccess_GetIndexKey_Call struct {
+	*mock.Call
+}
+
+// GetIndexKey is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) GetIndexKey() *DbAccess_GetIndexKey_Call {
+	return &DbAccess_GetIndexKey_Call{Call: _e.mock.On("GetIndexKey")}
+}
+
+func (_c *DbAccess_GetIndexKey_Call) Run(run func()) *DbAccess_GetIndexKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetIndexKey_Call) Return(_a0 string, _a1 error) *DbAccess_GetIndexKey_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetIndexKey_Call) RunAndReturn(run func() (string, error)) *DbAccess_GetIndexKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetJob provides a mock function with given fields: id
+func (_m *DbAccess) GetJob(id string) (db_access.Job, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetJob")
+	}
+
+	var r0 db_access.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.Job, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.Job); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(db_access.Job)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetJob'
+type DbAccess_GetJob_Call struct {
+	*mock.Call
+}
+
+// GetJob is a helper method to define mock.On call
+//   - id string
+func (_e *DbAccess_Expecter) GetJob(id interface{}) *DbAccess_GetJob_Call {
+	return &DbAccess_GetJob_Call{Call: _e.mock.On("GetJob", id)}
+}
+
+func (_c *DbAccess_GetJob_Call) Run(run func(id string)) *DbAccess_GetJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetJob_Call) Return(_a0 db_access.Job, _a1 error) *DbAccess_GetJob_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetJob_Call) RunAndReturn(run func(string) (db_access.Job, error)) *DbAccess_GetJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetMigration provides a mock function with given fields: id
+func (_m *DbAccess) GetMigration(id string) (db_access.Migration, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMigration")
+	}
+
+	var r0 db_access.Migration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (db_access.Migration, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) db_access.Migration); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(db_access.Migration)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetMigration_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMigration'
+type DbAccess_GetMigration_Call struct {
+	*mock.Call
+}
+
+// GetMigration is a helper method to define mock.On call
+//   - id string
+func (_e *DbAccess_Expecter) GetMigration(id interface{}) *DbAccess_GetMigration_Call {
+	return &DbAccess_GetMigration_Call{Call: _e.mock.On("GetMigration", id)}
+}
+
+func (_c *DbAccess_GetMigration_Call) Run(run func(id string)) *DbAccess_GetMigration_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetMigration_Call) Return(_a0 db_access.Migration, _a1 error) *DbAccess_GetMigration_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetMigration_Call) RunAndReturn(run func(string) (db_access.Migration, error)) *DbAccess_GetMigration_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetNamesBelowKeyVersion provides a mock function with given fields: version, after, limit
+func (_m *DbAccess) GetNamesBelowKeyVersion(version int64, after string, limit int) ([]db_access.WrappedName, error) {
+	ret := _m.Called(version, after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNamesBelowKeyVersion")
+	}
+
+	var r0 []db_access.WrappedName
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string, int) ([]db_access.WrappedName, error)); ok {
+		return rf(version, after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string, int) []db_access.WrappedName); ok {
+		r0 = rf(version, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.WrappedName)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string, int) error); ok {
+		r1 = rf(version, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetNamesBelowKeyVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNamesBelowKeyVersion'
+type DbAccess_GetNamesBelowKeyVersion_Call struct {
+	*mock.Call
+}
+
+// GetNamesBelowKeyVersion is a helper method to define mock.On call
+//   - version int64
+//   - after string
+//   - limit int
+func (_e *DbAccess_Expecter) GetNamesBelowKeyVersion(version interface{}, after interface{}, limit interface{}) *DbAccess_GetNamesBelowKeyVersion_Call {
+	return &DbAccess_GetNamesBelowKeyVersion_Call{Call: _e.mock.On("GetNamesBelowKeyVersion", version, after, limit)}
+}
+
+func (_c *DbAccess_GetNamesBelowKeyVersion_Call) Run(run func(version int64, after string, limit int)) *DbAccess_GetNamesBelowKeyVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetNamesBelowKeyVersion_Call) Return(_a0 []db_access.WrappedName, _a1 error) *DbAccess_GetNamesBelowKeyVersion_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetNamesBelowKeyVersion_Call) RunAndReturn(run func(int64, string, int) ([]db_access.WrappedName, error)) *DbAccess_GetNamesBelowKeyVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetNewestDEC provides a mock function with no fields
+func (_m *DbAccess) GetNewestDEC() (db_access.DEC, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNewestDEC")
+	}
+
+	var r0 db_access.DEC
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (db_access.DEC, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() db_access.DEC); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(db_access.DEC)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetNewestDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNewestDEC'
+type DbAccess_GetNewestDEC_Call struct {
+	*mock.Call
+}
+
+// GetNewestDEC is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) GetNewestDEC() *DbAccess_GetNewestDEC_Call {
+	return &DbAccess_GetNewestDEC_Call{Call: _e.mock.On("GetNewestDEC")}
+}
+
+func (_c *DbAccess_GetNewestDEC_Call) Run(run func()) *DbAccess_GetNewestDEC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetNewestDEC_Call) Return(_a0 db_access.DEC, _a1 error) *DbAccess_GetNewestDEC_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetNewestDEC_Call) RunAndReturn(run func() (db_access.DEC, error)) *DbAccess_GetNewestDEC_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetNewestUserDEC provides a mock function with given fields: ownerId
+func (_m *DbAccess) GetNewestUserDEC(ownerId int64) (db_access.DEC, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNewestUserDEC")
+	}
+
+	var r0 db_access.DEC
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (db_access.DEC, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) db_access.DEC); ok {
+		r0 = rf(ownerId)
+	} else {
+		r0 = ret.Get(0).(db_access.DEC)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetNewestUserDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNewestUserDEC'
+type DbAccess_GetNewestUserDEC_Call struct {
+	*mock.Call
+}
+
+// GetNewestUserDEC is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) GetNewestUserDEC(ownerId interface{}) *DbAccess_GetNewestUserDEC_Call {
+	return &DbAccess_GetNewestUserDEC_Call{Call: _e.mock.On("GetNewestUserDEC", ownerId)}
+}
+
+func (_c *DbAccess_GetNewestUserDEC_Call) Run(run func(ownerId int64)) *DbAccess_GetNewestUserDEC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetNewestUserDEC_Call) Return(_a0 db_access.DEC, _a1 error) *DbAccess_GetNewestUserDEC_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetNewestUserDEC_Call) RunAndReturn(run func(int64) (db_access.DEC, error)) *DbAccess_GetNewestUserDEC_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetNotifications provides a mock function with given fields: ownerId
+func (_m *DbAccess) GetNotifications(ownerId int64) ([]db_access.Notification, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNotifications")
+	}
+
+	var r0 []db_access.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.Notification, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.Notification); ok {
+		r0 = rf(ownerId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetNotifications_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNotifications'
+type DbAccess_GetNotifications_Call struct {
+	*mock.Call
+}
+
+// GetNotifications is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) GetNotifications(ownerId interface{}) *DbAccess_GetNotifications_Call {
+	return &DbAccess_GetNotifications_Call{Call: _e.mock.On("GetNotifications", ownerId)}
+}
+
+func (_c *DbAccess_GetNotifications_Call) Run(run func(ownerId int64)) *DbAccess_GetNotifications_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetNotifications_Call) Return(_a0 []db_access.Notification, _a1 error) *DbAccess_GetNotifications_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetNotifications_Call) RunAndReturn(run func(int64) ([]db_access.Notification, error)) *DbAccess_GetNotifications_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPendingDeletionRequests provides a mock function with no fields
+func (_m *DbAccess) GetPendingDeletionRequests() ([]db_access.DeletionRequest, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPendingDeletionRequests")
+	}
+
+	var r0 []db_access.DeletionRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]db_access.DeletionRequest, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []db_access.DeletionRequest); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.DeletionRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetPendingDeletionRequests_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPendingDeletionRequests'
+type DbAccess_GetPendingDeletionRequests_Call struct {
+	*mock.Call
+}
+
+// GetPendingDeletionRequests is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) GetPendingDeletionRequests() *DbAccess_GetPendingDeletionRequests_Call {
+	return &DbAccess_GetPendingDeletionRequests_Call{Call: _e.mock.On("GetPendingDeletionRequests")}
+}
+
+func (_c *DbAccess_GetPendingDeletionRequests_Call) Run(run func()) *DbAccess_GetPendingDeletionRequests_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetPendingDeletionRequests_Call) Return(_a0 []db_access.DeletionRequest, _a1 error) *DbAccess_GetPendingDeletionRequests_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetPendingDeletionRequests_Call) RunAndReturn(run func() ([]db_access.DeletionRequest, error)) *DbAccess_GetPendingDeletionRequests_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRecentFiles provides a mock function with given fields: ownerId, limit
+func (_m *DbAccess) GetRecentFiles(ownerId int64, limit int) ([]db_access.File, error) {
+	ret := _m.Called(ownerId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRecentFiles")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, int) ([]db_access.File, error)); ok {
+		return rf(ownerId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(int64, int) []db_access.File); ok {
+		r0 = rf(ownerId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, int) error); ok {
+		r1 = rf(ownerId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetRecentFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRecentFiles'
+type DbAccess_GetRecentFiles_Call struct {
+	*mock.Call
+}
+
+// GetRecentFiles is a helper method to define mock.On call
+//   - ownerId int64
+//   - limit int
+func (_e *DbAccess_Expecter) GetRecentFiles(ownerId interface{}, limit interface{}) *DbAccess_GetRecentFiles_Call {
+	return &DbAccess_GetRecentFiles_Call{Call: _e.mock.On("GetRecentFiles", ownerId, limit)}
+}
+
+func (_c *DbAccess_GetRecentFiles_Call) Run(run func(ownerId int64, limit int)) *DbAccess_GetRecentFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetRecentFiles_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetRecentFiles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetRecentFiles_Call) RunAndReturn(run func(int64, int) ([]db_access.File, error)) *DbAccess_GetRecentFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetReplicatedBlobs provides a mock function with given fields: target
+func (_m *DbAccess) GetReplicatedBlobs(target string) ([]db_access.ReplicatedBlob, error) {
+	ret := _m.Called(target)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReplicatedBlobs")
+	}
+
+	var r0 []db_access.ReplicatedBlob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]db_access.ReplicatedBlob, error)); ok {
+		return rf(target)
+	}
+	if rf, ok := ret.Get(0).(func(string) []db_access.ReplicatedBlob); ok {
+		r0 = rf(target)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.ReplicatedBlob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(target)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetReplicatedBlobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetReplicatedBlobs'
+type DbAccess_GetReplicatedBlobs_Call struct {
+	*mock.Call
+}
+
+// GetReplicatedBlobs is a helper method to define mock.On call
+//   - target string
+func (_e *DbAccess_Expecter) GetReplicatedBlobs(target interface{}) *DbAccess_GetReplicatedBlobs_Call {
+	return &DbAccess_GetReplicatedBlobs_Call{Call: _e.mock.On("GetReplicatedBlobs", target)}
+}
+
+func (_c *DbAccess_GetReplicatedBlobs_Call) Run(run func(target string)) *DbAccess_GetReplicatedBlobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetReplicatedBlobs_Call) Return(_a0 []db_access.ReplicatedBlob, _a1 error) *DbAccess_GetReplicatedBlobs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetReplicatedBlobs_Call) RunAndReturn(run func(string) ([]db_access.ReplicatedBlob, error)) *DbAccess_GetReplicatedBlobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRequestNetworkRules provides a mock function with given fields: userId, deviceId
+func (_m *DbAccess) GetRequestNetworkRules(userId int64, deviceId string) ([]db_access.NetworkRules, error) {
+	ret := _m.Called(userId, deviceId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRequestNetworkRules")
+	}
+
+	var r0 []db_access.NetworkRules
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string) ([]db_access.NetworkRules, error)); ok {
+		return rf(userId, deviceId)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string) []db_access.NetworkRules); ok {
+		r0 = rf(userId, deviceId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.NetworkRules)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = rf(userId, deviceId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetRequestNetworkRules_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRequestNetworkRules'
+type DbAccess_GetRequestNetworkRules_Call struct {
+	*mock.Call
+}
+
+// GetRequestNetworkRules is a helper method to define mock.On call
+//   - userId int64
+//   - deviceId string
+func (_e *DbAccess_Expecter) GetRequestNetworkRules(userId interface{}, deviceId interface{}) *DbAccess_GetRequestNetworkRules_Call {
+	return &DbAccess_GetRequestNetworkRules_Call{Call: _e.mock.On("GetRequestNetworkRules", userId, deviceId)}
+}
+
+func (_c *DbAccess_GetRequestNetworkRules_Call) Run(run func(userId int64, deviceId string)) *DbAccess_GetRequestNetworkRules_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetRequestNetworkRules_Call) Return(_a0 []db_access.NetworkRules, _a1 error) *DbAccess_GetRequestNetworkRules_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetRequestNetworkRules_Call) RunAndReturn(run func(int64, string) ([]db_access.NetworkRules, error)) *DbAccess_GetRequestNetworkRules_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetStarredFiles provides a mock function with given fields: userId
+func (_m *DbAccess) GetStarredFiles(userId int64) ([]db_access.File, error) {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStarredFiles")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.File, error)); ok {
+		return rf(userId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.File); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetStarredFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetStarredFiles'
+type DbAccess_GetStarredFiles_Call struct {
+	*mock.Call
+}
+
+// GetStarredFiles is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) GetStarredFiles(userId interface{}) *DbAccess_GetStarredFiles_Call {
+	return &DbAccess_GetStarredFiles_Call{Call: _e.mock.On("GetStarredFiles", userId)}
+}
+
+func (_c *DbAccess_GetStarredFiles_Call) Run(run func(userId int64)) *DbAccess_GetStarredFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetStarredFiles_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetStarredFiles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetStarredFiles_Call) RunAndReturn(run func(int64) ([]db_access.File, error)) *DbAccess_GetStarredFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetTotalUsage provides a mock function with given fields: month
+func (_m *DbAccess) GetTotalUsage(month db_access.Time) (db_access.Usage, error) {
+	ret := _m.Called(month)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTotalUsage")
+	}
+
+	var r0 db_access.Usage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.Time) (db_access.Usage, error)); ok {
+		return rf(month)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.Time) db_access.Usage); ok {
+		r0 = rf(month)
+	} else {
+		r0 = ret.Get(0).(db_access.Usage)
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.Time) error); ok {
+		r1 = rf(month)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetTotalUsage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetTotalUsage'
+type DbAccess_GetTotalUsage_Call struct {
+	*mock.Call
+}
+
+// GetTotalUsage is a helper method to define mock.On call
+//   - month db_access.Time
+func (_e *DbAccess_Expecter) GetTotalUsage(month interface{}) *DbAccess_GetTotalUsage_Call {
+	return &DbAccess_GetTotalUsage_Call{Call: _e.mock.On("GetTotalUsage", month)}
+}
+
+func (_c *DbAccess_GetTotalUsage_Call) Run(run func(month db_access.Time)) *DbAccess_GetTotalUsage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetTotalUsage_Call) Return(_a0 db_access.Usage, _a1 error) *DbAccess_GetTotalUsage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetTotalUsage_Call) RunAndReturn(run func(db_access.Time) (db_access.Usage, error)) *DbAccess_GetTotalUsage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetTrafficByUser provides a mock function with given fields: month
+func (_m *DbAccess) GetTrafficByUser(month db_access.Time) ([]db_access.UserTraffic, error) {
+	ret := _m.Called(month)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTrafficByUser")
+	}
+
+	var r0 []db_access.UserTraffic
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.Time) ([]db_access.UserTraffic, error)); ok {
+		return rf(month)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.Time) []db_access.UserTraffic); ok {
+		r0 = rf(month)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.UserTraffic)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.Time) error); ok {
+		r1 = rf(month)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetTrafficByUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetTrafficByUser'
+type DbAccess_GetTrafficByUser_Call struct {
+	*mock.Call
+}
+
+// GetTrafficByUser is a helper method to define mock.On call
+//   - month db_access.Time
+func (_e *DbAccess_Expecter) GetTrafficByUser(month interface{}) *DbAccess_GetTrafficByUser_Call {
+	return &DbAccess_GetTrafficByUser_Call{Call: _e.mock.On("GetTrafficByUser", month)}
+}
+
+func (_c *DbAccess_GetTrafficByUser_Call) Run(run func(month db_access.Time)) *DbAccess_GetTrafficByUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetTrafficByUser_Call) Return(_a0 []db_access.UserTraffic, _a1 error) *DbAccess_GetTrafficByUser_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetTrafficByUser_Call) RunAndReturn(run func(db_access.Time) ([]db_access.UserTraffic, error)) *DbAccess_GetTrafficByUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUnindexedFiles provides a mock function with given fields: after, limit
+func (_m *DbAccess) GetUnindexedFiles(after string, limit int) ([]db_access.File, error) {
+	ret := _m.Called(after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUnindexedFiles")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) ([]db_access.File, error)); ok {
+		return rf(after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) []db_access.File); ok {
+		r0 = rf(after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetUnindexedFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUnindexedFiles'
+type DbAccess_GetUnindexedFiles_Call struct {
+	*mock.Call
+}
+
+// GetUnindexedFiles is a helper method to define mock.On call
+//   - after string
+//   - limit int
+func (_e *DbAccess_Expecter) GetUnindexedFiles(after interface{}, limit interface{}) *DbAccess_GetUnindexedFiles_Call {
+	return &DbAccess_GetUnindexedFiles_Call{Call: _e.mock.On("GetUnindexedFiles", after, limit)}
+}
+
+func (_c *DbAccess_GetUnindexedFiles_Call) Run(run func(after string, limit int)) *DbAccess_GetUnindexedFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUnindexedFiles_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetUnindexedFiles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetUnindexedFiles_Call) RunAndReturn(run func(string, int) ([]db_access.File, error)) *DbAccess_GetUnindexedFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUnreplicatedBlobs provides a mock function with given fields: target, after, limit
+func (_m *DbAccess) GetUnreplicatedBlobs(target string, after string, limit int) ([]db_access.Blob, error) {
+	ret := _m.Called(target, after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUnreplicatedBlobs")
+	}
+
+	var r0 []db_access.Blob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, int) ([]db_access.Blob, error)); ok {
+		return rf(target, after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, int) []db_access.Blob); ok {
+		r0 = rf(target, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Blob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, int) error); ok {
+		r1 = rf(target, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetUnreplicatedBlobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUnreplicatedBlobs'
+type DbAccess_GetUnreplicatedBlobs_Call struct {
+	*mock.Call
+}
+
+// GetUnreplicatedBlobs is a helper method to define mock.On call
+//   - target string
+//   - after string
+//   - limit int
+func (_e *DbAccess_Expecter) GetUnreplicatedBlobs(target interface{}, after interface{}, limit interface{}) *DbAccess_GetUnreplicatedBlobs_Call {
+	return &DbAccess_GetUnreplicatedBlobs_Call{Call: _e.mock.On("GetUnreplicatedBlobs", target, after, limit)}
+}
+
+func (_c *DbAccess_GetUnreplicatedBlobs_Call) Run(run func(target string, after string, limit int)) *DbAccess_GetUnreplicatedBlobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUnreplicatedBlobs_Call) Return(_a0 []db_access.Blob, _a1 error) *DbAccess_GetUnreplicatedBlobs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetUnreplicatedBlobs_Call) RunAndReturn(run func(string, string, int) ([]db_access.Blob, error)) *DbAccess_GetUnreplicatedBlobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUsage provides a mock function with given fields: ownerId, month
+func (_m *DbAccess) GetUsage(ownerId int64, month db_access.Time) (db_access.Usage, error) {
+	ret := _m.Called(ownerId, month)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsage")
+	}
+
+	var r0 db_access.Usage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, db_access.Time) (db_access.Usage, error)); ok {
+		return rf(ownerId, month)
+	}
+	if rf, ok := ret.Get(0).(func(int64, db_access.Time) db_access.Usage); ok {
+		r0 = rf(ownerId, month)
+	} else {
+		r0 = ret.Get(0).(db_access.Usage)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, db_access.Time) error); ok {
+		r1 = rf(ownerId, month)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetUsage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUsage'
+type DbAccess_GetUsage_Call struct {
+	*mock.Call
+}
+
+// GetUsage is a helper method to define mock.On call
+//   - ownerId int64
+//   - month db_access.Time
+func (_e *DbAccess_Expecter) GetUsage(ownerId interface{}, month interface{}) *DbAccess_GetUsage_Call {
+	return &DbAccess_GetUsage_Call{Call: _e.mock.On("GetUsage", ownerId, month)}
+}
+
+func (_c *DbAccess_GetUsage_Call) Run(run func(ownerId int64, month db_access.Time)) *DbAccess_GetUsage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUsage_Call) Return(_a0 db_access.Usage, _a1 error) *DbAccess_GetUsage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetUsage_Call) RunAndReturn(run func(int64, db_access.Time) (db_access.Usage, error)) *DbAccess_GetUsage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUser provides a mock function with given fields: user
+func (_m *DbAccess) GetUser(user *db_access.User) error {
+	ret := _m.Called(user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.User) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_GetUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUser'
+type DbAccess_GetUser_Call struct {
+	*mock.Call
+}
+
+// GetUser is a helper method to define mock.On call
+//   - user *db_access.User
+func (_e *DbAccess_Expecter) GetUser(user interface{}) *DbAccess_GetUser_Call {
+	return &DbAccess_GetUser_Call{Call: _e.mock.On("GetUser", user)}
+}
+
+func (_c *DbAccess_GetUser_Call) Run(run func(user *db_access.User)) *DbAccess_GetUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.User))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUser_Call) Return(_a0 error) *DbAccess_GetUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_GetUser_Call) RunAndReturn(run func(*db_access.User) error) *DbAccess_GetUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserExports provides a mock function with given fields: ownerId
+func (_m *DbAccess) GetUserExports(ownerId int64) ([]db_access.Export, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserExports")
+	}
+
+	var r0 []db_access.Export
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.Export, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.Export); ok {
+		r0 = rf(ownerId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Export)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetUserExports_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserExports'
+type DbAccess_GetUserExports_Call struct {
+	*mock.Call
+}
+
+// GetUserExports is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) GetUserExports(ownerId interface{}) *DbAccess_GetUserExports_Call {
+	return &DbAccess_GetUserExports_Call{Call: _e.mock.On("GetUserExports", ownerId)}
+}
+
+func (_c *DbAccess_GetUserExports_Call) Run(run func(ownerId int64)) *DbAccess_GetUserExports_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUserExports_Call) Return(_a0 []db_access.Export, _a1 error) *DbAccess_GetUserExports_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetUserExports_Call) RunAndReturn(run func(int64) ([]db_access.Export, error)) *DbAccess_GetUserExports_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserFiles provides a mock function with given fields: ownerId
+func (_m *DbAccess) GetUserFiles(ownerId int64) ([]db_access.File, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserFiles")
+	}
+
+	var r0 []db_access.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.File, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.File); ok {
+		r0 = rf(ownerId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetUserFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserFiles'
+type DbAccess_GetUserFiles_Call struct {
+	*mock.Call
+}
+
+// GetUserFiles is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) GetUserFiles(ownerId interface{}) *DbAccess_GetUserFiles_Call {
+	return &DbAccess_GetUserFiles_Call{Call: _e.mock.On("GetUserFiles", ownerId)}
+}
+
+func (_c *DbAccess_GetUserFiles_Call) Run(run func(ownerId int64)) *DbAccess_GetUserFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUserFiles_Call) Return(_a0 []db_access.File, _a1 error) *DbAccess_GetUserFiles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetUserFiles_Call) RunAndReturn(run func(int64) ([]db_access.File, error)) *DbAccess_GetUserFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserPolicies provides a mock function with given fields: userId
+func (_m *DbAccess) GetUserPolicies(userId int64) ([]db_access.Policy, error) {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserPolicies")
+	}
+
+	var r0 []db_access.Policy
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.Policy, error)); ok {
+		return rf(userId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.Policy); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Policy)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetUserPolicies_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserPolicies'
+type DbAccess_GetUserPolicies_Call struct {
+	*mock.Call
+}
+
+// GetUserPolicies is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) GetUserPolicies(userId interface{}) *DbAccess_GetUserPolicies_Call {
+	return &DbAccess_GetUserPolicies_Call{Call: _e.mock.On("GetUserPolicies", userId)}
+}
+
+func (_c *DbAccess_GetUserPolicies_Call) Run(run func(userId int64)) *DbAccess_GetUserPolicies_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUserPolicies_Call) Return(_a0 []db_access.Policy, _a1 error) *DbAccess_GetUserPolicies_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetUserPolicies_Call) RunAndReturn(run func(int64) ([]db_access.Policy, error)) *DbAccess_GetUserPolicies_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUsers provides a mock function with no fields
+func (_m *DbAccess) GetUsers() ([]db_access.User, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsers")
+	}
+
+	var r0 []db_access.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]db_access.User, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []db_access.User); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_GetUsers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUsers'
+type DbAccess_GetUsers_Call struct {
+	*mock.Call
+}
+
+// GetUsers is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) GetUsers() *DbAccess_GetUsers_Call {
+	return &DbAccess_GetUsers_Call{Call: _e.mock.On("GetUsers")}
+}
+
+func (_c *DbAccess_GetUsers_Call) Run(run func()) *DbAccess_GetUsers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_GetUsers_Call) Return(_a0 []db_access.User, _a1 error) *DbAccess_GetUsers_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_GetUsers_Call) RunAndReturn(run func() ([]db_access.User, error)) *DbAccess_GetUsers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// KeepChangeCursors provides a mock function with given fields: consumers
+func (_m *DbAccess) KeepChangeCursors(consumers []string) error {
+	ret := _m.Called(consumers)
+
+	if len(ret) == 0 {
+		panic("no return value specified for KeepChangeCursors")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = rf(consumers)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_KeepChangeCursors_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'KeepChangeCursors'
+type DbAccess_KeepChangeCursors_Call struct {
+	*mock.Call
+}
+
+// KeepChangeCursors is a helper method to define mock.On call
+//   - consumers []string
+func (_e *DbAccess_Expecter) KeepChangeCursors(consumers interface{}) *DbAccess_KeepChangeCursors_Call {
+	return &DbAccess_KeepChangeCursors_Call{Call: _e.mock.On("KeepChangeCursors", consumers)}
+}
+
+func (_c *DbAccess_KeepChangeCursors_Call) Run(run func(consumers []string)) *DbAccess_KeepChangeCursors_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_KeepChangeCursors_Call) Return(_a0 error) *DbAccess_KeepChangeCursors_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_KeepChangeCursors_Call) RunAndReturn(run func([]string) error) *DbAccess_KeepChangeCursors_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListBlobNames provides a mock function with no fields
+func (_m *DbAccess) ListBlobNames() ([]string, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBlobNames")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_ListBlobNames_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListBlobNames'
+type DbAccess_ListBlobNames_Call struct {
+	*mock.Call
+}
+
+// ListBlobNames is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) ListBlobNames() *DbAccess_ListBlobNames_Call {
+	return &DbAccess_ListBlobNames_Call{Call: _e.mock.On("ListBlobNames")}
+}
+
+func (_c *DbAccess_ListBlobNames_Call) Run(run func()) *DbAccess_ListBlobNames_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_ListBlobNames_Call) Return(_a0 []string, _a1 error) *DbAccess_ListBlobNames_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_ListBlobNames_Call) RunAndReturn(run func() ([]string, error)) *DbAccess_ListBlobNames_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListNetworkRules provides a mock function with no fields
+func (_m *DbAccess) ListNetworkRules() ([]db_access.NetworkRules, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListNetworkRules")
+	}
+
+	var r0 []db_access.NetworkRules
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]db_access.NetworkRules, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []db_access.NetworkRules); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.NetworkRules)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_ListNetworkRules_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListNetworkRules'
+type DbAccess_ListNetworkRules_Call struct {
+	*mock.Call
+}
+
+// ListNetworkRules is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) ListNetworkRules() *DbAccess_ListNetworkRules_Call {
+	return &DbAccess_ListNetworkRules_Call{Call: _e.mock.On("ListNetworkRules")}
+}
+
+func (_c *DbAccess_ListNetworkRules_Call) Run(run func()) *DbAccess_ListNetworkRules_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_ListNetworkRules_Call) Return(_a0 []db_access.NetworkRules, _a1 error) *DbAccess_ListNetworkRules_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_ListNetworkRules_Call) RunAndReturn(run func() ([]db_access.NetworkRules, error)) *DbAccess_ListNetworkRules_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListPolicies provides a mock function with no fields
+func (_m *DbAccess) ListPolicies() ([]db_access.Policy, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPolicies")
+	}
+
+	var r0 []db_access.Policy
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]db_access.Policy, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []db_access.Policy); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.Policy)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_ListPolicies_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListPolicies'
+type DbAccess_ListPolicies_Call struct {
+	*mock.Call
+}
+
+// ListPolicies is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) ListPolicies() *DbAccess_ListPolicies_Call {
+	return &DbAccess_ListPolicies_Call{Call: _e.mock.On("ListPolicies")}
+}
+
+func (_c *DbAccess_ListPolicies_Call) Run(run func()) *DbAccess_ListPolicies_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_ListPolicies_Call) Return(_a0 []db_access.Policy, _a1 error) *DbAccess_ListPolicies_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_ListPolicies_Call) RunAndReturn(run func() ([]db_access.Policy, error)) *DbAccess_ListPolicies_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MarkBlobReplicated provides a mock function with given fields: target, blobName
+func (_m *DbAccess) MarkBlobReplicated(target string, blobName string) error {
+	ret := _m.Called(target, blobName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkBlobReplicated")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(target, blobName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_MarkBlobReplicated_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MarkBlobReplicated'
+type DbAccess_MarkBlobReplicated_Call struct {
+	*mock.Call
+}
+
+// MarkBlobReplicated is a helper method to define mock.On call
+//   - target string
+//   - blobName string
+func (_e *DbAccess_Expecter) MarkBlobReplicated(target interface{}, blobName interface{}) *DbAccess_MarkBlobReplicated_Call {
+	return &DbAccess_MarkBlobReplicated_Call{Call: _e.mock.On("MarkBlobReplicated", target, blobName)}
+}
+
+func (_c *DbAccess_MarkBlobReplicated_Call) Run(run func(target string, blobName string)) *DbAccess_MarkBlobReplicated_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_MarkBlobReplicated_Call) Return(_a0 error) *DbAccess_MarkBlobReplicated_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_MarkBlobReplicated_Call) RunAndReturn(run func(string, string) error) *DbAccess_MarkBlobReplicated_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MarkExpiryNotified provides a mock function with given fields: generatedName
+func (_m *DbAccess) MarkExpiryNotified(generatedName string) error {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkExpiryNotified")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(generatedName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_MarkExpiryNotified_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MarkExpiryNotified'
+type DbAccess_MarkExpiryNotified_Call struct {
+	*mock.Call
+}
+
+// MarkExpiryNotified is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) MarkExpiryNotified(generatedName interface{}) *DbAccess_MarkExpiryNotified_Call {
+	return &DbAccess_MarkExpiryNotified_Call{Call: _e.mock.On("MarkExpiryNotified", generatedName)}
+}
+
+func (_c *DbAccess_MarkExpiryNotified_Call) Run(run func(generatedName string)) *DbAccess_MarkExpiryNotified_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_MarkExpiryNotified_Call) Return(_a0 error) *DbAccess_MarkExpiryNotified_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_MarkExpiryNotified_Call) RunAndReturn(run func(string) error) *DbAccess_MarkExpiryNotified_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecalculateUsage provides a mock function with no fields
+func (_m *DbAccess) RecalculateUsage() (int64, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecalculateUsage")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (int64, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_RecalculateUsage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecalculateUsage'
+type DbAccess_RecalculateUsage_Call struct {
+	*mock.Call
+}
+
+// RecalculateUsage is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) RecalculateUsage() *DbAccess_RecalculateUsage_Call {
+	return &DbAccess_RecalculateUsage_Call{Call: _e.mock.On("RecalculateUsage")}
+}
+
+func (_c *DbAccess_RecalculateUsage_Call) Run(run func()) *DbAccess_RecalculateUsage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_RecalculateUsage_Call) Return(_a0 int64, _a1 error) *DbAccess_RecalculateUsage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_RecalculateUsage_Call) RunAndReturn(run func() (int64, error)) *DbAccess_RecalculateUsage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordFileAccesses provides a mock function with given fields: accesses
+func (_m *DbAccess) RecordFileAccesses(accesses []db_access.FileAccess) error {
+	ret := _m.Called(accesses)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordFileAccesses")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]db_access.FileAccess) error); ok {
+		r0 = rf(accesses)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RecordFileAccesses_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordFileAccesses'
+type DbAccess_RecordFileAccesses_Call struct {
+	*mock.Call
+}
+
+// RecordFileAccesses is a helper method to define mock.On call
+//   - accesses []db_access.FileAccess
+func (_e *DbAccess_Expecter) RecordFileAccesses(accesses interface{}) *DbAccess_RecordFileAccesses_Call {
+	return &DbAccess_RecordFileAccesses_Call{Call: _e.mock.On("RecordFileAccesses", accesses)}
+}
+
+func (_c *DbAccess_RecordFileAccesses_Call) Run(run func(accesses []db_access.FileAccess)) *DbAccess_RecordFileAccesses_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]db_access.FileAccess))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RecordFileAccesses_Call) Return(_a0 error) *DbAccess_RecordFileAccesses_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RecordFileAccesses_Call) RunAndReturn(run func([]db_access.FileAccess) error) *DbAccess_RecordFileAccesses_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveAuditEventsBefore provides a mock function with given fields: t
+func (_m *DbAccess) RemoveAuditEventsBefore(t db_access.Time) (int64, error) {
+	ret := _m.Called(t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveAuditEventsBefore")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(db_access.Time) (int64, error)); ok {
+		return rf(t)
+	}
+	if rf, ok := ret.Get(0).(func(db_access.Time) int64); ok {
+		r0 = rf(t)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(db_access.Time) error); ok {
+		r1 = rf(t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_RemoveAuditEventsBefore_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveAuditEventsBefore'
+type DbAccess_RemoveAuditEventsBefore_Call struct {
+	*mock.Call
+}
+
+// RemoveAuditEventsBefore is a helper method to define mock.On call
+//   - t db_access.Time
+func (_e *DbAccess_Expecter) RemoveAuditEventsBefore(t interface{}) *DbAccess_RemoveAuditEventsBefore_Call {
+	return &DbAccess_RemoveAuditEventsBefore_Call{Call: _e.mock.On("RemoveAuditEventsBefore", t)}
+}
+
+func (_c *DbAccess_RemoveAuditEventsBefore_Call) Run(run func(t db_access.Time)) *DbAccess_RemoveAuditEventsBefore_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveAuditEventsBefore_Call) Return(_a0 int64, _a1 error) *DbAccess_RemoveAuditEventsBefore_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_RemoveAuditEventsBefore_Call) RunAndReturn(run func(db_access.Time) (int64, error)) *DbAccess_RemoveAuditEventsBefore_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveComment provides a mock function with given fields: id
+func (_m *DbAccess) RemoveComment(id int64) error {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveComment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveComment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveComment'
+type DbAccess_RemoveComment_Call struct {
+	*mock.Call
+}
+
+// RemoveComment is a helper method to define mock.On call
+//   - id int64
+func (_e *DbAccess_Expecter) RemoveComment(id interface{}) *DbAccess_RemoveComment_Call {
+	return &DbAccess_RemoveComment_Call{Call: _e.mock.On("RemoveComment", id)}
+}
+
+func (_c *DbAccess_RemoveComment_Call) Run(run func(id int64)) *DbAccess_RemoveComment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveComment_Call) Return(_a0 error) *DbAccess_RemoveComment_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveComment_Call) RunAndReturn(run func(int64) error) *DbAccess_RemoveComment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveDEC provides a mock function with given fields: id
+func (_m *DbAccess) RemoveDEC(id db_access.DecId) error {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveDEC")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.DecId) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveDEC'
+type DbAccess_RemoveDEC_Call struct {
+	*mock.Call
+}
+
+// RemoveDEC is a helper method to define mock.On call
+//   - id db_access.DecId
+func (_e *DbAccess_Expecter) RemoveDEC(id interface{}) *DbAccess_RemoveDEC_Call {
+	return &DbAccess_RemoveDEC_Call{Call: _e.mock.On("RemoveDEC", id)}
+}
+
+func (_c *DbAccess_RemoveDEC_Call) Run(run func(id db_access.DecId)) *DbAccess_RemoveDEC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.DecId))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveDEC_Call) Return(_a0 error) *DbAccess_RemoveDEC_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveDEC_Call) RunAndReturn(run func(db_access.DecId) error) *DbAccess_RemoveDEC_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveDevice provides a mock function with given fields: ownerId, id
+func (_m *DbAccess) RemoveDevice(ownerId int64, id string) error {
+	ret := _m.Called(ownerId, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveDevice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, string) error); ok {
+		r0 = rf(ownerId, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveDevice_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveDevice'
+type DbAccess_RemoveDevice_Call struct {
+	*mock.Call
+}
+
+// RemoveDevice is a helper method to define mock.On call
+//   - ownerId int64
+//   - id string
+func (_e *DbAccess_Expecter) RemoveDevice(ownerId interface{}, id interface{}) *DbAccess_RemoveDevice_Call {
+	return &DbAccess_RemoveDevice_Call{Call: _e.mock.On("RemoveDevice", ownerId, id)}
+}
+
+func (_c *DbAccess_RemoveDevice_Call) Run(run func(ownerId int64, id string)) *DbAccess_RemoveDevice_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveDevice_Call) Return(_a0 error) *DbAccess_RemoveDevice_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveDevice_Call) RunAndReturn(run func(int64, string) error) *DbAccess_RemoveDevice_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveExport provides a mock function with given fields: id
+func (_m *DbAccess) RemoveExport(id string) error {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveExport")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveExport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveExport'
+type DbAccess_RemoveExport_Call struct {
+	*mock.Call
+}
+
+// RemoveExport is a helper method to define mock.On call
+//   - id string
+func (_e *DbAccess_Expecter) RemoveExport(id interface{}) *DbAccess_RemoveExport_Call {
+	return &DbAccess_RemoveExport_Call{Call: _e.mock.On("RemoveExport", id)}
+}
+
+func (_c *DbAccess_RemoveExport_Call) Run(run func(id string)) *DbAccess_RemoveExport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveExport_Call) Return(_a0 error) *DbAccess_RemoveExport_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveExport_Call) RunAndReturn(run func(string) error) *DbAccess_RemoveExport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveFile provides a mock function with given fields: generatedName
+func (_m *DbAccess) RemoveFile(generatedName string) error {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(generatedName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveFile'
+type DbAccess_RemoveFile_Call struct {
+	*mock.Call
+}
+
+// RemoveFile is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) RemoveFile(generatedName interface{}) *DbAccess_RemoveFile_Call {
+	return &DbAccess_RemoveFile_Call{Call: _e.mock.On("RemoveFile", generatedName)}
+}
+
+func (_c *DbAccess_RemoveFile_Call) Run(run func(generatedName string)) *DbAccess_RemoveFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveFile_Call) Return(_a0 error) *DbAccess_RemoveFile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveFile_Call) RunAndReturn(run func(string) error) *DbAccess_RemoveFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveFileLock provides a mock function with given fields: generatedName
+func (_m *DbAccess) RemoveFileLock(generatedName string) error {
+	ret := _m.Called(generatedName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveFileLock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(generatedName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveFileLock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveFileLock'
+type DbAccess_RemoveFileLock_Call struct {
+	*mock.Call
+}
+
+// RemoveFileLock is a helper method to define mock.On call
+//   - generatedName string
+func (_e *DbAccess_Expecter) RemoveFileLock(generatedName interface{}) *DbAccess_RemoveFileLock_Call {
+	return &DbAccess_RemoveFileLock_Call{Call: _e.mock.On("RemoveFileLock", generatedName)}
+}
+
+func (_c *DbAccess_RemoveFileLock_Call) Run(run func(generatedName string)) *DbAccess_RemoveFileLock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveFileLock_Call) Return(_a0 error) *DbAccess_RemoveFileLock_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveFileLock_Call) RunAndReturn(run func(string) error) *DbAccess_RemoveFileLock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveNotificationsBefore provides a mock function with given fields: t
+func (_m *DbAccess) RemoveNotificationsBefore(t db_access.Time) error {
+	ret := _m.Called(t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveNotificationsBefore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.Time) error); ok {
+		r0 = rf(t)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveNotificationsBefore_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveNotificationsBefore'
+type DbAccess_RemoveNotificationsBefore_Call struct {
+	*mock.Call
+}
+
+// RemoveNotificationsBefore is a helper method to define mock.On call
+//   - t db_access.Time
+func (_e *DbAccess_Expecter) RemoveNotificationsBefore(t interface{}) *DbAccess_RemoveNotificationsBefore_Call {
+	return &DbAccess_RemoveNotificationsBefore_Call{Call: _e.mock.On("RemoveNotificationsBefore", t)}
+}
+
+func (_c *DbAccess_RemoveNotificationsBefore_Call) Run(run func(t db_access.Time)) *DbAccess_RemoveNotificationsBefore_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveNotificationsBefore_Call) Return(_a0 error) *DbAccess_RemoveNotificationsBefore_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveNotificationsBefore_Call) RunAndReturn(run func(db_access.Time) error) *DbAccess_RemoveNotificationsBefore_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveReadChanges provides a mock function with no fields
+func (_m *DbAccess) RemoveReadChanges() error {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveReadChanges")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RemoveReadChanges_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveReadChanges'
+type DbAccess_RemoveReadChanges_Call struct {
+	*mock.Call
+}
+
+// RemoveReadChanges is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) RemoveReadChanges() *DbAccess_RemoveReadChanges_Call {
+	return &DbAccess_RemoveReadChanges_Call{Call: _e.mock.On("RemoveReadChanges")}
+}
+
+func (_c *DbAccess_RemoveReadChanges_Call) Run(run func()) *DbAccess_RemoveReadChanges_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveReadChanges_Call) Return(_a0 error) *DbAccess_RemoveReadChanges_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RemoveReadChanges_Call) RunAndReturn(run func() error) *DbAccess_RemoveReadChanges_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveUserDECs provides a mock function with given fields: ownerId
+func (_m *DbAccess) RemoveUserDECs(ownerId int64) ([]db_access.DecId, error) {
+	ret := _m.Called(ownerId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveUserDECs")
+	}
+
+	var r0 []db_access.DecId
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) ([]db_access.DecId, error)); ok {
+		return rf(ownerId)
+	}
+	if rf, ok := ret.Get(0).(func(int64) []db_access.DecId); ok {
+		r0 = rf(ownerId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db_access.DecId)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(ownerId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_RemoveUserDECs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveUserDECs'
+type DbAccess_RemoveUserDECs_Call struct {
+	*mock.Call
+}
+
+// RemoveUserDECs is a helper method to define mock.On call
+//   - ownerId int64
+func (_e *DbAccess_Expecter) RemoveUserDECs(ownerId interface{}) *DbAccess_RemoveUserDECs_Call {
+	return &DbAccess_RemoveUserDECs_Call{Call: _e.mock.On("RemoveUserDECs", ownerId)}
+}
+
+func (_c *DbAccess_RemoveUserDECs_Call) Run(run func(ownerId int64)) *DbAccess_RemoveUserDECs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RemoveUserDECs_Call) Return(_a0 []db_access.DecId, _a1 error) *DbAccess_RemoveUserDECs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_RemoveUserDECs_Call) RunAndReturn(run func(int64) ([]db_access.DecId, error)) *DbAccess_RemoveUserDECs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RenameFile provides a mock function with given fields: generatedName, filename
+func (_m *DbAccess) RenameFile(generatedName string, filename string) error {
+	ret := _m.Called(generatedName, filename)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenameFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(generatedName, filename)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RenameFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RenameFile'
+type DbAccess_RenameFile_Call struct {
+	*mock.Call
+}
+
+// RenameFile is a helper method to define mock.On call
+//   - generatedName string
+//   - filename string
+func (_e *DbAccess_Expecter) RenameFile(generatedName interface{}, filename interface{}) *DbAccess_RenameFile_Call {
+	return &DbAccess_RenameFile_Call{Call: _e.mock.On("RenameFile", generatedName, filename)}
+}
+
+func (_c *DbAccess_RenameFile_Call) Run(run func(generatedName string, filename string)) *DbAccess_RenameFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RenameFile_Call) Return(_a0 error) *DbAccess_RenameFile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RenameFile_Call) RunAndReturn(run func(string, string) error) *DbAccess_RenameFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RenameUser provides a mock function with given fields: userId, name
+func (_m *DbAccess) RenameUser(userId int64, name string) error {
+	ret := _m.Called(userId, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenameUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, string) error); ok {
+		r0 = rf(userId, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RenameUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RenameUser'
+type DbAccess_RenameUser_Call struct {
+	*mock.Call
+}
+
+// RenameUser is a helper method to define mock.On call
+//   - userId int64
+//   - name string
+func (_e *DbAccess_Expecter) RenameUser(userId interface{}, name interface{}) *DbAccess_RenameUser_Call {
+	return &DbAccess_RenameUser_Call{Call: _e.mock.On("RenameUser", userId, name)}
+}
+
+func (_c *DbAccess_RenameUser_Call) Run(run func(userId int64, name string)) *DbAccess_RenameUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RenameUser_Call) Return(_a0 error) *DbAccess_RenameUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RenameUser_Call) RunAndReturn(run func(int64, string) error) *DbAccess_RenameUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeUserTokens provides a mock function with given fields: userId
+func (_m *DbAccess) RevokeUserTokens(userId int64) error {
+	ret := _m.Called(userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeUserTokens")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RevokeUserTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeUserTokens'
+type DbAccess_RevokeUserTokens_Call struct {
+	*mock.Call
+}
+
+// RevokeUserTokens is a helper method to define mock.On call
+//   - userId int64
+func (_e *DbAccess_Expecter) RevokeUserTokens(userId interface{}) *DbAccess_RevokeUserTokens_Call {
+	return &DbAccess_RevokeUserTokens_Call{Call: _e.mock.On("RevokeUserTokens", userId)}
+}
+
+func (_c *DbAccess_RevokeUserTokens_Call) Run(run func(userId int64)) *DbAccess_RevokeUserTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RevokeUserTokens_Call) Return(_a0 error) *DbAccess_RevokeUserTokens_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RevokeUserTokens_Call) RunAndReturn(run func(int64) error) *DbAccess_RevokeUserTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RewrapDEC provides a mock function with given fields: id, value, keyVersion
+func (_m *DbAccess) RewrapDEC(id db_access.DecId, value string, keyVersion int64) error {
+	ret := _m.Called(id, value, keyVersion)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RewrapDEC")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.DecId, string, int64) error); ok {
+		r0 = rf(id, value, keyVersion)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RewrapDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RewrapDEC'
+type DbAccess_RewrapDEC_Call struct {
+	*mock.Call
+}
+
+// RewrapDEC is a helper method to define mock.On call
+//   - id db_access.DecId
+//   - value string
+//   - keyVersion int64
+func (_e *DbAccess_Expecter) RewrapDEC(id interface{}, value interface{}, keyVersion interface{}) *DbAccess_RewrapDEC_Call {
+	return &DbAccess_RewrapDEC_Call{Call: _e.mock.On("RewrapDEC", id, value, keyVersion)}
+}
+
+func (_c *DbAccess_RewrapDEC_Call) Run(run func(id db_access.DecId, value string, keyVersion int64)) *DbAccess_RewrapDEC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.DecId), args[1].(string), args[2].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RewrapDEC_Call) Return(_a0 error) *DbAccess_RewrapDEC_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RewrapDEC_Call) RunAndReturn(run func(db_access.DecId, string, int64) error) *DbAccess_RewrapDEC_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RewrapFileName provides a mock function with given fields: generatedName, old, new
+func (_m *DbAccess) RewrapFileName(generatedName string, old string, new string) error {
+	ret := _m.Called(generatedName, old, new)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RewrapFileName")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(generatedName, old, new)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_RewrapFileName_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RewrapFileName'
+type DbAccess_RewrapFileName_Call struct {
+	*mock.Call
+}
+
+// RewrapFileName is a helper method to define mock.On call
+//   - generatedName string
+//   - old string
+//   - new string
+func (_e *DbAccess_Expecter) RewrapFileName(generatedName interface{}, old interface{}, new interface{}) *DbAccess_RewrapFileName_Call {
+	return &DbAccess_RewrapFileName_Call{Call: _e.mock.On("RewrapFileName", generatedName, old, new)}
+}
+
+func (_c *DbAccess_RewrapFileName_Call) Run(run func(generatedName string, old string, new string)) *DbAccess_RewrapFileName_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_RewrapFileName_Call) Return(_a0 error) *DbAccess_RewrapFileName_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_RewrapFileName_Call) RunAndReturn(run func(string, string, string) error) *DbAccess_RewrapFileName_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetAlertThresholds provides a mock function with given fields: t
+func (_m *DbAccess) SetAlertThresholds(t db_access.AlertThresholds) error {
+	ret := _m.Called(t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetAlertThresholds")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.AlertThresholds) error); ok {
+		r0 = rf(t)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetAlertThresholds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetAlertThresholds'
+type DbAccess_SetAlertThresholds_Call struct {
+	*mock.Call
+}
+
+// SetAlertThresholds is a helper method to define mock.On call
+//   - t db_access.AlertThresholds
+func (_e *DbAccess_Expecter) SetAlertThresholds(t interface{}) *DbAccess_SetAlertThresholds_Call {
+	return &DbAccess_SetAlertThresholds_Call{Call: _e.mock.On("SetAlertThresholds", t)}
+}
+
+func (_c *DbAccess_SetAlertThresholds_Call) Run(run func(t db_access.AlertThresholds)) *DbAccess_SetAlertThresholds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.AlertThresholds))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetAlertThresholds_Call) Return(_a0 error) *DbAccess_SetAlertThresholds_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetAlertThresholds_Call) RunAndReturn(run func(db_access.AlertThresholds) error) *DbAccess_SetAlertThresholds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetBlobBackend provides a mock function with given fields: blobName, from, to
+func (_m *DbAccess) SetBlobBackend(blobName string, from string, to string) (bool, error) {
+	ret := _m.Called(blobName, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBlobBackend")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, string) (bool, error)); ok {
+		return rf(blobName, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string) bool); ok {
+		r0 = rf(blobName, from, to)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(blobName, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DbAccess_SetBlobBackend_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetBlobBackend'
+type DbAccess_SetBlobBackend_Call struct {
+	*mock.Call
+}
+
+// SetBlobBackend is a helper method to define mock.On call
+//   - blobName string
+//   - from string
+//   - to string
+func (_e *DbAccess_Expecter) SetBlobBackend(blobName interface{}, from interface{}, to interface{}) *DbAccess_SetBlobBackend_Call {
+	return &DbAccess_SetBlobBackend_Call{Call: _e.mock.On("SetBlobBackend", blobName, from, to)}
+}
+
+func (_c *DbAccess_SetBlobBackend_Call) Run(run func(blobName string, from string, to string)) *DbAccess_SetBlobBackend_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetBlobBackend_Call) Return(_a0 bool, _a1 error) *DbAccess_SetBlobBackend_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DbAccess_SetBlobBackend_Call) RunAndReturn(run func(string, string, string) (bool, error)) *DbAccess_SetBlobBackend_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetChangeCursor provides a mock function with given fields: consumer, seq
+func (_m *DbAccess) SetChangeCursor(consumer string, seq int64) error {
+	ret := _m.Called(consumer, seq)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetChangeCursor")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = rf(consumer, seq)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetChangeCursor_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetChangeCursor'
+type DbAccess_SetChangeCursor_Call struct {
+	*mock.Call
+}
+
+// SetChangeCursor is a helper method to define mock.On call
+//   - consumer string
+//   - seq int64
+func (_e *DbAccess_Expecter) SetChangeCursor(consumer interface{}, seq interface{}) *DbAccess_SetChangeCursor_Call {
+	return &DbAccess_SetChangeCursor_Call{Call: _e.mock.On("SetChangeCursor", consumer, seq)}
+}
+
+func (_c *DbAccess_SetChangeCursor_Call) Run(run func(consumer string, seq int64)) *DbAccess_SetChangeCursor_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetChangeCursor_Call) Return(_a0 error) *DbAccess_SetChangeCursor_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetChangeCursor_Call) RunAndReturn(run func(string, int64) error) *DbAccess_SetChangeCursor_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFileExpiry provides a mock function with given fields: generatedName, expiresAt
+func (_m *DbAccess) SetFileExpiry(generatedName string, expiresAt db_access.Time) error {
+	ret := _m.Called(generatedName, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFileExpiry")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, db_access.Time) error); ok {
+		r0 = rf(generatedName, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetFileExpiry_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFileExpiry'
+type DbAccess_SetFileExpiry_Call struct {
+	*mock.Call
+}
+
+// SetFileExpiry is a helper method to define mock.On call
+//   - generatedName string
+//   - expiresAt db_access.Time
+func (_e *DbAccess_Expecter) SetFileExpiry(generatedName interface{}, expiresAt interface{}) *DbAccess_SetFileExpiry_Call {
+	return &DbAccess_SetFileExpiry_Call{Call: _e.mock.On("SetFileExpiry", generatedName, expiresAt)}
+}
+
+func (_c *DbAccess_SetFileExpiry_Call) Run(run func(generatedName string, expiresAt db_access.Time)) *DbAccess_SetFileExpiry_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(db_access.Time))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetFileExpiry_Call) Return(_a0 error) *DbAccess_SetFileExpiry_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetFileExpiry_Call) RunAndReturn(run func(string, db_access.Time) error) *DbAccess_SetFileExpiry_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFileFolder provides a mock function with given fields: generatedName, folderId
+func (_m *DbAccess) SetFileFolder(generatedName string, folderId int64) error {
+	ret := _m.Called(generatedName, folderId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFileFolder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = rf(generatedName, folderId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetFileFolder_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFileFolder'
+type DbAccess_SetFileFolder_Call struct {
+	*mock.Call
+}
+
+// SetFileFolder is a helper method to define mock.On call
+//   - generatedName string
+//   - folderId int64
+func (_e *DbAccess_Expecter) SetFileFolder(generatedName interface{}, folderId interface{}) *DbAccess_SetFileFolder_Call {
+	return &DbAccess_SetFileFolder_Call{Call: _e.mock.On("SetFileFolder", generatedName, folderId)}
+}
+
+func (_c *DbAccess_SetFileFolder_Call) Run(run func(generatedName string, folderId int64)) *DbAccess_SetFileFolder_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetFileFolder_Call) Return(_a0 error) *DbAccess_SetFileFolder_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetFileFolder_Call) RunAndReturn(run func(string, int64) error) *DbAccess_SetFileFolder_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFileLock provides a mock function with given fields: lock
+func (_m *DbAccess) SetFileLock(lock db_access.FileLock) error {
+	ret := _m.Called(lock)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFileLock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.FileLock) error); ok {
+		r0 = rf(lock)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetFileLock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFileLock'
+type DbAccess_SetFileLock_Call struct {
+	*mock.Call
+}
+
+// SetFileLock is a helper method to define mock.On call
+//   - lock db_access.FileLock
+func (_e *DbAccess_Expecter) SetFileLock(lock interface{}) *DbAccess_SetFileLock_Call {
+	return &DbAccess_SetFileLock_Call{Call: _e.mock.On("SetFileLock", lock)}
+}
+
+func (_c *DbAccess_SetFileLock_Call) Run(run func(lock db_access.FileLock)) *DbAccess_SetFileLock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.FileLock))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetFileLock_Call) Return(_a0 error) *DbAccess_SetFileLock_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetFileLock_Call) RunAndReturn(run func(db_access.FileLock) error) *DbAccess_SetFileLock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFileMetadata provides a mock function with given fields: generatedName, ownerId, data, terms
+func (_m *DbAccess) SetFileMetadata(generatedName string, ownerId int64, data string, terms []string) error {
+	ret := _m.Called(generatedName, ownerId, data, terms)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFileMetadata")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64, string, []string) error); ok {
+		r0 = rf(generatedName, ownerId, data, terms)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetFileMetadata_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFileMetadata'
+type DbAccess_SetFileMetadata_Call struct {
+	*mock.Call
+}
+
+// SetFileMetadata is a helper method to define mock.On call
+//   - generatedName string
+//   - ownerId int64
+//   - data string
+//   - terms []string
+func (_e *DbAccess_Expecter) SetFileMetadata(generatedName interface{}, ownerId interface{}, data interface{}, terms interface{}) *DbAccess_SetFileMetadata_Call {
+	return &DbAccess_SetFileMetadata_Call{Call: _e.mock.On("SetFileMetadata", generatedName, ownerId, data, terms)}
+}
+
+func (_c *DbAccess_SetFileMetadata_Call) Run(run func(generatedName string, ownerId int64, data string, terms []string)) *DbAccess_SetFileMetadata_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int64), args[2].(string), args[3].([]string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetFileMetadata_Call) Return(_a0 error) *DbAccess_SetFileMetadata_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetFileMetadata_Call) RunAndReturn(run func(string, int64, string, []string) error) *DbAccess_SetFileMetadata_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFileNameIndex provides a mock function with given fields: generatedName, index
+func (_m *DbAccess) SetFileNameIndex(generatedName string, index string) error {
+	ret := _m.Called(generatedName, index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFileNameIndex")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(generatedName, index)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetFileNameIndex_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFileNameIndex'
+type DbAccess_SetFileNameIndex_Call struct {
+	*mock.Call
+}
+
+// SetFileNameIndex is a helper method to define mock.On call
+//   - generatedName string
+//   - index string
+func (_e *DbAccess_Expecter) SetFileNameIndex(generatedName interface{}, index interface{}) *DbAccess_SetFileNameIndex_Call {
+	return &DbAccess_SetFileNameIndex_Call{Call: _e.mock.On("SetFileNameIndex", generatedName, index)}
+}
+
+func (_c *DbAccess_SetFileNameIndex_Call) Run(run func(generatedName string, index string)) *DbAccess_SetFileNameIndex_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetFileNameIndex_Call) Return(_a0 error) *DbAccess_SetFileNameIndex_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetFileNameIndex_Call) RunAndReturn(run func(string, string) error) *DbAccess_SetFileNameIndex_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFileTerms provides a mock function with given fields: generatedName, ownerId, terms
+func (_m *DbAccess) SetFileTerms(generatedName string, ownerId int64, terms []string) error {
+	ret := _m.Called(generatedName, ownerId, terms)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFileTerms")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64, []string) error); ok {
+		r0 = rf(generatedName, ownerId, terms)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetFileTerms_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFileTerms'
+type DbAccess_SetFileTerms_Call struct {
+	*mock.Call
+}
+
+// SetFileTerms is a helper method to define mock.On call
+//   - generatedName string
+//   - ownerId int64
+//   - terms []string
+func (_e *DbAccess_Expecter) SetFileTerms(generatedName interface{}, ownerId interface{}, terms interface{}) *DbAccess_SetFileTerms_Call {
+	return &DbAccess_SetFileTerms_Call{Call: _e.mock.On("SetFileTerms", generatedName, ownerId, terms)}
+}
+
+func (_c *DbAccess_SetFileTerms_Call) Run(run func(generatedName string, ownerId int64, terms []string)) *DbAccess_SetFileTerms_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int64), args[2].([]string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetFileTerms_Call) Return(_a0 error) *DbAccess_SetFileTerms_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetFileTerms_Call) RunAndReturn(run func(string, int64, []string) error) *DbAccess_SetFileTerms_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetNetworkRules provides a mock function with given fields: rules
+func (_m *DbAccess) SetNetworkRules(rules db_access.NetworkRules) error {
+	ret := _m.Called(rules)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetNetworkRules")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.NetworkRules) error); ok {
+		r0 = rf(rules)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetNetworkRules_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetNetworkRules'
+type DbAccess_SetNetworkRules_Call struct {
+	*mock.Call
+}
+
+// SetNetworkRules is a helper method to define mock.On call
+//   - rules db_access.NetworkRules
+func (_e *DbAccess_Expecter) SetNetworkRules(rules interface{}) *DbAccess_SetNetworkRules_Call {
+	return &DbAccess_SetNetworkRules_Call{Call: _e.mock.On("SetNetworkRules", rules)}
+}
+
+func (_c *DbAccess_SetNetworkRules_Call) Run(run func(rules db_access.NetworkRules)) *DbAccess_SetNetworkRules_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.NetworkRules))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetNetworkRules_Call) Return(_a0 error) *DbAccess_SetNetworkRules_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetNetworkRules_Call) RunAndReturn(run func(db_access.NetworkRules) error) *DbAccess_SetNetworkRules_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetPolicy provides a mock function with given fields: p
+func (_m *DbAccess) SetPolicy(p db_access.Policy) error {
+	ret := _m.Called(p)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPolicy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(db_access.Policy) error); ok {
+		r0 = rf(p)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetPolicy'
+type DbAccess_SetPolicy_Call struct {
+	*mock.Call
+}
+
+// SetPolicy is a helper method to define mock.On call
+//   - p db_access.Policy
+func (_e *DbAccess_Expecter) SetPolicy(p interface{}) *DbAccess_SetPolicy_Call {
+	return &DbAccess_SetPolicy_Call{Call: _e.mock.On("SetPolicy", p)}
+}
+
+func (_c *DbAccess_SetPolicy_Call) Run(run func(p db_access.Policy)) *DbAccess_SetPolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(db_access.Policy))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetPolicy_Call) Return(_a0 error) *DbAccess_SetPolicy_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetPolicy_Call) RunAndReturn(run func(db_access.Policy) error) *DbAccess_SetPolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetUserOrg provides a mock function with given fields: userId, org
+func (_m *DbAccess) SetUserOrg(userId int64, org string) error {
+	ret := _m.Called(userId, org)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserOrg")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, string) error); ok {
+		r0 = rf(userId, org)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DbAccess_SetUserOrg_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetUserOrg'
+type DbAccess_SetUserOrg_Call struct {
+	*mock.Call
+}
+
+// SetUserOrg is a helper method to define mock.On call
+//   - userId int64
+//   - org string
+func (_e *DbAccess_Expecter) SetUserOrg(userId interface{}, org interface{}) *DbAccess_SetUserOrg_Call {
+	return &DbAccess_SetUserOrg_Call{Call: _e.mock.On("SetUserOrg", userId, org)}
+}
+
+func (_c *DbAccess_SetUserOrg_Call) Run(run func(userId int64, org string)) *DbAccess_SetUserOrg_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_SetUserOrg_Call) Return(_a0 error) *DbAccess_SetUserOrg_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_SetUserOrg_Call) RunAndReturn(run func(int64, string) error) *DbAccess_SetUserOrg_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetUserRoles provides a mock function with given fields: userId, roles
+func (_m *DbAccess) SetUserRoles(userId int64, roles []string) error {
+	ret := _m.Called(userId, roles)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserRoles")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*db_access.DEC) error); ok {
-		r0 = rf(dec)
+	if rf, ok := ret.Get(0).(func(int64, []string) error); ok {
+		r0 = rf(userId, roles)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -39,45 +6437,46 @@ func (_m *DbAccess) AddDEC(dec *db_access.DEC) error {
 	return r0
 }
 
-// DbAccess_AddDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddDEC'
-type DbAccess_AddDEC_Call struct {
+// DbAccess_SetUserRoles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetUserRoles'
+type DbAccess_SetUserRoles_Call struct {
 	*mock.Call
 }
 
-// AddDEC is a helper method to define mock.On call
-//   - dec *db_access.DEC
-func (_e *DbAccess_Expecter) AddDEC(dec interface{}) *DbAccess_AddDEC_Call {
-	return &DbAccess_AddDEC_Call{Call: _e.mock.On("AddDEC", dec)}
+// SetUserRoles is a helper method to define mock.On call
+//   - userId int64
+//   - roles []string
+func (_e *DbAccess_Expecter) SetUserRoles(userId interface{}, roles interface{}) *DbAccess_SetUserRoles_Call {
+	return &DbAccess_SetUserRoles_Call{Call: _e.mock.On("SetUserRoles", userId, roles)}
 }
 
-func (_c *DbAccess_AddDEC_Call) Run(run func(dec *db_access.DEC)) *DbAccess_AddDEC_Call {
+func (_c *DbAccess_SetUserRoles_Call) Run(run func(userId int64, roles []string)) *DbAccess_SetUserRoles_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(*db_access.DEC))
+		run(args[0].(int64), args[1].([]string))
 	})
 	return _c
 }
 
-func (_c *DbAccess_AddDEC_Call) Return(_a0 error) *DbAccess_AddDEC_Call {
+func (_c *DbAccess_SetUserRoles_Call) Return(_a0 error) *DbAccess_SetUserRoles_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_AddDEC_Call) RunAndReturn(run func(*db_access.DEC) error) *DbAccess_AddDEC_Call {
+func (_c *DbAccess_SetUserRoles_Call) RunAndReturn(run func(int64, []string) error) *DbAccess_SetUserRoles_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// AddFile provides a mock function with given fields: generatedName, filename
-func (_m *DbAccess) AddFile(generatedName string, filename string) error {
-	ret := _m.Called(generatedName, filename)
+// StarFile provides a mock function with given fields: userId, generatedName, at
+func (_m *DbAccess) StarFile(userId int64, generatedName string, at db_access.Time) error {
+	ret := _m.Called(userId, generatedName, at)
 
 	if len(ret) == 0 {
-		panic("no return value specified for AddFile")
+		panic("no return value specified for StarFile")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(generatedName, filename)
+	if rf, ok := ret.Get(0).(func(int64, string, db_access.Time) error); ok {
+		r0 = rf(userId, generatedName, at)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -85,46 +6484,47 @@ func (_m *DbAccess) AddFile(generatedName string, filename string) error {
 	return r0
 }
 
-// DbAccess_AddFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddFile'
-type DbAccess_AddFile_Call struct {
+// DbAccess_StarFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StarFile'
+type DbAccess_StarFile_Call struct {
 	*mock.Call
 }
 
-// AddFile is a helper method to define mock.On call
+// StarFile is a helper method to define mock.On call
+//   - userId int64
 //   - generatedName string
-//   - filename string
-func (_e *DbAccess_Expecter) AddFile(generatedName interface{}, filename interface{}) *DbAccess_AddFile_Call {
-	return &DbAccess_AddFile_Call{Call: _e.mock.On("AddFile", generatedName, filename)}
+//   - at db_access.Time
+func (_e *DbAccess_Expecter) StarFile(userId interface{}, generatedName interface{}, at interface{}) *DbAccess_StarFile_Call {
+	return &DbAccess_StarFile_Call{Call: _e.mock.On("StarFile", userId, generatedName, at)}
 }
 
-func (_c *DbAccess_AddFile_Call) Run(run func(generatedName string, filename string)) *DbAccess_AddFile_Call {
+func (_c *DbAccess_StarFile_Call) Run(run func(userId int64, generatedName string, at db_access.Time)) *DbAccess_StarFile_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string))
+		run(args[0].(int64), args[1].(string), args[2].(db_access.Time))
 	})
 	return _c
 }
 
-func (_c *DbAccess_AddFile_Call) Return(_a0 error) *DbAccess_AddFile_Call {
+func (_c *DbAccess_StarFile_Call) Return(_a0 error) *DbAccess_StarFile_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_AddFile_Call) RunAndReturn(run func(string, string) error) *DbAccess_AddFile_Call {
+func (_c *DbAccess_StarFile_Call) RunAndReturn(run func(int64, string, db_access.Time) error) *DbAccess_StarFile_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// AddUser provides a mock function with given fields: user
-func (_m *DbAccess) AddUser(user *db_access.User) error {
-	ret := _m.Called(user)
+// TouchDevice provides a mock function with given fields: id, at
+func (_m *DbAccess) TouchDevice(id string, at db_access.Time) error {
+	ret := _m.Called(id, at)
 
 	if len(ret) == 0 {
-		panic("no return value specified for AddUser")
+		panic("no return value specified for TouchDevice")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*db_access.User) error); ok {
-		r0 = rf(user)
+	if rf, ok := ret.Get(0).(func(string, db_access.Time) error); ok {
+		r0 = rf(id, at)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -132,212 +6532,323 @@ func (_m *DbAccess) AddUser(user *db_access.User) error {
 	return r0
 }
 
-// DbAccess_AddUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddUser'
-type DbAccess_AddUser_Call struct {
+// DbAccess_TouchDevice_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TouchDevice'
+type DbAccess_TouchDevice_Call struct {
 	*mock.Call
 }
 
-// AddUser is a helper method to define mock.On call
-//   - user *db_access.User
-func (_e *DbAccess_Expecter) AddUser(user interface{}) *DbAccess_AddUser_Call {
-	return &DbAccess_AddUser_Call{Call: _e.mock.On("AddUser", user)}
+// TouchDevice is a helper method to define mock.On call
+//   - id string
+//   - at db_access.Time
+func (_e *DbAccess_Expecter) TouchDevice(id interface{}, at interface{}) *DbAccess_TouchDevice_Call {
+	return &DbAccess_TouchDevice_Call{Call: _e.mock.On("TouchDevice", id, at)}
 }
 
-func (_c *DbAccess_AddUser_Call) Run(run func(user *db_access.User)) *DbAccess_AddUser_Call {
+func (_c *DbAccess_TouchDevice_Call) Run(run func(id string, at db_access.Time)) *DbAccess_TouchDevice_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(*db_access.User))
+		run(args[0].(string), args[1].(db_access.Time))
 	})
 	return _c
 }
 
-func (_c *DbAccess_AddUser_Call) Return(_a0 error) *DbAccess_AddUser_Call {
+func (_c *DbAccess_TouchDevice_Call) Return(_a0 error) *DbAccess_TouchDevice_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_AddUser_Call) RunAndReturn(run func(*db_access.User) error) *DbAccess_AddUser_Call {
+func (_c *DbAccess_TouchDevice_Call) RunAndReturn(run func(string, db_access.Time) error) *DbAccess_TouchDevice_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetDEC provides a mock function with given fields: id
-func (_m *DbAccess) GetDEC(id db_access.DecId) (db_access.DEC, error) {
-	ret := _m.Called(id)
+// UniqueUserNames provides a mock function with no fields
+func (_m *DbAccess) UniqueUserNames() error {
+	ret := _m.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetDEC")
+		panic("no return value specified for UniqueUserNames")
 	}
 
-	var r0 db_access.DEC
-	var r1 error
-	if rf, ok := ret.Get(0).(func(db_access.DecId) (db_access.DEC, error)); ok {
-		return rf(id)
-	}
-	if rf, ok := ret.Get(0).(func(db_access.DecId) db_access.DEC); ok {
-		r0 = rf(id)
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
 	} else {
-		r0 = ret.Get(0).(db_access.DEC)
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(1).(func(db_access.DecId) error); ok {
-		r1 = rf(id)
+	return r0
+}
+
+// DbAccess_UniqueUserNames_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UniqueUserNames'
+type DbAccess_UniqueUserNames_Call struct {
+	*mock.Call
+}
+
+// UniqueUserNames is a helper method to define mock.On call
+func (_e *DbAccess_Expecter) UniqueUserNames() *DbAccess_UniqueUserNames_Call {
+	return &DbAccess_UniqueUserNames_Call{Call: _e.mock.On("UniqueUserNames")}
+}
+
+func (_c *DbAccess_UniqueUserNames_Call) Run(run func()) *DbAccess_UniqueUserNames_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *DbAccess_UniqueUserNames_Call) Return(_a0 error) *DbAccess_UniqueUserNames_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_UniqueUserNames_Call) RunAndReturn(run func() error) *DbAccess_UniqueUserNames_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UnmarkBlobReplicated provides a mock function with given fields: target, blobName
+func (_m *DbAccess) UnmarkBlobReplicated(target string, blobName string) error {
+	ret := _m.Called(target, blobName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnmarkBlobReplicated")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(target, blobName)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
-// DbAccess_GetDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDEC'
-type DbAccess_GetDEC_Call struct {
+// DbAccess_UnmarkBlobReplicated_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnmarkBlobReplicated'
+type DbAccess_UnmarkBlobReplicated_Call struct {
 	*mock.Call
 }
 
-// GetDEC is a helper method to define mock.On call
-//   - id db_access.DecId
-func (_e *DbAccess_Expecter) GetDEC(id interface{}) *DbAccess_GetDEC_Call {
-	return &DbAccess_GetDEC_Call{Call: _e.mock.On("GetDEC", id)}
+// UnmarkBlobReplicated is a helper method to define mock.On call
+//   - target string
+//   - blobName string
+func (_e *DbAccess_Expecter) UnmarkBlobReplicated(target interface{}, blobName interface{}) *DbAccess_UnmarkBlobReplicated_Call {
+	return &DbAccess_UnmarkBlobReplicated_Call{Call: _e.mock.On("UnmarkBlobReplicated", target, blobName)}
 }
 
-func (_c *DbAccess_GetDEC_Call) Run(run func(id db_access.DecId)) *DbAccess_GetDEC_Call {
+func (_c *DbAccess_UnmarkBlobReplicated_Call) Run(run func(target string, blobName string)) *DbAccess_UnmarkBlobReplicated_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(db_access.DecId))
+		run(args[0].(string), args[1].(string))
 	})
 	return _c
 }
 
-func (_c *DbAccess_GetDEC_Call) Return(_a0 db_access.DEC, _a1 error) *DbAccess_GetDEC_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *DbAccess_UnmarkBlobReplicated_Call) Return(_a0 error) *DbAccess_UnmarkBlobReplicated_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_GetDEC_Call) RunAndReturn(run func(db_access.DecId) (db_access.DEC, error)) *DbAccess_GetDEC_Call {
+func (_c *DbAccess_UnmarkBlobReplicated_Call) RunAndReturn(run func(string, string) error) *DbAccess_UnmarkBlobReplicated_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetFile provides a mock function with given fields: generatedName
-func (_m *DbAccess) GetFile(generatedName string) (string, error) {
-	ret := _m.Called(generatedName)
+// UnstarFile provides a mock function with given fields: userId, generatedName
+func (_m *DbAccess) UnstarFile(userId int64, generatedName string) error {
+	ret := _m.Called(userId, generatedName)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetFile")
+		panic("no return value specified for UnstarFile")
 	}
 
-	var r0 string
-	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(generatedName)
-	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(generatedName)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, string) error); ok {
+		r0 = rf(userId, generatedName)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(generatedName)
+	return r0
+}
+
+// DbAccess_UnstarFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnstarFile'
+type DbAccess_UnstarFile_Call struct {
+	*mock.Call
+}
+
+// UnstarFile is a helper method to define mock.On call
+//   - userId int64
+//   - generatedName string
+func (_e *DbAccess_Expecter) UnstarFile(userId interface{}, generatedName interface{}) *DbAccess_UnstarFile_Call {
+	return &DbAccess_UnstarFile_Call{Call: _e.mock.On("UnstarFile", userId, generatedName)}
+}
+
+func (_c *DbAccess_UnstarFile_Call) Run(run func(userId int64, generatedName string)) *DbAccess_UnstarFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *DbAccess_UnstarFile_Call) Return(_a0 error) *DbAccess_UnstarFile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_UnstarFile_Call) RunAndReturn(run func(int64, string) error) *DbAccess_UnstarFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateExport provides a mock function with given fields: export
+func (_m *DbAccess) UpdateExport(export *db_access.Export) error {
+	ret := _m.Called(export)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateExport")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Export) error); ok {
+		r0 = rf(export)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
-// DbAccess_GetFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFile'
-type DbAccess_GetFile_Call struct {
+// DbAccess_UpdateExport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateExport'
+type DbAccess_UpdateExport_Call struct {
 	*mock.Call
 }
 
-// GetFile is a helper method to define mock.On call
-//   - generatedName string
-func (_e *DbAccess_Expecter) GetFile(generatedName interface{}) *DbAccess_GetFile_Call {
-	return &DbAccess_GetFile_Call{Call: _e.mock.On("GetFile", generatedName)}
+// UpdateExport is a helper method to define mock.On call
+//   - export *db_access.Export
+func (_e *DbAccess_Expecter) UpdateExport(export interface{}) *DbAccess_UpdateExport_Call {
+	return &DbAccess_UpdateExport_Call{Call: _e.mock.On("UpdateExport", export)}
 }
 
-func (_c *DbAccess_GetFile_Call) Run(run func(generatedName string)) *DbAccess_GetFile_Call {
+func (_c *DbAccess_UpdateExport_Call) Run(run func(export *db_access.Export)) *DbAccess_UpdateExport_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(*db_access.Export))
 	})
 	return _c
 }
 
-func (_c *DbAccess_GetFile_Call) Return(filename string, err error) *DbAccess_GetFile_Call {
-	_c.Call.Return(filename, err)
+func (_c *DbAccess_UpdateExport_Call) Return(_a0 error) *DbAccess_UpdateExport_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_GetFile_Call) RunAndReturn(run func(string) (string, error)) *DbAccess_GetFile_Call {
+func (_c *DbAccess_UpdateExport_Call) RunAndReturn(run func(*db_access.Export) error) *DbAccess_UpdateExport_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetNewestDEC provides a mock function with no fields
-func (_m *DbAccess) GetNewestDEC() (db_access.DEC, error) {
-	ret := _m.Called()
+// UpdateImport provides a mock function with given fields: imp
+func (_m *DbAccess) UpdateImport(imp *db_access.Import) error {
+	ret := _m.Called(imp)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetNewestDEC")
+		panic("no return value specified for UpdateImport")
 	}
 
-	var r0 db_access.DEC
-	var r1 error
-	if rf, ok := ret.Get(0).(func() (db_access.DEC, error)); ok {
-		return rf()
-	}
-	if rf, ok := ret.Get(0).(func() db_access.DEC); ok {
-		r0 = rf()
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Import) error); ok {
+		r0 = rf(imp)
 	} else {
-		r0 = ret.Get(0).(db_access.DEC)
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	return r0
+}
+
+// DbAccess_UpdateImport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateImport'
+type DbAccess_UpdateImport_Call struct {
+	*mock.Call
+}
+
+// UpdateImport is a helper method to define mock.On call
+//   - imp *db_access.Import
+func (_e *DbAccess_Expecter) UpdateImport(imp interface{}) *DbAccess_UpdateImport_Call {
+	return &DbAccess_UpdateImport_Call{Call: _e.mock.On("UpdateImport", imp)}
+}
+
+func (_c *DbAccess_UpdateImport_Call) Run(run func(imp *db_access.Import)) *DbAccess_UpdateImport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*db_access.Import))
+	})
+	return _c
+}
+
+func (_c *DbAccess_UpdateImport_Call) Return(_a0 error) *DbAccess_UpdateImport_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DbAccess_UpdateImport_Call) RunAndReturn(run func(*db_access.Import) error) *DbAccess_UpdateImport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateJob provides a mock function with given fields: job
+func (_m *DbAccess) UpdateJob(job *db_access.Job) error {
+	ret := _m.Called(job)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*db_access.Job) error); ok {
+		r0 = rf(job)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
-// DbAccess_GetNewestDEC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNewestDEC'
-type DbAccess_GetNewestDEC_Call struct {
+// DbAccess_UpdateJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateJob'
+type DbAccess_UpdateJob_Call struct {
 	*mock.Call
 }
 
-// GetNewestDEC is a helper method to define mock.On call
-func (_e *DbAccess_Expecter) GetNewestDEC() *DbAccess_GetNewestDEC_Call {
-	return &DbAccess_GetNewestDEC_Call{Call: _e.mock.On("GetNewestDEC")}
+// UpdateJob is a helper method to define mock.On call
+//   - job *db_access.Job
+func (_e *DbAccess_Expecter) UpdateJob(job interface{}) *DbAccess_UpdateJob_Call {
+	return &DbAccess_UpdateJob_Call{Call: _e.mock.On("UpdateJob", job)}
 }
 
-func (_c *DbAccess_GetNewestDEC_Call) Run(run func()) *DbAccess_GetNewestDEC_Call {
+func (_c *DbAccess_UpdateJob_Call) Run(run func(job *db_access.Job)) *DbAccess_UpdateJob_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		run(args[0].(*db_access.Job))
 	})
 	return _c
 }
 
-func (_c *DbAccess_GetNewestDEC_Call) Return(_a0 db_access.DEC, _a1 error) *DbAccess_GetNewestDEC_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *DbAccess_UpdateJob_Call) Return(_a0 error) *DbAccess_UpdateJob_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_GetNewestDEC_Call) RunAndReturn(run func() (db_access.DEC, error)) *DbAccess_GetNewestDEC_Call {
+func (_c *DbAccess_UpdateJob_Call) RunAndReturn(run func(*db_access.Job) error) *DbAccess_UpdateJob_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetUser provides a mock function with given fields: user
-func (_m *DbAccess) GetUser(user *db_access.User) error {
-	ret := _m.Called(user)
+// UpdateMigration provides a mock function with given fields: m
+func (_m *DbAccess) UpdateMigration(m *db_access.Migration) error {
+	ret := _m.Called(m)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUser")
+		panic("no return value specified for UpdateMigration")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*db_access.User) error); ok {
-		r0 = rf(user)
+	if rf, ok := ret.Get(0).(func(*db_access.Migration) error); ok {
+		r0 = rf(m)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -345,45 +6856,45 @@ func (_m *DbAccess) GetUser(user *db_access.User) error {
 	return r0
 }
 
-// DbAccess_GetUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUser'
-type DbAccess_GetUser_Call struct {
+// DbAccess_UpdateMigration_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateMigration'
+type DbAccess_UpdateMigration_Call struct {
 	*mock.Call
 }
 
-// GetUser is a helper method to define mock.On call
-//   - user *db_access.User
-func (_e *DbAccess_Expecter) GetUser(user interface{}) *DbAccess_GetUser_Call {
-	return &DbAccess_GetUser_Call{Call: _e.mock.On("GetUser", user)}
+// UpdateMigration is a helper method to define mock.On call
+//   - m *db_access.Migration
+func (_e *DbAccess_Expecter) UpdateMigration(m interface{}) *DbAccess_UpdateMigration_Call {
+	return &DbAccess_UpdateMigration_Call{Call: _e.mock.On("UpdateMigration", m)}
 }
 
-func (_c *DbAccess_GetUser_Call) Run(run func(user *db_access.User)) *DbAccess_GetUser_Call {
+func (_c *DbAccess_UpdateMigration_Call) Run(run func(m *db_access.Migration)) *DbAccess_UpdateMigration_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(*db_access.User))
+		run(args[0].(*db_access.Migration))
 	})
 	return _c
 }
 
-func (_c *DbAccess_GetUser_Call) Return(_a0 error) *DbAccess_GetUser_Call {
+func (_c *DbAccess_UpdateMigration_Call) Return(_a0 error) *DbAccess_UpdateMigration_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_GetUser_Call) RunAndReturn(run func(*db_access.User) error) *DbAccess_GetUser_Call {
+func (_c *DbAccess_UpdateMigration_Call) RunAndReturn(run func(*db_access.Migration) error) *DbAccess_UpdateMigration_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// RemoveFile provides a mock function with given fields: generatedName
-func (_m *DbAccess) RemoveFile(generatedName string) error {
-	ret := _m.Called(generatedName)
+// WithTx provides a mock function with given fields: ctx, fn
+func (_m *DbAccess) WithTx(ctx context.Context, fn func(db_access.DbAccess) error) error {
+	ret := _m.Called(ctx, fn)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RemoveFile")
+		panic("no return value specified for WithTx")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(generatedName)
+	if rf, ok := ret.Get(0).(func(context.Context, func(db_access.DbAccess) error) error); ok {
+		r0 = rf(ctx, fn)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -391,30 +6902,31 @@ func (_m *DbAccess) RemoveFile(generatedName string) error {
 	return r0
 }
 
-// DbAccess_RemoveFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveFile'
-type DbAccess_RemoveFile_Call struct {
+// DbAccess_WithTx_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WithTx'
+type DbAccess_WithTx_Call struct {
 	*mock.Call
 }
 
-// RemoveFile is a helper method to define mock.On call
-//   - generatedName string
-func (_e *DbAccess_Expecter) RemoveFile(generatedName interface{}) *DbAccess_RemoveFile_Call {
-	return &DbAccess_RemoveFile_Call{Call: _e.mock.On("RemoveFile", generatedName)}
+// WithTx is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(db_access.DbAccess) error
+func (_e *DbAccess_Expecter) WithTx(ctx interface{}, fn interface{}) *DbAccess_WithTx_Call {
+	return &DbAccess_WithTx_Call{Call: _e.mock.On("WithTx", ctx, fn)}
 }
 
-func (_c *DbAccess_RemoveFile_Call) Run(run func(generatedName string)) *DbAccess_RemoveFile_Call {
+func (_c *DbAccess_WithTx_Call) Run(run func(ctx context.Context, fn func(db_access.DbAccess) error)) *DbAccess_WithTx_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(func(db_access.DbAccess) error))
 	})
 	return _c
 }
 
-func (_c *DbAccess_RemoveFile_Call) Return(_a0 error) *DbAccess_RemoveFile_Call {
+func (_c *DbAccess_WithTx_Call) Return(_a0 error) *DbAccess_WithTx_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *DbAccess_RemoveFile_Call) RunAndReturn(run func(string) error) *DbAccess_RemoveFile_Call {
+func (_c *DbAccess_WithTx_Call) RunAndReturn(run func(context.Context, func(db_access.DbAccess) error) error) *DbAccess_WithTx_Call {
 	_c.Call.Return(run)
 	return _c
 }
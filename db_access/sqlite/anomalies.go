@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (db *SqliteDb) createAnomalies() error {
+	const op = "db-access.sqlite.createAnomalies"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create alertThresholds table", `
+		CREATE TABLE IF NOT EXISTS alertThresholds(
+			userId INTEGER PRIMARY KEY REFERENCES users(id),
+			downloadFactor REAL,
+			minDownloadBytes INTEGER,
+			deletesPerHour INTEGER,
+			newCountries INTEGER
+		);`},
+		{"create loginCountries table", `
+		CREATE TABLE IF NOT EXISTS loginCountries(
+			userId INTEGER NOT NULL REFERENCES users(id),
+			country TEXT NOT NULL,
+			firstSeen INTEGER NOT NULL,
+			PRIMARY KEY(userId, country)
+		) WITHOUT ROWID;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) SetAlertThresholds(t db_access.AlertThresholds) error {
+	const op = "db-access.sqlite.SetAlertThresholds"
+
+	_, err := db.Exec(
+		`INSERT OR REPLACE INTO alertThresholds(userId, downloadFactor, minDownloadBytes, deletesPerHour, newCountries)
+		VALUES (?,?,?,?,?)`,
+		t.UserId, t.DownloadFactor, t.MinDownloadBytes, t.DeletesPerHour, t.NewCountries,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) DeleteAlertThresholds(userId int64) error {
+	const op = "db-access.sqlite.DeleteAlertThresholds"
+
+	res, err := db.Exec(`DELETE FROM alertThresholds WHERE userId = ?`, userId)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "alertThresholds"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetAlertThresholds(userId int64) (db_access.AlertThresholds, error) {
+	const op = "db-access.sqlite.GetAlertThresholds"
+
+	var downloadFactor sql.NullFloat64
+	var minDownloadBytes, deletesPerHour sql.NullInt64
+	var newCountries sql.NullBool
+	err := db.QueryRow(
+		`SELECT downloadFactor, minDownloadBytes, deletesPerHour, newCountries FROM alertThresholds WHERE userId = ?`,
+		userId,
+	).Scan(&downloadFactor, &minDownloadBytes, &deletesPerHour, &newCountries)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.AlertThresholds{}, db_access.NoRowsError{Table: "alertThresholds"}
+	} else if err != nil {
+		return db_access.AlertThresholds{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	t := db_access.AlertThresholds{UserId: userId}
+	if downloadFactor.Valid {
+		t.DownloadFactor = &downloadFactor.Float64
+	}
+	if minDownloadBytes.Valid {
+		t.MinDownloadBytes = &minDownloadBytes.Int64
+	}
+	if deletesPerHour.Valid {
+		t.DeletesPerHour = &deletesPerHour.Int64
+	}
+	if newCountries.Valid {
+		t.NewCountries = &newCountries.Bool
+	}
+
+	return t, nil
+}
+
+func (db *SqliteDb) AddLoginCountry(userId int64, country string, at db_access.Time) (bool, error) {
+	const op = "db-access.sqlite.AddLoginCountry"
+
+	var isNew bool
+	err := db.inTx(context.Background(), func(tx *SqliteDb) error {
+		var known, seen int64
+		err := tx.QueryRow(
+			`SELECT count(*), count(CASE WHEN country = ? THEN 1 END) FROM loginCountries WHERE userId = ?`,
+			country,
+			userId,
+		).Scan(&known, &seen)
+		if err != nil {
+			return fmt.Errorf("%s: tx.QueryRow: %w", op, err)
+		}
+
+		if seen > 0 {
+			isNew = false
+			return nil
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO loginCountries(userId, country, firstSeen) VALUES (?,?,?)`,
+			userId, country, at,
+		); err != nil {
+			return fmt.Errorf("%s: tx.Exec: %w", op, err)
+		}
+
+		// the first country of a user is where they usually are
+		isNew = known > 0
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return isNew, nil
+}
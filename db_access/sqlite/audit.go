@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"fmt"
+)
+
+func (db *SqliteDb) AddAuditEvent(e *db_access.AuditEvent) error {
+	const op = "db-access.sqlite.AddAuditEvent"
+
+	res, err := db.Exec(
+		`INSERT INTO audit(at, userId, method, path, status, remoteAddr, requestId) values(?,?,?,?,?,?,?)`,
+		e.At,
+		e.UserId,
+		e.Method,
+		e.Path,
+		e.Status,
+		e.RemoteAddr,
+		e.RequestId,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	e.Id, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("%s: res.LastInsertId: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetAuditEvents(from db_access.Time, to db_access.Time) ([]db_access.AuditEvent, error) {
+	const op = "db-access.sqlite.GetAuditEvents"
+
+	rows, err := db.Query(
+		`SELECT id, at, userId, method, path, status, remoteAddr, requestId FROM audit
+		WHERE at >= ? AND at < ?
+		ORDER BY at, id`,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	events := make([]db_access.AuditEvent, 0)
+	for rows.Next() {
+		var e db_access.AuditEvent
+		if err := rows.Scan(&e.Id, &e.At, &e.UserId, &e.Method, &e.Path, &e.Status, &e.RemoteAddr, &e.RequestId); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return events, nil
+}
+
+func (db *SqliteDb) RemoveAuditEventsBefore(t db_access.Time) (int64, error) {
+	const op = "db-access.sqlite.RemoveAuditEventsBefore"
+
+	res, err := db.Exec(`DELETE FROM audit WHERE at < ?`, t)
+	if err != nil {
+		return 0, fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+
+	return n, nil
+}
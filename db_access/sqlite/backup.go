@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// number of pages copied per backup step; stepping lets writers make progress
+// between steps instead of being blocked for the whole backup
+const backupPagesPerStep = 1024
+
+// Backup copies database at srcPath into destPath using the sqlite online backup API.
+// It is safe to run while the server is using the source database.
+func Backup(ctx context.Context, srcPath string, destPath string) error {
+	const op = "db-access.sqlite.Backup"
+
+	src, err := sql.Open("sqlite3", srcPath)
+	if err != nil {
+		return fmt.Errorf("%s: open source: %w", op, err)
+	}
+	defer src.Close()
+
+	dest, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return fmt.Errorf("%s: open destination: %w", op, err)
+	}
+	defer dest.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: src.Conn: %w", op, err)
+	}
+	defer srcConn.Close()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: dest.Conn: %w", op, err)
+	}
+	defer destConn.Close()
+
+	err = destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			destSqlite, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("destination is not a sqlite connection")
+			}
+
+			srcSqlite, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("source is not a sqlite connection")
+			}
+
+			backup, err := destSqlite.Backup("main", srcSqlite, "main")
+			if err != nil {
+				return fmt.Errorf("start backup: %w", err)
+			}
+
+			for {
+				done, err := backup.Step(backupPagesPerStep)
+				if err != nil {
+					backup.Finish()
+					return fmt.Errorf("backup step: %w", err)
+				}
+
+				if done {
+					break
+				}
+
+				if err := ctx.Err(); err != nil {
+					backup.Finish()
+					return err
+				}
+			}
+
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
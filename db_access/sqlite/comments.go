@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// createComments sets up the comments on files; comments go with their files
+func (db *SqliteDb) createComments() error {
+	const op = "db-access.sqlite.createComments"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create comments table", `
+		CREATE TABLE IF NOT EXISTS comments(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			generatedName TEXT NOT NULL,
+			authorId INTEGER NOT NULL,
+			replyTo INTEGER,
+			body TEXT,
+			createdAt INTEGER NOT NULL
+		);`},
+		{"create comments file index", `CREATE INDEX IF NOT EXISTS idx_comments_generatedName ON comments(generatedName);`},
+		{"create delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_comments_delete AFTER DELETE ON files
+		BEGIN
+			DELETE FROM comments WHERE generatedName = OLD.generatedName;
+		END;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+const commentColumns = `id, generatedName, authorId, replyTo, body, createdAt`
+
+func scanComment(row interface{ Scan(dest ...any) error }) (db_access.Comment, error) {
+	var c db_access.Comment
+	var replyTo sql.NullInt64
+	var body sql.NullString
+	if err := row.Scan(&c.Id, &c.GeneratedName, &c.AuthorId, &replyTo, &body, &c.CreatedAt); err != nil {
+		return db_access.Comment{}, err
+	}
+
+	c.ReplyTo, c.Body = replyTo.Int64, body.String
+	return c, nil
+}
+
+func (db *SqliteDb) AddComment(c *db_access.Comment) error {
+	const op = "db-access.sqlite.AddComment"
+
+	res, err := db.Exec(
+		`INSERT INTO comments(generatedName, authorId, replyTo, body, createdAt) VALUES (?,?,?,?,?)`,
+		c.GeneratedName,
+		c.AuthorId,
+		sql.NullInt64{Int64: c.ReplyTo, Valid: c.ReplyTo != 0},
+		sql.NullString{String: c.Body, Valid: c.Body != ""},
+		c.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	c.Id, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("%s: res.LastInsertId: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetComment(id int64) (db_access.Comment, error) {
+	const op = "db-access.sqlite.GetComment"
+
+	c, err := scanComment(db.QueryRow(`SELECT `+commentColumns+` FROM comments WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.Comment{}, db_access.NoRowsError{Table: "comments"}
+	} else if err != nil {
+		return db_access.Comment{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return c, nil
+}
+
+func (db *SqliteDb) GetComments(generatedName string) ([]db_access.Comment, error) {
+	const op = "db-access.sqlite.GetComments"
+
+	rows, err := db.Query(`SELECT `+commentColumns+` FROM comments WHERE generatedName = ? ORDER BY id`, generatedName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	comments := make([]db_access.Comment, 0)
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return comments, nil
+}
+
+func (db *SqliteDb) RemoveComment(id int64) error {
+	const op = "db-access.sqlite.RemoveComment"
+
+	res, err := db.Exec(
+		`WITH RECURSIVE thread(id) AS (
+			SELECT ?
+			UNION ALL
+			SELECT comments.id FROM comments JOIN thread ON comments.replyTo = thread.id
+		)
+		DELETE FROM comments WHERE id IN thread`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "comments"}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// createContentIndex sets up the index of file contents; terms go with their files
+func (db *SqliteDb) createContentIndex() error {
+	const op = "db-access.sqlite.createContentIndex"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create contentTerms table", `
+		CREATE TABLE IF NOT EXISTS contentTerms(
+			ownerId INTEGER NOT NULL,
+			term TEXT NOT NULL,
+			generatedName TEXT NOT NULL,
+			PRIMARY KEY(ownerId, term, generatedName)
+		) WITHOUT ROWID;`},
+		{"create contentTerms file index", `CREATE INDEX IF NOT EXISTS idx_contentTerms_generatedName ON contentTerms(generatedName);`},
+		{"create indexedFiles table", `
+		CREATE TABLE IF NOT EXISTS indexedFiles(
+			generatedName TEXT PRIMARY KEY,
+			terms INTEGER NOT NULL
+		);`},
+		{"create delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_content_delete AFTER DELETE ON files
+		BEGIN
+			DELETE FROM contentTerms WHERE generatedName = OLD.generatedName;
+			DELETE FROM indexedFiles WHERE generatedName = OLD.generatedName;
+		END;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetUnindexedFiles(after string, limit int) ([]db_access.File, error) {
+	const op = "db-access.sqlite.GetUnindexedFiles"
+
+	rows, err := db.Query(
+		`SELECT `+fileColumns+` FROM files
+		WHERE generatedName > ? AND generatedName NOT IN (SELECT generatedName FROM indexedFiles)
+		ORDER BY generatedName LIMIT ?`,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	files := make([]db_access.File, 0)
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return files, nil
+}
+
+func (db *SqliteDb) SetFileTerms(generatedName string, ownerId int64, terms []string) error {
+	const op = "db-access.sqlite.SetFileTerms"
+
+	return db.inTx(context.Background(), func(tx *SqliteDb) error {
+		// terms of a file deleted meanwhile would never go
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM files WHERE generatedName = ?)`, generatedName).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("%s: check file: %w", op, err)
+		} else if !exists {
+			return db_access.NoRowsError{Table: "files"}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM contentTerms WHERE generatedName = ?`, generatedName); err != nil {
+			return fmt.Errorf("%s: delete terms: %w", op, err)
+		}
+
+		stmt, err := tx.Prepare(`INSERT OR IGNORE INTO contentTerms(ownerId, term, generatedName) VALUES (?,?,?)`)
+		if err != nil {
+			return fmt.Errorf("%s: tx.Prepare: %w", op, err)
+		}
+		defer stmt.Close()
+
+		for _, term := range terms {
+			if _, err := stmt.Exec(ownerId, term, generatedName); err != nil {
+				return fmt.Errorf("%s: insert term: %w", op, err)
+			}
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO indexedFiles(generatedName, terms) VALUES (?,?)
+			ON CONFLICT(generatedName) DO UPDATE SET terms = excluded.terms`,
+			generatedName,
+			len(terms),
+		)
+		if err != nil {
+			return fmt.Errorf("%s: mark indexed: %w", op, err)
+		}
+
+		return nil
+	})
+}
+
+func (db *SqliteDb) GetFilesByTerms(ownerId int64, terms []string) ([]db_access.File, error) {
+	const op = "db-access.sqlite.GetFilesByTerms"
+
+	if len(terms) == 0 {
+		return make([]db_access.File, 0), nil
+	}
+
+	// every term has to be counted once for the match to need all of them
+	terms = slices.Compact(slices.Sorted(slices.Values(terms)))
+	args := []any{ownerId}
+	for _, term := range terms {
+		args = append(args, term)
+	}
+	args = append(args, len(terms), ownerId)
+
+	rows, err := db.Query(
+		`SELECT `+fileColumns+` FROM files WHERE generatedName IN (
+			SELECT generatedName FROM contentTerms
+			WHERE ownerId = ? AND term IN (?`+strings.Repeat(",?", len(terms)-1)+`)
+			GROUP BY generatedName
+			HAVING COUNT(*) = ?
+		) AND ownerId = ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	files := make([]db_access.File, 0)
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return files, nil
+}
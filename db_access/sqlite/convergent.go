@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// createConvergentBlobs sets up the blobs of convergent encryption; the row of a blob goes with the last file referencing it
+func (db *SqliteDb) createConvergentBlobs() error {
+	const op = "db-access.sqlite.createConvergentBlobs"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create convergentBlobs table", `
+		CREATE TABLE IF NOT EXISTS convergentBlobs(
+			contentId TEXT PRIMARY KEY,
+			blobName TEXT NOT NULL UNIQUE,
+			key TEXT NOT NULL
+		);`},
+		{"create delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_convergentBlobs_delete AFTER DELETE ON files
+		WHEN NOT EXISTS (
+			SELECT 1 FROM files WHERE COALESCE(blobName, generatedName) = COALESCE(OLD.blobName, OLD.generatedName)
+		)
+		BEGIN
+			DELETE FROM convergentBlobs WHERE blobName = COALESCE(OLD.blobName, OLD.generatedName);
+		END;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetConvergentBlob(contentId string) (db_access.ConvergentBlob, error) {
+	const op = "db-access.sqlite.GetConvergentBlob"
+
+	blob := db_access.ConvergentBlob{ContentId: contentId}
+	err := db.QueryRow(`SELECT blobName, key FROM convergentBlobs WHERE contentId = ?`, contentId).Scan(&blob.BlobName, &blob.Key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.ConvergentBlob{}, db_access.NoRowsError{Table: "convergentBlobs"}
+	} else if err != nil {
+		return db_access.ConvergentBlob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return blob, nil
+}
+
+func (db *SqliteDb) AddConvergentBlob(blob db_access.ConvergentBlob) error {
+	const op = "db-access.sqlite.AddConvergentBlob"
+
+	_, err := db.Execute(
+		`INSERT INTO convergentBlobs(contentId, blobName, key) VALUES (?,?,?)`,
+		blob.ContentId,
+		blob.BlobName,
+		blob.Key,
+	)
+	if err != nil {
+		return uniqueConstraintError(op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetContentKey(contentId string) (string, error) {
+	const op = "db-access.sqlite.GetContentKey"
+
+	blob, err := db.GetConvergentBlob(contentId)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return blob.Key, nil
+}
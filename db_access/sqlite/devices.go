@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// createDevices sets up the devices users registered; they go with their user
+func (db *SqliteDb) createDevices() error {
+	const op = "db-access.sqlite.createDevices"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create devices table", `
+		CREATE TABLE IF NOT EXISTS devices(
+			id TEXT PRIMARY KEY,
+			ownerId INTEGER NOT NULL REFERENCES users(id),
+			name TEXT NOT NULL,
+			platform TEXT NOT NULL,
+			refreshHash BLOB NOT NULL UNIQUE,
+			createdAt INTEGER NOT NULL,
+			lastUsed INTEGER NOT NULL
+		);`},
+		{"create owner index", `CREATE INDEX IF NOT EXISTS idx_devices_ownerId ON devices(ownerId, createdAt);`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+const deviceColumns = `id, ownerId, name, platform, refreshHash, createdAt, lastUsed`
+
+func scanDevice(row interface{ Scan(dest ...any) error }) (db_access.Device, error) {
+	var d db_access.Device
+	err := row.Scan(&d.Id, &d.OwnerId, &d.Name, &d.Platform, &d.RefreshHash, &d.CreatedAt, &d.LastUsed)
+	return d, err
+}
+
+func (db *SqliteDb) AddDevice(device db_access.Device) error {
+	const op = "db-access.sqlite.AddDevice"
+
+	_, err := db.Exec(
+		`INSERT INTO devices(`+deviceColumns+`) VALUES (?,?,?,?,?,?,?)`,
+		device.Id,
+		device.OwnerId,
+		device.Name,
+		device.Platform,
+		device.RefreshHash,
+		device.CreatedAt,
+		device.LastUsed,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetDevice(id string) (db_access.Device, error) {
+	const op = "db-access.sqlite.GetDevice"
+
+	device, err := scanDevice(db.QueryRow(`SELECT `+deviceColumns+` FROM devices WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.Device{}, db_access.NoRowsError{Table: "devices"}
+	} else if err != nil {
+		return db_access.Device{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return device, nil
+}
+
+func (db *SqliteDb) GetDeviceByRefreshHash(hash []byte) (db_access.Device, error) {
+	const op = "db-access.sqlite.GetDeviceByRefreshHash"
+
+	device, err := scanDevice(db.QueryRow(`SELECT `+deviceColumns+` FROM devices WHERE refreshHash = ?`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.Device{}, db_access.NoRowsError{Table: "devices"}
+	} else if err != nil {
+		return db_access.Device{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return device, nil
+}
+
+func (db *SqliteDb) GetDevices(ownerId int64) ([]db_access.Device, error) {
+	const op = "db-access.sqlite.GetDevices"
+
+	rows, err := db.Query(`SELECT `+deviceColumns+` FROM devices WHERE ownerId = ? ORDER BY createdAt DESC, rowid DESC`, ownerId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	devices := make([]db_access.Device, 0)
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return devices, nil
+}
+
+func (db *SqliteDb) TouchDevice(id string, at db_access.Time) error {
+	const op = "db-access.sqlite.TouchDevice"
+
+	_, err := db.Exec(`UPDATE devices SET lastUsed = ? WHERE id = ?`, at, id)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) RemoveDevice(ownerId int64, id string) error {
+	const op = "db-access.sqlite.RemoveDevice"
+
+	res, err := db.Exec(`DELETE FROM devices WHERE ownerId = ? AND id = ?`, ownerId, id)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+	if affected == 0 {
+		return db_access.NoRowsError{Table: "devices"}
+	}
+
+	return nil
+}
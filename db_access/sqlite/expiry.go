@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"fmt"
+)
+
+func (db *SqliteDb) SetFileExpiry(generatedName string, expiresAt db_access.Time) error {
+	const op = "db-access.sqlite.SetFileExpiry"
+
+	res, err := db.Exec(`UPDATE files SET expiresAt = ?, expiryNotified = 0 WHERE generatedName = ?`, expiresAt, generatedName)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "files"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) queryFiles(op string, query string, args ...any) ([]db_access.File, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	files := make([]db_access.File, 0)
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return files, nil
+}
+
+func (db *SqliteDb) GetExpiredFiles(now db_access.Time) ([]db_access.File, error) {
+	return db.queryFiles(
+		"db-access.sqlite.GetExpiredFiles",
+		`SELECT `+fileColumns+` FROM files WHERE expiresAt IS NOT NULL AND expiresAt <= ?`,
+		now,
+	)
+}
+
+func (db *SqliteDb) GetFilesToNotify(t db_access.Time) ([]db_access.File, error) {
+	return db.queryFiles(
+		"db-access.sqlite.GetFilesToNotify",
+		`SELECT `+fileColumns+` FROM files WHERE expiresAt IS NOT NULL AND expiresAt <= ? AND expiryNotified = 0`,
+		t,
+	)
+}
+
+func (db *SqliteDb) MarkExpiryNotified(generatedName string) error {
+	const op = "db-access.sqlite.MarkExpiryNotified"
+
+	_, err := db.Exec(`UPDATE files SET expiryNotified = 1 WHERE generatedName = ?`, generatedName)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) AddNotification(n *db_access.Notification) error {
+	const op = "db-access.sqlite.AddNotification"
+
+	res, err := db.Exec(
+		`INSERT INTO notifications(ownerId, kind, fileId, fileName, at, creationTime) values(?,?,?,?,?,?)`,
+		n.OwnerId,
+		n.Kind,
+		n.FileId,
+		n.FileName,
+		n.At,
+		n.CreationTime,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	n.Id, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("%s: res.LastInsertId: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetNotifications(ownerId int64) ([]db_access.Notification, error) {
+	const op = "db-access.sqlite.GetNotifications"
+
+	rows, err := db.Query(
+		`SELECT id, ownerId, kind, fileId, fileName, at, creationTime FROM notifications WHERE ownerId = ? ORDER BY id DESC`,
+		ownerId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	notifications := make([]db_access.Notification, 0)
+	for rows.Next() {
+		var n db_access.Notification
+		if err := rows.Scan(&n.Id, &n.OwnerId, &n.Kind, &n.FileId, &n.FileName, &n.At, &n.CreationTime); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return notifications, nil
+}
+
+func (db *SqliteDb) RemoveNotificationsBefore(t db_access.Time) error {
+	const op = "db-access.sqlite.RemoveNotificationsBefore"
+
+	_, err := db.Exec(`DELETE FROM notifications WHERE creationTime < ?`, t)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func (db *SqliteDb) AddExport(export *db_access.Export) error {
+	const op = "db-access.sqlite.AddExport"
+
+	_, err := db.Exec(
+		`INSERT INTO exports(id, ownerId, status, error, creationTime, expiresAt) values(?,?,?,?,?,?)`,
+		export.Id,
+		export.OwnerId,
+		export.Status,
+		export.Error,
+		export.CreationTime,
+		export.ExpiresAt,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return db_access.UniqueConstraintError{Table: "exports", Column: "id"}
+	} else if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) UpdateExport(export *db_access.Export) error {
+	const op = "db-access.sqlite.UpdateExport"
+
+	res, err := db.Exec(
+		`UPDATE exports SET status = ?, error = ?, expiresAt = ? WHERE id = ?`,
+		export.Status,
+		export.Error,
+		export.ExpiresAt,
+		export.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+
+	if n == 0 {
+		return db_access.NoRowsError{Table: "exports"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetExport(id string) (db_access.Export, error) {
+	const op = "db-access.sqlite.GetExport"
+
+	var export db_access.Export
+	err := db.QueryRow(
+		`SELECT id, ownerId, status, error, creationTime, expiresAt FROM exports WHERE id = ?`,
+		id,
+	).Scan(&export.Id, &export.OwnerId, &export.Status, &export.Error, &export.CreationTime, &export.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.Export{}, db_access.NoRowsError{Table: "exports"}
+	} else if err != nil {
+		return db_access.Export{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return export, nil
+}
+
+func (db *SqliteDb) GetExpiredExports(now db_access.Time) ([]db_access.Export, error) {
+	const op = "db-access.sqlite.GetExpiredExports"
+
+	exports, err := db.queryExports(`WHERE expiresAt IS NOT NULL AND expiresAt <= ?`, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exports, nil
+}
+
+func (db *SqliteDb) GetUserExports(ownerId int64) ([]db_access.Export, error) {
+	const op = "db-access.sqlite.GetUserExports"
+
+	exports, err := db.queryExports(`WHERE ownerId = ?`, ownerId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exports, nil
+}
+
+// queryExports returns the exports the where clause selects
+func (db *SqliteDb) queryExports(where string, args ...any) ([]db_access.Export, error) {
+	rows, err := db.Query(`SELECT id, ownerId, status, error, creationTime, expiresAt FROM exports `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+
+	exports := make([]db_access.Export, 0)
+	for rows.Next() {
+		var export db_access.Export
+		err := rows.Scan(&export.Id, &export.OwnerId, &export.Status, &export.Error, &export.CreationTime, &export.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		exports = append(exports, export)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return exports, nil
+}
+
+func (db *SqliteDb) RemoveExport(id string) error {
+	const op = "db-access.sqlite.RemoveExport"
+
+	_, err := db.Exec(`DELETE FROM exports WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) FailUnfinishedExports(reason string, expiresAt db_access.Time) error {
+	const op = "db-access.sqlite.FailUnfinishedExports"
+
+	_, err := db.Exec(
+		`UPDATE exports SET status = ?, error = ?, expiresAt = ? WHERE status IN (?, ?)`,
+		db_access.ExportFailed,
+		reason,
+		expiresAt,
+		db_access.ExportPending,
+		db_access.ExportRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (db *SqliteDb) DeleteFile(generatedName string) (blob db_access.Blob, orphaned bool, err error) {
+	const op = "db-access.sqlite.DeleteFile"
+
+	err = db.inTx(context.Background(), func(tx *SqliteDb) error {
+		err := tx.QueryRow(
+			`SELECT COALESCE(blobName, generatedName), backend, size FROM files WHERE generatedName = ?`,
+			generatedName,
+		).Scan(&blob.Name, &blob.Backend, &blob.Size)
+		if errors.Is(err, sql.ErrNoRows) {
+			return db_access.NoRowsError{Table: "files"}
+		} else if err != nil {
+			return fmt.Errorf("%s: select blob: %w", op, err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM fileTags WHERE generatedName = ?`, generatedName); err != nil {
+			return fmt.Errorf("%s: delete tags: %w", op, err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM files WHERE generatedName = ?`, generatedName); err != nil {
+			return fmt.Errorf("%s: delete file: %w", op, err)
+		}
+
+		var refs int64
+		err = tx.QueryRow(`SELECT COUNT(*) FROM files WHERE COALESCE(blobName, generatedName) = ?`, blob.Name).Scan(&refs)
+		if err != nil {
+			return fmt.Errorf("%s: count blob refs: %w", op, err)
+		}
+		orphaned = refs == 0
+
+		return nil
+	})
+	if err != nil {
+		return db_access.Blob{}, false, err
+	}
+
+	return blob, orphaned, nil
+}
+
+func (db *SqliteDb) AddFileTags(generatedName string, tags []string) error {
+	const op = "db-access.sqlite.AddFileTags"
+
+	return db.inTx(context.Background(), func(tx *SqliteDb) error {
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM files WHERE generatedName = ?)`, generatedName).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("%s: check file: %w", op, err)
+		} else if !exists {
+			return db_access.NoRowsError{Table: "files"}
+		}
+
+		for _, tag := range tags {
+			_, err := tx.Exec(`INSERT OR IGNORE INTO fileTags(generatedName, tag) values(?,?)`, generatedName, tag)
+			if err != nil {
+				return fmt.Errorf("%s: insert tag: %w", op, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *SqliteDb) GetFileTags(generatedName string) ([]string, error) {
+	return db.queryStrings("db-access.sqlite.GetFileTags", `SELECT tag FROM fileTags WHERE generatedName = ? ORDER BY tag`, generatedName)
+}
+
+// queryStrings returns the single text column of the rows of the query
+func (db *SqliteDb) queryStrings(op string, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return values, nil
+}
+
+func (db *SqliteDb) RecordFileAccesses(accesses []db_access.FileAccess) error {
+	const op = "db-access.sqlite.RecordFileAccesses"
+
+	return db.inTx(context.Background(), func(tx *SqliteDb) error {
+		stmt, err := tx.Prepare(
+			`UPDATE files SET lastAccess = MAX(COALESCE(lastAccess, 0), ?), downloadCount = downloadCount + ?
+			WHERE generatedName = ?`,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: tx.Prepare: %w", op, err)
+		}
+		defer stmt.Close()
+
+		for _, access := range accesses {
+			if _, err := stmt.Exec(access.At, access.Count, access.GeneratedName); err != nil {
+				return fmt.Errorf("%s: stmt.Exec: %w", op, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *SqliteDb) GetRecentFiles(ownerId int64, limit int) ([]db_access.File, error) {
+	return db.queryFiles(
+		"db-access.sqlite.GetRecentFiles",
+		`SELECT `+fileColumns+` FROM files WHERE ownerId = ? AND lastAccess IS NOT NULL ORDER BY lastAccess DESC LIMIT ?`,
+		ownerId,
+		limit,
+	)
+}
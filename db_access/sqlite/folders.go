@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"fmt"
+)
+
+const folderColumns = `id, ownerId, parentId, name, nameIndex`
+
+// createFolders sets up the folders of users and puts every file in the root until it is moved
+func (db *SqliteDb) createFolders() error {
+	const op = "db-access.sqlite.createFolders"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create folders table", `
+		CREATE TABLE IF NOT EXISTS folders(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ownerId INTEGER NOT NULL REFERENCES users(id),
+			parentId INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			nameIndex TEXT NOT NULL
+		);`},
+		{"create name index", `CREATE INDEX IF NOT EXISTS idx_folders_ownerId_parentId_nameIndex ON folders(ownerId, parentId, nameIndex);`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	if err := db.addColumnIfNotExists("files", "folderId", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanFolder(row interface{ Scan(dest ...any) error }) (folder db_access.Folder, err error) {
+	err = row.Scan(&folder.Id, &folder.OwnerId, &folder.ParentId, &folder.Name, &folder.NameIndex)
+	return
+}
+
+func (db *SqliteDb) AddFolder(folder *db_access.Folder) error {
+	const op = "db-access.sqlite.AddFolder"
+
+	res, err := db.Execute(
+		`INSERT INTO folders(ownerId, parentId, name, nameIndex) VALUES (?, ?, ?, ?)`,
+		folder.OwnerId, folder.ParentId, folder.Name, folder.NameIndex,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	folder.Id, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("%s: res.LastInsertId: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetFoldersByNameIndex(ownerId int64, parentId int64, index string) ([]db_access.Folder, error) {
+	const op = "db-access.sqlite.GetFoldersByNameIndex"
+
+	rows, err := db.Query(
+		`SELECT `+folderColumns+` FROM folders WHERE ownerId = ? AND parentId = ? AND nameIndex = ? ORDER BY id`,
+		ownerId, parentId, index,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+
+	folders, err := scanFolderRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return folders, nil
+}
+
+func (db *SqliteDb) GetFolders(ownerId int64) ([]db_access.Folder, error) {
+	const op = "db-access.sqlite.GetFolders"
+
+	rows, err := db.Query(`SELECT `+folderColumns+` FROM folders WHERE ownerId = ? ORDER BY id`, ownerId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+
+	folders, err := scanFolderRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return folders, nil
+}
+
+func (db *SqliteDb) SetFileFolder(generatedName string, folderId int64) error {
+	const op = "db-access.sqlite.SetFileFolder"
+
+	res, err := db.Execute(`UPDATE files SET folderId = ? WHERE generatedName = ?`, folderId, generatedName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "files"}
+	}
+
+	return nil
+}
+
+func scanFolderRows(rows *sql.Rows) ([]db_access.Folder, error) {
+	defer rows.Close()
+
+	folders := make([]db_access.Folder, 0)
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return folders, nil
+}
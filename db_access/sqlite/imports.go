@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func (db *SqliteDb) AddImport(imp *db_access.Import) error {
+	const op = "db-access.sqlite.AddImport"
+
+	_, err := db.Exec(
+		`INSERT INTO imports(id, ownerId, source, status, error, creationTime) values(?,?,?,?,?,?)`,
+		imp.Id,
+		imp.OwnerId,
+		imp.Source,
+		imp.Status,
+		imp.Error,
+		imp.CreationTime,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return db_access.UniqueConstraintError{Table: "imports", Column: "id"}
+	} else if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) UpdateImport(imp *db_access.Import) error {
+	const op = "db-access.sqlite.UpdateImport"
+
+	res, err := db.Exec(
+		`UPDATE imports SET status = ?, totalFiles = ?, importedFiles = ?, failedFiles = ?, importedBytes = ?, error = ?
+		WHERE id = ?`,
+		imp.Status,
+		imp.TotalFiles,
+		imp.ImportedFiles,
+		imp.FailedFiles,
+		imp.ImportedBytes,
+		imp.Error,
+		imp.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+
+	if n == 0 {
+		return db_access.NoRowsError{Table: "imports"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetImport(id string) (db_access.Import, error) {
+	const op = "db-access.sqlite.GetImport"
+
+	var imp db_access.Import
+	err := db.QueryRow(
+		`SELECT id, ownerId, source, status, totalFiles, importedFiles, failedFiles, importedBytes, error, creationTime
+		FROM imports WHERE id = ?`,
+		id,
+	).Scan(
+		&imp.Id,
+		&imp.OwnerId,
+		&imp.Source,
+		&imp.Status,
+		&imp.TotalFiles,
+		&imp.ImportedFiles,
+		&imp.FailedFiles,
+		&imp.ImportedBytes,
+		&imp.Error,
+		&imp.CreationTime,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.Import{}, db_access.NoRowsError{Table: "imports"}
+	} else if err != nil {
+		return db_access.Import{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return imp, nil
+}
+
+// FailUnfinishedImports marks imports that were interrupted by a restart;
+// source credentials are never persisted so they can't be resumed
+func (db *SqliteDb) FailUnfinishedImports(reason string) error {
+	const op = "db-access.sqlite.FailUnfinishedImports"
+
+	_, err := db.Exec(
+		`UPDATE imports SET status = ?, error = ? WHERE status IN (?, ?)`,
+		db_access.ImportFailed,
+		reason,
+		db_access.ImportQueued,
+		db_access.ImportRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// createJobs sets up the jobs table, which outlives the work it reports on
+func (db *SqliteDb) createJobs() error {
+	const op = "db-access.sqlite.createJobs"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create jobs table", `
+		CREATE TABLE IF NOT EXISTS jobs(
+			id TEXT PRIMARY KEY,
+			type TEXT NOT NULL,
+			ownerId INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			done INTEGER NOT NULL DEFAULT 0,
+			total INTEGER NOT NULL DEFAULT 0,
+			result TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			creationTime INTEGER NOT NULL,
+			updateTime INTEGER NOT NULL
+		);`},
+		{"create jobs status index", `CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) AddJob(job *db_access.Job) error {
+	const op = "db-access.sqlite.AddJob"
+
+	_, err := db.Exec(
+		`INSERT INTO jobs(id, type, ownerId, status, done, total, result, error, creationTime, updateTime)
+		values(?,?,?,?,?,?,?,?,?,?)`,
+		job.Id,
+		job.Type,
+		job.OwnerId,
+		job.Status,
+		job.Done,
+		job.Total,
+		job.Result,
+		job.Error,
+		job.CreationTime,
+		job.UpdateTime,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return db_access.UniqueConstraintError{Table: "jobs", Column: "id"}
+	} else if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) UpdateJob(job *db_access.Job) error {
+	const op = "db-access.sqlite.UpdateJob"
+
+	res, err := db.Exec(
+		`UPDATE jobs SET status = ?, done = ?, total = ?, result = ?, error = ?, updateTime = ? WHERE id = ?`,
+		job.Status,
+		job.Done,
+		job.Total,
+		job.Result,
+		job.Error,
+		job.UpdateTime,
+		job.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+
+	if n == 0 {
+		return db_access.NoRowsError{Table: "jobs"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetJob(id string) (db_access.Job, error) {
+	const op = "db-access.sqlite.GetJob"
+
+	var job db_access.Job
+	err := db.QueryRow(
+		`SELECT id, type, ownerId, status, done, total, result, error, creationTime, updateTime FROM jobs WHERE id = ?`,
+		id,
+	).Scan(
+		&job.Id,
+		&job.Type,
+		&job.OwnerId,
+		&job.Status,
+		&job.Done,
+		&job.Total,
+		&job.Result,
+		&job.Error,
+		&job.CreationTime,
+		&job.UpdateTime,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.Job{}, db_access.NoRowsError{Table: "jobs"}
+	} else if err != nil {
+		return db_access.Job{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return job, nil
+}
+
+// FailUnfinishedJobs marks jobs that were interrupted by a restart; their work is gone with the process
+func (db *SqliteDb) FailUnfinishedJobs(reason string) error {
+	const op = "db-access.sqlite.FailUnfinishedJobs"
+
+	_, err := db.Exec(
+		`UPDATE jobs SET status = ?, error = ? WHERE status IN (?, ?)`,
+		db_access.JobFailed,
+		reason,
+		db_access.JobQueued,
+		db_access.JobRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"fmt"
+)
+
+// vaultKeyVersion is the SQL of encryption.KeyVersion: the version vault puts in front of its ciphertexts
+// as "vault:v<version>:", or 0
+func vaultKeyVersion(column string) string {
+	return fmt.Sprintf(
+		`CASE WHEN %[1]s GLOB 'vault:v[0-9]*:*' THEN CAST(substr(%[1]s, 8, instr(substr(%[1]s, 8), ':') - 1) AS INTEGER) ELSE 0 END`,
+		column,
+	)
+}
+
+// nameDecId is the SQL of the id of the DEC a file name is sealed with as "dec:<id>:", or 0
+func nameDecId(column string) string {
+	return fmt.Sprintf(
+		`CASE WHEN %[1]s GLOB 'dec:[0-9]*:*' THEN CAST(substr(%[1]s, 5, instr(substr(%[1]s, 5), ':') - 1) AS INTEGER) ELSE 0 END`,
+		column,
+	)
+}
+
+// createKeyVersions records which version of the vault transit key DECs and file names are wrapped with.
+// DECs store the version vault returns; names are written from many places, so the db reads it off
+// the ciphertext itself and can't get out of step.
+func (db *SqliteDb) createKeyVersions() error {
+	const op = "db-access.sqlite.createKeyVersions"
+
+	if err := db.addColumnIfNotExists("decs", "keyVersion", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := db.addColumnIfNotExists("files", "nameKeyVersion", "INTEGER GENERATED ALWAYS AS ("+vaultKeyVersion("fileName")+") VIRTUAL")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// names sealed locally reference their DEC, which mustn't be pruned while they do
+	err = db.addColumnIfNotExists("files", "nameDecId", "INTEGER GENERATED ALWAYS AS ("+nameDecId("fileName")+") VIRTUAL")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		// keys added before the column was
+		{"backfill dec key versions", `UPDATE decs SET keyVersion = ` + vaultKeyVersion("value") + ` WHERE keyVersion = 0`},
+		{"create name key version index", `CREATE INDEX IF NOT EXISTS idx_files_nameKeyVersion ON files(nameKeyVersion);`},
+		{"create name dec index", `CREATE INDEX IF NOT EXISTS idx_files_nameDecId ON files(nameDecId);`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) RewrapDEC(id db_access.DecId, value string, keyVersion int64) error {
+	const op = "db-access.sqlite.RewrapDEC"
+
+	res, err := db.Exec(`UPDATE decs SET value = ?, keyVersion = ? WHERE id = ?`, value, keyVersion, id)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "decs"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetNamesBelowKeyVersion(version int64, after string, limit int) ([]db_access.WrappedName, error) {
+	const op = "db-access.sqlite.GetNamesBelowKeyVersion"
+
+	rows, err := db.Query(
+		`SELECT generatedName, fileName FROM files
+		WHERE nameKeyVersion > 0 AND nameKeyVersion < ? AND generatedName > ?
+		ORDER BY generatedName
+		LIMIT ?`,
+		version,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	names := make([]db_access.WrappedName, 0)
+	for rows.Next() {
+		var name db_access.WrappedName
+		if err := rows.Scan(&name.GeneratedName, &name.FileName); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return names, nil
+}
+
+func (db *SqliteDb) RewrapFileName(generatedName string, old string, new string) error {
+	const op = "db-access.sqlite.RewrapFileName"
+
+	res, err := db.Exec(`UPDATE files SET fileName = ? WHERE generatedName = ? AND fileName = ?`, new, generatedName, old)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "files"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) CountNamesByKeyVersion() ([]db_access.KeyVersionCount, error) {
+	const op = "db-access.sqlite.CountNamesByKeyVersion"
+
+	rows, err := db.Query(
+		`SELECT nameKeyVersion, COUNT(*) FROM files WHERE nameKeyVersion > 0
+		GROUP BY nameKeyVersion ORDER BY nameKeyVersion`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	counts := make([]db_access.KeyVersionCount, 0)
+	for rows.Next() {
+		var count db_access.KeyVersionCount
+		if err := rows.Scan(&count.KeyVersion, &count.Count); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return counts, nil
+}
+
+func (db *SqliteDb) CountNamesByDEC() (map[db_access.DecId]int, error) {
+	const op = "db-access.sqlite.CountNamesByDEC"
+
+	rows, err := db.Query(`SELECT nameDecId, COUNT(*) FROM files WHERE nameDecId > 0 GROUP BY nameDecId`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[db_access.DecId]int)
+	for rows.Next() {
+		var id db_access.DecId
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		counts[id] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// createFileLocks sets up the locks of files being edited; locks go with their files
+func (db *SqliteDb) createFileLocks() error {
+	const op = "db-access.sqlite.createFileLocks"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create fileLocks table", `
+		CREATE TABLE IF NOT EXISTS fileLocks(
+			generatedName TEXT PRIMARY KEY,
+			token TEXT NOT NULL,
+			holder TEXT NOT NULL,
+			createdAt INTEGER NOT NULL,
+			expiresAt INTEGER NOT NULL
+		);`},
+		{"create delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_lock_delete AFTER DELETE ON files
+		BEGIN
+			DELETE FROM fileLocks WHERE generatedName = OLD.generatedName;
+		END;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetFileLock(generatedName string) (db_access.FileLock, error) {
+	const op = "db-access.sqlite.GetFileLock"
+
+	lock := db_access.FileLock{GeneratedName: generatedName}
+	err := db.QueryRow(
+		`SELECT token, holder, createdAt, expiresAt FROM fileLocks WHERE generatedName = ?`,
+		generatedName,
+	).Scan(&lock.Token, &lock.Holder, &lock.CreatedAt, &lock.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.FileLock{}, db_access.NoRowsError{Table: "fileLocks"}
+	} else if err != nil {
+		return db_access.FileLock{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return lock, nil
+}
+
+func (db *SqliteDb) SetFileLock(lock db_access.FileLock) error {
+	const op = "db-access.sqlite.SetFileLock"
+
+	_, err := db.Exec(
+		`INSERT INTO fileLocks(generatedName, token, holder, createdAt, expiresAt) VALUES (?,?,?,?,?)
+		ON CONFLICT(generatedName) DO UPDATE SET
+			token = excluded.token, holder = excluded.holder, createdAt = excluded.createdAt, expiresAt = excluded.expiresAt`,
+		lock.GeneratedName,
+		lock.Token,
+		lock.Holder,
+		lock.CreatedAt,
+		lock.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) RemoveFileLock(generatedName string) error {
+	const op = "db-access.sqlite.RemoveFileLock"
+
+	_, err := db.Exec(`DELETE FROM fileLocks WHERE generatedName = ?`, generatedName)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// createMetadata sets up the custom metadata of files; metadata goes with its file
+func (db *SqliteDb) createMetadata() error {
+	const op = "db-access.sqlite.createMetadata"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create fileMetadata table", `
+		CREATE TABLE IF NOT EXISTS fileMetadata(
+			generatedName TEXT PRIMARY KEY,
+			data TEXT NOT NULL
+		);`},
+		{"create metadataTerms table", `
+		CREATE TABLE IF NOT EXISTS metadataTerms(
+			ownerId INTEGER NOT NULL,
+			term TEXT NOT NULL,
+			generatedName TEXT NOT NULL,
+			PRIMARY KEY(ownerId, term, generatedName)
+		) WITHOUT ROWID;`},
+		{"create metadataTerms file index", `CREATE INDEX IF NOT EXISTS idx_metadataTerms_generatedName ON metadataTerms(generatedName);`},
+		{"create delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_metadata_delete AFTER DELETE ON files
+		BEGIN
+			DELETE FROM fileMetadata WHERE generatedName = OLD.generatedName;
+			DELETE FROM metadataTerms WHERE generatedName = OLD.generatedName;
+		END;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) SetFileMetadata(generatedName string, ownerId int64, data string, terms []string) error {
+	const op = "db-access.sqlite.SetFileMetadata"
+
+	return db.inTx(context.Background(), func(tx *SqliteDb) error {
+		// metadata of a file deleted meanwhile would never go
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM files WHERE generatedName = ?)`, generatedName).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("%s: check file: %w", op, err)
+		} else if !exists {
+			return db_access.NoRowsError{Table: "files"}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM metadataTerms WHERE generatedName = ?`, generatedName); err != nil {
+			return fmt.Errorf("%s: delete terms: %w", op, err)
+		}
+
+		if data == "" {
+			if _, err := tx.Exec(`DELETE FROM fileMetadata WHERE generatedName = ?`, generatedName); err != nil {
+				return fmt.Errorf("%s: delete metadata: %w", op, err)
+			}
+			return nil
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO fileMetadata(generatedName, data) VALUES (?,?) ON CONFLICT(generatedName) DO UPDATE SET data = excluded.data`,
+			generatedName,
+			data,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: set metadata: %w", op, err)
+		}
+
+		stmt, err := tx.Prepare(`INSERT OR IGNORE INTO metadataTerms(ownerId, term, generatedName) VALUES (?,?,?)`)
+		if err != nil {
+			return fmt.Errorf("%s: tx.Prepare: %w", op, err)
+		}
+		defer stmt.Close()
+
+		for _, term := range terms {
+			if _, err := stmt.Exec(ownerId, term, generatedName); err != nil {
+				return fmt.Errorf("%s: insert term: %w", op, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *SqliteDb) GetFileMetadata(generatedName string) (string, error) {
+	const op = "db-access.sqlite.GetFileMetadata"
+
+	var data string
+	err := db.QueryRow(`SELECT data FROM fileMetadata WHERE generatedName = ?`, generatedName).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return data, nil
+}
+
+func (db *SqliteDb) GetFileMetadataTerms(generatedName string) ([]string, error) {
+	return db.queryStrings(
+		"db-access.sqlite.GetFileMetadataTerms",
+		`SELECT term FROM metadataTerms WHERE generatedName = ? ORDER BY term`,
+		generatedName,
+	)
+}
+
+func (db *SqliteDb) GetFilesByMetadata(ownerId int64, terms []string) ([]db_access.File, error) {
+	if len(terms) == 0 {
+		return make([]db_access.File, 0), nil
+	}
+
+	// every term has to be counted once for the match to need all of them
+	terms = slices.Compact(slices.Sorted(slices.Values(terms)))
+	args := []any{ownerId}
+	for _, term := range terms {
+		args = append(args, term)
+	}
+	args = append(args, len(terms), ownerId)
+
+	return db.queryFiles(
+		"db-access.sqlite.GetFilesByMetadata",
+		`SELECT `+fileColumns+` FROM files WHERE generatedName IN (
+			SELECT generatedName FROM metadataTerms
+			WHERE ownerId = ? AND term IN (?`+strings.Repeat(",?", len(terms)-1)+`)
+			GROUP BY generatedName
+			HAVING COUNT(*) = ?
+		) AND ownerId = ?`,
+		args...,
+	)
+}
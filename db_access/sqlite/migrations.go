@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func (db *SqliteDb) GetBlobs(backend string, filter db_access.BlobFilter) ([]db_access.Blob, error) {
+	const op = "db-access.sqlite.GetBlobs"
+
+	// files that were never downloaded count as accessed when they were created
+	having := []string{"1"}
+	args := []any{backend}
+	if !time.Time(filter.CreatedBefore).IsZero() {
+		having = append(having, "MIN(COALESCE(creationTime, 0)) < ?")
+		args = append(args, filter.CreatedBefore)
+	}
+	if !time.Time(filter.AccessedBefore).IsZero() {
+		having = append(having, "MAX(COALESCE(lastAccess, creationTime, 0)) < ?")
+		args = append(args, filter.AccessedBefore)
+	}
+	if !time.Time(filter.AccessedAfter).IsZero() {
+		having = append(having, "MAX(COALESCE(lastAccess, creationTime, 0)) > ?")
+		args = append(args, filter.AccessedAfter)
+	}
+	if filter.MinSize > 0 {
+		having = append(having, "MAX(size) >= ?")
+		args = append(args, filter.MinSize)
+	}
+	if filter.MaxSize > 0 {
+		having = append(having, "MAX(size) <= ?")
+		args = append(args, filter.MaxSize)
+	}
+
+	rows, err := db.Query(
+		`SELECT COALESCE(blobName, generatedName) AS blob, MAX(size) FROM files
+		WHERE backend = ?
+		GROUP BY blob
+		HAVING `+strings.Join(having, " AND "),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	blobs := make([]db_access.Blob, 0)
+	for rows.Next() {
+		blob := db_access.Blob{Backend: backend}
+		if err := rows.Scan(&blob.Name, &blob.Size); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return blobs, nil
+}
+
+func (db *SqliteDb) SetBlobBackend(blobName string, from string, to string) (bool, error) {
+	const op = "db-access.sqlite.SetBlobBackend"
+
+	res, err := db.Exec(
+		`UPDATE files SET backend = ? WHERE COALESCE(blobName, generatedName) = ? AND backend = ?`,
+		to,
+		blobName,
+		from,
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+
+	return n > 0, nil
+}
+
+func (db *SqliteDb) AddMigration(m *db_access.Migration) error {
+	const op = "db-access.sqlite.AddMigration"
+
+	_, err := db.Exec(
+		`INSERT INTO migrations(id, fromBackend, toBackend, createdBefore, status, error, creationTime) values(?,?,?,?,?,?,?)`,
+		m.Id,
+		m.FromBackend,
+		m.ToBackend,
+		m.CreatedBefore,
+		m.Status,
+		m.Error,
+		m.CreationTime,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return db_access.UniqueConstraintError{Table: "migrations", Column: "id"}
+	} else if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) UpdateMigration(m *db_access.Migration) error {
+	const op = "db-access.sqlite.UpdateMigration"
+
+	res, err := db.Exec(
+		`UPDATE migrations SET status = ?, totalBlobs = ?, movedBlobs = ?, failedBlobs = ?, movedBytes = ?, error = ?
+		WHERE id = ?`,
+		m.Status,
+		m.TotalBlobs,
+		m.MovedBlobs,
+		m.FailedBlobs,
+		m.MovedBytes,
+		m.Error,
+		m.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+
+	if n == 0 {
+		return db_access.NoRowsError{Table: "migrations"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetMigration(id string) (db_access.Migration, error) {
+	const op = "db-access.sqlite.GetMigration"
+
+	var m db_access.Migration
+	err := db.QueryRow(
+		`SELECT id, fromBackend, toBackend, createdBefore, status, totalBlobs, movedBlobs, failedBlobs, movedBytes, error, creationTime
+		FROM migrations WHERE id = ?`,
+		id,
+	).Scan(
+		&m.Id,
+		&m.FromBackend,
+		&m.ToBackend,
+		&m.CreatedBefore,
+		&m.Status,
+		&m.TotalBlobs,
+		&m.MovedBlobs,
+		&m.FailedBlobs,
+		&m.MovedBytes,
+		&m.Error,
+		&m.CreationTime,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.Migration{}, db_access.NoRowsError{Table: "migrations"}
+	} else if err != nil {
+		return db_access.Migration{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return m, nil
+}
+
+// FailUnfinishedMigrations marks migrations that were interrupted by a restart;
+// blobs moved so far stay where they are, a new migration picks up the rest
+func (db *SqliteDb) FailUnfinishedMigrations(reason string) error {
+	const op = "db-access.sqlite.FailUnfinishedMigrations"
+
+	_, err := db.Exec(
+		`UPDATE migrations SET status = ?, error = ? WHERE status IN (?, ?)`,
+		db_access.MigrationFailed,
+		reason,
+		db_access.MigrationQueued,
+		db_access.MigrationRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
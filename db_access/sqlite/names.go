@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (db *SqliteDb) SetFileNameIndex(generatedName string, index string) error {
+	const op = "db-access.sqlite.SetFileNameIndex"
+
+	res, err := db.Exec(`UPDATE files SET nameIndex = ? WHERE generatedName = ?`, index, generatedName)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "files"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetFilesByNameIndex(ownerId int64, index string) ([]db_access.File, error) {
+	const op = "db-access.sqlite.GetFilesByNameIndex"
+
+	return db.queryFiles(
+		op,
+		`SELECT `+fileColumns+` FROM files WHERE ownerId = ? AND (nameIndex = ? OR nameIndex IS NULL)`,
+		ownerId,
+		index,
+	)
+}
+
+func (db *SqliteDb) GetIndexKey() (string, error) {
+	const op = "db-access.sqlite.GetIndexKey"
+
+	var value string
+	err := db.QueryRow(`SELECT value FROM indexKeys WHERE id = 1`).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", db_access.NoRowsError{Table: "indexKeys"}
+	} else if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return value, nil
+}
+
+func (db *SqliteDb) AddIndexKey(value string) (string, error) {
+	const op = "db-access.sqlite.AddIndexKey"
+
+	// whoever adds the key first wins, everyone else gets that one back
+	if _, err := db.Execute(`INSERT OR IGNORE INTO indexKeys(id, value) VALUES(1, ?)`, value); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	value, err := db.GetIndexKey()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return value, nil
+}
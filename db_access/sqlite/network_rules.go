@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const networkRuleColumns = `scope, subject, allow, deny`
+
+func (db *SqliteDb) createNetworkRules() error {
+	const op = "db-access.sqlite.createNetworkRules"
+
+	// allow and deny are comma separated
+	_, err := db.Execute(`
+	CREATE TABLE IF NOT EXISTS networkRules(
+		scope TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		allow TEXT NOT NULL,
+		deny TEXT NOT NULL,
+		PRIMARY KEY(scope, subject)
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("%s: create networkRules table: %w", op, err)
+	}
+
+	return nil
+}
+
+func splitNetworks(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func scanNetworkRules(row interface{ Scan(dest ...any) error }) (db_access.NetworkRules, error) {
+	var rules db_access.NetworkRules
+	var allow, deny string
+	if err := row.Scan(&rules.Scope, &rules.Subject, &allow, &deny); err != nil {
+		return db_access.NetworkRules{}, err
+	}
+
+	rules.Allow = splitNetworks(allow)
+	rules.Deny = splitNetworks(deny)
+	return rules, nil
+}
+
+func (db *SqliteDb) SetNetworkRules(rules db_access.NetworkRules) error {
+	const op = "db-access.sqlite.SetNetworkRules"
+
+	_, err := db.Exec(
+		`INSERT OR REPLACE INTO networkRules(`+networkRuleColumns+`) VALUES (?, ?, ?, ?)`,
+		rules.Scope, rules.Subject, strings.Join(rules.Allow, ","), strings.Join(rules.Deny, ","),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) DeleteNetworkRules(scope db_access.NetworkScope, subject string) error {
+	const op = "db-access.sqlite.DeleteNetworkRules"
+
+	res, err := db.Exec(`DELETE FROM networkRules WHERE scope = ? AND subject = ?`, scope, subject)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "networkRules"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) ListNetworkRules() ([]db_access.NetworkRules, error) {
+	const op = "db-access.sqlite.ListNetworkRules"
+
+	rows, err := db.Query(`SELECT ` + networkRuleColumns + ` FROM networkRules ORDER BY scope DESC, subject`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+
+	rules, err := scanNetworkRuleRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rules, nil
+}
+
+func (db *SqliteDb) GetRequestNetworkRules(userId int64, deviceId string) ([]db_access.NetworkRules, error) {
+	const op = "db-access.sqlite.GetRequestNetworkRules"
+
+	rows, err := db.Query(
+		`SELECT `+networkRuleColumns+` FROM networkRules
+		WHERE (scope = 'user' AND subject = ?) OR (scope = 'device' AND subject = ? AND subject != '')
+		ORDER BY scope DESC`,
+		strconv.FormatInt(userId, 10),
+		deviceId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+
+	rules, err := scanNetworkRuleRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rules, nil
+}
+
+func scanNetworkRuleRows(rows *sql.Rows) ([]db_access.NetworkRules, error) {
+	defer rows.Close()
+
+	rules := make([]db_access.NetworkRules, 0)
+	for rows.Next() {
+		r, err := scanNetworkRules(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		rules = append(rules, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return rules, nil
+}
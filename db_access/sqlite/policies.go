@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const policyColumns = `scope, subject, quota, maxFileSize, allowedTypes, retention, maxFileSizeOverride`
+
+// the widest scope comes first
+const policyOrder = `CASE scope WHEN 'global' THEN 0 WHEN 'org' THEN 1 ELSE 2 END, subject`
+
+func scanPolicy(row interface{ Scan(dest ...any) error }) (db_access.Policy, error) {
+	var p db_access.Policy
+	var quota, maxFileSize, retention, maxFileSizeOverride sql.NullInt64
+	var allowedTypes sql.NullString
+	if err := row.Scan(&p.Scope, &p.Subject, &quota, &maxFileSize, &allowedTypes, &retention, &maxFileSizeOverride); err != nil {
+		return db_access.Policy{}, err
+	}
+
+	if quota.Valid {
+		p.Quota = &quota.Int64
+	}
+	if maxFileSize.Valid {
+		p.MaxFileSize = &maxFileSize.Int64
+	}
+	if allowedTypes.Valid {
+		// an empty list still overrides what the wider scope allows
+		p.AllowedTypes = []string{}
+		if allowedTypes.String != "" {
+			p.AllowedTypes = strings.Split(allowedTypes.String, ",")
+		}
+	}
+	if retention.Valid {
+		d := time.Duration(retention.Int64) * time.Second
+		p.Retention = &d
+	}
+	if maxFileSizeOverride.Valid {
+		p.MaxFileSizeOverride = &maxFileSizeOverride.Int64
+	}
+
+	return p, nil
+}
+
+func (db *SqliteDb) SetPolicy(p db_access.Policy) error {
+	const op = "db-access.sqlite.SetPolicy"
+
+	var allowedTypes sql.NullString
+	if p.AllowedTypes != nil {
+		allowedTypes = sql.NullString{String: strings.Join(p.AllowedTypes, ","), Valid: true}
+	}
+	var retention sql.NullInt64
+	if p.Retention != nil {
+		retention = sql.NullInt64{Int64: int64(*p.Retention / time.Second), Valid: true}
+	}
+
+	_, err := db.Exec(
+		`INSERT OR REPLACE INTO policies(`+policyColumns+`) values(?, ?, ?, ?, ?, ?, ?)`,
+		p.Scope, p.Subject, p.Quota, p.MaxFileSize, allowedTypes, retention, p.MaxFileSizeOverride,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) DeletePolicy(scope db_access.PolicyScope, subject string) error {
+	const op = "db-access.sqlite.DeletePolicy"
+
+	res, err := db.Exec(`DELETE FROM policies WHERE scope = ? AND subject = ?`, scope, subject)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "policies"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) ListPolicies() ([]db_access.Policy, error) {
+	const op = "db-access.sqlite.ListPolicies"
+
+	rows, err := db.Query(`SELECT ` + policyColumns + ` FROM policies ORDER BY ` + policyOrder)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+
+	policies, err := scanPolicies(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return policies, nil
+}
+
+func (db *SqliteDb) GetUserPolicies(userId int64) ([]db_access.Policy, error) {
+	const op = "db-access.sqlite.GetUserPolicies"
+
+	rows, err := db.Query(
+		`SELECT `+policyColumns+` FROM policies
+		WHERE (scope = 'global' AND subject = '')
+			OR (scope = 'org' AND subject = (SELECT org FROM users WHERE id = ? AND org != ''))
+			OR (scope = 'user' AND subject = ?)
+		ORDER BY `+policyOrder,
+		userId,
+		strconv.FormatInt(userId, 10),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+
+	policies, err := scanPolicies(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return policies, nil
+}
+
+func scanPolicies(rows *sql.Rows) ([]db_access.Policy, error) {
+	defer rows.Close()
+
+	policies := make([]db_access.Policy, 0)
+	for rows.Next() {
+		p, err := scanPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		policies = append(policies, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,284 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// createPrivacy sets up the records of consent and the requests for erasure;
+// neither goes with the user, the rows of erased users are only made anonymous.
+// Contents are encrypted with DECs of their owner, which are shredded when the owner is erased.
+func (db *SqliteDb) createPrivacy() error {
+	const op = "db-access.sqlite.createPrivacy"
+
+	// keys added before are owned by no user
+	if err := db.addColumnIfNotExists("decs", "ownerId", "INTEGER REFERENCES users(id)"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create consents table", `
+		CREATE TABLE IF NOT EXISTS consents(
+			id INTEGER PRIMARY KEY,
+			userId INTEGER NOT NULL REFERENCES users(id),
+			purpose TEXT NOT NULL,
+			version TEXT NOT NULL,
+			at INTEGER NOT NULL
+		);`},
+		{"create consents index", `CREATE INDEX IF NOT EXISTS idx_consents_userId ON consents(userId);`},
+		{"create dec owner index", `CREATE INDEX IF NOT EXISTS idx_decs_ownerId ON decs(ownerId, creationTime);`},
+		{"create deletionRequests table", `
+		CREATE TABLE IF NOT EXISTS deletionRequests(
+			userId INTEGER PRIMARY KEY REFERENCES users(id),
+			requestedAt INTEGER NOT NULL,
+			deadline INTEGER NOT NULL,
+			completedAt INTEGER
+		);`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) AddConsent(consent db_access.Consent) error {
+	const op = "db-access.sqlite.AddConsent"
+
+	_, err := db.Exec(
+		`INSERT INTO consents(userId, purpose, version, at) VALUES (?,?,?,?)`,
+		consent.UserId,
+		consent.Purpose,
+		consent.Version,
+		consent.At,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetConsents(userId int64) ([]db_access.Consent, error) {
+	const op = "db-access.sqlite.GetConsents"
+
+	rows, err := db.Query(`SELECT purpose, version, at FROM consents WHERE userId = ? ORDER BY id`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	consents := make([]db_access.Consent, 0)
+	for rows.Next() {
+		consent := db_access.Consent{UserId: userId}
+		if err := rows.Scan(&consent.Purpose, &consent.Version, &consent.At); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		consents = append(consents, consent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return consents, nil
+}
+
+func (db *SqliteDb) AddDeletionRequest(req db_access.DeletionRequest) error {
+	const op = "db-access.sqlite.AddDeletionRequest"
+
+	_, err := db.Exec(
+		`INSERT INTO deletionRequests(userId, requestedAt, deadline, completedAt) VALUES (?,?,?,?)`,
+		req.UserId,
+		req.RequestedAt,
+		req.Deadline,
+		req.CompletedAt,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return db_access.UniqueConstraintError{Table: "deletionRequests", Column: "userId"}
+	} else if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetDeletionRequest(userId int64) (db_access.DeletionRequest, error) {
+	const op = "db-access.sqlite.GetDeletionRequest"
+
+	req := db_access.DeletionRequest{UserId: userId}
+	err := db.QueryRow(
+		`SELECT requestedAt, deadline, completedAt FROM deletionRequests WHERE userId = ?`,
+		userId,
+	).Scan(&req.RequestedAt, &req.Deadline, &req.CompletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.DeletionRequest{}, db_access.NoRowsError{Table: "deletionRequests"}
+	} else if err != nil {
+		return db_access.DeletionRequest{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return req, nil
+}
+
+func (db *SqliteDb) GetPendingDeletionRequests() ([]db_access.DeletionRequest, error) {
+	const op = "db-access.sqlite.GetPendingDeletionRequests"
+
+	rows, err := db.Query(
+		`SELECT userId, requestedAt, deadline FROM deletionRequests
+		WHERE completedAt IS NULL
+		ORDER BY deadline, userId`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	requests := make([]db_access.DeletionRequest, 0)
+	for rows.Next() {
+		var req db_access.DeletionRequest
+		if err := rows.Scan(&req.UserId, &req.RequestedAt, &req.Deadline); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		requests = append(requests, req)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return requests, nil
+}
+
+func (db *SqliteDb) EraseUser(userId int64) error {
+	const op = "db-access.sqlite.EraseUser"
+
+	return db.inTx(context.Background(), func(tx *SqliteDb) error {
+		// the name is freed for others, and no password can match a missing hash
+		res, err := tx.Exec(
+			`UPDATE users SET name = 'erased-' || id, passwordHash = NULL, roles = '', org = '', tokenVersion = tokenVersion + 1
+			WHERE id = ?`,
+			userId,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: update users: %w", op, err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+		}
+		if affected == 0 {
+			return db_access.NoRowsError{Table: "users"}
+		}
+
+		statements := []struct {
+			name  string
+			query string
+		}{
+			// before the devices the rules of which it looks up
+			{"delete networkRules", `
+			DELETE FROM networkRules
+			WHERE (scope = 'user' AND subject = CAST(?1 AS TEXT))
+				OR (scope = 'device' AND subject IN (SELECT id FROM devices WHERE ownerId = ?1))`},
+			{"delete devices", `DELETE FROM devices WHERE ownerId = ?`},
+			{"delete notifications", `DELETE FROM notifications WHERE ownerId = ?`},
+			{"delete imports", `DELETE FROM imports WHERE ownerId = ?`},
+			{"delete stars", `DELETE FROM stars WHERE userId = ?`},
+			{"delete alertThresholds", `DELETE FROM alertThresholds WHERE userId = ?`},
+			{"delete loginCountries", `DELETE FROM loginCountries WHERE userId = ?`},
+			{"delete folders", `DELETE FROM folders WHERE ownerId = ?`},
+			{"delete usage", `DELETE FROM usage WHERE ownerId = ?`},
+			{"delete traffic", `DELETE FROM traffic WHERE ownerId = ?`},
+			// replies keep their place in the threads of others
+			{"clear comments", `UPDATE comments SET body = NULL WHERE authorId = ?`},
+		}
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt.query, userId); err != nil {
+				return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *SqliteDb) CompleteDeletionRequest(userId int64, at db_access.Time) error {
+	const op = "db-access.sqlite.CompleteDeletionRequest"
+
+	res, err := db.Exec(`UPDATE deletionRequests SET completedAt = ? WHERE userId = ?`, at, userId)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+	if affected == 0 {
+		return db_access.NoRowsError{Table: "deletionRequests"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetNewestUserDEC(ownerId int64) (db_access.DEC, error) {
+	const op = "db-access.sqlite.GetNewestUserDEC"
+
+	dec := db_access.DEC{OwnerId: ownerId}
+	err := db.QueryRow(
+		`SELECT id, value, creationTime, keyVersion FROM decs WHERE ownerId = ? ORDER BY creationTime DESC, id DESC LIMIT 1`,
+		ownerId,
+	).Scan(&dec.Id, &dec.Value, &dec.CreationTime, &dec.KeyVersion)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db_access.DEC{}, db_access.NoRowsError{Table: "decs"}
+	} else if err != nil {
+		return db_access.DEC{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return dec, nil
+}
+
+func (db *SqliteDb) RemoveUserDECs(ownerId int64) ([]db_access.DecId, error) {
+	const op = "db-access.sqlite.RemoveUserDECs"
+
+	var ids []db_access.DecId
+	err := db.inTx(context.Background(), func(tx *SqliteDb) error {
+		rows, err := tx.Query(`SELECT id FROM decs WHERE ownerId = ?`, ownerId)
+		if err != nil {
+			return fmt.Errorf("%s: tx.Query: %w", op, err)
+		}
+		defer rows.Close()
+
+		ids = make([]db_access.DecId, 0)
+		for rows.Next() {
+			var id db_access.DecId
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("%s: rows.Scan: %w", op, err)
+			}
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("%s: rows.Err: %w", op, err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM decs WHERE ownerId = ?`, ownerId); err != nil {
+			return fmt.Errorf("%s: tx.Exec: %w", op, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,371 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// createChangeFeed sets up the feed of file row changes. Triggers on files only record changes while
+// some consumer has a cursor, so the feed costs nothing until replication or event publishing is turned on.
+func (db *SqliteDb) createChangeFeed() error {
+	const op = "db-access.sqlite.createChangeFeed"
+
+	tables := []struct {
+		name  string
+		query string
+	}{
+		{"create changes table", `
+		CREATE TABLE IF NOT EXISTS changes(
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			generatedName TEXT NOT NULL,
+			blobName TEXT,
+			at INTEGER NOT NULL
+		);`},
+		{"create changeCursors table", `
+		CREATE TABLE IF NOT EXISTS changeCursors(
+			consumer TEXT PRIMARY KEY,
+			seq INTEGER NOT NULL
+		);`},
+		{"create replicatedBlobs table", `
+		CREATE TABLE IF NOT EXISTS replicatedBlobs(
+			target TEXT NOT NULL,
+			blobName TEXT NOT NULL,
+			PRIMARY KEY(target, blobName)
+		);`},
+	}
+
+	for _, stmt := range tables {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	for _, column := range []string{"ownerId", "size"} {
+		if err := db.addColumnIfNotExists("changes", column, "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// the triggers are created anew when they differ from what the db holds, so that dbs made before
+	// a change to them get it too. Blobs of deleted files are only recorded once no file references them, since that is when replicas of them can go.
+	triggers := []struct {
+		name  string
+		query string
+	}{
+		{"trg_files_changes_insert", `
+		CREATE TRIGGER trg_files_changes_insert AFTER INSERT ON files
+		WHEN EXISTS(SELECT 1 FROM changeCursors)
+		BEGIN
+			INSERT INTO changes(kind, generatedName, blobName, ownerId, size, at)
+			VALUES (
+				'created',
+				NEW.generatedName,
+				COALESCE(NEW.blobName, NEW.generatedName),
+				COALESCE(NEW.ownerId, 0),
+				NEW.size,
+				CAST(strftime('%s', 'now') AS INTEGER)
+			);
+		END;`},
+		{"trg_files_changes_update", `
+		CREATE TRIGGER trg_files_changes_update AFTER UPDATE OF fileName, ownerId, backend, expiresAt ON files
+		WHEN EXISTS(SELECT 1 FROM changeCursors)
+		BEGIN
+			INSERT INTO changes(kind, generatedName, blobName, ownerId, size, at)
+			VALUES (
+				'updated',
+				NEW.generatedName,
+				COALESCE(NEW.blobName, NEW.generatedName),
+				COALESCE(NEW.ownerId, 0),
+				NEW.size,
+				CAST(strftime('%s', 'now') AS INTEGER)
+			);
+		END;`},
+		{"trg_files_changes_delete", `
+		CREATE TRIGGER trg_files_changes_delete AFTER DELETE ON files
+		WHEN EXISTS(SELECT 1 FROM changeCursors)
+		BEGIN
+			INSERT INTO changes(kind, generatedName, blobName, ownerId, size, at)
+			VALUES (
+				'deleted',
+				OLD.generatedName,
+				CASE WHEN EXISTS(
+					SELECT 1 FROM files WHERE COALESCE(blobName, generatedName) = COALESCE(OLD.blobName, OLD.generatedName)
+				) THEN NULL ELSE COALESCE(OLD.blobName, OLD.generatedName) END,
+				COALESCE(OLD.ownerId, 0),
+				OLD.size,
+				CAST(strftime('%s', 'now') AS INTEGER)
+			);
+		END;`},
+	}
+
+	existing, err := db.triggers()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	outdated := triggers[:0:0]
+	for _, trigger := range triggers {
+		if existing[trigger.name] != triggerSQL(trigger.query) {
+			outdated = append(outdated, trigger)
+		}
+	}
+	if len(outdated) == 0 {
+		return nil
+	}
+
+	// one transaction, so that no crash leaves the feed without a trigger
+	return db.inTx(context.Background(), func(tx *SqliteDb) error {
+		for _, trigger := range outdated {
+			if _, err := tx.Execute(`DROP TRIGGER IF EXISTS ` + trigger.name); err != nil {
+				return fmt.Errorf("%s: drop %s: %w", op, trigger.name, err)
+			}
+			if _, err := tx.Execute(trigger.query); err != nil {
+				return fmt.Errorf("%s: create %s: %w", op, trigger.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// triggerSQL is query as sqlite_master holds it, without the space around it and the closing semicolon
+func triggerSQL(query string) string {
+	return strings.TrimSuffix(strings.TrimSpace(query), ";")
+}
+
+// triggers returns the sql of the triggers of the db by name
+func (db *SqliteDb) triggers() (map[string]string, error) {
+	const op = "db-access.sqlite.triggers"
+
+	rows, err := db.Query(`SELECT name, sql FROM sqlite_master WHERE type = 'trigger'`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	triggers := make(map[string]string)
+	for rows.Next() {
+		var name, query string
+		if err := rows.Scan(&name, &query); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		triggers[name] = query
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return triggers, nil
+}
+
+func (db *SqliteDb) GetChanges(after int64, limit int) ([]db_access.Change, error) {
+	const op = "db-access.sqlite.GetChanges"
+
+	rows, err := db.Query(
+		`SELECT seq, kind, generatedName, COALESCE(blobName, ''), ownerId, size, at FROM changes WHERE seq > ? ORDER BY seq LIMIT ?`,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	changes := make([]db_access.Change, 0)
+	for rows.Next() {
+		var c db_access.Change
+		if err := rows.Scan(&c.Seq, &c.Kind, &c.GeneratedName, &c.BlobName, &c.OwnerId, &c.Size, &c.At); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return changes, nil
+}
+
+func (db *SqliteDb) GetChangeCursor(consumer string) (int64, error) {
+	const op = "db-access.sqlite.GetChangeCursor"
+
+	var seq int64
+	err := db.QueryRow(`SELECT seq FROM changeCursors WHERE consumer = ?`, consumer).Scan(&seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, db_access.NoRowsError{Table: "changeCursors"}
+	} else if err != nil {
+		return 0, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return seq, nil
+}
+
+func (db *SqliteDb) SetChangeCursor(consumer string, seq int64) error {
+	const op = "db-access.sqlite.SetChangeCursor"
+
+	_, err := db.Exec(
+		`INSERT INTO changeCursors(consumer, seq) VALUES (?,?) ON CONFLICT(consumer) DO UPDATE SET seq = excluded.seq`,
+		consumer,
+		seq,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) KeepChangeCursors(consumers []string) error {
+	const op = "db-access.sqlite.KeepChangeCursors"
+
+	query := `DELETE FROM changeCursors`
+	args := make([]any, 0, len(consumers))
+	if len(consumers) > 0 {
+		query += ` WHERE consumer NOT IN (?` + strings.Repeat(",?", len(consumers)-1) + `)`
+		for _, consumer := range consumers {
+			args = append(args, consumer)
+		}
+	}
+
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	// the removed consumers may have been the ones holding changes back
+	if err := db.RemoveReadChanges(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) RemoveReadChanges() error {
+	const op = "db-access.sqlite.RemoveReadChanges"
+
+	// without consumers nothing reads the feed anymore
+	_, err := db.Exec(`DELETE FROM changes
+		WHERE NOT EXISTS(SELECT 1 FROM changeCursors) OR seq <= (SELECT MIN(seq) FROM changeCursors)`)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetFiles(after string, limit int) ([]db_access.File, error) {
+	const op = "db-access.sqlite.GetFiles"
+
+	rows, err := db.Query(`SELECT `+fileColumns+` FROM files WHERE generatedName > ? ORDER BY generatedName LIMIT ?`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	files := make([]db_access.File, 0)
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return files, nil
+}
+
+func (db *SqliteDb) GetUnreplicatedBlobs(target string, after string, limit int) ([]db_access.Blob, error) {
+	const op = "db-access.sqlite.GetUnreplicatedBlobs"
+
+	rows, err := db.Query(
+		`SELECT COALESCE(f.blobName, f.generatedName) AS blob, MIN(f.backend), MAX(f.size) FROM files f
+		WHERE COALESCE(f.blobName, f.generatedName) > ? AND NOT EXISTS(
+			SELECT 1 FROM replicatedBlobs r WHERE r.target = ? AND r.blobName = COALESCE(f.blobName, f.generatedName)
+		)
+		GROUP BY blob
+		ORDER BY blob
+		LIMIT ?`,
+		after,
+		target,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	blobs := make([]db_access.Blob, 0)
+	for rows.Next() {
+		var blob db_access.Blob
+		if err := rows.Scan(&blob.Name, &blob.Backend, &blob.Size); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return blobs, nil
+}
+
+func (db *SqliteDb) GetReplicatedBlobs(target string) ([]db_access.ReplicatedBlob, error) {
+	const op = "db-access.sqlite.GetReplicatedBlobs"
+
+	rows, err := db.Query(
+		`SELECT r.blobName, EXISTS(SELECT 1 FROM files f WHERE COALESCE(f.blobName, f.generatedName) = r.blobName)
+		FROM replicatedBlobs r WHERE r.target = ? ORDER BY r.blobName`,
+		target,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	blobs := make([]db_access.ReplicatedBlob, 0)
+	for rows.Next() {
+		var blob db_access.ReplicatedBlob
+		if err := rows.Scan(&blob.Name, &blob.Referenced); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return blobs, nil
+}
+
+func (db *SqliteDb) MarkBlobReplicated(target string, blobName string) error {
+	const op = "db-access.sqlite.MarkBlobReplicated"
+
+	_, err := db.Exec(`INSERT OR IGNORE INTO replicatedBlobs(target, blobName) VALUES (?,?)`, target, blobName)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) UnmarkBlobReplicated(target string, blobName string) error {
+	const op = "db-access.sqlite.UnmarkBlobReplicated"
+
+	_, err := db.Exec(`DELETE FROM replicatedBlobs WHERE target = ? AND blobName = ?`, target, blobName)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"expvar"
+	"math/rand/v2"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// A write that finds the db locked by another connection is tried again up to busyAttempts times in all,
+// waiting busyBackoff and twice as long every time after, plus up to as much again at random
+const (
+	busyAttempts = 5
+	busyBackoff  = 10 * time.Millisecond
+)
+
+var (
+	busyRetries  = expvar.NewInt("sqlite_busy_retries")
+	busyFailures = expvar.NewInt("sqlite_busy_failures")
+)
+
+// isBusy reports whether err is sqlite failing for a lock held by someone else, which leaves
+// the db as it was, so that the write can be made again
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+// retryBusy runs write again while it fails with isBusy, as bounded by busyAttempts
+func retryBusy(write func() error) error {
+	backoff := busyBackoff
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if !isBusy(err) {
+			return err
+		}
+		if attempt == busyAttempts {
+			busyFailures.Add(1)
+			return err
+		}
+
+		busyRetries.Add(1)
+		time.Sleep(backoff + rand.N(backoff))
+		backoff *= 2
+	}
+}
+
+// Exec retries statements made outside of a transaction; in one, inTx retries the whole transaction
+func (db *SqliteDb) Exec(query string, args ...any) (sql.Result, error) {
+	if db.sqlDb == nil {
+		return db.conn.Exec(query, args...)
+	}
+
+	var res sql.Result
+	err := retryBusy(func() error {
+		var err error
+		res, err = db.conn.Exec(query, args...)
+		return err
+	})
+	return res, err
+}
@@ -2,16 +2,64 @@ package sqlite
 
 import (
 	"cloud-storage/db_access"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 )
 
+// conn is what *sql.DB and *sql.Tx have in common
+type conn interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 type SqliteDb struct {
-	*sql.DB
+	conn
+	// sqlDb is nil for repos bound to a transaction
+	sqlDb *sql.DB
+}
+
+func (db *SqliteDb) WithTx(ctx context.Context, fn func(repos db_access.DbAccess) error) error {
+	return db.inTx(ctx, func(tx *SqliteDb) error {
+		return fn(tx)
+	})
+}
+
+// inTx runs fn in a transaction of its own, or in the one db is bound to. A transaction of its own
+// that finds the db locked is rolled back and fn runs again, see retryBusy, so fn must be idempotent.
+func (db *SqliteDb) inTx(ctx context.Context, fn func(tx *SqliteDb) error) error {
+	const op = "db-access.sqlite.inTx"
+
+	if db.sqlDb == nil {
+		return fn(db)
+	}
+
+	return retryBusy(func() error {
+		tx, err := db.sqlDb.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("%s: db.BeginTx: %w", op, err)
+		}
+		defer tx.Rollback()
+
+		if err := fn(&SqliteDb{conn: tx}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("%s: tx.Commit: %w", op, err)
+		}
+
+		return nil
+	})
 }
 
 // TODO: maybe we should just use db.Exec() instead of this function
@@ -24,7 +72,17 @@ func (db *SqliteDb) Execute(query string, args ...any) (sql.Result, error) {
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(args...)
+	var res sql.Result
+	exec := func() error {
+		res, err = stmt.Exec(args...)
+		return err
+	}
+	// see Exec
+	if db.sqlDb != nil {
+		err = retryBusy(exec)
+	} else {
+		err = exec()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%s: stmt.Exec: %w", op, err)
 	}
@@ -32,6 +90,17 @@ func (db *SqliteDb) Execute(query string, args ...any) (sql.Result, error) {
 	return res, nil
 }
 
+// isReadOnly reports whether path is a URI filename that opens the db read-only
+func isReadOnly(path string) bool {
+	_, query, ok := strings.Cut(path, "?")
+	if !ok {
+		return false
+	}
+
+	params, err := url.ParseQuery(query)
+	return err == nil && (params.Get("mode") == "ro" || params.Get("immutable") == "1")
+}
+
 func New(path string) (db_access.DbAccess, error) {
 	const op = "db-access.sqlite.New"
 
@@ -40,7 +109,11 @@ func New(path string) (db_access.DbAccess, error) {
 		return nil, fmt.Errorf("%s: sql.Open: %w", op, err)
 	}
 
-	db := &SqliteDb{sqlite}
+	db := &SqliteDb{conn: sqlite, sqlDb: sqlite}
+	// a read-only db can't be set up, it has to be one New was run on before
+	if isReadOnly(path) {
+		return db, nil
+	}
 
 	_, err = db.Execute(`
 	CREATE TABLE IF NOT EXISTS files(
@@ -74,37 +147,438 @@ func New(path string) (db_access.DbAccess, error) {
 		return nil, fmt.Errorf("%s: create users table: %w", op, err)
 	}
 
+	// comma separated role names handed out by an external directory
+	err = db.addColumnIfNotExists("users", "roles", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("users", "org", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("users", "tokenVersion", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// a database holding names that differ only in case from before gets the index once they are renamed,
+	// see UniqueUserNames
+	var unce db_access.UserNamesConflictError
+	if err := db.UniqueUserNames(); err != nil && !errors.As(err, &unce) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_genName ON files(generatedName);`)
 	if err != nil {
 		return nil, fmt.Errorf("%s: create index on files: %w", op, err)
 	}
 
+	err = db.addColumnIfNotExists("files", "ownerId", "INTEGER REFERENCES users(id)")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_files_ownerId ON files(ownerId);`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create owner index on files: %w", op, err)
+	}
+
+	// NULL when the file owns a blob named after it
+	err = db.addColumnIfNotExists("files", "blobName", "TEXT")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_files_blobName ON files(blobName);`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create blob index on files: %w", op, err)
+	}
+
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS fileTags(
+		generatedName TEXT NOT NULL REFERENCES files(generatedName),
+		tag TEXT NOT NULL,
+		PRIMARY KEY(generatedName, tag)
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create fileTags table: %w", op, err)
+	}
+
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS exports(
+		id TEXT PRIMARY KEY,
+		ownerId INTEGER NOT NULL REFERENCES users(id),
+		status TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		creationTime INTEGER NOT NULL,
+		expiresAt INTEGER
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create exports table: %w", op, err)
+	}
+
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS imports(
+		id TEXT PRIMARY KEY,
+		ownerId INTEGER NOT NULL REFERENCES users(id),
+		source TEXT NOT NULL,
+		status TEXT NOT NULL,
+		totalFiles INTEGER NOT NULL DEFAULT 0,
+		importedFiles INTEGER NOT NULL DEFAULT 0,
+		failedFiles INTEGER NOT NULL DEFAULT 0,
+		importedBytes INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		creationTime INTEGER NOT NULL
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create imports table: %w", op, err)
+	}
+
+	// plaintext size; files stored before it was recorded count as empty
+	err = db.addColumnIfNotExists("files", "size", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createUsageTables(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("files", "expiresAt", "INTEGER")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("files", "expiryNotified", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_files_expiresAt ON files(expiresAt) WHERE expiresAt IS NOT NULL;`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create expiry index on files: %w", op, err)
+	}
+
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS notifications(
+		id INTEGER PRIMARY KEY,
+		ownerId INTEGER NOT NULL REFERENCES users(id),
+		kind TEXT NOT NULL,
+		fileId TEXT NOT NULL,
+		fileName TEXT NOT NULL,
+		at INTEGER,
+		creationTime INTEGER NOT NULL
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create notifications table: %w", op, err)
+	}
+
+	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_notifications_ownerId ON notifications(ownerId);`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create owner index on notifications: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("files", "backend", "TEXT NOT NULL DEFAULT 'local'")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// files stored before it was recorded count as the oldest ones
+	err = db.addColumnIfNotExists("files", "creationTime", "INTEGER")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("files", "lastAccess", "INTEGER")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("files", "downloadCount", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_files_ownerId_lastAccess ON files(ownerId, lastAccess) WHERE lastAccess IS NOT NULL;`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create last access index on files: %w", op, err)
+	}
+
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS migrations(
+		id TEXT PRIMARY KEY,
+		fromBackend TEXT NOT NULL,
+		toBackend TEXT NOT NULL,
+		createdBefore INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		totalBlobs INTEGER NOT NULL DEFAULT 0,
+		movedBlobs INTEGER NOT NULL DEFAULT 0,
+		failedBlobs INTEGER NOT NULL DEFAULT 0,
+		movedBytes INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		creationTime INTEGER NOT NULL
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create migrations table: %w", op, err)
+	}
+
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS audit(
+		id INTEGER PRIMARY KEY,
+		at INTEGER NOT NULL,
+		userId INTEGER NOT NULL,
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		remoteAddr TEXT NOT NULL,
+		requestId TEXT NOT NULL
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create audit table: %w", op, err)
+	}
+
+	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_audit_at ON audit(at);`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create time index on audit: %w", op, err)
+	}
+
+	// NULL columns inherit from the wider scope; allowedTypes is comma separated
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS policies(
+		scope TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		quota INTEGER,
+		maxFileSize INTEGER,
+		allowedTypes TEXT,
+		retention INTEGER,
+		PRIMARY KEY(scope, subject)
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create policies table: %w", op, err)
+	}
+
+	err = db.addColumnIfNotExists("policies", "maxFileSizeOverride", "INTEGER")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// files named before the index existed, or renamed since, have none until it is computed again
+	err = db.addColumnIfNotExists("files", "nameIndex", "TEXT")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = db.Execute(`CREATE INDEX IF NOT EXISTS idx_files_ownerId_nameIndex ON files(ownerId, nameIndex);`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create name index on files: %w", op, err)
+	}
+
+	// a single row holding the wrapped key of the name index
+	_, err = db.Execute(`
+	CREATE TABLE IF NOT EXISTS indexKeys(
+		id INTEGER PRIMARY KEY CHECK(id = 1),
+		value TEXT NOT NULL
+	);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create indexKeys table: %w", op, err)
+	}
+
+	if err := db.createChangeFeed(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createContentIndex(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createFileLocks(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createComments(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createStars(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createMetadata(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createConvergentBlobs(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createJobs(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createKeyVersions(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createCollectionVersions(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createDevices(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createPrivacy(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createAnomalies(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createNetworkRules(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.createFolders(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return db, nil
 }
 
-func (db *SqliteDb) AddFile(generatedName string, filename string) error {
+// addColumnIfNotExists is used for migrating tables that were created before the column was introduced
+func (db *SqliteDb) addColumnIfNotExists(table string, column string, definition string) error {
+	const op = "db-access.sqlite.addColumnIfNotExists"
+
+	// table_xinfo lists generated columns too
+	rows, err := db.Query(fmt.Sprintf(`SELECT name FROM pragma_table_xinfo('%s')`, table))
+	if err != nil {
+		return fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+
+		if name == column {
+			return nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	_, err = db.Execute(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	if err != nil {
+		return fmt.Errorf("%s: add %s.%s: %w", op, table, column, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) AddFile(generatedName string, filename string, ownerId int64, size int64) error {
 	const op = "db-access.sqlite.AddFile"
 
 	_, err := db.Execute(
-		`INSERT INTO files(generatedName, fileName) values(?,?)`,
+		`INSERT INTO files(generatedName, fileName, ownerId, size, creationTime) values(?,?,?,?,?)`,
 		generatedName,
 		filename,
+		ownerId,
+		size,
+		db_access.Time(time.Now()),
 	)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			// TODO: this is really dumb. Like wtf why are we getting table and column names from debug error string representation?
-			errorMsg, _ := strings.CutPrefix(sqliteErr.Error(), "UNIQUE constraint failed: ")
-			tableColumn := strings.Split(errorMsg, ".")
-			return db_access.UniqueConstraintError{Table: tableColumn[0], Column: tableColumn[1]}
-		}
+		return uniqueConstraintError(op, err)
+	}
 
+	return nil
+}
+
+func (db *SqliteDb) AddFileCopy(generatedName string, filename string, ownerId int64, blobName string, size int64) error {
+	const op = "db-access.sqlite.AddFileCopy"
+
+	// the copy lives wherever the blob currently is
+	_, err := db.Execute(
+		`INSERT INTO files(generatedName, fileName, ownerId, blobName, size, creationTime, backend)
+		values(?,?,?,?,?,?, COALESCE((SELECT backend FROM files WHERE COALESCE(blobName, generatedName) = ? LIMIT 1), 'local'))`,
+		generatedName,
+		filename,
+		ownerId,
+		blobName,
+		size,
+		db_access.Time(time.Now()),
+		blobName,
+	)
+	if err != nil {
+		return uniqueConstraintError(op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) RenameFile(generatedName string, filename string) error {
+	const op = "db-access.sqlite.RenameFile"
+
+	res, err := db.Execute(`UPDATE files SET fileName = ?, nameIndex = NULL WHERE generatedName = ?`, filename, generatedName)
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	} else if n == 0 {
+		return db_access.NoRowsError{Table: "files"}
+	}
+
 	return nil
 }
 
+// uniqueIndexes are the unique indexes sqlite names in its errors instead of their columns
+var uniqueIndexes = map[string]db_access.UniqueConstraintError{
+	"idx_users_name_nocase": {Table: "users", Column: "name"},
+}
+
+// constraintFromMessage reads the table and column out of the message of a unique constraint
+// violation, "UNIQUE constraint failed: users.name" or "UNIQUE constraint failed: index 'idx'";
+// of a constraint over several columns it keeps the first
+func constraintFromMessage(msg string) db_access.UniqueConstraintError {
+	failed, _ := strings.CutPrefix(msg, "UNIQUE constraint failed: ")
+	if index, ok := strings.CutPrefix(failed, "index "); ok {
+		return uniqueIndexes[strings.Trim(index, "'")]
+	}
+
+	first, _, _ := strings.Cut(failed, ", ")
+	table, column, _ := strings.Cut(first, ".")
+	return db_access.UniqueConstraintError{Table: table, Column: column}
+}
+
+// uniqueConstraintError maps violations of unique constraints and of primary keys, which are unique
+// as well, to UniqueConstraintError and wraps any other error with op
+func uniqueConstraintError(op string, err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		// sqlite3.Error carries the message of sqlite, which is the only place naming the constraint
+		return constraintFromMessage(sqliteErr.Error())
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
+
 func (db *SqliteDb) RemoveFile(generatedName string) error {
 	const op = "db-access.sqlite.RemoveFile"
 
@@ -119,10 +593,20 @@ func (db *SqliteDb) RemoveFile(generatedName string) error {
 	return nil
 }
 
-func (db *SqliteDb) GetFile(generatedName string) (filename string, err error) {
+// fileColumns is the select list scanned by scanFile
+const fileColumns = `generatedName, fileName, ownerId, COALESCE(blobName, generatedName), backend, size, expiresAt, lastAccess, downloadCount, COALESCE(nameIndex, ''), folderId`
+
+func scanFile(row interface{ Scan(dest ...any) error }) (file db_access.File, err error) {
+	var ownerId sql.NullInt64
+	err = row.Scan(&file.GeneratedName, &file.FileName, &ownerId, &file.BlobName, &file.Backend, &file.Size, &file.ExpiresAt, &file.LastAccess, &file.DownloadCount, &file.NameIndex, &file.FolderId)
+	file.OwnerId = ownerId.Int64
+	return
+}
+
+func (db *SqliteDb) GetFile(generatedName string) (file db_access.File, err error) {
 	const op = "db-access.sqlite.GetFile"
 
-	err = db.QueryRow(`SELECT fileName FROM files WHERE generatedName = ? LIMIT 1`, generatedName).Scan(&filename)
+	file, err = scanFile(db.QueryRow(`SELECT `+fileColumns+` FROM files WHERE generatedName = ? LIMIT 1`, generatedName))
 	if errors.Is(err, sql.ErrNoRows) {
 		err = db_access.NoRowsError{}
 	} else if err != nil {
@@ -132,11 +616,61 @@ func (db *SqliteDb) GetFile(generatedName string) (filename string, err error) {
 	return
 }
 
+func (db *SqliteDb) GetUserFiles(ownerId int64) ([]db_access.File, error) {
+	const op = "db-access.sqlite.GetUserFiles"
+
+	rows, err := db.Query(`SELECT `+fileColumns+` FROM files WHERE ownerId = ?`, ownerId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	files := make([]db_access.File, 0)
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return files, nil
+}
+
+func (db *SqliteDb) ListBlobNames() ([]string, error) {
+	const op = "db-access.sqlite.ListBlobNames"
+
+	rows, err := db.Query(`SELECT DISTINCT COALESCE(blobName, generatedName) FROM files WHERE backend = 'local'`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return names, nil
+}
+
 func (db *SqliteDb) GetDEC(id db_access.DecId) (db_access.DEC, error) {
 	const op = "db-access.sqlite.GetDEC"
 
 	stmt, err := db.Prepare(`
-	SELECT * FROM decs WHERE id = ?
+	SELECT id, value, creationTime, keyVersion, COALESCE(ownerId, 0) FROM decs WHERE id = ?
 	`)
 	if err != nil {
 		return db_access.DEC{}, fmt.Errorf("%s: prepare statement: %w", op, err)
@@ -144,7 +678,7 @@ func (db *SqliteDb) GetDEC(id db_access.DecId) (db_access.DEC, error) {
 	defer stmt.Close()
 
 	var dec db_access.DEC
-	err = stmt.QueryRow(id).Scan(&dec.Id, &dec.Value, &dec.CreationTime)
+	err = stmt.QueryRow(id).Scan(&dec.Id, &dec.Value, &dec.CreationTime, &dec.KeyVersion, &dec.OwnerId)
 	if err != nil {
 		return db_access.DEC{}, fmt.Errorf("%s: stmt.QueryRow: %w", op, err)
 	}
@@ -156,14 +690,14 @@ func (db *SqliteDb) GetNewestDEC() (db_access.DEC, error) {
 	const op = "db-access.sqlite.GetNewestDEC"
 
 	// TODO: speed of this sql query
-	stmt, err := db.Prepare(`SELECT * FROM decs ORDER BY creationTime DESC LIMIT 1`)
+	stmt, err := db.Prepare(`SELECT id, value, creationTime, keyVersion FROM decs WHERE ownerId IS NULL ORDER BY creationTime DESC LIMIT 1`)
 	if err != nil {
 		return db_access.DEC{}, fmt.Errorf("%s: prepare statement: %w", op, err)
 	}
 	defer stmt.Close()
 
 	var dec db_access.DEC
-	err = stmt.QueryRow().Scan(&dec.Id, &dec.Value, &dec.CreationTime)
+	err = stmt.QueryRow().Scan(&dec.Id, &dec.Value, &dec.CreationTime, &dec.KeyVersion)
 	if errors.Is(err, sql.ErrNoRows) {
 		return db_access.DEC{}, db_access.NoRowsError{Table: "decs"}
 	} else if err != nil {
@@ -177,9 +711,11 @@ func (db *SqliteDb) AddDEC(dec *db_access.DEC) error {
 	const op = "db-access.sqlite.AddDEC"
 
 	res, err := db.Execute(
-		`INSERT INTO decs(value, creationTime) values(?,?)`,
+		`INSERT INTO decs(value, creationTime, keyVersion, ownerId) values(?,?,?,NULLIF(?, 0))`,
 		dec.Value,
 		dec.CreationTime,
+		dec.KeyVersion,
+		dec.OwnerId,
 	)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -195,13 +731,51 @@ func (db *SqliteDb) AddDEC(dec *db_access.DEC) error {
 	return nil
 }
 
+func (db *SqliteDb) GetDECs() ([]db_access.DEC, error) {
+	const op = "db-access.sqlite.GetDECs"
+
+	rows, err := db.Query(`SELECT id, value, creationTime, keyVersion, COALESCE(ownerId, 0) FROM decs ORDER BY creationTime, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	decs := make([]db_access.DEC, 0)
+	for rows.Next() {
+		var dec db_access.DEC
+		if err := rows.Scan(&dec.Id, &dec.Value, &dec.CreationTime, &dec.KeyVersion, &dec.OwnerId); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		decs = append(decs, dec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return decs, nil
+}
+
+func (db *SqliteDb) RemoveDEC(id db_access.DecId) error {
+	const op = "db-access.sqlite.RemoveDEC"
+
+	if _, err := db.Execute(`DELETE FROM decs WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (db *SqliteDb) GetUser(user *db_access.User) (err error) {
 	const op = "db-access.sqlite.GetUser"
 
+	var roles string
 	if user.Name == "" {
-		err = db.QueryRow(`SELECT name, passwordHash FROM users WHERE id = ? LIMIT 1`, user.Id).Scan(&user.Name, &user.PasswordHash)
+		err = db.QueryRow(`SELECT name, passwordHash, roles, org, tokenVersion FROM users WHERE id = ? LIMIT 1`, user.Id).
+			Scan(&user.Name, &user.PasswordHash, &roles, &user.Org, &user.TokenVersion)
 	} else {
-		err = db.QueryRow(`SELECT id, passwordHash FROM users WHERE name = ? LIMIT 1`, user.Name).Scan(&user.Id, &user.PasswordHash)
+		err = db.QueryRow(`SELECT id, passwordHash, roles, org, tokenVersion FROM users WHERE name = ? LIMIT 1`, user.Name).
+			Scan(&user.Id, &user.PasswordHash, &roles, &user.Org, &user.TokenVersion)
 	}
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -210,18 +784,23 @@ func (db *SqliteDb) GetUser(user *db_access.User) (err error) {
 		err = fmt.Errorf("%s: db.QueryRow: %w", op, err)
 	}
 
+	user.Roles = nil
+	if roles != "" {
+		user.Roles = strings.Split(roles, ",")
+	}
+
 	return
 }
 
 func (db *SqliteDb) AddUser(user *db_access.User) error {
 	const op = "db-access.sqlite.AddUser"
 
-	res, err := db.Exec(`INSERT INTO users(name, passwordHash) values(?, ?)`, user.Name, user.PasswordHash)
-	var sqliteErr sqlite3.Error
-	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-		return db_access.UniqueConstraintError{}
-	} else if err != nil {
-		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	res, err := db.Exec(
+		`INSERT INTO users(name, passwordHash, roles, org) values(?, ?, ?, ?)`,
+		user.Name, user.PasswordHash, strings.Join(user.Roles, ","), user.Org,
+	)
+	if err != nil {
+		return uniqueConstraintError(op, fmt.Errorf("db.Exec: %w", err))
 	}
 
 	user.Id, err = res.LastInsertId()
@@ -231,3 +810,153 @@ func (db *SqliteDb) AddUser(user *db_access.User) error {
 
 	return nil
 }
+
+func (db *SqliteDb) SetUserRoles(userId int64, roles []string) error {
+	const op = "db-access.sqlite.SetUserRoles"
+
+	res, err := db.Exec(`UPDATE users SET roles = ? WHERE id = ?`, strings.Join(roles, ","), userId)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+	if affected == 0 {
+		return db_access.NoRowsError{Table: "users"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) RevokeUserTokens(userId int64) error {
+	const op = "db-access.sqlite.RevokeUserTokens"
+
+	return db.inTx(context.Background(), func(tx *SqliteDb) error {
+		res, err := tx.Exec(`UPDATE users SET tokenVersion = tokenVersion + 1 WHERE id = ?`, userId)
+		if err != nil {
+			return fmt.Errorf("%s: update users: %w", op, err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+		}
+		if affected == 0 {
+			return db_access.NoRowsError{Table: "users"}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM devices WHERE ownerId = ?`, userId); err != nil {
+			return fmt.Errorf("%s: delete devices: %w", op, err)
+		}
+
+		return nil
+	})
+}
+
+func (db *SqliteDb) SetUserOrg(userId int64, org string) error {
+	const op = "db-access.sqlite.SetUserOrg"
+
+	res, err := db.Exec(`UPDATE users SET org = ? WHERE id = ?`, org, userId)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+	if affected == 0 {
+		return db_access.NoRowsError{Table: "users"}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetUsers() ([]db_access.User, error) {
+	const op = "db-access.sqlite.GetUsers"
+
+	rows, err := db.Query(`SELECT id, name, roles, org, tokenVersion FROM users ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	users := make([]db_access.User, 0)
+	for rows.Next() {
+		var user db_access.User
+		var roles string
+		if err := rows.Scan(&user.Id, &user.Name, &roles, &user.Org, &user.TokenVersion); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		if roles != "" {
+			user.Roles = strings.Split(roles, ",")
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// UniqueUserNames adds a unique index on the names of users, which sqlite compares without case
+// in the ASCII range; names kept normalized differ in case nowhere else
+func (db *SqliteDb) UniqueUserNames() error {
+	const op = "db-access.sqlite.UniqueUserNames"
+
+	rows, err := db.Query(`
+	SELECT json_group_array(name) FROM (SELECT name FROM users ORDER BY id)
+	GROUP BY name COLLATE NOCASE HAVING count(*) > 1 ORDER BY min(name)`)
+	if err != nil {
+		return fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	var conflict db_access.UserNamesConflictError
+	for rows.Next() {
+		var list string
+		if err := rows.Scan(&list); err != nil {
+			return fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		var names []string
+		if err := json.Unmarshal([]byte(list), &names); err != nil {
+			return fmt.Errorf("%s: json.Unmarshal: %w", op, err)
+		}
+		conflict.Names = append(conflict.Names, names)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+	if len(conflict.Names) > 0 {
+		return conflict
+	}
+
+	_, err = db.Execute(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_name_nocase ON users(name COLLATE NOCASE);`)
+	if err != nil {
+		return fmt.Errorf("%s: create index: %w", op, err)
+	}
+	return nil
+}
+
+func (db *SqliteDb) RenameUser(userId int64, name string) error {
+	const op = "db-access.sqlite.RenameUser"
+
+	res, err := db.Exec(`UPDATE users SET name = ? WHERE id = ?`, name, userId)
+	if err != nil {
+		return uniqueConstraintError(op, fmt.Errorf("db.Exec: %w", err))
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+	}
+	if affected == 0 {
+		return db_access.NoRowsError{Table: "users"}
+	}
+
+	return nil
+}
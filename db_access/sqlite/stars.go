@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"fmt"
+)
+
+// createStars sets up the files users starred; stars go with their files
+func (db *SqliteDb) createStars() error {
+	const op = "db-access.sqlite.createStars"
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create stars table", `
+		CREATE TABLE IF NOT EXISTS stars(
+			userId INTEGER NOT NULL,
+			generatedName TEXT NOT NULL,
+			starredAt INTEGER NOT NULL,
+			PRIMARY KEY(userId, generatedName)
+		) WITHOUT ROWID;`},
+		{"create stars file index", `CREATE INDEX IF NOT EXISTS idx_stars_generatedName ON stars(generatedName);`},
+		{"create delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_stars_delete AFTER DELETE ON files
+		BEGIN
+			DELETE FROM stars WHERE generatedName = OLD.generatedName;
+		END;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) StarFile(userId int64, generatedName string, at db_access.Time) error {
+	const op = "db-access.sqlite.StarFile"
+
+	_, err := db.Exec(
+		`INSERT INTO stars(userId, generatedName, starredAt) VALUES (?,?,?) ON CONFLICT(userId, generatedName) DO NOTHING`,
+		userId,
+		generatedName,
+		at,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) UnstarFile(userId int64, generatedName string) error {
+	const op = "db-access.sqlite.UnstarFile"
+
+	_, err := db.Exec(`DELETE FROM stars WHERE userId = ? AND generatedName = ?`, userId, generatedName)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetStarredFiles(userId int64) ([]db_access.File, error) {
+	return db.queryFiles(
+		"db-access.sqlite.GetStarredFiles",
+		`SELECT `+fileColumns+` FROM files JOIN stars USING (generatedName)
+		WHERE stars.userId = ? AND files.ownerId = stars.userId ORDER BY stars.starredAt DESC, generatedName`,
+		userId,
+	)
+}
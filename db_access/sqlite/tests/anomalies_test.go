@@ -0,0 +1,59 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertThresholds(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	alice := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&alice))
+
+	var nre db_access.NoRowsError
+	_, err = db.GetAlertThresholds(alice.Id)
+	assert.ErrorAs(t, err, &nre)
+
+	factor, deletes, off := 2.5, int64(0), false
+	thresholds := db_access.AlertThresholds{UserId: alice.Id, DownloadFactor: &factor, DeletesPerHour: &deletes, NewCountries: &off}
+	require.NoError(t, db.SetAlertThresholds(thresholds))
+
+	got, err := db.GetAlertThresholds(alice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, thresholds, got)
+
+	require.NoError(t, db.DeleteAlertThresholds(alice.Id))
+	assert.ErrorAs(t, db.DeleteAlertThresholds(alice.Id), &nre)
+}
+
+func TestAddLoginCountry(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	alice := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&alice))
+
+	at := db_access.Time(time.Unix(1, 0))
+	for _, tc := range []struct {
+		country string
+		isNew   bool
+	}{
+		// the first country is where the user usually is
+		{"DE", false},
+		{"DE", false},
+		{"NZ", true},
+		{"NZ", false},
+	} {
+		isNew, err := db.AddLoginCountry(alice.Id, tc.country, at)
+		require.NoError(t, err)
+		assert.Equal(t, tc.isNew, isNew, tc.country)
+	}
+}
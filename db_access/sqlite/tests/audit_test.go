@@ -0,0 +1,64 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEvents(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, at := range []time.Time{start.Add(time.Hour), start, start.Add(48 * time.Hour)} {
+		e := db_access.AuditEvent{
+			At:         db_access.Time(at),
+			UserId:     int64(i),
+			Method:     "POST",
+			Path:       "/api/upload",
+			Status:     201,
+			RemoteAddr: "10.0.0.1:5000",
+			RequestId:  "id",
+		}
+		require.NoError(t, db.AddAuditEvent(&e))
+		assert.Equal(t, int64(i+1), e.Id)
+	}
+
+	events, err := db.GetAuditEvents(db_access.Time(start), db_access.Time(start.Add(24*time.Hour)))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(events))
+	assert.Equal(t, int64(2), events[0].Id)
+	assert.Equal(t, int64(1), events[1].Id)
+	assert.Equal(t, "/api/upload", events[1].Path)
+	assert.True(t, time.Time(events[1].At).Equal(start.Add(time.Hour)))
+
+	events, err = db.GetAuditEvents(db_access.Time(start.Add(time.Hour)), db_access.Time(start.Add(2*time.Hour)))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(events))
+	assert.Equal(t, int64(1), events[0].Id)
+}
+
+func TestRemoveAuditEventsBefore(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{start, start.Add(time.Hour), start.Add(48 * time.Hour)} {
+		require.NoError(t, db.AddAuditEvent(&db_access.AuditEvent{At: db_access.Time(at), Method: "POST", Path: "/api/upload"}))
+	}
+
+	removed, err := db.RemoveAuditEventsBefore(db_access.Time(start.Add(time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	events, err := db.GetAuditEvents(db_access.Time(start), db_access.Time(start.Add(72*time.Hour)))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(events))
+	assert.Equal(t, int64(2), events[0].Id)
+}
@@ -0,0 +1,53 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComments(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+	require.NoError(t, db.AddFile("b", "enc:b", 1, 4))
+
+	now := db_access.Time(time.Unix(time.Now().Unix(), 0))
+	first := db_access.Comment{GeneratedName: "a", AuthorId: 1, Body: "enc:first", CreatedAt: now}
+	require.NoError(t, db.AddComment(&first))
+	reply := db_access.Comment{GeneratedName: "a", AuthorId: 1, ReplyTo: first.Id, CreatedAt: now}
+	require.NoError(t, db.AddComment(&reply))
+	other := db_access.Comment{GeneratedName: "a", AuthorId: 1, Body: "enc:other", CreatedAt: now}
+	require.NoError(t, db.AddComment(&other))
+	onB := db_access.Comment{GeneratedName: "b", AuthorId: 1, Body: "enc:b", CreatedAt: now}
+	require.NoError(t, db.AddComment(&onB))
+
+	got, err := db.GetComment(reply.Id)
+	require.NoError(t, err)
+	assert.Equal(t, first.Id, got.ReplyTo)
+	assert.Empty(t, got.Body)
+
+	comments, err := db.GetComments("a")
+	require.NoError(t, err)
+	require.Len(t, comments, 3)
+	assert.Equal(t, "enc:first", comments[0].Body)
+
+	// replies go with the comment they answer
+	require.NoError(t, db.RemoveComment(first.Id))
+	comments, err = db.GetComments("a")
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, other.Id, comments[0].Id)
+	assert.ErrorAs(t, db.RemoveComment(first.Id), &db_access.NoRowsError{})
+
+	// comments go with their files
+	require.NoError(t, db.RemoveFile("b"))
+	_, err = db.GetComment(onB.Id)
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+}
@@ -0,0 +1,69 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentIndex(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+	require.NoError(t, db.AddFile("b", "enc:b", 1, 4))
+	require.NoError(t, db.AddFile("c", "enc:c", 2, 4))
+
+	files, err := db.GetUnindexedFiles("", 10)
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	require.NoError(t, db.SetFileTerms("a", 1, []string{"x", "y"}))
+	require.NoError(t, db.SetFileTerms("b", 1, []string{"x"}))
+	require.NoError(t, db.SetFileTerms("c", 2, nil))
+	assert.ErrorAs(t, db.SetFileTerms("missing", 1, []string{"x"}), &db_access.NoRowsError{})
+
+	files, err = db.GetUnindexedFiles("", 10)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	ids := func(files []db_access.File) []string {
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.GeneratedName)
+		}
+		return names
+	}
+
+	files, err = db.GetFilesByTerms(1, []string{"x"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, ids(files))
+
+	files, err = db.GetFilesByTerms(1, []string{"y", "x", "x"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids(files))
+
+	// terms of other owners don't match
+	files, err = db.GetFilesByTerms(2, []string{"x"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	require.NoError(t, db.SetFileTerms("a", 1, []string{"z"}))
+	files, err = db.GetFilesByTerms(1, []string{"y"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// terms go with their files
+	require.NoError(t, db.RemoveFile("b"))
+	require.NoError(t, db.AddFile("b", "enc:b", 1, 4))
+	files, err = db.GetUnindexedFiles("", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids(files))
+	files, err = db.GetFilesByTerms(1, []string{"x"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,48 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvergentBlobs(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	_, err = db.GetConvergentBlob("content")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+
+	// two users uploaded the same content
+	require.NoError(t, db.AddFileCopy("a", "enc:a", 1, "blob", 4))
+	require.NoError(t, db.AddFileCopy("b", "enc:b", 2, "blob", 4))
+	blob := db_access.ConvergentBlob{ContentId: "content", BlobName: "blob", Key: "wrapped"}
+	require.NoError(t, db.AddConvergentBlob(blob))
+
+	var uce db_access.UniqueConstraintError
+	require.ErrorAs(t, db.AddConvergentBlob(db_access.ConvergentBlob{ContentId: "content", BlobName: "other", Key: "k"}), &uce)
+	assert.Equal(t, db_access.UniqueConstraintError{Table: "convergentBlobs", Column: "contentId"}, uce)
+
+	got, err := db.GetConvergentBlob("content")
+	require.NoError(t, err)
+	assert.Equal(t, blob, got)
+
+	key, err := db.GetContentKey("content")
+	require.NoError(t, err)
+	assert.Equal(t, "wrapped", key)
+
+	// the blob stays while a file references it
+	require.NoError(t, db.RemoveFile("a"))
+	_, err = db.GetConvergentBlob("content")
+	require.NoError(t, err)
+
+	_, orphaned, err := db.DeleteFile("b")
+	require.NoError(t, err)
+	assert.True(t, orphaned)
+	_, err = db.GetConvergentBlob("content")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+}
@@ -0,0 +1,62 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevices(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	alice := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&alice))
+	bob := db_access.User{Name: "bob"}
+	require.NoError(t, db.AddUser(&bob))
+
+	at := func(sec int64) db_access.Time { return db_access.Time(time.Unix(sec, 0)) }
+	phone := db_access.Device{Id: "phone", OwnerId: alice.Id, Name: "Phone", Platform: "android", RefreshHash: []byte("h1"), CreatedAt: at(1), LastUsed: at(1)}
+	laptop := db_access.Device{Id: "laptop", OwnerId: alice.Id, Name: "Laptop", RefreshHash: []byte("h2"), CreatedAt: at(2), LastUsed: at(2)}
+	tablet := db_access.Device{Id: "tablet", OwnerId: bob.Id, Name: "Tablet", RefreshHash: []byte("h3"), CreatedAt: at(3), LastUsed: at(3)}
+	for _, device := range []db_access.Device{phone, laptop, tablet} {
+		require.NoError(t, db.AddDevice(device))
+	}
+
+	got, err := db.GetDeviceByRefreshHash([]byte("h1"))
+	require.NoError(t, err)
+	assert.Equal(t, phone, got)
+
+	require.NoError(t, db.TouchDevice("phone", at(10)))
+	got, err = db.GetDevice("phone")
+	require.NoError(t, err)
+	assert.Equal(t, at(10), got.LastUsed)
+
+	devices, err := db.GetDevices(alice.Id)
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "laptop", devices[0].Id)
+	assert.Equal(t, "phone", devices[1].Id)
+
+	// a user can't remove the device of another
+	var nre db_access.NoRowsError
+	assert.ErrorAs(t, db.RemoveDevice(alice.Id, "tablet"), &nre)
+	require.NoError(t, db.RemoveDevice(alice.Id, "phone"))
+	_, err = db.GetDevice("phone")
+	assert.ErrorAs(t, err, &nre)
+	_, err = db.GetDeviceByRefreshHash([]byte("h1"))
+	assert.ErrorAs(t, err, &nre)
+
+	// revoking all sessions takes the devices along
+	require.NoError(t, db.RevokeUserTokens(alice.Id))
+	devices, err = db.GetDevices(alice.Id)
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+	_, err = db.GetDevice("tablet")
+	assert.NoError(t, err)
+}
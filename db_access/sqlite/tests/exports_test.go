@@ -0,0 +1,73 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailUnfinishedExports(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+	alice := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&alice))
+
+	now := db_access.Time(time.Unix(time.Now().Unix(), 0))
+	for _, export := range []db_access.Export{
+		{Id: "pending", OwnerId: alice.Id, Status: db_access.ExportPending, CreationTime: now},
+		{Id: "running", OwnerId: alice.Id, Status: db_access.ExportRunning, CreationTime: now},
+		{Id: "ready", OwnerId: alice.Id, Status: db_access.ExportReady, CreationTime: now, ExpiresAt: db_access.Time(time.Time(now).Add(time.Hour))},
+	} {
+		require.NoError(t, db.AddExport(&export))
+	}
+
+	// interrupted exports expire, so that the cleanup removes them
+	require.NoError(t, db.FailUnfinishedExports("Interrupted", now))
+	expired, err := db.GetExpiredExports(now)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(expired))
+	for _, export := range expired {
+		assert.Equal(t, db_access.ExportFailed, export.Status)
+		assert.Equal(t, "Interrupted", export.Error)
+		ids = append(ids, export.Id)
+	}
+	assert.ElementsMatch(t, []string{"pending", "running"}, ids)
+
+	ready, err := db.GetExport("ready")
+	require.NoError(t, err)
+	assert.Equal(t, db_access.ExportReady, ready.Status)
+}
+
+func TestGetUserExports(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+	alice := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&alice))
+	bob := db_access.User{Name: "bob"}
+	require.NoError(t, db.AddUser(&bob))
+
+	now := db_access.Time(time.Unix(time.Now().Unix(), 0))
+	for _, export := range []db_access.Export{
+		{Id: "alice-1", OwnerId: alice.Id, Status: db_access.ExportReady, CreationTime: now},
+		{Id: "alice-2", OwnerId: alice.Id, Status: db_access.ExportPending, CreationTime: now},
+		{Id: "bob", OwnerId: bob.Id, Status: db_access.ExportReady, CreationTime: now},
+	} {
+		require.NoError(t, db.AddExport(&export))
+	}
+
+	exports, err := db.GetUserExports(alice.Id)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(exports))
+	for _, export := range exports {
+		assert.Equal(t, alice.Id, export.OwnerId)
+		ids = append(ids, export.Id)
+	}
+	assert.ElementsMatch(t, []string{"alice-1", "alice-2"}, ids)
+}
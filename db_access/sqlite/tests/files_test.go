@@ -0,0 +1,44 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteFile_SharedBlob(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.AddFile("src", "name", 1, 0))
+	assert.NoError(t, db.AddFileCopy("copy", "name", 1, "src", 0))
+	assert.NoError(t, db.AddFileTags("src", []string{"b", "a", "a"}))
+
+	tags, err := db.GetFileTags("src")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	blobs, err := db.ListBlobNames()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"src"}, blobs)
+
+	blob, orphaned, err := db.DeleteFile("src")
+	assert.NoError(t, err)
+	assert.Equal(t, db_access.Blob{Name: "src", Backend: "local"}, blob)
+	assert.False(t, orphaned)
+
+	copied, err := db.GetFile("copy")
+	assert.NoError(t, err)
+	assert.Equal(t, "src", copied.BlobName)
+
+	blob, orphaned, err = db.DeleteFile("copy")
+	assert.NoError(t, err)
+	assert.Equal(t, db_access.Blob{Name: "src", Backend: "local"}, blob)
+	assert.True(t, orphaned)
+
+	_, _, err = db.DeleteFile("copy")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+}
@@ -0,0 +1,53 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFolders(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	alice := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&alice))
+	bob := db_access.User{Name: "bob"}
+	require.NoError(t, db.AddUser(&bob))
+
+	photos := db_access.Folder{OwnerId: alice.Id, Name: "enc:photos", NameIndex: "idx:photos"}
+	require.NoError(t, db.AddFolder(&photos))
+	year := db_access.Folder{OwnerId: alice.Id, ParentId: photos.Id, Name: "enc:2024", NameIndex: "idx:2024"}
+	require.NoError(t, db.AddFolder(&year))
+	other := db_access.Folder{OwnerId: bob.Id, Name: "enc:photos", NameIndex: "idx:photos"}
+	require.NoError(t, db.AddFolder(&other))
+	assert.NotEqual(t, photos.Id, year.Id)
+
+	found, err := db.GetFoldersByNameIndex(alice.Id, 0, "idx:photos")
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.Folder{photos}, found)
+	found, err = db.GetFoldersByNameIndex(alice.Id, 0, "idx:2024")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	folders, err := db.GetFolders(alice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.Folder{photos, year}, folders)
+
+	// files start out in the root
+	require.NoError(t, db.AddFile("a", "enc:a", alice.Id, 4))
+	file, err := db.GetFile("a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), file.FolderId)
+
+	require.NoError(t, db.SetFileFolder("a", year.Id))
+	file, err = db.GetFile("a")
+	require.NoError(t, err)
+	assert.Equal(t, year.Id, file.FolderId)
+
+	assert.ErrorAs(t, db.SetFileFolder("missing", year.Id), &db_access.NoRowsError{})
+}
@@ -0,0 +1,49 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	now := db_access.Time(time.Unix(time.Now().Unix(), 0))
+	job := db_access.Job{Id: "a", Type: "export", OwnerId: 1, Status: db_access.JobQueued, CreationTime: now, UpdateTime: now}
+	require.NoError(t, db.AddJob(&job))
+	assert.ErrorAs(t, db.AddJob(&job), &db_access.UniqueConstraintError{})
+
+	job.Status = db_access.JobFinished
+	job.Done, job.Total = 3, 3
+	job.Result = `{"download":"/link"}`
+	require.NoError(t, db.UpdateJob(&job))
+
+	stored, err := db.GetJob("a")
+	require.NoError(t, err)
+	assert.Equal(t, job, stored)
+
+	running := db_access.Job{Id: "b", Type: "import", OwnerId: 2, Status: db_access.JobRunning, CreationTime: now, UpdateTime: now}
+	require.NoError(t, db.AddJob(&running))
+	require.NoError(t, db.FailUnfinishedJobs("Interrupted"))
+
+	stored, err = db.GetJob("b")
+	require.NoError(t, err)
+	assert.Equal(t, db_access.JobFailed, stored.Status)
+	assert.Equal(t, "Interrupted", stored.Error)
+
+	// finished jobs stay as they were
+	stored, err = db.GetJob("a")
+	require.NoError(t, err)
+	assert.Equal(t, db_access.JobFinished, stored.Status)
+
+	_, err = db.GetJob("missing")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+	assert.ErrorAs(t, db.UpdateJob(&db_access.Job{Id: "missing"}), &db_access.NoRowsError{})
+}
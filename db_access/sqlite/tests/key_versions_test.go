@@ -0,0 +1,89 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db, err := sqlite.New(path)
+	require.NoError(t, err)
+
+	dec := db_access.DEC{Value: "vault:v2:key", CreationTime: db_access.Time(time.Now()), KeyVersion: 2}
+	require.NoError(t, db.AddDEC(&dec))
+
+	// added before key versions were recorded
+	raw, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = raw.Exec(`INSERT INTO decs(value, creationTime) VALUES ('vault:v1:old', 0)`)
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+
+	db, err = sqlite.New(path)
+	require.NoError(t, err)
+
+	decs, err := db.GetDECs()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(decs))
+	assert.Equal(t, int64(1), decs[0].KeyVersion)
+	assert.Equal(t, int64(2), decs[1].KeyVersion)
+
+	require.NoError(t, db.RewrapDEC(decs[0].Id, "vault:v2:old", 2))
+	rewrapped, err := db.GetDEC(decs[0].Id)
+	require.NoError(t, err)
+	assert.Equal(t, "vault:v2:old", rewrapped.Value)
+	assert.Equal(t, int64(2), rewrapped.KeyVersion)
+
+	var nre db_access.NoRowsError
+	assert.ErrorAs(t, db.RewrapDEC(100, "vault:v2:gone", 2), &nre)
+
+	for name, fileName := range map[string]string{"a": "vault:v1:a", "b": "vault:v1:b", "c": "vault:v12:c", "d": "enc:d"} {
+		require.NoError(t, db.AddFile(name, fileName, 1, 1))
+	}
+
+	counts, err := db.CountNamesByKeyVersion()
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.KeyVersionCount{{KeyVersion: 1, Count: 2}, {KeyVersion: 12, Count: 1}}, counts)
+
+	names, err := db.GetNamesBelowKeyVersion(12, "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.WrappedName{{GeneratedName: "a", FileName: "vault:v1:a"}}, names)
+
+	names, err = db.GetNamesBelowKeyVersion(12, "a", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.WrappedName{{GeneratedName: "b", FileName: "vault:v1:b"}}, names)
+
+	require.NoError(t, db.RewrapFileName("a", "vault:v1:a", "vault:v12:a"))
+	// renamed since the name was read
+	assert.ErrorAs(t, db.RewrapFileName("b", "vault:v1:other", "vault:v12:other"), &nre)
+
+	counts, err = db.CountNamesByKeyVersion()
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.KeyVersionCount{{KeyVersion: 1, Count: 1}, {KeyVersion: 12, Count: 2}}, counts)
+}
+
+func TestCountNamesByDEC(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	for name, fileName := range map[string]string{"a": "dec:3:a", "b": "dec:3:b", "c": "dec:12:c", "d": "vault:v1:d", "e": "dec:x:e"} {
+		require.NoError(t, db.AddFile(name, fileName, 1, 1))
+	}
+
+	counts, err := db.CountNamesByDEC()
+	require.NoError(t, err)
+	assert.Equal(t, map[db_access.DecId]int{3: 2, 12: 1}, counts)
+
+	// names sealed locally aren't for vault to rewrap
+	names, err := db.GetNamesBelowKeyVersion(2, "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.WrappedName{{GeneratedName: "d", FileName: "vault:v1:d"}}, names)
+}
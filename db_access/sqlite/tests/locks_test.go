@@ -0,0 +1,54 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLocks(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+
+	_, err = db.GetFileLock("a")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+
+	now := time.Unix(time.Now().Unix(), 0)
+	lock := db_access.FileLock{
+		GeneratedName: "a",
+		Token:         "t1",
+		Holder:        "Word on laptop",
+		CreatedAt:     db_access.Time(now),
+		ExpiresAt:     db_access.Time(now.Add(time.Minute)),
+	}
+	require.NoError(t, db.SetFileLock(lock))
+
+	got, err := db.GetFileLock("a")
+	require.NoError(t, err)
+	assert.Equal(t, lock.Token, got.Token)
+	assert.Equal(t, lock.Holder, got.Holder)
+	assert.True(t, time.Time(lock.ExpiresAt).Equal(time.Time(got.ExpiresAt)))
+
+	lock.Token = "t2"
+	require.NoError(t, db.SetFileLock(lock))
+	got, err = db.GetFileLock("a")
+	require.NoError(t, err)
+	assert.Equal(t, "t2", got.Token)
+
+	require.NoError(t, db.RemoveFileLock("a"))
+	_, err = db.GetFileLock("a")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+
+	// locks go with their files
+	require.NoError(t, db.SetFileLock(lock))
+	require.NoError(t, db.RemoveFile("a"))
+	_, err = db.GetFileLock("a")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+}
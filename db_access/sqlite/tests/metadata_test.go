@@ -0,0 +1,62 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMetadata(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+	require.NoError(t, db.AddFile("b", "enc:b", 1, 4))
+
+	data, err := db.GetFileMetadata("a")
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	require.NoError(t, db.SetFileMetadata("a", 1, "enc:a-meta", []string{"x", "y"}))
+	require.NoError(t, db.SetFileMetadata("b", 1, "enc:b-meta", []string{"x"}))
+	assert.ErrorAs(t, db.SetFileMetadata("missing", 1, "enc", []string{"x"}), &db_access.NoRowsError{})
+
+	data, err = db.GetFileMetadata("a")
+	require.NoError(t, err)
+	assert.Equal(t, "enc:a-meta", data)
+	terms, err := db.GetFileMetadataTerms("a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, terms)
+
+	files, err := db.GetFilesByMetadata(1, []string{"x"})
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	files, err = db.GetFilesByMetadata(1, []string{"y", "x", "y"})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "a", files[0].GeneratedName)
+
+	files, err = db.GetFilesByMetadata(2, []string{"x"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// empty data removes the metadata along with its terms
+	require.NoError(t, db.SetFileMetadata("a", 1, "", nil))
+	data, err = db.GetFileMetadata("a")
+	require.NoError(t, err)
+	assert.Empty(t, data)
+	files, err = db.GetFilesByMetadata(1, []string{"y"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// metadata goes with its file
+	require.NoError(t, db.RemoveFile("b"))
+	files, err = db.GetFilesByMetadata(1, []string{"x"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,59 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFilesByNameIndex(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 0))
+	require.NoError(t, db.AddFile("b", "enc:b", 1, 0))
+	require.NoError(t, db.AddFile("legacy", "enc:c", 1, 0))
+	require.NoError(t, db.AddFile("other", "enc:a", 2, 0))
+	require.NoError(t, db.SetFileNameIndex("a", "idx:a"))
+	require.NoError(t, db.SetFileNameIndex("b", "idx:b"))
+	require.NoError(t, db.SetFileNameIndex("other", "idx:a"))
+	assert.ErrorAs(t, db.SetFileNameIndex("missing", "idx:a"), &db_access.NoRowsError{})
+
+	names := func(files []db_access.File) []string {
+		var names []string
+		for _, file := range files {
+			names = append(names, file.GeneratedName)
+		}
+		return names
+	}
+
+	files, err := db.GetFilesByNameIndex(1, "idx:a")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "legacy"}, names(files))
+
+	// a renamed file needs a new index
+	require.NoError(t, db.RenameFile("b", "enc:a"))
+	files, err = db.GetFilesByNameIndex(1, "idx:a")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "legacy"}, names(files))
+}
+
+func TestAddIndexKey(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	_, err = db.GetIndexKey()
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+
+	key, err := db.AddIndexKey("first")
+	require.NoError(t, err)
+	assert.Equal(t, "first", key)
+
+	key, err = db.AddIndexKey("second")
+	require.NoError(t, err)
+	assert.Equal(t, "first", key)
+}
@@ -0,0 +1,42 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkRules(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	user := db_access.NetworkRules{Scope: db_access.NetworkUser, Subject: "1", Deny: []string{"192.0.2.0/24"}}
+	device := db_access.NetworkRules{Scope: db_access.NetworkDevice, Subject: "uploader", Allow: []string{"10.0.0.0/8", "10.1.0.0/16"}}
+	other := db_access.NetworkRules{Scope: db_access.NetworkUser, Subject: "2", Allow: []string{"10.0.0.0/8"}}
+	for _, rules := range []db_access.NetworkRules{device, user, other} {
+		require.NoError(t, db.SetNetworkRules(rules))
+	}
+
+	got, err := db.GetRequestNetworkRules(1, "uploader")
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.NetworkRules{user, device}, got)
+
+	got, err = db.GetRequestNetworkRules(1, "")
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.NetworkRules{user}, got)
+
+	all, err := db.ListNetworkRules()
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, db_access.NetworkUser, all[0].Scope)
+	assert.Equal(t, db_access.NetworkDevice, all[2].Scope)
+
+	require.NoError(t, db.DeleteNetworkRules(db_access.NetworkUser, strconv.Itoa(1)))
+	var nre db_access.NoRowsError
+	assert.ErrorAs(t, db.DeleteNetworkRules(db_access.NetworkUser, "1"), &nre)
+}
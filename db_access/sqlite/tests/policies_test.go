@@ -0,0 +1,61 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicies(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	user := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&user))
+	subject := strconv.FormatInt(user.Id, 10)
+
+	quota := int64(1000)
+	retention := time.Hour
+	override := int64(1 << 40)
+	require.NoError(t, db.SetPolicy(db_access.Policy{Scope: db_access.UserScope, Subject: subject, Retention: &retention, MaxFileSizeOverride: &override}))
+	require.NoError(t, db.SetPolicy(db_access.Policy{Scope: db_access.OrgScope, Subject: "acme", AllowedTypes: []string{}}))
+	require.NoError(t, db.SetPolicy(db_access.Policy{Scope: db_access.GlobalScope, Quota: &quota, AllowedTypes: []string{"image/*", "text/plain"}}))
+
+	// the org policy only applies once the user is in it
+	policies, err := db.GetUserPolicies(user.Id)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(policies))
+	assert.Equal(t, db_access.GlobalScope, policies[0].Scope)
+	assert.Equal(t, quota, *policies[0].Quota)
+	assert.Nil(t, policies[0].MaxFileSize)
+	assert.Nil(t, policies[0].MaxFileSizeOverride)
+	assert.Equal(t, []string{"image/*", "text/plain"}, policies[0].AllowedTypes)
+	assert.Equal(t, db_access.UserScope, policies[1].Scope)
+	assert.Equal(t, retention, *policies[1].Retention)
+	assert.Equal(t, override, *policies[1].MaxFileSizeOverride)
+	assert.Nil(t, policies[1].AllowedTypes)
+
+	require.NoError(t, db.SetUserOrg(user.Id, "acme"))
+	policies, err = db.GetUserPolicies(user.Id)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(policies))
+	assert.Equal(t, db_access.OrgScope, policies[1].Scope)
+	assert.Equal(t, []string{}, policies[1].AllowedTypes)
+
+	// setting a policy again replaces it
+	require.NoError(t, db.SetPolicy(db_access.Policy{Scope: db_access.OrgScope, Subject: "acme"}))
+	all, err := db.ListPolicies()
+	require.NoError(t, err)
+	require.Equal(t, 3, len(all))
+	assert.Nil(t, all[1].AllowedTypes)
+
+	require.NoError(t, db.DeletePolicy(db_access.OrgScope, "acme"))
+	var nre db_access.NoRowsError
+	assert.ErrorAs(t, db.DeletePolicy(db_access.OrgScope, "acme"), &nre)
+}
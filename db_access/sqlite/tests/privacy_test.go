@@ -0,0 +1,135 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsents(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	alice := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&alice))
+
+	at := func(sec int64) db_access.Time { return db_access.Time(time.Unix(sec, 0)) }
+	records := []db_access.Consent{
+		{UserId: alice.Id, Purpose: "terms", Version: "2024-01", At: at(1)},
+		{UserId: alice.Id, Purpose: "analytics", Version: "1", At: at(2)},
+		{UserId: alice.Id, Purpose: "analytics", At: at(3)},
+	}
+	for _, consent := range records {
+		require.NoError(t, db.AddConsent(consent))
+	}
+
+	got, err := db.GetConsents(alice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, records, got)
+
+	got, err = db.GetConsents(alice.Id + 1)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestDeletionRequests(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	alice := db_access.User{Name: "alice", PasswordHash: []byte("hash"), Org: "acme"}
+	require.NoError(t, db.AddUser(&alice))
+	bob := db_access.User{Name: "bob"}
+	require.NoError(t, db.AddUser(&bob))
+
+	at := func(sec int64) db_access.Time { return db_access.Time(time.Unix(sec, 0)) }
+	require.NoError(t, db.AddDeletionRequest(db_access.DeletionRequest{UserId: bob.Id, RequestedAt: at(1), Deadline: at(20)}))
+	require.NoError(t, db.AddDeletionRequest(db_access.DeletionRequest{UserId: alice.Id, RequestedAt: at(2), Deadline: at(10)}))
+
+	var uce db_access.UniqueConstraintError
+	assert.ErrorAs(t, db.AddDeletionRequest(db_access.DeletionRequest{UserId: alice.Id, RequestedAt: at(3), Deadline: at(30)}), &uce)
+
+	pending, err := db.GetPendingDeletionRequests()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, alice.Id, pending[0].UserId)
+	assert.Equal(t, bob.Id, pending[1].UserId)
+
+	require.NoError(t, db.AddDevice(db_access.Device{Id: "phone", OwnerId: alice.Id, Name: "Phone", RefreshHash: []byte("h"), CreatedAt: at(1), LastUsed: at(1)}))
+	require.NoError(t, db.AddTraffic(alice.Id, at(1), 10, 20))
+	require.NoError(t, db.AddTraffic(bob.Id, at(1), 1, 2))
+	require.NoError(t, db.EraseUser(alice.Id))
+	require.NoError(t, db.CompleteDeletionRequest(alice.Id, at(5)))
+
+	// the name is free again and the row keeps nothing of alice
+	erased := db_access.User{Id: alice.Id}
+	require.NoError(t, db.GetUser(&erased))
+	assert.NotEqual(t, "alice", erased.Name)
+	assert.Empty(t, erased.PasswordHash)
+	assert.Empty(t, erased.Org)
+	assert.Equal(t, alice.TokenVersion+1, erased.TokenVersion)
+	require.NoError(t, db.AddUser(&db_access.User{Name: "alice"}))
+
+	var nre db_access.NoRowsError
+	_, err = db.GetDevice("phone")
+	assert.ErrorAs(t, err, &nre)
+
+	traffic, err := db.GetTrafficByUser(at(1))
+	require.NoError(t, err)
+	require.Len(t, traffic, 1)
+	assert.Equal(t, bob.Id, traffic[0].OwnerId)
+
+	req, err := db.GetDeletionRequest(alice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, at(5), req.CompletedAt)
+
+	pending, err = db.GetPendingDeletionRequests()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, bob.Id, pending[0].UserId)
+
+	assert.ErrorAs(t, db.EraseUser(bob.Id+100), &nre)
+	_, err = db.GetDeletionRequest(bob.Id + 100)
+	assert.ErrorAs(t, err, &nre)
+}
+
+func TestUserDECs(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	at := func(sec int64) db_access.Time { return db_access.Time(time.Unix(sec, 0)) }
+	decs := []db_access.DEC{
+		{Value: "shared", CreationTime: at(1)},
+		{Value: "alice", CreationTime: at(2), OwnerId: 1},
+		{Value: "alice newer", CreationTime: at(3), OwnerId: 1},
+		{Value: "bob", CreationTime: at(4), OwnerId: 2},
+	}
+	for i := range decs {
+		require.NoError(t, db.AddDEC(&decs[i]))
+	}
+
+	// keys of users are never the shared one in use, however new
+	shared, err := db.GetNewestDEC()
+	require.NoError(t, err)
+	assert.Equal(t, decs[0], shared)
+
+	newest, err := db.GetNewestUserDEC(1)
+	require.NoError(t, err)
+	assert.Equal(t, decs[2], newest)
+
+	ids, err := db.RemoveUserDECs(1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []db_access.DecId{decs[1].Id, decs[2].Id}, ids)
+
+	var nre db_access.NoRowsError
+	_, err = db.GetNewestUserDEC(1)
+	assert.ErrorAs(t, err, &nre)
+
+	left, err := db.GetDECs()
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.DEC{decs[0], decs[3]}, left)
+}
@@ -0,0 +1,121 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeFeed(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	// nothing is recorded before a consumer has a cursor
+	require.NoError(t, db.AddFile("early", "enc:early", 1, 4))
+	_, err = db.GetChangeCursor("replica")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+	require.NoError(t, db.SetChangeCursor("replica", 0))
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+	require.NoError(t, db.AddFileCopy("b", "enc:b", 1, "a", 4))
+	require.NoError(t, db.RenameFile("a", "enc:renamed"))
+	require.NoError(t, db.RecordFileAccesses([]db_access.FileAccess{{GeneratedName: "a", Count: 1}}))
+	require.NoError(t, db.RemoveFile("a"))
+	require.NoError(t, db.RemoveFile("b"))
+
+	changes, err := db.GetChanges(0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 5)
+
+	kinds := make([]db_access.ChangeKind, 0, len(changes))
+	for _, change := range changes {
+		kinds = append(kinds, change.Kind)
+	}
+	assert.Equal(t, []db_access.ChangeKind{
+		db_access.ChangeCreated,
+		db_access.ChangeCreated,
+		db_access.ChangeUpdated,
+		db_access.ChangeDeleted,
+		db_access.ChangeDeleted,
+	}, kinds)
+	assert.Equal(t, "a", changes[1].BlobName)
+	assert.Equal(t, int64(1), changes[1].OwnerId)
+	assert.Equal(t, int64(4), changes[1].Size)
+	// the copy still referenced the blob when its source went
+	assert.Empty(t, changes[3].BlobName)
+	assert.Equal(t, "a", changes[4].BlobName)
+
+	require.NoError(t, db.SetChangeCursor("replica", changes[2].Seq))
+	require.NoError(t, db.RemoveReadChanges())
+	changes, err = db.GetChanges(0, 10)
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+
+	// without consumers nothing is kept or recorded
+	require.NoError(t, db.KeepChangeCursors(nil))
+	require.NoError(t, db.AddFile("late", "enc:late", 1, 4))
+	changes, err = db.GetChanges(0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestReplicatedBlobs(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+	require.NoError(t, db.AddFileCopy("a-copy", "enc:a", 1, "a", 4))
+	require.NoError(t, db.AddFile("b", "enc:b", 1, 2))
+
+	blobs, err := db.GetUnreplicatedBlobs("dr", "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.Blob{{Name: "a", Backend: "local", Size: 4}, {Name: "b", Backend: "local", Size: 2}}, blobs)
+
+	blobs, err = db.GetUnreplicatedBlobs("dr", "a", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.Blob{{Name: "b", Backend: "local", Size: 2}}, blobs)
+
+	require.NoError(t, db.MarkBlobReplicated("dr", "a"))
+	require.NoError(t, db.MarkBlobReplicated("dr", "a"))
+	blobs, err = db.GetUnreplicatedBlobs("dr", "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.Blob{{Name: "b", Backend: "local", Size: 2}}, blobs)
+
+	require.NoError(t, db.RemoveFile("a"))
+	require.NoError(t, db.RemoveFile("a-copy"))
+	replicated, err := db.GetReplicatedBlobs("dr")
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.ReplicatedBlob{{Name: "a", Referenced: false}}, replicated)
+
+	require.NoError(t, db.UnmarkBlobReplicated("dr", "a"))
+	replicated, err = db.GetReplicatedBlobs("dr")
+	require.NoError(t, err)
+	assert.Empty(t, replicated)
+}
+
+func TestChangeFeed_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db, err := sqlite.New(path)
+	require.NoError(t, err)
+	require.NoError(t, db.SetChangeCursor("replica", 0))
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+
+	// a read-only open leaves the db as it is, as a backup needs it to
+	readOnly, err := sqlite.New("file:" + path + "?mode=ro")
+	require.NoError(t, err)
+	names, err := readOnly.ListBlobNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, names)
+
+	// the triggers are only created again when they changed
+	again, err := sqlite.New(path)
+	require.NoError(t, err)
+	require.NoError(t, again.AddFile("b", "enc:b", 1, 4))
+	changes, err := again.GetChanges(0, 10)
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+}
@@ -0,0 +1,96 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"context"
+	"database/sql"
+	"expvar"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openBusy opens a db that doesn't wait for locks itself, so that writes fail with SQLITE_BUSY at once,
+// and returns it with a connection in a read transaction; writes can be made but not committed until it ends
+func openBusy(t *testing.T) (db_access.DbAccess, *sql.Conn) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db, err := sqlite.New(path + "?_busy_timeout=0")
+	require.NoError(t, err)
+
+	other, err := sql.Open("sqlite3", path+"?_busy_timeout=0")
+	require.NoError(t, err)
+	t.Cleanup(func() { other.Close() })
+
+	lock, err := other.Conn(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() { lock.Close() })
+	_, err = lock.ExecContext(context.Background(), "BEGIN")
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, lock.QueryRowContext(context.Background(), "SELECT count(*) FROM files").Scan(&n))
+
+	return db, lock
+}
+
+func busyCounter(t *testing.T, name string) int64 {
+	counter, ok := expvar.Get(name).(*expvar.Int)
+	require.True(t, ok, name)
+	return counter.Value()
+}
+
+func TestRetryBusy_GivesUp(t *testing.T) {
+	db, _ := openBusy(t)
+	retries, failures := busyCounter(t, "sqlite_busy_retries"), busyCounter(t, "sqlite_busy_failures")
+
+	start := time.Now()
+	err := db.AddFile("a", "name", 1, 10)
+	elapsed := time.Since(start)
+
+	var sqliteErr sqlite3.Error
+	require.ErrorAs(t, err, &sqliteErr)
+	assert.Equal(t, sqlite3.ErrBusy, sqliteErr.Code)
+
+	// five attempts in all, with four waits between them of 10, 20, 40 and 80ms plus up to as much again
+	assert.Equal(t, int64(4), busyCounter(t, "sqlite_busy_retries")-retries)
+	assert.Equal(t, int64(1), busyCounter(t, "sqlite_busy_failures")-failures)
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	assert.Less(t, elapsed, 300*time.Millisecond+200*time.Millisecond)
+}
+
+func TestInTx_RetriedAfterBusy(t *testing.T) {
+	db, lock := openBusy(t)
+	retries, failures := busyCounter(t, "sqlite_busy_retries"), busyCounter(t, "sqlite_busy_failures")
+
+	// the first run makes its writes, then fails to commit them; had they stayed, the second
+	// would fail on the name taken by the first
+	runs := 0
+	err := db.WithTx(context.Background(), func(repos db_access.DbAccess) error {
+		runs++
+		if runs == 2 {
+			_, err := lock.ExecContext(context.Background(), "COMMIT")
+			require.NoError(t, err)
+		}
+
+		if err := repos.AddFile("a", "name", 1, 10); err != nil {
+			return err
+		}
+		return repos.SetFileExpiry("a", db_access.Time(time.Now().Add(time.Hour)))
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, int64(1), busyCounter(t, "sqlite_busy_retries")-retries)
+	assert.Equal(t, int64(0), busyCounter(t, "sqlite_busy_failures")-failures)
+
+	usage, err := db.GetUsage(1, db_access.Time(time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), usage.StoredBytes)
+	file, err := db.GetFile("a")
+	require.NoError(t, err)
+	assert.False(t, time.Time(file.ExpiresAt).IsZero())
+}
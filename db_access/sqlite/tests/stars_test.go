@@ -0,0 +1,52 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStars(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddFile("a", "enc:a", 1, 4))
+	require.NoError(t, db.AddFile("b", "enc:b", 1, 4))
+	require.NoError(t, db.AddFile("c", "enc:c", 2, 4))
+
+	now := time.Now()
+	require.NoError(t, db.StarFile(1, "a", db_access.Time(now.Add(-time.Hour))))
+	require.NoError(t, db.StarFile(1, "b", db_access.Time(now)))
+	// starring again keeps the first time
+	require.NoError(t, db.StarFile(1, "a", db_access.Time(now.Add(time.Hour))))
+	require.NoError(t, db.StarFile(2, "c", db_access.Time(now)))
+
+	ids := func(files []db_access.File) []string {
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.GeneratedName)
+		}
+		return names
+	}
+
+	files, err := db.GetStarredFiles(1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, ids(files))
+
+	require.NoError(t, db.UnstarFile(1, "b"))
+	require.NoError(t, db.UnstarFile(1, "b"))
+	files, err = db.GetStarredFiles(1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids(files))
+
+	// stars go with their files
+	require.NoError(t, db.RemoveFile("c"))
+	files, err = db.GetStarredFiles(2)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,70 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTx(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	month := db_access.Time(time.Now())
+	errAbort := errors.New("abort")
+
+	err = db.WithTx(context.Background(), func(repos db_access.DbAccess) error {
+		require.NoError(t, repos.AddFile("rolled-back", "name", 1, 10))
+		// the file counts as stored within the transaction
+		usage, err := repos.GetUsage(1, month)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), usage.StoredBytes)
+
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	_, err = db.GetFile("rolled-back")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+	usage, err := db.GetUsage(1, month)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), usage.StoredBytes)
+
+	err = db.WithTx(context.Background(), func(repos db_access.DbAccess) error {
+		if err := repos.AddFile("committed", "name", 1, 10); err != nil {
+			return err
+		}
+		// methods with transactions of their own and nested WithTx join the outer one
+		if err := repos.AddFileTags("committed", []string{"a"}); err != nil {
+			return err
+		}
+		return repos.WithTx(context.Background(), func(repos db_access.DbAccess) error {
+			return repos.SetFileExpiry("committed", db_access.Time(time.Now().Add(time.Hour)))
+		})
+	})
+	require.NoError(t, err)
+
+	file, err := db.GetFile("committed")
+	require.NoError(t, err)
+	assert.False(t, time.Time(file.ExpiresAt).IsZero())
+
+	// a failing step rolls back the ones before it
+	err = db.WithTx(context.Background(), func(repos db_access.DbAccess) error {
+		if err := repos.RenameFile("committed", "renamed"); err != nil {
+			return err
+		}
+		return repos.AddFile("committed", "duplicate", 1, 1)
+	})
+	assert.ErrorAs(t, err, &db_access.UniqueConstraintError{})
+
+	file, err = db.GetFile("committed")
+	require.NoError(t, err)
+	assert.Equal(t, "name", file.FileName)
+}
@@ -0,0 +1,118 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageCounters(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	for _, name := range []string{"alice", "bob"} {
+		require.NoError(t, db.AddUser(&db_access.User{Name: name}))
+	}
+
+	require.NoError(t, db.AddFile("a", "name", 1, 100))
+	require.NoError(t, db.AddFileCopy("a-copy", "name", 1, "a", 100))
+	require.NoError(t, db.AddFile("b", "name", 2, 7))
+	_, _, err = db.DeleteFile("a")
+	require.NoError(t, err)
+
+	may := db_access.Time(time.Date(2024, 5, 31, 23, 0, 0, 0, time.UTC))
+	june := db_access.Time(time.Date(2024, 6, 1, 1, 0, 0, 0, time.UTC))
+	require.NoError(t, db.AddTraffic(1, may, 100, 0))
+	require.NoError(t, db.AddTraffic(1, may, 0, 40))
+	require.NoError(t, db.AddTraffic(1, june, 0, 5))
+	require.NoError(t, db.AddTraffic(2, may, 7, 0))
+
+	usage, err := db.GetUsage(1, may)
+	require.NoError(t, err)
+	assert.Equal(t, db_access.Usage{Users: 1, Files: 1, StoredBytes: 100, UploadedBytes: 100, DownloadedBytes: 40}, usage)
+
+	usage, err = db.GetUsage(1, june)
+	require.NoError(t, err)
+	assert.Equal(t, db_access.Usage{Users: 1, Files: 1, StoredBytes: 100, DownloadedBytes: 5}, usage)
+
+	usage, err = db.GetUsage(3, may)
+	require.NoError(t, err)
+	assert.Equal(t, db_access.Usage{Users: 1}, usage)
+
+	total, err := db.GetTotalUsage(may)
+	require.NoError(t, err)
+	assert.Equal(t, db_access.Usage{Users: 2, Files: 2, StoredBytes: 107, UploadedBytes: 107, DownloadedBytes: 40}, total)
+
+	traffic, err := db.GetTrafficByUser(may)
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.UserTraffic{
+		{OwnerId: 1, Name: "alice", UploadedBytes: 100, DownloadedBytes: 40},
+		{OwnerId: 2, Name: "bob", UploadedBytes: 7},
+	}, traffic)
+
+	traffic, err = db.GetTrafficByUser(db_access.Time(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, err)
+	assert.Empty(t, traffic)
+}
+
+func TestUsageCounters_Backfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db, err := sqlite.New(path)
+	require.NoError(t, err)
+	require.NoError(t, db.AddFile("a", "name", 1, 10))
+	require.NoError(t, db.AddFile("b", "name", 1, 20))
+
+	// pretend the files were stored before the counters existed
+	raw, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = raw.Exec(`DROP TRIGGER trg_files_usage_insert; DROP TRIGGER trg_files_usage_delete; DROP TABLE usage;`)
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+
+	// the second open must not count the same files twice
+	for range 2 {
+		db, err = sqlite.New(path)
+		require.NoError(t, err)
+	}
+
+	usage, err := db.GetUsage(1, db_access.Time(time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Files)
+	assert.Equal(t, int64(30), usage.StoredBytes)
+}
+
+func TestRecalculateUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db, err := sqlite.New(path)
+	require.NoError(t, err)
+	require.NoError(t, db.AddFile("a", "name", 1, 10))
+	require.NoError(t, db.AddFile("b", "name", 2, 20))
+
+	fixed, err := db.RecalculateUsage()
+	require.NoError(t, err)
+	assert.Zero(t, fixed)
+
+	// rows changed around the triggers
+	raw, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = raw.Exec(`UPDATE usage SET files = 5, storedBytes = 0 WHERE ownerId = 1; DELETE FROM usage WHERE ownerId = 2;`)
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+
+	fixed, err = db.RecalculateUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), fixed)
+
+	for ownerId, stored := range map[int64]int64{1: 10, 2: 20} {
+		usage, err := db.GetUsage(ownerId, db_access.Time(time.Now()))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Files)
+		assert.Equal(t, stored, usage.StoredBytes)
+	}
+}
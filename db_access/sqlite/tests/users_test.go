@@ -0,0 +1,134 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRoles(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	user := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&user))
+
+	got := db_access.User{Name: "alice"}
+	require.NoError(t, db.GetUser(&got))
+	assert.Nil(t, got.Roles)
+	assert.Nil(t, got.PasswordHash)
+
+	require.NoError(t, db.SetUserRoles(user.Id, []string{"admin", "auditor"}))
+	got = db_access.User{Id: user.Id}
+	require.NoError(t, db.GetUser(&got))
+	assert.Equal(t, "alice", got.Name)
+	assert.Equal(t, []string{"admin", "auditor"}, got.Roles)
+
+	require.NoError(t, db.SetUserRoles(user.Id, nil))
+	got = db_access.User{Name: "alice"}
+	require.NoError(t, db.GetUser(&got))
+	assert.Nil(t, got.Roles)
+
+	var nre db_access.NoRowsError
+	assert.ErrorAs(t, db.SetUserRoles(user.Id+1, nil), &nre)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	user := db_access.User{Name: "alice"}
+	require.NoError(t, db.AddUser(&user))
+
+	require.NoError(t, db.RevokeUserTokens(user.Id))
+	require.NoError(t, db.RevokeUserTokens(user.Id))
+	got := db_access.User{Name: "alice"}
+	require.NoError(t, db.GetUser(&got))
+	assert.Equal(t, int64(2), got.TokenVersion)
+
+	var nre db_access.NoRowsError
+	assert.ErrorAs(t, db.RevokeUserTokens(user.Id+1), &nre)
+}
+
+func TestAddUser_TakenName(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddUser(&db_access.User{Name: "alice"}))
+
+	// a name is taken in any case, and the error tells which constraint it broke
+	for _, name := range []string{"alice", "Alice", "ALICE"} {
+		var uce db_access.UniqueConstraintError
+		require.ErrorAs(t, db.AddUser(&db_access.User{Name: name}), &uce)
+		assert.Equal(t, db_access.UniqueConstraintError{Table: "users", Column: "name"}, uce)
+	}
+
+	require.NoError(t, db.AddUser(&db_access.User{Name: "alicia"}))
+}
+
+func TestRenameUser(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	alice := db_access.User{Name: "Alice"}
+	require.NoError(t, db.AddUser(&alice))
+	bob := db_access.User{Name: "bob", Roles: []string{"auditor"}}
+	require.NoError(t, db.AddUser(&bob))
+
+	users, err := db.GetUsers()
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.User{{Id: alice.Id, Name: "Alice"}, {Id: bob.Id, Name: "bob", Roles: []string{"auditor"}}}, users)
+
+	require.NoError(t, db.RenameUser(alice.Id, "alice"))
+	got := db_access.User{Name: "alice"}
+	require.NoError(t, db.GetUser(&got))
+	assert.Equal(t, alice.Id, got.Id)
+
+	var uce db_access.UniqueConstraintError
+	assert.ErrorAs(t, db.RenameUser(alice.Id, "BOB"), &uce)
+	var nre db_access.NoRowsError
+	assert.ErrorAs(t, db.RenameUser(bob.Id+1, "carol"), &nre)
+}
+
+func TestUniqueUserNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db, err := sqlite.New(path)
+	require.NoError(t, err)
+	alice := db_access.User{Name: "Alice"}
+	require.NoError(t, db.AddUser(&alice))
+
+	// users of a database from before names were unique in any case
+	raw, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer raw.Close()
+	_, err = raw.Exec(`DROP INDEX idx_users_name_nocase`)
+	require.NoError(t, err)
+	_, err = raw.Exec(`INSERT INTO users(name, passwordHash) VALUES ('alice', x''), ('ALICE', x''), ('bob', x'')`)
+	require.NoError(t, err)
+
+	// starts without the index, which waits for the names to be renamed
+	db, err = sqlite.New(path)
+	require.NoError(t, err)
+	var unce db_access.UserNamesConflictError
+	require.ErrorAs(t, db.UniqueUserNames(), &unce)
+	assert.Equal(t, [][]string{{"Alice", "alice", "ALICE"}}, unce.Names)
+	require.NoError(t, db.AddUser(&db_access.User{Name: "aLICE"}))
+
+	users, err := db.GetUsers()
+	require.NoError(t, err)
+	for _, user := range users {
+		if user.Id != alice.Id && user.Name != "bob" {
+			require.NoError(t, db.RenameUser(user.Id, fmt.Sprintf("alice-%d", user.Id)))
+		}
+	}
+	require.NoError(t, db.UniqueUserNames())
+
+	var uce db_access.UniqueConstraintError
+	assert.ErrorAs(t, db.AddUser(&db_access.User{Name: "ALICE"}), &uce)
+}
@@ -0,0 +1,54 @@
+package sqlite_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionVersion(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	version := func(ownerId int64) int64 {
+		v, err := db.GetCollectionVersion(ownerId)
+		require.NoError(t, err)
+		return v
+	}
+
+	assert.Zero(t, version(1))
+
+	require.NoError(t, db.AddFile("a", "name", 1, 100))
+	require.NoError(t, db.AddFile("b", "name", 2, 7))
+	v := version(1)
+	assert.Positive(t, v)
+
+	changes := []func() error{
+		func() error { return db.RenameFile("a", "other") },
+		func() error { return db.SetFileExpiry("a", db_access.Time(time.Now().Add(time.Hour))) },
+		func() error {
+			return db.RecordFileAccesses([]db_access.FileAccess{{GeneratedName: "a", At: db_access.Time(time.Now()), Count: 1}})
+		},
+		func() error { return db.SetFileMetadata("a", 1, "data", []string{"term"}) },
+		func() error {
+			_, _, err := db.DeleteFile("a")
+			return err
+		},
+	}
+	for i, change := range changes {
+		require.NoError(t, change(), i)
+		next := version(1)
+		assert.Greater(t, next, v, i)
+		v = next
+	}
+
+	// changes to the files of others don't count
+	v2 := version(2)
+	require.NoError(t, db.AddFile("c", "name", 1, 1))
+	assert.Equal(t, v2, version(2))
+}
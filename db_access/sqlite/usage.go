@@ -0,0 +1,205 @@
+package sqlite
+
+import (
+	"cloud-storage/db_access"
+	"context"
+	"fmt"
+	"time"
+)
+
+// createUsageTables sets up counters that triggers on files maintain,
+// so that usage queries never have to scan the files table
+func (db *SqliteDb) createUsageTables() error {
+	const op = "db-access.sqlite.createUsageTables"
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'usage')`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("%s: check usage table: %w", op, err)
+	}
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create usage table", `
+		CREATE TABLE IF NOT EXISTS usage(
+			ownerId INTEGER PRIMARY KEY REFERENCES users(id),
+			files INTEGER NOT NULL DEFAULT 0,
+			storedBytes INTEGER NOT NULL DEFAULT 0
+		);`},
+		{"create traffic table", `
+		CREATE TABLE IF NOT EXISTS traffic(
+			ownerId INTEGER NOT NULL REFERENCES users(id),
+			month TEXT NOT NULL,
+			uploadedBytes INTEGER NOT NULL DEFAULT 0,
+			downloadedBytes INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY(ownerId, month)
+		);`},
+		{"create traffic month index", `CREATE INDEX IF NOT EXISTS idx_traffic_month ON traffic(month);`},
+		{"create insert trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_usage_insert AFTER INSERT ON files
+		WHEN NEW.ownerId IS NOT NULL
+		BEGIN
+			INSERT OR IGNORE INTO usage(ownerId) VALUES (NEW.ownerId);
+			UPDATE usage SET files = files + 1, storedBytes = storedBytes + NEW.size WHERE ownerId = NEW.ownerId;
+		END;`},
+		{"create delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_usage_delete AFTER DELETE ON files
+		WHEN OLD.ownerId IS NOT NULL
+		BEGIN
+			UPDATE usage SET files = files - 1, storedBytes = storedBytes - OLD.size WHERE ownerId = OLD.ownerId;
+		END;`},
+	}
+
+	if !exists {
+		// counts files that predate the counters; done once, since from here on the triggers keep up
+		statements = append(statements, struct {
+			name  string
+			query string
+		}{"backfill usage", `
+		INSERT INTO usage(ownerId, files, storedBytes)
+		SELECT ownerId, COUNT(*), SUM(size) FROM files WHERE ownerId IS NOT NULL GROUP BY ownerId;`})
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func month(t db_access.Time) string {
+	return time.Time(t).UTC().Format("2006-01")
+}
+
+func (db *SqliteDb) AddTraffic(ownerId int64, at db_access.Time, uploaded int64, downloaded int64) error {
+	const op = "db-access.sqlite.AddTraffic"
+
+	_, err := db.Exec(`
+		INSERT INTO traffic(ownerId, month, uploadedBytes, downloadedBytes) VALUES (?,?,?,?)
+		ON CONFLICT(ownerId, month) DO UPDATE SET
+			uploadedBytes = uploadedBytes + excluded.uploadedBytes,
+			downloadedBytes = downloadedBytes + excluded.downloadedBytes`,
+		ownerId,
+		month(at),
+		uploaded,
+		downloaded,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: db.Exec: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetUsage(ownerId int64, at db_access.Time) (db_access.Usage, error) {
+	const op = "db-access.sqlite.GetUsage"
+
+	usage := db_access.Usage{Users: 1}
+	err := db.QueryRow(`
+		SELECT
+			COALESCE((SELECT files FROM usage WHERE ownerId = ?1), 0),
+			COALESCE((SELECT storedBytes FROM usage WHERE ownerId = ?1), 0),
+			COALESCE((SELECT uploadedBytes FROM traffic WHERE ownerId = ?1 AND month = ?2), 0),
+			COALESCE((SELECT downloadedBytes FROM traffic WHERE ownerId = ?1 AND month = ?2), 0)`,
+		ownerId,
+		month(at),
+	).Scan(&usage.Files, &usage.StoredBytes, &usage.UploadedBytes, &usage.DownloadedBytes)
+	if err != nil {
+		return db_access.Usage{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return usage, nil
+}
+
+func (db *SqliteDb) GetTotalUsage(at db_access.Time) (db_access.Usage, error) {
+	const op = "db-access.sqlite.GetTotalUsage"
+
+	var usage db_access.Usage
+	err := db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			COALESCE((SELECT SUM(files) FROM usage), 0),
+			COALESCE((SELECT SUM(storedBytes) FROM usage), 0),
+			COALESCE((SELECT SUM(uploadedBytes) FROM traffic WHERE month = ?1), 0),
+			COALESCE((SELECT SUM(downloadedBytes) FROM traffic WHERE month = ?1), 0)`,
+		month(at),
+	).Scan(&usage.Users, &usage.Files, &usage.StoredBytes, &usage.UploadedBytes, &usage.DownloadedBytes)
+	if err != nil {
+		return db_access.Usage{}, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return usage, nil
+}
+
+func (db *SqliteDb) GetTrafficByUser(at db_access.Time) ([]db_access.UserTraffic, error) {
+	const op = "db-access.sqlite.GetTrafficByUser"
+
+	rows, err := db.Query(`
+		SELECT traffic.ownerId, COALESCE(users.name, ''), traffic.uploadedBytes, traffic.downloadedBytes
+		FROM traffic LEFT JOIN users ON users.id = traffic.ownerId
+		WHERE traffic.month = ?
+		ORDER BY traffic.uploadedBytes + traffic.downloadedBytes DESC, traffic.ownerId`,
+		month(at),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: db.Query: %w", op, err)
+	}
+	defer rows.Close()
+
+	traffic := make([]db_access.UserTraffic, 0)
+	for rows.Next() {
+		var t db_access.UserTraffic
+		if err := rows.Scan(&t.OwnerId, &t.Name, &t.UploadedBytes, &t.DownloadedBytes); err != nil {
+			return nil, fmt.Errorf("%s: rows.Scan: %w", op, err)
+		}
+		traffic = append(traffic, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows.Err: %w", op, err)
+	}
+
+	return traffic, nil
+}
+
+// RecalculateUsage counts the files of every user again and returns for how many users
+// the counters were off; the triggers keep them right unless rows were changed around them
+func (db *SqliteDb) RecalculateUsage() (int64, error) {
+	const op = "db-access.sqlite.RecalculateUsage"
+
+	var fixed int64
+	err := db.inTx(context.Background(), func(tx *SqliteDb) error {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO usage(ownerId) SELECT DISTINCT ownerId FROM files WHERE ownerId IS NOT NULL`)
+		if err != nil {
+			return fmt.Errorf("%s: add missing counters: %w", op, err)
+		}
+
+		res, err := tx.Exec(`
+			WITH counted AS (
+				SELECT usage.ownerId, COUNT(files.ownerId) AS files, COALESCE(SUM(files.size), 0) AS storedBytes
+				FROM usage LEFT JOIN files ON files.ownerId = usage.ownerId
+				GROUP BY usage.ownerId
+			)
+			UPDATE usage SET files = counted.files, storedBytes = counted.storedBytes
+			FROM counted
+			WHERE counted.ownerId = usage.ownerId
+				AND (counted.files != usage.files OR counted.storedBytes != usage.storedBytes)`)
+		if err != nil {
+			return fmt.Errorf("%s: update counters: %w", op, err)
+		}
+
+		if fixed, err = res.RowsAffected(); err != nil {
+			return fmt.Errorf("%s: res.RowsAffected: %w", op, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return fixed, nil
+}
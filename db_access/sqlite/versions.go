@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// createCollectionVersions keeps a version per owner that the db moves on itself with every change
+// to their files or the metadata terms listings are filtered by, whatever makes the change
+func (db *SqliteDb) createCollectionVersions() error {
+	const op = "db-access.sqlite.createCollectionVersions"
+
+	bump := func(owner string) string {
+		return `INSERT INTO collectionVersions(ownerId, version) VALUES (` + owner + `, 1)
+			ON CONFLICT(ownerId) DO UPDATE SET version = version + 1;`
+	}
+
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"create collectionVersions table", `
+		CREATE TABLE IF NOT EXISTS collectionVersions(
+			ownerId INTEGER PRIMARY KEY,
+			version INTEGER NOT NULL
+		);`},
+		{"create files insert trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_version_insert AFTER INSERT ON files
+		WHEN NEW.ownerId IS NOT NULL
+		BEGIN
+			` + bump("NEW.ownerId") + `
+		END;`},
+		{"create files update trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_version_update AFTER UPDATE ON files
+		WHEN NEW.ownerId IS NOT NULL
+		BEGIN
+			` + bump("NEW.ownerId") + `
+		END;`},
+		// the file left the collection of its previous owner
+		{"create files owner trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_version_owner AFTER UPDATE OF ownerId ON files
+		WHEN OLD.ownerId IS NOT NULL AND OLD.ownerId IS NOT NEW.ownerId
+		BEGIN
+			` + bump("OLD.ownerId") + `
+		END;`},
+		{"create files delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_files_version_delete AFTER DELETE ON files
+		WHEN OLD.ownerId IS NOT NULL
+		BEGIN
+			` + bump("OLD.ownerId") + `
+		END;`},
+		{"create metadataTerms insert trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_metadataTerms_version_insert AFTER INSERT ON metadataTerms
+		BEGIN
+			` + bump("NEW.ownerId") + `
+		END;`},
+		{"create metadataTerms delete trigger", `
+		CREATE TRIGGER IF NOT EXISTS trg_metadataTerms_version_delete AFTER DELETE ON metadataTerms
+		BEGIN
+			` + bump("OLD.ownerId") + `
+		END;`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Execute(stmt.query); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, stmt.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *SqliteDb) GetCollectionVersion(ownerId int64) (int64, error) {
+	const op = "db-access.sqlite.GetCollectionVersion"
+
+	var version int64
+	err := db.QueryRow(`SELECT version FROM collectionVersions WHERE ownerId = ?`, ownerId).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("%s: db.QueryRow: %w", op, err)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,225 @@
+// Package delta lets clients upload a new version of a file as its changes against the stored one, the way rsync
+// does: the server hands out checksums of the blocks of its version, the client finds those blocks in its own with
+// a rolling checksum and sends references to them along with literal data for everything else.
+//
+// Stored files are encrypted as one stream that can only be read from the start, so a delta has to reference the
+// blocks in the order they come in the stored file; a block found before one referenced already is sent as literal data.
+package delta
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	MinBlockSize     = 512
+	MaxBlockSize     = 1 << 20
+	DefaultBlockSize = 64 << 10
+)
+
+// ErrInvalidDelta is returned for deltas that are malformed or reference blocks the stored file doesn't have
+var ErrInvalidDelta = errors.New("invalid delta")
+
+// a delta is a sequence of operations, each a byte telling its kind followed by uvarint arguments:
+// opCopy start count copies count blocks of the stored file from block start on,
+// opLiteral n is followed by n bytes of data
+const (
+	opCopy    = 'c'
+	opLiteral = 'l'
+)
+
+type Block struct {
+	// Weak is the rolling checksum of the block, see Weak
+	Weak uint32 `json:"weak"`
+	// Strong is the hex sha256 of the block
+	Strong string `json:"strong"`
+}
+
+type Signature struct {
+	BlockSize int   `json:"block_size"`
+	Size      int64 `json:"size"`
+	// Blocks are every blockSize bytes of the file, the last one may be shorter
+	Blocks []Block `json:"blocks"`
+}
+
+// Sign reads r to its end and returns the checksums of its blocks
+func Sign(r io.Reader, blockSize int) (Signature, error) {
+	const op = "delta.Sign"
+
+	sig := Signature{BlockSize: blockSize, Blocks: make([]Block, 0)}
+	buf := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			strong := sha256.Sum256(buf[:n])
+			sig.Blocks = append(sig.Blocks, Block{Weak: Weak(buf[:n]), Strong: hex.EncodeToString(strong[:])})
+			sig.Size += int64(n)
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return sig, nil
+		} else if err != nil {
+			return Signature{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+}
+
+// Weak is the checksum of rsync: with a the sum of the bytes of block and b the sum of every intermediate a,
+// both mod 2^16, it is a | b<<16
+func Weak(block []byte) uint32 {
+	var a, b uint32
+	for _, x := range block {
+		a += uint32(x)
+		b += a
+	}
+	return a&0xffff | (b&0xffff)<<16
+}
+
+// Roll moves the window of size bytes that weak is the Weak of one byte on, dropping out and taking in
+func Roll(weak uint32, out byte, in byte, size int) uint32 {
+	a := weak&0xffff - uint32(out) + uint32(in)
+	b := weak>>16 - uint32(size)*uint32(out) + a
+	return a&0xffff | (b&0xffff)<<16
+}
+
+// Writer encodes a delta
+type Writer struct {
+	w   *bufio.Writer
+	buf [1 + 2*binary.MaxVarintLen64]byte
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Copy references count blocks of the stored file from block start on
+func (d *Writer) Copy(start uint64, count uint64) error {
+	b := append(d.buf[:0], opCopy)
+	b = binary.AppendUvarint(b, start)
+	b = binary.AppendUvarint(b, count)
+	_, err := d.w.Write(b)
+	return err
+}
+
+// Literal adds data that the stored file doesn't have
+func (d *Writer) Literal(data []byte) error {
+	b := append(d.buf[:0], opLiteral)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	if _, err := d.w.Write(b); err != nil {
+		return err
+	}
+	_, err := d.w.Write(data)
+	return err
+}
+
+// Flush writes out what is buffered; the delta is complete once it is flushed
+func (d *Writer) Flush() error {
+	return d.w.Flush()
+}
+
+// Apply writes to w the file that delta makes of base, the stored file its blocks of blockSize refer to,
+// and returns how many bytes it wrote. Base is read no further than the last block referenced.
+func Apply(w io.Writer, base io.Reader, blockSize int, delta io.Reader) (int64, error) {
+	const op = "delta.Apply"
+
+	d := bufio.NewReader(delta)
+	bs := uint64(blockSize)
+	// next is the first block of base that wasn't read yet
+	var next uint64
+	var exhausted bool
+	var written int64
+	for {
+		kind, err := d.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		} else if err != nil {
+			return written, fmt.Errorf("%s: %w", op, err)
+		}
+
+		switch kind {
+		case opCopy:
+			start, err := readUvarint(d)
+			if err != nil {
+				return written, fmt.Errorf("%s: copy start: %w", op, err)
+			}
+			count, err := readUvarint(d)
+			if err != nil {
+				return written, fmt.Errorf("%s: copy count: %w", op, err)
+			}
+
+			if exhausted || start < next || count == 0 || count > math.MaxInt64/bs || start > math.MaxInt64/bs-count {
+				return written, fmt.Errorf("%s: copy of %d blocks from %d after block %d: %w", op, count, start, next, ErrInvalidDelta)
+			}
+
+			if _, err := io.CopyN(io.Discard, base, int64((start-next)*bs)); errors.Is(err, io.EOF) {
+				return written, fmt.Errorf("%s: block %d is past the end: %w", op, start, ErrInvalidDelta)
+			} else if err != nil {
+				return written, fmt.Errorf("%s: skip blocks: %w", op, err)
+			}
+
+			n, err := io.CopyN(w, base, int64(count*bs))
+			written += n
+			if errors.Is(err, io.EOF) {
+				// only the last block may be short
+				if n <= int64((count-1)*bs) {
+					return written, fmt.Errorf("%s: blocks %d to %d are past the end: %w", op, start, start+count-1, ErrInvalidDelta)
+				}
+				exhausted = true
+			} else if err != nil {
+				return written, fmt.Errorf("%s: copy blocks: %w", op, err)
+			}
+			next = start + count
+
+		case opLiteral:
+			size, err := readUvarint(d)
+			if err != nil {
+				return written, fmt.Errorf("%s: literal size: %w", op, err)
+			}
+			if size > math.MaxInt64 {
+				return written, fmt.Errorf("%s: literal of %d bytes: %w", op, size, ErrInvalidDelta)
+			}
+
+			n, err := io.CopyN(w, d, int64(size))
+			written += n
+			if errors.Is(err, io.EOF) {
+				return written, fmt.Errorf("%s: literal cut short: %w", op, ErrInvalidDelta)
+			} else if err != nil {
+				return written, fmt.Errorf("%s: copy literal: %w", op, err)
+			}
+
+		default:
+			return written, fmt.Errorf("%s: unknown operation %q: %w", op, kind, ErrInvalidDelta)
+		}
+	}
+}
+
+// readUvarint tells malformed varints apart from failures to read them
+func readUvarint(r io.ByteReader) (uint64, error) {
+	br := &byteReader{r: r}
+	v, err := binary.ReadUvarint(br)
+	if err == nil {
+		return v, nil
+	}
+
+	if br.err == nil || errors.Is(br.err, io.EOF) {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidDelta, err)
+	}
+	return 0, br.err
+}
+
+type byteReader struct {
+	r   io.ByteReader
+	err error
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	b, err := br.r.ReadByte()
+	br.err = err
+	return b, err
+}
@@ -0,0 +1,101 @@
+package delta_test
+
+import (
+	"bytes"
+	"cloud-storage/delta"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const blockSize = 4
+
+func TestSign(t *testing.T) {
+	sig, err := delta.Sign(bytes.NewReader([]byte("abcdefghij")), blockSize)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), sig.Size)
+	require.Len(t, sig.Blocks, 3)
+
+	last := sha256.Sum256([]byte("ij"))
+	assert.Equal(t, hex.EncodeToString(last[:]), sig.Blocks[2].Strong)
+	assert.Equal(t, delta.Weak([]byte("efgh")), sig.Blocks[1].Weak)
+
+	sig, err = delta.Sign(bytes.NewReader(nil), blockSize)
+	require.NoError(t, err)
+	assert.Empty(t, sig.Blocks)
+}
+
+func TestRoll(t *testing.T) {
+	data := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	const size = 700
+	weak := delta.Weak(data[:size])
+	for i := size; i < len(data); i++ {
+		weak = delta.Roll(weak, data[i-size], data[i], size)
+		require.Equal(t, delta.Weak(data[i-size+1:i+1]), weak, i)
+	}
+}
+
+func encode(t *testing.T, ops func(d *delta.Writer)) []byte {
+	var buf bytes.Buffer
+	d := delta.NewWriter(&buf)
+	ops(d)
+	require.NoError(t, d.Flush())
+	return buf.Bytes()
+}
+
+func TestApply(t *testing.T) {
+	base := []byte("aaaabbbbccccdd")
+
+	d := encode(t, func(d *delta.Writer) {
+		assert.NoError(t, d.Literal([]byte("new ")))
+		assert.NoError(t, d.Copy(0, 2))
+		assert.NoError(t, d.Literal([]byte("-")))
+		// the last block is shorter than the rest
+		assert.NoError(t, d.Copy(3, 1))
+	})
+
+	var out bytes.Buffer
+	n, err := delta.Apply(&out, bytes.NewReader(base), blockSize, bytes.NewReader(d))
+	require.NoError(t, err)
+	assert.Equal(t, "new aaaabbbb-dd", out.String())
+	assert.Equal(t, int64(out.Len()), n)
+}
+
+func TestApply_Invalid(t *testing.T) {
+	base := []byte("aaaabbbbccccdd")
+
+	testCases := []struct {
+		name  string
+		delta []byte
+	}{
+		{"Backwards", encode(t, func(d *delta.Writer) {
+			d.Copy(1, 1)
+			d.Copy(0, 1)
+		})},
+		{"Overlapping", encode(t, func(d *delta.Writer) {
+			d.Copy(0, 2)
+			d.Copy(1, 1)
+		})},
+		{"Past the end", encode(t, func(d *delta.Writer) { d.Copy(4, 1) })},
+		{"Running past the end", encode(t, func(d *delta.Writer) { d.Copy(2, 3) })},
+		{"No blocks", encode(t, func(d *delta.Writer) { d.Copy(0, 0) })},
+		{"Huge copy", encode(t, func(d *delta.Writer) { d.Copy(0, 1<<62) })},
+		{"Literal cut short", encode(t, func(d *delta.Writer) { d.Literal([]byte("abc")) })[:3]},
+		{"Copy cut short", []byte{'c', 1}},
+		{"Varint overflow", append([]byte{'l'}, bytes.Repeat([]byte{0xff}, 11)...)},
+		{"Unknown operation", []byte("x")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := delta.Apply(&bytes.Buffer{}, bytes.NewReader(base), blockSize, bytes.NewReader(tc.delta))
+			assert.ErrorIs(t, err, delta.ErrInvalidDelta)
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Stream layout written by AesGcmProvider:
+//
+//	[1 format version][4 chunk size, little endian][32 salt][chunk 0]...[chunk n]
+//
+// Every chunk is sealed with a per-file key derived from the DEC and the salt.
+// Chunk nonces are the chunk counter with the last byte set on the final chunk,
+// so reordered, dropped or truncated chunks fail authentication.
+const (
+	aesGcmFormatVersion = 1
+	aesGcmSaltSize      = 32
+	aesGcmHeaderSize    = 1 + 4 + aesGcmSaltSize
+	aesGcmTagSize       = 16
+
+	// chunk size is read from untrusted blob headers, so it has to be bounded
+	maxChunkSize = 16 << 20
+
+	fileKeyInfo = "cloud-storage file key"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported ciphertext format")
+
+type AesGcmProvider struct {
+	chunkSize int
+	workers   int
+	buffers   *sync.Pool
+}
+
+// NewAesGcmProvider seals chunks on the given number of goroutines;
+// workers <= 0 means one per GOMAXPROCS
+func NewAesGcmProvider(chunkSize int, workers int) AesGcmProvider {
+	if chunkSize <= 0 || chunkSize > maxChunkSize {
+		panic(fmt.Sprintf("invalid encryption chunk size: %d", chunkSize))
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return AesGcmProvider{
+		chunkSize: chunkSize,
+		workers:   workers,
+		buffers: &sync.Pool{
+			New: func() any {
+				buf := make([]byte, chunkSize+aesGcmTagSize)
+				return &buf
+			},
+		},
+	}
+}
+
+func (p AesGcmProvider) GetKeySize() int {
+	return 32
+}
+
+func (p AesGcmProvider) GetChunkSize() int {
+	return p.chunkSize
+}
+
+// getBuffer returns a buffer that fits a sealed chunk of chunkSize plaintext bytes
+func (p AesGcmProvider) getBuffer(chunkSize int) *[]byte {
+	if chunkSize != p.chunkSize {
+		// blobs written with a different chunk size can't use the pool
+		buf := make([]byte, chunkSize+aesGcmTagSize)
+		return &buf
+	}
+	return p.buffers.Get().(*[]byte)
+}
+
+func (p AesGcmProvider) putBuffer(buf *[]byte) {
+	if len(*buf) == p.chunkSize+aesGcmTagSize {
+		p.buffers.Put(buf)
+	}
+}
+
+func newFileAEAD(key []byte, salt []byte) (cipher.AEAD, error) {
+	fileKey, err := hkdf.Key(sha256.New, key, salt, fileKeyInfo, len(key))
+	if err != nil {
+		return nil, fmt.Errorf("hkdf.Key: %w", err)
+	}
+
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM: %w", err)
+	}
+
+	return gcm, nil
+}
+
+func chunkNonce(nonce []byte, counter uint64, last bool) {
+	clear(nonce)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+// readChunk fills buf and reports whether r has ended
+func readChunk(r io.Reader, buf []byte) (n int, eof bool, err error) {
+	n, err = io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, true, nil
+	}
+	return n, false, err
+}
+
+func (p AesGcmProvider) Encrypt(w io.Writer, r io.Reader, key []byte, rs RandomSource) error {
+	const op = "encryption.AesGcmProvider.Encrypt"
+
+	header := make([]byte, aesGcmHeaderSize)
+	header[0] = aesGcmFormatVersion
+	binary.LittleEndian.PutUint32(header[1:5], uint32(p.chunkSize))
+	salt := header[5:]
+	if _, err := io.ReadFull(rs, salt); err != nil {
+		return fmt.Errorf("%s: read salt: %w", op, err)
+	}
+
+	aead, err := newFileAEAD(key, salt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("%s: write header: %w", op, err)
+	}
+
+	if p.workers == 1 {
+		err = p.encryptChunks(w, r, aead)
+	} else {
+		err = p.encryptChunksParallel(w, r, key, salt)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p AesGcmProvider) encryptChunks(w io.Writer, r io.Reader, aead cipher.AEAD) error {
+	cur, next := p.getBuffer(p.chunkSize), p.getBuffer(p.chunkSize)
+	defer p.putBuffer(cur)
+	defer p.putBuffer(next)
+
+	nonce := make([]byte, aead.NonceSize())
+
+	// one chunk of read-ahead tells us whether the current chunk is the last one
+	n, eof, err := readChunk(r, (*cur)[:p.chunkSize])
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	for counter := uint64(0); ; counter++ {
+		var m int
+		last := eof
+		if !eof {
+			m, eof, err = readChunk(r, (*next)[:p.chunkSize])
+			if err != nil {
+				return fmt.Errorf("read: %w", err)
+			}
+			last = m == 0 && eof
+		}
+
+		chunkNonce(nonce, counter, last)
+		ciphertext := aead.Seal((*cur)[:0], nonce, (*cur)[:n], nil)
+		if _, err := w.Write(ciphertext); err != nil {
+			return fmt.Errorf("write chunk: %w", err)
+		}
+
+		if last {
+			return nil
+		}
+
+		cur, next = next, cur
+		n = m
+	}
+}
+
+type sealJob struct {
+	buf     *[]byte
+	n       int
+	counter uint64
+	last    bool
+
+	sealed []byte
+	done   chan struct{}
+}
+
+// encryptChunksParallel reads chunks on one goroutine, seals them on p.workers
+// goroutines and writes them out in the order they were read
+func (p AesGcmProvider) encryptChunksParallel(w io.Writer, r io.Reader, key []byte, salt []byte) error {
+	jobs := make(chan *sealJob)
+	// bounds the number of chunks in flight, and so the memory used
+	pending := make(chan *sealJob, p.workers)
+	stop := make(chan struct{})
+
+	// AEADs are not documented as safe for concurrent use, so each worker gets its own
+	aeads := make([]cipher.AEAD, p.workers)
+	for i := range aeads {
+		aead, err := newFileAEAD(key, salt)
+		if err != nil {
+			return err
+		}
+		aeads[i] = aead
+	}
+
+	var workers sync.WaitGroup
+	for _, aead := range aeads {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			nonce := make([]byte, aead.NonceSize())
+			for job := range jobs {
+				chunkNonce(nonce, job.counter, job.last)
+				job.sealed = aead.Seal((*job.buf)[:0], nonce, (*job.buf)[:job.n], nil)
+				close(job.done)
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+
+		buf := p.getBuffer(p.chunkSize)
+		n, eof, err := readChunk(r, (*buf)[:p.chunkSize])
+
+		for counter := uint64(0); err == nil; counter++ {
+			var next *[]byte
+			var m int
+			last := eof
+			if !eof {
+				next = p.getBuffer(p.chunkSize)
+				m, eof, err = readChunk(r, (*next)[:p.chunkSize])
+				if err != nil {
+					p.putBuffer(next)
+					break
+				}
+				last = m == 0 && eof
+			}
+
+			job := &sealJob{buf: buf, n: n, counter: counter, last: last, done: make(chan struct{})}
+			select {
+			case pending <- job:
+			case <-stop:
+				p.putBuffer(buf)
+				if next != nil {
+					p.putBuffer(next)
+				}
+				return
+			}
+			jobs <- job
+
+			if last {
+				return
+			}
+
+			buf, n = next, m
+		}
+
+		p.putBuffer(buf)
+		readErr = fmt.Errorf("read: %w", err)
+	}()
+
+	var writeErr error
+	for job := range pending {
+		<-job.done
+		if writeErr == nil {
+			if _, err := w.Write(job.sealed); err != nil {
+				writeErr = fmt.Errorf("write chunk: %w", err)
+				close(stop)
+			}
+		}
+		p.putBuffer(job.buf)
+	}
+	workers.Wait()
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	// pending is closed only after readErr is set
+	return readErr
+}
+
+func (p AesGcmProvider) Decrypt(w io.Writer, r io.Reader, key []byte) error {
+	const op = "encryption.AesGcmProvider.Decrypt"
+
+	header := make([]byte, aesGcmHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%s: read header: %w", op, err)
+	}
+
+	if header[0] != aesGcmFormatVersion {
+		return fmt.Errorf("%s: %w: version %d", op, ErrUnsupportedFormat, header[0])
+	}
+
+	chunkSize := int(binary.LittleEndian.Uint32(header[1:5]))
+	if chunkSize <= 0 || chunkSize > maxChunkSize {
+		return fmt.Errorf("%s: %w: chunk size %d", op, ErrUnsupportedFormat, chunkSize)
+	}
+
+	aead, err := newFileAEAD(key, header[5:])
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sealedSize := chunkSize + aead.Overhead()
+	cur, next := p.getBuffer(chunkSize), p.getBuffer(chunkSize)
+	defer p.putBuffer(cur)
+	defer p.putBuffer(next)
+
+	nonce := make([]byte, aead.NonceSize())
+
+	n, eof, err := readChunk(r, (*cur)[:sealedSize])
+	if err != nil {
+		return fmt.Errorf("%s: read: %w", op, err)
+	}
+
+	for counter := uint64(0); ; counter++ {
+		var m int
+		last := eof
+		if !eof {
+			m, eof, err = readChunk(r, (*next)[:sealedSize])
+			if err != nil {
+				return fmt.Errorf("%s: read: %w", op, err)
+			}
+			last = m == 0 && eof
+		}
+
+		chunkNonce(nonce, counter, last)
+		plaintext, err := aead.Open((*cur)[:0], nonce, (*cur)[:n], nil)
+		if err != nil {
+			return fmt.Errorf("%s: chunk %d: %w", op, counter, err)
+		}
+
+		if _, err := w.Write(plaintext); err != nil {
+			return fmt.Errorf("%s: write chunk: %w", op, err)
+		}
+
+		if last {
+			return nil
+		}
+
+		cur, next = next, cur
+		n = m
+	}
+}
@@ -0,0 +1,156 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Convergent encryption derives the key of a blob from its content, so that the same content
+// uploaded by different users is stored once. This trades away some privacy, which is why it is
+// off unless a deployment enables it:
+//   - anyone able to derive content keys, i.e. with access to the convergent vault key, can confirm
+//     whether a given file is stored by deriving its id and looking it up
+//   - an uploader may learn that someone else stores the same content, e.g. by how fast an upload
+//     of a guessed file completes
+//   - the db tells which users share content with each other
+//
+// Content ids are keyed by vault, so the db alone doesn't allow confirming a guessed file.
+// Rotating the convergent key starts dedup over for new uploads, as ids depend on the key version.
+
+// convergentKeyId takes the place of the DEC id in the header of convergently encrypted blobs;
+// the id of the content their key is kept under follows it
+const convergentKeyId = math.MaxUint64
+
+// contentIdLen is the length of a content id, the unpadded base64 of 32 bytes
+const contentIdLen = 43
+
+// ContentKey is the key of a convergently encrypted blob
+type ContentKey struct {
+	// Id is what the blob of the content is found by
+	Id string
+	// Wrapped is the key encrypted by the key service, to be kept with the blob
+	Wrapped string
+	key     []byte
+}
+
+type ConvergentCrypter interface {
+	// DeriveContentKey returns the key of content with the given sha256 hash
+	DeriveContentKey(contentHash []byte) (ContentKey, error)
+	// EncryptWithContentKey encrypts r into a blob that DecryptAndCopy reads once the wrapped key is stored
+	// under key.Id; the same content always gives the same blob
+	EncryptWithContentKey(w io.Writer, r io.Reader, key ContentKey) error
+}
+
+// EnableConvergentEncryption lets c derive content keys through cks
+func (c *SymmetricCrypter) EnableConvergentEncryption(cks ConvergentKeyService) *SymmetricCrypter {
+	c.cks = cks
+	return c
+}
+
+// DeriveContentKey has the key service encrypt the hash with the hash as the context, which gives
+// a secret only the content and the service together determine; the key and the id are taken from it
+func (c *SymmetricCrypter) DeriveContentKey(contentHash []byte) (ContentKey, error) {
+	const op = "encryption.SymmetricCrypter.DeriveContentKey"
+
+	if c.cks == nil {
+		return ContentKey{}, fmt.Errorf("%s: convergent encryption is not enabled", op)
+	}
+
+	response, err := c.cks.MakeConvergentEncryptRequest(contentHash, contentHash)
+	if err != nil {
+		return ContentKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	derive := func(label string) []byte {
+		mac := hmac.New(sha256.New, []byte(response.Ciphertext))
+		mac.Write([]byte(label))
+		return mac.Sum(nil)
+	}
+
+	key := derive("key")[:c.sep.GetKeySize()]
+	wrapped, err := c.es.MakeEncryptRequest(key)
+	if err != nil {
+		return ContentKey{}, fmt.Errorf("%s: wrap key: %w", op, err)
+	}
+
+	return ContentKey{
+		Id:      base64.RawURLEncoding.EncodeToString(derive("id")),
+		Wrapped: wrapped.Ciphertext,
+		key:     key,
+	}, nil
+}
+
+func (c *SymmetricCrypter) EncryptWithContentKey(w io.Writer, r io.Reader, key ContentKey) error {
+	const op = "encryption.SymmetricCrypter.EncryptWithContentKey"
+
+	if len(key.Id) != contentIdLen || key.key == nil {
+		return fmt.Errorf("%s: invalid content key", op)
+	}
+
+	header := binary.LittleEndian.AppendUint64(nil, convergentKeyId)
+	if _, err := w.Write(append(header, key.Id...)); err != nil {
+		return fmt.Errorf("%s: write header: %w", op, err)
+	}
+
+	// the key is only ever used for this content, so nonces taken from it can't repeat for different plaintexts
+	if err := c.sep.Encrypt(w, r, key.key, newKeyedSource(key.key)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// contentKey reads the content id following the header of a convergently encrypted blob and returns its key
+func (c *SymmetricCrypter) contentKey(r io.Reader) ([]byte, error) {
+	id := make([]byte, contentIdLen)
+	if _, err := io.ReadFull(r, id); err != nil {
+		return nil, fmt.Errorf("read content id: %w", err)
+	}
+
+	wrapped, err := c.db.GetContentKey(string(id))
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.es.MakeDecryptRequest([]byte(wrapped))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(response.Plaintext), nil
+}
+
+// keyedSource is a deterministic RandomSource: the HMAC of a counter under the key
+type keyedSource struct {
+	key     []byte
+	counter uint64
+	buf     []byte
+}
+
+func newKeyedSource(key []byte) *keyedSource {
+	return &keyedSource{key: key}
+}
+
+func (s *keyedSource) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.buf) == 0 {
+			mac := hmac.New(sha256.New, s.key)
+			mac.Write([]byte("nonce"))
+			mac.Write(binary.BigEndian.AppendUint64(nil, s.counter))
+			s.counter++
+			s.buf = mac.Sum(nil)
+		}
+
+		copied := copy(p[n:], s.buf)
+		s.buf = s.buf[copied:]
+		n += copied
+	}
+
+	return n, nil
+}
@@ -1,278 +1,481 @@
 package encryption
 
 import (
-	"bytes"
 	dbaccess "cloud-storage/db_access"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 type Crypter interface {
+	// EncryptAndCopy encrypts r with the shared DECs, for what belongs to no user
 	EncryptAndCopy(w io.Writer, r io.Reader) error
+	// EncryptAndCopyFor encrypts r with a DEC of the owner's own, so that ShredUserKeys can make it unreadable
+	EncryptAndCopyFor(ownerId int64, w io.Writer, r io.Reader) error
 	EncryptFileName(filename string) (string, error)
 	
 	DecryptAndCopy(w io.Writer, r io.Reader) error
 	DecryptFileName(ciphertext string) (string, error)
+	// DecryptFileNames decrypts many names at once, in the order given
+	DecryptFileNames(ciphertexts []string) ([]string, error)
+
+	// FileNameIndex is the same for names that only differ in case, unlike their ciphertexts
+	FileNameIndex(filename string) (string, error)
+	// ContentTermIndex hides a term of the contents of a file of the owner, so that
+	// the same term gives unrelated hashes for different owners
+	ContentTermIndex(ownerId int64, term string) (string, error)
+}
+
+// KeyShredder makes whatever was encrypted for a user unreadable, wherever copies of it are kept
+type KeyShredder interface {
+	ShredUserKeys(ownerId int64) error
 }
 
 type SymmetricEncryptionProvider interface {
-	Encrypt(r io.Reader, key []byte, rs RandomSource) (ciphertext []byte, nonce []byte, err error)
-	Decrypt(r io.Reader, key, nonce []byte) (plaintext []byte, err error)
-	
-	GetNonceSize() int
+	// Encrypt writes a self-describing ciphertext stream of r into w;
+	// anything it needs for decryption apart from the key is part of that stream
+	Encrypt(w io.Writer, r io.Reader, key []byte, rs RandomSource) error
+	Decrypt(w io.Writer, r io.Reader, key []byte) error
+
 	GetKeySize() int
 }
 
 type RandomSource io.Reader
 
-type AesGcmProvider struct {
-	maxFileSize int64
-}
+// MaxFileNameLen is the longest file name in bytes that EncryptFileName takes
+const MaxFileNameLen = 255
+
+// MaxEncryptedFileNameLen bounds what EncryptFileName returns for names up to MaxFileNameLen,
+// and what vault returned for them before names were sealed locally:
+// either is a short prefix followed by the base64 of the nonce, the name and the tag
+const MaxEncryptedFileNameLen = 512
+
+// File names are sealed with AES-GCM under a key derived from the newest DEC, as
+//
+//	dec:<dec id>:<base64url of the nonce, the sealed name and the tag>
+//
+// with the prefix as additional data, so that a name can't be opened under another DEC.
+// Names stored before are vault ciphertexts, which DecryptFileName still takes.
+const (
+	nameCiphertextPrefix = "dec:"
+	fileNameKeyInfo      = "cloud-storage file name key"
+	fileNameKeySize      = 32
+)
 
-func NewAesGcmProvider(maxFileSize int64) AesGcmProvider {
-	return AesGcmProvider{
-		maxFileSize: maxFileSize,
-	}
-}
+var ErrFileNameTooLong = errors.New("file name is too long")
+
+const indexKeySize = 32
 
-func (p AesGcmProvider) GetNonceSize() int {
-	return 12
+type SymmetricCrypter struct {
+	db  dbaccess.KeyRepo
+	es  EncryptionService
+	rs  RandomSource
+	sep SymmetricEncryptionProvider
+
+	decRotationPeriod time.Duration
+
+	// the key of the name index is loaded on first use and kept
+	indexKeyMu sync.Mutex
+	indexKey   []byte
+
+	// DECs are unwrapped by vault once and kept by id; a DEC never changes, whatever it is wrapped with
+	decKeysMu sync.Mutex
+	decKeys   map[dbaccess.DecId][]byte
+
+	// see ConfigureNameDecryption
+	nameSlots     chan struct{}
+	nameBatchSize int
+	names         *nameCache
+	bs            BatchDecryptService
+
+	// nil unless convergent encryption is enabled
+	cks ConvergentKeyService
 }
 
-func (p AesGcmProvider) GetKeySize() int {
-	return 32
+func NewSymmetricCrypter(
+	db dbaccess.KeyRepo,
+	es EncryptionService,
+	rs RandomSource,
+	sep SymmetricEncryptionProvider,
+	decRotationPeriod time.Duration,
+) *SymmetricCrypter {
+	return &SymmetricCrypter{
+		db:                db,
+		es:                es,
+		rs:                rs,
+		sep:               sep,
+		decRotationPeriod: decRotationPeriod,
+		decKeys:           make(map[dbaccess.DecId][]byte),
+		nameSlots:         make(chan struct{}, defaultNameDecryption.Workers),
+		nameBatchSize:     defaultNameDecryption.BatchSize,
+	}
 }
 
-func (p AesGcmProvider) Encrypt(r io.Reader, key []byte, rs RandomSource) (ciphertext []byte, nonce []byte, err error) {
-	const op = "encryption.AesGcmProvider.Encrypt"
+func (c *SymmetricCrypter) EncryptFileName(filename string) (string, error) {
+	const op = "encryption.SymmetricCrypter.EncryptFileName"
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		err = fmt.Errorf("%s: aes.NewCipher: %w", op, err)
-		return
+	if len(filename) > MaxFileNameLen {
+		return "", fmt.Errorf("%s: %d bytes: %w", op, len(filename), ErrFileNameTooLong)
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	dec, key, err := c.currentDEC(0)
 	if err != nil {
-		err = fmt.Errorf("%s: cipher.NewGCM: %w", op, err)
-		return
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	nonce = make([]byte, gcm.NonceSize())
-	_, err = rs.Read(nonce)
+	aead, err := fileNameAEAD(key)
 	if err != nil {
-		err = fmt.Errorf("%s: rs.Read: %w", op, err)
-		return
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	// TODO: p.maxFileSize can be really large so we want to do this in chunks
-	data := make([]byte, p.maxFileSize)
-	n, err := io.ReadFull(r, data)
-	if errors.Is(err, io.ErrUnexpectedEOF) {
-		// do nothing
-		err = nil
-	} else if err != nil {
-		err = fmt.Errorf("%s: buf.ReadFrom: %w", op, err)
-		return
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(c.rs, nonce); err != nil {
+		return "", fmt.Errorf("%s: c.rs.Read: %w", op, err)
 	}
 
-	ciphertext = gcm.Seal(data[:0], nonce, data[:n], nil)
-	return
+	prefix := nameCiphertextPrefix + strconv.FormatInt(int64(dec.Id), 10) + ":"
+	sealed := aead.Seal(nonce, nonce, []byte(filename), []byte(prefix))
+	return prefix + base64.RawURLEncoding.EncodeToString(sealed), nil
 }
 
-func (p AesGcmProvider) Decrypt(r io.Reader, key, nonce []byte) (plaintext []byte, err error) {
-	const op = "encryption.AesGcmProvider.Encrypt"
-	
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		err = fmt.Errorf("%s: aes.NewCipher: %w", op, err)
-		return
+func (c *SymmetricCrypter) DecryptFileName(ciphertext string) (string, error) {
+	const op = "encryption.SymmetricCrypter.DecryptFileName"
+
+	if name, ok := c.names.get(ciphertext); ok {
+		return name, nil
 	}
-	
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		err = fmt.Errorf("%s: cipher.NewGCM: %w", op, err)
-		return
+
+	var name string
+	if strings.HasPrefix(ciphertext, nameCiphertextPrefix) {
+		var err error
+		name, err = c.openFileName(ciphertext, make(map[dbaccess.DecId]cipher.AEAD))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	} else {
+		response, err := c.es.MakeDecryptRequest([]byte(ciphertext))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		name = response.Plaintext
 	}
-	
-	// TODO: p.maxFileSize can be really large so we want to do this in chunks
-	// we use bytes.Buffer here because size of the ciphertext may be bigger than maxFileSize
-	buf := bytes.NewBuffer(make([]byte, 0, p.maxFileSize))
-	_, err = buf.ReadFrom(r)
+
+	c.names.put(ciphertext, name)
+	return name, nil
+}
+
+// openFileName opens a name sealed locally; aeads keeps the name keys derived so far by DEC
+func (c *SymmetricCrypter) openFileName(ciphertext string, aeads map[dbaccess.DecId]cipher.AEAD) (string, error) {
+	const op = "encryption.SymmetricCrypter.openFileName"
+
+	rest, _ := strings.CutPrefix(ciphertext, nameCiphertextPrefix)
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, ErrUnsupportedFormat)
+	}
+	decId, err := strconv.ParseUint(id, 10, 63)
 	if err != nil {
-		err = fmt.Errorf("%s: buf.Read: %w", op, err)
-		return
+		return "", fmt.Errorf("%s: dec id: %w", op, ErrUnsupportedFormat)
 	}
-	
-	ciphertext := buf.Bytes()
-	plaintext, err = gcm.Open(ciphertext[:0], nonce, ciphertext, nil)
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
 	if err != nil {
-		err = fmt.Errorf("%s: gcm.Open: %w", op, err)
+		return "", fmt.Errorf("%s: base64: %w", op, ErrUnsupportedFormat)
 	}
-	return
-}
 
-type SymmetricCrypter struct {
-	db  dbaccess.DbAccess
-	es  EncryptionService
-	rs  RandomSource
-	sep SymmetricEncryptionProvider
+	aead, ok := aeads[dbaccess.DecId(decId)]
+	if !ok {
+		key, err := c.decKey(dbaccess.DecId(decId))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
 
-	decRotationPeriod time.Duration
+		aead, err = fileNameAEAD(key)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		aeads[dbaccess.DecId(decId)] = aead
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("%s: %w", op, ErrUnsupportedFormat)
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	name, err := aead.Open(nil, nonce, sealed, []byte(ciphertext[:len(ciphertext)-len(encoded)]))
+	if err != nil {
+		return "", fmt.Errorf("%s: aead.Open: %w", op, err)
+	}
+
+	return string(name), nil
 }
 
-func NewSymmetricCrypter(
-	db dbaccess.DbAccess,
-	es EncryptionService,
-	rs RandomSource,
-	sep SymmetricEncryptionProvider,
-	decRotationPeriod time.Duration,
-) *SymmetricCrypter {
-	return &SymmetricCrypter{
-		db:                db,
-		es:                es,
-		rs:                rs,
-		sep:               sep,
-		decRotationPeriod: decRotationPeriod,
+// fileNameAEAD derives the key names are sealed with from the DEC, apart from the keys of file contents
+func fileNameAEAD(dec []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, dec, nil, fileNameKeyInfo, fileNameKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("hkdf.Key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %w", err)
 	}
+
+	return cipher.NewGCM(block)
 }
 
-func (c *SymmetricCrypter) EncryptFileName(filename string) (string, error) {
-	const op = "encryption.SymmetricCrypter.EncryptFileName"
+// FileNameIndex hashes the lowercased name with a key of its own, which vault keeps wrapped in the db
+// like the DECs, so that files of a name can be found without decrypting every name
+func (c *SymmetricCrypter) FileNameIndex(filename string) (string, error) {
+	const op = "encryption.SymmetricCrypter.FileNameIndex"
 
-	response, err := c.es.MakeEncryptRequest([]byte(filename))
+	key, err := c.getIndexKey()
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return string(response.Ciphertext), nil
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.ToLower(filename)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
 }
 
-func (c *SymmetricCrypter) DecryptFileName(ciphertext string) (string, error) {
-	const op = "encryption.SymmetricCrypter.DecryptFileName"
-	
-	response, err := c.es.MakeDecryptRequest([]byte(ciphertext))
+// ContentTermIndex hashes the term with the index key, apart from names by a prefix of its own;
+// the hash is cut to 16 bytes since the index holds a row per term of every file
+func (c *SymmetricCrypter) ContentTermIndex(ownerId int64, term string) (string, error) {
+	const op = "encryption.SymmetricCrypter.ContentTermIndex"
+
+	key, err := c.getIndexKey()
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	
-	return string(response.Plaintext), nil
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("content\x00" + strconv.FormatInt(ownerId, 10) + "\x00" + term))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]), nil
 }
 
-func (c *SymmetricCrypter) EncryptAndCopy(w io.Writer, r io.Reader) error {
-	const op = "encryption.SymmetricCrypter.EncryptAndCopy"
+func (c *SymmetricCrypter) getIndexKey() ([]byte, error) {
+	const op = "encryption.SymmetricCrypter.getIndexKey"
 
-	var key []byte
+	c.indexKeyMu.Lock()
+	defer c.indexKeyMu.Unlock()
 
-	dec, err := c.db.GetNewestDEC()
-	var nre dbaccess.NoRowsError
-	if errors.As(err, &nre) || time.Since(time.Time(dec.CreationTime)) > c.decRotationPeriod {
-		// generate new key
+	if c.indexKey != nil {
+		return c.indexKey, nil
+	}
 
-		key = make([]byte, c.sep.GetKeySize())
-		_, err := c.rs.Read(key)
-		if err != nil {
-			return fmt.Errorf("%s: c.rs.Read: %w", op, err)
+	wrapped, err := c.db.GetIndexKey()
+	var nre dbaccess.NoRowsError
+	if errors.As(err, &nre) {
+		key := make([]byte, indexKeySize)
+		if _, err := io.ReadFull(c.rs, key); err != nil {
+			return nil, fmt.Errorf("%s: c.rs.Read: %w", op, err)
 		}
 
 		response, err := c.es.MakeEncryptRequest(key)
 		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		dec.Value = string(response.Ciphertext)
-		dec.CreationTime = dbaccess.Time(time.Now())
-		err = c.db.AddDEC(&dec)
+		// another instance may have added its key meanwhile, which is the one to use then
+		wrapped, err = c.db.AddIndexKey(response.Ciphertext)
 		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 	} else if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if key == nil {
-		// decrypt the key
+	response, err := c.es.MakeDecryptRequest([]byte(wrapped))
+	if err != nil {
+		return nil, fmt.Errorf("%s: decrypt: %w", op, err)
+	}
 
-		response, err := c.es.MakeDecryptRequest([]byte(dec.Value))
-		if err != nil {
-			return fmt.Errorf("%s: decrypt: %w", op, err)
-		}
+	c.indexKey = []byte(response.Plaintext)
+	return c.indexKey, nil
+}
+
+func (c *SymmetricCrypter) EncryptAndCopy(w io.Writer, r io.Reader) error {
+	const op = "encryption.SymmetricCrypter.EncryptAndCopy"
 
-		key = []byte(response.Plaintext)
+	if err := c.encryptAndCopy(0, w, r); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	return nil
+}
 
-	// ecnrypt the data
+func (c *SymmetricCrypter) EncryptAndCopyFor(ownerId int64, w io.Writer, r io.Reader) error {
+	const op = "encryption.SymmetricCrypter.EncryptAndCopyFor"
 
-	ciphertext, nonce, err := c.sep.Encrypt(r, key, c.rs)
-	if err != nil {
+	if err := c.encryptAndCopy(ownerId, w, r); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	return nil
+}
 
-	// TODO: check if compiler actually optimizes this function away
-	err = func() error {
-		id := make([]byte, 8)
-		binary.LittleEndian.PutUint64(id, uint64(dec.Id))
-		_, err := w.Write(id)
-		if err != nil {
-			return fmt.Errorf("write id: %w", err)
+// encryptAndCopy writes the id of the newest DEC of the owner, 0 for the shared ones, and then r encrypted with it
+func (c *SymmetricCrypter) encryptAndCopy(ownerId int64, w io.Writer, r io.Reader) error {
+	dec, key, err := c.currentDEC(ownerId)
+	if err != nil {
+		return err
+	}
+
+	id := make([]byte, 8)
+	binary.LittleEndian.PutUint64(id, uint64(dec.Id))
+	_, err = w.Write(id)
+	if err != nil {
+		return fmt.Errorf("write id: %w", err)
+	}
+
+	// ecnrypt the data
+
+	return c.sep.Encrypt(w, r, key, c.rs)
+}
+
+// currentDEC returns the newest DEC of the owner, 0 for the shared ones, and its key,
+// after adding a new one if the newest is due for rotation
+func (c *SymmetricCrypter) currentDEC(ownerId int64) (dbaccess.DEC, []byte, error) {
+	const op = "encryption.SymmetricCrypter.currentDEC"
+
+	var dec dbaccess.DEC
+	var err error
+	if ownerId == 0 {
+		dec, err = c.db.GetNewestDEC()
+	} else {
+		dec, err = c.db.GetNewestUserDEC(ownerId)
+	}
+	var nre dbaccess.NoRowsError
+	if errors.As(err, &nre) || (err == nil && time.Since(time.Time(dec.CreationTime)) > c.decRotationPeriod) {
+		key := make([]byte, c.sep.GetKeySize())
+		if _, err := c.rs.Read(key); err != nil {
+			return dbaccess.DEC{}, nil, fmt.Errorf("%s: c.rs.Read: %w", op, err)
 		}
 
-		_, err = w.Write(nonce)
+		response, err := c.es.MakeEncryptRequest(key)
 		if err != nil {
-			return fmt.Errorf("write nonce: %w", err)
+			return dbaccess.DEC{}, nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		_, err = w.Write(ciphertext)
-		if err != nil {
-			return fmt.Errorf("write ciphertext: %w", err)
+		dec = dbaccess.DEC{
+			Value:        string(response.Ciphertext),
+			KeyVersion:   response.KeyVersion,
+			CreationTime: dbaccess.Time(time.Now()),
+			OwnerId:      ownerId,
+		}
+		if err := c.db.AddDEC(&dec); err != nil {
+			return dbaccess.DEC{}, nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		return nil
-	}()
+		c.decKeysMu.Lock()
+		c.decKeys[dec.Id] = key
+		c.decKeysMu.Unlock()
+		return dec, key, nil
+	} else if err != nil {
+		return dbaccess.DEC{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := c.unwrapDEC(dec)
 	if err != nil {
-		return fmt.Errorf("%s: write encrypted data: %w", op, err)
+		return dbaccess.DEC{}, nil, fmt.Errorf("%s: %w", op, err)
 	}
+	return dec, key, nil
+}
 
+// ShredUserKeys removes the DECs of the user from the db and from what is kept of them here;
+// the contents of the user then can't be decrypted anymore, nor can copies of them in backups and replicas
+func (c *SymmetricCrypter) ShredUserKeys(ownerId int64) error {
+	const op = "encryption.SymmetricCrypter.ShredUserKeys"
+
+	ids, err := c.db.RemoveUserDECs(ownerId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.decKeysMu.Lock()
+	for _, id := range ids {
+		delete(c.decKeys, id)
+	}
+	c.decKeysMu.Unlock()
 	return nil
 }
 
+// decKey returns the key of the DEC with the id
+func (c *SymmetricCrypter) decKey(id dbaccess.DecId) ([]byte, error) {
+	c.decKeysMu.Lock()
+	key, ok := c.decKeys[id]
+	c.decKeysMu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	dec, err := c.db.GetDEC(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.unwrapDEC(dec)
+}
+
+func (c *SymmetricCrypter) unwrapDEC(dec dbaccess.DEC) ([]byte, error) {
+	c.decKeysMu.Lock()
+	key, ok := c.decKeys[dec.Id]
+	c.decKeysMu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	response, err := c.es.MakeDecryptRequest([]byte(dec.Value))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+
+	key = []byte(response.Plaintext)
+	c.decKeysMu.Lock()
+	c.decKeys[dec.Id] = key
+	c.decKeysMu.Unlock()
+	return key, nil
+}
+
 func (c *SymmetricCrypter) DecryptAndCopy(w io.Writer, r io.Reader) error {
 	const op = "encryption.SymmetricCrypter.DecryptAndCopy"
 	
 	keyIdBytes := make([]byte, 8)
-	_, err := r.Read(keyIdBytes)
+	_, err := io.ReadFull(r, keyIdBytes)
 	if err != nil {
-		return fmt.Errorf("%s: r.Read: %w", op, err)
+		return fmt.Errorf("%s: read key id: %w", op, err)
 	}
 	
 	keyId := binary.LittleEndian.Uint64(keyIdBytes)
-	dec, err := c.db.GetDEC(dbaccess.DecId(keyId))
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if keyId == convergentKeyId {
+		key, err := c.contentKey(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := c.sep.Decrypt(w, r, key); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
 	}
-	
-	response, err := c.es.MakeDecryptRequest([]byte(dec.Value))
+
+	key, err := c.decKey(dbaccess.DecId(keyId))
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	
-	nonce := make([]byte, c.sep.GetNonceSize())
-	r.Read(nonce)
-	
-	plaintext, err := c.sep.Decrypt(r, []byte(response.Plaintext), nonce)
+	err = c.sep.Decrypt(w, r, key)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	
-	_, err = w.Write(plaintext)
-	if err != nil {
-		return fmt.Errorf("%s: w.Write: %w", op, err)
-	}
-	
 	return nil
 }
@@ -0,0 +1,199 @@
+package encryption
+
+import (
+	dbaccess "cloud-storage/db_access"
+	"container/list"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BatchDecryptService decrypts many ciphertexts in one request
+type BatchDecryptService interface {
+	MakeBatchDecryptRequest(ciphertexts [][]byte) ([]DecryptResponse, error)
+}
+
+// NameDecryption tunes DecryptFileNames
+type NameDecryption struct {
+	// Workers bounds how many batches of names are decrypted at once, over all callers
+	Workers int
+	// BatchSize is how many names a batch takes at most
+	BatchSize int
+	// CacheSize is how many decrypted names are kept by their ciphertext; with 0 none are
+	CacheSize int
+}
+
+var defaultNameDecryption = NameDecryption{Workers: 4, BatchSize: 100}
+
+// ConfigureNameDecryption replaces the defaults of DecryptFileNames; it must be called before c is used.
+// Names stored before they were sealed locally go to vault through bs in one request per batch,
+// or one by one if bs is nil.
+func (c *SymmetricCrypter) ConfigureNameDecryption(cfg NameDecryption, bs BatchDecryptService) *SymmetricCrypter {
+	c.nameSlots = make(chan struct{}, max(cfg.Workers, 1))
+	c.nameBatchSize = max(cfg.BatchSize, 1)
+	c.names = newNameCache(cfg.CacheSize)
+	c.bs = bs
+	return c
+}
+
+// DecryptFileNames splits the names not cached into batches and decrypts them concurrently.
+// In a batch the name key of a DEC is derived once for all its names.
+func (c *SymmetricCrypter) DecryptFileNames(ciphertexts []string) ([]string, error) {
+	const op = "encryption.SymmetricCrypter.DecryptFileNames"
+
+	names := make([]string, len(ciphertexts))
+	var missing []int
+	for i, ciphertext := range ciphertexts {
+		if name, ok := c.names.get(ciphertext); ok {
+			names[i] = name
+		} else {
+			missing = append(missing, i)
+		}
+	}
+
+	batches := slices.Collect(slices.Chunk(missing, c.nameBatchSize))
+	errs := make([]error, len(batches))
+	var wg sync.WaitGroup
+	for b, batch := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c.nameSlots <- struct{}{}
+			defer func() { <-c.nameSlots }()
+
+			errs[b] = c.decryptNameBatch(ciphertexts, names, batch)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return names, nil
+}
+
+// decryptNameBatch fills in names at the indexes of batch
+func (c *SymmetricCrypter) decryptNameBatch(ciphertexts []string, names []string, batch []int) error {
+	const op = "encryption.SymmetricCrypter.decryptNameBatch"
+
+	aeads := make(map[dbaccess.DecId]cipher.AEAD)
+	var wrapped []int
+	for _, i := range batch {
+		if !strings.HasPrefix(ciphertexts[i], nameCiphertextPrefix) {
+			wrapped = append(wrapped, i)
+			continue
+		}
+
+		name, err := c.openFileName(ciphertexts[i], aeads)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		names[i] = name
+		c.names.put(ciphertexts[i], name)
+	}
+
+	if len(wrapped) == 0 {
+		return nil
+	}
+
+	if c.bs == nil {
+		for _, i := range wrapped {
+			response, err := c.es.MakeDecryptRequest([]byte(ciphertexts[i]))
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			names[i] = response.Plaintext
+			c.names.put(ciphertexts[i], response.Plaintext)
+		}
+		return nil
+	}
+
+	request := make([][]byte, 0, len(wrapped))
+	for _, i := range wrapped {
+		request = append(request, []byte(ciphertexts[i]))
+	}
+
+	responses, err := c.bs.MakeBatchDecryptRequest(request)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(responses) != len(wrapped) {
+		return fmt.Errorf("%s: %d names decrypted of %d", op, len(responses), len(wrapped))
+	}
+
+	for j, i := range wrapped {
+		names[i] = responses[j].Plaintext
+		c.names.put(ciphertexts[i], responses[j].Plaintext)
+	}
+
+	return nil
+}
+
+// nameCache keeps the most recently used names by ciphertext. A ciphertext is authenticated
+// and never reused for another name, so entries can't go stale. A nil cache keeps nothing.
+type nameCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type nameEntry struct {
+	ciphertext string
+	name       string
+}
+
+func newNameCache(size int) *nameCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &nameCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+func (nc *nameCache) get(ciphertext string) (string, bool) {
+	if nc == nil {
+		return "", false
+	}
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	e, ok := nc.entries[ciphertext]
+	if !ok {
+		return "", false
+	}
+	nc.order.MoveToFront(e)
+	return e.Value.(nameEntry).name, true
+}
+
+func (nc *nameCache) put(ciphertext string, name string) {
+	if nc == nil {
+		return
+	}
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if e, ok := nc.entries[ciphertext]; ok {
+		nc.order.MoveToFront(e)
+		return
+	}
+
+	nc.entries[ciphertext] = nc.order.PushFront(nameEntry{ciphertext: ciphertext, name: name})
+	if nc.order.Len() > nc.size {
+		oldest := nc.order.Back()
+		nc.order.Remove(oldest)
+		delete(nc.entries, oldest.Value.(nameEntry).ciphertext)
+	}
+}
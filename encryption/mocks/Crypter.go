@@ -21,6 +21,63 @@ func (_m *Crypter) EXPECT() *Crypter_Expecter {
 	return &Crypter_Expecter{mock: &_m.Mock}
 }
 
+// ContentTermIndex provides a mock function with given fields: ownerId, term
+func (_m *Crypter) ContentTermIndex(ownerId int64, term string) (string, error) {
+	ret := _m.Called(ownerId, term)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ContentTermIndex")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string) (string, error)); ok {
+		return rf(ownerId, term)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string) string); ok {
+		r0 = rf(ownerId, term)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = rf(ownerId, term)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Crypter_ContentTermIndex_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ContentTermIndex'
+type Crypter_ContentTermIndex_Call struct {
+	*mock.Call
+}
+
+// ContentTermIndex is a helper method to define mock.On call
+//   - ownerId int64
+//   - term string
+func (_e *Crypter_Expecter) ContentTermIndex(ownerId interface{}, term interface{}) *Crypter_ContentTermIndex_Call {
+	return &Crypter_ContentTermIndex_Call{Call: _e.mock.On("ContentTermIndex", ownerId, term)}
+}
+
+func (_c *Crypter_ContentTermIndex_Call) Run(run func(ownerId int64, term string)) *Crypter_ContentTermIndex_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Crypter_ContentTermIndex_Call) Return(_a0 string, _a1 error) *Crypter_ContentTermIndex_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Crypter_ContentTermIndex_Call) RunAndReturn(run func(int64, string) (string, error)) *Crypter_ContentTermIndex_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DecryptAndCopy provides a mock function with given fields: w, r
 func (_m *Crypter) DecryptAndCopy(w io.Writer, r io.Reader) error {
 	ret := _m.Called(w, r)
@@ -124,6 +181,64 @@ func (_c *Crypter_DecryptFileName_Call) RunAndReturn(run func(string) (string, e
 	return _c
 }
 
+// DecryptFileNames provides a mock function with given fields: ciphertexts
+func (_m *Crypter) DecryptFileNames(ciphertexts []string) ([]string, error) {
+	ret := _m.Called(ciphertexts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DecryptFileNames")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]string) ([]string, error)); ok {
+		return rf(ciphertexts)
+	}
+	if rf, ok := ret.Get(0).(func([]string) []string); ok {
+		r0 = rf(ciphertexts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(ciphertexts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Crypter_DecryptFileNames_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DecryptFileNames'
+type Crypter_DecryptFileNames_Call struct {
+	*mock.Call
+}
+
+// DecryptFileNames is a helper method to define mock.On call
+//   - ciphertexts []string
+func (_e *Crypter_Expecter) DecryptFileNames(ciphertexts interface{}) *Crypter_DecryptFileNames_Call {
+	return &Crypter_DecryptFileNames_Call{Call: _e.mock.On("DecryptFileNames", ciphertexts)}
+}
+
+func (_c *Crypter_DecryptFileNames_Call) Run(run func(ciphertexts []string)) *Crypter_DecryptFileNames_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]string))
+	})
+	return _c
+}
+
+func (_c *Crypter_DecryptFileNames_Call) Return(_a0 []string, _a1 error) *Crypter_DecryptFileNames_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Crypter_DecryptFileNames_Call) RunAndReturn(run func([]string) ([]string, error)) *Crypter_DecryptFileNames_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // EncryptAndCopy provides a mock function with given fields: w, r
 func (_m *Crypter) EncryptAndCopy(w io.Writer, r io.Reader) error {
 	ret := _m.Called(w, r)
@@ -171,6 +286,54 @@ func (_c *Crypter_EncryptAndCopy_Call) RunAndReturn(run func(io.Writer, io.Reade
 	return _c
 }
 
+// EncryptAndCopyFor provides a mock function with given fields: ownerId, w, r
+func (_m *Crypter) EncryptAndCopyFor(ownerId int64, w io.Writer, r io.Reader) error {
+	ret := _m.Called(ownerId, w, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EncryptAndCopyFor")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, io.Writer, io.Reader) error); ok {
+		r0 = rf(ownerId, w, r)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Crypter_EncryptAndCopyFor_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EncryptAndCopyFor'
+type Crypter_EncryptAndCopyFor_Call struct {
+	*mock.Call
+}
+
+// EncryptAndCopyFor is a helper method to define mock.On call
+//   - ownerId int64
+//   - w io.Writer
+//   - r io.Reader
+func (_e *Crypter_Expecter) EncryptAndCopyFor(ownerId interface{}, w interface{}, r interface{}) *Crypter_EncryptAndCopyFor_Call {
+	return &Crypter_EncryptAndCopyFor_Call{Call: _e.mock.On("EncryptAndCopyFor", ownerId, w, r)}
+}
+
+func (_c *Crypter_EncryptAndCopyFor_Call) Run(run func(ownerId int64, w io.Writer, r io.Reader)) *Crypter_EncryptAndCopyFor_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(io.Writer), args[2].(io.Reader))
+	})
+	return _c
+}
+
+func (_c *Crypter_EncryptAndCopyFor_Call) Return(_a0 error) *Crypter_EncryptAndCopyFor_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Crypter_EncryptAndCopyFor_Call) RunAndReturn(run func(int64, io.Writer, io.Reader) error) *Crypter_EncryptAndCopyFor_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // EncryptFileName provides a mock function with given fields: filename
 func (_m *Crypter) EncryptFileName(filename string) (string, error) {
 	ret := _m.Called(filename)
@@ -227,6 +390,62 @@ func (_c *Crypter_EncryptFileName_Call) RunAndReturn(run func(string) (string, e
 	return _c
 }
 
+// FileNameIndex provides a mock function with given fields: filename
+func (_m *Crypter) FileNameIndex(filename string) (string, error) {
+	ret := _m.Called(filename)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FileNameIndex")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(filename)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(filename)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(filename)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Crypter_FileNameIndex_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FileNameIndex'
+type Crypter_FileNameIndex_Call struct {
+	*mock.Call
+}
+
+// FileNameIndex is a helper method to define mock.On call
+//   - filename string
+func (_e *Crypter_Expecter) FileNameIndex(filename interface{}) *Crypter_FileNameIndex_Call {
+	return &Crypter_FileNameIndex_Call{Call: _e.mock.On("FileNameIndex", filename)}
+}
+
+func (_c *Crypter_FileNameIndex_Call) Run(run func(filename string)) *Crypter_FileNameIndex_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Crypter_FileNameIndex_Call) Return(_a0 string, _a1 error) *Crypter_FileNameIndex_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Crypter_FileNameIndex_Call) RunAndReturn(run func(string) (string, error)) *Crypter_FileNameIndex_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewCrypter creates a new instance of Crypter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCrypter(t interface {
@@ -22,34 +22,22 @@ func (_m *SymmetricEncryptionProvider) EXPECT() *SymmetricEncryptionProvider_Exp
 	return &SymmetricEncryptionProvider_Expecter{mock: &_m.Mock}
 }
 
-// Decrypt provides a mock function with given fields: r, key, nonce
-func (_m *SymmetricEncryptionProvider) Decrypt(r io.Reader, key []byte, nonce []byte) ([]byte, error) {
-	ret := _m.Called(r, key, nonce)
+// Decrypt provides a mock function with given fields: w, r, key
+func (_m *SymmetricEncryptionProvider) Decrypt(w io.Writer, r io.Reader, key []byte) error {
+	ret := _m.Called(w, r, key)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Decrypt")
 	}
 
-	var r0 []byte
-	var r1 error
-	if rf, ok := ret.Get(0).(func(io.Reader, []byte, []byte) ([]byte, error)); ok {
-		return rf(r, key, nonce)
-	}
-	if rf, ok := ret.Get(0).(func(io.Reader, []byte, []byte) []byte); ok {
-		r0 = rf(r, key, nonce)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]byte)
-		}
-	}
-
-	if rf, ok := ret.Get(1).(func(io.Reader, []byte, []byte) error); ok {
-		r1 = rf(r, key, nonce)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Writer, io.Reader, []byte) error); ok {
+		r0 = rf(w, r, key)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
 // SymmetricEncryptionProvider_Decrypt_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Decrypt'
@@ -58,67 +46,46 @@ type SymmetricEncryptionProvider_Decrypt_Call struct {
 }
 
 // Decrypt is a helper method to define mock.On call
+//   - w io.Writer
 //   - r io.Reader
 //   - key []byte
-//   - nonce []byte
-func (_e *SymmetricEncryptionProvider_Expecter) Decrypt(r interface{}, key interface{}, nonce interface{}) *SymmetricEncryptionProvider_Decrypt_Call {
-	return &SymmetricEncryptionProvider_Decrypt_Call{Call: _e.mock.On("Decrypt", r, key, nonce)}
+func (_e *SymmetricEncryptionProvider_Expecter) Decrypt(w interface{}, r interface{}, key interface{}) *SymmetricEncryptionProvider_Decrypt_Call {
+	return &SymmetricEncryptionProvider_Decrypt_Call{Call: _e.mock.On("Decrypt", w, r, key)}
 }
 
-func (_c *SymmetricEncryptionProvider_Decrypt_Call) Run(run func(r io.Reader, key []byte, nonce []byte)) *SymmetricEncryptionProvider_Decrypt_Call {
+func (_c *SymmetricEncryptionProvider_Decrypt_Call) Run(run func(w io.Writer, r io.Reader, key []byte)) *SymmetricEncryptionProvider_Decrypt_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(io.Reader), args[1].([]byte), args[2].([]byte))
+		run(args[0].(io.Writer), args[1].(io.Reader), args[2].([]byte))
 	})
 	return _c
 }
 
-func (_c *SymmetricEncryptionProvider_Decrypt_Call) Return(plaintext []byte, err error) *SymmetricEncryptionProvider_Decrypt_Call {
-	_c.Call.Return(plaintext, err)
+func (_c *SymmetricEncryptionProvider_Decrypt_Call) Return(_a0 error) *SymmetricEncryptionProvider_Decrypt_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *SymmetricEncryptionProvider_Decrypt_Call) RunAndReturn(run func(io.Reader, []byte, []byte) ([]byte, error)) *SymmetricEncryptionProvider_Decrypt_Call {
+func (_c *SymmetricEncryptionProvider_Decrypt_Call) RunAndReturn(run func(io.Writer, io.Reader, []byte) error) *SymmetricEncryptionProvider_Decrypt_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Encrypt provides a mock function with given fields: r, key, rs
-func (_m *SymmetricEncryptionProvider) Encrypt(r io.Reader, key []byte, rs encryption.RandomSource) ([]byte, []byte, error) {
-	ret := _m.Called(r, key, rs)
+// Encrypt provides a mock function with given fields: w, r, key, rs
+func (_m *SymmetricEncryptionProvider) Encrypt(w io.Writer, r io.Reader, key []byte, rs encryption.RandomSource) error {
+	ret := _m.Called(w, r, key, rs)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Encrypt")
 	}
 
-	var r0 []byte
-	var r1 []byte
-	var r2 error
-	if rf, ok := ret.Get(0).(func(io.Reader, []byte, encryption.RandomSource) ([]byte, []byte, error)); ok {
-		return rf(r, key, rs)
-	}
-	if rf, ok := ret.Get(0).(func(io.Reader, []byte, encryption.RandomSource) []byte); ok {
-		r0 = rf(r, key, rs)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]byte)
-		}
-	}
-
-	if rf, ok := ret.Get(1).(func(io.Reader, []byte, encryption.RandomSource) []byte); ok {
-		r1 = rf(r, key, rs)
-	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).([]byte)
-		}
-	}
-
-	if rf, ok := ret.Get(2).(func(io.Reader, []byte, encryption.RandomSource) error); ok {
-		r2 = rf(r, key, rs)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Writer, io.Reader, []byte, encryption.RandomSource) error); ok {
+		r0 = rf(w, r, key, rs)
 	} else {
-		r2 = ret.Error(2)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1, r2
+	return r0
 }
 
 // SymmetricEncryptionProvider_Encrypt_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Encrypt'
@@ -127,26 +94,27 @@ type SymmetricEncryptionProvider_Encrypt_Call struct {
 }
 
 // Encrypt is a helper method to define mock.On call
+//   - w io.Writer
 //   - r io.Reader
 //   - key []byte
 //   - rs encryption.RandomSource
-func (_e *SymmetricEncryptionProvider_Expecter) Encrypt(r interface{}, key interface{}, rs interface{}) *SymmetricEncryptionProvider_Encrypt_Call {
-	return &SymmetricEncryptionProvider_Encrypt_Call{Call: _e.mock.On("Encrypt", r, key, rs)}
+func (_e *SymmetricEncryptionProvider_Expecter) Encrypt(w interface{}, r interface{}, key interface{}, rs interface{}) *SymmetricEncryptionProvider_Encrypt_Call {
+	return &SymmetricEncryptionProvider_Encrypt_Call{Call: _e.mock.On("Encrypt", w, r, key, rs)}
 }
 
-func (_c *SymmetricEncryptionProvider_Encrypt_Call) Run(run func(r io.Reader, key []byte, rs encryption.RandomSource)) *SymmetricEncryptionProvider_Encrypt_Call {
+func (_c *SymmetricEncryptionProvider_Encrypt_Call) Run(run func(w io.Writer, r io.Reader, key []byte, rs encryption.RandomSource)) *SymmetricEncryptionProvider_Encrypt_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(io.Reader), args[1].([]byte), args[2].(encryption.RandomSource))
+		run(args[0].(io.Writer), args[1].(io.Reader), args[2].([]byte), args[3].(encryption.RandomSource))
 	})
 	return _c
 }
 
-func (_c *SymmetricEncryptionProvider_Encrypt_Call) Return(ciphertext []byte, nonce []byte, err error) *SymmetricEncryptionProvider_Encrypt_Call {
-	_c.Call.Return(ciphertext, nonce, err)
+func (_c *SymmetricEncryptionProvider_Encrypt_Call) Return(_a0 error) *SymmetricEncryptionProvider_Encrypt_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *SymmetricEncryptionProvider_Encrypt_Call) RunAndReturn(run func(io.Reader, []byte, encryption.RandomSource) ([]byte, []byte, error)) *SymmetricEncryptionProvider_Encrypt_Call {
+func (_c *SymmetricEncryptionProvider_Encrypt_Call) RunAndReturn(run func(io.Writer, io.Reader, []byte, encryption.RandomSource) error) *SymmetricEncryptionProvider_Encrypt_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -196,51 +164,6 @@ func (_c *SymmetricEncryptionProvider_GetKeySize_Call) RunAndReturn(run func() i
 	return _c
 }
 
-// GetNonceSize provides a mock function with no fields
-func (_m *SymmetricEncryptionProvider) GetNonceSize() int {
-	ret := _m.Called()
-
-	if len(ret) == 0 {
-		panic("no return value specified for GetNonceSize")
-	}
-
-	var r0 int
-	if rf, ok := ret.Get(0).(func() int); ok {
-		r0 = rf()
-	} else {
-		r0 = ret.Get(0).(int)
-	}
-
-	return r0
-}
-
-// SymmetricEncryptionProvider_GetNonceSize_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNonceSize'
-type SymmetricEncryptionProvider_GetNonceSize_Call struct {
-	*mock.Call
-}
-
-// GetNonceSize is a helper method to define mock.On call
-func (_e *SymmetricEncryptionProvider_Expecter) GetNonceSize() *SymmetricEncryptionProvider_GetNonceSize_Call {
-	return &SymmetricEncryptionProvider_GetNonceSize_Call{Call: _e.mock.On("GetNonceSize")}
-}
-
-func (_c *SymmetricEncryptionProvider_GetNonceSize_Call) Run(run func()) *SymmetricEncryptionProvider_GetNonceSize_Call {
-	_c.Call.Run(func(args mock.Arguments) {
-		run()
-	})
-	return _c
-}
-
-func (_c *SymmetricEncryptionProvider_GetNonceSize_Call) Return(_a0 int) *SymmetricEncryptionProvider_GetNonceSize_Call {
-	_c.Call.Return(_a0)
-	return _c
-}
-
-func (_c *SymmetricEncryptionProvider_GetNonceSize_Call) RunAndReturn(run func() int) *SymmetricEncryptionProvider_GetNonceSize_Call {
-	_c.Call.Return(run)
-	return _c
-}
-
 // NewSymmetricEncryptionProvider creates a new instance of SymmetricEncryptionProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewSymmetricEncryptionProvider(t interface {
@@ -0,0 +1,68 @@
+package encryption
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// large enough to span several chunks at the default chunk size, and not a multiple of it
+const selfTestSampleSize = 256<<10 + 1
+
+// SelfTest round-trips a random key through es and a random sample through sep with that key,
+// so that a broken Vault setup or cipher shows up on startup rather than on the first upload.
+// The key is thrown away; nothing is stored.
+func SelfTest(es EncryptionService, sep SymmetricEncryptionProvider, rs RandomSource) error {
+	const op = "encryption.SelfTest"
+
+	key := make([]byte, sep.GetKeySize())
+	if _, err := io.ReadFull(rs, key); err != nil {
+		return fmt.Errorf("%s: generate key: %w", op, err)
+	}
+
+	wrapped, err := es.MakeEncryptRequest(key)
+	if err != nil {
+		return fmt.Errorf("%s: wrap key: %w", op, err)
+	}
+	if wrapped.Ciphertext == "" {
+		return fmt.Errorf("%s: wrap key: encryption service returned no ciphertext", op)
+	}
+
+	unwrapped, err := es.MakeDecryptRequest([]byte(wrapped.Ciphertext))
+	if err != nil {
+		return fmt.Errorf("%s: unwrap key: %w", op, err)
+	}
+	if !bytes.Equal([]byte(unwrapped.Plaintext), key) {
+		return fmt.Errorf("%s: unwrap key: encryption service returned a different key", op)
+	}
+
+	sample := make([]byte, selfTestSampleSize)
+	if _, err := io.ReadFull(rs, sample); err != nil {
+		return fmt.Errorf("%s: generate sample: %w", op, err)
+	}
+
+	var ciphertext bytes.Buffer
+	if err := sep.Encrypt(&ciphertext, bytes.NewReader(sample), key, rs); err != nil {
+		return fmt.Errorf("%s: encrypt sample: %w", op, err)
+	}
+	if bytes.Contains(ciphertext.Bytes(), sample[:64]) {
+		return fmt.Errorf("%s: encrypt sample: ciphertext contains the plaintext", op)
+	}
+
+	var plaintext bytes.Buffer
+	if err := sep.Decrypt(&plaintext, bytes.NewReader(ciphertext.Bytes()), key); err != nil {
+		return fmt.Errorf("%s: decrypt sample: %w", op, err)
+	}
+	if !bytes.Equal(plaintext.Bytes(), sample) {
+		return fmt.Errorf("%s: decrypt sample: plaintext differs from the sample", op)
+	}
+
+	// a cipher that doesn't authenticate would hand out tampered blobs without complaint
+	tampered := ciphertext.Bytes()
+	tampered[len(tampered)-1] ^= 1
+	if err := sep.Decrypt(io.Discard, bytes.NewReader(tampered), key); err == nil {
+		return fmt.Errorf("%s: decrypt sample: tampered ciphertext was accepted", op)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package encryption_test
+
+import (
+	"bytes"
+	"cloud-storage/encryption"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testChunkSize = 64
+
+func encryptWithAesGcm(t testing.TB, p encryption.AesGcmProvider, key []byte, plaintext []byte) []byte {
+	ciphertext := bytes.NewBuffer(make([]byte, 0))
+	assert.NoError(t, p.Encrypt(ciphertext, bytes.NewReader(plaintext), key, rand.Reader))
+	return ciphertext.Bytes()
+}
+
+func TestAesGcmProvider_RoundTrip(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			p := encryption.NewAesGcmProvider(testChunkSize, workers)
+			key := make([]byte, p.GetKeySize())
+			rand.Read(key)
+
+			sizes := []int{0, 1, testChunkSize - 1, testChunkSize, testChunkSize + 1, 3 * testChunkSize, 20*testChunkSize + 7}
+			for _, size := range sizes {
+				plaintext := make([]byte, size)
+				rand.Read(plaintext)
+
+				ciphertext := encryptWithAesGcm(t, p, key, plaintext)
+
+				decrypted := bytes.NewBuffer(make([]byte, 0))
+				assert.NoError(t, p.Decrypt(decrypted, bytes.NewReader(ciphertext), key), "size %d", size)
+				assert.Equal(t, plaintext, decrypted.Bytes(), "size %d", size)
+			}
+		})
+	}
+}
+
+func TestAesGcmProvider_ParallelMatchesSequential(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	plaintext := make([]byte, 50*testChunkSize+3)
+	rand.Read(plaintext)
+
+	encrypt := func(workers int) []byte {
+		p := encryption.NewAesGcmProvider(testChunkSize, workers)
+		ciphertext := bytes.NewBuffer(make([]byte, 0))
+		salt := bytes.NewReader(make([]byte, 32))
+		assert.NoError(t, p.Encrypt(ciphertext, bytes.NewReader(plaintext), key, salt))
+		return ciphertext.Bytes()
+	}
+
+	assert.Equal(t, encrypt(1), encrypt(8))
+}
+
+type failingWriter struct {
+	left int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.left <= 0 {
+		return 0, errors.New("disk full")
+	}
+	w.left--
+	return len(p), nil
+}
+
+func TestAesGcmProvider_ParallelWriteError(t *testing.T) {
+	p := encryption.NewAesGcmProvider(testChunkSize, 4)
+	key := make([]byte, p.GetKeySize())
+
+	plaintext := make([]byte, 100*testChunkSize)
+	err := p.Encrypt(&failingWriter{left: 3}, bytes.NewReader(plaintext), key, rand.Reader)
+	assert.ErrorContains(t, err, "disk full")
+}
+
+func TestAesGcmProvider_DetectsTampering(t *testing.T) {
+	p := encryption.NewAesGcmProvider(testChunkSize, 1)
+	key := make([]byte, p.GetKeySize())
+	rand.Read(key)
+
+	plaintext := make([]byte, 3*testChunkSize)
+	rand.Read(plaintext)
+	ciphertext := encryptWithAesGcm(t, p, key, plaintext)
+
+	sealedChunk := testChunkSize + 16
+	header := len(ciphertext) - 3*sealedChunk - 16
+
+	cases := []struct {
+		name       string
+		ciphertext func() []byte
+	}{
+		{
+			name: "Flipped bit",
+			ciphertext: func() []byte {
+				c := bytes.Clone(ciphertext)
+				c[header+sealedChunk+3] ^= 1
+				return c
+			},
+		},
+		{
+			name: "Truncated at chunk boundary",
+			ciphertext: func() []byte {
+				return bytes.Clone(ciphertext[:header+2*sealedChunk])
+			},
+		},
+		{
+			name: "Swapped chunks",
+			ciphertext: func() []byte {
+				c := bytes.Clone(ciphertext)
+				first := bytes.Clone(c[header : header+sealedChunk])
+				copy(c[header:], c[header+sealedChunk:header+2*sealedChunk])
+				copy(c[header+sealedChunk:], first)
+				return c
+			},
+		},
+		{
+			name: "Only header",
+			ciphertext: func() []byte {
+				return bytes.Clone(ciphertext[:header])
+			},
+		},
+		{
+			name: "Wrong key",
+			ciphertext: func() []byte {
+				key[0] ^= 1
+				return bytes.Clone(ciphertext)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.ciphertext()
+			assert.Error(t, p.Decrypt(bytes.NewBuffer(make([]byte, 0)), bytes.NewReader(c), key))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package encryption_test
+
+import (
+	"bytes"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/encryption"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConvergentKeys is deterministic in the plaintext and context, like a convergent vault key
+type fakeConvergentKeys struct{}
+
+func (fakeConvergentKeys) MakeConvergentEncryptRequest(plaintext []byte, context []byte) (encryption.EncryptResponse, error) {
+	sum := sha256.Sum256(append(append([]byte("secret"), context...), plaintext...))
+	return encryption.EncryptResponse{Ciphertext: "vault:v1:" + hex.EncodeToString(sum[:])}, nil
+}
+
+func TestConvergentEncryption(t *testing.T) {
+	db := db_access_mocks.NewKeyRepo(t)
+	es := encryption_mocks.NewEncryptionService(t)
+	sep := encryption.NewAesGcmProvider(64, 1)
+	c := encryption.NewSymmetricCrypter(db, es, rand.Reader, sep, time.Hour).EnableConvergentEncryption(fakeConvergentKeys{})
+
+	// wrapping reverses the key, so it stays recognizable
+	es.EXPECT().MakeEncryptRequest(mock.Anything).RunAndReturn(func(key []byte) (encryption.EncryptResponse, error) {
+		return encryption.EncryptResponse{Ciphertext: "wrapped:" + string(key)}, nil
+	})
+
+	content := bytes.Repeat([]byte("same content "), 20)
+	hash := sha256.Sum256(content)
+	otherHash := sha256.Sum256([]byte("other content"))
+
+	key, err := c.DeriveContentKey(hash[:])
+	require.NoError(t, err)
+	again, err := c.DeriveContentKey(hash[:])
+	require.NoError(t, err)
+	other, err := c.DeriveContentKey(otherHash[:])
+	require.NoError(t, err)
+
+	assert.Equal(t, key.Id, again.Id)
+	assert.NotEqual(t, key.Id, other.Id)
+	assert.NotContains(t, key.Id, hex.EncodeToString(hash[:]))
+
+	var first, second bytes.Buffer
+	require.NoError(t, c.EncryptWithContentKey(&first, bytes.NewReader(content), key))
+	require.NoError(t, c.EncryptWithContentKey(&second, bytes.NewReader(content), again))
+	// the same content gives the same blob
+	assert.Equal(t, first.Bytes(), second.Bytes())
+	assert.NotContains(t, first.String(), "same content")
+
+	db.EXPECT().GetContentKey(key.Id).Return(key.Wrapped, nil).Once()
+	es.EXPECT().MakeDecryptRequest([]byte(key.Wrapped)).RunAndReturn(func(ciphertext []byte) (encryption.DecryptResponse, error) {
+		return encryption.DecryptResponse{Plaintext: string(bytes.TrimPrefix(ciphertext, []byte("wrapped:")))}, nil
+	}).Once()
+
+	var plaintext bytes.Buffer
+	require.NoError(t, c.DecryptAndCopy(&plaintext, &first))
+	assert.Equal(t, content, plaintext.Bytes())
+}
+
+func TestConvergentEncryption_Disabled(t *testing.T) {
+	c := encryption.NewSymmetricCrypter(
+		db_access_mocks.NewKeyRepo(t),
+		encryption_mocks.NewEncryptionService(t),
+		rand.Reader,
+		encryption.NewAesGcmProvider(64, 1),
+		time.Hour,
+	)
+
+	hash := sha256.Sum256([]byte("content"))
+	_, err := c.DeriveContentKey(hash[:])
+	assert.Error(t, err)
+}
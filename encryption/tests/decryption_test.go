@@ -6,7 +6,10 @@ import (
 	db_access_mocks "cloud-storage/db_access/mocks"
 	"cloud-storage/encryption"
 	encryption_mocks "cloud-storage/encryption/mocks"
+	"crypto/rand"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"slices"
 	"testing"
 	"time"
@@ -15,34 +18,22 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-func newSEPWithNonceSize(t *testing.T) *encryption_mocks.SymmetricEncryptionProvider {
-	sep := encryption_mocks.NewSymmetricEncryptionProvider(t)
-	sep.EXPECT().GetNonceSize().Return(nonceSize)
-	return sep
-}
-
 func TestDecryptAndCopy_AES_GCM(t *testing.T) {
-	sep := newSEPWithNonceSize(t)
-	db := db_access_mocks.NewDbAccess(t)
+	sep := encryption_mocks.NewSymmetricEncryptionProvider(t)
+	db := db_access_mocks.NewKeyRepo(t)
 	es := encryption_mocks.NewEncryptionService(t)
 	rs := encryption_mocks.NewRandomSource(t)
 
 	keyId := 5
 	ciphertext := []byte("ciphertext")
 	plaintext := []byte("plaintext")
-	nonce := make([]byte, nonceSize)
-	for i := range nonce {
-		nonce[i] = byte(i)
-	}
 
 	c := encryption.NewSymmetricCrypter(db, es, rs, sep, time.Duration(0))
 
-	data := make([]byte, 8+nonceSize+len(ciphertext))
+	data := make([]byte, 8+len(ciphertext))
 	binary.LittleEndian.PutUint64(data[:8], uint64(keyId))
 
-	assert.Equal(t, len(nonce), copy(data[8:][:nonceSize], nonce))
-
-	assert.Equal(t, len(ciphertext), copy(data[8+nonceSize:], ciphertext))
+	assert.Equal(t, len(ciphertext), copy(data[8:], ciphertext))
 
 	w := bytes.NewBuffer(make([]byte, 0))
 	r := bytes.NewReader(data)
@@ -75,13 +66,71 @@ func TestDecryptAndCopy_AES_GCM(t *testing.T) {
 	})
 
 	sep.EXPECT().Decrypt(
+		w,
 		r,
 		mock.MatchedBy(func(key []byte) bool {
 			return assert.Equal(t, expectedKey, key)
 		}),
-		nonce,
-	).Return(plaintext, nil).Once()
+	).RunAndReturn(func(w io.Writer, r io.Reader, _ []byte) error {
+		rest, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, ciphertext, rest)
+
+		_, err = w.Write(plaintext)
+		return err
+	}).Once()
 
 	assert.NoError(t, c.DecryptAndCopy(w, r))
 	assert.Equal(t, plaintext, w.Bytes())
 }
+
+// FuzzDecryptAndCopy feeds corrupted blobs through the whole decryption path;
+// whatever the input, it has to either fail or reproduce the plaintext, never panic
+func FuzzDecryptAndCopy(f *testing.F) {
+	p := encryption.NewAesGcmProvider(testChunkSize, 1)
+	key := make([]byte, p.GetKeySize())
+	rand.Read(key)
+
+	const keyId = 1
+	plaintext := make([]byte, 3*testChunkSize+5)
+	rand.Read(plaintext)
+
+	blob := binary.LittleEndian.AppendUint64(nil, keyId)
+	blob = append(blob, encryptWithAesGcm(f, p, key, plaintext)...)
+
+	f.Add(blob)
+	f.Add(blob[:8])
+	f.Add(blob[:5])
+	f.Add(blob[:8+1+4+32])
+	f.Add(blob[:len(blob)-1])
+	f.Add(append(bytes.Clone(blob), 0))
+	for _, i := range []int{0, 8, 9, 13, 8 + 1 + 4 + 32, len(blob) - 1} {
+		corrupted := bytes.Clone(blob)
+		corrupted[i] ^= 0xff
+		f.Add(corrupted)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		db := db_access_mocks.NewKeyRepo(t)
+		es := encryption_mocks.NewEncryptionService(t)
+		rs := encryption_mocks.NewRandomSource(t)
+
+		db.EXPECT().GetDEC(mock.Anything).RunAndReturn(func(id db_access.DecId) (db_access.DEC, error) {
+			if id != keyId {
+				return db_access.DEC{}, fmt.Errorf("no key %d", id)
+			}
+			return db_access.DEC{Id: keyId, Value: "wrapped"}, nil
+		}).Maybe()
+		es.EXPECT().MakeDecryptRequest([]byte("wrapped")).Return(encryption.DecryptResponse{Plaintext: string(key)}, nil).Maybe()
+
+		c := encryption.NewSymmetricCrypter(db, es, rs, p, time.Duration(0))
+
+		w := bytes.NewBuffer(make([]byte, 0))
+		if err := c.DecryptAndCopy(w, bytes.NewReader(data)); err != nil {
+			return
+		}
+
+		// authentication makes any accepted blob the one that was encrypted
+		assert.Equal(t, plaintext, w.Bytes())
+	})
+}
@@ -8,7 +8,9 @@ import (
 	encryption_mocks "cloud-storage/encryption/mocks"
 	"encoding/binary"
 	"encoding/hex"
+	"io"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,7 +20,6 @@ import (
 
 const defaultKeyRotationPeriod = "1h"
 const defaultKey = "6368616e676520746869732070617373776f726420746f206120736563726574"
-const nonceSize = 12
 const aesKeySize = 32
 
 const firstKeyId = 2
@@ -30,7 +31,7 @@ func TestEncryptAndCopy_AES_GCM(t *testing.T) {
 	cases := []struct {
 		name string
 		cfg  func(
-			db *db_access_mocks.DbAccess,
+			db *db_access_mocks.KeyRepo,
 			es *encryption_mocks.EncryptionService,
 			rs *encryption_mocks.RandomSource,
 			sep *encryption_mocks.SymmetricEncryptionProvider,
@@ -54,7 +55,7 @@ func TestEncryptAndCopy_AES_GCM(t *testing.T) {
 			key, err := hex.DecodeString(defaultKey)
 			assert.NoError(t, err)
 
-			db := db_access_mocks.NewDbAccess(t)
+			db := db_access_mocks.NewKeyRepo(t)
 			es := encryption_mocks.NewEncryptionService(t)
 			rs := encryption_mocks.NewRandomSource(t)
 			sep := encryption_mocks.NewSymmetricEncryptionProvider(t)
@@ -81,7 +82,7 @@ func TestEncryptAndCopy_AES_GCM_KeyRotation(t *testing.T) {
 	newKey := slices.Clone(oldKey)
 	slices.Reverse(newKey)
 
-	db := db_access_mocks.NewDbAccess(t)
+	db := db_access_mocks.NewKeyRepo(t)
 	es := encryption_mocks.NewEncryptionService(t)
 	rs := encryption_mocks.NewRandomSource(t)
 	sep := encryption_mocks.NewSymmetricEncryptionProvider(t)
@@ -94,7 +95,7 @@ func TestEncryptAndCopy_AES_GCM_KeyRotation(t *testing.T) {
 	sep.EXPECT().GetKeySize().Return(aesKeySize).Once()
 
 	db.EXPECT().GetNewestDEC().Return(dbaccess.DEC{
-		Id:           newKeyId,
+		Id:           firstKeyId,
 		Value:        encryptedOldKey,
 		CreationTime: zeroTime,
 	}, nil).Once()
@@ -106,11 +107,12 @@ func TestEncryptAndCopy_AES_GCM_KeyRotation(t *testing.T) {
 
 	es.EXPECT().MakeEncryptRequest(newKey).Return(encryption.EncryptResponse{
 		Ciphertext: encryptedNewKey,
-		KeyVersion: 1,
+		KeyVersion: 2,
 	}, nil).Once()
 
 	db.EXPECT().AddDEC(mock.MatchedBy(func(dec *dbaccess.DEC) bool {
-		return assert.Equal(t, encryptedNewKey, dec.Value)
+		dec.Id = newKeyId
+		return assert.Equal(t, encryptedNewKey, dec.Value) && assert.Equal(t, int64(2), dec.KeyVersion)
 	})).Return(nil).Once()
 
 	d, err := time.ParseDuration(defaultKeyRotationPeriod)
@@ -122,7 +124,7 @@ func TestEncryptAndCopy_AES_GCM_KeyRotation(t *testing.T) {
 }
 
 func WhenNewestDecProvided(
-	db *db_access_mocks.DbAccess,
+	db *db_access_mocks.KeyRepo,
 	es *encryption_mocks.EncryptionService,
 	rs *encryption_mocks.RandomSource,
 	sep *encryption_mocks.SymmetricEncryptionProvider,
@@ -142,7 +144,7 @@ func WhenNewestDecProvided(
 }
 
 func WhenNoDEC(
-	db *db_access_mocks.DbAccess,
+	db *db_access_mocks.KeyRepo,
 	es *encryption_mocks.EncryptionService,
 	rs *encryption_mocks.RandomSource,
 	sep *encryption_mocks.SymmetricEncryptionProvider,
@@ -183,25 +185,149 @@ func assertEncryption(
 	w := bytes.NewBuffer(make([]byte, 0))
 
 	expectedCiphertext := []byte("test ciphertext")
-	expectedNonce := make([]byte, nonceSize)
-	fillWithNonce(expectedNonce)
 
-	sep.EXPECT().Encrypt(r, expectedKey, rs).Return(expectedCiphertext, expectedNonce, nil).Once()
+	sep.EXPECT().Encrypt(w, r, expectedKey, rs).RunAndReturn(func(w io.Writer, _ io.Reader, _ []byte, _ encryption.RandomSource) error {
+		_, err := w.Write(expectedCiphertext)
+		return err
+	}).Once()
 	assert.NoError(t, crypter.EncryptAndCopy(w, r))
 
 	data := w.Bytes()
 	keyId := data[:8]
 	assert.Equal(t, expectedKeyId, int64(binary.LittleEndian.Uint64(keyId)))
 
-	nonce := data[8:][:nonceSize]
-	assert.Equal(t, expectedNonce, nonce)
-
-	ciphertext := data[8+nonceSize:]
+	ciphertext := data[8:]
 	assert.Equal(t, expectedCiphertext, ciphertext)
 }
 
-func fillWithNonce(p []byte) {
-	for i := range p {
-		p[i] = byte(i)
+func newNameCrypter(t *testing.T) (*encryption.SymmetricCrypter, *db_access_mocks.KeyRepo, *encryption_mocks.EncryptionService) {
+	key, err := hex.DecodeString(defaultKey)
+	assert.NoError(t, err)
+
+	db := db_access_mocks.NewKeyRepo(t)
+	es := encryption_mocks.NewEncryptionService(t)
+	rs := encryption_mocks.NewRandomSource(t)
+	rs.EXPECT().Read(mock.Anything).RunAndReturn(func(p []byte) (int, error) {
+		return copy(p, bytes.Repeat([]byte{1}, len(p))), nil
+	}).Maybe()
+
+	db.EXPECT().GetNewestDEC().Return(dbaccess.DEC{
+		Id:           firstKeyId,
+		Value:        "vault:v1:key",
+		CreationTime: dbaccess.Time(time.Now()),
+	}, nil).Maybe()
+	// unwrapped once however many names use it
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:key")).Return(encryption.DecryptResponse{Plaintext: string(key)}, nil).Once()
+
+	c := encryption.NewSymmetricCrypter(db, es, rs, encryption_mocks.NewSymmetricEncryptionProvider(t), time.Hour)
+	return c, db, es
+}
+
+func TestEncryptFileName(t *testing.T) {
+	c, db, es := newNameCrypter(t)
+
+	ciphertext, err := c.EncryptFileName("report.txt")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "dec:2:"), ciphertext)
+	assert.NotContains(t, ciphertext, "report")
+
+	name, err := c.DecryptFileName(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "report.txt", name)
+
+	// a name sealed under one DEC doesn't open as sealed under another
+	db.EXPECT().GetDEC(dbaccess.DecId(3)).Return(dbaccess.DEC{Id: 3, Value: "vault:v1:other"}, nil).Once()
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:other")).Return(encryption.DecryptResponse{Plaintext: strings.Repeat("k", 32)}, nil).Once()
+	_, err = c.DecryptFileName(strings.Replace(ciphertext, "dec:2:", "dec:3:", 1))
+	assert.Error(t, err)
+	_, err = c.DecryptFileName("dec:2:!!")
+	assert.ErrorIs(t, err, encryption.ErrUnsupportedFormat)
+
+	// names from before are vault ciphertexts
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:old")).Return(encryption.DecryptResponse{Plaintext: "old.txt"}, nil).Once()
+	name, err = c.DecryptFileName("vault:v1:old")
+	assert.NoError(t, err)
+	assert.Equal(t, "old.txt", name)
+}
+
+func TestEncryptFileName_OtherDEC(t *testing.T) {
+	c, db, es := newNameCrypter(t)
+
+	ciphertext, err := c.EncryptFileName("report.txt")
+	assert.NoError(t, err)
+
+	// another instance only knows the DEC by its id
+	other := encryption.NewSymmetricCrypter(db, es, nil, nil, time.Hour)
+	db.EXPECT().GetDEC(dbaccess.DecId(firstKeyId)).Return(dbaccess.DEC{Id: firstKeyId, Value: "vault:v1:key"}, nil).Once()
+	key, _ := hex.DecodeString(defaultKey)
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:key")).Return(encryption.DecryptResponse{Plaintext: string(key)}, nil).Once()
+
+	for range 2 {
+		name, err := other.DecryptFileName(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, "report.txt", name)
 	}
 }
+
+func TestEncryptFileName_Length(t *testing.T) {
+	c, _, _ := newNameCrypter(t)
+
+	name := string(bytes.Repeat([]byte("a"), encryption.MaxFileNameLen))
+	ciphertext, err := c.EncryptFileName(name)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(ciphertext), encryption.MaxEncryptedFileNameLen)
+
+	_, err = c.EncryptFileName(name + "a")
+	assert.ErrorIs(t, err, encryption.ErrFileNameTooLong)
+}
+
+func TestFileNameIndex(t *testing.T) {
+	db := db_access_mocks.NewKeyRepo(t)
+	es := encryption_mocks.NewEncryptionService(t)
+	rs := encryption_mocks.NewRandomSource(t)
+	c := encryption.NewSymmetricCrypter(db, es, rs, encryption_mocks.NewSymmetricEncryptionProvider(t), time.Hour)
+
+	key := bytes.Repeat([]byte{7}, 32)
+	db.EXPECT().GetIndexKey().Return("", dbaccess.NoRowsError{}).Once()
+	rs.EXPECT().Read(mock.Anything).RunAndReturn(func(p []byte) (int, error) {
+		return copy(p, key), nil
+	}).Once()
+	es.EXPECT().MakeEncryptRequest(key).Return(encryption.EncryptResponse{Ciphertext: "vault:v1:mine"}, nil).Once()
+	// another instance got there first, so its key is used
+	db.EXPECT().AddIndexKey("vault:v1:mine").Return("vault:v1:theirs", nil).Once()
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:theirs")).Return(encryption.DecryptResponse{Plaintext: "their key"}, nil).Once()
+
+	first, err := c.FileNameIndex("Report.TXT")
+	assert.NoError(t, err)
+	second, err := c.FileNameIndex("report.txt")
+	assert.NoError(t, err)
+	other, err := c.FileNameIndex("report.txt ")
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+	assert.NotContains(t, first, "report")
+}
+
+func TestContentTermIndex(t *testing.T) {
+	db := db_access_mocks.NewKeyRepo(t)
+	es := encryption_mocks.NewEncryptionService(t)
+	c := encryption.NewSymmetricCrypter(db, es, encryption_mocks.NewRandomSource(t), encryption_mocks.NewSymmetricEncryptionProvider(t), time.Hour)
+
+	db.EXPECT().GetIndexKey().Return("vault:v1:key", nil).Once()
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:key")).Return(encryption.DecryptResponse{Plaintext: "index key"}, nil).Once()
+
+	first, err := c.ContentTermIndex(1, "invoice")
+	assert.NoError(t, err)
+	again, err := c.ContentTermIndex(1, "invoice")
+	assert.NoError(t, err)
+	otherOwner, err := c.ContentTermIndex(2, "invoice")
+	assert.NoError(t, err)
+	name, err := c.FileNameIndex("invoice")
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first, otherOwner)
+	assert.NotEqual(t, first, name)
+	assert.Len(t, first, 22)
+}
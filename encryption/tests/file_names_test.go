@@ -0,0 +1,106 @@
+package encryption_test
+
+import (
+	"cloud-storage/encryption"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBatchVault decrypts names of the form vault:v1:<name> and records the batches it got
+type fakeBatchVault struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (v *fakeBatchVault) MakeBatchDecryptRequest(ciphertexts [][]byte) ([]encryption.DecryptResponse, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	batch := make([]string, 0, len(ciphertexts))
+	responses := make([]encryption.DecryptResponse, 0, len(ciphertexts))
+	for _, ciphertext := range ciphertexts {
+		batch = append(batch, string(ciphertext))
+		responses = append(responses, encryption.DecryptResponse{Plaintext: strings.TrimPrefix(string(ciphertext), "vault:v1:")})
+	}
+	v.batches = append(v.batches, batch)
+
+	if v.err != nil {
+		return nil, v.err
+	}
+	return responses, nil
+}
+
+func TestDecryptFileNames(t *testing.T) {
+	c, _, _ := newNameCrypter(t)
+	bs := &fakeBatchVault{}
+	c.ConfigureNameDecryption(encryption.NameDecryption{Workers: 2, BatchSize: 2, CacheSize: 10}, bs)
+
+	var ciphertexts, expected []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		ciphertext, err := c.EncryptFileName(name)
+		require.NoError(t, err)
+		ciphertexts = append(ciphertexts, ciphertext, "vault:v1:old-"+name)
+		expected = append(expected, name, "old-"+name)
+	}
+
+	names, err := c.DecryptFileNames(ciphertexts)
+	require.NoError(t, err)
+	assert.Equal(t, expected, names)
+
+	// vault gets the old names of a batch together
+	assert.Len(t, bs.batches, 3)
+	for _, batch := range bs.batches {
+		assert.Len(t, batch, 1)
+	}
+
+	// decrypted names are cached
+	names, err = c.DecryptFileNames(ciphertexts)
+	require.NoError(t, err)
+	assert.Equal(t, expected, names)
+	assert.Len(t, bs.batches, 3)
+
+	name, err := c.DecryptFileName("vault:v1:old-b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "old-b.txt", name)
+
+	names, err = c.DecryptFileNames(nil)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestDecryptFileNames_Failure(t *testing.T) {
+	c, _, _ := newNameCrypter(t)
+	bs := &fakeBatchVault{err: errors.New("vault sealed")}
+	c.ConfigureNameDecryption(encryption.NameDecryption{BatchSize: 10}, bs)
+
+	ciphertext, err := c.EncryptFileName("a.txt")
+	require.NoError(t, err)
+
+	_, err = c.DecryptFileNames([]string{ciphertext, "vault:v1:old.txt"})
+	assert.ErrorContains(t, err, "vault sealed")
+
+	_, err = c.DecryptFileNames([]string{ciphertext, "dec:2:!!"})
+	assert.ErrorIs(t, err, encryption.ErrUnsupportedFormat)
+}
+
+func TestDecryptFileNames_OneByOne(t *testing.T) {
+	c, _, es := newNameCrypter(t)
+	// without a batch service nor a cache, old names go to vault as they come
+	c.ConfigureNameDecryption(encryption.NameDecryption{Workers: 1, BatchSize: 1}, nil)
+
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:a")).Return(encryption.DecryptResponse{Plaintext: "a"}, nil).Twice()
+	es.EXPECT().MakeDecryptRequest([]byte("vault:v1:b")).Return(encryption.DecryptResponse{Plaintext: "b"}, nil).Once()
+
+	ciphertext, err := c.EncryptFileName("c")
+	require.NoError(t, err)
+
+	names, err := c.DecryptFileNames([]string{"vault:v1:a", ciphertext, "vault:v1:b", "vault:v1:a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c", "b", "a"}, names)
+}
@@ -0,0 +1,72 @@
+package encryption_test
+
+import (
+	"cloud-storage/encryption"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// wrappingService stands in for vault with a reversible prefix
+func wrappingService(t *testing.T, unwrap func(ciphertext []byte) (encryption.DecryptResponse, error)) *encryption_mocks.EncryptionService {
+	es := encryption_mocks.NewEncryptionService(t)
+	es.EXPECT().MakeEncryptRequest(mock.Anything).RunAndReturn(func(plaintext []byte) (encryption.EncryptResponse, error) {
+		return encryption.EncryptResponse{Ciphertext: "vault:v1:" + string(plaintext)}, nil
+	}).Once()
+	es.EXPECT().MakeDecryptRequest(mock.Anything).RunAndReturn(unwrap).Once()
+	return es
+}
+
+func unwrapPrefix(ciphertext []byte) (encryption.DecryptResponse, error) {
+	return encryption.DecryptResponse{Plaintext: strings.TrimPrefix(string(ciphertext), "vault:v1:")}, nil
+}
+
+// copyProvider "encrypts" by copying, so it neither hides nor authenticates anything
+type copyProvider struct{}
+
+func (copyProvider) Encrypt(w io.Writer, r io.Reader, _ []byte, _ encryption.RandomSource) error {
+	_, err := io.Copy(w, r)
+	return err
+}
+
+func (copyProvider) Decrypt(w io.Writer, r io.Reader, _ []byte) error {
+	_, err := io.Copy(w, r)
+	return err
+}
+
+func (copyProvider) GetKeySize() int {
+	return aesKeySize
+}
+
+func TestSelfTest(t *testing.T) {
+	sep := encryption.NewAesGcmProvider(testChunkSize, 2)
+
+	assert.NoError(t, encryption.SelfTest(wrappingService(t, unwrapPrefix), sep, rand.Reader))
+
+	err := encryption.SelfTest(wrappingService(t, func([]byte) (encryption.DecryptResponse, error) {
+		return encryption.DecryptResponse{Plaintext: "some other key"}, nil
+	}), sep, rand.Reader)
+	assert.ErrorContains(t, err, "unwrap key")
+
+	err = encryption.SelfTest(wrappingService(t, func([]byte) (encryption.DecryptResponse, error) {
+		return encryption.DecryptResponse{}, errors.New("permission denied")
+	}), sep, rand.Reader)
+	assert.ErrorContains(t, err, "permission denied")
+
+	err = encryption.SelfTest(wrappingService(t, unwrapPrefix), copyProvider{}, rand.Reader)
+	assert.ErrorContains(t, err, "encrypt sample")
+}
+
+func TestSelfTest_VaultUnreachable(t *testing.T) {
+	es := encryption_mocks.NewEncryptionService(t)
+	es.EXPECT().MakeEncryptRequest(mock.Anything).Return(encryption.EncryptResponse{}, errors.New("connection refused")).Once()
+
+	err := encryption.SelfTest(es, encryption.NewAesGcmProvider(testChunkSize, 1), rand.Reader)
+	assert.ErrorContains(t, err, "wrap key")
+}
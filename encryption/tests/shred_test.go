@@ -0,0 +1,44 @@
+package encryption_test
+
+import (
+	"bytes"
+	dbaccess "cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/encryption"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShredUserKeys(t *testing.T) {
+	db := db_access_mocks.NewKeyRepo(t)
+	es := encryption_mocks.NewEncryptionService(t)
+	c := encryption.NewSymmetricCrypter(db, es, rand.Reader, encryption.NewAesGcmProvider(64, 1), time.Hour)
+
+	// the user gets a key of their own, apart from the shared ones
+	db.EXPECT().GetNewestUserDEC(int64(1)).Return(dbaccess.DEC{}, dbaccess.NoRowsError{}).Once()
+	es.EXPECT().MakeEncryptRequest(mock.Anything).Return(encryption.EncryptResponse{Ciphertext: "wrapped"}, nil).Once()
+	db.EXPECT().AddDEC(mock.MatchedBy(func(dec *dbaccess.DEC) bool {
+		dec.Id = 7
+		return dec.OwnerId == 1
+	})).Return(nil).Once()
+
+	var blob bytes.Buffer
+	require.NoError(t, c.EncryptAndCopyFor(1, &blob, bytes.NewReader([]byte("content"))))
+
+	var plain bytes.Buffer
+	require.NoError(t, c.DecryptAndCopy(&plain, bytes.NewReader(blob.Bytes())))
+	assert.Equal(t, "content", plain.String())
+
+	db.EXPECT().RemoveUserDECs(int64(1)).Return([]dbaccess.DecId{7}, nil).Once()
+	require.NoError(t, c.ShredUserKeys(1))
+
+	// the key isn't kept in memory either, so copies of the blob can't be read anymore
+	db.EXPECT().GetDEC(dbaccess.DecId(7)).Return(dbaccess.DEC{}, dbaccess.NoRowsError{Table: "decs"}).Once()
+	assert.Error(t, c.DecryptAndCopy(&plain, bytes.NewReader(blob.Bytes())))
+}
@@ -0,0 +1,186 @@
+package encryption_test
+
+import (
+	"bytes"
+	"cloud-storage/encryption"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVault "encrypts" by prefixing the base64 plaintext
+func newFakeVault(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-token", r.Header.Get("X-Vault-Token"))
+
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/encrypt/test-key"):
+			fmt.Fprintf(w, `{"data":{"ciphertext":"vault:v1:%s","key_version":1}}`, body["plaintext"])
+		case strings.HasSuffix(r.URL.Path, "/decrypt/test-key"):
+			fmt.Fprintf(w, `{"data":{"plaintext":"%s"}}`, strings.TrimPrefix(body["ciphertext"], "vault:v1:"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestVault(t *testing.T, address string) *encryption.Vault {
+	t.Setenv("VAULT_TOKEN", "test-token")
+	t.Setenv("VAULT_ADDR", address)
+	t.Setenv("KEY_STORAGE", "transit")
+	t.Setenv("KEY_NAME", "test-key")
+	return encryption.NewVault()
+}
+
+func TestVault_RoundTrip(t *testing.T) {
+	server := newFakeVault(t)
+	defer server.Close()
+
+	v := newTestVault(t, server.URL)
+
+	cases := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{name: "Small payload", plaintext: []byte("file name.txt")},
+		// big enough to be streamed through a pipe
+		{name: "Large payload", plaintext: bytes.Repeat([]byte{0, 1, 2, 250}, 4096)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted, err := v.MakeEncryptRequest(tc.plaintext)
+			assert.NoError(t, err)
+			assert.Equal(t, "vault:v1:"+base64.StdEncoding.EncodeToString(tc.plaintext), encrypted.Ciphertext)
+
+			decrypted, err := v.MakeDecryptRequest([]byte(encrypted.Ciphertext))
+			assert.NoError(t, err)
+			assert.Equal(t, string(tc.plaintext), decrypted.Plaintext)
+		})
+	}
+}
+
+func TestVault_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+	}))
+	defer server.Close()
+
+	v := newTestVault(t, server.URL)
+
+	_, err := v.MakeEncryptRequest([]byte("name"))
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestVault_ConvergentEncrypt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		if !strings.HasSuffix(r.URL.Path, "/encrypt/convergent-key") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"ciphertext":"vault:v1:%s:%s","key_version":1}}`, body["context"], body["plaintext"])
+	}))
+	defer server.Close()
+
+	t.Setenv("CONVERGENT_KEY_NAME", "convergent-key")
+	v := newTestVault(t, server.URL)
+
+	encrypted, err := v.MakeConvergentEncryptRequest([]byte("hash"), []byte("context"))
+	assert.NoError(t, err)
+	assert.Equal(t, "vault:v1:"+base64.StdEncoding.EncodeToString([]byte("context"))+":"+base64.StdEncoding.EncodeToString([]byte("hash")), encrypted.Ciphertext)
+
+	// without the key convergent encryption can't be used
+	v = newTestVault(t, server.URL)
+	_, err = v.MakeConvergentEncryptRequest([]byte("hash"), []byte("context"))
+	assert.ErrorContains(t, err, "CONVERGENT_KEY_NAME")
+}
+
+func TestVault_Rewrap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/transit/keys/test-key"):
+			fmt.Fprint(w, `{"data":{"name":"test-key","latest_version":3}}`)
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/transit/rewrap/test-key"):
+			var body map[string]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			fmt.Fprintf(w, `{"data":{"ciphertext":"vault:v3:%s","key_version":3}}`, strings.TrimPrefix(body["ciphertext"], "vault:v1:"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	v := newTestVault(t, server.URL)
+
+	latest, err := v.LatestKeyVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), latest)
+
+	rewrapped, err := v.MakeRewrapRequest([]byte("vault:v1:bmFtZQ=="))
+	assert.NoError(t, err)
+	assert.Equal(t, "vault:v3:bmFtZQ==", rewrapped.Ciphertext)
+	assert.Equal(t, int64(3), rewrapped.KeyVersion)
+}
+
+func TestKeyVersion(t *testing.T) {
+	cases := map[string]int64{
+		"vault:v1:bmFtZQ==":  1,
+		"vault:v12:bmFtZQ==": 12,
+		"vault:v:bmFtZQ==":   0,
+		"vault:vx:bmFtZQ==":  0,
+		"vault:v3":           0,
+		"enc:name":           0,
+		"":                   0,
+	}
+
+	for ciphertext, want := range cases {
+		assert.Equal(t, want, encryption.KeyVersion(ciphertext), ciphertext)
+	}
+}
+
+func TestVault_BatchDecrypt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			BatchInput []struct {
+				Ciphertext string `json:"ciphertext"`
+			} `json:"batch_input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.True(t, strings.HasSuffix(r.URL.Path, "/transit/decrypt/test-key"))
+
+		results := make([]map[string]string, 0, len(body.BatchInput))
+		for _, item := range body.BatchInput {
+			if item.Ciphertext == "vault:v1:broken" {
+				results = append(results, map[string]string{"error": "invalid ciphertext"})
+				continue
+			}
+			results = append(results, map[string]string{"plaintext": strings.TrimPrefix(item.Ciphertext, "vault:v1:")})
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"batch_results": results}}))
+	}))
+	defer server.Close()
+
+	v := newTestVault(t, server.URL)
+
+	a := base64.StdEncoding.EncodeToString([]byte("a.txt"))
+	b := base64.StdEncoding.EncodeToString([]byte("b.txt"))
+	responses, err := v.MakeBatchDecryptRequest([][]byte{[]byte("vault:v1:" + a), []byte("vault:v1:" + b)})
+	assert.NoError(t, err)
+	assert.Equal(t, []encryption.DecryptResponse{{Plaintext: "a.txt"}, {Plaintext: "b.txt"}}, responses)
+
+	_, err = v.MakeBatchDecryptRequest([][]byte{[]byte("vault:v1:" + a), []byte("vault:v1:broken")})
+	assert.ErrorContains(t, err, "item 1: invalid ciphertext")
+}
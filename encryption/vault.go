@@ -5,9 +5,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type EncryptionService interface {
@@ -15,6 +19,19 @@ type EncryptionService interface {
 	MakeDecryptRequest(ciphertext []byte) (DecryptResponse, error)
 }
 
+// ConvergentKeyService encrypts deterministically: the same plaintext and context always give the same ciphertext
+type ConvergentKeyService interface {
+	MakeConvergentEncryptRequest(plaintext []byte, context []byte) (EncryptResponse, error)
+}
+
+// RewrapService moves ciphertexts onto the latest version of the transit key;
+// vault decrypts and encrypts again by itself, so the plaintext never leaves it
+type RewrapService interface {
+	// LatestKeyVersion returns the version of the transit key that new ciphertexts are made with
+	LatestKeyVersion() (int64, error)
+	MakeRewrapRequest(ciphertext []byte) (EncryptResponse, error)
+}
+
 type EncryptResponse struct {
 	Ciphertext string `json:"ciphertext"`
 	KeyVersion int64  `json:"key_version"`
@@ -24,11 +41,47 @@ type DecryptResponse struct {
 	Plaintext string `json:"plaintext"`
 }
 
+type batchItem struct {
+	Ciphertext string `json:"ciphertext"`
+}
+
+type batchResponse struct {
+	BatchResults []struct {
+		Plaintext string `json:"plaintext"`
+		Error     string `json:"error"`
+	} `json:"batch_results"`
+}
+
+type keyResponse struct {
+	LatestVersion int64 `json:"latest_version"`
+}
+
+// KeyVersion returns the version of the transit key a vault ciphertext was made with,
+// which vault puts in front of it as "vault:v<version>:"; it is 0 for anything else
+func KeyVersion(ciphertext string) int64 {
+	rest, ok := strings.CutPrefix(ciphertext, "vault:v")
+	if !ok {
+		return 0
+	}
+	version, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0
+	}
+
+	n, err := strconv.ParseInt(version, 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
 type vaultAction string
 
 const (
 	encrypt vaultAction = "encrypt"
 	decrypt vaultAction = "decrypt"
+	rewrap  vaultAction = "rewrap"
+	keyInfo vaultAction = "keys"
 )
 
 const (
@@ -36,6 +89,9 @@ const (
 	vaultAddrEnvVar  = "VAULT_ADDR"
 	keyStorageEnvVar = "KEY_STORAGE"
 	keyNameEnvVar    = "KEY_NAME"
+	// convergentKeyNameEnvVar names a transit key created with derived=true and convergent_encryption=true;
+	// it is only needed with convergent encryption enabled
+	convergentKeyNameEnvVar = "CONVERGENT_KEY_NAME"
 )
 
 type Vault struct {
@@ -43,6 +99,8 @@ type Vault struct {
 	vaultToken   string
 	keyStorage   string
 	keyName      string
+	// empty unless convergent encryption is set up
+	convergentKeyName string
 }
 
 type VaultResponse[DataT any] struct {
@@ -74,44 +132,69 @@ func NewVault() *Vault {
 	}
 	defer os.Unsetenv(keyNameEnvVar)
 
+	convergentKeyName := os.Getenv(convergentKeyNameEnvVar)
+	defer os.Unsetenv(convergentKeyNameEnvVar)
+
 	// TODO: renew token
 
 	return &Vault{
-		vaultAddress: address,
-		vaultToken:   token,
-		keyStorage:   keyStorage,
-		keyName:      keyName,
+		vaultAddress:      address,
+		vaultToken:        token,
+		keyStorage:        keyStorage,
+		keyName:           keyName,
+		convergentKeyName: convergentKeyName,
 	}
 }
 
 func (v *Vault) MakeEncryptRequest(plaintext []byte) (EncryptResponse, error) {
 	const op = "encryption.Vault.MakeEncryptRequest"
 
-	buf := bytes.NewBuffer(make([]byte, 0))
-	encoder := base64.NewEncoder(base64.StdEncoding, buf)
+	body, release := newVaultRequestBody("plaintext", plaintext, true)
+	defer release()
 
-	_, err := encoder.Write(plaintext)
+	resp, err := v.makeRequest("POST", encrypt, v.keyName, body)
 	if err != nil {
-		return EncryptResponse{}, fmt.Errorf("%s: encoder.Write: %w", op, err)
+		return EncryptResponse{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer resp.Body.Close()
+
+	var response VaultResponse[EncryptResponse]
 
-	err = encoder.Close()
+	jsonDecoder := json.NewDecoder(resp.Body)
+	err = jsonDecoder.Decode(&response)
 	if err != nil {
-		return EncryptResponse{}, fmt.Errorf("%s: encoder.Close: %w", op, err)
+		return EncryptResponse{}, fmt.Errorf("%s: decoder.Decode: %w", op, err)
 	}
 
-	body := newVaultRequestBody(`{ "plaintext":"`, buf.Bytes(), `" }`)
-	resp, err := v.makeRequest(encrypt, body)
+	return response.Data, nil
+}
+
+// MakeConvergentEncryptRequest encrypts with the key derived from the convergent key for the context,
+// which vault does deterministically for keys set up for convergent encryption
+func (v *Vault) MakeConvergentEncryptRequest(plaintext []byte, context []byte) (EncryptResponse, error) {
+	const op = "encryption.Vault.MakeConvergentEncryptRequest"
+
+	if v.convergentKeyName == "" {
+		return EncryptResponse{}, fmt.Errorf("%s: env var %s is not set", op, convergentKeyNameEnvVar)
+	}
+
+	// the payload is a hash, so there is nothing to stream
+	body, err := json.Marshal(map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+		"context":   base64.StdEncoding.EncodeToString(context),
+	})
+	if err != nil {
+		return EncryptResponse{}, fmt.Errorf("%s: json.Marshal: %w", op, err)
+	}
+
+	resp, err := v.makeRequest("POST", encrypt, v.convergentKeyName, bytes.NewReader(body))
 	if err != nil {
 		return EncryptResponse{}, fmt.Errorf("%s: %w", op, err)
 	}
 	defer resp.Body.Close()
 
 	var response VaultResponse[EncryptResponse]
-
-	jsonDecoder := json.NewDecoder(resp.Body)
-	err = jsonDecoder.Decode(&response)
-	if err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return EncryptResponse{}, fmt.Errorf("%s: decoder.Decode: %w", op, err)
 	}
 
@@ -121,8 +204,10 @@ func (v *Vault) MakeEncryptRequest(plaintext []byte) (EncryptResponse, error) {
 func (v *Vault) MakeDecryptRequest(ciphertext []byte) (DecryptResponse, error) {
 	const op = "encryption.Vault.MakeDecryptRequest"
 
-	body := newVaultRequestBody(`{ "ciphertext":"`, ciphertext, `" }`)
-	resp, err := v.makeRequest(decrypt, body)
+	body, release := newVaultRequestBody("ciphertext", ciphertext, false)
+	defer release()
+
+	resp, err := v.makeRequest("POST", decrypt, v.keyName, body)
 	if err != nil {
 		return DecryptResponse{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -136,8 +221,12 @@ func (v *Vault) MakeDecryptRequest(ciphertext []byte) (DecryptResponse, error) {
 		return DecryptResponse{}, fmt.Errorf("%s: decoder.Decode: %w", op, err)
 	}
 
-	buf := bytes.NewBuffer(make([]byte, 0))
-	base64Decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader([]byte(response.Data.Plaintext)))
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	encoded := response.Data.Plaintext
+	buf.Grow(base64.StdEncoding.DecodedLen(len(encoded)))
+	base64Decoder := base64.NewDecoder(base64.StdEncoding, strings.NewReader(encoded))
 	_, err = buf.ReadFrom(base64Decoder)
 	if err != nil {
 		return DecryptResponse{}, fmt.Errorf("%s: decoder.Read: %w", op, err)
@@ -146,23 +235,160 @@ func (v *Vault) MakeDecryptRequest(ciphertext []byte) (DecryptResponse, error) {
 	return DecryptResponse{Plaintext: buf.String()}, nil
 }
 
-func newVaultRequestBody(first string, value []byte, last string) *bytes.Reader {
-	totalLen := len(first) + len(value) + len(last)
-	contents := make([]byte, totalLen)
+// MakeBatchDecryptRequest decrypts the ciphertexts with one request to vault, which returns
+// the results in the same order; the batch fails if any ciphertext doesn't decrypt
+func (v *Vault) MakeBatchDecryptRequest(ciphertexts [][]byte) ([]DecryptResponse, error) {
+	const op = "encryption.Vault.MakeBatchDecryptRequest"
+
+	input := make([]batchItem, 0, len(ciphertexts))
+	for _, ciphertext := range ciphertexts {
+		input = append(input, batchItem{Ciphertext: string(ciphertext)})
+	}
+	body, err := json.Marshal(map[string][]batchItem{"batch_input": input})
+	if err != nil {
+		return nil, fmt.Errorf("%s: json.Marshal: %w", op, err)
+	}
+
+	resp, err := v.makeRequest("POST", decrypt, v.keyName, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
 
-	n := copy(contents, first)
-	n += copy(contents[n:], value)
-	copy(contents[n:], last)
+	var response VaultResponse[batchResponse]
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("%s: decoder.Decode: %w", op, err)
+	}
 
-	return bytes.NewReader(contents)
+	results := response.Data.BatchResults
+	if len(results) != len(ciphertexts) {
+		return nil, fmt.Errorf("%s: %d results for %d ciphertexts", op, len(results), len(ciphertexts))
+	}
+
+	plaintexts := make([]DecryptResponse, 0, len(results))
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("%s: item %d: %s", op, i, result.Error)
+		}
+
+		plaintext, err := base64.StdEncoding.DecodeString(result.Plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("%s: item %d: base64: %w", op, i, err)
+		}
+		plaintexts = append(plaintexts, DecryptResponse{Plaintext: string(plaintext)})
+	}
+
+	return plaintexts, nil
+}
+
+func (v *Vault) MakeRewrapRequest(ciphertext []byte) (EncryptResponse, error) {
+	const op = "encryption.Vault.MakeRewrapRequest"
+
+	body, release := newVaultRequestBody("ciphertext", ciphertext, false)
+	defer release()
+
+	resp, err := v.makeRequest("POST", rewrap, v.keyName, body)
+	if err != nil {
+		return EncryptResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	var response VaultResponse[EncryptResponse]
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return EncryptResponse{}, fmt.Errorf("%s: decoder.Decode: %w", op, err)
+	}
+
+	return response.Data, nil
+}
+
+func (v *Vault) LatestKeyVersion() (int64, error) {
+	const op = "encryption.Vault.LatestKeyVersion"
+
+	resp, err := v.makeRequest("GET", keyInfo, v.keyName, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	var response VaultResponse[keyResponse]
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("%s: decoder.Decode: %w", op, err)
+	}
+
+	return response.Data.LatestVersion, nil
+}
+
+// payloads above this size are streamed through a pipe instead of being assembled in memory
+const pipeBodyThreshold = 4 << 10
+
+// buffers that grew past this are dropped instead of being kept alive by the pool
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
 }
 
-func (v *Vault) makeRequest(action vaultAction, body *bytes.Reader) (*http.Response, error) {
+// newVaultRequestBody builds { "field":"value" } with value optionally base64 encoded.
+// release must only be called after the response body is closed,
+// since the transport may still be reading the request body until then.
+func newVaultRequestBody(field string, value []byte, encode bool) (body io.Reader, release func()) {
+	if len(value) > pipeBodyThreshold {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeVaultRequestBody(pw, field, value, encode))
+		}()
+		// the transport closes the reader which stops the writer on early errors
+		return pr, func() {}
+	}
+
+	buf := getBuffer()
+	writeVaultRequestBody(buf, field, value, encode)
+	return bytes.NewReader(buf.Bytes()), func() { putBuffer(buf) }
+}
+
+func writeVaultRequestBody(w io.Writer, field string, value []byte, encode bool) error {
+	if _, err := io.WriteString(w, `{ "`+field+`":"`); err != nil {
+		return err
+	}
+
+	if encode {
+		encoder := base64.NewEncoder(base64.StdEncoding, w)
+		if _, err := encoder.Write(value); err != nil {
+			return err
+		}
+
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+	} else if _, err := w.Write(value); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, `" }`)
+	return err
+}
+
+func (v *Vault) makeRequest(method string, action vaultAction, keyName string, body io.Reader) (*http.Response, error) {
 	const op = "encryption.Vault.makeRequest"
 
 	r, err := http.NewRequest(
-		"POST",
-		fmt.Sprintf("%s/v1/%s/%s/%s", v.vaultAddress, v.keyStorage, action, v.keyName),
+		method,
+		fmt.Sprintf("%s/v1/%s/%s/%s", v.vaultAddress, v.keyStorage, action, keyName),
 		body,
 	)
 	if err != nil {
@@ -178,7 +404,11 @@ func (v *Vault) makeRequest(action vaultAction, body *bytes.Reader) (*http.Respo
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		buf := bytes.NewBuffer(make([]byte, 0))
+		defer resp.Body.Close()
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
 		buf.ReadFrom(resp.Body)
 		return nil, fmt.Errorf("%s: unexpected response code from vault: %d; body: %s", op, resp.StatusCode, buf.String())
 	}
@@ -0,0 +1,168 @@
+// Package events publishes storage events to systems outside the server: webhooks and NATS subjects.
+//
+// Events come from the change feed of the db, which records every change to files in the transaction making it,
+// so no event is lost to a crash between the change and its publishing. Each publisher has a cursor of its own
+// on the feed and only moves it on once a batch is published: events are delivered at least once and in order,
+// and consumers tell repeated ones apart by their seq.
+package events
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// events are published in batches of at most this many
+const batchSize = 100
+
+type Type string
+
+const (
+	FileCreated Type = "file.created"
+	FileUpdated Type = "file.updated"
+	FileDeleted Type = "file.deleted"
+)
+
+var eventTypes = map[db_access.ChangeKind]Type{
+	db_access.ChangeCreated: FileCreated,
+	db_access.ChangeUpdated: FileUpdated,
+	db_access.ChangeDeleted: FileDeleted,
+}
+
+type Event struct {
+	// Seq orders events and is never reused
+	Seq     int64     `json:"seq"`
+	Type    Type      `json:"type"`
+	FileId  string    `json:"file_id"`
+	OwnerId int64     `json:"owner_id"`
+	Size    int64     `json:"size"`
+	At      time.Time `json:"at"`
+}
+
+type Publisher interface {
+	// Publish fails unless all of the events were accepted; they are published again then
+	Publish(ctx context.Context, events []Event) error
+}
+
+// Dispatcher hands the events of the feed to a publisher
+type Dispatcher struct {
+	db        db_access.ChangeRepo
+	publisher Publisher
+	consumer  string
+	log       *slog.Logger
+}
+
+// NewDispatcher names the cursor of the publisher after name, which must not change as long as it is published to
+func NewDispatcher(db db_access.ChangeRepo, name string, publisher Publisher, log *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		db:        db,
+		publisher: publisher,
+		consumer:  "events:" + name,
+		log:       log.With(slog.String("component", "events"), slog.String("publisher", name)),
+	}
+}
+
+// Consumer is the name of the cursor of the dispatcher on the change feed
+func (d *Dispatcher) Consumer() string {
+	return d.consumer
+}
+
+// Register puts the cursor of the dispatcher on the feed, so that events are recorded for it from now on
+func (d *Dispatcher) Register() error {
+	const op = "events.Dispatcher.Register"
+
+	_, err := d.db.GetChangeCursor(d.consumer)
+	if err == nil {
+		return nil
+	} else if !errors.As(err, &db_access.NoRowsError{}) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := d.db.SetChangeCursor(d.consumer, 0); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Run publishes new events every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	if err := d.Register(); err != nil {
+		d.log.Error("Could not register with the change feed", slogext.Error(err))
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Dispatch(ctx); err != nil {
+				d.log.Error("Could not publish events", slogext.Error(err))
+			}
+		}
+	}
+}
+
+// Dispatch publishes the events after the cursor and moves it past those that were published
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	const op = "events.Dispatcher.Dispatch"
+
+	cursor, err := d.db.GetChangeCursor(d.consumer)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var published int
+	for {
+		changes, err := d.db.GetChanges(cursor, batchSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(changes) == 0 {
+			break
+		}
+
+		events := make([]Event, 0, len(changes))
+		for _, change := range changes {
+			events = append(events, Event{
+				Seq:     change.Seq,
+				Type:    eventTypes[change.Kind],
+				FileId:  change.GeneratedName,
+				OwnerId: change.OwnerId,
+				Size:    change.Size,
+				At:      time.Time(change.At).UTC(),
+			})
+		}
+
+		if err := d.publisher.Publish(ctx, events); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		cursor = changes[len(changes)-1].Seq
+		if err := d.db.SetChangeCursor(d.consumer, cursor); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		published += len(events)
+
+		if len(changes) < batchSize {
+			break
+		}
+	}
+
+	if published > 0 {
+		d.log.Debug("Published events", slog.Int("events", published))
+		if err := d.db.RemoveReadChanges(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeout of connecting to NATS and of publishing a batch when ctx has no deadline
+const natsTimeout = 10 * time.Second
+
+type NATSError struct {
+	Message string
+}
+
+func (err NATSError) Error() string {
+	return fmt.Sprintf("nats: %s", err.Message)
+}
+
+// NATS publishes each event as a message to Subject.<event type>, such as storage.file.created,
+// over the core NATS protocol. A batch counts as published once the server answers a PING sent after it.
+type NATS struct {
+	// URL is nats://host:port or tls://host:port, with the user and password or the token as user info
+	URL     string
+	Subject string
+	Schema  Schema
+	// Source goes into CloudEvents messages
+	Source string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func NewNATS(rawURL string, subject string, schema Schema, source string) (*NATS, error) {
+	const op = "events.NewNATS"
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+		return nil, fmt.Errorf("%s: url must be nats://host:port or tls://host:port", op)
+	}
+
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("%s: invalid subject %q", op, subject)
+	}
+
+	return &NATS{URL: rawURL, Subject: subject, Schema: schema, Source: source}, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, events []Event) error {
+	const op = "events.NATS.Publish"
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.publish(ctx, events); err != nil {
+		// whatever the connection was in the middle of, the next batch starts on a new one
+		n.close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *NATS) publish(ctx context.Context, events []Event) error {
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsTimeout)
+	}
+	if err := n.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(n.conn)
+	for _, e := range events {
+		msg, err := n.Schema.Encode(e, n.Source)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "PUB %s.%s %d\r\n", n.Subject, e.Type, len(msg))
+		w.Write(msg)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("PING\r\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return n.awaitPong()
+}
+
+func (n *NATS) connect(ctx context.Context) error {
+	u, err := url.Parse(n.URL)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: natsTimeout}
+	var conn net.Conn
+	if u.Scheme == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", u.Host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", u.Host)
+	}
+	if err != nil {
+		return err
+	}
+	n.conn = conn
+	n.r = bufio.NewReader(conn)
+
+	if err := conn.SetDeadline(time.Now().Add(natsTimeout)); err != nil {
+		return err
+	}
+
+	// the server introduces itself first
+	line, err := n.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return NATSError{Message: "expected INFO, got " + strings.TrimSpace(line)}
+	}
+
+	opts := map[string]any{"verbose": false, "pedantic": false, "name": "cloud-storage", "lang": "go", "protocol": 0}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"] = u.User.Username()
+			opts["pass"] = pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return err
+	}
+
+	return n.awaitPong()
+}
+
+// awaitPong reads until the answer to the last PING, failing on errors the server sends meanwhile
+func (n *NATS) awaitPong() error {
+	for {
+		line, err := n.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return NATSError{Message: strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")}
+		case line == "+OK", strings.HasPrefix(line, "INFO "):
+		default:
+			return NATSError{Message: "unexpected " + line}
+		}
+	}
+}
+
+// Close drops the connection; the next Publish connects again
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.close()
+}
+
+func (n *NATS) close() error {
+	if n.conn == nil {
+		return nil
+	}
+
+	err := n.conn.Close()
+	n.conn = nil
+	n.r = nil
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Schema is how a single event is encoded in a message. The zero value behaves as NativeSchema.
+type Schema string
+
+const (
+	// NativeSchema is Event as json
+	NativeSchema Schema = "native"
+	// CloudEventsSchema is a CloudEvents 1.0 event in structured json mode, with the type prefixed
+	// by the source and the file id as the subject
+	CloudEventsSchema Schema = "cloudevents"
+)
+
+func (s *Schema) UnmarshalText(text []byte) error {
+	switch v := Schema(text); v {
+	case NativeSchema, CloudEventsSchema:
+		*s = v
+		return nil
+	case "":
+		*s = NativeSchema
+		return nil
+	}
+
+	return fmt.Errorf("unknown event schema %q; expected one of native, cloudevents", text)
+}
+
+type cloudEvent struct {
+	SpecVersion     string        `json:"specversion"`
+	Id              string        `json:"id"`
+	Source          string        `json:"source"`
+	Type            string        `json:"type"`
+	Subject         string        `json:"subject"`
+	Time            time.Time     `json:"time"`
+	DataContentType string        `json:"datacontenttype"`
+	Data            cloudFileData `json:"data"`
+}
+
+type cloudFileData struct {
+	OwnerId int64 `json:"owner_id"`
+	Size    int64 `json:"size"`
+}
+
+// Encode returns the message of the event; source names the server, such as its public url
+func (s Schema) Encode(e Event, source string) ([]byte, error) {
+	if s != CloudEventsSchema {
+		return json.Marshal(e)
+	}
+
+	return json.Marshal(cloudEvent{
+		SpecVersion:     "1.0",
+		Id:              strconv.FormatInt(e.Seq, 10),
+		Source:          source,
+		Type:            source + "." + string(e.Type),
+		Subject:         e.FileId,
+		Time:            e.At,
+		DataContentType: "application/json",
+		Data:            cloudFileData{OwnerId: e.OwnerId, Size: e.Size},
+	})
+}
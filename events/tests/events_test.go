@@ -0,0 +1,108 @@
+package events_test
+
+import (
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/events"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const consumer = "events:hook"
+
+type publisherFunc func(ctx context.Context, events []events.Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, events []events.Event) error {
+	return f(ctx, events)
+}
+
+func TestDispatch(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+
+	var published []events.Event
+	d := events.NewDispatcher(db, "hook", publisherFunc(func(_ context.Context, batch []events.Event) error {
+		published = append(published, batch...)
+		return nil
+	}), slogext.NewDiscardLogger())
+	assert.Equal(t, consumer, d.Consumer())
+
+	at := time.Unix(1700000000, 0)
+	db.EXPECT().GetChangeCursor(consumer).Return(4, nil).Once()
+	db.EXPECT().GetChanges(int64(4), mock.Anything).Return([]db_access.Change{
+		{Seq: 5, Kind: db_access.ChangeCreated, GeneratedName: "a", OwnerId: 1, Size: 10, At: db_access.Time(at)},
+		{Seq: 7, Kind: db_access.ChangeDeleted, GeneratedName: "b", OwnerId: 2, Size: 3, At: db_access.Time(at)},
+	}, nil).Once()
+	db.EXPECT().SetChangeCursor(consumer, int64(7)).Return(nil).Once()
+	db.EXPECT().RemoveReadChanges().Return(nil).Once()
+
+	require.NoError(t, d.Dispatch(context.Background()))
+	assert.Equal(t, []events.Event{
+		{Seq: 5, Type: events.FileCreated, FileId: "a", OwnerId: 1, Size: 10, At: at.UTC()},
+		{Seq: 7, Type: events.FileDeleted, FileId: "b", OwnerId: 2, Size: 3, At: at.UTC()},
+	}, published)
+}
+
+func TestDispatch_PublishFails(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	d := events.NewDispatcher(db, "hook", publisherFunc(func(context.Context, []events.Event) error {
+		return errors.New("unreachable")
+	}), slogext.NewDiscardLogger())
+
+	// the cursor stays where it was, so the events are published again next time
+	db.EXPECT().GetChangeCursor(consumer).Return(4, nil).Once()
+	db.EXPECT().GetChanges(int64(4), mock.Anything).Return([]db_access.Change{
+		{Seq: 5, Kind: db_access.ChangeUpdated, GeneratedName: "a"},
+	}, nil).Once()
+
+	assert.Error(t, d.Dispatch(context.Background()))
+}
+
+func TestRegister(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	d := events.NewDispatcher(db, "hook", nil, slogext.NewDiscardLogger())
+
+	db.EXPECT().GetChangeCursor(consumer).Return(0, db_access.NoRowsError{}).Once()
+	db.EXPECT().SetChangeCursor(consumer, int64(0)).Return(nil).Once()
+	require.NoError(t, d.Register())
+
+	db.EXPECT().GetChangeCursor(consumer).Return(3, nil).Once()
+	require.NoError(t, d.Register())
+}
+
+func TestWebhook(t *testing.T) {
+	var body []byte
+	var signature string
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(events.SignatureHeader)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	hook := events.Webhook{URL: srv.URL, Secret: "secret", Client: srv.Client()}
+	batch := []events.Event{{Seq: 1, Type: events.FileCreated, FileId: "a"}}
+	require.NoError(t, hook.Publish(context.Background(), batch))
+
+	var delivery events.Delivery
+	require.NoError(t, json.Unmarshal(body, &delivery))
+	assert.Equal(t, batch[0].FileId, delivery.Events[0].FileId)
+	assert.Equal(t, events.Sign("secret", body), signature)
+
+	status = http.StatusInternalServerError
+	err := hook.Publish(context.Background(), batch)
+	var we events.WebhookError
+	require.ErrorAs(t, err, &we)
+	assert.Equal(t, http.StatusInternalServerError, we.Status)
+}
@@ -0,0 +1,144 @@
+package events_test
+
+import (
+	"bufio"
+	"cloud-storage/events"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type natsMessage struct {
+	Subject string
+	Payload []byte
+}
+
+// serveNATS accepts a single connection and answers its PINGs, failing every publish to reject
+func serveNATS(t *testing.T, reject string) (string, <-chan natsMessage, <-chan map[string]any) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan natsMessage, 16)
+	connects := make(chan map[string]any, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				var opts map[string]any
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &opts)
+				connects <- opts
+			case line == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case strings.HasPrefix(line, "PUB "):
+				var subject string
+				var n int
+				fmt.Sscanf(line, "PUB %s %d", &subject, &n)
+				payload := make([]byte, n+2)
+				io.ReadFull(r, payload)
+				if subject == reject {
+					fmt.Fprint(conn, "-ERR 'Permissions Violation'\r\n")
+					continue
+				}
+				messages <- natsMessage{Subject: subject, Payload: payload[:n]}
+			}
+		}
+	}()
+
+	return ln.Addr().String(), messages, connects
+}
+
+func TestNATS(t *testing.T) {
+	addr, messages, connects := serveNATS(t, "")
+
+	nats, err := events.NewNATS("nats://user:pass@"+addr, "storage", events.NativeSchema, "")
+	require.NoError(t, err)
+	defer nats.Close()
+
+	at := time.Unix(1700000000, 0).UTC()
+	batch := []events.Event{
+		{Seq: 1, Type: events.FileCreated, FileId: "a", OwnerId: 1, Size: 4, At: at},
+		{Seq: 2, Type: events.FileDeleted, FileId: "a", OwnerId: 1, Size: 4, At: at},
+	}
+	require.NoError(t, nats.Publish(context.Background(), batch))
+
+	opts := <-connects
+	assert.Equal(t, "user", opts["user"])
+	assert.Equal(t, "pass", opts["pass"])
+
+	for _, expected := range batch {
+		msg := <-messages
+		assert.Equal(t, "storage."+string(expected.Type), msg.Subject)
+
+		var e events.Event
+		require.NoError(t, json.Unmarshal(msg.Payload, &e))
+		assert.Equal(t, expected, e)
+	}
+}
+
+func TestNATS_Rejected(t *testing.T) {
+	addr, _, _ := serveNATS(t, "storage.file.created")
+
+	nats, err := events.NewNATS("nats://"+addr, "storage", events.NativeSchema, "")
+	require.NoError(t, err)
+	defer nats.Close()
+
+	err = nats.Publish(context.Background(), []events.Event{{Seq: 1, Type: events.FileCreated}})
+	var ne events.NATSError
+	require.ErrorAs(t, err, &ne)
+	assert.Equal(t, "Permissions Violation", ne.Message)
+}
+
+func TestNewNATS_Invalid(t *testing.T) {
+	for _, tc := range []struct{ url, subject string }{
+		{"http://localhost:4222", "storage"},
+		{"nats://", "storage"},
+		{"nats://localhost:4222", ""},
+		{"nats://localhost:4222", "storage.>"},
+	} {
+		_, err := events.NewNATS(tc.url, tc.subject, events.NativeSchema, "")
+		assert.Error(t, err, tc)
+	}
+}
+
+func TestSchema_CloudEvents(t *testing.T) {
+	at := time.Unix(1700000000, 0).UTC()
+	msg, err := events.CloudEventsSchema.Encode(events.Event{Seq: 7, Type: events.FileCreated, FileId: "a", OwnerId: 1, Size: 4, At: at}, "storage")
+	require.NoError(t, err)
+
+	var ce map[string]any
+	require.NoError(t, json.Unmarshal(msg, &ce))
+	assert.Equal(t, "1.0", ce["specversion"])
+	assert.Equal(t, "7", ce["id"])
+	assert.Equal(t, "storage", ce["source"])
+	assert.Equal(t, "storage.file.created", ce["type"])
+	assert.Equal(t, "a", ce["subject"])
+	assert.Equal(t, map[string]any{"owner_id": float64(1), "size": float64(4)}, ce["data"])
+
+	var s events.Schema
+	assert.Error(t, s.UnmarshalText([]byte("avro")))
+	require.NoError(t, s.UnmarshalText(nil))
+	assert.Equal(t, events.NativeSchema, s)
+}
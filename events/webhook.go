@@ -0,0 +1,76 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SignatureHeader carries the hex hmac-sha256 of the body under the secret of the webhook, prefixed with "sha256="
+const SignatureHeader = "X-Signature-256"
+
+// Delivery is the body POSTed to webhooks
+type Delivery struct {
+	Events []Event `json:"events"`
+}
+
+type WebhookError struct {
+	Status int
+}
+
+func (err WebhookError) Error() string {
+	return fmt.Sprintf("webhook answered with status %d", err.Status)
+}
+
+// Webhook POSTs events as a Delivery to URL; any 2xx answer accepts all of them
+type Webhook struct {
+	URL string
+	// Secret signs deliveries when set, see SignatureHeader
+	Secret string
+	Client *http.Client
+}
+
+func (h Webhook) Publish(ctx context.Context, events []Event) error {
+	const op = "events.Webhook.Publish"
+
+	body, err := json.Marshal(Delivery{Events: events})
+	if err != nil {
+		return fmt.Errorf("%s: json.Marshal: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: http.NewRequest: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	// lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %w", op, WebhookError{Status: resp.StatusCode})
+	}
+
+	return nil
+}
+
+// Sign returns the value of SignatureHeader for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
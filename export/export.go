@@ -0,0 +1,489 @@
+package export
+
+import (
+	"archive/zip"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/jobs"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// exports are kept next to the blobs but out of the way of generated names
+const exportDirName = ".exports"
+
+const linkKeySize = 32
+
+// the key of download links is kept with the exports, so that their links outlive restarts as they do
+const linkKeyFileName = "link.key"
+
+type Config struct {
+	// archives are built in the storage dir of Blobs
+	Blobs          *blobstore.Store
+	LinkTimeToLive time.Duration
+	// Jobs builds the archives
+	Jobs *jobs.Pool
+}
+
+type Exporter struct {
+	db        db_access.DbAccess
+	c         encryption.Crypter
+	blobs     *blobstore.Store
+	exportDir string
+	linkTTL   time.Duration
+	linkKey   []byte
+	jobs      *jobs.Pool
+	log       *slog.Logger
+}
+
+type InvalidLinkError struct {
+	Reason string
+}
+
+func (err InvalidLinkError) Error() string {
+	return fmt.Sprintf("invalid export link: %s", err.Reason)
+}
+
+type ManifestEntry struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type Manifest struct {
+	ExportId  string          `json:"export_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+func New(db db_access.DbAccess, c encryption.Crypter, cfg Config, log *slog.Logger) (*Exporter, error) {
+	const op = "export.New"
+
+	exportDir := filepath.Join(cfg.Blobs.Dir(), exportDirName)
+	if err := os.MkdirAll(exportDir, 0o700); err != nil {
+		return nil, fmt.Errorf("%s: os.MkdirAll: %w", op, err)
+	}
+
+	key, err := loadLinkKey(filepath.Join(exportDir, linkKeyFileName))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Exporter{
+		db:        db,
+		c:         c,
+		blobs:     cfg.Blobs,
+		exportDir: exportDir,
+		linkTTL:   cfg.LinkTimeToLive,
+		linkKey:   key,
+		jobs:      cfg.Jobs,
+		log:       log.With(slog.String("component", "export")),
+	}, nil
+}
+
+// loadLinkKey reads the key at path, generating it on first use
+func loadLinkKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key = make([]byte, linkKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("rand.Read: %w", err)
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("os.OpenFile: %w", err)
+		}
+		defer file.Close()
+
+		if _, err := file.Write(key); err != nil {
+			return nil, fmt.Errorf("write link key: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			return nil, fmt.Errorf("file.Sync: %w", err)
+		}
+		return key, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	if len(key) != linkKeySize {
+		return nil, fmt.Errorf("link key is %d bytes instead of %d", len(key), linkKeySize)
+	}
+	return key, nil
+}
+
+// Recover fails the exports a restart interrupted, so that they expire and get cleaned up like
+// other failed ones; it must run before any export is started
+func (e *Exporter) Recover() {
+	expiresAt := db_access.Time(time.Now().Add(e.linkTTL))
+	if err := e.db.FailUnfinishedExports("Interrupted by server restart", expiresAt); err != nil {
+		e.log.Error("Could not fail unfinished exports", slogext.Error(err))
+	}
+}
+
+// Start registers a new export for the user and builds it in the background. The job of the export
+// has the same id and counts files as its units of work.
+func (e *Exporter) Start(ownerId int64) (db_access.Export, error) {
+	const op = "export.Exporter.Start"
+
+	export := db_access.Export{
+		Id:           uuid.New().String(),
+		OwnerId:      ownerId,
+		Status:       db_access.ExportPending,
+		CreationTime: db_access.Time(time.Now()),
+	}
+
+	if err := e.db.AddExport(&export); err != nil {
+		return db_access.Export{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := e.jobs.Submit(
+		db_access.Job{Id: export.Id, Type: jobs.TypeExport, OwnerId: ownerId},
+		func(_ context.Context, r *jobs.Reporter) (any, error) {
+			return e.build(export, r)
+		},
+	)
+	if err != nil {
+		export.Status = db_access.ExportFailed
+		export.Error = "Could not queue export"
+		export.ExpiresAt = db_access.Time(time.Now().Add(e.linkTTL))
+		if err := e.db.UpdateExport(&export); err != nil {
+			e.log.Error("Could not update export status", slogext.Error(err), slog.String("export-id", export.Id))
+		}
+		return db_access.Export{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return export, nil
+}
+
+// ExportResult is what the job of an export results in
+type ExportResult struct {
+	// Download is the link of the archive, see Link
+	Download string `json:"download"`
+}
+
+func (e *Exporter) build(export db_access.Export, r *jobs.Reporter) (ExportResult, error) {
+	log := e.log.With(slog.String("export-id", export.Id), slog.Int64("user-id", export.OwnerId))
+
+	export.Status = db_access.ExportRunning
+	if err := e.db.UpdateExport(&export); err != nil {
+		return ExportResult{}, fmt.Errorf("update export status: %w", err)
+	}
+
+	err := e.writeArchive(export, r)
+	if err != nil {
+		log.Error("Could not build export", slogext.Error(err))
+
+		if err := os.Remove(e.archivePath(export.Id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error("Could not remove incomplete export", slogext.Error(err))
+		}
+
+		export.Status = db_access.ExportFailed
+		export.Error = "Could not build export"
+	} else {
+		export.Status = db_access.ExportReady
+	}
+
+	// failed exports expire as well so that they get cleaned up
+	export.ExpiresAt = db_access.Time(time.Now().Add(e.linkTTL))
+	if err := e.db.UpdateExport(&export); err != nil {
+		return ExportResult{}, fmt.Errorf("update export status: %w", err)
+	}
+
+	log.Info("Export finished", slog.String("status", string(export.Status)))
+	e.notify(log, export)
+
+	if export.Status == db_access.ExportFailed {
+		return ExportResult{}, jobs.Failure(export.Error)
+	}
+	return ExportResult{Download: e.Link(export)}, nil
+}
+
+// notify tells the owner that the export is ready to download, or that it failed
+func (e *Exporter) notify(log *slog.Logger, export db_access.Export) {
+	kind := db_access.NotificationExportReady
+	if export.Status == db_access.ExportFailed {
+		kind = db_access.NotificationExportFailed
+	}
+
+	err := e.db.AddNotification(&db_access.Notification{
+		OwnerId:      export.OwnerId,
+		Kind:         kind,
+		FileId:       export.Id,
+		At:           export.ExpiresAt,
+		CreationTime: db_access.Time(time.Now()),
+	})
+	if err != nil {
+		log.Error("Could not add notification", slogext.Error(err))
+	}
+}
+
+func (e *Exporter) archivePath(id string) string {
+	return filepath.Join(e.exportDir, id)
+}
+
+// writeArchive streams a zip of every user file through the crypter into the export dir
+func (e *Exporter) writeArchive(export db_access.Export, r *jobs.Reporter) error {
+	const op = "export.Exporter.writeArchive"
+
+	files, err := e.db.GetUserFiles(export.OwnerId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	r.SetTotal(int64(len(files)))
+
+	out, err := os.OpenFile(e.archivePath(export.Id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: os.OpenFile: %w", op, err)
+	}
+	defer out.Close()
+
+	pr, pw := io.Pipe()
+	zipErr := make(chan error, 1)
+	go func() {
+		err := e.zipFiles(pw, export, files, r)
+		pw.CloseWithError(err)
+		zipErr <- err
+	}()
+
+	err = e.c.EncryptAndCopyFor(export.OwnerId, out, pr)
+	// unblocks the zip writer if encryption stopped reading early
+	pr.CloseWithError(errors.New("archive encryption stopped"))
+	if err := <-zipErr; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("%s: out.Sync: %w", op, err)
+	}
+
+	return nil
+}
+
+func (e *Exporter) zipFiles(w io.Writer, export db_access.Export, files []db_access.File, r *jobs.Reporter) error {
+	archive := zip.NewWriter(w)
+
+	manifest := Manifest{
+		ExportId:  export.Id,
+		CreatedAt: time.Time(export.CreationTime).UTC(),
+		Files:     make([]ManifestEntry, 0, len(files)),
+	}
+
+	used := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		name, err := e.c.DecryptFileName(file.FileName)
+		if err != nil {
+			return fmt.Errorf("decrypt name of %s: %w", file.GeneratedName, err)
+		}
+
+		entryPath := uniqueEntryPath(used, name)
+		entry, err := archive.Create(entryPath)
+		if err != nil {
+			return fmt.Errorf("archive.Create: %w", err)
+		}
+
+		size, err := e.copyBlob(entry, file)
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", file.GeneratedName, err)
+		}
+
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Id:   file.GeneratedName,
+			Name: name,
+			Path: entryPath,
+			Size: size,
+		})
+		r.Add(1)
+	}
+
+	entry, err := archive.Create("manifest.json")
+	if err != nil {
+		return fmt.Errorf("archive.Create: %w", err)
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("archive.Close: %w", err)
+	}
+
+	return nil
+}
+
+func (e *Exporter) copyBlob(w io.Writer, file db_access.File) (int64, error) {
+	blob, err := e.blobs.Open(context.Background(), file.Backend, file.BlobName)
+	if err != nil {
+		return 0, err
+	}
+	defer blob.Close()
+
+	cw := &countingWriter{w: w}
+	if err := e.c.DecryptAndCopy(cw, blob); err != nil {
+		return 0, err
+	}
+
+	return cw.n, nil
+}
+
+// uniqueEntryPath keeps files with the same name from overwriting each other inside the archive
+func uniqueEntryPath(used map[string]struct{}, name string) string {
+	name = path.Base(filepath.ToSlash(name))
+	candidate := path.Join("files", name)
+
+	ext := path.Ext(name)
+	base := name[:len(name)-len(ext)]
+	for i := 1; ; i++ {
+		if _, ok := used[candidate]; !ok {
+			break
+		}
+		candidate = path.Join("files", fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+
+	used[candidate] = struct{}{}
+	return candidate
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// Link returns a download path for a ready export that does not need an Authorization header.
+func (e *Exporter) Link(export db_access.Export) string {
+	expires := strconv.FormatInt(time.Time(export.ExpiresAt).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", e.sign(export.Id, expires))
+
+	return fmt.Sprintf("/api/export/%s/download?%s", export.Id, query.Encode())
+}
+
+func (e *Exporter) sign(id string, expires string) string {
+	mac := hmac.New(sha256.New, e.linkKey)
+	mac.Write([]byte(id))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyLink checks the signature and expiry of a link produced by Link.
+func (e *Exporter) VerifyLink(id string, expires string, signature string) error {
+	expected, err := hex.DecodeString(e.sign(id, expires))
+	if err != nil {
+		return err
+	}
+
+	actual, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, actual) {
+		return InvalidLinkError{Reason: "bad signature"}
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return InvalidLinkError{Reason: "bad expiry"}
+	}
+
+	if time.Now().After(time.Unix(unix, 0)) {
+		return InvalidLinkError{Reason: "link expired"}
+	}
+
+	return nil
+}
+
+// WriteArchive decrypts a ready export into w.
+func (e *Exporter) WriteArchive(w io.Writer, id string) error {
+	const op = "export.Exporter.WriteArchive"
+
+	file, err := os.Open(e.archivePath(id))
+	if err != nil {
+		return fmt.Errorf("%s: os.Open: %w", op, err)
+	}
+	defer file.Close()
+
+	if err := e.c.DecryptAndCopy(w, file); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveExpired removes the archives and rows of exports expired as of now
+func (e *Exporter) RemoveExpired(now time.Time) {
+	exports, err := e.db.GetExpiredExports(db_access.Time(now))
+	if err != nil {
+		e.log.Error("Could not get expired exports", slogext.Error(err))
+		return
+	}
+
+	for _, export := range exports {
+		err := os.Remove(e.archivePath(export.Id))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			e.log.Error("Could not remove expired export", slogext.Error(err), slog.String("export-id", export.Id))
+			continue
+		}
+
+		if err := e.db.RemoveExport(export.Id); err != nil {
+			e.log.Error("Could not remove expired export from db", slogext.Error(err), slog.String("export-id", export.Id))
+			continue
+		}
+
+		e.log.Info("Removed expired export", slog.String("export-id", export.Id))
+	}
+}
+
+// RemoveUserExports removes the archives and rows of every export of the owner, so that links to them stop working
+func (e *Exporter) RemoveUserExports(ownerId int64) error {
+	const op = "export.Exporter.RemoveUserExports"
+
+	exports, err := e.db.GetUserExports(ownerId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, export := range exports {
+		err := os.Remove(e.archivePath(export.Id))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: os.Remove: %w", op, err)
+		}
+
+		if err := e.db.RemoveExport(export.Id); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package export_test
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/export"
+	"cloud-storage/jobs"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newExporter(t *testing.T, db *db_access_mocks.DbAccess, c *encryption_mocks.Crypter, dir string, pool *jobs.Pool) *export.Exporter {
+	e, err := export.New(db, c, export.Config{
+		Blobs:          blobstore.NewStore(dir, storage.DurabilityNone, nil),
+		LinkTimeToLive: time.Hour,
+		Jobs:           pool,
+	}, slogext.NewDiscardLogger())
+	require.NoError(t, err)
+	return e
+}
+
+func TestExporter_LinkSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ready := db_access.Export{Id: "a", Status: db_access.ExportReady, ExpiresAt: db_access.Time(time.Now().Add(time.Hour))}
+
+	link, err := url.Parse(newExporter(t, db_access_mocks.NewDbAccess(t), encryption_mocks.NewCrypter(t), dir, nil).Link(ready))
+	require.NoError(t, err)
+
+	restarted := newExporter(t, db_access_mocks.NewDbAccess(t), encryption_mocks.NewCrypter(t), dir, nil)
+	query := link.Query()
+	require.NoError(t, restarted.VerifyLink("a", query.Get("expires"), query.Get("signature")))
+
+	// another storage dir has a key of its own
+	other := newExporter(t, db_access_mocks.NewDbAccess(t), encryption_mocks.NewCrypter(t), t.TempDir(), nil)
+	assert.ErrorAs(t, other.VerifyLink("a", query.Get("expires"), query.Get("signature")), &export.InvalidLinkError{})
+}
+
+func TestExporter_Recover(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().FailUnfinishedExports("Interrupted by server restart", mock.MatchedBy(func(expiresAt db_access.Time) bool {
+		return time.Time(expiresAt).After(time.Now())
+	})).Return(nil).Once()
+
+	newExporter(t, db, encryption_mocks.NewCrypter(t), t.TempDir(), nil).Recover()
+}
+
+func TestExporter_RemoveUserExports(t *testing.T) {
+	dir := t.TempDir()
+	db := db_access_mocks.NewDbAccess(t)
+	e := newExporter(t, db, encryption_mocks.NewCrypter(t), dir, nil)
+
+	archive := filepath.Join(dir, ".exports", "ready")
+	require.NoError(t, os.WriteFile(archive, []byte("archive"), 0o600))
+
+	// a pending export has no archive yet
+	db.EXPECT().GetUserExports(int64(1)).Return([]db_access.Export{{Id: "ready"}, {Id: "pending"}}, nil).Once()
+	db.EXPECT().RemoveExport("ready").Return(nil).Once()
+	db.EXPECT().RemoveExport("pending").Return(nil).Once()
+
+	require.NoError(t, e.RemoveUserExports(1))
+	_, err := os.Stat(archive)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestExporter_NotifiesWhenReady(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	db.EXPECT().FailUnfinishedJobs(mock.Anything).Return(nil).Once()
+	db.EXPECT().AddExport(mock.Anything).Return(nil).Once()
+	db.EXPECT().AddJob(mock.Anything).Return(nil).Once()
+	done := make(chan struct{})
+	db.EXPECT().UpdateJob(mock.Anything).RunAndReturn(func(job *db_access.Job) error {
+		if job.Status == db_access.JobFinished {
+			close(done)
+		}
+		return nil
+	})
+	db.EXPECT().UpdateExport(mock.Anything).Return(nil)
+	db.EXPECT().GetUserFiles(int64(1)).Return(nil, nil).Once()
+	c.EXPECT().EncryptAndCopyFor(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ int64, w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Once()
+
+	notified := make(chan db_access.Notification, 1)
+	db.EXPECT().AddNotification(mock.Anything).RunAndReturn(func(n *db_access.Notification) error {
+		notified <- *n
+		return nil
+	}).Once()
+
+	pool := jobs.New(db, jobs.Config{}, slogext.NewDiscardLogger())
+	go pool.Run(ctx)
+
+	started, err := newExporter(t, db, c, t.TempDir(), pool).Start(1)
+	require.NoError(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("export did not finish")
+	}
+
+	n := <-notified
+	assert.Equal(t, db_access.NotificationExportReady, n.Kind)
+	assert.Equal(t, int64(1), n.OwnerId)
+	assert.Equal(t, started.Id, n.FileId)
+	assert.Empty(t, n.FileName)
+	assert.True(t, time.Time(n.At).After(time.Now()))
+}
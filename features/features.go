@@ -0,0 +1,130 @@
+// Package features turns risky functionality on and off per deployment. Flags live in a json file
+// mapping flag names to booleans; flags missing from it keep their defaults.
+package features
+
+import (
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type UnknownFlagError struct {
+	Name string
+}
+
+func (err UnknownFlagError) Error() string {
+	return fmt.Sprintf("unknown feature flag %q", err.Name)
+}
+
+type Flags struct {
+	path     string
+	defaults map[string]bool
+	log      *slog.Logger
+
+	enabled atomic.Pointer[map[string]bool]
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// New reads the flags from path; with an empty path every flag keeps its default.
+// Only the names in defaults are known, so that a typo in the file doesn't go unnoticed.
+func New(path string, defaults map[string]bool, log *slog.Logger) (*Flags, error) {
+	const op = "features.New"
+
+	f := &Flags{
+		path:     path,
+		defaults: defaults,
+		log:      log.With(slog.String("component", "features")),
+	}
+	enabled := maps.Clone(defaults)
+	f.enabled.Store(&enabled)
+
+	if path == "" {
+		return f, nil
+	}
+
+	if _, err := f.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return f, nil
+}
+
+// Enabled reports whether the flag is on; unknown flags are off
+func (f *Flags) Enabled(name string) bool {
+	return (*f.enabled.Load())[name]
+}
+
+// All returns the state of every known flag
+func (f *Flags) All() map[string]bool {
+	return maps.Clone(*f.enabled.Load())
+}
+
+// Reload rereads the file if it changed since the last read and reports whether it did.
+// A file that fails to parse leaves the flags as they were.
+func (f *Flags) Reload() (bool, error) {
+	const op = "features.Flags.Reload"
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("%s: os.Stat: %w", op, err)
+	}
+	if info.ModTime().Equal(f.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("%s: os.ReadFile: %w", op, err)
+	}
+
+	var values map[string]bool
+	if err := json.Unmarshal(data, &values); err != nil {
+		return false, fmt.Errorf("%s: json.Unmarshal: %w", op, err)
+	}
+
+	enabled := maps.Clone(f.defaults)
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if _, ok := f.defaults[name]; !ok {
+			return false, fmt.Errorf("%s: %w", op, UnknownFlagError{Name: name})
+		}
+		enabled[name] = values[name]
+	}
+
+	f.enabled.Store(&enabled)
+	f.modTime = info.ModTime()
+
+	return true, nil
+}
+
+// Watch reloads the file every interval until ctx is done
+func (f *Flags) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := f.Reload()
+			if err != nil {
+				f.log.Error("Could not reload feature flags", slogext.Error(err))
+			} else if changed {
+				f.log.Info("Reloaded feature flags", slog.Any("flags", f.All()))
+			}
+		}
+	}
+}
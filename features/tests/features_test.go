@@ -0,0 +1,67 @@
+package features_test
+
+import (
+	"cloud-storage/features"
+	slogext "cloud-storage/utils/slogExt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var defaults = map[string]bool{"sharing": true, "import": false}
+
+func TestNew_WithoutFile(t *testing.T) {
+	flags, err := features.New("", defaults, slogext.NewDiscardLogger())
+	require.NoError(t, err)
+
+	assert.True(t, flags.Enabled("sharing"))
+	assert.False(t, flags.Enabled("import"))
+	assert.False(t, flags.Enabled("unknown"))
+	assert.Equal(t, defaults, flags.All())
+}
+
+func TestNew_UnknownFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"sharnig": false}`), 0o600))
+
+	_, err := features.New(path, defaults, slogext.NewDiscardLogger())
+	var ufe features.UnknownFlagError
+	require.ErrorAs(t, err, &ufe)
+	assert.Equal(t, "sharnig", ufe.Name)
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"import": true}`), 0o600))
+
+	flags, err := features.New(path, defaults, slogext.NewDiscardLogger())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"sharing": true, "import": true}, flags.All())
+
+	changed, err := flags.Reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"sharing": false}`), 0o600))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	changed, err = flags.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	// flags dropped from the file go back to their defaults
+	assert.Equal(t, map[string]bool{"sharing": false, "import": false}, flags.All())
+
+	// a broken file keeps the flags as they were
+	require.NoError(t, os.WriteFile(path, []byte(`{"sharing": `), 0o600))
+	later = later.Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	_, err = flags.Reload()
+	assert.Error(t, err)
+	assert.False(t, flags.Enabled("sharing"))
+}
@@ -0,0 +1,155 @@
+// Package filecache keeps what downloads need of the files served most, so that a hot file,
+// e.g. one behind a presigned link, doesn't cost a db lookup and a name decryption per request.
+// Entries are dropped as their rows change, see Wrap.
+package filecache
+
+import (
+	"cloud-storage/db_access"
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+type Entry struct {
+	File db_access.File
+	// FileName is decrypted
+	FileName string
+}
+
+type Stats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Size      int   `json:"size"`
+}
+
+// Cache keeps up to size entries, dropping the least recently used. A nil Cache keeps nothing.
+type Cache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	// gen moves on with every invalidation; a load that started before one doesn't keep what it read,
+	// which may be what was just invalidated
+	gen uint64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+type element struct {
+	id    string
+	entry Entry
+}
+
+// New returns nil for a size of 0 or less
+func New(size int) *Cache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &Cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Load returns the entry of the file id, calling load on a miss; errors aren't kept
+func (c *Cache) Load(id string, load func() (Entry, error)) (Entry, error) {
+	if c == nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[id]; ok {
+		c.order.MoveToFront(e)
+		entry := e.Value.(element).entry
+		c.mu.Unlock()
+
+		c.hits.Add(1)
+		return entry, nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	entry, err := load()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.gen != gen {
+		return entry, nil
+	}
+	if _, ok := c.entries[id]; !ok {
+		c.entries[id] = c.order.PushFront(element{id: id, entry: entry})
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(element).id)
+			c.evictions.Add(1)
+		}
+	}
+
+	return entry, nil
+}
+
+// Invalidate drops the entries of the files
+func (c *Cache) Invalidate(ids ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, id := range ids {
+		if e, ok := c.entries[id]; ok {
+			c.order.Remove(e)
+			delete(c.entries, id)
+		}
+	}
+}
+
+// InvalidateBlob drops the entries of every file of the blob
+func (c *Cache) InvalidateBlob(blobName string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for id, e := range c.entries {
+		file := e.Value.(element).entry.File
+		if file.BlobName == blobName || file.GeneratedName == blobName {
+			c.order.Remove(e)
+			delete(c.entries, id)
+		}
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+	}
+}
@@ -0,0 +1,107 @@
+package filecache
+
+import (
+	"cloud-storage/db_access"
+	"context"
+)
+
+// Repo drops the entries of files as their rows change; writes made in a transaction are only
+// applied to the cache once it ends, so that nothing reads the old row back in meanwhile.
+// Download counts and access times aren't served from the cache and don't invalidate it.
+type Repo struct {
+	db_access.DbAccess
+	cache *Cache
+
+	// set in a transaction, which collects what to invalidate once it ends
+	pending *pending
+}
+
+type pending struct {
+	ids   []string
+	blobs []string
+}
+
+// Wrap returns db with every write going through Repo; every part of the server writing to the db
+// has to use what it returns for the cache to stay correct. It returns db itself for a nil cache.
+func Wrap(db db_access.DbAccess, cache *Cache) db_access.DbAccess {
+	if cache == nil {
+		return db
+	}
+	return &Repo{DbAccess: db, cache: cache}
+}
+
+func (r *Repo) WithTx(ctx context.Context, fn func(repos db_access.DbAccess) error) error {
+	if r.pending != nil {
+		// joins the transaction, which invalidates once it ends
+		return r.DbAccess.WithTx(ctx, func(repos db_access.DbAccess) error {
+			return fn(&Repo{DbAccess: repos, cache: r.cache, pending: r.pending})
+		})
+	}
+
+	p := &pending{}
+	err := r.DbAccess.WithTx(ctx, func(repos db_access.DbAccess) error {
+		return fn(&Repo{DbAccess: repos, cache: r.cache, pending: p})
+	})
+
+	r.cache.Invalidate(p.ids...)
+	for _, blob := range p.blobs {
+		r.cache.InvalidateBlob(blob)
+	}
+	return err
+}
+
+func (r *Repo) invalidate(id string) {
+	if r.pending != nil {
+		r.pending.ids = append(r.pending.ids, id)
+		return
+	}
+	r.cache.Invalidate(id)
+}
+
+func (r *Repo) invalidateBlob(blobName string) {
+	if r.pending != nil {
+		r.pending.blobs = append(r.pending.blobs, blobName)
+		return
+	}
+	r.cache.InvalidateBlob(blobName)
+}
+
+func (r *Repo) RenameFile(generatedName string, filename string) error {
+	defer r.invalidate(generatedName)
+	return r.DbAccess.RenameFile(generatedName, filename)
+}
+
+func (r *Repo) RemoveFile(generatedName string) error {
+	defer r.invalidate(generatedName)
+	return r.DbAccess.RemoveFile(generatedName)
+}
+
+func (r *Repo) DeleteFile(generatedName string) (db_access.Blob, bool, error) {
+	defer r.invalidate(generatedName)
+	return r.DbAccess.DeleteFile(generatedName)
+}
+
+func (r *Repo) SetBlobBackend(blobName string, from string, to string) (bool, error) {
+	defer r.invalidateBlob(blobName)
+	return r.DbAccess.SetBlobBackend(blobName, from, to)
+}
+
+func (r *Repo) SetFileExpiry(generatedName string, expiresAt db_access.Time) error {
+	defer r.invalidate(generatedName)
+	return r.DbAccess.SetFileExpiry(generatedName, expiresAt)
+}
+
+func (r *Repo) MarkExpiryNotified(generatedName string) error {
+	defer r.invalidate(generatedName)
+	return r.DbAccess.MarkExpiryNotified(generatedName)
+}
+
+func (r *Repo) SetFileNameIndex(generatedName string, index string) error {
+	defer r.invalidate(generatedName)
+	return r.DbAccess.SetFileNameIndex(generatedName, index)
+}
+
+func (r *Repo) RewrapFileName(generatedName string, old string, new string) error {
+	defer r.invalidate(generatedName)
+	return r.DbAccess.RewrapFileName(generatedName, old, new)
+}
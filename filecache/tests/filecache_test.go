@@ -0,0 +1,116 @@
+package filecache_test
+
+import (
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/filecache"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func loader(file db_access.File, loads *int) func() (filecache.Entry, error) {
+	return func() (filecache.Entry, error) {
+		*loads++
+		return filecache.Entry{File: file, FileName: "name of " + file.GeneratedName}, nil
+	}
+}
+
+func TestCache_Load(t *testing.T) {
+	c := filecache.New(2)
+	loads := 0
+
+	for _, id := range []string{"a", "b", "a", "c", "a", "b"} {
+		entry, err := c.Load(id, loader(db_access.File{GeneratedName: id}, &loads))
+		require.NoError(t, err)
+		assert.Equal(t, "name of "+id, entry.FileName)
+	}
+
+	// b was the least recently used when c came in, and c when b came back
+	assert.Equal(t, 4, loads)
+	assert.Equal(t, filecache.Stats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}, c.Stats())
+
+	_, err := c.Load("d", func() (filecache.Entry, error) {
+		return filecache.Entry{}, errors.New("db is gone")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 2, c.Stats().Size)
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	c := filecache.New(10)
+	loads := 0
+
+	c.Load("a", loader(db_access.File{GeneratedName: "a"}, &loads))
+	c.Load("b", loader(db_access.File{GeneratedName: "b", BlobName: "blob"}, &loads))
+	c.Load("c", loader(db_access.File{GeneratedName: "c", BlobName: "blob"}, &loads))
+
+	c.Invalidate("a")
+	c.InvalidateBlob("blob")
+	assert.Zero(t, c.Stats().Size)
+
+	// what a load read before an invalidation may be stale, so it isn't kept
+	c.Load("a", func() (filecache.Entry, error) {
+		c.Invalidate("a")
+		return filecache.Entry{File: db_access.File{GeneratedName: "a"}}, nil
+	})
+	assert.Zero(t, c.Stats().Size)
+
+	var nilCache *filecache.Cache
+	_, err := nilCache.Load("a", loader(db_access.File{GeneratedName: "a"}, &loads))
+	assert.NoError(t, err)
+	nilCache.Invalidate("a")
+	assert.Equal(t, filecache.Stats{}, nilCache.Stats())
+}
+
+func TestWrap(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := filecache.New(10)
+	repos := filecache.Wrap(db, c)
+	loads := 0
+
+	load := func(ids ...string) {
+		for _, id := range ids {
+			c.Load(id, loader(db_access.File{GeneratedName: id, BlobName: "blob-" + id}, &loads))
+		}
+	}
+
+	load("a", "b", "c")
+	db.EXPECT().RenameFile("a", "enc:new").Return(nil).Once()
+	require.NoError(t, repos.RenameFile("a", "enc:new"))
+	db.EXPECT().SetBlobBackend("blob-b", "local", "cold").Return(true, nil).Once()
+	_, err := repos.SetBlobBackend("blob-b", "local", "cold")
+	require.NoError(t, err)
+	// failed writes invalidate too, they may have gone through before failing
+	db.EXPECT().DeleteFile("c").Return(db_access.Blob{}, false, errors.New("disk I/O error")).Once()
+	_, _, err = repos.DeleteFile("c")
+	require.Error(t, err)
+	assert.Zero(t, c.Stats().Size)
+
+	// reads and access counts go straight through
+	db.EXPECT().RecordFileAccesses(mock.Anything).Return(nil).Once()
+	load("a")
+	require.NoError(t, repos.RecordFileAccesses(nil))
+	assert.Equal(t, 1, c.Stats().Size)
+
+	// writes in a transaction only invalidate once it ends
+	db.EXPECT().WithTx(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, fn func(db_access.DbAccess) error) error {
+		return fn(db)
+	})
+	db.EXPECT().SetFileExpiry("a", mock.Anything).Return(nil).Once()
+	err = repos.WithTx(context.Background(), func(tx db_access.DbAccess) error {
+		if err := tx.SetFileExpiry("a", db_access.Time{}); err != nil {
+			return err
+		}
+		assert.Equal(t, 1, c.Stats().Size)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Zero(t, c.Stats().Size)
+
+	assert.Same(t, db, filecache.Wrap(db, nil))
+}
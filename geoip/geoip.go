@@ -0,0 +1,110 @@
+// Package geoip tells the country of client addresses. Providers are pluggable; the one built in
+// reads a table of networks and their countries, which the CSV exports of the common GeoIP
+// databases can be turned into.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrUnknown is returned for addresses the provider has no country of
+var ErrUnknown = errors.New("geoip: unknown address")
+
+// Provider returns the ISO 3166 alpha-2 country of an address, upper case
+type Provider interface {
+	Country(addr netip.Addr) (string, error)
+}
+
+type entry struct {
+	prefix  netip.Prefix
+	country string
+}
+
+// Table is a Provider of networks and their countries; the most specific network of an address wins
+type Table struct {
+	// longest prefixes first
+	entries []entry
+}
+
+// ValidCountry reports whether s is an ISO 3166 alpha-2 code, upper case
+func ValidCountry(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadTable reads lines of a network in CIDR notation and a country, separated by a comma;
+// lines starting with # are left out
+func ReadTable(r io.Reader) (*Table, error) {
+	const op = "geoip.ReadTable"
+
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+
+	var t Table
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		prefix, err := netip.ParsePrefix(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		country := strings.ToUpper(record[1])
+		if !ValidCountry(country) {
+			return nil, fmt.Errorf("%s: invalid country %q of %s", op, record[1], record[0])
+		}
+		t.entries = append(t.entries, entry{prefix: prefix.Masked(), country: country})
+	}
+
+	sort.SliceStable(t.entries, func(i, j int) bool {
+		return t.entries[i].prefix.Bits() > t.entries[j].prefix.Bits()
+	})
+	return &t, nil
+}
+
+// LoadTable reads the table in the file at path, see ReadTable
+func LoadTable(path string) (*Table, error) {
+	const op = "geoip.LoadTable"
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Close()
+
+	t, err := ReadTable(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return t, nil
+}
+
+func (t *Table) Country(addr netip.Addr) (string, error) {
+	addr = addr.Unmap()
+	for _, e := range t.entries {
+		if e.prefix.Contains(addr) {
+			return e.country, nil
+		}
+	}
+	return "", ErrUnknown
+}
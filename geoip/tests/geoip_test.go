@@ -0,0 +1,45 @@
+package geoip_test
+
+import (
+	"cloud-storage/geoip"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable(t *testing.T) {
+	table, err := geoip.ReadTable(strings.NewReader(`# network,country
+10.0.0.0/8,de
+10.1.0.0/16,FR
+2001:db8::/32,NL
+`))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		addr    string
+		country string
+	}{
+		{addr: "10.2.3.4", country: "DE"},
+		{addr: "10.1.3.4", country: "FR"},
+		{addr: "::ffff:10.1.3.4", country: "FR"},
+		{addr: "2001:db8::1", country: "NL"},
+	}
+	for _, tc := range testCases {
+		country, err := table.Country(netip.MustParseAddr(tc.addr))
+		require.NoError(t, err, tc.addr)
+		assert.Equal(t, tc.country, country, tc.addr)
+	}
+
+	_, err = table.Country(netip.MustParseAddr("192.0.2.1"))
+	assert.ErrorIs(t, err, geoip.ErrUnknown)
+}
+
+func TestReadTable_Invalid(t *testing.T) {
+	for _, table := range []string{"10.0.0.0/33,DE\n", "10.0.0.0/8,Germany\n", "10.0.0.0/8\n"} {
+		_, err := geoip.ReadTable(strings.NewReader(table))
+		assert.Error(t, err, table)
+	}
+}
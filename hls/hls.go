@@ -0,0 +1,324 @@
+package hls
+
+import (
+	"bufio"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// transcodes are kept next to the blobs, one dir per blob, so copies sharing a blob share its stream
+const hlsDirName = ".hls"
+
+// how many files may be transcoded at the same time
+const maxConcurrentTranscodes = 1
+
+var segmentName = regexp.MustCompile(`^seg[0-9]{5}\.ts$`)
+
+type Status string
+
+const (
+	StatusNone    Status = ""
+	StatusRunning Status = "running"
+	StatusReady   Status = "ready"
+	StatusFailed  Status = "failed"
+)
+
+var ErrNotReady = errors.New("stream is not ready")
+var ErrUnknownSegment = errors.New("unknown segment")
+
+type Config struct {
+	// streams are kept in the storage dir of Blobs, whichever backend the source blob is on
+	Blobs      *blobstore.Store
+	Durability storage.Durability
+}
+
+type Service struct {
+	c          encryption.Crypter
+	t          Transcoder
+	blobs      *blobstore.Store
+	storageDir string
+	hlsDir     string
+	durability storage.Durability
+	slots      chan struct{}
+	log        *slog.Logger
+
+	mu     sync.Mutex
+	states map[string]Status
+}
+
+func New(c encryption.Crypter, t Transcoder, cfg Config, log *slog.Logger) (*Service, error) {
+	const op = "hls.New"
+
+	hlsDir := filepath.Join(cfg.Blobs.Dir(), hlsDirName)
+	if err := os.MkdirAll(hlsDir, 0o700); err != nil {
+		return nil, fmt.Errorf("%s: os.MkdirAll: %w", op, err)
+	}
+
+	s := &Service{
+		c:          c,
+		t:          t,
+		blobs:      cfg.Blobs,
+		storageDir: cfg.Blobs.Dir(),
+		hlsDir:     hlsDir,
+		durability: cfg.Durability,
+		slots:      make(chan struct{}, maxConcurrentTranscodes),
+		log:        log.With(slog.String("component", "hls")),
+		states:     make(map[string]Status),
+	}
+
+	if err := s.removeIncomplete(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Remove deletes the stream of a blob; used when the blob itself is deleted or the owner of the stream is erased
+func Remove(storageDir string, blobName string) error {
+	return os.RemoveAll(filepath.Join(storageDir, hlsDirName, blobName))
+}
+
+// removeIncomplete drops streams whose transcoding was interrupted by a restart
+func (s *Service) removeIncomplete() error {
+	entries, err := os.ReadDir(s.hlsDir)
+	if err != nil {
+		return fmt.Errorf("os.ReadDir: %w", err)
+	}
+
+	for _, entry := range entries {
+		dir := filepath.Join(s.hlsDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, PlaylistName)); errors.Is(err, os.ErrNotExist) {
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("os.RemoveAll: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) streamDir(blobName string) string {
+	return filepath.Join(s.hlsDir, blobName)
+}
+
+func (s *Service) Status(file db_access.File) Status {
+	s.mu.Lock()
+	status, ok := s.states[file.BlobName]
+	s.mu.Unlock()
+	if ok {
+		return status
+	}
+
+	if _, err := os.Stat(filepath.Join(s.streamDir(file.BlobName), PlaylistName)); err == nil {
+		return StatusReady
+	}
+
+	return StatusNone
+}
+
+// Start transcodes the file in the background unless it is already transcoded or being transcoded
+func (s *Service) Start(file db_access.File) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status, ok := s.states[file.BlobName]; ok && status != StatusFailed {
+		return status
+	}
+
+	if _, err := os.Stat(filepath.Join(s.streamDir(file.BlobName), PlaylistName)); err == nil {
+		return StatusReady
+	}
+
+	s.states[file.BlobName] = StatusRunning
+	go s.transcode(file)
+
+	return StatusRunning
+}
+
+func (s *Service) setStatus(blobName string, status Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status == StatusReady {
+		// the playlist on disk is the source of truth from now on
+		delete(s.states, blobName)
+	} else {
+		s.states[blobName] = status
+	}
+}
+
+func (s *Service) transcode(file db_access.File) {
+	log := s.log.With(slog.String("blob", file.BlobName))
+
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	if err := s.build(file); err != nil {
+		log.Error("Could not transcode file", slogext.Error(err))
+
+		if err := os.RemoveAll(s.streamDir(file.BlobName)); err != nil {
+			log.Error("Could not remove incomplete stream", slogext.Error(err))
+		}
+
+		s.setStatus(file.BlobName, StatusFailed)
+		return
+	}
+
+	s.setStatus(file.BlobName, StatusReady)
+	log.Info("Transcoded file")
+}
+
+// build runs the transcoder on the decrypted blob and encrypts its output into the stream dir.
+// The plaintext output only lives in the storage temp dir, which is swept on startup.
+func (s *Service) build(file db_access.File) error {
+	const op = "hls.Service.build"
+
+	tmpDir := filepath.Join(s.storageDir, storage.TempDirName)
+	if err := os.MkdirAll(tmpDir, 0o700); err != nil {
+		return fmt.Errorf("%s: os.MkdirAll: %w", op, err)
+	}
+
+	workDir, err := os.MkdirTemp(tmpDir, "hls-")
+	if err != nil {
+		return fmt.Errorf("%s: os.MkdirTemp: %w", op, err)
+	}
+	defer os.RemoveAll(workDir)
+
+	blob, err := s.blobs.Open(context.Background(), file.Backend, file.BlobName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer blob.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.c.DecryptAndCopy(pw, blob))
+	}()
+
+	err = s.t.Transcode(context.Background(), pr, workDir)
+	// unblocks decryption if the transcoder stopped reading early
+	pr.CloseWithError(errors.New("transcoding stopped"))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	streamDir := s.streamDir(file.BlobName)
+	if err := os.MkdirAll(streamDir, 0o700); err != nil {
+		return fmt.Errorf("%s: os.MkdirAll: %w", op, err)
+	}
+
+	segments, err := filepath.Glob(filepath.Join(workDir, "seg*.ts"))
+	if err != nil {
+		return fmt.Errorf("%s: filepath.Glob: %w", op, err)
+	}
+
+	for _, segment := range segments {
+		if err := s.encryptInto(file.OwnerId, segment, streamDir); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// the playlist goes last, its presence marks the stream as complete
+	if err := s.encryptInto(file.OwnerId, filepath.Join(workDir, PlaylistName), streamDir); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// encryptInto encrypts the file at srcPath into destDir for the owner of the file the stream is of
+func (s *Service) encryptInto(ownerId int64, srcPath string, destDir string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("os.Open: %w", err)
+	}
+	defer src.Close()
+
+	dest, err := storage.CreateTemp(s.storageDir, s.durability)
+	if err != nil {
+		return err
+	}
+	defer dest.Discard()
+
+	if err := s.c.EncryptAndCopyFor(ownerId, dest, src); err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(s.storageDir, filepath.Join(destDir, filepath.Base(srcPath)))
+	if err != nil {
+		return fmt.Errorf("filepath.Rel: %w", err)
+	}
+
+	return dest.CommitAs(rel)
+}
+
+// WritePlaylist writes the playlist with segment uris relative to segmentPrefix
+func (s *Service) WritePlaylist(w io.Writer, file db_access.File, segmentPrefix string) error {
+	const op = "hls.Service.WritePlaylist"
+
+	playlist, err := os.Open(filepath.Join(s.streamDir(file.BlobName), PlaylistName))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", op, ErrNotReady)
+	} else if err != nil {
+		return fmt.Errorf("%s: os.Open: %w", op, err)
+	}
+	defer playlist.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.c.DecryptAndCopy(pw, playlist))
+	}()
+	defer pr.Close()
+
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if segmentName.MatchString(line) {
+			line = segmentPrefix + line
+		}
+
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return fmt.Errorf("%s: write: %w", op, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Service) WriteSegment(w io.Writer, file db_access.File, name string) error {
+	const op = "hls.Service.WriteSegment"
+
+	if !segmentName.MatchString(name) {
+		return fmt.Errorf("%s: %w", op, ErrUnknownSegment)
+	}
+
+	segment, err := os.Open(filepath.Join(s.streamDir(file.BlobName), name))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", op, ErrUnknownSegment)
+	} else if err != nil {
+		return fmt.Errorf("%s: os.Open: %w", op, err)
+	}
+	defer segment.Close()
+
+	if err := s.c.DecryptAndCopy(w, segment); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package hls_test
+
+import (
+	"bytes"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/hls"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// xorCrypter stands in for the real crypter; it only has to round-trip
+type xorCrypter struct{}
+
+func xorCopy(w io.Writer, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		data[i] ^= 0x5a
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (xorCrypter) EncryptAndCopy(w io.Writer, r io.Reader) error     { return xorCopy(w, r) }
+func (xorCrypter) EncryptAndCopyFor(ownerId int64, w io.Writer, r io.Reader) error {
+	return xorCopy(w, r)
+}
+func (xorCrypter) DecryptAndCopy(w io.Writer, r io.Reader) error     { return xorCopy(w, r) }
+func (xorCrypter) EncryptFileName(filename string) (string, error)   { return filename, nil }
+func (xorCrypter) DecryptFileName(ciphertext string) (string, error) { return ciphertext, nil }
+func (xorCrypter) FileNameIndex(filename string) (string, error)     { return filename, nil }
+func (xorCrypter) ContentTermIndex(ownerId int64, term string) (string, error) {
+	return term, nil
+}
+func (xorCrypter) DecryptFileNames(ciphertexts []string) ([]string, error) {
+	return ciphertexts, nil
+}
+
+type fakeTranscoder struct {
+	input []byte
+	fail  bool
+}
+
+func (t *fakeTranscoder) Transcode(_ context.Context, r io.Reader, dir string) error {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	t.input = input
+
+	if t.fail {
+		return errors.New("unsupported codec")
+	}
+
+	playlist := "#EXTM3U\n#EXTINF:6.0,\nseg00000.ts\n#EXTINF:2.5,\nseg00001.ts\n#EXT-X-ENDLIST\n"
+	if err := os.WriteFile(filepath.Join(dir, "seg00000.ts"), []byte("segment 0"), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "seg00001.ts"), []byte("segment 1"), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, hls.PlaylistName), []byte(playlist), 0o600)
+}
+
+func waitForStatus(t *testing.T, s *hls.Service, file db_access.File, status hls.Status) {
+	assert.Eventually(t, func() bool {
+		return s.Status(file) == status
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func setup(t *testing.T, transcoder hls.Transcoder) (*hls.Service, db_access.File, string) {
+	dir := t.TempDir()
+	file := db_access.File{GeneratedName: "copy", BlobName: "blob"}
+
+	encrypted := bytes.NewBuffer(make([]byte, 0))
+	assert.NoError(t, xorCrypter{}.EncryptAndCopy(encrypted, bytes.NewReader([]byte("media"))))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, file.BlobName), encrypted.Bytes(), 0o600))
+
+	s, err := hls.New(xorCrypter{}, transcoder, hls.Config{Blobs: blobstore.NewStore(dir, storage.DurabilityNone, nil)}, slogext.NewDiscardLogger())
+	assert.NoError(t, err)
+
+	return s, file, dir
+}
+
+func TestService_Transcode(t *testing.T) {
+	transcoder := &fakeTranscoder{}
+	s, file, dir := setup(t, transcoder)
+
+	assert.Equal(t, hls.StatusNone, s.Status(file))
+	assert.Equal(t, hls.StatusRunning, s.Start(file))
+	waitForStatus(t, s, file, hls.StatusReady)
+	assert.Equal(t, []byte("media"), transcoder.input)
+
+	playlist := bytes.NewBuffer(make([]byte, 0))
+	assert.NoError(t, s.WritePlaylist(playlist, file, "segments/"))
+	assert.Contains(t, playlist.String(), "\nsegments/seg00000.ts\n")
+	assert.Contains(t, playlist.String(), "\nsegments/seg00001.ts\n")
+
+	segment := bytes.NewBuffer(make([]byte, 0))
+	assert.NoError(t, s.WriteSegment(segment, file, "seg00001.ts"))
+	assert.Equal(t, "segment 1", segment.String())
+
+	// segments are stored encrypted
+	stored, err := os.ReadFile(filepath.Join(dir, ".hls", file.BlobName, "seg00001.ts"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, []byte("segment 1"), stored)
+
+	assert.ErrorIs(t, s.WriteSegment(io.Discard, file, "../blob"), hls.ErrUnknownSegment)
+	assert.ErrorIs(t, s.WriteSegment(io.Discard, file, "seg00002.ts"), hls.ErrUnknownSegment)
+
+	// a finished stream is not transcoded again
+	assert.Equal(t, hls.StatusReady, s.Start(file))
+
+	assert.NoError(t, hls.Remove(dir, file.BlobName))
+	assert.Equal(t, hls.StatusNone, s.Status(file))
+}
+
+func TestService_TranscodeFailure(t *testing.T) {
+	s, file, dir := setup(t, &fakeTranscoder{fail: true})
+
+	s.Start(file)
+	waitForStatus(t, s, file, hls.StatusFailed)
+
+	_, err := os.Stat(filepath.Join(dir, ".hls", file.BlobName))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.ErrorIs(t, s.WritePlaylist(io.Discard, file, ""), hls.ErrNotReady)
+}
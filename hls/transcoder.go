@@ -0,0 +1,55 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Transcoder turns a media stream into an HLS playlist named PlaylistName
+// plus the segments it references, all written flat into dir.
+type Transcoder interface {
+	Transcode(ctx context.Context, r io.Reader, dir string) error
+}
+
+const PlaylistName = "index.m3u8"
+
+// segment names are predictable so that requests for anything else can be rejected without touching the disk
+const segmentPattern = "seg%05d.ts"
+
+type FFmpeg struct {
+	Path            string
+	SegmentDuration int
+}
+
+func (f FFmpeg) Transcode(ctx context.Context, r io.Reader, dir string) error {
+	const op = "hls.FFmpeg.Transcode"
+
+	cmd := exec.CommandContext(
+		ctx,
+		f.Path,
+		"-hide_banner", "-loglevel", "error", "-nostdin",
+		"-i", "pipe:0",
+		"-c:v", "libx264", "-preset", "veryfast",
+		"-c:a", "aac",
+		"-f", "hls",
+		"-hls_time", fmt.Sprint(f.SegmentDuration),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, segmentPattern),
+		filepath.Join(dir, PlaylistName),
+	)
+	cmd.Stdin = r
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", op, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
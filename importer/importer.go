@@ -0,0 +1,298 @@
+package importer
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/jobs"
+	"cloud-storage/utils/safehttp"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxAttempts   = 3
+	retryBaseWait = time.Second
+)
+
+type Config struct {
+	// Store stores the imported files
+	Store       Store
+	MaxFileSize int64
+	LocalRoots  []string
+	// UrlHosts are the hosts files may be fetched from by url, none if empty
+	UrlHosts safehttp.Hosts
+	// UrlTimeout limits fetching a file by url, zero for no limit
+	UrlTimeout time.Duration
+	// Client fetches from WebDAV and S3 sources, nil for one that only connects to public addresses
+	Client *http.Client
+	// Jobs runs the imports
+	Jobs *jobs.Pool
+}
+
+// File is a file of an import about to be stored
+type File struct {
+	OwnerId int64
+	Name    string
+	// Size is -1 if the source doesn't tell it up front, in which case the content may take up to MaxSize bytes
+	Size    int64
+	MaxSize int64
+}
+
+// Store stores imported files the way uploads are, held to the same checks and policies.
+// Files it turns down fail with RejectedError.
+type Store interface {
+	Store(ctx context.Context, file File, content io.Reader) (int64, error)
+}
+
+// RejectedError is a file Store turned down, which it would turn down again
+type RejectedError struct {
+	Reason string
+}
+
+func (err RejectedError) Error() string {
+	return fmt.Sprintf("file rejected: %s", err.Reason)
+}
+
+type Importer struct {
+	db  db_access.DbAccess
+	cfg Config
+	// both clients only reach public addresses unless configured otherwise, as users pick the servers
+	client    *http.Client
+	urlClient *http.Client
+	log       *slog.Logger
+}
+
+var ErrQueueFull = jobs.ErrQueueFull
+
+type tooBigFileError struct {
+	path string
+}
+
+func (err tooBigFileError) Error() string {
+	return fmt.Sprintf("%s exceeds max file size", err.path)
+}
+
+func New(db db_access.DbAccess, cfg Config, log *slog.Logger) *Importer {
+	client := cfg.Client
+	if client == nil {
+		client = safehttp.NewClient(safehttp.Hosts{"*"}, 0)
+	}
+
+	return &Importer{
+		db:        db,
+		cfg:       cfg,
+		client:    client,
+		urlClient: safehttp.NewClient(cfg.UrlHosts, cfg.UrlTimeout),
+		log:       log.With(slog.String("component", "importer")),
+	}
+}
+
+// Recover fails the imports a restart interrupted; it must run before any import is started
+func (i *Importer) Recover() {
+	if err := i.db.FailUnfinishedImports("Interrupted by server restart"); err != nil {
+		i.log.Error("Could not fail unfinished imports", slogext.Error(err))
+	}
+}
+
+func (i *Importer) newSource(spec SourceSpec) (Source, error) {
+	switch spec.Type {
+	case SourceLocal:
+		return newLocalSource(spec.Local, i.cfg.LocalRoots)
+	case SourceWebDAV:
+		return newWebDAVSource(spec.WebDAV, i.client)
+	case SourceS3:
+		return newS3Source(spec.S3, i.client)
+	case SourceUrl:
+		return newUrlSource(spec.Url, i.cfg.UrlHosts, i.urlClient, i.cfg.MaxFileSize)
+	}
+
+	return nil, InvalidSourceError{Reason: fmt.Sprintf("unknown source type %q", spec.Type)}
+}
+
+// Start validates the source and queues an import into the user's account. The job of the import
+// has the same id and counts files as its units of work.
+func (i *Importer) Start(ownerId int64, spec SourceSpec) (db_access.Import, error) {
+	const op = "importer.Importer.Start"
+
+	source, err := i.newSource(spec)
+	if err != nil {
+		return db_access.Import{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	imp := db_access.Import{
+		Id:           uuid.New().String(),
+		OwnerId:      ownerId,
+		Source:       spec.Type,
+		Status:       db_access.ImportQueued,
+		CreationTime: db_access.Time(time.Now()),
+	}
+
+	if err := i.db.AddImport(&imp); err != nil {
+		return db_access.Import{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	jobType := jobs.TypeImport
+	if spec.Type == SourceUrl {
+		jobType = jobs.TypeFromUrl
+	}
+
+	_, err = i.cfg.Jobs.Submit(
+		db_access.Job{Id: imp.Id, Type: jobType, OwnerId: ownerId},
+		func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			return i.run(ctx, imp, source, r)
+		},
+	)
+	if err != nil {
+		imp.Status = db_access.ImportFailed
+		imp.Error = "Could not queue import"
+		if errors.Is(err, ErrQueueFull) {
+			imp.Error = ErrQueueFull.Error()
+		}
+		if err := i.db.UpdateImport(&imp); err != nil {
+			i.log.Error("Could not update import", slogext.Error(err), slog.String("import-id", imp.Id))
+		}
+		return db_access.Import{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return imp, nil
+}
+
+// ImportResult is what the job of an import results in
+type ImportResult struct {
+	ImportedFiles int64 `json:"imported_files"`
+	FailedFiles   int64 `json:"failed_files"`
+	ImportedBytes int64 `json:"imported_bytes"`
+}
+
+func (i *Importer) run(ctx context.Context, imp db_access.Import, source Source, r *jobs.Reporter) (ImportResult, error) {
+	log := i.log.With(slog.String("import-id", imp.Id), slog.Int64("user-id", imp.OwnerId))
+
+	update := func() {
+		if err := i.db.UpdateImport(&imp); err != nil {
+			log.Error("Could not update import", slogext.Error(err))
+		}
+	}
+
+	imp.Status = db_access.ImportRunning
+	update()
+
+	var entries []Entry
+	err := retry(ctx, func() (err error) {
+		entries, err = source.List(ctx)
+		return
+	})
+	if err != nil {
+		log.Error("Could not list source", slogext.Error(err))
+		imp.Status = db_access.ImportFailed
+		imp.Error = "Could not list source"
+		update()
+		return ImportResult{}, jobs.Failure(imp.Error)
+	}
+
+	imp.TotalFiles = int64(len(entries))
+	update()
+	r.SetTotal(imp.TotalFiles)
+
+	for _, entry := range entries {
+		var size int64
+		err := retry(ctx, func() (err error) {
+			size, err = i.ingest(ctx, imp.OwnerId, source, entry)
+			return
+		})
+		if err != nil {
+			log.Error("Could not import file", slogext.Error(err), slog.String("path", entry.Path))
+			imp.FailedFiles++
+		} else {
+			imp.ImportedFiles++
+			imp.ImportedBytes += size
+		}
+		update()
+		r.Add(1)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	imp.Status = db_access.ImportFinished
+	if ctx.Err() != nil {
+		imp.Status = db_access.ImportFailed
+		imp.Error = "Interrupted by server shutdown"
+	}
+	update()
+
+	log.Info(
+		"Import finished",
+		slog.Int64("imported", imp.ImportedFiles),
+		slog.Int64("failed", imp.FailedFiles),
+	)
+
+	if imp.Status == db_access.ImportFailed {
+		return ImportResult{}, jobs.Failure(imp.Error)
+	}
+	return ImportResult{
+		ImportedFiles: imp.ImportedFiles,
+		FailedFiles:   imp.FailedFiles,
+		ImportedBytes: imp.ImportedBytes,
+	}, nil
+}
+
+func retry(ctx context.Context, f func() error) error {
+	var err error
+	for attempt := range maxAttempts {
+		err = f()
+		var tbfe tooBigFileError
+		var re RejectedError
+		// refused urls stay refused
+		if err == nil || errors.As(err, &tbfe) || errors.As(err, &re) ||
+			errors.Is(err, safehttp.ErrHostNotAllowed) || errors.Is(err, safehttp.ErrForbiddenAddress) {
+			return err
+		}
+
+		if attempt == maxAttempts-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryBaseWait << attempt):
+		}
+	}
+
+	return err
+}
+
+// ingest stores a single source file through the Store
+func (i *Importer) ingest(ctx context.Context, ownerId int64, source Source, entry Entry) (int64, error) {
+	if entry.Size > i.cfg.MaxFileSize {
+		return 0, tooBigFileError{path: entry.Path}
+	}
+
+	rc, err := source.Open(ctx, entry)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	// sources may send more than the size they report, the Store tells from the byte past the limit
+	size, err := i.cfg.Store.Store(ctx, File{
+		OwnerId: ownerId,
+		Name:    path.Base(entry.Path),
+		Size:    entry.Size,
+		MaxSize: i.cfg.MaxFileSize,
+	}, io.LimitReader(rc, i.cfg.MaxFileSize+1))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", entry.Path, err)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"cloud-storage/utils/sigv4"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type s3Source struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+func newS3Source(spec *S3Spec, client *http.Client) (*s3Source, error) {
+	if spec == nil || spec.Endpoint == "" || spec.Bucket == "" {
+		return nil, InvalidSourceError{Reason: "endpoint and bucket are required"}
+	}
+
+	endpoint, err := url.Parse(spec.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, InvalidSourceError{Reason: "endpoint must be http or https"}
+	}
+
+	region := spec.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &s3Source{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    spec.Bucket,
+		prefix:    spec.Prefix,
+		accessKey: spec.AccessKey,
+		secretKey: spec.SecretKey,
+		client:    client,
+	}, nil
+}
+
+// objectUrl uses path-style addressing so that any s3 compatible server works
+func (s *s3Source) objectUrl(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = sigv4.UriEncode(u.Path, false)
+	u.RawQuery = sigv4.CanonicalQuery(query)
+	return &u
+}
+
+func (s *s3Source) do(ctx context.Context, u *url.URL) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	if s.accessKey != "" {
+		// every request we make to s3 has no body
+		sigv4.Sign(r, sigv4.Credentials{
+			AccessKey: s.accessKey,
+			SecretKey: s.secretKey,
+			Region:    s.region,
+			Service:   "s3",
+		}, sigv4.EmptyPayloadHash, time.Now())
+	}
+
+	resp, err := s.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+func (s *s3Source) List(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	token := ""
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		if s.prefix != "" {
+			query.Set("prefix", s.prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, s.objectUrl("", query))
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", s.bucket, err)
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list %s: decode: %w", s.bucket, err)
+		}
+
+		for _, object := range result.Contents {
+			// "directory" placeholder objects
+			if strings.HasSuffix(object.Key, "/") {
+				continue
+			}
+			entries = append(entries, Entry{Path: object.Key, Size: object.Size})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	return entries, nil
+}
+
+func (s *s3Source) Open(ctx context.Context, entry Entry) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, s.objectUrl(entry.Path, nil))
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", entry.Path, err)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Entry struct {
+	// Path is relative to the root of the source
+	Path string
+	// Size is -1 if the source can't tell it before the file is read
+	Size int64
+}
+
+type Source interface {
+	List(ctx context.Context) ([]Entry, error)
+	Open(ctx context.Context, entry Entry) (io.ReadCloser, error)
+}
+
+const (
+	SourceLocal  = "local"
+	SourceWebDAV = "webdav"
+	SourceS3     = "s3"
+	SourceUrl    = "url"
+)
+
+type LocalSpec struct {
+	Path string `json:"path"`
+}
+
+type WebDAVSpec struct {
+	Url      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type S3Spec struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+type UrlSpec struct {
+	Url string `json:"url"`
+	// FileName defaults to the last segment of the url path
+	FileName string `json:"file_name"`
+}
+
+type SourceSpec struct {
+	Type   string      `json:"type"`
+	Local  *LocalSpec  `json:"local,omitempty"`
+	WebDAV *WebDAVSpec `json:"webdav,omitempty"`
+	S3     *S3Spec     `json:"s3,omitempty"`
+	Url    *UrlSpec    `json:"url,omitempty"`
+}
+
+type InvalidSourceError struct {
+	Reason string
+}
+
+func (err InvalidSourceError) Error() string {
+	return fmt.Sprintf("invalid import source: %s", err.Reason)
+}
+
+type localSource struct {
+	root string
+}
+
+// newLocalSource only allows paths inside one of the roots configured by the operator,
+// otherwise any user could import the server's own files
+func newLocalSource(spec *LocalSpec, allowedRoots []string) (*localSource, error) {
+	if spec == nil || spec.Path == "" {
+		return nil, InvalidSourceError{Reason: "path is required"}
+	}
+
+	path, err := filepath.Abs(spec.Path)
+	if err != nil {
+		return nil, InvalidSourceError{Reason: "bad path"}
+	}
+
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, InvalidSourceError{Reason: "path does not exist"}
+	}
+
+	for _, root := range allowedRoots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return &localSource{root: path}, nil
+		}
+	}
+
+	return nil, InvalidSourceError{Reason: "path is outside of allowed import roots"}
+}
+
+func (s *localSource) List(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// symlinks are skipped so they can't point outside of the root
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, Entry{Path: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", s.root, err)
+	}
+
+	return entries, nil
+}
+
+func (s *localSource) Open(_ context.Context, entry Entry) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.root, filepath.FromSlash(entry.Path)))
+}
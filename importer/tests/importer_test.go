@@ -0,0 +1,251 @@
+package importer_test
+
+import (
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/importer"
+	"cloud-storage/jobs"
+	"cloud-storage/utils/safehttp"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const ownerId = int64(7)
+
+// remote serves a.txt, and big.txt which holds more than the size it reports
+var remote = map[string]string{
+	"a.txt":   "hello",
+	"big.txt": strings.Repeat("b", 32),
+}
+
+func reportedSize(name string) int {
+	if name == "big.txt" {
+		return 4
+	}
+	return len(remote[name])
+}
+
+func newWebDAVServer(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PROPFIND" && r.URL.Path == "/":
+			var b strings.Builder
+			b.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+			b.WriteString(`<d:response><d:href>/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+			for _, name := range []string{"a.txt", "big.txt"} {
+				fmt.Fprintf(&b, `<d:response><d:href>/%s</d:href><d:propstat><d:prop><d:resourcetype/><d:getcontentlength>%d</d:getcontentlength></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, name, reportedSize(name))
+			}
+			b.WriteString(`</d:multistatus>`)
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, b.String())
+		case r.Method == http.MethodGet:
+			content, ok := remote[strings.TrimPrefix(r.URL.Path, "/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			io.WriteString(w, content)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newS3Server(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket" && r.URL.Query().Get("list-type") == "2" {
+			var b strings.Builder
+			b.WriteString(`<?xml version="1.0"?><ListBucketResult><IsTruncated>false</IsTruncated>`)
+			for _, name := range []string{"a.txt", "big.txt"} {
+				fmt.Fprintf(&b, `<Contents><Key>%s</Key><Size>%d</Size></Contents>`, name, reportedSize(name))
+			}
+			b.WriteString(`</ListBucketResult>`)
+			io.WriteString(w, b.String())
+			return
+		}
+
+		content, ok := remote[strings.TrimPrefix(r.URL.Path, "/bucket/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// store keeps the content it is given, turning down files that don't end right at their size
+type store struct {
+	mu sync.Mutex
+	// read is what was read of each file, calls how often each was stored
+	read  map[string]string
+	calls map[string]int
+}
+
+func newStore() *store {
+	return &store{read: make(map[string]string), calls: make(map[string]int)}
+}
+
+func (s *store) Store(_ context.Context, file importer.File, content io.Reader) (int64, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read[file.Name] = string(data)
+	s.calls[file.Name]++
+
+	size := int64(len(data))
+	if (file.Size >= 0 && size != file.Size) || size > file.MaxSize {
+		return 0, importer.RejectedError{Reason: "wrong size"}
+	}
+	return size, nil
+}
+
+// runImport starts an import on a running pool and returns its state once its job is done
+func runImport(t *testing.T, db *db_access_mocks.DbAccess, cfg importer.Config, spec importer.SourceSpec) db_access.Import {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db.EXPECT().FailUnfinishedJobs(mock.Anything).Return(nil).Once()
+	db.EXPECT().AddImport(mock.Anything).Return(nil).Once()
+	db.EXPECT().AddJob(mock.Anything).Return(nil).Once()
+
+	done := make(chan struct{})
+	db.EXPECT().UpdateJob(mock.Anything).RunAndReturn(func(job *db_access.Job) error {
+		if job.Status == db_access.JobFinished || job.Status == db_access.JobFailed {
+			close(done)
+		}
+		return nil
+	})
+
+	var mu sync.Mutex
+	var last db_access.Import
+	db.EXPECT().UpdateImport(mock.Anything).RunAndReturn(func(imp *db_access.Import) error {
+		mu.Lock()
+		defer mu.Unlock()
+		last = *imp
+		return nil
+	})
+
+	cfg.Jobs = jobs.New(db, jobs.Config{}, slogext.NewDiscardLogger())
+	go cfg.Jobs.Run(ctx)
+
+	if cfg.Store == nil {
+		cfg.Store = newStore()
+	}
+
+	imp := importer.New(db, cfg, slogext.NewDiscardLogger())
+	_, err := imp.Start(ownerId, spec)
+	require.NoError(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("import did not finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return last
+}
+
+func TestImport_RemoteSources(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec func(ts *httptest.Server) importer.SourceSpec
+		ts   func(t *testing.T) *httptest.Server
+	}{
+		{
+			name: "WebDAV",
+			ts:   newWebDAVServer,
+			spec: func(ts *httptest.Server) importer.SourceSpec {
+				return importer.SourceSpec{Type: importer.SourceWebDAV, WebDAV: &importer.WebDAVSpec{Url: ts.URL}}
+			},
+		},
+		{
+			name: "S3",
+			ts:   newS3Server,
+			spec: func(ts *httptest.Server) importer.SourceSpec {
+				return importer.SourceSpec{Type: importer.SourceS3, S3: &importer.S3Spec{Endpoint: ts.URL, Bucket: "bucket"}}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("Imported", func(t *testing.T) {
+				ts := tc.ts(t)
+				s := newStore()
+				imp := runImport(t, db_access_mocks.NewDbAccess(t), importer.Config{
+					Store:       s,
+					MaxFileSize: 16,
+					Client:      ts.Client(),
+				}, tc.spec(ts))
+
+				assert.Equal(t, db_access.ImportFinished, imp.Status)
+				assert.Equal(t, int64(2), imp.TotalFiles)
+				assert.Equal(t, int64(1), imp.ImportedFiles)
+				assert.Equal(t, int64(len(remote["a.txt"])), imp.ImportedBytes)
+				assert.Equal(t, remote["a.txt"], s.read["a.txt"])
+
+				// big.txt is cut off a byte past the max size instead of being read to the end,
+				// and isn't tried again once turned down
+				assert.Equal(t, int64(1), imp.FailedFiles)
+				assert.Len(t, s.read["big.txt"], 17)
+				assert.Equal(t, 1, s.calls["big.txt"])
+			})
+
+			t.Run("Loopback refused", func(t *testing.T) {
+				var requests atomic.Int32
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests.Add(1)
+				}))
+				defer ts.Close()
+
+				db := db_access_mocks.NewDbAccess(t)
+				imp := runImport(t, db, importer.Config{MaxFileSize: 16}, tc.spec(ts))
+
+				assert.Equal(t, db_access.ImportFailed, imp.Status)
+				assert.Equal(t, "Could not list source", imp.Error)
+				assert.Zero(t, requests.Load())
+			})
+		})
+	}
+}
+
+func TestImport_UrlLoopbackRefused(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer ts.Close()
+
+	db := db_access_mocks.NewDbAccess(t)
+	imp := runImport(t, db, importer.Config{
+		MaxFileSize: 16,
+		UrlHosts:    safehttp.Hosts{"*"},
+	}, importer.SourceSpec{Type: importer.SourceUrl, Url: &importer.UrlSpec{Url: ts.URL + "/a.txt"}})
+
+	assert.Equal(t, db_access.ImportFinished, imp.Status)
+	assert.Equal(t, int64(1), imp.FailedFiles)
+	assert.Zero(t, requests.Load())
+}
@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/></d:prop></d:propfind>`
+
+// guards against servers that return collections pointing back at their ancestors
+const maxWebDAVDepth = 32
+
+type webDAVSource struct {
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength *int64 `xml:"getcontentlength"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func newWebDAVSource(spec *WebDAVSpec, client *http.Client) (*webDAVSource, error) {
+	if spec == nil || spec.Url == "" {
+		return nil, InvalidSourceError{Reason: "url is required"}
+	}
+
+	base, err := url.Parse(spec.Url)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, InvalidSourceError{Reason: "url must be http or https"}
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	return &webDAVSource{
+		base:     base,
+		username: spec.Username,
+		password: spec.Password,
+		client:   client,
+	}, nil
+}
+
+func (s *webDAVSource) newRequest(ctx context.Context, method string, u *url.URL, body io.Reader) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.username != "" {
+		r.SetBasicAuth(s.username, s.password)
+	}
+
+	return r, nil
+}
+
+func (s *webDAVSource) List(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	visited := make(map[string]struct{})
+
+	var walk func(dir *url.URL, depth int) error
+	walk = func(dir *url.URL, depth int) error {
+		if depth > maxWebDAVDepth {
+			return fmt.Errorf("collection tree is deeper than %d", maxWebDAVDepth)
+		}
+		visited[dir.Path] = struct{}{}
+
+		children, err := s.propfind(ctx, dir)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			if _, ok := visited[child.url.Path]; ok {
+				continue
+			}
+
+			if child.collection {
+				if err := walk(child.url, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+
+			rel := strings.TrimPrefix(child.url.Path, s.base.Path)
+			entries = append(entries, Entry{Path: rel, Size: child.size})
+		}
+
+		return nil
+	}
+
+	if err := walk(s.base, 0); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+type webDAVChild struct {
+	url        *url.URL
+	collection bool
+	size       int64
+}
+
+func (s *webDAVSource) propfind(ctx context.Context, dir *url.URL) ([]webDAVChild, error) {
+	r, err := s.newRequest(ctx, "PROPFIND", dir, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	r.Header.Set("Depth", "1")
+	r.Header.Set("Content-Type", "application/xml")
+
+	resp, err := s.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("propfind %s: %w", dir.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("propfind %s: unexpected status %d", dir.Path, resp.StatusCode)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("propfind %s: decode: %w", dir.Path, err)
+	}
+
+	children := make([]webDAVChild, 0, len(ms.Responses))
+	for _, response := range ms.Responses {
+		href, err := url.Parse(response.Href)
+		if err != nil {
+			continue
+		}
+
+		child := dir.ResolveReference(href)
+		// only follow entries on the same server and below the import root
+		if child.Host != s.base.Host || !strings.HasPrefix(child.Path, s.base.Path) {
+			continue
+		}
+
+		if path.Clean(child.Path) == path.Clean(dir.Path) {
+			continue
+		}
+
+		for _, propstat := range response.Propstat {
+			if !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+
+			size := int64(-1)
+			if propstat.Prop.ContentLength != nil {
+				size = *propstat.Prop.ContentLength
+			}
+
+			children = append(children, webDAVChild{
+				url:        child,
+				collection: propstat.Prop.ResourceType.Collection != nil,
+				size:       size,
+			})
+			break
+		}
+	}
+
+	return children, nil
+}
+
+func (s *webDAVSource) Open(ctx context.Context, entry Entry) (io.ReadCloser, error) {
+	u := *s.base
+	u.Path = s.base.Path + entry.Path
+
+	r, err := s.newRequest(ctx, http.MethodGet, &u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := s.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", entry.Path, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("get %s: unexpected status %d", entry.Path, resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
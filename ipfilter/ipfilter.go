@@ -0,0 +1,74 @@
+// Package ipfilter decides which client addresses may reach the api, by networks allowed and denied
+// globally in the config file and for single users and devices by admins.
+package ipfilter
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/utils/realip"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// Rules deny the networks in Deny and, unless Allow is empty, everything outside of Allow
+type Rules struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+// Parse accepts networks in CIDR notation and single addresses
+func Parse(allow []string, deny []string) (Rules, error) {
+	const op = "ipfilter.Parse"
+
+	allowed, err := realip.ParseProxies(allow)
+	if err != nil {
+		return Rules{}, fmt.Errorf("%s: allow: %w", op, err)
+	}
+	denied, err := realip.ParseProxies(deny)
+	if err != nil {
+		return Rules{}, fmt.Errorf("%s: deny: %w", op, err)
+	}
+
+	return Rules{Allow: allowed, Deny: denied}, nil
+}
+
+// FromDb parses rules that admins stored
+func FromDb(rules db_access.NetworkRules) (Rules, error) {
+	return Parse(rules.Allow, rules.Deny)
+}
+
+func (r Rules) Empty() bool {
+	return len(r.Allow) == 0 && len(r.Deny) == 0
+}
+
+// Permits reports whether the address, with or without a port, may pass; addresses that can't be
+// parsed only pass empty rules
+func (r Rules) Permits(addr string) bool {
+	if r.Empty() {
+		return true
+	}
+
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	parsed, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	parsed = parsed.Unmap()
+
+	for _, prefix := range r.Deny {
+		if prefix.Contains(parsed) {
+			return false
+		}
+	}
+	if len(r.Allow) == 0 {
+		return true
+	}
+	for _, prefix := range r.Allow {
+		if prefix.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
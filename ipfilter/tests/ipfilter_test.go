@@ -0,0 +1,46 @@
+package ipfilter_test
+
+import (
+	"cloud-storage/ipfilter"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermits(t *testing.T) {
+	office, err := ipfilter.Parse([]string{"10.0.0.0/8", "2001:db8::/32"}, []string{"10.0.66.0/24"})
+	require.NoError(t, err)
+	blocked, err := ipfilter.Parse(nil, []string{"192.0.2.1"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		rules ipfilter.Rules
+		addr  string
+		want  bool
+	}{
+		{name: "empty rules", rules: ipfilter.Rules{}, addr: "garbage", want: true},
+		{name: "allowed", rules: office, addr: "10.1.2.3:443", want: true},
+		{name: "allowed v6", rules: office, addr: "[2001:db8::1]:443", want: true},
+		{name: "mapped v4", rules: office, addr: "::ffff:10.1.2.3", want: true},
+		{name: "denied inside allowed", rules: office, addr: "10.0.66.7", want: false},
+		{name: "outside allowed", rules: office, addr: "198.51.100.1:443", want: false},
+		{name: "unparsable", rules: office, addr: "garbage", want: false},
+		{name: "denied only", rules: blocked, addr: "192.0.2.1:80", want: false},
+		{name: "not denied", rules: blocked, addr: "192.0.2.2:80", want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.rules.Permits(tc.addr))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := ipfilter.Parse([]string{"10.0.0.0/33"}, nil)
+	assert.Error(t, err)
+	_, err = ipfilter.Parse(nil, []string{"office"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,236 @@
+// Package jobs runs long operations in the background and keeps their state in the db,
+// so that clients follow any of them the same way, whatever kind of work they are.
+package jobs
+
+import (
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// types of the jobs of the server
+const (
+	TypeImport    = "import"
+	TypeFromUrl   = "from-url"
+	TypeExport    = "export"
+	TypeMigration = "migration"
+	TypeRewrap    = "rewrap"
+)
+
+const queueSize = 64
+
+// saveInterval is how often progress is written to the db at most
+const saveInterval = time.Second
+
+var ErrQueueFull = errors.New("job queue is full")
+
+// Failure is an error a job fails with that is fit to show to its owner;
+// jobs failing with any other error only report that they failed
+type Failure string
+
+func (f Failure) Error() string {
+	return string(f)
+}
+
+type Config struct {
+	// Workers is how many submitted jobs run at the same time
+	Workers int
+}
+
+// Func does the work of a job, reporting its progress through r. The result is stored as JSON.
+type Func func(ctx context.Context, r *Reporter) (any, error)
+
+// Pool runs submitted jobs on a few workers and keeps the state of those and of tracked jobs
+type Pool struct {
+	db    db_access.JobRepo
+	cfg   Config
+	queue chan task
+	log   *slog.Logger
+}
+
+type task struct {
+	r  *Reporter
+	fn Func
+}
+
+func New(db db_access.JobRepo, cfg Config, log *slog.Logger) *Pool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+
+	return &Pool{
+		db:    db,
+		cfg:   cfg,
+		queue: make(chan task, queueSize),
+		log:   log.With(slog.String("component", "jobs")),
+	}
+}
+
+// Run fails the jobs a restart interrupted, then starts the workers and blocks until ctx is done
+func (p *Pool) Run(ctx context.Context) {
+	if err := p.db.FailUnfinishedJobs("Interrupted by server restart"); err != nil {
+		p.log.Error("Could not fail unfinished jobs", slogext.Error(err))
+	}
+
+	for range p.cfg.Workers {
+		go p.worker(ctx)
+	}
+
+	<-ctx.Done()
+}
+
+// Track registers a job that its caller runs itself, reporting through the returned Reporter.
+// The job gets a generated id unless it has one.
+func (p *Pool) Track(job db_access.Job) (*Reporter, error) {
+	const op = "jobs.Pool.Track"
+
+	if job.Id == "" {
+		job.Id = uuid.New().String()
+	}
+	now := db_access.Time(time.Now())
+	job.Status = db_access.JobQueued
+	job.CreationTime = now
+	job.UpdateTime = now
+
+	if err := p.db.AddJob(&job); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Reporter{
+		db:  p.db,
+		job: job,
+		log: p.log.With(slog.String("job-id", job.Id), slog.String("job-type", job.Type)),
+	}, nil
+}
+
+// Submit registers a job and queues fn to run on a worker. With too many jobs waiting
+// the job is registered as failed and ErrQueueFull returned.
+func (p *Pool) Submit(job db_access.Job, fn Func) (db_access.Job, error) {
+	const op = "jobs.Pool.Submit"
+
+	r, err := p.Track(job)
+	if err != nil {
+		return db_access.Job{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	select {
+	case p.queue <- task{r: r, fn: fn}:
+	default:
+		r.Finish(nil, Failure(ErrQueueFull.Error()))
+		return db_access.Job{}, fmt.Errorf("%s: %w", op, ErrQueueFull)
+	}
+
+	return r.Job(), nil
+}
+
+func (p *Pool) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-p.queue:
+			t.r.Start()
+			result, err := t.fn(ctx, t.r)
+			if err != nil && ctx.Err() != nil {
+				err = Failure("Interrupted by server shutdown")
+			}
+			t.r.Finish(result, err)
+		}
+	}
+}
+
+// Reporter keeps the state of one job in the db as it runs
+type Reporter struct {
+	db  db_access.JobRepo
+	log *slog.Logger
+
+	mu    sync.Mutex
+	job   db_access.Job
+	saved time.Time
+}
+
+func (r *Reporter) Id() string {
+	return r.job.Id
+}
+
+// Job returns the state of the job as last reported
+func (r *Reporter) Job() db_access.Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.job
+}
+
+func (r *Reporter) Start() {
+	r.update(true, func(job *db_access.Job) {
+		job.Status = db_access.JobRunning
+	})
+}
+
+// SetTotal tells how many units of work the job has, once it knows
+func (r *Reporter) SetTotal(total int64) {
+	r.update(true, func(job *db_access.Job) {
+		job.Total = total
+	})
+}
+
+// Add counts n more units of work as done; the count reaches the db every saveInterval
+func (r *Reporter) Add(n int64) {
+	r.update(false, func(job *db_access.Job) {
+		job.Done += n
+	})
+}
+
+// Finish ends the job with the result, or as failed if err isn't nil
+func (r *Reporter) Finish(result any, err error) {
+	var data []byte
+	if err == nil && result != nil {
+		var merr error
+		if data, merr = json.Marshal(result); merr != nil {
+			err = fmt.Errorf("marshal result: %w", merr)
+		}
+	}
+
+	var failure Failure
+	if err != nil && !errors.As(err, &failure) {
+		r.log.Error("Job failed", slogext.Error(err))
+		failure = "Job failed"
+	}
+
+	r.update(true, func(job *db_access.Job) {
+		if err != nil {
+			job.Status = db_access.JobFailed
+			job.Error = string(failure)
+		} else {
+			job.Status = db_access.JobFinished
+			job.Result = string(data)
+		}
+	})
+}
+
+func (r *Reporter) update(force bool, change func(job *db_access.Job)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(&r.job)
+
+	now := time.Now()
+	if !force && now.Sub(r.saved) < saveInterval {
+		return
+	}
+
+	r.job.UpdateTime = db_access.Time(now)
+	if err := r.db.UpdateJob(&r.job); err != nil {
+		r.log.Error("Could not update job", slogext.Error(err))
+		return
+	}
+	r.saved = now
+}
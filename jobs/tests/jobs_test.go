@@ -0,0 +1,124 @@
+package jobs_test
+
+import (
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/jobs"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordJobs keeps the last state saved of every job
+func recordJobs(db *db_access_mocks.DbAccess) func(id string) db_access.Job {
+	var mu sync.Mutex
+	saved := make(map[string]db_access.Job)
+	save := func(job *db_access.Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		saved[job.Id] = *job
+		return nil
+	}
+
+	db.EXPECT().AddJob(mock.Anything).RunAndReturn(save).Maybe()
+	db.EXPECT().UpdateJob(mock.Anything).RunAndReturn(save).Maybe()
+
+	return func(id string) db_access.Job {
+		mu.Lock()
+		defer mu.Unlock()
+		return saved[id]
+	}
+}
+
+func TestPool_Submit(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status db_access.JobStatus
+		result string
+		reason string
+	}{
+		{name: "Finished", status: db_access.JobFinished, result: `{"count":3}`},
+		{name: "Failure", err: jobs.Failure("Could not list source"), status: db_access.JobFailed, reason: "Could not list source"},
+		{name: "Internal error", err: errors.New("disk on fire"), status: db_access.JobFailed, reason: "Job failed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := db_access_mocks.NewDbAccess(t)
+			saved := recordJobs(db)
+			db.EXPECT().FailUnfinishedJobs(mock.Anything).Return(nil).Once()
+
+			pool := jobs.New(db, jobs.Config{Workers: 2}, slogext.NewDiscardLogger())
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go pool.Run(ctx)
+
+			job, err := pool.Submit(db_access.Job{Type: jobs.TypeExport, OwnerId: 7}, func(_ context.Context, r *jobs.Reporter) (any, error) {
+				assert.Equal(t, db_access.JobRunning, saved(r.Id()).Status)
+				r.SetTotal(3)
+				for range 3 {
+					r.Add(1)
+				}
+				return map[string]int{"count": 3}, tc.err
+			})
+			require.NoError(t, err)
+			assert.NotEmpty(t, job.Id)
+			assert.Equal(t, db_access.JobQueued, job.Status)
+
+			require.Eventually(t, func() bool {
+				return saved(job.Id).Status == tc.status
+			}, 5*time.Second, 10*time.Millisecond)
+
+			finished := saved(job.Id)
+			assert.Equal(t, jobs.TypeExport, finished.Type)
+			assert.Equal(t, int64(7), finished.OwnerId)
+			assert.Equal(t, int64(3), finished.Done)
+			assert.Equal(t, int64(3), finished.Total)
+			assert.Equal(t, tc.result, finished.Result)
+			assert.Equal(t, tc.reason, finished.Error)
+		})
+	}
+}
+
+func TestPool_SubmitQueueFull(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	saved := recordJobs(db)
+
+	// without Run nothing takes jobs off the queue
+	pool := jobs.New(db, jobs.Config{}, slogext.NewDiscardLogger())
+	noop := func(context.Context, *jobs.Reporter) (any, error) { return nil, nil }
+
+	var err error
+	for i := 0; err == nil; i++ {
+		require.Less(t, i, 1000)
+		_, err = pool.Submit(db_access.Job{Id: "last", Type: jobs.TypeImport}, noop)
+	}
+	assert.ErrorIs(t, err, jobs.ErrQueueFull)
+	assert.Equal(t, db_access.JobFailed, saved("last").Status)
+	assert.Equal(t, jobs.ErrQueueFull.Error(), saved("last").Error)
+}
+
+func TestPool_Track(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	saved := recordJobs(db)
+
+	pool := jobs.New(db, jobs.Config{}, slogext.NewDiscardLogger())
+	r, err := pool.Track(db_access.Job{Id: "migration", Type: jobs.TypeMigration})
+	require.NoError(t, err)
+	assert.Equal(t, db_access.JobQueued, saved("migration").Status)
+
+	r.Start()
+	assert.Equal(t, db_access.JobRunning, saved("migration").Status)
+
+	r.Finish(nil, nil)
+	assert.Equal(t, db_access.JobFinished, saved("migration").Status)
+	assert.Empty(t, saved("migration").Result)
+}
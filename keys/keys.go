@@ -0,0 +1,238 @@
+// Package keys prunes data encryption keys that no blob is encrypted with anymore,
+// and rewraps keys and file names after the vault transit key rotates.
+// The db doesn't know which key a blob uses, every encrypted file starts with the id of its key,
+// so finding the keys in use means reading the beginning of every blob, stream segment and export.
+package keys
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/storage"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Config struct {
+	// Blobs are scanned on every backend; derived data is scanned in the storage dir
+	Blobs *blobstore.Store
+	// RetiredFor is how long a newer key has to be in use before an unreferenced key may go.
+	// Writes pick the newest key when they start, so it must be longer than any write takes.
+	RetiredFor time.Duration
+}
+
+type KeyStatus struct {
+	Id           db_access.DecId
+	CreationTime time.Time
+	// OwnerId is the user whose contents the key encrypts, 0 for a shared key
+	OwnerId int64
+	// RetiredAt is when a newer key of the same owner took over, zero for the key in use
+	RetiredAt time.Time
+	// References counts the scanned files encrypted with the key and the file names sealed with it
+	References int
+	// KeyVersion is the version of the vault transit key the key is wrapped with
+	KeyVersion int64
+	Prunable   bool
+}
+
+type Report struct {
+	Keys []KeyStatus
+	// Scanned counts the encrypted files whose key was read
+	Scanned int
+	// NameKeyVersions counts the file names wrapped with each version of the vault transit key
+	NameKeyVersions []db_access.KeyVersionCount
+}
+
+func (r Report) Prunable() []db_access.DecId {
+	var ids []db_access.DecId
+	for _, key := range r.Keys {
+		if key.Prunable {
+			ids = append(ids, key.Id)
+		}
+	}
+	return ids
+}
+
+type Pruner struct {
+	db  db_access.DbAccess
+	cfg Config
+	log *slog.Logger
+}
+
+func New(db db_access.DbAccess, cfg Config, log *slog.Logger) *Pruner {
+	return &Pruner{
+		db:  db,
+		cfg: cfg,
+		log: log.With(slog.String("component", "keys")),
+	}
+}
+
+// Check reads the key ids of everything encrypted and reports which keys are prunable as of now
+func (p *Pruner) Check(ctx context.Context, now time.Time) (Report, error) {
+	const op = "keys.Pruner.Check"
+
+	// keys are listed before scanning, so that a key added meanwhile isn't taken for unreferenced
+	decs, err := p.db.GetDECs()
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	references := make(map[db_access.DecId]int)
+	scanned := 0
+	count := func(id db_access.DecId) {
+		references[id]++
+		scanned++
+	}
+
+	if err := p.scanDir(count); err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := p.scanRemotes(ctx, count); err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	names, err := p.db.CountNamesByDEC()
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+	for id, n := range names {
+		references[id] += n
+	}
+
+	nameKeyVersions, err := p.db.CountNamesByKeyVersion()
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// each user has keys of their own in use besides the shared ones, so a key is only retired by the next one of its owner
+	retiredAt := make([]time.Time, len(decs))
+	next := make(map[int64]time.Time)
+	for i := len(decs) - 1; i >= 0; i-- {
+		retiredAt[i] = next[decs[i].OwnerId]
+		next[decs[i].OwnerId] = time.Time(decs[i].CreationTime)
+	}
+
+	report := Report{Keys: make([]KeyStatus, 0, len(decs)), Scanned: scanned, NameKeyVersions: nameKeyVersions}
+	for i, dec := range decs {
+		key := KeyStatus{
+			Id:           dec.Id,
+			CreationTime: time.Time(dec.CreationTime),
+			OwnerId:      dec.OwnerId,
+			RetiredAt:    retiredAt[i],
+			References:   references[dec.Id],
+			KeyVersion:   dec.KeyVersion,
+		}
+		if !key.RetiredAt.IsZero() {
+			key.Prunable = key.References == 0 && now.Sub(key.RetiredAt) >= p.cfg.RetiredFor
+		}
+		report.Keys = append(report.Keys, key)
+	}
+
+	return report, nil
+}
+
+// Prune removes the keys Check finds prunable
+func (p *Pruner) Prune(ctx context.Context, now time.Time) (Report, error) {
+	const op = "keys.Pruner.Prune"
+
+	report, err := p.Check(ctx, now)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, id := range report.Prunable() {
+		if err := p.db.RemoveDEC(id); err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+		p.log.Info("Removed unreferenced key", slog.Int64("id", int64(id)))
+	}
+
+	return report, nil
+}
+
+// scanDir reads local blobs along with hls streams and exports; writes in progress are in the temp dir
+// and use the newest key, which is never pruned
+func (p *Pruner) scanDir(count func(db_access.DecId)) error {
+	dir := p.cfg.Blobs.Dir()
+
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path == filepath.Join(dir, storage.TempDirName) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || path == filepath.Join(dir, storage.JournalName) {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// removed since the dir was read
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("os.Open: %w", err)
+		}
+		defer file.Close()
+
+		return readKeyId(file, path, count)
+	})
+}
+
+// scanRemotes reads the blobs the db has on remote backends
+func (p *Pruner) scanRemotes(ctx context.Context, count func(db_access.DecId)) error {
+	for _, backend := range p.cfg.Blobs.Names() {
+		if backend == blobstore.Local {
+			continue
+		}
+
+		blobs, err := p.db.GetBlobs(backend, db_access.BlobFilter{})
+		if err != nil {
+			return err
+		}
+
+		for _, blob := range blobs {
+			obj, err := p.cfg.Blobs.Open(ctx, backend, blob.Name)
+			if errors.Is(err, fs.ErrNotExist) {
+				// deleted or moved back since the db was asked
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			err = readKeyId(obj, backend+"/"+blob.Name, count)
+			obj.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func readKeyId(r io.Reader, name string, count func(db_access.DecId)) error {
+	id := make([]byte, 8)
+	if _, err := io.ReadFull(r, id); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// too short to be encrypted
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("read key id of %s: %w", name, err)
+	}
+
+	count(db_access.DecId(binary.LittleEndian.Uint64(id)))
+	return nil
+}
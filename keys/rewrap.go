@@ -0,0 +1,170 @@
+package keys
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/jobs"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// rewrapBatch is how many file names are read at a time
+const rewrapBatch = 100
+
+// Outdated counts the DECs and file names wrapped with versions of the transit key older than the latest
+type Outdated struct {
+	LatestVersion int64
+	Keys          int64
+	Names         int64
+}
+
+type RewrapResult struct {
+	KeyVersion int64 `json:"key_version"`
+	Keys       int   `json:"rewrapped_keys"`
+	Names      int   `json:"rewrapped_names"`
+}
+
+// Rewrapper moves DECs and file names onto the latest version of the vault transit key after it rotates,
+// so that min_decryption_version can then be raised past the old versions. Only what the db records
+// as wrapped with an older version is sent to vault.
+type Rewrapper struct {
+	db  db_access.KeyRepo
+	rs  encryption.RewrapService
+	log *slog.Logger
+
+	running atomic.Bool
+}
+
+func NewRewrapper(db db_access.KeyRepo, rs encryption.RewrapService, log *slog.Logger) *Rewrapper {
+	return &Rewrapper{
+		db:  db,
+		rs:  rs,
+		log: log.With(slog.String("component", "rewrap")),
+	}
+}
+
+// Running reports whether a rewrap is in progress
+func (rw *Rewrapper) Running() bool {
+	return rw.running.Load()
+}
+
+func (rw *Rewrapper) Outdated() (Outdated, error) {
+	const op = "keys.Rewrapper.Outdated"
+
+	latest, err := rw.rs.LatestKeyVersion()
+	if err != nil {
+		return Outdated{}, fmt.Errorf("%s: %w", op, err)
+	}
+	outdated := Outdated{LatestVersion: latest}
+
+	decs, err := rw.db.GetDECs()
+	if err != nil {
+		return Outdated{}, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, dec := range decs {
+		if dec.KeyVersion > 0 && dec.KeyVersion < latest {
+			outdated.Keys++
+		}
+	}
+
+	counts, err := rw.db.CountNamesByKeyVersion()
+	if err != nil {
+		return Outdated{}, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, count := range counts {
+		if count.KeyVersion < latest {
+			outdated.Names += count.Count
+		}
+	}
+
+	return outdated, nil
+}
+
+// Run rewraps everything Outdated counts; it is a jobs.Func. Names renamed or files deleted meanwhile
+// are left alone, as their new names are wrapped with the latest version anyway.
+func (rw *Rewrapper) Run(ctx context.Context, r *jobs.Reporter) (any, error) {
+	const op = "keys.Rewrapper.Run"
+
+	if !rw.running.CompareAndSwap(false, true) {
+		return nil, jobs.Failure("Another rewrap is in progress")
+	}
+	defer rw.running.Store(false)
+
+	outdated, err := rw.Outdated()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	r.SetTotal(outdated.Keys + outdated.Names)
+	result := RewrapResult{KeyVersion: outdated.LatestVersion}
+
+	decs, err := rw.db.GetDECs()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, dec := range decs {
+		if dec.KeyVersion == 0 || dec.KeyVersion >= outdated.LatestVersion {
+			continue
+		}
+
+		response, err := rw.rs.MakeRewrapRequest([]byte(dec.Value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %d: %w", op, dec.Id, err)
+		}
+
+		err = rw.db.RewrapDEC(dec.Id, response.Ciphertext, response.KeyVersion)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			// pruned meanwhile
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		} else {
+			result.Keys++
+		}
+		r.Add(1)
+	}
+
+	after := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		names, err := rw.db.GetNamesBelowKeyVersion(outdated.LatestVersion, after, rewrapBatch)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if len(names) == 0 {
+			break
+		}
+
+		for _, name := range names {
+			response, err := rw.rs.MakeRewrapRequest([]byte(name.FileName))
+			if err != nil {
+				return nil, fmt.Errorf("%s: name of %s: %w", op, name.GeneratedName, err)
+			}
+
+			err = rw.db.RewrapFileName(name.GeneratedName, name.FileName, response.Ciphertext)
+			var nre db_access.NoRowsError
+			if errors.As(err, &nre) {
+				// renamed or deleted meanwhile
+			} else if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			} else {
+				result.Names++
+			}
+			r.Add(1)
+		}
+		after = names[len(names)-1].GeneratedName
+	}
+
+	rw.log.Info(
+		"Rewrapped with the latest key version",
+		slog.Int64("key-version", result.KeyVersion),
+		slog.Int("keys", result.Keys),
+		slog.Int("names", result.Names),
+	)
+	return result, nil
+}
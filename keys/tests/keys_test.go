@@ -0,0 +1,109 @@
+package keys_test
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"cloud-storage/keys"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEncrypted(t *testing.T, path string, id db_access.DecId) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+
+	content := binary.LittleEndian.AppendUint64(nil, uint64(id))
+	require.NoError(t, os.WriteFile(path, append(content, "ciphertext"...), 0o600))
+}
+
+func TestPrune(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	now := time.Now().Truncate(time.Second)
+	var ids []db_access.DecId
+	for _, age := range []time.Duration{100 * time.Hour, 90 * time.Hour, 80 * time.Hour, time.Hour} {
+		dec := db_access.DEC{Value: "wrapped", CreationTime: db_access.Time(now.Add(-age))}
+		require.NoError(t, db.AddDEC(&dec))
+		ids = append(ids, dec.Id)
+	}
+
+	writeEncrypted(t, filepath.Join(dir, "blob"), ids[3])
+	// derived data counts as much as blobs
+	writeEncrypted(t, filepath.Join(dir, ".hls", "blob", "seg00000.ts"), ids[1])
+	// what is in the temp dir is still being written with the newest key
+	writeEncrypted(t, filepath.Join(dir, storage.TempDirName, "upload"), ids[0])
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "short"), []byte("abc"), 0o600))
+	// so is a file name sealed with a key
+	require.NoError(t, db.AddFile("named", fmt.Sprintf("dec:%d:sealed", ids[2]), 1, 1))
+
+	p := keys.New(db, keys.Config{
+		Blobs:      blobstore.NewStore(dir, storage.DurabilityNone, nil),
+		RetiredFor: 24 * time.Hour,
+	}, slogext.NewDiscardLogger())
+
+	report, err := p.Check(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Scanned)
+	require.Equal(t, 4, len(report.Keys))
+
+	// the third key was retired too recently and the newest one is in use
+	assert.Equal(t, []db_access.DecId{ids[0]}, report.Prunable())
+	assert.Equal(t, 1, report.Keys[1].References)
+	assert.Equal(t, 1, report.Keys[2].References)
+	assert.Equal(t, now.Add(-90*time.Hour), report.Keys[0].RetiredAt)
+	assert.True(t, report.Keys[3].RetiredAt.IsZero())
+
+	report, err = p.Prune(context.Background(), now.Add(100*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []db_access.DecId{ids[0]}, report.Prunable())
+
+	decs, err := db.GetDECs()
+	require.NoError(t, err)
+	require.Equal(t, 3, len(decs))
+	assert.Equal(t, ids[1], decs[0].Id)
+	assert.Equal(t, ids[2], decs[1].Id)
+}
+
+func TestCheck_UserKeys(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	var ids []db_access.DecId
+	for _, key := range []struct {
+		owner int64
+		age   time.Duration
+	}{{0, 100 * time.Hour}, {1, 90 * time.Hour}, {2, 80 * time.Hour}, {1, 70 * time.Hour}} {
+		dec := db_access.DEC{Value: "wrapped", CreationTime: db_access.Time(now.Add(-key.age)), OwnerId: key.owner}
+		require.NoError(t, db.AddDEC(&dec))
+		ids = append(ids, dec.Id)
+	}
+
+	p := keys.New(db, keys.Config{
+		Blobs:      blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil),
+		RetiredFor: 24 * time.Hour,
+	}, slogext.NewDiscardLogger())
+
+	report, err := p.Check(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(report.Keys))
+
+	// the keys of users don't take over from the shared one or from each other
+	assert.Equal(t, []db_access.DecId{ids[1]}, report.Prunable())
+	assert.Equal(t, int64(1), report.Keys[1].OwnerId)
+	assert.Equal(t, now.Add(-70*time.Hour), report.Keys[1].RetiredAt)
+	assert.True(t, report.Keys[0].RetiredAt.IsZero())
+	assert.True(t, report.Keys[2].RetiredAt.IsZero())
+}
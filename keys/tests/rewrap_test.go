@@ -0,0 +1,74 @@
+package keys_test
+
+import (
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/encryption"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/jobs"
+	"cloud-storage/keys"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrap(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	rs := encryption_mocks.NewRewrapService(t)
+
+	var saved db_access.Job
+	save := func(job *db_access.Job) error {
+		saved = *job
+		return nil
+	}
+	db.EXPECT().AddJob(mock.Anything).RunAndReturn(save)
+	db.EXPECT().UpdateJob(mock.Anything).RunAndReturn(save)
+
+	rs.EXPECT().LatestKeyVersion().Return(2, nil)
+	rs.EXPECT().MakeRewrapRequest(mock.Anything).RunAndReturn(func(ciphertext []byte) (encryption.EncryptResponse, error) {
+		return encryption.EncryptResponse{Ciphertext: strings.Replace(string(ciphertext), "vault:v1:", "vault:v2:", 1), KeyVersion: 2}, nil
+	})
+
+	db.EXPECT().GetDECs().Return([]db_access.DEC{
+		{Id: 1, Value: "vault:v1:old", KeyVersion: 1},
+		{Id: 2, Value: "vault:v1:pruned", KeyVersion: 1},
+		{Id: 3, Value: "vault:v2:new", KeyVersion: 2},
+	}, nil)
+	db.EXPECT().CountNamesByKeyVersion().Return([]db_access.KeyVersionCount{{KeyVersion: 1, Count: 2}, {KeyVersion: 2, Count: 5}}, nil)
+	db.EXPECT().RewrapDEC(db_access.DecId(1), "vault:v2:old", int64(2)).Return(nil).Once()
+	db.EXPECT().RewrapDEC(db_access.DecId(2), "vault:v2:pruned", int64(2)).Return(db_access.NoRowsError{Table: "decs"}).Once()
+
+	db.EXPECT().GetNamesBelowKeyVersion(int64(2), "", 100).Return([]db_access.WrappedName{
+		{GeneratedName: "a", FileName: "vault:v1:a"},
+		{GeneratedName: "b", FileName: "vault:v1:b"},
+	}, nil).Once()
+	db.EXPECT().GetNamesBelowKeyVersion(int64(2), "b", 100).Return(nil, nil).Once()
+	db.EXPECT().RewrapFileName("a", "vault:v1:a", "vault:v2:a").Return(nil).Once()
+	// renamed since it was read
+	db.EXPECT().RewrapFileName("b", "vault:v1:b", "vault:v2:b").Return(db_access.NoRowsError{Table: "files"}).Once()
+
+	rw := keys.NewRewrapper(db, rs, slogext.NewDiscardLogger())
+
+	outdated, err := rw.Outdated()
+	require.NoError(t, err)
+	assert.Equal(t, keys.Outdated{LatestVersion: 2, Keys: 2, Names: 2}, outdated)
+
+	pool := jobs.New(db, jobs.Config{}, slogext.NewDiscardLogger())
+	r, err := pool.Track(db_access.Job{Type: jobs.TypeRewrap})
+	require.NoError(t, err)
+
+	result, err := rw.Run(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, keys.RewrapResult{KeyVersion: 2, Keys: 1, Names: 1}, result)
+	assert.False(t, rw.Running())
+
+	r.Finish(result, nil)
+	assert.Equal(t, int64(4), saved.Total)
+	assert.Equal(t, int64(4), saved.Done)
+	assert.JSONEq(t, `{"key_version":2,"rewrapped_keys":1,"rewrapped_names":1}`, saved.Result)
+}
@@ -0,0 +1,171 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// just enough of BER (X.690) for the messages of RFC 4511 that a bind and a search need
+
+const (
+	tagBoolean     byte = 0x01
+	tagInteger     byte = 0x02
+	tagOctetString byte = 0x04
+	tagEnumerated  byte = 0x0a
+	tagSequence    byte = 0x30
+	tagSet         byte = 0x31
+)
+
+// responses bigger than this are not something a login needs to read
+const maxElementSize = 1 << 20
+
+var errMalformed = errors.New("ldap: malformed message")
+
+type element struct {
+	tag  byte
+	data []byte
+}
+
+func encode(tag byte, content ...[]byte) []byte {
+	n := 0
+	for _, c := range content {
+		n += len(c)
+	}
+
+	out := appendLength([]byte{tag}, n)
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func appendLength(b []byte, n int) []byte {
+	if n < 0x80 {
+		return append(b, byte(n))
+	}
+
+	var length []byte
+	for v := n; v > 0; v >>= 8 {
+		length = append([]byte{byte(v)}, length...)
+	}
+	b = append(b, 0x80|byte(len(length)))
+	return append(b, length...)
+}
+
+func encodeInt(tag byte, v int64) []byte {
+	// shortest two's complement, big endian
+	var content []byte
+	for {
+		content = append([]byte{byte(v)}, content...)
+		if v >= -0x80 && v < 0x80 {
+			break
+		}
+		v >>= 8
+	}
+	return encode(tag, content)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0x00})
+}
+
+func decodeInt(data []byte) (int64, error) {
+	if len(data) == 0 || len(data) > 8 {
+		return 0, errMalformed
+	}
+
+	// sign extend from the first byte
+	v := int64(int8(data[0]))
+	for _, b := range data[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// readElement reads one whole element from the connection
+func readElement(r *bufio.Reader) (element, error) {
+	const op = "ldap.readElement"
+
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	first, err := r.ReadByte()
+	if err != nil {
+		return element{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		// the indefinite form is not allowed in LDAP
+		if count == 0 || count > 4 {
+			return element{}, fmt.Errorf("%s: %w", op, errMalformed)
+		}
+
+		n = 0
+		for range count {
+			b, err := r.ReadByte()
+			if err != nil {
+				return element{}, fmt.Errorf("%s: %w", op, err)
+			}
+			n = n<<8 | int(b)
+		}
+	}
+	if n < 0 || n > maxElementSize {
+		return element{}, fmt.Errorf("%s: element of %d bytes is too big", op, n)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return element{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return element{tag: tag, data: data}, nil
+}
+
+// children splits the content of a constructed element
+func (e element) children() ([]element, error) {
+	var children []element
+
+	data := e.data
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errMalformed
+		}
+		tag, first := data[0], data[1]
+		data = data[2:]
+
+		n := int(first)
+		if first&0x80 != 0 {
+			count := int(first & 0x7f)
+			if count == 0 || count > 4 || len(data) < count {
+				return nil, errMalformed
+			}
+
+			n = 0
+			for _, b := range data[:count] {
+				n = n<<8 | int(b)
+			}
+			data = data[count:]
+		}
+		if n < 0 || n > len(data) {
+			return nil, errMalformed
+		}
+
+		children = append(children, element{tag: tag, data: data[:n]})
+		data = data[n:]
+	}
+
+	return children, nil
+}
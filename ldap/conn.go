@@ -0,0 +1,305 @@
+// Package ldap checks passwords against an LDAP directory or Active Directory. It speaks just the
+// part of the protocol a login needs: simple binds and equality searches.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// protocol operations, tagged [APPLICATION n]
+const (
+	opBindRequest     byte = 0x60
+	opBindResponse    byte = 0x61
+	opUnbindRequest   byte = 0x42
+	opSearchRequest   byte = 0x63
+	opSearchEntry     byte = 0x64
+	opSearchDone      byte = 0x65
+	opSearchReference byte = 0x73
+)
+
+const (
+	// context specific tags of the authentication and filter choices
+	tagSimpleAuth    byte = 0x80
+	tagEqualityMatch byte = 0xa3
+
+	protocolVersion   = 3
+	scopeWholeSubtree = 2
+	derefNever        = 0
+)
+
+// result codes of RFC 4511 4.1.9 that are told apart
+const (
+	ResultSuccess            = 0
+	resultSizeLimitExceeded  = 4
+	ResultInvalidCredentials = 49
+)
+
+// ResultError is a result code other than success
+type ResultError struct {
+	Code    int64
+	Message string
+}
+
+func (e ResultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Entry is a search result; attribute names are lower case
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+func (e Entry) Values(attribute string) []string {
+	return e.Attributes[strings.ToLower(attribute)]
+}
+
+// Conn is a connection to a directory server; operations run one at a time
+type Conn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	lastId int64
+}
+
+// Dial connects to an ldap:// or ldaps:// url. The connection gives up once timeout passes
+// from now, so one Conn serves a single login.
+func Dial(ctx context.Context, rawURL string, timeout time.Duration) (*Conn, error) {
+	const op = "ldap.Dial"
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var dialer interface {
+		DialContext(ctx context.Context, network string, address string) (net.Conn, error)
+	}
+	port := "389"
+	switch u.Scheme {
+	case "ldap":
+		dialer = &net.Dialer{}
+	case "ldaps":
+		port = "636"
+		dialer = &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+	default:
+		return nil, fmt.Errorf("%s: unsupported scheme %q", op, u.Scheme)
+	}
+
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	deadline := time.Now().Add(timeout)
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Conn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func (c *Conn) send(protocolOp []byte) (int64, error) {
+	c.lastId++
+	message := encode(tagSequence, encodeInt(tagInteger, c.lastId), protocolOp)
+	if _, err := c.conn.Write(message); err != nil {
+		return 0, err
+	}
+	return c.lastId, nil
+}
+
+// receive reads the protocol operation of the next response to the message id
+func (c *Conn) receive(id int64) (element, error) {
+	message, err := readElement(c.r)
+	if err != nil {
+		return element{}, err
+	}
+	if message.tag != tagSequence {
+		return element{}, errMalformed
+	}
+
+	parts, err := message.children()
+	if err != nil {
+		return element{}, err
+	}
+	if len(parts) < 2 || parts[0].tag != tagInteger {
+		return element{}, errMalformed
+	}
+
+	messageId, err := decodeInt(parts[0].data)
+	if err != nil {
+		return element{}, err
+	}
+	if messageId != id {
+		// id 0 is the server announcing it is about to hang up
+		return element{}, fmt.Errorf("ldap: response to message %d while waiting for %d", messageId, id)
+	}
+
+	return parts[1], nil
+}
+
+func parseResult(e element) error {
+	parts, err := e.children()
+	if err != nil {
+		return err
+	}
+	if len(parts) < 3 || parts[0].tag != tagEnumerated {
+		return errMalformed
+	}
+
+	code, err := decodeInt(parts[0].data)
+	if err != nil {
+		return err
+	}
+	if code != ResultSuccess {
+		return ResultError{Code: code, Message: string(parts[2].data)}
+	}
+
+	return nil
+}
+
+// Bind authenticates the connection with a simple bind. An empty password makes it an
+// unauthenticated bind, which servers accept without checking anything, so it is refused here.
+func (c *Conn) Bind(dn string, password string) error {
+	const op = "ldap.Conn.Bind"
+
+	if password == "" {
+		return fmt.Errorf("%s: %w", op, ResultError{Code: ResultInvalidCredentials, Message: "empty password"})
+	}
+
+	id, err := c.send(encode(opBindRequest,
+		encodeInt(tagInteger, protocolVersion),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := c.receive(id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.tag != opBindResponse {
+		return fmt.Errorf("%s: %w", op, errMalformed)
+	}
+	if err := parseResult(resp); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Search finds the entries under base whose attribute equals value. The value goes into
+// an equality filter as is, so it needs no escaping.
+func (c *Conn) Search(base string, attribute string, value string, attributes []string) ([]Entry, error) {
+	const op = "ldap.Conn.Search"
+
+	requested := make([][]byte, 0, len(attributes))
+	for _, a := range attributes {
+		requested = append(requested, encodeString(tagOctetString, a))
+	}
+
+	id, err := c.send(encode(opSearchRequest,
+		encodeString(tagOctetString, base),
+		encodeInt(tagEnumerated, scopeWholeSubtree),
+		encodeInt(tagEnumerated, derefNever),
+		// size limit; a login that matches more than one entry is refused anyway
+		encodeInt(tagInteger, 2),
+		// time limit in seconds
+		encodeInt(tagInteger, 10),
+		encodeBool(false),
+		encode(tagEqualityMatch, encodeString(tagOctetString, attribute), encodeString(tagOctetString, value)),
+		encode(tagSequence, requested...),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var entries []Entry
+	for {
+		resp, err := c.receive(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		switch resp.tag {
+		case opSearchEntry:
+			entry, err := parseEntry(resp)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			entries = append(entries, entry)
+		case opSearchReference:
+			// referrals to other servers are not followed
+		case opSearchDone:
+			if err := parseResult(resp); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("%s: %w", op, errMalformed)
+		}
+	}
+}
+
+func parseEntry(e element) (Entry, error) {
+	parts, err := e.children()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(parts) < 2 || parts[0].tag != tagOctetString {
+		return Entry{}, errMalformed
+	}
+
+	entry := Entry{DN: string(parts[0].data), Attributes: make(map[string][]string)}
+
+	attributes, err := parts[1].children()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, attribute := range attributes {
+		fields, err := attribute.children()
+		if err != nil {
+			return Entry{}, err
+		}
+		if len(fields) < 2 {
+			return Entry{}, errMalformed
+		}
+
+		values, err := fields[1].children()
+		if err != nil {
+			return Entry{}, err
+		}
+
+		name := strings.ToLower(string(fields[0].data))
+		for _, v := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(v.data))
+		}
+	}
+
+	return entry, nil
+}
+
+// Close unbinds, which needs no answer, and closes the connection
+func (c *Conn) Close() error {
+	c.send(encode(opUnbindRequest))
+	return c.conn.Close()
+}
@@ -0,0 +1,102 @@
+package ldap
+
+import (
+	"cloud-storage/auth"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// ldap://host[:389] or ldaps://host[:636]
+	URL string
+	// service account that searches for users; empty for an anonymous search
+	BindDN       string
+	BindPassword string
+	// subtree that users are searched in
+	BaseDN string
+	// attribute holding the login name, uid or sAMAccountName for Active Directory
+	UserAttribute string
+	// attribute listing the DNs of the groups of a user
+	GroupAttribute string
+	// group DN to role, e.g. "cn=storage-admins,ou=groups,dc=example,dc=com": "admin"
+	GroupRoles map[string]string
+	Timeout    time.Duration
+}
+
+// Directory is an auth.Directory backed by an LDAP server. Every login searches for the user
+// and then binds as them, so passwords are only ever checked by the server.
+type Directory struct {
+	cfg Config
+}
+
+func NewDirectory(cfg Config) *Directory {
+	if cfg.UserAttribute == "" {
+		cfg.UserAttribute = "uid"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Directory{cfg: cfg}
+}
+
+func (d *Directory) Authenticate(ctx context.Context, name string, password string) (auth.Identity, error) {
+	const op = "ldap.Directory.Authenticate"
+
+	if name == "" || password == "" {
+		return auth.Identity{}, auth.ErrInvalidCredentials
+	}
+
+	conn, err := Dial(ctx, d.cfg.URL, d.cfg.Timeout)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if d.cfg.BindDN != "" {
+		// a rejected service account is a config problem, not a wrong password of the user
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return auth.Identity{}, fmt.Errorf("%s: service account: %w", op, err)
+		}
+	}
+
+	entries, err := conn.Search(d.cfg.BaseDN, d.cfg.UserAttribute, name, []string{d.cfg.UserAttribute, d.cfg.GroupAttribute})
+	var re ResultError
+	if errors.As(err, &re) && re.Code == resultSizeLimitExceeded {
+		return auth.Identity{}, fmt.Errorf("%s: %q matches more than one entry: %w", op, name, auth.ErrInvalidCredentials)
+	} else if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(entries) != 1 {
+		return auth.Identity{}, fmt.Errorf("%s: %q matches %d entries: %w", op, name, len(entries), auth.ErrInvalidCredentials)
+	}
+	entry := entries[0]
+
+	if err := conn.Bind(entry.DN, password); errors.As(err, &re) && re.Code == ResultInvalidCredentials {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, auth.ErrInvalidCredentials)
+	} else if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	identity := auth.Identity{Name: name}
+	// directories compare names without case; the stored spelling keeps it to one local user
+	if names := entry.Values(d.cfg.UserAttribute); len(names) > 0 {
+		identity.Name = names[0]
+	}
+	for _, group := range entry.Values(d.cfg.GroupAttribute) {
+		for groupDN, role := range d.cfg.GroupRoles {
+			if strings.EqualFold(group, groupDN) && !slices.Contains(identity.Roles, role) {
+				identity.Roles = append(identity.Roles, role)
+			}
+		}
+	}
+	slices.Sort(identity.Roles)
+
+	return identity, nil
+}
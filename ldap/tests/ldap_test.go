@@ -0,0 +1,260 @@
+package ldap_test
+
+import (
+	"bufio"
+	"cloud-storage/auth"
+	"cloud-storage/ldap"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tlv is a BER element as the fake server sees it
+type tlv struct {
+	tag  byte
+	data []byte
+}
+
+func encodeTlv(tag byte, content ...[]byte) []byte {
+	var body []byte
+	for _, c := range content {
+		body = append(body, c...)
+	}
+
+	out := []byte{tag}
+	if len(body) < 0x80 {
+		out = append(out, byte(len(body)))
+	} else {
+		out = append(out, 0x82, byte(len(body)>>8), byte(len(body)))
+	}
+	return append(out, body...)
+}
+
+func str(tag byte, s string) []byte {
+	return encodeTlv(tag, []byte(s))
+}
+
+func readTlv(r *bufio.Reader) (tlv, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return tlv{}, err
+	}
+
+	n := int(header[1])
+	if n&0x80 != 0 {
+		length := make([]byte, n&0x7f)
+		if _, err := io.ReadFull(r, length); err != nil {
+			return tlv{}, err
+		}
+		n = 0
+		for _, b := range length {
+			n = n<<8 | int(b)
+		}
+	}
+
+	data := make([]byte, n)
+	_, err := io.ReadFull(r, data)
+	return tlv{tag: header[0], data: data}, err
+}
+
+func (e tlv) children(t *testing.T) []tlv {
+	var children []tlv
+	r := bufio.NewReader(strings.NewReader(string(e.data)))
+	for {
+		child, err := readTlv(r)
+		if err == io.EOF {
+			return children
+		}
+		require.NoError(t, err)
+		children = append(children, child)
+	}
+}
+
+type fakeUser struct {
+	dn       string
+	uid      string
+	password string
+	groups   []string
+}
+
+type fakeServer struct {
+	t        *testing.T
+	listener net.Listener
+	users    []fakeUser
+
+	mu       sync.Mutex
+	searches []string
+}
+
+func newFakeServer(t *testing.T, users ...fakeUser) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &fakeServer{t: t, listener: listener, users: users}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func result(op byte, code int) []byte {
+	return encodeTlv(op, encodeTlv(0x0a, []byte{byte(code)}), str(0x04, ""), str(0x04, ""))
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		message, err := readTlv(r)
+		if err != nil {
+			return
+		}
+		parts := message.children(s.t)
+		id, op := parts[0], parts[1]
+		reply := func(protocolOp []byte) {
+			conn.Write(encodeTlv(0x30, encodeTlv(0x02, id.data), protocolOp))
+		}
+
+		switch op.tag {
+		case 0x60:
+			fields := op.children(s.t)
+			dn, password := string(fields[1].data), string(fields[2].data)
+
+			code := 49
+			for _, u := range s.users {
+				if u.dn == dn && u.password == password {
+					code = 0
+				}
+			}
+			if dn == "cn=service" && password == "service-secret" {
+				code = 0
+			}
+			reply(result(0x61, code))
+		case 0x63:
+			fields := op.children(s.t)
+			filter := fields[6].children(s.t)
+			value := string(filter[1].data)
+
+			s.mu.Lock()
+			s.searches = append(s.searches, value)
+			s.mu.Unlock()
+
+			for _, u := range s.users {
+				if !strings.EqualFold(u.uid, value) {
+					continue
+				}
+
+				var groups [][]byte
+				for _, g := range u.groups {
+					groups = append(groups, str(0x04, g))
+				}
+				reply(encodeTlv(0x64,
+					str(0x04, u.dn),
+					encodeTlv(0x30,
+						encodeTlv(0x30, str(0x04, "uid"), encodeTlv(0x31, str(0x04, u.uid))),
+						encodeTlv(0x30, str(0x04, "memberOf"), encodeTlv(0x31, groups...)),
+					),
+				))
+			}
+			reply(result(0x65, 0))
+		case 0x42:
+			return
+		}
+	}
+}
+
+var alice = fakeUser{
+	dn:       "uid=alice,ou=people,dc=example,dc=com",
+	uid:      "alice",
+	password: "wonderland",
+	groups:   []string{"cn=staff,ou=groups,dc=example,dc=com", "CN=Storage-Admins,ou=groups,dc=example,dc=com"},
+}
+
+func newDirectory(s *fakeServer) *ldap.Directory {
+	return ldap.NewDirectory(ldap.Config{
+		URL:          s.url(),
+		BindDN:       "cn=service",
+		BindPassword: "service-secret",
+		BaseDN:       "dc=example,dc=com",
+		GroupRoles:   map[string]string{"cn=storage-admins,ou=groups,dc=example,dc=com": auth.RoleAdmin},
+		Timeout:      5 * time.Second,
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := newFakeServer(t, alice)
+
+	identity, err := newDirectory(s).Authenticate(context.Background(), "ALICE", "wonderland")
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Name: "alice", Roles: []string{auth.RoleAdmin}}, identity)
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	s := newFakeServer(t, alice)
+	d := newDirectory(s)
+
+	testCases := []struct {
+		name     string
+		user     string
+		password string
+	}{
+		{name: "Wrong password", user: "alice", password: "looking-glass"},
+		{name: "Unknown user", user: "bob", password: "wonderland"},
+		{name: "Empty password", user: "alice", password: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := d.Authenticate(context.Background(), tc.user, tc.password)
+			assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		})
+	}
+}
+
+func TestAuthenticateFilterInjection(t *testing.T) {
+	s := newFakeServer(t, alice)
+
+	_, err := newDirectory(s).Authenticate(context.Background(), "*)(uid=*", "wonderland")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	// the name reaches the server as the value of an equality match, not as filter syntax
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assert.Equal(t, []string{"*)(uid=*"}, s.searches)
+}
+
+func TestAuthenticateServiceAccount(t *testing.T) {
+	s := newFakeServer(t, alice)
+
+	d := ldap.NewDirectory(ldap.Config{URL: s.url(), BindDN: "cn=service", BindPassword: "wrong"})
+	_, err := d.Authenticate(context.Background(), "alice", "wonderland")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+func TestDialUnsupportedScheme(t *testing.T) {
+	_, err := ldap.Dial(context.Background(), "http://localhost", time.Second)
+	assert.Error(t, err)
+}
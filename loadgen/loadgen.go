@@ -0,0 +1,266 @@
+// Package loadgen drives concurrent uploads and downloads against a running server
+// and reports their latencies.
+package loadgen
+
+import (
+	"cloud-storage/client"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target is the part of the api the load is put on; *client.Client implements it
+type Target interface {
+	Upload(ctx context.Context, fileName string, r io.Reader, size int64) (client.File, error)
+	Download(ctx context.Context, id string, w io.Writer) (string, error)
+}
+
+type Config struct {
+	// Concurrency is the number of workers sending requests back to back
+	Concurrency int
+	Duration    time.Duration
+	// FileSizes are picked from at random for every upload
+	FileSizes []int64
+	// DownloadRatio is the share of operations that download one of the uploaded files, from 0 to 1
+	DownloadRatio float64
+}
+
+type Op string
+
+const (
+	OpUpload   Op = "upload"
+	OpDownload Op = "download"
+)
+
+// Stats sums up every request of one kind
+type Stats struct {
+	Count  int
+	Errors int
+	Bytes  int64
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// Throughput is in bytes per second of the whole run
+func (s Stats) Throughput(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / elapsed.Seconds()
+}
+
+type Report struct {
+	Elapsed time.Duration
+	Ops     map[Op]Stats
+	// FirstError is kept so a run that fails on every request says why
+	FirstError error
+}
+
+// recorder collects the results of all workers
+type recorder struct {
+	mu        sync.Mutex
+	latencies map[Op][]time.Duration
+	stats     map[Op]Stats
+	firstErr  error
+	// ids of uploaded files for downloads to pick from
+	ids []string
+}
+
+func (rec *recorder) record(op Op, latency time.Duration, bytes int64, err error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	s := rec.stats[op]
+	s.Count++
+	if err != nil {
+		s.Errors++
+		if rec.firstErr == nil {
+			rec.firstErr = fmt.Errorf("%s: %w", op, err)
+		}
+	} else {
+		s.Bytes += bytes
+		rec.latencies[op] = append(rec.latencies[op], latency)
+	}
+	rec.stats[op] = s
+}
+
+func (rec *recorder) addId(id string) {
+	rec.mu.Lock()
+	rec.ids = append(rec.ids, id)
+	rec.mu.Unlock()
+}
+
+func (rec *recorder) randomId() (string, bool) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if len(rec.ids) == 0 {
+		return "", false
+	}
+	return rec.ids[mrand.IntN(len(rec.ids))], true
+}
+
+// Run keeps cfg.Concurrency workers busy until cfg.Duration passes or ctx is done.
+// Failed requests are counted and do not stop the run.
+func Run(ctx context.Context, target Target, cfg Config) (Report, error) {
+	if cfg.Concurrency <= 0 {
+		return Report{}, errors.New("loadgen: concurrency has to be positive")
+	}
+	if len(cfg.FileSizes) == 0 {
+		return Report{}, errors.New("loadgen: no file sizes given")
+	}
+	if cfg.DownloadRatio < 0 || cfg.DownloadRatio > 1 {
+		return Report{}, fmt.Errorf("loadgen: download ratio %v is not between 0 and 1", cfg.DownloadRatio)
+	}
+
+	// every upload is cut out of the same random bytes, so content generation is not measured
+	content := make([]byte, slices.Max(cfg.FileSizes))
+	rand.Read(content)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	rec := &recorder{
+		latencies: make(map[Op][]time.Duration),
+		stats:     make(map[Op]Stats),
+	}
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for worker := range cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for n := 0; ctx.Err() == nil; n++ {
+				id, ok := rec.randomId()
+				if ok && mrand.Float64() < cfg.DownloadRatio {
+					download(ctx, target, rec, id)
+				} else {
+					size := cfg.FileSizes[mrand.IntN(len(cfg.FileSizes))]
+					upload(ctx, target, rec, fmt.Sprintf("loadgen-%d-%d", worker, n), content[:size])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Elapsed:    time.Since(start),
+		Ops:        make(map[Op]Stats, len(rec.stats)),
+		FirstError: rec.firstErr,
+	}
+	for op, s := range rec.stats {
+		latencies := rec.latencies[op]
+		slices.Sort(latencies)
+		s.P50 = percentile(latencies, 50)
+		s.P90 = percentile(latencies, 90)
+		s.P99 = percentile(latencies, 99)
+		if len(latencies) > 0 {
+			s.Max = latencies[len(latencies)-1]
+		}
+		report.Ops[op] = s
+	}
+
+	return report, nil
+}
+
+func upload(ctx context.Context, target Target, rec *recorder, name string, content []byte) {
+	start := time.Now()
+	file, err := target.Upload(ctx, name, &readerOnly{content: content}, int64(len(content)))
+	if ctx.Err() != nil {
+		// requests cut short by the end of the run say nothing about the server
+		return
+	}
+
+	rec.record(OpUpload, time.Since(start), int64(len(content)), err)
+	if err == nil {
+		rec.addId(file.Id)
+	}
+}
+
+func download(ctx context.Context, target Target, rec *recorder, id string) {
+	start := time.Now()
+	counter := &countingWriter{}
+	_, err := target.Download(ctx, id, counter)
+	if ctx.Err() != nil {
+		return
+	}
+
+	rec.record(OpDownload, time.Since(start), counter.n, err)
+}
+
+// percentile uses the nearest rank of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// readerOnly hides bytes.Reader's WriterTo and Seeker so uploads stream like a real client's would
+type readerOnly struct {
+	content []byte
+	off     int
+}
+
+func (r *readerOnly) Read(p []byte) (int, error) {
+	if r.off >= len(r.content) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.content[r.off:])
+	r.off += n
+	return n, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// ParseSizes reads a comma separated list of sizes in bytes, optionally suffixed with k, m or g
+// for binary kilo, mega and gigabytes, e.g. "4k,1m,64m"
+func ParseSizes(s string) ([]int64, error) {
+	var sizes []int64
+	for _, raw := range strings.Split(s, ",") {
+		field := strings.ToLower(strings.TrimSpace(raw))
+
+		var unit int64 = 1
+		switch {
+		case strings.HasSuffix(field, "k"):
+			unit = 1 << 10
+		case strings.HasSuffix(field, "m"):
+			unit = 1 << 20
+		case strings.HasSuffix(field, "g"):
+			unit = 1 << 30
+		}
+		if unit != 1 {
+			field = field[:len(field)-1]
+		}
+
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("loadgen: invalid size %q", raw)
+		}
+		sizes = append(sizes, n*unit)
+	}
+
+	return sizes, nil
+}
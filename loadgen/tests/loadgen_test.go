@@ -0,0 +1,126 @@
+package loadgen_test
+
+import (
+	"cloud-storage/client"
+	"cloud-storage/loadgen"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTarget struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	next  atomic.Int64
+	// every upload fails with it when set
+	uploadErr error
+}
+
+func (f *fakeTarget) Upload(_ context.Context, fileName string, r io.Reader, size int64) (client.File, error) {
+	if f.uploadErr != nil {
+		return client.File{}, f.uploadErr
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return client.File{}, err
+	}
+	if int64(len(content)) != size {
+		return client.File{}, fmt.Errorf("read %d bytes of %d", len(content), size)
+	}
+
+	id := fmt.Sprint(f.next.Add(1))
+	f.mu.Lock()
+	f.files[id] = content
+	f.mu.Unlock()
+	return client.File{Id: id, FileName: fileName}, nil
+}
+
+func (f *fakeTarget) Download(_ context.Context, id string, w io.Writer) (string, error) {
+	f.mu.Lock()
+	content, ok := f.files[id]
+	f.mu.Unlock()
+	if !ok {
+		return "", errors.New("no such file")
+	}
+
+	_, err := w.Write(content)
+	return id, err
+}
+
+func TestRun(t *testing.T) {
+	target := &fakeTarget{files: make(map[string][]byte)}
+
+	report, err := loadgen.Run(context.Background(), target, loadgen.Config{
+		Concurrency:   4,
+		Duration:      100 * time.Millisecond,
+		FileSizes:     []int64{10, 1000},
+		DownloadRatio: 0.5,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, report.FirstError)
+
+	uploads := report.Ops[loadgen.OpUpload]
+	downloads := report.Ops[loadgen.OpDownload]
+	assert.Positive(t, uploads.Count)
+	assert.Positive(t, downloads.Count)
+	assert.Zero(t, uploads.Errors+downloads.Errors)
+	assert.Positive(t, downloads.Bytes)
+
+	for _, s := range []loadgen.Stats{uploads, downloads} {
+		assert.LessOrEqual(t, s.P50, s.P90)
+		assert.LessOrEqual(t, s.P90, s.P99)
+		assert.LessOrEqual(t, s.P99, s.Max)
+	}
+}
+
+func TestRun_CountsErrors(t *testing.T) {
+	target := &fakeTarget{files: make(map[string][]byte), uploadErr: errors.New("storage is full")}
+
+	report, err := loadgen.Run(context.Background(), target, loadgen.Config{
+		Concurrency:   2,
+		Duration:      20 * time.Millisecond,
+		FileSizes:     []int64{10},
+		DownloadRatio: 0.5,
+	})
+	require.NoError(t, err)
+
+	uploads := report.Ops[loadgen.OpUpload]
+	assert.Positive(t, uploads.Errors)
+	assert.Equal(t, uploads.Count, uploads.Errors)
+	assert.ErrorContains(t, report.FirstError, "storage is full")
+	// nothing was uploaded, so there was nothing to download
+	assert.NotContains(t, report.Ops, loadgen.OpDownload)
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	target := &fakeTarget{files: make(map[string][]byte)}
+
+	for _, cfg := range []loadgen.Config{
+		{Concurrency: 0, Duration: time.Millisecond, FileSizes: []int64{1}},
+		{Concurrency: 1, Duration: time.Millisecond},
+		{Concurrency: 1, Duration: time.Millisecond, FileSizes: []int64{1}, DownloadRatio: 1.5},
+	} {
+		_, err := loadgen.Run(context.Background(), target, cfg)
+		assert.Error(t, err)
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	sizes, err := loadgen.ParseSizes("100, 4k,2M,1g")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{100, 4 << 10, 2 << 20, 1 << 30}, sizes)
+
+	for _, s := range []string{"", "k", "-4k", "4x", "1,,2"} {
+		_, err := loadgen.ParseSizes(s)
+		assert.Error(t, err, s)
+	}
+}
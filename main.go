@@ -1,18 +1,48 @@
 package main
 
 import (
+	"cloud-storage/access"
+	"cloud-storage/anomaly"
 	"cloud-storage/api"
+	"cloud-storage/audit"
 	"cloud-storage/auth"
 	"cloud-storage/config"
+	"cloud-storage/db_access"
 	"cloud-storage/db_access/sqlite"
 	"cloud-storage/encryption"
+	"cloud-storage/events"
+	"cloud-storage/export"
+	"cloud-storage/features"
+	"cloud-storage/filecache"
+	"cloud-storage/hls"
+	"cloud-storage/importer"
+	"cloud-storage/jobs"
+	"cloud-storage/keys"
+	"cloud-storage/maintenance"
+	"cloud-storage/policy"
+	"cloud-storage/presign"
+	"cloud-storage/preview"
+	"cloud-storage/privacy"
+	"cloud-storage/repair"
+	"cloud-storage/replication"
+	"cloud-storage/retention"
+	"cloud-storage/scheduler"
+	"cloud-storage/search"
+	"cloud-storage/stack"
+	"cloud-storage/storage"
+	"cloud-storage/tiering"
+	"cloud-storage/utils/drain"
+	"cloud-storage/utils/problem"
+	"cloud-storage/utils/realip"
 	slogext "cloud-storage/utils/slogExt"
+	"cloud-storage/web"
+	"context"
 	"crypto/rand"
 	"errors"
+	"expvar"
 	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -29,81 +59,623 @@ func main() {
 
 	log.Debug("dec-rotation-period", slog.String("value", time.Duration(appConfig.DecRotationPeriod).String()))
 
-	db, err := sqlite.New(appConfig.DbPath)
+	store, err := sqlite.New(appConfig.DbPath)
 	if err != nil {
 		log.Error("Could not load a db", slogext.Error(err))
 		os.Exit(1)
 	}
+	// every write has to go through db for cached files to be dropped as they change
+	files := filecache.New(appConfig.FileCacheSize)
+	db := filecache.Wrap(store, files)
 
-	err = func() error {
-		if info, err := os.Stat(appConfig.FileStoragePath); err != nil && errors.Is(err, os.ErrNotExist) {
-			fullPath, err := filepath.Abs(appConfig.FileStoragePath)
-			if err != nil {
-				return err
-			}
-
-			log.Info("Storage dir does not exists; creating", slog.String("path", fullPath))
-			err = os.Mkdir(fullPath, os.ModeDir)
-			if err != nil {
-				return err
-			}
-		} else if err != nil {
-			return err
-		} else if !info.IsDir() {
-			return errors.New("file already exists with such name")
-		}
+	if err := storage.PrepareDir(appConfig.FileStoragePath, appConfig.StorageDirMode); err != nil {
+		log.Error("Could not prepare storage dir", slogext.Error(err), slog.String("path", appConfig.FileStoragePath))
+		os.Exit(1)
+	}
 
-		return nil
-	}()
+	// nothing writes to the storage dir yet, so whatever is left in its temp dir was interrupted by a crash
+	if n, err := storage.SweepTemp(appConfig.FileStoragePath); err != nil {
+		log.Error("Could not sweep storage temp dir", slogext.Error(err))
+		os.Exit(1)
+	} else if n > 0 {
+		log.Info("Removed incomplete uploads", slog.Int("count", n))
+	}
 
+	recovery, err := storage.Recover(appConfig.FileStoragePath, appConfig.Durability)
 	if err != nil {
-		log.Error("Could not create storage dir", slogext.Error(err))
+		log.Error("Could not recover storage journal", slogext.Error(err))
 		os.Exit(1)
 	}
+	if len(recovery.Verified) > 0 || len(recovery.Removed) > 0 {
+		log.Info(
+			"Recovered interrupted blob writes",
+			slog.Int("verified", len(recovery.Verified)),
+			slog.Int("removed", len(recovery.Removed)),
+		)
+	}
+	if len(recovery.Incomplete) > 0 {
+		log.Error("Some blobs were not completely written; run the repair command", slog.Int("count", len(recovery.Incomplete)))
+	}
 
 	encryptionService := encryption.NewVault()
+	encryptionProvider := encryption.NewAesGcmProvider(appConfig.ChunkSize, appConfig.EncryptionWorkers)
+	if err := encryption.SelfTest(encryptionService, encryptionProvider, rand.Reader); err != nil {
+		log.Error("Encryption self-test failed; check the vault settings", slogext.Error(err))
+		os.Exit(1)
+	}
+	log.Debug("Encryption self-test passed")
+
 	fileCrypter := encryption.NewSymmetricCrypter(
 		db,
 		encryptionService,
 		rand.Reader,
-		encryption.NewAesGcmProvider(appConfig.MaxUploadSize),
+		encryptionProvider,
 		time.Duration(appConfig.DecRotationPeriod),
-	)
+	).ConfigureNameDecryption(encryption.NameDecryption{
+		Workers:   appConfig.NameDecryption.Workers,
+		BatchSize: appConfig.NameDecryption.BatchSize,
+		CacheSize: appConfig.NameDecryption.CacheSize,
+	}, encryptionService)
+	if appConfig.ConvergentEncryption {
+		fileCrypter.EnableConvergentEncryption(encryptionService)
+		log.Warn("Convergent encryption is enabled: identical uploads of different users share their blob")
+	}
+
+	// names kept before they were normalized would not match the ones users log in with
+	if n, err := auth.NormalizeNames(db, log); err != nil {
+		log.Error("Could not normalize user names", slogext.Error(err))
+		os.Exit(1)
+	} else if n > 0 {
+		log.Info("Normalized user names", slog.Int("count", n))
+	}
+	// only once names are normalized, as the names of before may differ in case alone
+	var unce db_access.UserNamesConflictError
+	if err := db.UniqueUserNames(); errors.As(err, &unce) {
+		log.Error("Users have names that differ only in case; rename them to start", slogext.Error(err))
+		os.Exit(1)
+	} else if err != nil {
+		log.Error("Could not make user names unique", slogext.Error(err))
+		os.Exit(1)
+	}
 
 	authData := auth.NewAuthData(db, time.Duration(appConfig.TokenTimeToLive))
+	authData.SetRegistrations(appConfig.Registrations)
+	authData.SetTokenConfig(appConfig.TokenConfig())
+	if appConfig.Cookies.Enabled {
+		cookieConfig, err := appConfig.CookieConfig()
+		if err != nil {
+			log.Error("Invalid cookies config", slogext.Error(err))
+			os.Exit(1)
+		}
+		authData.UseCookies(cookieConfig)
+		log.Info("Browsers may keep their session in cookies", slog.String("cookie", cookieConfig.Name))
+	}
+	if appConfig.LDAP.URL != "" {
+		authData.UseDirectory(appConfig.LDAPDirectory())
+		log.Info("Logins are checked against LDAP", slog.String("url", appConfig.LDAP.URL))
+	}
+	// the network rules of users and devices hold for getting tokens as for using them
+	authData.FilterLogins(api.NewLoginNetworkFilter(db))
+	authenticate := auth.Auth(authData)
+	if appConfig.ProxyAuth.Enabled {
+		proxyConfig, err := appConfig.ProxyAuthConfig()
+		if err != nil {
+			log.Error("Invalid proxy auth config", slogext.Error(err))
+			os.Exit(1)
+		}
+		authenticate = auth.ProxyAuth(db, proxyConfig)
+		log.Info("Users are authenticated by the fronting proxy", slog.String("header", proxyConfig.Header))
+	}
+
+	blobs, err := appConfig.BlobStore()
+	if err != nil {
+		log.Error("Could not set up storage backends", slogext.Error(err))
+		os.Exit(1)
+	}
+
+	jobPool := jobs.New(db, appConfig.JobsConfig(), log)
+	go jobPool.Run(context.Background())
+
+	exporter, err := export.New(db, fileCrypter, appConfig.ExportConfig(blobs, jobPool), log)
+	if err != nil {
+		log.Error("Could not set up exports", slogext.Error(err))
+		os.Exit(1)
+	}
+	exporter.Recover()
+
+	signer, err := presign.New(appConfig.PresignConfig())
+	if err != nil {
+		log.Error("Could not set up presigned links", slogext.Error(err))
+		os.Exit(1)
+	}
+
+	mode := maintenance.New(appConfig.Maintenance, "enabled in config")
+	if mode.Enabled() {
+		log.Warn("Starting in maintenance mode")
+	}
+
+	janitor := retention.New(db, appConfig.RetentionConfig(blobs), log)
+	eraser := privacy.New(db, appConfig.PrivacyConfig(blobs, exporter, fileCrypter), log)
+
+	anomalyConfig, err := appConfig.AnomalyConfig()
+	if err != nil {
+		log.Error("Could not set up anomaly alerts", slogext.Error(err))
+		os.Exit(1)
+	}
+	detector := anomaly.New(db, anomalyConfig, log)
+	go detector.Run(context.Background())
+	if !appConfig.ProxyAuth.Enabled {
+		authData.WatchLogins(detector)
+	}
+
+	// limits from the config file apply until a policy overrides them
+	policies := policy.NewEvaluator(db, appConfig.PolicyDefaults())
+
+	importStore := api.NewImportStore(db, appConfig.UploadConfig(policies, blobs, fileCrypter), fileCrypter)
+	fileImporter := importer.New(db, appConfig.ImportConfig(importStore, jobPool), log)
+	fileImporter.Recover()
+
+	migrator, err := tiering.New(db, blobs, appConfig.TieringConfig(jobPool), log)
+	if err != nil {
+		log.Error("Could not set up storage tiering", slogext.Error(err))
+		os.Exit(1)
+	}
+	go migrator.Run(context.Background())
+
+	if appConfig.ContentIndex.Enabled {
+		indexer := search.New(db, fileCrypter, blobs, appConfig.ContentIndexConfig(), log)
+		go indexer.Run(context.Background())
+	}
+
+	// consumers of the change feed; cursors of any other are dropped so the feed doesn't keep changes for them
+	var consumers []string
+
+	var replicator *replication.Replicator
+	if appConfig.Replication.Target != "" {
+		replicator, err = replication.New(db, blobs, appConfig.ReplicationConfig(), log)
+		if err != nil {
+			log.Error("Could not set up replication", slogext.Error(err))
+			os.Exit(1)
+		}
+		consumers = append(consumers, replicator.Consumer())
+	}
+
+	publishers, err := appConfig.EventPublishers()
+	if err != nil {
+		log.Error("Could not set up event publishing", slogext.Error(err))
+		os.Exit(1)
+	}
+	dispatchers := make([]*events.Dispatcher, 0, len(publishers))
+	for name, publisher := range publishers {
+		dispatcher := events.NewDispatcher(db, name, publisher, log)
+		dispatchers = append(dispatchers, dispatcher)
+		consumers = append(consumers, dispatcher.Consumer())
+	}
+
+	if err := db.KeepChangeCursors(consumers); err != nil {
+		log.Error("Could not drop unused change feed cursors", slogext.Error(err))
+		os.Exit(1)
+	}
+	if replicator != nil {
+		go replicator.Run(context.Background())
+	}
+	for _, dispatcher := range dispatchers {
+		go dispatcher.Run(context.Background(), time.Duration(appConfig.EventsInterval))
+	}
+
+	pruner := keys.New(db, appConfig.KeysConfig(blobs), log)
+
+	schedule := scheduler.New(appConfig.SchedulerConfig(mode), log)
+	schedule.Add(scheduler.Task{
+		Name:     "retention",
+		Interval: appConfig.TaskInterval("retention", appConfig.RetentionInterval),
+		Run: func(ctx context.Context, now time.Time) error {
+			janitor.Sweep(now)
+			return nil
+		},
+	})
+	schedule.Add(scheduler.Task{
+		Name:     "export-cleanup",
+		Interval: appConfig.TaskInterval("export-cleanup", appConfig.ExportCleanup),
+		Run: func(ctx context.Context, now time.Time) error {
+			exporter.RemoveExpired(now)
+			return nil
+		},
+	})
+	// removing keys can't be undone, so pruning only runs when given an interval
+	schedule.Add(scheduler.Task{
+		Name:     "dec-prune",
+		Interval: appConfig.TaskInterval("dec-prune", appConfig.DecPruneInterval),
+		Run: func(ctx context.Context, now time.Time) error {
+			_, err := pruner.Prune(ctx, now)
+			return err
+		},
+	})
+	schedule.Add(scheduler.Task{
+		Name:     "orphan-gc",
+		Interval: appConfig.TaskInterval("orphan-gc", appConfig.Schedule.OrphanGCInterval),
+		Run: func(ctx context.Context, now time.Time) error {
+			removed, err := repair.CollectOrphans(db, blobs.Dir(), appConfig.Durability, time.Duration(appConfig.Schedule.OrphanMinAge), now)
+			for _, name := range removed {
+				log.Info("Removed orphaned blob", slog.String("blob", name))
+			}
+			return err
+		},
+	})
+	schedule.Add(scheduler.Task{
+		Name:     "usage-recalculation",
+		Interval: appConfig.TaskInterval("usage-recalculation", appConfig.Schedule.UsageInterval),
+		Run: func(ctx context.Context, now time.Time) error {
+			fixed, err := db.RecalculateUsage()
+			if fixed > 0 {
+				log.Warn("Corrected usage counters", slog.Int64("users", fixed))
+			}
+			return err
+		},
+	})
+	auditInterval := appConfig.Schedule.AuditInterval
+	if appConfig.Schedule.AuditRetention <= 0 {
+		auditInterval = 0
+	}
+	schedule.Add(scheduler.Task{
+		Name:     "audit-retention",
+		Interval: appConfig.TaskInterval("audit-retention", auditInterval),
+		Run: func(ctx context.Context, now time.Time) error {
+			removed, err := db.RemoveAuditEventsBefore(db_access.Time(now.Add(-time.Duration(appConfig.Schedule.AuditRetention))))
+			if removed > 0 {
+				log.Info("Removed old audit events", slog.Int64("events", removed))
+			}
+			return err
+		},
+	})
+	schedule.Add(scheduler.Task{
+		Name:     "account-erasure",
+		Interval: appConfig.TaskInterval("account-erasure", appConfig.Privacy.ErasureInterval),
+		Run: func(ctx context.Context, now time.Time) error {
+			_, err := eraser.Erase(ctx, now)
+			return err
+		},
+	})
+	rewrapper := keys.NewRewrapper(db, encryptionService, log)
+	schedule.Add(scheduler.Task{
+		Name:     "key-rewrap",
+		Interval: appConfig.TaskInterval("key-rewrap", appConfig.Schedule.RewrapInterval),
+		Run: func(ctx context.Context, now time.Time) error {
+			if rewrapper.Running() {
+				return nil
+			}
+			outdated, err := rewrapper.Outdated()
+			if err != nil || outdated.Keys+outdated.Names == 0 {
+				return err
+			}
+
+			job, err := jobPool.Submit(db_access.Job{Type: jobs.TypeRewrap}, rewrapper.Run)
+			if err != nil {
+				return err
+			}
+			log.Info("Queued rewrap after the transit key rotated", slog.String("job-id", job.Id), slog.Int64("key-version", outdated.LatestVersion))
+			return nil
+		},
+	})
+	go schedule.Run(context.Background())
+
+	accesses := access.New(db, log)
+	go accesses.Run(context.Background(), time.Duration(appConfig.AccessFlush))
+
+	// flags are reread only with a reload interval; otherwise changing them takes a restart
+	flags, err := features.New(appConfig.FeatureFlags, api.FeatureDefaults, log)
+	if err != nil {
+		log.Error("Could not load feature flags", slogext.Error(err))
+		os.Exit(1)
+	}
+	if appConfig.FeatureFlags != "" && appConfig.FeatureReload > 0 {
+		go flags.Watch(context.Background(), time.Duration(appConfig.FeatureReload))
+	}
+	log.Debug("Feature flags", slog.Any("flags", flags.All()))
+
+	// exports are only offered once there is a key to sign them with; events are recorded either way
+	var auditSigner *audit.Signer
+	if appConfig.AuditSigningKey != "" {
+		auditSigner, err = audit.LoadSigner(appConfig.AuditSigningKey)
+		if err != nil {
+			log.Error("Could not load the audit signing key", slogext.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	var hlsService *hls.Service
+	if appConfig.HLS.Enabled {
+		transcoder := hls.FFmpeg{Path: appConfig.HLS.FFmpegPath, SegmentDuration: appConfig.HLS.SegmentDuration}
+		hlsService, err = hls.New(fileCrypter, transcoder, appConfig.HLSServiceConfig(blobs), log)
+		if err != nil {
+			log.Error("Could not set up hls streaming", slogext.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	var pdfRenderer preview.Renderer
+	if appConfig.Preview.PDF {
+		pdfRenderer = preview.Pdftoppm{
+			Path:       appConfig.Preview.PdftoppmPath,
+			Resolution: appConfig.Preview.Resolution,
+			Timeout:    time.Duration(appConfig.Preview.Timeout),
+		}
+	}
+
+	expvar.Publish("file_cache", expvar.Func(func() any {
+		return files.Stats()
+	}))
+
+	space := appConfig.StorageSpace()
+	expvar.Publish("storage_free_bytes", expvar.Func(func() any {
+		free, err := space.Free()
+		if err != nil {
+			return nil
+		}
+		return free
+	}))
+
+	proxies, err := realip.ParseProxies(appConfig.TrustedProxies)
+	if err != nil {
+		log.Error("Invalid trusted proxies", slogext.Error(err))
+		os.Exit(1)
+	}
+
+	tls := appConfig.TLSCertFile != "" || appConfig.TLSKeyFile != ""
+	if tls && (appConfig.TLSCertFile == "" || appConfig.TLSKeyFile == "") {
+		log.Error("Both tls-cert-file and tls-key-file have to be set")
+		os.Exit(1)
+	}
+
+	registry := stack.Registry{
+		"request-id":       middleware.RequestID,
+		"logger":           slogext.Logger(log),
+		"drain":            drain.Middleware(drain.DefaultLimit),
+		"problem":          problem.Negotiate,
+		"recoverer":        api.Recoverer,
+		"rate-limit":       api.RateLimit(appConfig.RateLimit, appConfig.RateBurst),
+		"security-headers": stack.SecurityHeaders(tls),
+	}
+	// handlers take their logger from the request context
+	apiMiddlewares, err := registry.Build(appConfig.MiddlewareNames(), "logger")
+	if err != nil {
+		log.Error("Invalid middlewares", slogext.Error(err))
+		os.Exit(1)
+	}
+	log.Debug("Api middlewares", slog.Any("names", appConfig.MiddlewareNames()))
+
+	networkRules, err := appConfig.NetworkRules()
+	if err != nil {
+		log.Error("Invalid network rules", slogext.Error(err))
+		os.Exit(1)
+	}
+	geo, err := appConfig.GeoIPProvider()
+	if err != nil {
+		log.Error("Could not load the GeoIP table", slogext.Error(err))
+		os.Exit(1)
+	}
 
 	r := chi.NewRouter()
+	r.Use(realip.Middleware(proxies))
 
 	r.Route("/api", func(r chi.Router) {
-		r.Use(middleware.RequestID)
-		r.Use(slogext.Logger(log))
-		r.Use(middleware.Recoverer)
+		r.Use(apiMiddlewares...)
+		r.Use(api.FilterNetwork(db, networkRules))
+
+		// routes that write are read-only in maintenance mode
+		writes := api.RejectInMaintenance(mode)
+		// transfers count against the monthly caps of the user, or of the owner for presigned links
+		uploadCap := api.RequireTraffic(db, appConfig.TrafficCaps(), api.UploadTraffic)
+		downloadCap := chi.Chain(api.RequireTraffic(db, appConfig.TrafficCaps(), api.DownloadTraffic), api.WatchDownloads(detector)).Handler
 
 		r.Group(func(r chi.Router) {
-			r.Use(auth.Auth(authData))
+			r.Use(authenticate)
+			r.Use(api.FilterUserNetwork(db))
+			r.Use(api.Audit(db))
 
-			r.Post("/upload", api.FileUpload(db, appConfig.UploadConfig(), fileCrypter))
-			r.Get("/download", api.FileDownload(db, fileCrypter, appConfig.FileStoragePath))
+			r.With(writes, uploadCap).Post("/upload", api.FileUpload(db, appConfig.UploadConfig(policies, blobs, fileCrypter), fileCrypter))
+			r.With(writes, uploadCap).Post("/upload/folder", api.FolderUpload(db, appConfig.UploadConfig(policies, blobs, fileCrypter), fileCrypter))
+			r.With(downloadCap).Get("/download", api.FileDownload(db, fileCrypter, blobs, accesses, appConfig.ChunkSize))
+			r.Get("/files", api.FileList(db, fileCrypter))
+			r.Get("/folders", api.Folders(db, fileCrypter))
+			r.With(api.RequireFeature(flags, api.FeatureRawUploads), writes, uploadCap).Put(
+				"/files",
+				api.FilePut(db, appConfig.UploadConfig(policies, blobs, fileCrypter), fileCrypter),
+			)
+			r.With(downloadCap).Get("/files/{id}", api.FileGet(db, fileCrypter, blobs, accesses, appConfig.ChunkSize))
+			r.Head("/files/{id}", api.FileExists(db))
+			r.Get("/files/{id}/exists", api.FileExists(db))
+			r.Get("/files/recent", api.FileRecent(db, fileCrypter))
+			r.Get("/files/starred", api.FileStarred(db, fileCrypter))
+			r.Get("/files/search", api.FileSearch(db, fileCrypter, appConfig.ContentIndex.Enabled))
+			r.With(downloadCap).Get("/files/by-path", api.FileByPath(db, fileCrypter, blobs, accesses, appConfig.ChunkSize))
+			r.With(api.RequireFeature(flags, api.FeatureBatch), writes).Post("/files/batch", api.FileBatch(db, fileCrypter, blobs, appConfig.DuplicateNames, detector))
+			r.Get("/files/{id}/preview", api.FilePreview(db, fileCrypter, blobs, pdfRenderer))
+			if hlsService != nil {
+				r.With(writes).Post("/files/{id}/hls", api.HLSStart(db, hlsService))
+				r.Get("/files/{id}/hls", api.HLSStatus(db, hlsService))
+				r.Get("/files/{id}/hls/"+hls.PlaylistName, api.HLSPlaylist(db, hlsService))
+				r.Get("/files/{id}/hls/segments/{segment}", api.HLSSegment(db, hlsService))
+			}
+
+			r.With(writes).Post("/files/{id}/copy", api.FileCopy(db, fileCrypter, appConfig.UploadConfig(policies, blobs, fileCrypter), blobs))
+			r.With(writes).Post("/files/{id}/move", api.FileMove(db, fileCrypter, appConfig.DuplicateNames, blobs))
+			r.With(api.RequireFeature(flags, api.FeatureSharing)).Post("/files/{id}/presign", api.FilePresign(db, signer, geo))
+			r.With(api.RequireFeature(flags, api.FeatureSharing)).Get("/shares/{id}/stats", api.ShareStats(signer))
+			r.With(writes).Post("/files/{id}/expiry", api.FileExpiry(db))
+			r.With(writes).Post("/files/{id}/lock", api.FileLock(db, appConfig.LockConfig()))
+			r.With(writes).Post("/files/{id}/unlock", api.FileUnlock(db))
+			r.With(writes).Put("/files/{id}/star", api.FileStar(db))
+			r.With(writes).Delete("/files/{id}/star", api.FileUnstar(db))
+			r.Get("/files/{id}/metadata", api.FileMetadata(db, fileCrypter))
+			r.With(writes).Patch("/files/{id}/metadata", api.FileMetadataPatch(db, fileCrypter))
+			r.Group(func(r chi.Router) {
+				r.Use(api.RequireFeature(flags, api.FeatureDeltaSync))
+
+				r.Get("/files/{id}/signature", api.FileSignature(db, fileCrypter, blobs))
+				r.With(writes, uploadCap).Put("/files/{id}/delta", api.FileDelta(db, fileCrypter, appConfig.UploadConfig(policies, blobs, fileCrypter), blobs))
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(api.RequireFeature(flags, api.FeatureComments))
+
+				r.Get("/files/{id}/comments", api.FileComments(db, fileCrypter))
+				r.With(writes).Post("/files/{id}/comments", api.FileCommentAdd(db, fileCrypter))
+				r.With(writes).Delete("/files/{id}/comments/{commentId}", api.FileCommentDelete(db))
+			})
+			r.Get("/notifications", api.Notifications(db, fileCrypter))
+			r.Get("/jobs/{id}", api.JobStatus(db))
+
+			r.Group(func(r chi.Router) {
+				r.Use(api.RequireFeature(flags, api.FeatureExport))
+
+				r.With(writes).Post("/export", api.ExportStart(exporter))
+				r.Get("/export/{id}", api.ExportStatus(db, exporter))
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(api.RequireFeature(flags, api.FeatureImport))
+
+				r.With(writes).Post("/import", api.ImportStart(fileImporter))
+				r.Get("/import/{id}", api.ImportStatus(db))
+				r.With(writes, uploadCap).Post("/files/from-url", api.FileFromUrl(fileImporter))
+			})
+
+			r.Get("/usage", api.Usage(db, appConfig.TrafficCaps()))
+			r.Get("/quota", api.QuotaStatus(db, appConfig.Quota()))
+			r.Get("/features", api.Features(flags))
+			r.Get("/consents", api.Consents(db))
+			r.With(writes).Put("/consents/{purpose}", api.ConsentGive(db))
+			r.With(writes).Delete("/consents/{purpose}", api.ConsentWithdraw(db))
+			r.With(writes).Post("/account/deletion", api.AccountDeletion(db, time.Duration(appConfig.Privacy.DeletionDeadline)))
+			r.Get("/account/deletion", api.AccountDeletionStatus(db))
+			if !appConfig.ProxyAuth.Enabled {
+				r.Get("/sessions", auth.Sessions(authData))
+				r.With(writes).Post("/sessions/revoke", auth.RevokeSessions(authData))
+				r.With(writes).Delete("/sessions/{id}", auth.RevokeDevice(authData))
+				r.With(writes).Post("/devices", auth.RegisterDevice(authData))
+			}
 		})
 
-		r.Route("/auth", func(r chi.Router) {
-			r.Post("/register", auth.Register(authData))
-			r.Post("/login", auth.Login(authData))
+		r.With(api.RequireFeature(flags, api.FeatureExport)).Get("/export/{id}/download", api.ExportDownload(db, exporter))
+		r.With(api.RequireFeature(flags, api.FeatureSharing), api.PresignedAuth(signer, db, geo, appConfig.PresignGuessRate, appConfig.PresignGuessBurst), downloadCap).Get(
+			"/presigned/files/{id}",
+			api.FileRaw(db, fileCrypter, blobs, files, accesses, appConfig.ChunkSize),
+		)
+
+		// users behind the proxy have no password to log in with
+		if !appConfig.ProxyAuth.Enabled {
+			r.Route("/auth", func(r chi.Router) {
+				r.Use(api.Audit(db))
+				// directory users are added on their first login
+				if appConfig.LDAP.URL == "" {
+					r.With(writes).Post("/register", auth.Register(authData))
+				}
+				r.Post("/login", auth.Login(authData))
+				r.Post("/refresh", auth.Refresh(authData))
+				if appConfig.Cookies.Enabled {
+					r.With(auth.RejectCrossSite).Post("/session", auth.SessionLogin(authData))
+					r.With(auth.RejectCrossSite).Delete("/session", auth.SessionLogout(authData))
+				}
+			})
+		}
+	})
+
+	r.Handle("/*", web.Handler())
+
+	// the management listener is meant for operators only and must not be reachable through the public address
+	m := chi.NewRouter()
+	m.Use(realip.Middleware(proxies))
+
+	m.Mount("/debug", middleware.Profiler())
+
+	m.Route("/api", func(r chi.Router) {
+		r.Use(middleware.RequestID)
+		r.Use(slogext.Logger(log))
+		r.Use(problem.Negotiate)
+		r.Use(api.Recoverer)
+
+		r.Get("/health/ready", api.Ready(
+			api.ReadinessCheck{Name: "storage-space", Check: func() error { return space.Require(0) }},
+		))
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(authenticate)
+			r.Use(api.FilterUserNetwork(db))
+			r.Use(auth.Admin(db, appConfig.AdminUsers))
+			r.Use(api.Audit(db))
+
+			r.Get("/usage", api.AdminUsage(db))
+			r.Get("/usage/traffic", api.AdminTraffic(db))
+			r.Get("/policies", api.AdminPolicies(db))
+			r.Put("/policies/{scope}", api.AdminPolicySet(db))
+			r.Put("/policies/{scope}/{subject}", api.AdminPolicySet(db))
+			r.Delete("/policies/{scope}", api.AdminPolicyDelete(db))
+			r.Delete("/policies/{scope}/{subject}", api.AdminPolicyDelete(db))
+			r.Get("/users/{id}/limits", api.AdminUserLimits(db, policies))
+			r.Put("/users/{id}/org", api.AdminUserOrg(db))
+			r.Put("/users/{id}/name", auth.AdminRenameUser(db))
+			r.Get("/keys", api.AdminKeys(pruner))
+			r.Post("/keys/rewrap", api.AdminKeysRewrap(jobPool, rewrapper))
+			r.Get("/maintenance", api.MaintenanceStatus(mode))
+			r.Get("/schedule", api.AdminSchedule(schedule))
+			r.Put("/maintenance", api.MaintenanceSet(mode))
+			r.Post("/migrations", api.MigrationStart(migrator))
+			r.Get("/migrations/{id}", api.MigrationStatus(db))
+			r.Get("/jobs/{id}", api.AdminJobStatus(db))
+			r.Get("/deletion-requests", api.AdminDeletionRequests(db))
+			r.Get("/users/{id}/alert-thresholds", api.AdminAlertThresholds(db, detector))
+			r.Put("/users/{id}/alert-thresholds", api.AdminAlertThresholdsSet(db, detector))
+			r.Delete("/users/{id}/alert-thresholds", api.AdminAlertThresholdsDelete(db, detector))
+			r.Get("/network-rules", api.AdminNetworkRules(db))
+			r.Put("/network-rules/{scope}/{subject}", api.AdminNetworkRulesSet(db))
+			r.Delete("/network-rules/{scope}/{subject}", api.AdminNetworkRulesDelete(db))
+			if auditSigner != nil {
+				r.Get("/audit", api.AuditExport(db, auditSigner))
+				r.Get("/audit/key", api.AuditKey(auditSigner))
+			}
 		})
 	})
 
+	management := &http.Server{
+		Addr:              appConfig.ManagementAddress,
+		IdleTimeout:       time.Duration(appConfig.IdleTimeout),
+		ReadTimeout:       time.Duration(appConfig.ReadTimout),
+		ReadHeaderTimeout: time.Duration(appConfig.ReadHeaderTimeout),
+		MaxHeaderBytes:    appConfig.MaxHeaderBytes,
+		Handler:           m,
+	}
+
+	log.Info("Starting management server", slog.String("address", appConfig.ManagementAddress))
+	go func() {
+		log.Error("Management server terminated", slog.String("server-crash", management.ListenAndServe().Error()))
+		os.Exit(1)
+	}()
+
 	log.Info(
 		"Starting server",
 		slog.String("address", appConfig.Address),
 		slog.Int64("max-upload-size", appConfig.MaxUploadSize),
 	)
 
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(appConfig.HTTP2.Cleartext)
+
 	server := &http.Server{
-		Addr:         appConfig.Address,
-		IdleTimeout:  time.Duration(appConfig.IdleTimeout),
-		WriteTimeout: time.Duration(appConfig.WriteTimeout),
-		ReadTimeout:  time.Duration(appConfig.ReadTimout),
-		Handler:      r,
+		Addr:              appConfig.Address,
+		IdleTimeout:       time.Duration(appConfig.IdleTimeout),
+		WriteTimeout:      time.Duration(appConfig.WriteTimeout),
+		ReadTimeout:       time.Duration(appConfig.ReadTimout),
+		ReadHeaderTimeout: time.Duration(appConfig.ReadHeaderTimeout),
+		MaxHeaderBytes:    appConfig.MaxHeaderBytes,
+		Protocols:         protocols,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams:          appConfig.HTTP2.MaxConcurrentStreams,
+			MaxReceiveBufferPerStream:     appConfig.HTTP2.MaxReceiveBufferPerStream,
+			MaxReceiveBufferPerConnection: appConfig.HTTP2.MaxReceiveBufferPerConnection,
+		},
+		Handler: r,
 	}
 
 	log.Debug(
@@ -111,9 +683,22 @@ func main() {
 		slog.String("idle-timeout", server.IdleTimeout.String()),
 		slog.String("write-timeout", server.WriteTimeout.String()),
 		slog.String("read-timeout", server.ReadTimeout.String()),
+		slog.String("read-header-timeout", server.ReadHeaderTimeout.String()),
+	)
+	log.Debug(
+		"Server limits",
+		slog.Bool("tls", tls),
+		slog.Bool("h2c", appConfig.HTTP2.Cleartext),
+		slog.Int("max-header-bytes", server.MaxHeaderBytes),
+		slog.Int("http2-max-concurrent-streams", server.HTTP2.MaxConcurrentStreams),
 	)
 
-	log.Error("Server terminated", slog.String("server-crash", server.ListenAndServe().Error()))
+	if tls {
+		err = server.ListenAndServeTLS(appConfig.TLSCertFile, appConfig.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	log.Error("Server terminated", slog.String("server-crash", err.Error()))
 }
 
 func setupLogger(env string) *slog.Logger {
@@ -0,0 +1,62 @@
+// Package maintenance holds the read-only switch that stops user writes and deleting jobs,
+// e.g. while the db is backed up or storage is migrated by an operator.
+package maintenance
+
+import (
+	"sync"
+	"time"
+)
+
+type Status struct {
+	Enabled bool
+	Reason  string
+	// Since is zero while disabled
+	Since time.Time
+}
+
+// Mode is safe for concurrent use; a nil Mode is never enabled
+type Mode struct {
+	mu     sync.RWMutex
+	status Status
+}
+
+func New(enabled bool, reason string) *Mode {
+	m := &Mode{}
+	if enabled {
+		m.Enable(reason)
+	}
+	return m
+}
+
+func (m *Mode) Enable(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.status.Enabled {
+		m.status.Since = time.Now()
+	}
+	m.status.Enabled = true
+	m.status.Reason = reason
+}
+
+func (m *Mode) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status = Status{}
+}
+
+func (m *Mode) Enabled() bool {
+	return m.Status().Enabled
+}
+
+func (m *Mode) Status() Status {
+	if m == nil {
+		return Status{}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.status
+}
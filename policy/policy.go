@@ -0,0 +1,108 @@
+// Package policy works out the storage limits of a user from the global policy, the one of their org
+// and their own, each narrower scope overriding what it sets.
+package policy
+
+import (
+	"cloud-storage/db_access"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits are what a user may store; zero values mean no limit
+type Limits struct {
+	Quota       int64
+	MaxFileSize int64
+	// MaxFileSizeOverride is the most a client may raise MaxFileSize to for a single upload
+	MaxFileSizeOverride int64
+	// media types such as image/png or image/*, empty to allow everything
+	AllowedTypes []string
+	// files are kept at most this long
+	Retention time.Duration
+}
+
+// Resolve applies policies, widest scope first, over defaults
+func Resolve(defaults Limits, policies []db_access.Policy) Limits {
+	limits := defaults
+	for _, p := range policies {
+		if p.Quota != nil {
+			limits.Quota = *p.Quota
+		}
+		if p.MaxFileSize != nil {
+			limits.MaxFileSize = *p.MaxFileSize
+		}
+		if p.MaxFileSizeOverride != nil {
+			limits.MaxFileSizeOverride = *p.MaxFileSizeOverride
+		}
+		if p.AllowedTypes != nil {
+			limits.AllowedTypes = p.AllowedTypes
+		}
+		if p.Retention != nil {
+			limits.Retention = *p.Retention
+		}
+	}
+	return limits
+}
+
+// Allows reports whether files of mediaType may be stored
+func (l Limits) Allows(mediaType string) bool {
+	if len(l.AllowedTypes) == 0 {
+		return true
+	}
+
+	mediaType = strings.ToLower(mediaType)
+	kind, _, _ := strings.Cut(mediaType, "/")
+	for _, allowed := range l.AllowedTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*/*" || allowed == mediaType || allowed == kind+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidType checks that t can be listed in AllowedTypes: type/subtype, type/* or */*
+func ValidType(t string) error {
+	kind, sub, ok := strings.Cut(t, "/")
+	if !ok || kind == "" || sub == "" || strings.ContainsAny(t, ", ;") || (kind == "*" && sub != "*") {
+		return fmt.Errorf("policy: %q is not a media type", t)
+	}
+	return nil
+}
+
+// MediaType tells the type of a file by its extension, or else by the type the client declared for it
+func MediaType(fileName string, declared string) string {
+	if t := mime.TypeByExtension(filepath.Ext(fileName)); t != "" {
+		declared = t
+	}
+
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// Evaluator resolves the limits of users against the policies in the db
+type Evaluator struct {
+	db       db_access.PolicyRepo
+	defaults Limits
+}
+
+// NewEvaluator takes the limits from the config file as what applies without any policy
+func NewEvaluator(db db_access.PolicyRepo, defaults Limits) *Evaluator {
+	return &Evaluator{db: db, defaults: defaults}
+}
+
+func (e *Evaluator) For(userId int64) (Limits, error) {
+	const op = "policy.Evaluator.For"
+
+	policies, err := e.db.GetUserPolicies(userId)
+	if err != nil {
+		return Limits{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Resolve(e.defaults, policies), nil
+}
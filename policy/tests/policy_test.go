@@ -0,0 +1,54 @@
+package policy_test
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/policy"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestResolve(t *testing.T) {
+	defaults := policy.Limits{Quota: 100, MaxFileSize: 10}
+
+	limits := policy.Resolve(defaults, []db_access.Policy{
+		{Scope: db_access.GlobalScope, Quota: ptr(int64(200)), AllowedTypes: []string{"image/*"}},
+		{Scope: db_access.OrgScope, Subject: "acme", MaxFileSize: ptr(int64(20)), Retention: ptr(time.Hour)},
+		{Scope: db_access.UserScope, Subject: "1", Quota: ptr(int64(0)), AllowedTypes: []string{}, MaxFileSizeOverride: ptr(int64(40))},
+	})
+	assert.Equal(t, policy.Limits{Quota: 0, MaxFileSize: 20, MaxFileSizeOverride: 40, AllowedTypes: []string{}, Retention: time.Hour}, limits)
+
+	assert.Equal(t, defaults, policy.Resolve(defaults, nil))
+}
+
+func TestAllows(t *testing.T) {
+	limits := policy.Limits{AllowedTypes: []string{"image/*", "Application/PDF"}}
+
+	assert.True(t, limits.Allows("image/png"))
+	assert.True(t, limits.Allows("application/pdf"))
+	assert.False(t, limits.Allows("application/zip"))
+	assert.False(t, limits.Allows("imagex/png"))
+
+	assert.True(t, policy.Limits{}.Allows("application/zip"))
+	assert.True(t, policy.Limits{AllowedTypes: []string{"*/*"}}.Allows("application/zip"))
+}
+
+func TestMediaType(t *testing.T) {
+	assert.Equal(t, "image/png", policy.MediaType("a.png", "application/octet-stream"))
+	assert.Equal(t, "text/csv", policy.MediaType("report", "text/csv; charset=utf-8"))
+	assert.Equal(t, "application/octet-stream", policy.MediaType("report", ""))
+}
+
+func TestValidType(t *testing.T) {
+	for _, valid := range []string{"image/png", "image/*", "*/*"} {
+		assert.NoError(t, policy.ValidType(valid), valid)
+	}
+	for _, invalid := range []string{"image", "/png", "image/", "*/png", "text/plain;q=1", "a/b,c/d"} {
+		assert.Error(t, policy.ValidType(invalid), invalid)
+	}
+}
@@ -0,0 +1,410 @@
+// Package presign signs short-lived download links that work without an Authorization header.
+package presign
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const keySize = 32
+
+const nonceSize = 16
+
+const linkIdSize = 8
+
+// maxLinkClients bounds the clients told apart per link; past it UniqueClients stops growing
+const maxLinkClients = 10000
+
+type Config struct {
+	// used when the client does not ask for a specific time to live
+	TimeToLive    time.Duration
+	MaxTimeToLive time.Duration
+}
+
+// Signer keeps its key in memory only, so links die with the process
+// and so does the record of used one-time links and of the uses of links.
+type Signer struct {
+	key    []byte
+	ttl    time.Duration
+	maxTTL time.Duration
+
+	mu sync.Mutex
+	// nonces of consumed one-time links until they would have expired anyway
+	used map[string]time.Time
+	// links by id until they expire
+	links map[string]*linkState
+}
+
+type linkState struct {
+	fileId    string
+	ownerId   int64
+	expiresAt time.Time
+	// password hash of protected links
+	hash         []byte
+	maxDownloads int64
+	downloads    int64
+	clients      map[string]struct{}
+	lastAccess   time.Time
+}
+
+// Stats are the uses of a link so far
+type Stats struct {
+	FileId        string
+	ExpiresAt     time.Time
+	Downloads     int64
+	UniqueClients int
+	// zero if the link wasn't used yet
+	LastAccess time.Time
+	// zero if unlimited
+	MaxDownloads int64
+	Protected    bool
+}
+
+// Disabled reports whether the link used up its downloads
+func (st Stats) Disabled() bool {
+	return st.MaxDownloads > 0 && st.Downloads >= st.MaxDownloads
+}
+
+// ErrNoLink is returned for links that expired, were never signed, or belong to someone else
+var ErrNoLink = errors.New("presign: no such link")
+
+type InvalidLinkError struct {
+	Reason string
+}
+
+func (err InvalidLinkError) Error() string {
+	return fmt.Sprintf("invalid presigned link: %s", err.Reason)
+}
+
+// PasswordError is returned for protected links verified without their password or with a wrong one
+type PasswordError struct {
+	Missing bool
+}
+
+func (err PasswordError) Error() string {
+	if err.Missing {
+		return "presigned link needs a password"
+	}
+	return "wrong password of presigned link"
+}
+
+type TimeToLiveError struct {
+	Max time.Duration
+}
+
+func (err TimeToLiveError) Error() string {
+	return fmt.Sprintf("time to live must be positive and at most %s", err.Max)
+}
+
+// Restriction keeps a link to clients of the listed countries and networks; an empty list
+// doesn't restrict. Restrictions are signed into the link, so they can't be stripped off it.
+type Restriction struct {
+	// ISO 3166 alpha-2 codes, upper case
+	Countries []string
+	Networks  []netip.Prefix
+}
+
+func (r Restriction) Empty() bool {
+	return len(r.Countries) == 0 && len(r.Networks) == 0
+}
+
+type Link struct {
+	// Id tells the link apart from other links to the same file, e.g. in the audit log
+	Id        string
+	Query     url.Values
+	ExpiresAt time.Time
+}
+
+// Grant is what a verified link allows
+type Grant struct {
+	OwnerId     int64
+	LinkId      string
+	Restriction Restriction
+	// nonce of one-time links, consumed by Use
+	nonce     string
+	expiresAt time.Time
+}
+
+func New(cfg Config) (*Signer, error) {
+	const op = "presign.New"
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("%s: rand.Read: %w", op, err)
+	}
+
+	return &Signer{
+		key:    key,
+		ttl:    cfg.TimeToLive,
+		maxTTL: cfg.MaxTimeToLive,
+		used:   make(map[string]time.Time),
+		links:  make(map[string]*linkState),
+	}, nil
+}
+
+// Sign returns the query of a link to the file; ttl of zero means the configured default.
+func (s *Signer) Sign(fileId string, ownerId int64, ttl time.Duration, oneTime bool, restrict Restriction) (Link, error) {
+	const op = "presign.Signer.Sign"
+
+	if ttl == 0 {
+		ttl = s.ttl
+	}
+	if ttl <= 0 || ttl > s.maxTTL {
+		return Link{}, TimeToLiveError{Max: s.maxTTL}
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	id := make([]byte, linkIdSize)
+	if _, err := rand.Read(id); err != nil {
+		return Link{}, fmt.Errorf("%s: rand.Read: %w", op, err)
+	}
+
+	query := url.Values{}
+	query.Set("link", hex.EncodeToString(id))
+	query.Set("user", strconv.FormatInt(ownerId, 10))
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	if oneTime {
+		nonce := make([]byte, nonceSize)
+		if _, err := rand.Read(nonce); err != nil {
+			return Link{}, fmt.Errorf("%s: rand.Read: %w", op, err)
+		}
+		query.Set("once", hex.EncodeToString(nonce))
+	}
+	if len(restrict.Countries) != 0 {
+		query.Set("countries", strings.Join(restrict.Countries, ","))
+	}
+	if len(restrict.Networks) != 0 {
+		networks := make([]string, 0, len(restrict.Networks))
+		for _, prefix := range restrict.Networks {
+			networks = append(networks, prefix.String())
+		}
+		query.Set("networks", strings.Join(networks, ","))
+	}
+	query.Set("signature", s.sign(fileId, query))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, st := range s.links {
+		if now.After(st.expiresAt) {
+			delete(s.links, id)
+		}
+	}
+	s.links[query.Get("link")] = &linkState{
+		fileId:    fileId,
+		ownerId:   ownerId,
+		expiresAt: expiresAt,
+		clients:   make(map[string]struct{}),
+	}
+
+	return Link{Id: query.Get("link"), Query: query, ExpiresAt: expiresAt}, nil
+}
+
+// Protect makes the link need the password; only its hash is kept, in memory like the key
+func (s *Signer) Protect(link Link, password string) error {
+	const op = "presign.Signer.Protect"
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.links[link.Id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, ErrNoLink)
+	}
+	st.hash = hash
+	return nil
+}
+
+// Protected reports whether the link of the id needs a password
+func (s *Signer) Protected(linkId string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.links[linkId]
+	return ok && st.hash != nil
+}
+
+// LimitDownloads disables the link once it was used for n downloads, see Use
+func (s *Signer) LimitDownloads(link Link, n int64) error {
+	const op = "presign.Signer.LimitDownloads"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.links[link.Id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, ErrNoLink)
+	}
+	st.maxDownloads = n
+	return nil
+}
+
+// Use counts a download through a verified link by the client, an address without port, once the
+// link passed any other checks and the file was opened, and consumes the link if it is one-time;
+// links that used up their downloads are invalid
+func (s *Signer) Use(grant Grant, client string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.links[grant.LinkId]
+	if !ok {
+		return InvalidLinkError{Reason: "link expired"}
+	}
+	if st.maxDownloads > 0 && st.downloads >= st.maxDownloads {
+		return InvalidLinkError{Reason: "download limit reached"}
+	}
+
+	if grant.nonce != "" {
+		now := time.Now()
+		for usedNonce, until := range s.used {
+			if now.After(until) {
+				delete(s.used, usedNonce)
+			}
+		}
+
+		if _, ok := s.used[grant.nonce]; ok {
+			return InvalidLinkError{Reason: "link already used"}
+		}
+		s.used[grant.nonce] = grant.expiresAt
+	}
+
+	st.downloads++
+	st.lastAccess = time.Now()
+	if _, ok := st.clients[client]; ok || len(st.clients) < maxLinkClients {
+		st.clients[client] = struct{}{}
+	}
+	return nil
+}
+
+// Stats returns the uses of the link of the id, if it is one of the owner's
+func (s *Signer) Stats(linkId string, ownerId int64) (Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.links[linkId]
+	if !ok || st.ownerId != ownerId || time.Now().After(st.expiresAt) {
+		return Stats{}, ErrNoLink
+	}
+
+	return Stats{
+		FileId:        st.fileId,
+		ExpiresAt:     st.expiresAt,
+		Downloads:     st.downloads,
+		UniqueClients: len(st.clients),
+		LastAccess:    st.lastAccess,
+		MaxDownloads:  st.maxDownloads,
+		Protected:     st.hash != nil,
+	}, nil
+}
+
+func (s *Signer) sign(fileId string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.key)
+	for _, part := range []string{
+		fileId,
+		query.Get("link"),
+		query.Get("user"),
+		query.Get("expires"),
+		query.Get("once"),
+		query.Get("countries"),
+		query.Get("networks"),
+	} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a link produced by Sign, and the password of protected links. One-time links are
+// only consumed by Use, so that links turned down here or by their restriction, which is left
+// to the caller to enforce, stay usable.
+// A PasswordError comes with the grant of the link, which is genuine, so that failures can be recorded.
+func (s *Signer) Verify(fileId string, query url.Values, password string) (Grant, error) {
+	expected, err := hex.DecodeString(s.sign(fileId, query))
+	if err != nil {
+		return Grant{}, err
+	}
+
+	actual, err := hex.DecodeString(query.Get("signature"))
+	if err != nil || !hmac.Equal(expected, actual) {
+		return Grant{}, InvalidLinkError{Reason: "bad signature"}
+	}
+
+	unix, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return Grant{}, InvalidLinkError{Reason: "bad expiry"}
+	}
+	expiresAt := time.Unix(unix, 0)
+
+	now := time.Now()
+	if now.After(expiresAt) {
+		return Grant{}, InvalidLinkError{Reason: "link expired"}
+	}
+
+	grant := Grant{LinkId: query.Get("link"), nonce: query.Get("once"), expiresAt: expiresAt}
+	grant.OwnerId, err = strconv.ParseInt(query.Get("user"), 10, 64)
+	if err != nil {
+		return Grant{}, InvalidLinkError{Reason: "bad user"}
+	}
+
+	if countries := query.Get("countries"); countries != "" {
+		grant.Restriction.Countries = strings.Split(countries, ",")
+	}
+	if networks := query.Get("networks"); networks != "" {
+		for _, network := range strings.Split(networks, ",") {
+			prefix, err := netip.ParsePrefix(network)
+			if err != nil {
+				return Grant{}, InvalidLinkError{Reason: "bad networks"}
+			}
+			grant.Restriction.Networks = append(grant.Restriction.Networks, prefix)
+		}
+	}
+
+	var hash []byte
+	s.mu.Lock()
+	if st, ok := s.links[grant.LinkId]; ok {
+		hash = st.hash
+	}
+	s.mu.Unlock()
+	if hash != nil {
+		if password == "" {
+			return grant, PasswordError{Missing: true}
+		}
+		if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+			return grant, PasswordError{}
+		}
+	}
+
+	// Use checks again, as the link may get used up meanwhile; these spare opening the file for nothing
+	s.mu.Lock()
+	_, used := s.used[grant.nonce]
+	st, ok := s.links[grant.LinkId]
+	exhausted := ok && st.maxDownloads > 0 && st.downloads >= st.maxDownloads
+	s.mu.Unlock()
+	if grant.nonce != "" && used {
+		return Grant{}, InvalidLinkError{Reason: "link already used"}
+	}
+	if exhausted {
+		return Grant{}, InvalidLinkError{Reason: "download limit reached"}
+	}
+
+	return grant, nil
+}
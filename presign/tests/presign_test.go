@@ -0,0 +1,187 @@
+package presign_test
+
+import (
+	"cloud-storage/presign"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigner(t *testing.T) *presign.Signer {
+	s, err := presign.New(presign.Config{TimeToLive: time.Minute, MaxTimeToLive: time.Hour})
+	require.NoError(t, err)
+	return s
+}
+
+func TestSignAndVerify(t *testing.T) {
+	s := newSigner(t)
+
+	link, err := s.Sign("file", 7, 0, false, presign.Restriction{})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), link.ExpiresAt, 2*time.Second)
+
+	for i := 0; i < 2; i++ {
+		grant, err := s.Verify("file", link.Query, "")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), grant.OwnerId)
+		assert.Equal(t, link.Id, grant.LinkId)
+		assert.True(t, grant.Restriction.Empty())
+	}
+}
+
+func TestVerifyRejectsTamperedLinks(t *testing.T) {
+	s := newSigner(t)
+
+	link, err := s.Sign("file", 7, 0, false, presign.Restriction{})
+	require.NoError(t, err)
+
+	_, err = s.Verify("other-file", link.Query, "")
+	assert.ErrorAs(t, err, &presign.InvalidLinkError{})
+
+	for _, param := range []string{"link", "user", "expires", "signature"} {
+		query := url.Values{}
+		for k, v := range link.Query {
+			query[k] = v
+		}
+		query.Set(param, "8")
+
+		_, err := s.Verify("file", query, "")
+		assert.ErrorAs(t, err, &presign.InvalidLinkError{}, param)
+	}
+
+	_, err = newSigner(t).Verify("file", link.Query, "")
+	assert.ErrorAs(t, err, &presign.InvalidLinkError{})
+}
+
+func TestVerifyRejectsExpiredLinks(t *testing.T) {
+	s := newSigner(t)
+
+	link, err := s.Sign("file", 7, time.Second, false, presign.Restriction{})
+	require.NoError(t, err)
+
+	expires, err := strconv.ParseInt(link.Query.Get("expires"), 10, 64)
+	require.NoError(t, err)
+	time.Sleep(time.Until(time.Unix(expires+1, 0)))
+
+	_, err = s.Verify("file", link.Query, "")
+	assert.Equal(t, presign.InvalidLinkError{Reason: "link expired"}, err)
+}
+
+func TestOneTimeLinks(t *testing.T) {
+	s := newSigner(t)
+
+	link, err := s.Sign("file", 7, 0, true, presign.Restriction{})
+	require.NoError(t, err)
+
+	// only a use consumes the link, a link turned down after it was verified stays usable
+	_, err = s.Verify("file", link.Query, "")
+	require.NoError(t, err)
+	grant, err := s.Verify("file", link.Query, "")
+	require.NoError(t, err)
+	require.NoError(t, s.Use(grant, "192.0.2.1"))
+
+	_, err = s.Verify("file", link.Query, "")
+	assert.Equal(t, presign.InvalidLinkError{Reason: "link already used"}, err)
+	// a grant verified before the use doesn't make it twice
+	assert.Equal(t, presign.InvalidLinkError{Reason: "link already used"}, s.Use(grant, "192.0.2.1"))
+
+	// dropping the nonce breaks the signature instead of making the link reusable
+	link.Query.Del("once")
+	_, err = s.Verify("file", link.Query, "")
+	assert.Equal(t, presign.InvalidLinkError{Reason: "bad signature"}, err)
+}
+
+func TestSignRejectsTimeToLive(t *testing.T) {
+	s := newSigner(t)
+
+	for _, ttl := range []time.Duration{-time.Second, 2 * time.Hour} {
+		_, err := s.Sign("file", 7, ttl, false, presign.Restriction{})
+		assert.Equal(t, presign.TimeToLiveError{Max: time.Hour}, err)
+	}
+}
+
+func TestRestrictedLinks(t *testing.T) {
+	s := newSigner(t)
+
+	restrict := presign.Restriction{
+		Countries: []string{"DE", "FR"},
+		Networks:  []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}
+	link, err := s.Sign("file", 7, 0, false, restrict)
+	require.NoError(t, err)
+
+	grant, err := s.Verify("file", link.Query, "")
+	require.NoError(t, err)
+	assert.Equal(t, restrict, grant.Restriction)
+
+	// a restriction can be neither stripped nor widened
+	for param, value := range map[string]string{"countries": "", "networks": "0.0.0.0/0"} {
+		query := url.Values{}
+		for k, v := range link.Query {
+			query[k] = v
+		}
+		query.Set(param, value)
+
+		_, err := s.Verify("file", query, "")
+		assert.Equal(t, presign.InvalidLinkError{Reason: "bad signature"}, err, param)
+	}
+}
+
+func TestProtectedLinks(t *testing.T) {
+	s := newSigner(t)
+
+	link, err := s.Sign("file", 7, 0, true, presign.Restriction{})
+	require.NoError(t, err)
+	require.NoError(t, s.Protect(link, "secret"))
+	assert.True(t, s.Protected(link.Id))
+
+	_, err = s.Verify("file", link.Query, "")
+	assert.Equal(t, presign.PasswordError{Missing: true}, err)
+	_, err = s.Verify("file", link.Query, "guess")
+	assert.Equal(t, presign.PasswordError{}, err)
+
+	// wrong passwords don't use up one-time links
+	grant, err := s.Verify("file", link.Query, "secret")
+	require.NoError(t, err)
+	require.NoError(t, s.Use(grant, "192.0.2.1"))
+	_, err = s.Verify("file", link.Query, "secret")
+	assert.Equal(t, presign.InvalidLinkError{Reason: "link already used"}, err)
+
+	other, err := s.Sign("file", 7, 0, false, presign.Restriction{})
+	require.NoError(t, err)
+	assert.False(t, s.Protected(other.Id))
+}
+
+func TestUseAndStats(t *testing.T) {
+	s := newSigner(t)
+
+	link, err := s.Sign("file", 7, 0, false, presign.Restriction{})
+	require.NoError(t, err)
+	require.NoError(t, s.LimitDownloads(link, 3))
+
+	grant, err := s.Verify("file", link.Query, "")
+	require.NoError(t, err)
+	for _, client := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.1"} {
+		require.NoError(t, s.Use(grant, client))
+	}
+	assert.Equal(t, presign.InvalidLinkError{Reason: "download limit reached"}, s.Use(grant, "192.0.2.3"))
+
+	stats, err := s.Stats(link.Id, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "file", stats.FileId)
+	assert.Equal(t, int64(3), stats.Downloads)
+	assert.Equal(t, 2, stats.UniqueClients)
+	assert.WithinDuration(t, time.Now(), stats.LastAccess, time.Second)
+	assert.True(t, stats.Disabled())
+
+	// links of others can't be told from ones that don't exist
+	_, err = s.Stats(link.Id, 8)
+	assert.ErrorIs(t, err, presign.ErrNoLink)
+	_, err = s.Stats("unknown", 7)
+	assert.ErrorIs(t, err, presign.ErrNoLink)
+}
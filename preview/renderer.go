@@ -0,0 +1,61 @@
+// Package preview renders the first page of documents as an image, for previews of files
+// that aren't text.
+package preview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ContentType is what every Renderer writes
+const ContentType = "image/png"
+
+// Renderer turns the first page of a PDF read from r into a PNG written into w
+type Renderer interface {
+	RenderFirstPage(ctx context.Context, r io.Reader, w io.Writer) error
+}
+
+// Pdftoppm renders with pdftoppm of poppler, which reads the PDF from stdin
+type Pdftoppm struct {
+	Path string
+	// Resolution of the image in DPI
+	Resolution int
+	// Timeout stops rendering that takes longer; zero means no limit
+	Timeout time.Duration
+}
+
+func (p Pdftoppm) RenderFirstPage(ctx context.Context, r io.Reader, w io.Writer) error {
+	const op = "preview.Pdftoppm.RenderFirstPage"
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	// without an output root the page goes to stdout
+	cmd := exec.CommandContext(
+		ctx,
+		p.Path,
+		"-png", "-singlefile",
+		"-f", "1", "-l", "1",
+		"-r", fmt.Sprint(p.Resolution),
+		"-",
+	)
+	cmd.Stdin = r
+	cmd.Stdout = w
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", op, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+// Package privacy carries out the requests of users to have their accounts erased.
+//
+// The files, streams and exports of the user are deleted, and then the DECs their contents, metadata and comments
+// are encrypted with are shredded: every user has DECs of their own for these. Copies in backups and replicas
+// can't be decrypted anymore once the keys are gone, whenever they age out. Blobs stored with convergent encryption
+// are encrypted with keys of their content instead, and stay readable as long as another user keeps the content.
+package privacy
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	"cloud-storage/hls"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ExportRemover removes the exports of a user, see export.Exporter
+type ExportRemover interface {
+	RemoveUserExports(ownerId int64) error
+}
+
+type Config struct {
+	Blobs   *blobstore.Store
+	Exports ExportRemover
+	Keys    encryption.KeyShredder
+}
+
+type Eraser struct {
+	db  db_access.DbAccess
+	cfg Config
+	log *slog.Logger
+}
+
+func New(db db_access.DbAccess, cfg Config, log *slog.Logger) *Eraser {
+	return &Eraser{
+		db:  db,
+		cfg: cfg,
+		log: log.With(slog.String("component", "privacy")),
+	}
+}
+
+// Erase erases the accounts of the pending requests, the earliest deadline first, and returns
+// how many it has erased; a request that fails stays pending for the next run
+func (e *Eraser) Erase(ctx context.Context, now time.Time) (int, error) {
+	const op = "privacy.Eraser.Erase"
+
+	requests, err := e.db.GetPendingDeletionRequests()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	erased := 0
+	var errs []error
+	for _, req := range requests {
+		if err := ctx.Err(); err != nil {
+			return erased, err
+		}
+
+		log := e.log.With(slog.Int64("user-id", req.UserId))
+		if err := e.erase(ctx, log, req.UserId, now); err != nil {
+			log.Error("Could not erase account", slogext.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		erased++
+		log.Info("Erased account", slog.Time("deadline", time.Time(req.Deadline)))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return erased, fmt.Errorf("%s: %w", op, err)
+	}
+	return erased, nil
+}
+
+func (e *Eraser) erase(ctx context.Context, log *slog.Logger, userId int64, now time.Time) error {
+	files, err := e.db.GetUserFiles(userId)
+	if err != nil {
+		return fmt.Errorf("get files: %w", err)
+	}
+
+	for _, file := range files {
+		blob, orphaned, err := e.db.DeleteFile(file.GeneratedName)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("delete file %s: %w", file.GeneratedName, err)
+		}
+
+		// the stream of a blob others keep is encrypted with the keys of the user too; it is built again on demand
+		if err := hls.Remove(e.cfg.Blobs.Dir(), blob.Name); err != nil {
+			log.Error("Could not remove stream of erased file", slogext.Error(err), slog.String("blob", blob.Name))
+		}
+
+		if !orphaned {
+			continue
+		}
+
+		// a blob left behind is found by orphan-gc, so the erasure carries on without it
+		if err := e.cfg.Blobs.Remove(ctx, blob.Backend, blob.Name); err != nil {
+			log.Error("Could not remove blob of erased file", slogext.Error(err), slog.String("blob", blob.Name))
+		}
+	}
+
+	if err := e.cfg.Exports.RemoveUserExports(userId); err != nil {
+		return fmt.Errorf("remove exports: %w", err)
+	}
+
+	// whatever is left of the user in backups, replicas and stray files can't be decrypted after this
+	if err := e.cfg.Keys.ShredUserKeys(userId); err != nil {
+		return fmt.Errorf("shred keys: %w", err)
+	}
+
+	if err := e.db.EraseUser(userId); err != nil {
+		return fmt.Errorf("erase user: %w", err)
+	}
+
+	if err := e.db.CompleteDeletionRequest(userId, db_access.Time(now)); err != nil {
+		return fmt.Errorf("complete request: %w", err)
+	}
+
+	return nil
+}
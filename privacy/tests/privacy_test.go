@@ -0,0 +1,81 @@
+package privacy_test
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/privacy"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// erasedUsers records the users something was removed for
+type erasedUsers []int64
+
+func (u *erasedUsers) RemoveUserExports(ownerId int64) error {
+	*u = append(*u, ownerId)
+	return nil
+}
+
+func (u *erasedUsers) ShredUserKeys(ownerId int64) error {
+	*u = append(*u, ownerId)
+	return nil
+}
+
+func TestErase(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"own", "shared"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("blob"), 0o600))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".hls", name), 0o700))
+	}
+
+	now := time.Now().Truncate(time.Second)
+	db := db_access_mocks.NewDbAccess(t)
+	db.EXPECT().GetPendingDeletionRequests().Return([]db_access.DeletionRequest{
+		{UserId: 1, Deadline: db_access.Time(now)},
+		{UserId: 2, Deadline: db_access.Time(now.Add(time.Hour))},
+	}, nil).Once()
+
+	db.EXPECT().GetUserFiles(int64(1)).Return([]db_access.File{{GeneratedName: "a"}, {GeneratedName: "b"}}, nil).Once()
+	db.EXPECT().DeleteFile("a").Return(db_access.Blob{Name: "own"}, true, nil).Once()
+	// the blob of a copy kept by another user stays
+	db.EXPECT().DeleteFile("b").Return(db_access.Blob{Name: "shared"}, false, nil).Once()
+	db.EXPECT().EraseUser(int64(1)).Return(nil).Once()
+	db.EXPECT().CompleteDeletionRequest(int64(1), db_access.Time(now)).Return(nil).Once()
+
+	// a request that fails stays pending, without the others waiting on it
+	db.EXPECT().GetUserFiles(int64(2)).Return(nil, errors.New("db is down")).Once()
+
+	var exports, keys erasedUsers
+	eraser := privacy.New(db, privacy.Config{
+		Blobs:   blobstore.NewStore(dir, storage.DurabilityNone, nil),
+		Exports: &exports,
+		Keys:    &keys,
+	}, slogext.NewDiscardLogger())
+
+	erased, err := eraser.Erase(context.Background(), now)
+	assert.Error(t, err)
+	assert.Equal(t, 1, erased)
+
+	_, err = os.Stat(filepath.Join(dir, "own"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "shared"))
+	assert.NoError(t, err)
+
+	// streams are encrypted for the user, so the one of the shared blob goes as well
+	for _, name := range []string{"own", "shared"} {
+		_, err = os.Stat(filepath.Join(dir, ".hls", name))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+	assert.Equal(t, erasedUsers{1}, exports)
+	assert.Equal(t, erasedUsers{1}, keys)
+}
@@ -0,0 +1,185 @@
+// Package repair cross-checks the storage journal, the db and the storage dir
+// after a crash. Check must only run while the server is stopped; CollectOrphans is safe while it serves.
+package repair
+
+import (
+	"cloud-storage/db_access"
+	"cloud-storage/storage"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Report struct {
+	Recovery storage.Recovery
+	// Missing blobs are referenced by the db but absent from the storage dir
+	Missing []string
+	// Corrupt blobs are referenced by the db but don't match the checksum journaled when they were written,
+	// or the one in their name if they are content-addressed
+	Corrupt []string
+	// Orphaned blobs sit in the storage dir without a file referencing them
+	Orphaned []string
+	// Abandoned blobs were never completely written and no file references them
+	Abandoned []string
+}
+
+// Damaged reports whether files lost their contents; nothing but a backup brings those back
+func (r Report) Damaged() bool {
+	return len(r.Missing) > 0 || len(r.Corrupt) > 0
+}
+
+// Check recovers the journal of dir and compares the blobs the db references with what is on disk.
+// With fix, orphaned and abandoned blobs are removed and the journal forgets about the latter;
+// missing and corrupt blobs are only reported since removing their files would lose user data.
+func Check(db db_access.FileRepo, dir string, durability storage.Durability, fix bool) (Report, error) {
+	const op = "repair.Check"
+
+	recovery, err := storage.Recover(dir, durability)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	names, err := db.ListBlobNames()
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+	referenced := make(map[string]bool, len(names))
+	for _, name := range names {
+		referenced[name] = true
+	}
+
+	stored, err := storedBlobs(dir)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	report := Report{Recovery: recovery}
+	incomplete := make(map[string]bool, len(recovery.Incomplete))
+	for _, name := range recovery.Incomplete {
+		incomplete[name] = true
+
+		if !referenced[name] {
+			report.Abandoned = append(report.Abandoned, name)
+		} else if stored[name] {
+			report.Corrupt = append(report.Corrupt, name)
+		}
+	}
+
+	for _, name := range names {
+		if !stored[name] {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+
+		// content-addressed blobs are verified against their name, however long ago they were written
+		if want, ok := storage.ContentChecksum(name); ok && !incomplete[name] {
+			checksum, err := storage.Checksum(filepath.Join(dir, name))
+			if err != nil {
+				return Report{}, fmt.Errorf("%s: checksum %s: %w", op, name, err)
+			}
+			if checksum != want {
+				report.Corrupt = append(report.Corrupt, name)
+			}
+		}
+	}
+
+	for name := range stored {
+		if !referenced[name] && !incomplete[name] {
+			report.Orphaned = append(report.Orphaned, name)
+		}
+	}
+
+	slices.Sort(report.Missing)
+	slices.Sort(report.Corrupt)
+	slices.Sort(report.Orphaned)
+	slices.Sort(report.Abandoned)
+
+	if !fix {
+		return report, nil
+	}
+
+	for _, name := range slices.Concat(report.Orphaned, report.Abandoned) {
+		if err := storage.Remove(dir, durability, name); err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// after Recover the journal only holds incomplete puts
+	entries, err := storage.ReadJournal(dir)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+
+	entries = slices.DeleteFunc(entries, func(entry storage.JournalEntry) bool {
+		return entry.Op != storage.JournalPut || !referenced[entry.Id]
+	})
+	if err := storage.RewriteJournal(dir, durability, entries); err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return report, nil
+}
+
+// CollectOrphans removes the blobs of dir that no file references and that are older than minAge as of now,
+// returning their names. Uploads may store a blob shortly before the row referencing it, which minAge
+// has to cover; blobs being written are in the temp dir and never listed.
+func CollectOrphans(db db_access.FileRepo, dir string, durability storage.Durability, minAge time.Duration, now time.Time) ([]string, error) {
+	const op = "repair.CollectOrphans"
+
+	// the dir is read before the db, so that a blob stored and referenced in between is seen referenced
+	stored, err := storedBlobs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	names, err := db.ListBlobNames()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, name := range names {
+		delete(stored, name)
+	}
+
+	removed := make([]string, 0)
+	for name := range stored {
+		// stat again right before removing, in case a content-addressed blob was written over since
+		info, err := os.Stat(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return removed, fmt.Errorf("%s: os.Stat: %w", op, err)
+		}
+		if now.Sub(info.ModTime()) < minAge {
+			continue
+		}
+
+		if err := storage.Remove(dir, durability, name); err != nil {
+			return removed, fmt.Errorf("%s: %w", op, err)
+		}
+		removed = append(removed, name)
+	}
+
+	slices.Sort(removed)
+	return removed, nil
+}
+
+// storedBlobs lists the blobs in dir; dot entries hold the journal and other service data
+func storedBlobs(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir: %w", err)
+	}
+
+	stored := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			stored[entry.Name()] = true
+		}
+	}
+
+	return stored, nil
+}
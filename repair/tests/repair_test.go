@@ -0,0 +1,144 @@
+package repair_test
+
+import (
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/db_access/sqlite"
+	"cloud-storage/repair"
+	"cloud-storage/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func commit(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+
+	file, err := storage.CreateTemp(dir, storage.DurabilityNone)
+	require.NoError(t, err)
+	defer file.Discard()
+
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, file.CommitAs(name))
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	for _, name := range []string{"fine", "missing", "corrupt", "crashed"} {
+		require.NoError(t, db.AddFile(name, "enc:"+name, 1, 4))
+	}
+
+	commit(t, dir, "fine", "blob")
+	commit(t, dir, "corrupt", "blob")
+	commit(t, dir, "orphan", "blob")
+
+	// crashes after the intent was journaled
+	journal, err := os.OpenFile(filepath.Join(dir, storage.JournalName), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = journal.WriteString(`{"op":"put","id":"corrupt","checksum":"00"}` + "\n" +
+		`{"op":"put","id":"crashed","checksum":"00"}` + "\n" +
+		`{"op":"put","id":"abandoned","checksum":"00"}` + "\n" +
+		`{"op":"remove","id":"removed"}` + "\n" +
+		`{"op":"put","id":"to`)
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "abandoned"), []byte("bl"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "removed"), []byte("blob"), 0o600))
+
+	report, err := repair.Check(db, dir, storage.DurabilityNone, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"removed"}, report.Recovery.Removed)
+	assert.ElementsMatch(t, []string{"corrupt", "crashed", "abandoned"}, report.Recovery.Incomplete)
+	assert.Equal(t, []string{"crashed", "missing"}, report.Missing)
+	assert.Equal(t, []string{"corrupt"}, report.Corrupt)
+	assert.Equal(t, []string{"orphan"}, report.Orphaned)
+	assert.Equal(t, []string{"abandoned"}, report.Abandoned)
+	assert.True(t, report.Damaged())
+
+	_, err = os.Stat(filepath.Join(dir, "removed"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "orphan"))
+	assert.NoError(t, err)
+
+	report, err = repair.Check(db, dir, storage.DurabilityNone, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orphan"}, report.Orphaned)
+	assert.Equal(t, []string{"abandoned"}, report.Abandoned)
+
+	for _, name := range []string{"orphan", "abandoned"} {
+		_, err = os.Stat(filepath.Join(dir, name))
+		assert.ErrorIs(t, err, os.ErrNotExist, name)
+	}
+
+	// damaged blobs keep being reported until someone restores them
+	entries, err := storage.ReadJournal(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.JournalEntry{
+		{Op: storage.JournalPut, Id: "corrupt", Checksum: "00"},
+		{Op: storage.JournalPut, Id: "crashed", Checksum: "00"},
+	}, entries)
+
+	report, err = repair.Check(db, dir, storage.DurabilityNone, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"crashed", "missing"}, report.Missing)
+	assert.Equal(t, []string{"corrupt"}, report.Corrupt)
+	assert.Empty(t, report.Orphaned)
+	assert.Empty(t, report.Abandoned)
+}
+
+func TestCheck_ContentAddressed(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	names := make(map[string]string)
+	for _, content := range []string{"fine", "rotten"} {
+		file, err := storage.CreateTemp(dir, storage.DurabilityNone)
+		require.NoError(t, err)
+		_, err = file.WriteString(content)
+		require.NoError(t, err)
+		names[content] = file.ContentAddress()
+		require.NoError(t, file.CommitAs(names[content]))
+
+		require.NoError(t, db.AddFileCopy(content, "enc:"+content, 1, names[content], 4))
+	}
+
+	// bit rot long after the journal forgot about the blob
+	require.NoError(t, os.WriteFile(filepath.Join(dir, names["rotten"]), []byte("rotted"), 0o600))
+
+	report, err := repair.Check(db, dir, storage.DurabilityNone, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Missing)
+	assert.Equal(t, []string{names["rotten"]}, report.Corrupt)
+	assert.Empty(t, report.Orphaned)
+}
+
+func TestCollectOrphans(t *testing.T) {
+	dir := t.TempDir()
+	db := db_access_mocks.NewFileRepo(t)
+	db.EXPECT().ListBlobNames().Return([]string{"referenced", "missing"}, nil)
+
+	for _, name := range []string{"referenced", "orphan", "fresh"} {
+		commit(t, dir, name, "blob")
+	}
+	now := time.Now()
+	for _, name := range []string{"referenced", "orphan"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), now.Add(-2*time.Hour), now.Add(-2*time.Hour)))
+	}
+
+	removed, err := repair.CollectOrphans(db, dir, storage.DurabilityNone, time.Hour, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orphan"}, removed)
+
+	assert.NoFileExists(t, filepath.Join(dir, "orphan"))
+	assert.FileExists(t, filepath.Join(dir, "referenced"))
+	assert.FileExists(t, filepath.Join(dir, "fresh"))
+}
@@ -0,0 +1,456 @@
+// Package replication copies encrypted blobs, file rows and the wrapped keys they need to a secondary
+// backend, so the storage can be recovered from there if the primary is lost.
+//
+// File rows follow the change feed of the db, which records every change while the replicator has a cursor
+// on it. Blobs are copied once they are committed: a blob is replicated when the db says it isn't yet,
+// which also covers blobs that predate the feed or were still being uploaded when their row was added.
+// Reconciliation checks the target against what the db believes it holds and rewrites every file record.
+package replication
+
+import (
+	"bytes"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// names on the target
+const (
+	blobsPrefix = "blobs/"
+	filesPrefix = "files/"
+	keysName    = "keys.json"
+)
+
+// changes, files and blobs are read from the db in pages of this many
+const pageSize = 256
+
+var ErrInvalidTarget = errors.New("invalid replication target")
+
+type Config struct {
+	// Target is the backend replicas are written to; it can't be the local one
+	Target string
+	// how often changes are replicated
+	Interval time.Duration
+	// how often the target is reconciled with the db; never if zero
+	ReconcileInterval time.Duration
+}
+
+// FileRecord is what the target keeps of a file row, next to its blob under blobs/
+type FileRecord struct {
+	Id string `json:"id"`
+	// Name is encrypted like files.fileName
+	Name      string `json:"name"`
+	OwnerId   int64  `json:"owner_id"`
+	Blob      string `json:"blob"`
+	Size      int64  `json:"size"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+// KeysRecord holds the keys blobs and names are encrypted with, as wrapped as they are in the db
+type KeysRecord struct {
+	DECs     []KeyRecord `json:"decs"`
+	IndexKey string      `json:"index_key,omitempty"`
+}
+
+type KeyRecord struct {
+	Id           int64  `json:"id"`
+	Value        string `json:"value"`
+	CreationTime int64  `json:"creation_time"`
+	KeyVersion   int64  `json:"key_version,omitempty"`
+	// OwnerId is the user the key is of, see db_access.DEC; keys of erased users are left out of the next record
+	OwnerId int64 `json:"owner_id,omitempty"`
+}
+
+// Replicator runs rounds of replication one at a time
+type Replicator struct {
+	db       db_access.DbAccess
+	blobs    *blobstore.Store
+	target   blobstore.Backend
+	cfg      Config
+	consumer string
+	// keys is the KeysRecord written last, so unchanged keys aren't written again every round
+	keys []byte
+	log  *slog.Logger
+}
+
+func New(db db_access.DbAccess, blobs *blobstore.Store, cfg Config, log *slog.Logger) (*Replicator, error) {
+	const op = "replication.New"
+
+	if cfg.Target == "" || cfg.Target == blobstore.Local {
+		return nil, fmt.Errorf("%s: %q: %w", op, cfg.Target, ErrInvalidTarget)
+	}
+
+	target, err := blobs.Backend(cfg.Target)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidTarget, err)
+	}
+
+	return &Replicator{
+		db:       db,
+		blobs:    blobs,
+		target:   target,
+		cfg:      cfg,
+		consumer: "replication:" + cfg.Target,
+		log:      log.With(slog.String("component", "replication"), slog.String("target", cfg.Target)),
+	}, nil
+}
+
+// Consumer is the name of the cursor of the replicator on the change feed
+func (r *Replicator) Consumer() string {
+	return r.consumer
+}
+
+// Register puts the cursor of the replicator on the change feed, which records nothing until some consumer has one.
+// It reports whether the replicator is new to the feed, and so has files to catch up on by reconciling.
+func (r *Replicator) Register() (bool, error) {
+	const op = "replication.Replicator.Register"
+
+	_, err := r.db.GetChangeCursor(r.consumer)
+	if err == nil {
+		return false, nil
+	} else if !errors.As(err, &db_access.NoRowsError{}) {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.db.SetChangeCursor(r.consumer, 0); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// Run replicates every interval and reconciles every reconcile interval until ctx is done
+func (r *Replicator) Run(ctx context.Context) {
+	registered, err := r.Register()
+	if err != nil {
+		r.log.Error("Could not register with the change feed", slogext.Error(err))
+		return
+	}
+
+	if registered {
+		r.log.Info("Replicating to a new target, reconciling first")
+		if err := r.Reconcile(ctx); err != nil {
+			r.log.Error("Could not reconcile", slogext.Error(err))
+		}
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	var reconcile <-chan time.Time
+	if r.cfg.ReconcileInterval > 0 {
+		reconcileTicker := time.NewTicker(r.cfg.ReconcileInterval)
+		defer reconcileTicker.Stop()
+		reconcile = reconcileTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Sync(ctx); err != nil {
+				r.log.Error("Could not replicate", slogext.Error(err))
+			}
+		case <-reconcile:
+			if err := r.Reconcile(ctx); err != nil {
+				r.log.Error("Could not reconcile", slogext.Error(err))
+			}
+		}
+	}
+}
+
+// Sync replicates keys, the changes since the last round and blobs that were not replicated yet.
+// It must not run concurrently with Reconcile or Run, which calls both.
+func (r *Replicator) Sync(ctx context.Context) error {
+	const op = "replication.Replicator.Sync"
+
+	if err := r.syncKeys(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.syncChanges(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.syncBlobs(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Replicator) syncKeys(ctx context.Context) error {
+	const op = "replication.Replicator.syncKeys"
+
+	decs, err := r.db.GetDECs()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	record := KeysRecord{DECs: make([]KeyRecord, 0, len(decs))}
+	for _, dec := range decs {
+		record.DECs = append(record.DECs, KeyRecord{
+			Id:           int64(dec.Id),
+			Value:        dec.Value,
+			CreationTime: time.Time(dec.CreationTime).Unix(),
+			KeyVersion:   dec.KeyVersion,
+			OwnerId:      dec.OwnerId,
+		})
+	}
+
+	record.IndexKey, err = r.db.GetIndexKey()
+	if err != nil && !errors.As(err, &db_access.NoRowsError{}) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("%s: json.Marshal: %w", op, err)
+	}
+
+	if bytes.Equal(data, r.keys) {
+		return nil
+	}
+
+	if err := r.target.Put(ctx, keysName, bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	r.keys = data
+
+	return nil
+}
+
+func (r *Replicator) syncChanges(ctx context.Context) error {
+	const op = "replication.Replicator.syncChanges"
+
+	cursor, err := r.db.GetChangeCursor(r.consumer)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var applied int
+	for {
+		changes, err := r.db.GetChanges(cursor, pageSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		var applyErr error
+		for _, change := range changes {
+			if applyErr = r.apply(ctx, change); applyErr != nil {
+				// later changes may undo this one, so they wait for it until the next round
+				applyErr = fmt.Errorf("change %d: %w", change.Seq, applyErr)
+				break
+			}
+			cursor = change.Seq
+			applied++
+		}
+
+		if len(changes) > 0 {
+			if err := r.db.SetChangeCursor(r.consumer, cursor); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if applyErr != nil {
+			return fmt.Errorf("%s: %w", op, applyErr)
+		}
+
+		if len(changes) < pageSize {
+			break
+		}
+	}
+
+	if applied > 0 {
+		r.log.Info("Replicated changes", slog.Int("changes", applied))
+		if err := r.db.RemoveReadChanges(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Replicator) apply(ctx context.Context, change db_access.Change) error {
+	switch change.Kind {
+	case db_access.ChangeCreated, db_access.ChangeUpdated:
+		file, err := r.db.GetFile(change.GeneratedName)
+		if errors.As(err, &db_access.NoRowsError{}) {
+			// its deletion is further on in the feed
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return r.putRecord(ctx, file)
+
+	case db_access.ChangeDeleted:
+		if err := r.target.Remove(ctx, filesPrefix+change.GeneratedName); err != nil {
+			return err
+		}
+
+		if change.BlobName != "" {
+			if err := r.target.Remove(ctx, blobsPrefix+change.BlobName); err != nil {
+				return err
+			}
+			return r.db.UnmarkBlobReplicated(r.cfg.Target, change.BlobName)
+		}
+		return nil
+
+	default:
+		r.log.Warn("Skipping change of unknown kind", slog.Int64("seq", change.Seq), slog.String("kind", string(change.Kind)))
+		return nil
+	}
+}
+
+func (r *Replicator) putRecord(ctx context.Context, file db_access.File) error {
+	record := FileRecord{
+		Id:      file.GeneratedName,
+		Name:    file.FileName,
+		OwnerId: file.OwnerId,
+		Blob:    file.BlobName,
+		Size:    file.Size,
+	}
+	if !time.Time(file.ExpiresAt).IsZero() {
+		record.ExpiresAt = time.Time(file.ExpiresAt).Unix()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return r.target.Put(ctx, filesPrefix+file.GeneratedName, bytes.NewReader(data), int64(len(data)))
+}
+
+func (r *Replicator) syncBlobs(ctx context.Context) error {
+	const op = "replication.Replicator.syncBlobs"
+
+	var after string
+	var copied, failed int
+	for {
+		blobs, err := r.db.GetUnreplicatedBlobs(r.cfg.Target, after, pageSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, blob := range blobs {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%s: %w", op, ctx.Err())
+			}
+			after = blob.Name
+
+			ok, err := r.copyBlob(ctx, blob)
+			if err != nil {
+				r.log.Error("Could not replicate blob", slogext.Error(err), slog.String("blob", blob.Name))
+				failed++
+			} else if ok {
+				copied++
+			}
+		}
+
+		if len(blobs) < pageSize {
+			break
+		}
+	}
+
+	if copied > 0 || failed > 0 {
+		r.log.Info("Replicated blobs", slog.Int("copied", copied), slog.Int("failed", failed))
+	}
+
+	return nil
+}
+
+// copyBlob reports false for blobs that aren't there, because they are still being uploaded or were removed since
+func (r *Replicator) copyBlob(ctx context.Context, blob db_access.Blob) (bool, error) {
+	obj, err := r.blobs.Open(ctx, blob.Backend, blob.Name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer obj.Close()
+
+	if err := r.target.Put(ctx, blobsPrefix+blob.Name, obj, obj.Size); err != nil {
+		return false, err
+	}
+
+	if err := r.db.MarkBlobReplicated(r.cfg.Target, blob.Name); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Reconcile removes replicas of blobs no file references anymore, forgets those missing on the target
+// so that the next round copies them again, and rewrites the record of every file.
+// Checking a blob opens it on the target, so reconciling is about as slow as reading every replica's first bytes.
+func (r *Replicator) Reconcile(ctx context.Context) error {
+	const op = "replication.Replicator.Reconcile"
+
+	replicated, err := r.db.GetReplicatedBlobs(r.cfg.Target)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var removed, missing int
+	for _, blob := range replicated {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+
+		if !blob.Referenced {
+			if err := r.target.Remove(ctx, blobsPrefix+blob.Name); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			if err := r.db.UnmarkBlobReplicated(r.cfg.Target, blob.Name); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			removed++
+			continue
+		}
+
+		obj, err := r.target.Open(ctx, blobsPrefix+blob.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if err := r.db.UnmarkBlobReplicated(r.cfg.Target, blob.Name); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			missing++
+			continue
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		obj.Close()
+	}
+
+	var after string
+	var records int
+	for {
+		files, err := r.db.GetFiles(after, pageSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, file := range files {
+			if err := r.putRecord(ctx, file); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			after = file.GeneratedName
+			records++
+		}
+
+		if len(files) < pageSize {
+			break
+		}
+	}
+
+	r.log.Info("Reconciled", slog.Int("removed", removed), slog.Int("missing", missing), slog.Int("records", records))
+	return nil
+}
@@ -0,0 +1,191 @@
+package replication_test
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	"cloud-storage/replication"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	target   = "dr"
+	consumer = "replication:dr"
+)
+
+// newReplicator returns the replicator with the storage dir and the dir of the target backend
+func newReplicator(t *testing.T, db db_access.DbAccess) (*replication.Replicator, string, string) {
+	dir := t.TempDir()
+	targetDir := t.TempDir()
+	for _, sub := range []string{"blobs", "files"} {
+		require.NoError(t, os.Mkdir(filepath.Join(targetDir, sub), 0o700))
+	}
+
+	blobs := blobstore.NewStore(dir, storage.DurabilityNone, map[string]blobstore.Backend{
+		target: blobstore.NewLocal(targetDir, storage.DurabilityNone),
+	})
+
+	r, err := replication.New(db, blobs, replication.Config{Target: target}, slogext.NewDiscardLogger())
+	require.NoError(t, err)
+
+	return r, dir, targetDir
+}
+
+func expectNoKeys(db *db_access_mocks.DbAccess) {
+	db.EXPECT().GetDECs().Return(nil, nil).Maybe()
+	db.EXPECT().GetIndexKey().Return("", db_access.NoRowsError{}).Maybe()
+}
+
+func TestNew(t *testing.T) {
+	blobs := blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil)
+	for _, name := range []string{"", blobstore.Local, "missing"} {
+		_, err := replication.New(db_access_mocks.NewDbAccess(t), blobs, replication.Config{Target: name}, slogext.NewDiscardLogger())
+		assert.ErrorIs(t, err, replication.ErrInvalidTarget, name)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	r, _, _ := newReplicator(t, db)
+
+	db.EXPECT().GetChangeCursor(consumer).Return(0, db_access.NoRowsError{}).Once()
+	db.EXPECT().SetChangeCursor(consumer, int64(0)).Return(nil).Once()
+	registered, err := r.Register()
+	require.NoError(t, err)
+	assert.True(t, registered)
+
+	db.EXPECT().GetChangeCursor(consumer).Return(7, nil).Once()
+	registered, err = r.Register()
+	require.NoError(t, err)
+	assert.False(t, registered)
+}
+
+func TestSync(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	r, dir, targetDir := newReplicator(t, db)
+
+	db.EXPECT().GetDECs().Return([]db_access.DEC{{Id: 1, Value: "wrapped"}}, nil).Twice()
+	db.EXPECT().GetIndexKey().Return("wrapped-index", nil).Twice()
+
+	file := db_access.File{GeneratedName: "a", FileName: "enc:a", OwnerId: 1, BlobName: "a", Backend: blobstore.Local, Size: 4}
+	db.EXPECT().GetChangeCursor(consumer).Return(10, nil).Once()
+	db.EXPECT().GetChanges(int64(10), mock.Anything).Return([]db_access.Change{
+		{Seq: 11, Kind: db_access.ChangeCreated, GeneratedName: "a", BlobName: "a"},
+		{Seq: 12, Kind: db_access.ChangeCreated, GeneratedName: "gone", BlobName: "gone"},
+		{Seq: 13, Kind: db_access.ChangeDeleted, GeneratedName: "old", BlobName: "old"},
+		{Seq: 14, Kind: db_access.ChangeDeleted, GeneratedName: "copy"},
+	}, nil).Once()
+	db.EXPECT().GetFile("a").Return(file, nil).Once()
+	db.EXPECT().GetFile("gone").Return(db_access.File{}, db_access.NoRowsError{}).Once()
+	db.EXPECT().UnmarkBlobReplicated(target, "old").Return(nil).Once()
+	db.EXPECT().SetChangeCursor(consumer, int64(14)).Return(nil).Once()
+	db.EXPECT().RemoveReadChanges().Return(nil).Once()
+
+	// the upload of pending is still running
+	db.EXPECT().GetUnreplicatedBlobs(target, "", mock.Anything).Return([]db_access.Blob{
+		{Name: "a", Backend: blobstore.Local, Size: 4},
+		{Name: "pending", Backend: blobstore.Local, Size: 4},
+	}, nil).Once()
+	db.EXPECT().MarkBlobReplicated(target, "a").Return(nil).Once()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), []byte("blob"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "blobs", "old"), []byte("blob"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "files", "old"), []byte("{}"), 0o600))
+
+	require.NoError(t, r.Sync(context.Background()))
+
+	content, err := os.ReadFile(filepath.Join(targetDir, "blobs", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, "blob", string(content))
+
+	data, err := os.ReadFile(filepath.Join(targetDir, "files", "a"))
+	require.NoError(t, err)
+	var record replication.FileRecord
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, replication.FileRecord{Id: "a", Name: "enc:a", OwnerId: 1, Blob: "a", Size: 4}, record)
+
+	data, err = os.ReadFile(filepath.Join(targetDir, "keys.json"))
+	require.NoError(t, err)
+	var keys replication.KeysRecord
+	require.NoError(t, json.Unmarshal(data, &keys))
+	assert.Equal(t, "wrapped-index", keys.IndexKey)
+	require.Len(t, keys.DECs, 1)
+	assert.Equal(t, "wrapped", keys.DECs[0].Value)
+
+	for _, name := range []string{"blobs/old", "files/old", "blobs/pending", "files/gone"} {
+		_, err := os.Stat(filepath.Join(targetDir, name))
+		assert.ErrorIs(t, err, os.ErrNotExist, name)
+	}
+
+	// nothing changed since, and unchanged keys aren't written again
+	require.NoError(t, os.Remove(filepath.Join(targetDir, "keys.json")))
+	db.EXPECT().GetChangeCursor(consumer).Return(14, nil).Once()
+	db.EXPECT().GetChanges(int64(14), mock.Anything).Return(nil, nil).Once()
+	db.EXPECT().GetUnreplicatedBlobs(target, "", mock.Anything).Return(nil, nil).Once()
+	require.NoError(t, r.Sync(context.Background()))
+
+	_, err = os.Stat(filepath.Join(targetDir, "keys.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSync_StopsAtFailedChange(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	r, _, _ := newReplicator(t, db)
+	expectNoKeys(db)
+
+	db.EXPECT().GetChangeCursor(consumer).Return(0, nil).Once()
+	db.EXPECT().GetChanges(int64(0), mock.Anything).Return([]db_access.Change{
+		{Seq: 1, Kind: db_access.ChangeCreated, GeneratedName: "gone"},
+		{Seq: 2, Kind: db_access.ChangeUpdated, GeneratedName: "a"},
+		{Seq: 3, Kind: db_access.ChangeDeleted, GeneratedName: "a"},
+	}, nil).Once()
+	db.EXPECT().GetFile("gone").Return(db_access.File{}, db_access.NoRowsError{}).Once()
+	db.EXPECT().GetFile("a").Return(db_access.File{}, errors.New("db is down")).Once()
+	// the failed change is tried again next round
+	db.EXPECT().SetChangeCursor(consumer, int64(1)).Return(nil).Once()
+
+	assert.Error(t, r.Sync(context.Background()))
+}
+
+func TestReconcile(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	r, _, targetDir := newReplicator(t, db)
+
+	for _, name := range []string{"kept", "unreferenced"} {
+		require.NoError(t, os.WriteFile(filepath.Join(targetDir, "blobs", name), []byte("blob"), 0o600))
+	}
+
+	db.EXPECT().GetReplicatedBlobs(target).Return([]db_access.ReplicatedBlob{
+		{Name: "kept", Referenced: true},
+		{Name: "lost", Referenced: true},
+		{Name: "unreferenced", Referenced: false},
+	}, nil).Once()
+	db.EXPECT().UnmarkBlobReplicated(target, "lost").Return(nil).Once()
+	db.EXPECT().UnmarkBlobReplicated(target, "unreferenced").Return(nil).Once()
+	db.EXPECT().GetFiles("", mock.Anything).Return([]db_access.File{
+		{GeneratedName: "kept", FileName: "enc:kept", BlobName: "kept"},
+		{GeneratedName: "lost", FileName: "enc:lost", BlobName: "lost"},
+	}, nil).Once()
+
+	require.NoError(t, r.Reconcile(context.Background()))
+
+	_, err := os.Stat(filepath.Join(targetDir, "blobs", "unreferenced"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(targetDir, "blobs", "kept"))
+	assert.NoError(t, err)
+	for _, name := range []string{"kept", "lost"} {
+		_, err := os.Stat(filepath.Join(targetDir, "files", name))
+		assert.NoError(t, err, name)
+	}
+}
@@ -0,0 +1,119 @@
+// Package retention deletes files once their expiry has passed and tells owners ahead of time.
+package retention
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/hls"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type Config struct {
+	Blobs *blobstore.Store
+	// how long before expiry owners get a notification
+	NotifyBefore time.Duration
+	// how long notifications are kept around
+	NotificationTTL time.Duration
+}
+
+type Janitor struct {
+	db  db_access.DbAccess
+	cfg Config
+	log *slog.Logger
+}
+
+func New(db db_access.DbAccess, cfg Config, log *slog.Logger) *Janitor {
+	return &Janitor{
+		db:  db,
+		cfg: cfg,
+		log: log.With(slog.String("component", "retention")),
+	}
+}
+
+// Sweep notifies owners of files expiring soon and deletes the expired ones as of now.
+func (j *Janitor) Sweep(now time.Time) {
+	j.notifyExpiring(now)
+	j.removeExpired(now)
+
+	if err := j.db.RemoveNotificationsBefore(db_access.Time(now.Add(-j.cfg.NotificationTTL))); err != nil {
+		j.log.Error("Could not remove old notifications", slogext.Error(err))
+	}
+}
+
+func (j *Janitor) notify(file db_access.File, kind db_access.NotificationKind, now time.Time) error {
+	return j.db.AddNotification(&db_access.Notification{
+		OwnerId:      file.OwnerId,
+		Kind:         kind,
+		FileId:       file.GeneratedName,
+		FileName:     file.FileName,
+		At:           file.ExpiresAt,
+		CreationTime: db_access.Time(now),
+	})
+}
+
+func (j *Janitor) notifyExpiring(now time.Time) {
+	files, err := j.db.GetFilesToNotify(db_access.Time(now.Add(j.cfg.NotifyBefore)))
+	if err != nil {
+		j.log.Error("Could not get files to notify about", slogext.Error(err))
+		return
+	}
+
+	for _, file := range files {
+		log := j.log.With(slog.String("generated-name", file.GeneratedName))
+
+		// files that are already due get the expired notification instead
+		if !now.Before(time.Time(file.ExpiresAt)) {
+			continue
+		}
+
+		if err := j.notify(file, db_access.NotificationFileExpiring, now); err != nil {
+			log.Error("Could not add notification", slogext.Error(err))
+			continue
+		}
+
+		if err := j.db.MarkExpiryNotified(file.GeneratedName); err != nil {
+			log.Error("Could not mark file as notified", slogext.Error(err))
+		}
+	}
+}
+
+func (j *Janitor) removeExpired(now time.Time) {
+	files, err := j.db.GetExpiredFiles(db_access.Time(now))
+	if err != nil {
+		j.log.Error("Could not get expired files", slogext.Error(err))
+		return
+	}
+
+	for _, file := range files {
+		log := j.log.With(slog.String("generated-name", file.GeneratedName))
+
+		blob, orphaned, err := j.db.DeleteFile(file.GeneratedName)
+		var nre db_access.NoRowsError
+		if errors.As(err, &nre) {
+			continue
+		} else if err != nil {
+			log.Error("Could not delete expired file", slogext.Error(err))
+			continue
+		}
+
+		if orphaned {
+			if err := j.cfg.Blobs.Remove(context.Background(), blob.Backend, blob.Name); err != nil {
+				log.Error("Could not remove blob of expired file", slogext.Error(err), slog.String("blob", blob.Name))
+			}
+
+			if err := hls.Remove(j.cfg.Blobs.Dir(), blob.Name); err != nil {
+				log.Error("Could not remove stream of expired file", slogext.Error(err), slog.String("blob", blob.Name))
+			}
+		}
+
+		if err := j.notify(file, db_access.NotificationFileExpired, now); err != nil {
+			log.Error("Could not add notification", slogext.Error(err))
+		}
+
+		log.Info("Removed expired file")
+	}
+}
@@ -0,0 +1,113 @@
+package retention_test
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"cloud-storage/retention"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweep(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sqlite.New(filepath.Join(dir, "db.sqlite"))
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	for name, expiresAt := range map[string]time.Time{
+		"expired": now.Add(-time.Minute),
+		"soon":    now.Add(time.Hour),
+		"later":   now.Add(48 * time.Hour),
+		"forever": {},
+	} {
+		require.NoError(t, db.AddFile(name, "enc:"+name, 1, 1))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("blob"), 0o600))
+		if !expiresAt.IsZero() {
+			require.NoError(t, db.SetFileExpiry(name, db_access.Time(expiresAt)))
+		}
+	}
+	require.NoError(t, db.AddFileCopy("expired-copy", "enc:copy", 1, "later", 1))
+	require.NoError(t, db.SetFileExpiry("expired-copy", db_access.Time(now.Add(-time.Second))))
+
+	janitor := retention.New(db, retention.Config{
+		Blobs:           blobstore.NewStore(dir, storage.DurabilityNone, nil),
+		NotifyBefore:    24 * time.Hour,
+		NotificationTTL: time.Hour,
+	}, slogext.NewDiscardLogger())
+
+	// a second sweep must not notify twice
+	janitor.Sweep(now)
+	janitor.Sweep(now)
+
+	for name, exists := range map[string]bool{"expired": false, "soon": true, "later": true, "forever": true} {
+		_, err := db.GetFile(name)
+		_, statErr := os.Stat(filepath.Join(dir, name))
+		if exists {
+			assert.NoError(t, err, name)
+			assert.NoError(t, statErr, name)
+		} else {
+			assert.ErrorAs(t, err, &db_access.NoRowsError{}, name)
+			assert.ErrorIs(t, statErr, os.ErrNotExist, name)
+		}
+	}
+	_, err = db.GetFile("expired-copy")
+	assert.ErrorAs(t, err, &db_access.NoRowsError{})
+
+	notifications, err := db.GetNotifications(1)
+	require.NoError(t, err)
+
+	kinds := map[string]db_access.NotificationKind{}
+	for _, n := range notifications {
+		_, seen := kinds[n.FileId]
+		assert.False(t, seen, n.FileId)
+		kinds[n.FileId] = n.Kind
+	}
+	assert.Equal(t, map[string]db_access.NotificationKind{
+		"expired":      db_access.NotificationFileExpired,
+		"expired-copy": db_access.NotificationFileExpired,
+		"soon":         db_access.NotificationFileExpiring,
+	}, kinds)
+
+	// old notifications get pruned
+	janitor.Sweep(now.Add(2 * time.Hour))
+	notifications, err = db.GetNotifications(1)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "soon", notifications[0].FileId)
+	assert.Equal(t, db_access.NotificationFileExpired, notifications[0].Kind)
+}
+
+func TestSetFileExpiryRenotifies(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, db.AddFile("file", "enc:file", 1, 1))
+	require.NoError(t, db.SetFileExpiry("file", db_access.Time(now.Add(time.Hour))))
+	require.NoError(t, db.MarkExpiryNotified("file"))
+
+	files, err := db.GetFilesToNotify(db_access.Time(now.Add(2 * time.Hour)))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	require.NoError(t, db.SetFileExpiry("file", db_access.Time(now.Add(90*time.Minute))))
+	files, err = db.GetFilesToNotify(db_access.Time(now.Add(2 * time.Hour)))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, now.Add(90*time.Minute).Unix(), time.Time(files[0].ExpiresAt).Unix())
+
+	require.NoError(t, db.SetFileExpiry("file", db_access.Time{}))
+	file, err := db.GetFile("file")
+	require.NoError(t, err)
+	assert.True(t, time.Time(file.ExpiresAt).IsZero())
+
+	assert.ErrorAs(t, db.SetFileExpiry("missing", db_access.Time{}), &db_access.NoRowsError{})
+}
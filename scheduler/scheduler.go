@@ -0,0 +1,166 @@
+// Package scheduler runs the periodic maintenance tasks of the server and keeps how their last runs went.
+package scheduler
+
+import (
+	"cloud-storage/maintenance"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Task runs every Interval; a task with no interval is disabled and only listed
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+}
+
+// Status is how a task fared so far; times are zero until they happen
+type Status struct {
+	Name     string
+	Interval time.Duration
+	Enabled  bool
+	Running  bool
+	Runs     int
+	// Failures counts the runs that returned an error
+	Failures  int
+	LastStart time.Time
+	LastEnd   time.Time
+	// LastError is empty if the last run succeeded
+	LastError string
+	// LastSkipped is when a run was last left out for maintenance mode
+	LastSkipped time.Time
+	Next        time.Time
+}
+
+type Config struct {
+	// Jitter delays every run by up to this fraction of its interval,
+	// so that tasks with the same interval don't all start at once
+	Jitter float64
+	// no task runs while it is enabled
+	Maintenance *maintenance.Mode
+}
+
+type Scheduler struct {
+	cfg Config
+	log *slog.Logger
+
+	mu     sync.Mutex
+	tasks  []Task
+	status map[string]*Status
+}
+
+func New(cfg Config, log *slog.Logger) *Scheduler {
+	return &Scheduler{
+		cfg:    cfg,
+		log:    log.With(slog.String("component", "scheduler")),
+		status: make(map[string]*Status),
+	}
+}
+
+// Add registers a task; tasks must be added before Run
+func (s *Scheduler) Add(task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = append(s.tasks, task)
+	s.status[task.Name] = &Status{
+		Name:     task.Name,
+		Interval: task.Interval,
+		Enabled:  task.Interval > 0,
+	}
+}
+
+// Run starts the enabled tasks and blocks until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	tasks := s.tasks
+	s.mu.Unlock()
+
+	for _, task := range tasks {
+		if task.Interval > 0 {
+			go s.loop(ctx, task)
+		}
+	}
+
+	<-ctx.Done()
+}
+
+// Status returns the status of every task in the order they were added
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		statuses = append(statuses, *s.status[task.Name])
+	}
+	return statuses
+}
+
+func (s *Scheduler) loop(ctx context.Context, task Task) {
+	log := s.log.With(slog.String("task", task.Name))
+
+	for {
+		wait := task.Interval
+		if s.cfg.Jitter > 0 {
+			wait += time.Duration(rand.Float64() * s.cfg.Jitter * float64(task.Interval))
+		}
+		s.update(task.Name, func(st *Status) {
+			st.Next = time.Now().Add(wait)
+		})
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if s.cfg.Maintenance.Enabled() {
+			s.update(task.Name, func(st *Status) {
+				st.LastSkipped = time.Now()
+			})
+			continue
+		}
+
+		s.run(ctx, log, task)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, log *slog.Logger, task Task) {
+	start := time.Now()
+	s.update(task.Name, func(st *Status) {
+		st.Running = true
+		st.LastStart = start
+	})
+
+	err := task.Run(ctx, start)
+	if err != nil {
+		log.Error("Task failed", slogext.Error(err))
+	} else {
+		log.Debug("Task finished", slog.Duration("took", time.Since(start)))
+	}
+
+	s.update(task.Name, func(st *Status) {
+		st.Running = false
+		st.Runs++
+		st.LastEnd = time.Now()
+		st.LastError = ""
+		if err != nil {
+			st.Failures++
+			st.LastError = err.Error()
+		}
+	})
+}
+
+func (s *Scheduler) update(name string, change func(st *Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change(s.status[name])
+}
@@ -0,0 +1,85 @@
+package scheduler_test
+
+import (
+	"cloud-storage/maintenance"
+	"cloud-storage/scheduler"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	s := scheduler.New(scheduler.Config{Jitter: 0.5}, slogext.NewDiscardLogger())
+
+	var runs, failing, disabled atomic.Int32
+	s.Add(scheduler.Task{Name: "ok", Interval: 5 * time.Millisecond, Run: func(ctx context.Context, now time.Time) error {
+		runs.Add(1)
+		return nil
+	}})
+	s.Add(scheduler.Task{Name: "failing", Interval: 5 * time.Millisecond, Run: func(ctx context.Context, now time.Time) error {
+		failing.Add(1)
+		return errors.New("disk full")
+	}})
+	s.Add(scheduler.Task{Name: "disabled", Run: func(ctx context.Context, now time.Time) error {
+		disabled.Add(1)
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return runs.Load() >= 2 && failing.Load() >= 2
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+
+	status := s.Status()
+	require.Len(t, status, 3)
+
+	assert.Equal(t, "ok", status[0].Name)
+	assert.True(t, status[0].Enabled)
+	assert.GreaterOrEqual(t, status[0].Runs, 2)
+	assert.Zero(t, status[0].Failures)
+	assert.Empty(t, status[0].LastError)
+	assert.False(t, status[0].LastStart.IsZero())
+	assert.False(t, status[0].Next.IsZero())
+
+	assert.Equal(t, status[1].Runs, status[1].Failures)
+	assert.Equal(t, "disk full", status[1].LastError)
+
+	assert.False(t, status[2].Enabled)
+	assert.Zero(t, status[2].Runs)
+	assert.Zero(t, disabled.Load())
+}
+
+func TestScheduler_Maintenance(t *testing.T) {
+	mode := maintenance.New(true, "backup")
+	s := scheduler.New(scheduler.Config{Maintenance: mode}, slogext.NewDiscardLogger())
+
+	var runs atomic.Int32
+	s.Add(scheduler.Task{Name: "retention", Interval: 5 * time.Millisecond, Run: func(ctx context.Context, now time.Time) error {
+		runs.Add(1)
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return !s.Status()[0].LastSkipped.IsZero()
+	}, 5*time.Second, time.Millisecond)
+	assert.Zero(t, runs.Load())
+
+	mode.Disable()
+	require.Eventually(t, func() bool {
+		return runs.Load() > 0
+	}, 5*time.Second, time.Millisecond)
+}
@@ -0,0 +1,152 @@
+// Package search indexes the contents of text documents, so that files can be found by the words in them.
+//
+// The index never holds a word in the clear: every term goes through Crypter.ContentTermIndex, which hashes
+// it with a key kept in vault and the owner of the file, so the db tells neither what files say
+// nor which files of different users say the same.
+package search
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// text beyond this is not indexed
+	maxTextSize = 4 << 20
+	// terms of a file beyond this many are not indexed
+	maxTerms = 20000
+	// terms shorter or longer than these are not words anyone searches for
+	minTermLen = 2
+	maxTermLen = 64
+	// bytes a document may decompress to, so a small zip bomb can't take the server's memory
+	maxInflated = 64 << 20
+)
+
+var ErrUnsupported = errors.New("unsupported document type")
+
+type extractor func(data []byte) (string, error)
+
+var extractors = map[string]extractor{
+	".txt":      extractText,
+	".text":     extractText,
+	".md":       extractText,
+	".markdown": extractText,
+	".docx":     extractDocx,
+	".pdf":      extractPDF,
+}
+
+// Supported tells whether text is extracted from files with that name
+func Supported(name string) bool {
+	_, ok := extractors[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// Extract returns the text of the document, which is of the type its name says
+func Extract(name string, data []byte) (string, error) {
+	const op = "search.Extract"
+
+	extract, ok := extractors[strings.ToLower(path.Ext(name))]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, ErrUnsupported)
+	}
+
+	text, err := extract(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return text, nil
+}
+
+// Terms splits text into lowercased words of letters and digits, each once
+func Terms(text string) []string {
+	if len(text) > maxTextSize {
+		text = text[:maxTextSize]
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]struct{})
+	terms := make([]string, 0)
+	for _, word := range words {
+		if n := utf8.RuneCountInString(word); n < minTermLen || n > maxTermLen {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		terms = append(terms, word)
+		if len(terms) == maxTerms {
+			break
+		}
+	}
+
+	return terms
+}
+
+func extractText(data []byte) (string, error) {
+	if len(data) > maxTextSize {
+		data = data[:maxTextSize]
+	}
+	return strings.ToValidUTF8(string(data), " "), nil
+}
+
+// extractDocx reads the runs of text of the main part of a word document, ending each paragraph with a space
+func extractDocx(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not a docx document: %w", err)
+	}
+
+	part, err := archive.Open("word/document.xml")
+	if err != nil {
+		return "", fmt.Errorf("not a docx document: %w", err)
+	}
+	defer part.Close()
+
+	var text strings.Builder
+	var inText bool
+	decoder := xml.NewDecoder(io.LimitReader(part, maxInflated))
+	for text.Len() < maxTextSize {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("docx document.xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab", "br", "cr":
+				text.WriteByte(' ')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteByte(' ')
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+
+	return text.String(), nil
+}
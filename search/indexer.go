@@ -0,0 +1,180 @@
+package search
+
+import (
+	"bytes"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/encryption"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// files are indexed in pages of this many
+const pageSize = 64
+
+type Config struct {
+	// how often new files are indexed
+	Interval time.Duration
+	// plaintext size of the largest file to be indexed; larger ones count as indexed without terms
+	MaxFileSize int64
+}
+
+// Indexer indexes the files that aren't yet. Files come from the db rather than the change feed,
+// since a file row exists before its blob is written: a file whose blob is missing is tried again next time.
+type Indexer struct {
+	db      db_access.ContentRepo
+	crypter encryption.Crypter
+	blobs   *blobstore.Store
+	cfg     Config
+	log     *slog.Logger
+}
+
+func New(db db_access.ContentRepo, c encryption.Crypter, blobs *blobstore.Store, cfg Config, log *slog.Logger) *Indexer {
+	return &Indexer{
+		db:      db,
+		crypter: c,
+		blobs:   blobs,
+		cfg:     cfg,
+		log:     log.With(slog.String("component", "search")),
+	}
+}
+
+// Run indexes new files every interval until ctx is done
+func (ix *Indexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(ix.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := ix.IndexPending(ctx); err != nil {
+			ix.log.Error("Could not index files", slogext.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IndexPending indexes every file that isn't yet, skipping those that fail until the next call
+func (ix *Indexer) IndexPending(ctx context.Context) error {
+	const op = "search.Indexer.IndexPending"
+
+	var indexed, failed int
+	for after := ""; ; {
+		files, err := ix.db.GetUnindexedFiles(after, pageSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, file := range files {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if err := ix.index(ctx, file); errors.Is(err, fs.ErrNotExist) {
+				// still being uploaded
+				continue
+			} else if err != nil {
+				ix.log.Error("Could not index file", slogext.Error(err), slog.String("generated-name", file.GeneratedName))
+				failed++
+				continue
+			}
+			indexed++
+		}
+
+		if len(files) < pageSize {
+			break
+		}
+		after = files[len(files)-1].GeneratedName
+	}
+
+	if indexed > 0 || failed > 0 {
+		ix.log.Info("Indexed files", slog.Int("indexed", indexed), slog.Int("failed", failed))
+	}
+
+	return nil
+}
+
+func (ix *Indexer) index(ctx context.Context, file db_access.File) error {
+	const op = "search.Indexer.index"
+
+	name, err := ix.crypter.DecryptFileName(file.FileName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var terms []string
+	if Supported(name) && file.Size <= ix.cfg.MaxFileSize {
+		text, err := ix.extract(ctx, file, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, term := range Terms(text) {
+			hash, err := ix.crypter.ContentTermIndex(file.OwnerId, term)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			terms = append(terms, hash)
+		}
+	}
+
+	err = ix.db.SetFileTerms(file.GeneratedName, file.OwnerId, terms)
+	if errors.As(err, &db_access.NoRowsError{}) {
+		// deleted meanwhile
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// extract decrypts the blob of the file into memory, where the formats of documents need it to be.
+// A document that can't be read is indexed without terms rather than tried again forever.
+func (ix *Indexer) extract(ctx context.Context, file db_access.File, name string) (string, error) {
+	blob, err := ix.blobs.Open(ctx, file.Backend, file.BlobName)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+
+	// sizes of files stored before they were recorded are unknown, so the limit holds here as well
+	var buf bytes.Buffer
+	if err := ix.crypter.DecryptAndCopy(&limitedWriter{w: &buf, n: ix.cfg.MaxFileSize}, blob); errors.Is(err, errTooLarge) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	text, err := Extract(name, buf.Bytes())
+	if err != nil {
+		ix.log.Warn("Could not extract text", slogext.Error(err), slog.String("generated-name", file.GeneratedName))
+		return "", nil
+	}
+
+	return text, nil
+}
+
+var errTooLarge = errors.New("file is too large to index")
+
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.n {
+		return 0, errTooLarge
+	}
+	lw.n -= int64(len(p))
+	return lw.w.Write(p)
+}
@@ -0,0 +1,341 @@
+package search
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// extractPDF reads the strings shown by the content streams of a pdf, uncompressed or deflated.
+// It doesn't parse the document structure, so text in fonts with encodings of their own,
+// which most CID fonts have, comes out as noise rather than words.
+func extractPDF(data []byte) (string, error) {
+	var text strings.Builder
+	var inflated int64
+
+	for rest := data; text.Len() < maxTextSize && inflated < maxInflated; {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+
+		dict := rest[:start]
+		if i := bytes.LastIndex(dict, []byte(" obj")); i >= 0 {
+			dict = dict[i:]
+		}
+
+		body := rest[start+len("stream"):]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		rest = body[end+len("endstream"):]
+		body = body[:end]
+
+		content, ok := streamContent(dict, body, maxInflated-inflated)
+		if !ok {
+			continue
+		}
+		inflated += int64(len(content))
+		showText(&text, content)
+	}
+
+	return text.String(), nil
+}
+
+// streamContent decodes the stream if it may be a content stream; images, fonts and the like are skipped
+func streamContent(dict []byte, body []byte, limit int64) ([]byte, bool) {
+	for _, key := range [][]byte{[]byte("/Type"), []byte("/Subtype"), []byte("/Length1")} {
+		if bytes.Contains(dict, key) {
+			return nil, false
+		}
+	}
+
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return body, true
+	}
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Count(dict, []byte("Decode")) > 1 {
+		return nil, false
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+
+	// a truncated stream still has text up to where it breaks off
+	content, _ := io.ReadAll(io.LimitReader(r, limit))
+	return content, true
+}
+
+// showText writes the strings shown between BT and ET, breaking words where the text moves
+func showText(text *strings.Builder, content []byte) {
+	var operands []pdfOperand
+	var inText bool
+
+	lex := pdfLexer{data: content}
+	for text.Len() < maxTextSize {
+		op, ok := lex.next()
+		if !ok {
+			return
+		}
+
+		if op.kind != pdfOperator {
+			operands = append(operands, op)
+			continue
+		}
+
+		switch op.word {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			text.WriteByte(' ')
+		case "ID":
+			lex.skipImage()
+		case "Td", "TD", "T*", "Tm":
+			if inText {
+				text.WriteByte(' ')
+			}
+		case "Tj", "'", "\"":
+			if !inText {
+				break
+			}
+			if op.word != "Tj" {
+				text.WriteByte(' ')
+			}
+			if n := len(operands); n > 0 && operands[n-1].kind == pdfString {
+				text.WriteString(operands[n-1].text)
+			}
+		case "TJ":
+			if n := len(operands); inText && n > 0 && operands[n-1].kind == pdfArray {
+				for _, item := range operands[n-1].items {
+					if item.kind == pdfString {
+						text.WriteString(item.text)
+					} else if item.kind == pdfNumber && item.num <= -200 {
+						// a large enough move to the right is how many generators put spaces
+						text.WriteByte(' ')
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+type pdfKind int
+
+const (
+	pdfOperator pdfKind = iota
+	pdfString
+	pdfNumber
+	pdfArray
+	pdfOther
+)
+
+type pdfOperand struct {
+	kind  pdfKind
+	word  string
+	text  string
+	num   float64
+	items []pdfOperand
+}
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0
+}
+
+func isPDFDelimiter(b byte) bool {
+	return strings.IndexByte("()<>[]{}/%", b) >= 0
+}
+
+func (l *pdfLexer) next() (pdfOperand, bool) {
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		switch {
+		case isPDFSpace(b):
+			l.pos++
+		case b == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case b == '(':
+			l.pos++
+			return pdfOperand{kind: pdfString, text: decodePDFString(l.literal())}, true
+		case b == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<',
+			b == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return pdfOperand{kind: pdfOther}, true
+		case b == '<':
+			l.pos++
+			return pdfOperand{kind: pdfString, text: decodePDFString(l.hex())}, true
+		case b == '[':
+			l.pos++
+			return l.array(), true
+		case b == '/':
+			l.pos++
+			l.word()
+			return pdfOperand{kind: pdfOther}, true
+		case isPDFDelimiter(b):
+			l.pos++
+			return pdfOperand{kind: pdfOther}, true
+		default:
+			word := l.word()
+			if num, err := strconv.ParseFloat(word, 64); err == nil {
+				return pdfOperand{kind: pdfNumber, num: num}, true
+			}
+			return pdfOperand{kind: pdfOperator, word: word}, true
+		}
+	}
+
+	return pdfOperand{}, false
+}
+
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *pdfLexer) array() pdfOperand {
+	arr := pdfOperand{kind: pdfArray}
+	for l.pos < len(l.data) {
+		for l.pos < len(l.data) && isPDFSpace(l.data[l.pos]) {
+			l.pos++
+		}
+		if l.pos < len(l.data) && l.data[l.pos] == ']' {
+			l.pos++
+			break
+		}
+
+		item, ok := l.next()
+		if !ok {
+			break
+		}
+		arr.items = append(arr.items, item)
+	}
+	return arr
+}
+
+// literal reads a (string) up to its closing parenthesis, which balanced ones inside don't close
+func (l *pdfLexer) literal() []byte {
+	var s []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		l.pos++
+
+		switch b {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return s
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				b = '\n'
+			case 'r':
+				b = '\r'
+			case 't':
+				b = '\t'
+			case 'b':
+				b = '\b'
+			case 'f':
+				b = '\f'
+			case '\r', '\n':
+				// a line continuation
+				if e == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := int(e - '0')
+				for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+					v = v*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				b = byte(v)
+			default:
+				b = e
+			}
+		}
+		s = append(s, b)
+	}
+	return s
+}
+
+// hex reads a <hex string>, where a missing last digit is 0
+func (l *pdfLexer) hex() []byte {
+	var s []byte
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if v, err := strconv.ParseUint(string(l.data[l.pos]), 16, 8); err == nil {
+			digits = append(digits, byte(v))
+		}
+		l.pos++
+	}
+	l.pos++
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, 0)
+	}
+	for i := 0; i < len(digits); i += 2 {
+		s = append(s, digits[i]<<4|digits[i+1])
+	}
+	return s
+}
+
+// skipImage moves past the data of an inline image, which ends with EI on its own
+func (l *pdfLexer) skipImage() {
+	for l.pos < len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		at := l.pos + i
+		l.pos = at + 2
+		if at > 0 && isPDFSpace(l.data[at-1]) && (l.pos == len(l.data) || isPDFSpace(l.data[l.pos])) {
+			return
+		}
+	}
+}
+
+// decodePDFString reads text strings marked as UTF-16BE as such and any other as Latin-1,
+// which is what the standard encodings of simple fonts mostly agree with
+func decodePDFString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(s))
+	for i, b := range s {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,137 @@
+package search_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	db_access_mocks "cloud-storage/db_access/mocks"
+	encryption_mocks "cloud-storage/encryption/mocks"
+	"cloud-storage/search"
+	"cloud-storage/storage"
+	slogext "cloud-storage/utils/slogExt"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func docx(t *testing.T, body string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	require.NoError(t, err)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func pdf(t *testing.T, content string) []byte {
+	var deflated bytes.Buffer
+	zw := zlib.NewWriter(&deflated)
+	zw.Write([]byte(content))
+	require.NoError(t, zw.Close())
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	buf.WriteString("4 0 obj\n<< /Length 5 /Subtype /Image /Filter /DCTDecode >>\nstream\n(Hid)\nendstream\nendobj\n")
+	fmt.Fprintf(&buf, "5 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", deflated.Len())
+	buf.Write(deflated.Bytes())
+	buf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileName string
+		data     []byte
+		expected string
+	}{
+		{name: "Text", fileName: "a.TXT", data: []byte("plain words"), expected: "plain words"},
+		{name: "Markdown", fileName: "a.md", data: []byte("# Title\n\nsome *text*"), expected: "# Title\n\nsome *text*"},
+		{
+			name:     "Docx",
+			fileName: "a.docx",
+			data:     docx(t, `<w:p><w:r><w:t>Quarterly</w:t></w:r><w:r><w:t xml:space="preserve"> rep</w:t></w:r><w:r><w:t>ort</w:t></w:r></w:p><w:p><w:r><w:t>Summary</w:t></w:r></w:p>`),
+			expected: "Quarterly report Summary ",
+		},
+		{
+			name:     "Pdf",
+			fileName: "a.pdf",
+			data:     pdf(t, "q BT /F1 12 Tf 72 712 Td (Hello \\(big\\)) Tj 0 -14 Td [(Wor) 20 (ld) -250 (caf\\351)] TJ ET Q"),
+			expected: " Hello (big) World café ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.True(t, search.Supported(tc.fileName))
+			text, err := search.Extract(tc.fileName, tc.data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, text)
+		})
+	}
+
+	assert.False(t, search.Supported("a.jpg"))
+	_, err := search.Extract("a.jpg", []byte("x"))
+	assert.ErrorIs(t, err, search.ErrUnsupported)
+
+	_, err = search.Extract("a.docx", []byte("not a zip"))
+	assert.Error(t, err)
+}
+
+func TestTerms(t *testing.T) {
+	terms := search.Terms("The quick-brown fox; the FOX, a Ünïcode 42 " + strings.Repeat("x", 65))
+	assert.Equal(t, []string{"the", "quick", "brown", "fox", "ünïcode", "42"}, terms)
+}
+
+func TestIndexPending(t *testing.T) {
+	db := db_access_mocks.NewDbAccess(t)
+	c := encryption_mocks.NewCrypter(t)
+	dir := t.TempDir()
+	blobs := blobstore.NewStore(dir, storage.DurabilityNone, nil)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "blob-a"), []byte("Hello hello world"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "blob-big"), []byte("longer than the twenty bytes allowed"), 0o600))
+
+	files := []db_access.File{
+		{GeneratedName: "a", FileName: "enc:a.txt", OwnerId: 1, BlobName: "blob-a", Size: 17},
+		// has no blob yet, so it is left for the next round
+		{GeneratedName: "b", FileName: "enc:b.txt", OwnerId: 1, BlobName: "blob-b", Size: 1},
+		{GeneratedName: "c", FileName: "enc:c.jpg", OwnerId: 1, BlobName: "blob-c", Size: 1},
+		{GeneratedName: "d", FileName: "enc:d.md", OwnerId: 2, BlobName: "blob-d", Size: 1 << 20},
+		// its size wasn't recorded
+		{GeneratedName: "e", FileName: "enc:e.md", OwnerId: 2, BlobName: "blob-big", Size: 0},
+	}
+	db.EXPECT().GetUnindexedFiles("", 64).Return(files, nil).Once()
+
+	c.EXPECT().DecryptFileName(mock.Anything).RunAndReturn(func(name string) (string, error) {
+		return strings.TrimPrefix(name, "enc:"), nil
+	}).Times(len(files))
+	c.EXPECT().DecryptAndCopy(mock.Anything, mock.Anything).RunAndReturn(func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Twice()
+	c.EXPECT().ContentTermIndex(int64(1), mock.Anything).RunAndReturn(func(_ int64, term string) (string, error) {
+		return "h:" + term, nil
+	}).Twice()
+
+	db.EXPECT().SetFileTerms("a", int64(1), []string{"h:hello", "h:world"}).Return(nil).Once()
+	db.EXPECT().SetFileTerms("c", int64(1), []string(nil)).Return(nil).Once()
+	db.EXPECT().SetFileTerms("d", int64(2), []string(nil)).Return(nil).Once()
+	// deleted while it was indexed
+	db.EXPECT().SetFileTerms("e", int64(2), []string(nil)).Return(db_access.NoRowsError{}).Once()
+
+	ix := search.New(db, c, blobs, search.Config{MaxFileSize: 20}, slogext.NewDiscardLogger())
+	require.NoError(t, ix.IndexPending(context.Background()))
+}
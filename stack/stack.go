@@ -0,0 +1,79 @@
+// Package stack assembles the middleware chain of the api from names,
+// so that every environment can run its own chain picked in the config.
+package stack
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type Middleware = func(http.Handler) http.Handler
+
+// Registry maps config names to middlewares
+type Registry map[string]Middleware
+
+type UnknownMiddlewareError struct {
+	Name  string
+	Known []string
+}
+
+func (err UnknownMiddlewareError) Error() string {
+	return fmt.Sprintf("unknown middleware %q; expected one of %s", err.Name, strings.Join(err.Known, ", "))
+}
+
+type MissingMiddlewareError struct {
+	Name string
+}
+
+func (err MissingMiddlewareError) Error() string {
+	return fmt.Sprintf("middleware %q is required", err.Name)
+}
+
+// Build returns the middlewares in the order of names, the first one being the outermost.
+// The chain must contain the required names, such as the ones handlers depend on.
+func (reg Registry) Build(names []string, required ...string) ([]Middleware, error) {
+	const op = "stack.Registry.Build"
+
+	for _, name := range required {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("%s: %w", op, MissingMiddlewareError{Name: name})
+		}
+	}
+
+	chain := make([]Middleware, 0, len(names))
+	for _, name := range names {
+		mw, ok := reg[name]
+		if !ok {
+			known := make([]string, 0, len(reg))
+			for name := range reg {
+				known = append(known, name)
+			}
+			slices.Sort(known)
+
+			return nil, fmt.Errorf("%s: %w", op, UnknownMiddlewareError{Name: name, Known: known})
+		}
+		chain = append(chain, mw)
+	}
+
+	return chain, nil
+}
+
+// SecurityHeaders sets the headers that keep browsers from sniffing, framing or leaking api responses;
+// hsts pins clients to https and must only be on when the server is reached over TLS
+func SecurityHeaders(hsts bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("X-Frame-Options", "DENY")
+			header.Set("Referrer-Policy", "no-referrer")
+			if hsts {
+				header.Set("Strict-Transport-Security", "max-age=31536000")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package stack_test
+
+import (
+	"cloud-storage/stack"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tag(name string) stack.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	reg := stack.Registry{"a": tag("a"), "b": tag("b"), "c": tag("c")}
+
+	chain, err := reg.Build([]string{"c", "a"}, "a")
+	require.NoError(t, err)
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, []string{"c", "a"}, w.Header().Values("X-Chain"))
+
+	_, err = reg.Build([]string{"a", "d"})
+	var ume stack.UnknownMiddlewareError
+	require.ErrorAs(t, err, &ume)
+	assert.Equal(t, "d", ume.Name)
+	assert.Equal(t, []string{"a", "b", "c"}, ume.Known)
+
+	_, err = reg.Build([]string{"b"}, "a")
+	assert.ErrorAs(t, err, &stack.MissingMiddlewareError{})
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	for _, hsts := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		stack.SecurityHeaders(hsts)(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+		assert.Equal(t, hsts, strings.HasPrefix(w.Header().Get("Strict-Transport-Security"), "max-age="))
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DirMode is the permissions the storage dir is created with, written in octal
+type DirMode os.FileMode
+
+func (m *DirMode) UnmarshalText(text []byte) error {
+	mode, err := strconv.ParseUint(string(text), 8, 32)
+	if err != nil || mode > uint64(os.ModePerm) {
+		return fmt.Errorf("invalid dir mode %q; expected octal permissions like 0700", text)
+	}
+
+	*m = DirMode(mode)
+	return nil
+}
+
+// ExpandPath makes path absolute, replacing a leading ~ with the home dir of the current user.
+// Relative paths are relative to the working dir.
+func ExpandPath(path string) (string, error) {
+	const op = "storage.ExpandPath"
+
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		path = filepath.Join(home, path[1:])
+	} else if strings.HasPrefix(path, "~") {
+		return "", fmt.Errorf("%s: home dirs of other users are not supported: %s", op, path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return abs, nil
+}
+
+// PrepareDir creates dir along with its parents if it doesn't exist and writes a probe file to it,
+// so that a storage dir files can't be written to fails startup rather than the first upload
+func PrepareDir(dir string, mode DirMode) error {
+	const op = "storage.PrepareDir"
+
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(dir, os.FileMode(mode)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s: not a directory: %s", op, dir)
+	}
+
+	probe, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("%s: storage dir is not writable: %w", op, err)
+	}
+	_, err = probe.WriteString("probe")
+	if closeErr := probe.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(probe.Name()); err == nil {
+		err = removeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: storage dir is not writable: %w", op, err)
+	}
+
+	return nil
+}
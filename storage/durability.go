@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Durability controls what is flushed to disk before a blob is committed.
+// The zero value behaves as DurabilityFull.
+type Durability string
+
+const (
+	// DurabilityNone leaves flushing to the OS; a crash can lose recently committed blobs
+	DurabilityNone Durability = "none"
+	// DurabilityData flushes blob contents but not all of their metadata
+	DurabilityData Durability = "fdatasync"
+	// DurabilityFull flushes blob contents and the directory entry created by the rename
+	DurabilityFull Durability = "fsync"
+)
+
+func (d *Durability) UnmarshalText(text []byte) error {
+	switch v := Durability(text); v {
+	case DurabilityNone, DurabilityData, DurabilityFull:
+		*d = v
+		return nil
+	case "":
+		*d = DurabilityFull
+		return nil
+	}
+
+	return fmt.Errorf("unknown durability %q; expected one of none, fdatasync, fsync", text)
+}
+
+func (d Durability) syncFile(file *os.File) error {
+	switch d {
+	case DurabilityNone:
+		return nil
+	case DurabilityData:
+		return fdatasync(file)
+	}
+
+	return file.Sync()
+}
+
+func (d Durability) syncDir(dir string) error {
+	if d == DurabilityNone || d == DurabilityData {
+		return nil
+	}
+
+	file, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
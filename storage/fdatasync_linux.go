@@ -0,0 +1,10 @@
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+func fdatasync(file *os.File) error {
+	return syscall.Fdatasync(int(file.Fd()))
+}
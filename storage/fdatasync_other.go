@@ -0,0 +1,12 @@
+//go:build !linux
+
+package storage
+
+import (
+	"os"
+)
+
+// fdatasync falls back to a full sync where the platform has no cheaper call
+func fdatasync(file *os.File) error {
+	return file.Sync()
+}
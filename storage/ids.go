@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidName is returned for names that would point outside of the storage dir
+var ErrInvalidName = errors.New("invalid blob name")
+
+// NewFileId returns a generated name for a new file. Time ordered ids (UUIDv7) land next to
+// each other in the generatedName index and sort by creation time; random ones (UUIDv4)
+// are what older rows have, and both kinds are valid names side by side.
+func NewFileId(timeOrdered bool) string {
+	if timeOrdered {
+		return uuid.Must(uuid.NewV7()).String()
+	}
+
+	return uuid.New().String()
+}
+
+// ValidFileId reports whether id is shaped like the ids NewFileId returns
+func ValidFileId(id string) bool {
+	parsed, err := uuid.Parse(id)
+	return err == nil && parsed.String() == id
+}
+
+// BlobPath returns the path of name in dir. Names are relative to dir and may not leave it,
+// so that whatever ends up in the db can't reach any other file.
+func BlobPath(dir string, name string) (string, error) {
+	if !filepath.IsLocal(name) || name == "." {
+		return "", fmt.Errorf("%q: %w", name, ErrInvalidName)
+	}
+
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// JournalName is the file inside the storage dir that records blob mutations.
+// Each mutation appends an intent entry before touching the blob and a done entry after,
+// so after a crash the journal tells which blobs may be incomplete.
+const JournalName = ".journal"
+
+type JournalOp string
+
+const (
+	// JournalPut is written before a blob is renamed into place, with the checksum it must end up with
+	JournalPut     JournalOp = "put"
+	JournalPutDone JournalOp = "put-done"
+	// JournalRemove is written before a blob is removed; the file row is gone by then
+	JournalRemove     JournalOp = "remove"
+	JournalRemoveDone JournalOp = "remove-done"
+)
+
+type JournalEntry struct {
+	Op JournalOp `json:"op"`
+	Id string    `json:"id"`
+	// hex sha256 of the blob contents; only set for JournalPut
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// appendJournal adds an entry to the journal of dir. Every entry goes in with a single
+// O_APPEND write, so concurrent writers don't need to coordinate.
+func appendJournal(dir string, durability Durability, entry JournalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, JournalName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("file.Write: %w", err)
+	}
+
+	// an intent must be on disk before the mutation it describes; a lost done entry
+	// only makes recovery verify a blob that turns out to be fine
+	if entry.Op == JournalPut || entry.Op == JournalRemove {
+		if err := durability.syncFile(file); err != nil {
+			return fmt.Errorf("sync journal: %w", err)
+		}
+	}
+
+	return file.Close()
+}
+
+// ReadJournal returns the entries of the journal in dir in the order they were written.
+// Lines that don't parse, such as one torn by a crash mid-append, are skipped.
+func ReadJournal(dir string) ([]JournalEntry, error) {
+	const op = "storage.ReadJournal"
+
+	file, err := os.Open(filepath.Join(dir, JournalName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: os.Open: %w", op, err)
+	}
+	defer file.Close()
+
+	entries := make([]JournalEntry, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Id == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: scanner.Err: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// Remove deletes the blob dir/name, journaling the removal around it
+func Remove(dir string, durability Durability, name string) error {
+	const op = "storage.Remove"
+
+	path, err := BlobPath(dir, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := appendJournal(dir, durability, JournalEntry{Op: JournalRemove, Id: name}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: os.Remove: %w", op, err)
+	}
+
+	// without the entry recovery removes the blob once more, which is harmless
+	appendJournal(dir, durability, JournalEntry{Op: JournalRemoveDone, Id: name})
+
+	return nil
+}
+
+type Recovery struct {
+	// Verified blobs had an unfinished put but their contents match the journaled checksum
+	Verified []string
+	// Incomplete blobs had an unfinished put and are missing or don't match the checksum
+	Incomplete []string
+	// Removed blobs had an unfinished removal that Recover finished
+	Removed []string
+}
+
+// Recover replays the journal of dir: unfinished removals are finished and unfinished puts
+// are checked against their checksums. The journal is then rewritten to keep only the puts
+// found incomplete, so they are reported again until someone repairs them.
+// Like SweepTemp, it must run before anything starts writing to dir.
+func Recover(dir string, durability Durability) (Recovery, error) {
+	const op = "storage.Recover"
+
+	entries, err := ReadJournal(dir)
+	if err != nil {
+		return Recovery{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pending := make(map[string]JournalEntry)
+	order := make([]string, 0)
+	for _, entry := range entries {
+		if _, ok := pending[entry.Id]; !ok {
+			order = append(order, entry.Id)
+		}
+		pending[entry.Id] = entry
+	}
+
+	var recovery Recovery
+	kept := make([]JournalEntry, 0)
+	for _, id := range order {
+		entry := pending[id]
+		path, err := BlobPath(dir, id)
+		if err != nil {
+			return Recovery{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		switch entry.Op {
+		case JournalPut:
+			checksum, err := Checksum(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return Recovery{}, fmt.Errorf("%s: %w", op, err)
+			}
+
+			if err == nil && checksum == entry.Checksum {
+				recovery.Verified = append(recovery.Verified, id)
+			} else {
+				recovery.Incomplete = append(recovery.Incomplete, id)
+				kept = append(kept, entry)
+			}
+		case JournalRemove:
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return Recovery{}, fmt.Errorf("%s: os.Remove: %w", op, err)
+			}
+			recovery.Removed = append(recovery.Removed, id)
+		}
+	}
+
+	if err := RewriteJournal(dir, durability, kept); err != nil {
+		return Recovery{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return recovery, nil
+}
+
+// RewriteJournal atomically replaces the journal of dir with entries.
+// Nothing may append to the journal meanwhile.
+func RewriteJournal(dir string, durability Durability, entries []JournalEntry) error {
+	const op = "storage.RewriteJournal"
+
+	path := filepath.Join(dir, JournalName)
+	if len(entries) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: os.Remove: %w", op, err)
+		}
+		return nil
+	}
+
+	file, err := os.OpenFile(path+".new", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: os.OpenFile: %w", op, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("%s: enc.Encode: %w", op, err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("%s: w.Flush: %w", op, err)
+	}
+
+	if err := durability.syncFile(file); err != nil {
+		return fmt.Errorf("%s: sync file: %w", op, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("%s: file.Close: %w", op, err)
+	}
+
+	if err := os.Rename(path+".new", path); err != nil {
+		return fmt.Errorf("%s: os.Rename: %w", op, err)
+	}
+
+	return durability.syncDir(dir)
+}
+
+// Checksum returns the hex sha256 of the file at path, as journaled for blobs
+func Checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
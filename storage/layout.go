@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Layout decides how blobs written to the storage dir are named.
+// The zero value behaves as LayoutRandom.
+type Layout string
+
+const (
+	// LayoutRandom names every blob with a new file id
+	LayoutRandom Layout = "random"
+	// LayoutContentAddressed names a blob after the sha256 of its ciphertext, see ContentAddress.
+	// A blob can then be verified by its name alone, stores with the same blobs list the same names,
+	// and files of identical ciphertext, such as convergent uploads, share one blob.
+	// Files reference their blob through files.blobName either way.
+	LayoutContentAddressed Layout = "content-addressed"
+)
+
+func (l *Layout) UnmarshalText(text []byte) error {
+	switch v := Layout(text); v {
+	case LayoutRandom, LayoutContentAddressed:
+		*l = v
+		return nil
+	case "":
+		*l = LayoutRandom
+		return nil
+	}
+
+	return fmt.Errorf("unknown blob layout %q; expected one of random, content-addressed", text)
+}
+
+// contentAddressPrefix names the hash of content addresses, so that they can't be taken for file ids
+const contentAddressPrefix = "sha256-"
+
+// ContentAddress returns the content-addressed name of a blob with the given hex sha256
+func ContentAddress(checksum string) string {
+	return contentAddressPrefix + checksum
+}
+
+// ContentChecksum returns the hex sha256 a content-addressed name stands for;
+// ok is false for names of any other layout
+func ContentChecksum(name string) (checksum string, ok bool) {
+	checksum, ok = strings.CutPrefix(name, contentAddressPrefix)
+	if !ok || len(checksum) != hex.EncodedLen(32) || strings.ToLower(checksum) != checksum {
+		return "", false
+	}
+
+	if _, err := hex.DecodeString(checksum); err != nil {
+		return "", false
+	}
+	return checksum, true
+}
+
+// ContentAddress returns the content-addressed name of what was written so far
+func (t *TempFile) ContentAddress() string {
+	return ContentAddress(hex.EncodeToString(t.hash.Sum(nil)))
+}
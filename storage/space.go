@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Space reports free space on the volume that holds Dir.
+// Reserve is kept free at all times for the db, temp files and encryption overhead.
+type Space struct {
+	Dir     string
+	Reserve uint64
+}
+
+type InsufficientSpaceError struct {
+	Free     uint64
+	Required uint64
+}
+
+func (err InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("insufficient storage: %d bytes free, %d required", err.Free, err.Required)
+}
+
+func (s Space) Free() (uint64, error) {
+	const op = "storage.Space.Free"
+
+	free, err := freeSpace(s.Dir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return free, nil
+}
+
+// Require returns InsufficientSpaceError if size bytes can't be stored without eating into Reserve
+func (s Space) Require(size int64) error {
+	const op = "storage.Space.Require"
+
+	free, err := s.Free()
+	if errors.Is(err, errors.ErrUnsupported) {
+		// nothing to check against on this platform
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	required := s.Reserve
+	if size > 0 {
+		required += uint64(size)
+	}
+
+	if free < required {
+		return InsufficientSpaceError{Free: free, Required: required}
+	}
+
+	return nil
+}
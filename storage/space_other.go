@@ -0,0 +1,11 @@
+//go:build !(linux || darwin)
+
+package storage
+
+import (
+	"errors"
+)
+
+func freeSpace(_ string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func freeSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, fmt.Errorf("syscall.Statfs: %w", err)
+	}
+
+	// Bavail excludes blocks reserved for root, which we can't write to
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
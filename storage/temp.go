@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// TempDirName is the directory inside the storage dir where blobs are written
+// before they get their final name. Anything found there on startup is garbage.
+const TempDirName = ".tmp"
+
+// TempFile is a blob that only appears in the storage dir once it is committed,
+// so a crash mid-write can't leave a truncated blob that looks valid.
+// Contents must be written sequentially through Write, WriteString or ReadFrom,
+// which keep the checksum journaled on commit.
+type TempFile struct {
+	*os.File
+	dir        string
+	durability Durability
+	hash       hash.Hash
+	done       bool
+}
+
+func CreateTemp(dir string, durability Durability) (*TempFile, error) {
+	const op = "storage.CreateTemp"
+
+	tmpDir := filepath.Join(dir, TempDirName)
+	path := filepath.Join(tmpDir, uuid.New().String())
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(tmpDir, 0o700); err != nil {
+			return nil, fmt.Errorf("%s: os.MkdirAll: %w", op, err)
+		}
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: os.OpenFile: %w", op, err)
+	}
+
+	return &TempFile{File: file, dir: dir, durability: durability, hash: sha256.New()}, nil
+}
+
+func (t *TempFile) Write(p []byte) (int, error) {
+	n, err := t.File.Write(p)
+	t.hash.Write(p[:n])
+	return n, err
+}
+
+func (t *TempFile) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+func (t *TempFile) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(t.File, io.TeeReader(r, t.hash))
+}
+
+// Open reads back what was written so far
+func (t *TempFile) Open() (*os.File, error) {
+	return os.Open(t.Name())
+}
+
+// CommitAs flushes the file to disk as far as the durability asks and atomically moves it to dir/name
+func (t *TempFile) CommitAs(name string) error {
+	const op = "storage.TempFile.CommitAs"
+
+	path, err := BlobPath(t.dir, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := t.durability.syncFile(t.File); err != nil {
+		return fmt.Errorf("%s: sync file: %w", op, err)
+	}
+
+	if err := t.Close(); err != nil {
+		return fmt.Errorf("%s: t.Close: %w", op, err)
+	}
+
+	checksum := hex.EncodeToString(t.hash.Sum(nil))
+	if err := appendJournal(t.dir, t.durability, JournalEntry{Op: JournalPut, Id: name, Checksum: checksum}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(t.Name(), path); err != nil {
+		return fmt.Errorf("%s: os.Rename: %w", op, err)
+	}
+	t.done = true
+
+	if err := t.durability.syncDir(t.dir); err != nil {
+		// callers drop the db row on error, so the blob must not outlive it; a content-addressed blob
+		// may have been there before, for other rows
+		if _, ok := ContentChecksum(name); !ok {
+			os.Remove(path)
+		}
+		return fmt.Errorf("%s: sync dir: %w", op, err)
+	}
+
+	// the blob is in place either way; without the entry recovery just verifies its checksum
+	appendJournal(t.dir, t.durability, JournalEntry{Op: JournalPutDone, Id: name})
+
+	return nil
+}
+
+// Discard removes the file unless it was committed; safe to defer right after CreateTemp
+func (t *TempFile) Discard() error {
+	const op = "storage.TempFile.Discard"
+
+	if t.done {
+		return nil
+	}
+	t.done = true
+
+	// the file may already be closed by a failed CommitAs
+	t.Close()
+
+	if err := os.Remove(t.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: os.Remove: %w", op, err)
+	}
+
+	return nil
+}
+
+// SweepTemp removes leftovers of writes interrupted by a crash and returns how many there were.
+// It must run before anything starts writing to dir.
+func SweepTemp(dir string) (int, error) {
+	const op = "storage.SweepTemp"
+
+	tmpDir := filepath.Join(dir, TempDirName)
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("%s: os.ReadDir: %w", op, err)
+	}
+
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return 0, fmt.Errorf("%s: os.RemoveAll: %w", op, err)
+	}
+
+	if err := os.Mkdir(tmpDir, 0o700); err != nil {
+		return 0, fmt.Errorf("%s: os.Mkdir: %w", op, err)
+	}
+
+	return len(entries), nil
+}
@@ -0,0 +1,76 @@
+package storage_test
+
+import (
+	"cloud-storage/storage"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "~", expected: home},
+		{path: "~/cloud/files", expected: filepath.Join(home, "cloud", "files")},
+		{path: "files/../blobs", expected: filepath.Join(wd, "blobs")},
+		{path: "/srv/files/", expected: "/srv/files"},
+		{path: "./~files", expected: filepath.Join(wd, "~files")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			path, err := storage.ExpandPath(tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, path)
+		})
+	}
+
+	_, err = storage.ExpandPath("~other/files")
+	assert.Error(t, err)
+}
+
+func TestPrepareDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b", "files")
+	require.NoError(t, storage.PrepareDir(dir, 0o750))
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	// the umask may take bits away, never add them
+	assert.Zero(t, info.Mode().Perm()&^0o750)
+
+	// the probe is gone and an existing dir is fine
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NoError(t, storage.PrepareDir(dir, 0o750))
+
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	assert.Error(t, storage.PrepareDir(file, 0o700))
+
+	if os.Geteuid() != 0 {
+		readOnly := t.TempDir()
+		require.NoError(t, os.Chmod(readOnly, 0o500))
+		assert.Error(t, storage.PrepareDir(readOnly, 0o700))
+	}
+}
+
+func TestDirMode_UnmarshalText(t *testing.T) {
+	var mode storage.DirMode
+	require.NoError(t, mode.UnmarshalText([]byte("0750")))
+	assert.Equal(t, storage.DirMode(0o750), mode)
+
+	assert.Error(t, mode.UnmarshalText([]byte("0800")))
+	assert.Error(t, mode.UnmarshalText([]byte("17777")))
+}
@@ -0,0 +1,57 @@
+package storage_test
+
+import (
+	"cloud-storage/storage"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFileId(t *testing.T) {
+	id, err := uuid.Parse(storage.NewFileId(false))
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(4), id.Version())
+
+	ids := make([]string, 0, 100)
+	for range 100 {
+		id := storage.NewFileId(true)
+		parsed, err := uuid.Parse(id)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), parsed.Version())
+		ids = append(ids, id)
+	}
+
+	// ids generated later sort after earlier ones
+	assert.True(t, slices.IsSorted(ids))
+}
+
+func TestValidFileId(t *testing.T) {
+	assert.True(t, storage.ValidFileId(storage.NewFileId(true)))
+	assert.True(t, storage.ValidFileId(storage.NewFileId(false)))
+
+	for _, id := range []string{"", "..", "../" + storage.NewFileId(true), strings.ToUpper(storage.NewFileId(true)), "{" + storage.NewFileId(true) + "}"} {
+		assert.False(t, storage.ValidFileId(id), id)
+	}
+}
+
+func TestBlobPath(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := storage.BlobPath(dir, "blob")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "blob"), path)
+
+	path, err = storage.BlobPath(dir, "hls/blob")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "hls", "blob"), path)
+
+	for _, name := range []string{"", ".", "..", "../blob", "hls/../../blob", "/etc/passwd"} {
+		_, err := storage.BlobPath(dir, name)
+		assert.ErrorIs(t, err, storage.ErrInvalidName, name)
+	}
+}
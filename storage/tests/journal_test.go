@@ -0,0 +1,76 @@
+package storage_test
+
+import (
+	"bytes"
+	"cloud-storage/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("content"), 1000)
+	sum := sha256.Sum256(content)
+
+	tmp, err := storage.CreateTemp(dir, storage.DurabilityNone)
+	require.NoError(t, err)
+	defer tmp.Discard()
+
+	// goes through ReadFrom
+	_, err = io.Copy(tmp, bytes.NewReader(content))
+	require.NoError(t, err)
+	require.NoError(t, tmp.CommitAs("blob"))
+	require.NoError(t, storage.Remove(dir, storage.DurabilityNone, "blob"))
+
+	entries, err := storage.ReadJournal(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.JournalEntry{
+		{Op: storage.JournalPut, Id: "blob", Checksum: hex.EncodeToString(sum[:])},
+		{Op: storage.JournalPutDone, Id: "blob"},
+		{Op: storage.JournalRemove, Id: "blob"},
+		{Op: storage.JournalRemoveDone, Id: "blob"},
+	}, entries)
+
+	recovery, err := storage.Recover(dir, storage.DurabilityNone)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Recovery{}, recovery)
+
+	_, err = os.Stat(filepath.Join(dir, storage.JournalName))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+
+	tmp, err := storage.CreateTemp(dir, storage.DurabilityNone)
+	require.NoError(t, err)
+	defer tmp.Discard()
+	_, err = tmp.WriteString("blob")
+	require.NoError(t, err)
+	require.NoError(t, tmp.CommitAs("complete"))
+
+	// drop the done entry as if the crash came right after the rename
+	entries, err := storage.ReadJournal(dir)
+	require.NoError(t, err)
+	require.NoError(t, storage.RewriteJournal(dir, storage.DurabilityNone, append(entries[:1],
+		storage.JournalEntry{Op: storage.JournalPut, Id: "truncated", Checksum: entries[0].Checksum},
+	)))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "truncated"), []byte("bl"), 0o600))
+
+	recovery, err := storage.Recover(dir, storage.DurabilityNone)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"complete"}, recovery.Verified)
+	assert.Equal(t, []string{"truncated"}, recovery.Incomplete)
+
+	entries, err = storage.ReadJournal(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "truncated", entries[0].Id)
+}
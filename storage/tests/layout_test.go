@@ -0,0 +1,78 @@
+package storage_test
+
+import (
+	"cloud-storage/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout_UnmarshalText(t *testing.T) {
+	var layout storage.Layout
+	require.NoError(t, layout.UnmarshalText([]byte("content-addressed")))
+	assert.Equal(t, storage.LayoutContentAddressed, layout)
+
+	require.NoError(t, layout.UnmarshalText(nil))
+	assert.Equal(t, storage.LayoutRandom, layout)
+
+	assert.Error(t, layout.UnmarshalText([]byte("cas")))
+}
+
+func TestTempFile_ContentAddress(t *testing.T) {
+	dir := t.TempDir()
+
+	tmp, err := storage.CreateTemp(dir, storage.DurabilityNone)
+	require.NoError(t, err)
+	defer tmp.Discard()
+
+	_, err = tmp.WriteString("content")
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte("content"))
+	name := tmp.ContentAddress()
+	assert.Equal(t, storage.ContentAddress(hex.EncodeToString(sum[:])), name)
+	require.NoError(t, tmp.CommitAs(name))
+
+	checksum, ok := storage.ContentChecksum(name)
+	require.True(t, ok)
+	actual, err := storage.Checksum(filepath.Join(dir, name))
+	require.NoError(t, err)
+	assert.Equal(t, checksum, actual)
+
+	// committing the same content again leaves the blob as it was
+	again, err := storage.CreateTemp(dir, storage.DurabilityNone)
+	require.NoError(t, err)
+	defer again.Discard()
+	_, err = again.WriteString("content")
+	require.NoError(t, err)
+	require.NoError(t, again.CommitAs(again.ContentAddress()))
+
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("content"), content)
+}
+
+func TestContentChecksum(t *testing.T) {
+	checksum := hex.EncodeToString(make([]byte, sha256.Size))
+
+	got, ok := storage.ContentChecksum(storage.ContentAddress(checksum))
+	assert.True(t, ok)
+	assert.Equal(t, checksum, got)
+
+	for _, name := range []string{
+		storage.NewFileId(true),
+		checksum,
+		storage.ContentAddress(checksum[2:]),
+		storage.ContentAddress(strings.ToUpper("ab" + checksum[2:])),
+		storage.ContentAddress("zz" + checksum[2:]),
+	} {
+		_, ok := storage.ContentChecksum(name)
+		assert.False(t, ok, name)
+	}
+}
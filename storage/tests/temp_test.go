@@ -0,0 +1,108 @@
+package storage_test
+
+import (
+	"cloud-storage/storage"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTempFile_CommitAs(t *testing.T) {
+	dir := t.TempDir()
+
+	tmp, err := storage.CreateTemp(dir, storage.DurabilityFull)
+	assert.NoError(t, err)
+	defer tmp.Discard()
+
+	_, err = tmp.Write([]byte("content"))
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "blob"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.NoError(t, tmp.CommitAs("blob"))
+	assert.NoError(t, tmp.Discard())
+
+	content, err := os.ReadFile(filepath.Join(dir, "blob"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("content"), content)
+
+	entries, err := os.ReadDir(filepath.Join(dir, storage.TempDirName))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestTempFile_CommitAsWithDurability(t *testing.T) {
+	for _, durability := range []storage.Durability{storage.DurabilityNone, storage.DurabilityData, storage.DurabilityFull} {
+		t.Run(string(durability), func(t *testing.T) {
+			dir := t.TempDir()
+
+			tmp, err := storage.CreateTemp(dir, durability)
+			assert.NoError(t, err)
+			defer tmp.Discard()
+
+			_, err = tmp.Write([]byte("content"))
+			assert.NoError(t, err)
+			assert.NoError(t, tmp.CommitAs("blob"))
+
+			content, err := os.ReadFile(filepath.Join(dir, "blob"))
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("content"), content)
+		})
+	}
+}
+
+func TestDurability_UnmarshalText(t *testing.T) {
+	var d storage.Durability
+	assert.NoError(t, d.UnmarshalText([]byte("fdatasync")))
+	assert.Equal(t, storage.DurabilityData, d)
+
+	assert.NoError(t, d.UnmarshalText([]byte("")))
+	assert.Equal(t, storage.DurabilityFull, d)
+
+	assert.Error(t, d.UnmarshalText([]byte("sometimes")))
+}
+
+func TestTempFile_Discard(t *testing.T) {
+	dir := t.TempDir()
+
+	tmp, err := storage.CreateTemp(dir, storage.DurabilityFull)
+	assert.NoError(t, err)
+
+	_, err = tmp.Write([]byte("partial"))
+	assert.NoError(t, err)
+	assert.NoError(t, tmp.Discard())
+
+	entries, err := os.ReadDir(filepath.Join(dir, storage.TempDirName))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSweepTemp(t *testing.T) {
+	dir := t.TempDir()
+
+	n, err := storage.SweepTemp(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// simulate writes interrupted by a crash
+	for range 3 {
+		tmp, err := storage.CreateTemp(dir, storage.DurabilityFull)
+		assert.NoError(t, err)
+		tmp.Close()
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "blob"), []byte("kept"), 0o600))
+
+	n, err = storage.SweepTemp(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	entries, err := os.ReadDir(filepath.Join(dir, storage.TempDirName))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = os.Stat(filepath.Join(dir, "blob"))
+	assert.NoError(t, err)
+}
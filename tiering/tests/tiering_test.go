@@ -0,0 +1,183 @@
+package tiering_test
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/db_access/sqlite"
+	"cloud-storage/jobs"
+	"cloud-storage/storage"
+	"cloud-storage/tiering"
+	slogext "cloud-storage/utils/slogExt"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMigrator(t *testing.T, cfg tiering.Config) (*tiering.Migrator, db_access.DbAccess, string, string) {
+	dir := t.TempDir()
+	coldDir := t.TempDir()
+
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+
+	blobs := blobstore.NewStore(dir, storage.DurabilityNone, map[string]blobstore.Backend{
+		"cold": blobstore.NewLocal(coldDir, storage.DurabilityNone),
+	})
+
+	cfg.Jobs = jobs.New(db, jobs.Config{}, slogext.NewDiscardLogger())
+	m, err := tiering.New(db, blobs, cfg, slogext.NewDiscardLogger())
+	require.NoError(t, err)
+
+	return m, db, dir, coldDir
+}
+
+func TestMigration(t *testing.T) {
+	m, db, dir, coldDir := newMigrator(t, tiering.Config{})
+
+	require.NoError(t, db.AddFile("old", "enc:old", 1, 4))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old"), []byte("blob"), 0o600))
+	require.NoError(t, db.AddFileCopy("old-copy", "enc:copy", 1, "old", 4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	cutoff := time.Now().Add(time.Second)
+	started, err := m.Start("", "cold", cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, db_access.MigrationQueued, started.Status)
+
+	var migration db_access.Migration
+	require.Eventually(t, func() bool {
+		migration, err = db.GetMigration(started.Id)
+		require.NoError(t, err)
+		return migration.Status == db_access.MigrationFinished
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, int64(1), migration.TotalBlobs)
+	assert.Equal(t, int64(1), migration.MovedBlobs)
+	assert.Equal(t, int64(4), migration.MovedBytes)
+
+	// the jobs api reports on the migration as well
+	var job db_access.Job
+	require.Eventually(t, func() bool {
+		job, err = db.GetJob(started.Id)
+		require.NoError(t, err)
+		return job.Status == db_access.JobFinished
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, jobs.TypeMigration, job.Type)
+	assert.Equal(t, int64(1), job.Done)
+	assert.Equal(t, int64(1), job.Total)
+	assert.JSONEq(t, `{"moved_blobs":1,"failed_blobs":0,"moved_bytes":4}`, job.Result)
+
+	for _, name := range []string{"old", "old-copy"} {
+		file, err := db.GetFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, "cold", file.Backend)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "old"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	content, err := os.ReadFile(filepath.Join(coldDir, "old"))
+	require.NoError(t, err)
+	assert.Equal(t, "blob", string(content))
+
+	// copies follow the blob to where it is now
+	require.NoError(t, db.AddFileCopy("new-copy", "enc:copy", 1, "old", 4))
+	file, err := db.GetFile("new-copy")
+	require.NoError(t, err)
+	assert.Equal(t, "cold", file.Backend)
+
+	blobs, err := db.ListBlobNames()
+	require.NoError(t, err)
+	assert.Empty(t, blobs)
+}
+
+func TestMoveDeletedFile(t *testing.T) {
+	m, db, dir, coldDir := newMigrator(t, tiering.Config{})
+
+	require.NoError(t, db.AddFile("gone", "enc:gone", 1, 4))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gone"), []byte("blob"), 0o600))
+	_, _, err := db.DeleteFile("gone")
+	require.NoError(t, err)
+
+	n, err := m.Move(context.Background(), "gone", blobstore.Local, "cold")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = os.Stat(filepath.Join(coldDir, "gone"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStartInvalid(t *testing.T) {
+	m, _, _, _ := newMigrator(t, tiering.Config{})
+
+	_, err := m.Start("cold", "cold", time.Now())
+	assert.ErrorAs(t, err, &tiering.InvalidMigrationError{})
+
+	_, err = m.Start("local", "glacier", time.Now())
+	assert.ErrorAs(t, err, &tiering.InvalidMigrationError{})
+}
+
+func TestApplyRules(t *testing.T) {
+	m, db, dir, coldDir := newMigrator(t, tiering.Config{Rules: []tiering.Rule{
+		{To: "cold", IdleFor: 24 * time.Hour, MinSize: 100},
+		{From: "cold", AccessedWithin: time.Hour},
+	}})
+
+	now := time.Now()
+	for name, size := range map[string]int64{"small": 10, "idle": 1000, "used": 1000} {
+		require.NoError(t, db.AddFile(name, "enc:"+name, 1, size))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("blob"), 0o600))
+	}
+	require.NoError(t, db.RecordFileAccesses([]db_access.FileAccess{
+		{GeneratedName: "used", At: db_access.Time(now.Add(47 * time.Hour)), Count: 1},
+	}))
+
+	m.ApplyRules(context.Background(), now.Add(48*time.Hour))
+
+	for name, backend := range map[string]string{"small": "local", "idle": "cold", "used": "local"} {
+		file, err := db.GetFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, backend, file.Backend, name)
+	}
+	_, err := os.Stat(filepath.Join(coldDir, "idle"))
+	assert.NoError(t, err)
+
+	// a download brings it back on the next run
+	require.NoError(t, db.RecordFileAccesses([]db_access.FileAccess{
+		{GeneratedName: "idle", At: db_access.Time(now.Add(72 * time.Hour)), Count: 1},
+	}))
+	m.ApplyRules(context.Background(), now.Add(72*time.Hour+time.Minute))
+
+	file, err := db.GetFile("idle")
+	require.NoError(t, err)
+	assert.Equal(t, "local", file.Backend)
+	_, err = os.Stat(filepath.Join(dir, "idle"))
+	assert.NoError(t, err)
+}
+
+func TestNewInvalidRules(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+	blobs := blobstore.NewStore(t.TempDir(), storage.DurabilityNone, nil)
+
+	for _, rule := range []tiering.Rule{
+		{To: "glacier", MinAge: time.Hour},
+		{To: "local", MinAge: time.Hour},
+	} {
+		_, err := tiering.New(db, blobs, tiering.Config{Rules: []tiering.Rule{rule}}, slogext.NewDiscardLogger())
+		assert.ErrorAs(t, err, &tiering.InvalidMigrationError{})
+	}
+
+	blobs = blobstore.NewStore(t.TempDir(), storage.DurabilityNone, map[string]blobstore.Backend{
+		"cold": blobstore.NewLocal(t.TempDir(), storage.DurabilityNone),
+	})
+	_, err = tiering.New(db, blobs, tiering.Config{Rules: []tiering.Rule{{To: "cold"}}}, slogext.NewDiscardLogger())
+	assert.ErrorAs(t, err, &tiering.InvalidMigrationError{})
+}
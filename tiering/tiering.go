@@ -0,0 +1,359 @@
+// Package tiering moves blobs between storage backends, either on request of an admin
+// or by rules that put files on the backend matching how old and how used they are.
+package tiering
+
+import (
+	"cloud-storage/blobstore"
+	"cloud-storage/db_access"
+	"cloud-storage/jobs"
+	slogext "cloud-storage/utils/slogExt"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// migrations run one after another, a few may wait for their turn
+const queueSize = 8
+
+// Rule moves blobs from one backend to another once all of its set conditions hold
+type Rule struct {
+	From string
+	To   string
+	// files created at least this long ago
+	MinAge time.Duration
+	// files not downloaded for this long; never downloaded files count from their creation
+	IdleFor time.Duration
+	// files downloaded within this time, e.g. to bring them back from cold storage
+	AccessedWithin time.Duration
+	// sizes are plaintext bytes, MaxSize 0 means no limit
+	MinSize int64
+	MaxSize int64
+}
+
+func (r Rule) filter(now time.Time) db_access.BlobFilter {
+	var filter db_access.BlobFilter
+	if r.MinAge > 0 {
+		filter.CreatedBefore = db_access.Time(now.Add(-r.MinAge))
+	}
+	if r.IdleFor > 0 {
+		filter.AccessedBefore = db_access.Time(now.Add(-r.IdleFor))
+	}
+	if r.AccessedWithin > 0 {
+		filter.AccessedAfter = db_access.Time(now.Add(-r.AccessedWithin))
+	}
+	filter.MinSize = r.MinSize
+	filter.MaxSize = r.MaxSize
+	return filter
+}
+
+type Config struct {
+	// rules are applied in order, so a blob moved by one rule may be moved on by the next
+	Rules []Rule
+	// how often rules are applied; they aren't if it is zero
+	Interval time.Duration
+	// Jobs reports on migrations, which the Migrator runs itself as they must not overlap with the rules
+	Jobs *jobs.Pool
+}
+
+// Migrator runs migrations and rules one at a time, so no blob is ever moved twice at once
+type Migrator struct {
+	db    db_access.DbAccess
+	blobs *blobstore.Store
+	cfg   Config
+	queue chan queuedMigration
+	log   *slog.Logger
+}
+
+type queuedMigration struct {
+	migration db_access.Migration
+	r         *jobs.Reporter
+}
+
+var ErrQueueFull = errors.New("migration queue is full")
+
+type InvalidMigrationError struct {
+	Reason string
+}
+
+func (err InvalidMigrationError) Error() string {
+	return fmt.Sprintf("invalid migration: %s", err.Reason)
+}
+
+func New(db db_access.DbAccess, blobs *blobstore.Store, cfg Config, log *slog.Logger) (*Migrator, error) {
+	const op = "tiering.New"
+
+	cfg.Rules = slices.Clone(cfg.Rules)
+	for i, rule := range cfg.Rules {
+		rule.From = cmp.Or(rule.From, blobstore.Local)
+		rule.To = cmp.Or(rule.To, blobstore.Local)
+		cfg.Rules[i] = rule
+
+		if err := validateBackends(blobs, rule.From, rule.To); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", op, i, err)
+		}
+
+		if rule.MinAge <= 0 && rule.IdleFor <= 0 && rule.AccessedWithin <= 0 && rule.MinSize <= 0 && rule.MaxSize <= 0 {
+			return nil, fmt.Errorf("%s: rule %d: %w", op, i, InvalidMigrationError{Reason: "rule has no conditions"})
+		}
+	}
+
+	return &Migrator{
+		db:    db,
+		blobs: blobs,
+		cfg:   cfg,
+		queue: make(chan queuedMigration, queueSize),
+		log:   log.With(slog.String("component", "tiering")),
+	}, nil
+}
+
+func validateBackends(blobs *blobstore.Store, from string, to string) error {
+	for _, name := range []string{from, to} {
+		if _, err := blobs.Backend(name); err != nil {
+			return InvalidMigrationError{Reason: err.Error()}
+		}
+	}
+
+	if from == to {
+		return InvalidMigrationError{Reason: "source and destination backends are the same"}
+	}
+
+	return nil
+}
+
+// Run works through queued migrations and applies the rules every interval until ctx is done.
+func (m *Migrator) Run(ctx context.Context) {
+	if err := m.db.FailUnfinishedMigrations("Interrupted by server restart"); err != nil {
+		m.log.Error("Could not fail unfinished migrations", slogext.Error(err))
+	}
+
+	var tick <-chan time.Time
+	if m.cfg.Interval > 0 && len(m.cfg.Rules) > 0 {
+		ticker := time.NewTicker(m.cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case queued := <-m.queue:
+			m.run(ctx, queued.migration, queued.r)
+		case now := <-tick:
+			m.ApplyRules(ctx, now)
+		}
+	}
+}
+
+// ApplyRules moves the blobs matching each rule as of now. It must not run
+// concurrently with Run, which calls it itself.
+func (m *Migrator) ApplyRules(ctx context.Context, now time.Time) {
+	for i, rule := range m.cfg.Rules {
+		log := m.log.With(slog.Int("rule", i), slog.String("from", rule.From), slog.String("to", rule.To))
+
+		blobs, err := m.db.GetBlobs(rule.From, rule.filter(now))
+		if err != nil {
+			log.Error("Could not get blobs matching rule", slogext.Error(err))
+			continue
+		}
+
+		var moved, failed int
+		for _, blob := range blobs {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if _, err := m.Move(ctx, blob.Name, rule.From, rule.To); err != nil {
+				log.Error("Could not move blob", slogext.Error(err), slog.String("blob", blob.Name))
+				failed++
+				continue
+			}
+			moved++
+		}
+
+		if moved > 0 || failed > 0 {
+			log.Info("Applied tiering rule", slog.Int("moved", moved), slog.Int("failed", failed))
+		}
+	}
+}
+
+// Start queues moving the blobs of files created before createdBefore from one backend to another.
+// The job of the migration has the same id, no owner, and counts blobs as its units of work.
+func (m *Migrator) Start(from string, to string, createdBefore time.Time) (db_access.Migration, error) {
+	const op = "tiering.Migrator.Start"
+
+	from = cmp.Or(from, blobstore.Local)
+	to = cmp.Or(to, blobstore.Local)
+
+	if err := validateBackends(m.blobs, from, to); err != nil {
+		return db_access.Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migration := db_access.Migration{
+		Id:            uuid.New().String(),
+		FromBackend:   from,
+		ToBackend:     to,
+		CreatedBefore: db_access.Time(createdBefore),
+		Status:        db_access.MigrationQueued,
+		CreationTime:  db_access.Time(time.Now()),
+	}
+
+	if err := m.db.AddMigration(&migration); err != nil {
+		return db_access.Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r, err := m.cfg.Jobs.Track(db_access.Job{Id: migration.Id, Type: jobs.TypeMigration})
+	if err != nil {
+		return db_access.Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	select {
+	case m.queue <- queuedMigration{migration: migration, r: r}:
+	default:
+		migration.Status = db_access.MigrationFailed
+		migration.Error = ErrQueueFull.Error()
+		if err := m.db.UpdateMigration(&migration); err != nil {
+			m.log.Error("Could not update migration", slogext.Error(err), slog.String("migration-id", migration.Id))
+		}
+		r.Finish(nil, jobs.Failure(migration.Error))
+		return db_access.Migration{}, fmt.Errorf("%s: %w", op, ErrQueueFull)
+	}
+
+	return migration, nil
+}
+
+// MigrationResult is what the job of a migration results in
+type MigrationResult struct {
+	MovedBlobs  int64 `json:"moved_blobs"`
+	FailedBlobs int64 `json:"failed_blobs"`
+	MovedBytes  int64 `json:"moved_bytes"`
+}
+
+func (m *Migrator) run(ctx context.Context, migration db_access.Migration, r *jobs.Reporter) {
+	log := m.log.With(
+		slog.String("migration-id", migration.Id),
+		slog.String("from", migration.FromBackend),
+		slog.String("to", migration.ToBackend),
+	)
+
+	update := func() {
+		if err := m.db.UpdateMigration(&migration); err != nil {
+			log.Error("Could not update migration", slogext.Error(err))
+		}
+	}
+
+	migration.Status = db_access.MigrationRunning
+	update()
+	r.Start()
+
+	blobs, err := m.db.GetBlobs(migration.FromBackend, db_access.BlobFilter{CreatedBefore: migration.CreatedBefore})
+	if err != nil {
+		log.Error("Could not get blobs to migrate", slogext.Error(err))
+		migration.Status = db_access.MigrationFailed
+		migration.Error = "Could not get blobs to migrate"
+		update()
+		r.Finish(nil, jobs.Failure(migration.Error))
+		return
+	}
+
+	migration.TotalBlobs = int64(len(blobs))
+	update()
+	r.SetTotal(migration.TotalBlobs)
+
+	for _, blob := range blobs {
+		n, err := m.Move(ctx, blob.Name, migration.FromBackend, migration.ToBackend)
+		if err != nil {
+			log.Error("Could not move blob", slogext.Error(err), slog.String("blob", blob.Name))
+			migration.FailedBlobs++
+		} else {
+			migration.MovedBlobs++
+			migration.MovedBytes += n
+		}
+		update()
+		r.Add(1)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	migration.Status = db_access.MigrationFinished
+	if ctx.Err() != nil {
+		migration.Status = db_access.MigrationFailed
+		migration.Error = "Interrupted by server shutdown"
+	}
+	update()
+
+	if migration.Status == db_access.MigrationFailed {
+		r.Finish(nil, jobs.Failure(migration.Error))
+	} else {
+		r.Finish(MigrationResult{
+			MovedBlobs:  migration.MovedBlobs,
+			FailedBlobs: migration.FailedBlobs,
+			MovedBytes:  migration.MovedBytes,
+		}, nil)
+	}
+
+	log.Info(
+		"Migration finished",
+		slog.Int64("moved", migration.MovedBlobs),
+		slog.Int64("failed", migration.FailedBlobs),
+	)
+}
+
+// Move copies the blob to the destination, points its files there and only then removes
+// the source, so the files can be downloaded throughout. A file deleted while its blob is
+// in flight leaves nothing behind: the deletion removes the blob from whichever backend
+// the row pointed to and the copy left on the other one is removed here.
+// It returns how many bytes were copied.
+func (m *Migrator) Move(ctx context.Context, blobName string, from string, to string) (int64, error) {
+	const op = "tiering.Migrator.Move"
+
+	src, err := m.blobs.Backend(from)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	dst, err := m.blobs.Backend(to)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	obj, err := src.Open(ctx, blobName)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = dst.Put(ctx, blobName, obj, obj.Size)
+	obj.Close()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	moved, err := m.db.SetBlobBackend(blobName, from, to)
+	if err != nil || !moved {
+		// background context: the copy must go even if ctx was cancelled meanwhile
+		if err := dst.Remove(context.Background(), blobName); err != nil {
+			m.log.Error("Could not remove copied blob", slogext.Error(err), slog.String("blob", blobName), slog.String("backend", to))
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		return 0, nil
+	}
+
+	// downloads that opened the source before the switch may still be reading a local blob,
+	// which is fine as an open file outlives its removal
+	if err := src.Remove(context.Background(), blobName); err != nil {
+		m.log.Error("Could not remove migrated blob", slogext.Error(err), slog.String("blob", blobName), slog.String("backend", from))
+	}
+
+	return obj.Size, nil
+}
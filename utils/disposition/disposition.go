@@ -0,0 +1,69 @@
+// Package disposition formats Content-Disposition headers for file names of any script.
+// Names that aren't plain ASCII get an RFC 5987 filename* parameter with the UTF-8 name,
+// next to an ASCII filename for clients that don't read it.
+package disposition
+
+import (
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+const hex = "0123456789ABCDEF"
+
+// Format returns the header value for disposition, which is inline or attachment
+func Format(disposition string, fileName string) string {
+	if isPlainASCII(fileName) {
+		return mime.FormatMediaType(disposition, map[string]string{"filename": fileName})
+	}
+
+	fileName = strings.ToValidUTF8(fileName, string(utf8.RuneError))
+	return mime.FormatMediaType(disposition, map[string]string{"filename": asciiFallback(fileName)}) +
+		"; filename*=UTF-8''" + encodeExtValue(fileName)
+}
+
+func isPlainASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// asciiFallback keeps the extension intact, so that the fallback name still opens with the right program
+func asciiFallback(fileName string) string {
+	var b strings.Builder
+	for _, r := range fileName {
+		if r >= 0x20 && r <= 0x7e {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// encodeExtValue percent-encodes every byte that isn't an attr-char of RFC 5987
+func encodeExtValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
+
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
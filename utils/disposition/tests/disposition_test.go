@@ -0,0 +1,61 @@
+package disposition_test
+
+import (
+	"cloud-storage/utils/disposition"
+	"mime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name        string
+		disposition string
+		fileName    string
+		expected    string
+	}{
+		{name: "Token", disposition: "inline", fileName: "report.txt", expected: `inline; filename=report.txt`},
+		{name: "Quoted", disposition: "attachment", fileName: `my "best" file.txt`, expected: `attachment; filename="my \"best\" file.txt"`},
+		{
+			name:        "Cyrillic",
+			disposition: "attachment",
+			fileName:    "отчёт.pdf",
+			expected:    `attachment; filename=_____.pdf; filename*=UTF-8''%D0%BE%D1%82%D1%87%D1%91%D1%82.pdf`,
+		},
+		{
+			name:        "Emoji and spaces",
+			disposition: "inline",
+			fileName:    "cat 🐈.jpg",
+			expected:    `inline; filename="cat _.jpg"; filename*=UTF-8''cat%20%F0%9F%90%88.jpg`,
+		},
+		{
+			name:        "Control characters",
+			disposition: "attachment",
+			fileName:    "a\nb.txt",
+			expected:    `attachment; filename=a_b.txt; filename*=UTF-8''a%0Ab.txt`,
+		},
+		{
+			name:        "Invalid UTF-8",
+			disposition: "attachment",
+			fileName:    "a\xffb",
+			expected:    `attachment; filename=a_b; filename*=UTF-8''a%EF%BF%BDb`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := disposition.Format(tc.disposition, tc.fileName)
+			assert.Equal(t, tc.expected, header)
+
+			// clients that understand filename* get the name back
+			mediaType, params, err := mime.ParseMediaType(header)
+			require.NoError(t, err)
+			assert.Equal(t, tc.disposition, mediaType)
+			if tc.name != "Invalid UTF-8" {
+				assert.Equal(t, tc.fileName, params["filename"])
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package drain keeps HTTP/1 connections reusable when handlers reject a request without
+// reading its body. The server only discards a small unread body by itself and closes
+// the connection for anything bigger.
+package drain
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultLimit is enough for any rejected form or json body without letting
+// a rejected upload keep the server reading
+const DefaultLimit = 1 << 20
+
+type drainingWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	limit       int64
+	wroteHeader bool
+}
+
+func (w *drainingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *drainingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status >= 400 {
+			Body(w.ResponseWriter, w.r, w.limit)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *drainingWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Middleware drains the body of every request answered with an error status, see Body
+func Middleware(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&drainingWriter{ResponseWriter: w, r: r, limit: limit}, r)
+		})
+	}
+}
+
+// Body reads what is left of the request body, up to limit bytes, so that the connection can
+// serve the next request. When more is left, the connection is closed after the response instead.
+// It has to run before the response status is written.
+func Body(w http.ResponseWriter, r *http.Request, limit int64) {
+	if r.ProtoMajor != 1 || r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+
+	// reading would ask a client waiting for 100 Continue to send a body nobody wants;
+	// the server closes such connections by itself
+	if strings.EqualFold(r.Header.Get("Expect"), "100-continue") {
+		return
+	}
+
+	_, err := io.CopyN(io.Discard, r.Body, limit+1)
+	if !errors.Is(err, io.EOF) {
+		w.Header().Set("Connection", "close")
+	}
+}
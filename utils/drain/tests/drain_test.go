@@ -0,0 +1,64 @@
+package drain_test
+
+import (
+	"bytes"
+	"cloud-storage/utils/drain"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	const limit = 1 << 20
+
+	// rejects everything without looking at the body
+	reject := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unsupported", http.StatusUnsupportedMediaType)
+	})
+	server := httptest.NewServer(drain.Middleware(limit)(reject))
+	defer server.Close()
+
+	testCases := []struct {
+		name   string
+		size   int
+		reused bool
+	}{
+		// bigger than what net/http discards by itself
+		{name: "Below limit", size: limit / 2, reused: true},
+		{name: "Above limit", size: limit * 2, reused: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := server.Client()
+			defer client.CloseIdleConnections()
+
+			send := func() (bool, *http.Response) {
+				var reused bool
+				trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+
+				req, err := http.NewRequest("POST", server.URL, bytes.NewReader(make([]byte, tc.size)))
+				require.NoError(t, err)
+				req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+				resp, err := client.Do(req)
+				require.NoError(t, err)
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				return reused, resp
+			}
+
+			_, resp := send()
+			assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+			assert.Equal(t, !tc.reused, resp.Close)
+
+			reused, _ := send()
+			assert.Equal(t, tc.reused, reused)
+		})
+	}
+}
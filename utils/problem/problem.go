@@ -0,0 +1,122 @@
+// Package problem implements the application/problem+json error format of RFC 7807
+// for clients that ask for it; everyone else keeps getting the legacy error envelope.
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const ContentType = "application/problem+json"
+
+// type URIs are urns so that they do not pretend to be dereferenceable
+const typePrefix = "urn:cloud-storage:problem:"
+
+// TypeURI turns a short problem name like "not-found" into a type URI
+func TypeURI(name string) string {
+	return typePrefix + name
+}
+
+// Param is an extension member describing one failed parameter or item
+type Param struct {
+	Code   int    `json:"code"`
+	Type   string `json:"type"`
+	Name   string `json:"name,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type Details struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// legacy numeric code of the first error, kept so that clients can migrate gradually
+	Code   int     `json:"code"`
+	Errors []Param `json:"errors,omitempty"`
+	// extension member for unexpected failures, so that they can be found in the logs
+	RequestId string `json:"request_id,omitempty"`
+}
+
+type negotiatedWriter struct {
+	http.ResponseWriter
+	instance string
+}
+
+func (w *negotiatedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Negotiate marks responses of requests accepting application/problem+json
+// so that error writers further down can pick the format.
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accepts(r.Header.Values("Accept")) {
+			w = &negotiatedWriter{ResponseWriter: w, instance: r.URL.Path}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func accepts(values []string) bool {
+	for _, value := range values {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil || mediaType != ContentType {
+				continue
+			}
+			if q, ok := params["q"]; ok && strings.Trim(q, "0.") == "" {
+				continue
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+func negotiated(w http.ResponseWriter) (*negotiatedWriter, bool) {
+	for {
+		switch ww := w.(type) {
+		case *negotiatedWriter:
+			return ww, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = ww.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// Requested reports whether the client asked for problem details
+func Requested(w http.ResponseWriter) bool {
+	_, ok := negotiated(w)
+	return ok
+}
+
+// Write sends details with the given status; Status and Instance are filled in when empty
+func Write(w http.ResponseWriter, details Details, status int) error {
+	const op = "problem.Write"
+
+	details.Status = status
+	if nw, ok := negotiated(w); ok && details.Instance == "" {
+		details.Instance = nw.instance
+	}
+
+	body, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("%s: json.Marshal: %w", op, err)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		return fmt.Errorf("%s: w.Write: %w", op, err)
+	}
+
+	return nil
+}
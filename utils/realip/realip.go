@@ -0,0 +1,137 @@
+// Package realip finds the address of the client behind reverse proxies. Forwarding headers
+// are only believed when they come from a trusted proxy, since anyone else can set them.
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	forwardedForHeader = "X-Forwarded-For"
+	realIpHeader       = "X-Real-IP"
+)
+
+// Proxies lists the networks whose forwarding headers are trusted
+type Proxies []netip.Prefix
+
+// ParseProxies accepts networks in CIDR notation and single addresses
+func ParseProxies(values []string) (Proxies, error) {
+	const op = "realip.ParseProxies"
+
+	proxies := make(Proxies, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return proxies, nil
+}
+
+// Trusts reports whether the address, with or without a port, is in one of the networks
+func (p Proxies) Trusts(addr string) bool {
+	parsed, ok := parseAddr(addr)
+	return ok && p.trusts(parsed)
+}
+
+func (p Proxies) trusts(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr returns the address of the client that sent r. X-Forwarded-For is read from the right,
+// skipping trusted proxies, so hops added by the client itself are never picked.
+// X-Real-IP is used when a trusted peer sends no X-Forwarded-For.
+func ClientAddr(r *http.Request, proxies Proxies) string {
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok || !proxies.trusts(peer) {
+		return r.RemoteAddr
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values(forwardedForHeader) {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			// whatever is further left can't be told apart from garbage
+			break
+		}
+
+		client = hop
+		if !proxies.trusts(hop) {
+			break
+		}
+	}
+
+	if len(hops) == 0 {
+		if addr, ok := parseAddr(strings.TrimSpace(r.Header.Get(realIpHeader))); ok {
+			client = addr
+		}
+	}
+
+	return client.String()
+}
+
+type peerCtx struct{}
+
+// Middleware replaces RemoteAddr with the address reported by trusted proxies,
+// so that logging and everything else down the chain sees the real client.
+// The address of the connection itself stays available through PeerAddr.
+func Middleware(proxies Proxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), peerCtx{}, r.RemoteAddr))
+			if len(proxies) > 0 {
+				r.RemoteAddr = ClientAddr(r, proxies)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PeerAddr returns the address the request came in from, before Middleware replaced RemoteAddr
+func PeerAddr(r *http.Request) string {
+	if peer, ok := r.Context().Value(peerCtx{}).(string); ok {
+		return peer
+	}
+	return r.RemoteAddr
+}
+
+// parseAddr accepts addresses with or without a port
+func parseAddr(value string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
@@ -0,0 +1,104 @@
+package realip_test
+
+import (
+	"cloud-storage/utils/realip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientAddr(t *testing.T) {
+	proxies, err := realip.ParseProxies([]string{"10.0.0.0/8", "192.168.1.1", "::ffff:172.16.0.1"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIp       string
+		expected     string
+	}{
+		{name: "Untrusted peer", remoteAddr: "203.0.113.7:1234", forwardedFor: []string{"198.51.100.1"}, expected: "203.0.113.7:1234"},
+		{name: "Trusted peer without headers", remoteAddr: "10.0.0.2:1234", expected: "10.0.0.2"},
+		{name: "Single hop", remoteAddr: "10.0.0.2:1234", forwardedFor: []string{"198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "Spoofed hop is skipped", remoteAddr: "10.0.0.2:1234", forwardedFor: []string{"1.1.1.1, 198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "Chain of proxies", remoteAddr: "192.168.1.1:1234", forwardedFor: []string{"198.51.100.1, 10.1.1.1", "172.16.0.1"}, expected: "198.51.100.1"},
+		{name: "Only proxies", remoteAddr: "10.0.0.2:1234", forwardedFor: []string{"10.0.0.3, 10.0.0.4"}, expected: "10.0.0.3"},
+		{name: "Garbage hop", remoteAddr: "10.0.0.2:1234", forwardedFor: []string{"198.51.100.1, unknown, 10.0.0.4"}, expected: "10.0.0.4"},
+		{name: "Hop with port", remoteAddr: "10.0.0.2:1234", forwardedFor: []string{"[2001:db8::1]:443"}, expected: "2001:db8::1"},
+		{name: "Real ip", remoteAddr: "10.0.0.2:1234", realIp: "198.51.100.1", expected: "198.51.100.1"},
+		{name: "Forwarded for wins over real ip", remoteAddr: "10.0.0.2:1234", forwardedFor: []string{"198.51.100.1"}, realIp: "198.51.100.2", expected: "198.51.100.1"},
+		{name: "Real ip from untrusted peer", remoteAddr: "203.0.113.7:1234", realIp: "198.51.100.1", expected: "203.0.113.7:1234"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tc.realIp != "" {
+				r.Header.Set("X-Real-IP", tc.realIp)
+			}
+
+			assert.Equal(t, tc.expected, realip.ClientAddr(r, proxies))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.RemoteAddr
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	// without trusted proxies the headers are ignored
+	realip.Middleware(nil)(next).ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "10.0.0.2:1234", seen)
+
+	proxies, err := realip.ParseProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	realip.Middleware(proxies)(next).ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "198.51.100.1", seen)
+}
+
+func TestPeerAddr(t *testing.T) {
+	var peer, remote string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, remote = realip.PeerAddr(r), r.RemoteAddr
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	proxies, err := realip.ParseProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	realip.Middleware(proxies)(next).ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "10.0.0.2:1234", peer)
+	assert.Equal(t, "198.51.100.1", remote)
+
+	// without the middleware the peer is RemoteAddr
+	assert.Equal(t, "10.0.0.2:1234", realip.PeerAddr(r))
+
+	assert.True(t, proxies.Trusts("10.1.2.3:80"))
+	assert.True(t, proxies.Trusts("::ffff:10.1.2.3"))
+	assert.False(t, proxies.Trusts("198.51.100.1"))
+	assert.False(t, proxies.Trusts("garbage"))
+}
+
+func TestParseProxies(t *testing.T) {
+	_, err := realip.ParseProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	_, err = realip.ParseProxies([]string{"proxy.local"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+// Package safehttp fetches urls that users hand to the server. Users must not be able to make it reach
+// anything they can't reach themselves, such as services on the loopback or the private network,
+// so hosts have to be allowed and resolve to public addresses only.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const maxRedirects = 5
+
+var (
+	// ErrHostNotAllowed is returned for urls, including redirects, to hosts off the allowlist
+	ErrHostNotAllowed = errors.New("host is not allowed")
+	// ErrForbiddenAddress is returned when a host resolves to an address that isn't public
+	ErrForbiddenAddress = errors.New("address is not public")
+)
+
+// Hosts is an allowlist of host names. "*.example.com" allows the subdomains of example.com,
+// and "*" allows any host; an empty list allows none.
+type Hosts []string
+
+// Allows reports whether the url is http or https on an allowed host
+func (h Hosts) Allows(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return false
+	}
+
+	for _, allowed := range h {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Public reports whether addr is a public unicast address
+func Public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// carrier-grade NAT, which IsPrivate leaves out
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewClient returns a client that only follows urls hosts allows and only connects to public addresses.
+// The address is checked as it is dialed, so a host can't resolve to a public one when checked
+// and a private one when connected to. Requests give up after timeout unless it is zero.
+func NewClient(hosts Hosts, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !Public(addrPort.Addr()) {
+				return fmt.Errorf("%s: %w", addrPort.Addr(), ErrForbiddenAddress)
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		// a proxy would connect on behalf of the server, past the address check
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		ForceAttemptHTTP2:   true,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if !hosts.Allows(r.URL) {
+				return fmt.Errorf("redirect to %s: %w", r.URL.Host, ErrHostNotAllowed)
+			}
+			return nil
+		},
+	}
+}
+
+// Get fetches u unless hosts doesn't allow it; the caller closes the body of a successful response
+func Get(ctx context.Context, client *http.Client, hosts Hosts, u *url.URL) (*http.Response, error) {
+	if !hosts.Allows(u) {
+		return nil, fmt.Errorf("%s: %w", u.Host, ErrHostNotAllowed)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,76 @@
+package safehttp_test
+
+import (
+	"cloud-storage/utils/safehttp"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHosts_Allows(t *testing.T) {
+	hosts := safehttp.Hosts{"example.com", "*.files.example.org"}
+
+	testCases := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://example.com/a.txt", allowed: true},
+		{url: "http://EXAMPLE.com./a.txt", allowed: true},
+		{url: "https://example.com:8443/a.txt", allowed: true},
+		{url: "https://cdn.files.example.org/a.txt", allowed: true},
+		{url: "https://files.example.org/a.txt"},
+		{url: "https://evilfiles.example.org/a.txt"},
+		{url: "https://example.com.evil.net/a.txt"},
+		{url: "https://sub.example.com/a.txt"},
+		{url: "ftp://example.com/a.txt"},
+		{url: "file:///etc/passwd"},
+	}
+
+	for _, tc := range testCases {
+		u, err := url.Parse(tc.url)
+		require.NoError(t, err)
+		assert.Equal(t, tc.allowed, hosts.Allows(u), tc.url)
+	}
+
+	u, _ := url.Parse("https://anything.net/")
+	assert.True(t, safehttp.Hosts{"*"}.Allows(u))
+	assert.False(t, safehttp.Hosts{}.Allows(u))
+}
+
+func TestPublic(t *testing.T) {
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		assert.True(t, safehttp.Public(netip.MustParseAddr(addr)), addr)
+	}
+
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.0.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "::", "fc00::1", "fe80::1", "224.0.0.1", "::ffff:127.0.0.1",
+	} {
+		assert.False(t, safehttp.Public(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestGet_ForbiddenAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client := safehttp.NewClient(safehttp.Hosts{"*"}, time.Second)
+
+	// allowed hosts still may not resolve to the loopback
+	_, err = safehttp.Get(context.Background(), client, safehttp.Hosts{"*"}, u)
+	assert.ErrorIs(t, err, safehttp.ErrForbiddenAddress)
+
+	_, err = safehttp.Get(context.Background(), client, safehttp.Hosts{"example.com"}, u)
+	assert.ErrorIs(t, err, safehttp.ErrHostNotAllowed)
+}
@@ -0,0 +1,34 @@
+package validate_test
+
+import (
+	"cloud-storage/utils/validate"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	var v validate.Validator
+	assert.True(t, v.Valid())
+
+	assert.True(t, v.Required("a", "x"))
+	assert.True(t, v.MaxLen("a", "xy", 2))
+	assert.True(t, v.Range("b", 5, 1, 5))
+	assert.True(t, v.Valid())
+
+	assert.False(t, v.UTF8("c", "\xff"))
+	assert.Equal(t, http.StatusBadRequest, v.Status())
+
+	assert.False(t, v.Required("a", ""))
+	assert.False(t, v.Range("b", 6, 1, 5))
+	assert.False(t, v.MaxLen("a", "xyz", 2))
+	assert.Equal(t, http.StatusUnprocessableEntity, v.Status())
+
+	assert.Equal(t, []validate.Failure{
+		{Param: "c", Kind: validate.Malformed, Message: "c must be valid UTF-8"},
+		{Param: "a", Kind: validate.Missing, Message: "a is required"},
+		{Param: "b", Kind: validate.OutOfRange, Message: "b must be from 1 to 5"},
+		{Param: "a", Kind: validate.OutOfRange, Message: "a must be at most 2 bytes long"},
+	}, v.Failures)
+}
@@ -0,0 +1,77 @@
+// Package validate collects the failed checks of a request, so that handlers answer with all of them at once
+// instead of one per round trip. Handlers turn failures into the error codes of their own responses.
+package validate
+
+import (
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+)
+
+type Kind int
+
+const (
+	// Missing is a required value that is empty or absent
+	Missing Kind = iota
+	// OutOfRange is a number, size or length beyond its bounds
+	OutOfRange
+	// Malformed is a value that doesn't parse or doesn't have the expected format
+	Malformed
+)
+
+type Failure struct {
+	Param   string
+	Kind    Kind
+	Message string
+}
+
+// Validator is ready to use as its zero value
+type Validator struct {
+	Failures []Failure
+}
+
+func (v *Validator) Valid() bool {
+	return len(v.Failures) == 0
+}
+
+// Fail records a failure and returns false, so checks of their own read like the ones below
+func (v *Validator) Fail(param string, kind Kind, message string) bool {
+	v.Failures = append(v.Failures, Failure{Param: param, Kind: kind, Message: message})
+	return false
+}
+
+// Check records the failure unless ok
+func (v *Validator) Check(ok bool, param string, kind Kind, message string) bool {
+	if ok {
+		return true
+	}
+	return v.Fail(param, kind, message)
+}
+
+func (v *Validator) Required(param string, value string) bool {
+	return v.Check(value != "", param, Missing, fmt.Sprintf("%s is required", param))
+}
+
+// MaxLen checks the length of value in bytes, which is what storage limits are about
+func (v *Validator) MaxLen(param string, value string, max int) bool {
+	return v.Check(len(value) <= max, param, OutOfRange, fmt.Sprintf("%s must be at most %d bytes long", param, max))
+}
+
+// Range checks min <= value <= max
+func (v *Validator) Range(param string, value int64, min int64, max int64) bool {
+	return v.Check(value >= min && value <= max, param, OutOfRange, fmt.Sprintf("%s must be from %d to %d", param, min, max))
+}
+
+func (v *Validator) UTF8(param string, value string) bool {
+	return v.Check(utf8.ValidString(value), param, Malformed, fmt.Sprintf("%s must be valid UTF-8", param))
+}
+
+// Status is 400 if every failure is a malformed value, which the client can't have meant, and 422 otherwise
+func (v *Validator) Status() int {
+	for _, failure := range v.Failures {
+		if failure.Kind != Malformed {
+			return http.StatusUnprocessableEntity
+		}
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,173 @@
+"use strict";
+
+const tokenKey = "session_token";
+
+const $ = (id) => document.getElementById(id);
+
+function setStatus(text) {
+	$("status").textContent = text;
+}
+
+function describeErrors(body, fallback) {
+	if (body && Array.isArray(body.errors) && body.errors.length > 0) {
+		return body.errors.map((e) => e.description || `error ${e.code}`).join("; ");
+	}
+	return fallback;
+}
+
+async function api(path, options = {}) {
+	const headers = new Headers(options.headers || {});
+	const token = sessionStorage.getItem(tokenKey);
+	if (token) {
+		headers.set("Authorization", `Bearer ${token}`);
+	}
+
+	const resp = await fetch(`/api${path}`, { ...options, headers });
+	if (resp.status === 401 && token) {
+		logout();
+	}
+	return resp;
+}
+
+async function readJson(resp) {
+	try {
+		return await resp.json();
+	} catch {
+		return null;
+	}
+}
+
+async function authenticate(path) {
+	const body = JSON.stringify({ name: $("name").value, password: $("password").value });
+	const resp = await api(path, { method: "POST", body, headers: { "Content-Type": "application/json" } });
+	if (!resp.ok) {
+		throw new Error(describeErrors(await readJson(resp), `request failed with ${resp.status}`));
+	}
+	return resp;
+}
+
+async function login() {
+	const resp = await authenticate("/auth/login");
+	const body = await resp.json();
+	sessionStorage.setItem(tokenKey, body.session_token);
+	show();
+}
+
+function logout() {
+	sessionStorage.removeItem(tokenKey);
+	show();
+}
+
+function show() {
+	const loggedIn = sessionStorage.getItem(tokenKey) !== null;
+	$("login").hidden = loggedIn;
+	$("files").hidden = !loggedIn;
+	if (loggedIn) {
+		refresh().catch((e) => setStatus(e.message));
+	}
+}
+
+async function refresh() {
+	const resp = await api("/files");
+	const body = await readJson(resp);
+	if (!resp.ok) {
+		throw new Error(describeErrors(body, "could not list files"));
+	}
+
+	const list = $("file-list");
+	list.replaceChildren();
+	for (const file of body.files) {
+		const row = document.createElement("tr");
+
+		const name = document.createElement("td");
+		name.textContent = file.file_name;
+
+		const actions = document.createElement("td");
+		const download = document.createElement("button");
+		download.type = "button";
+		download.textContent = "Download";
+		download.addEventListener("click", () => downloadFile(file).catch((e) => setStatus(e.message)));
+		actions.append(download);
+
+		row.append(name, actions);
+		list.append(row);
+	}
+}
+
+// the server wants the size as a little endian int64 before the file itself
+function fileSizePart(size) {
+	const buf = new ArrayBuffer(8);
+	new DataView(buf).setBigUint64(0, BigInt(size), true);
+	return new Blob([buf]);
+}
+
+async function upload(files) {
+	for (const file of files) {
+		setStatus(`Uploading ${file.name}…`);
+
+		const form = new FormData();
+		form.append("file-size", fileSizePart(file.size));
+		form.append("file", file, file.name);
+
+		const resp = await api("/upload", { method: "POST", body: form });
+		if (!resp.ok) {
+			throw new Error(`${file.name}: ${describeErrors(await readJson(resp), "upload failed")}`);
+		}
+	}
+
+	setStatus("Upload finished");
+	await refresh();
+}
+
+async function downloadFile(file) {
+	setStatus(`Downloading ${file.file_name}…`);
+
+	const resp = await api(`/files/${encodeURIComponent(file.id)}`);
+	if (!resp.ok) {
+		throw new Error(describeErrors(await readJson(resp), "download failed"));
+	}
+
+	// downloads are single-part multipart forms
+	const form = await resp.formData();
+	const content = form.get("file");
+
+	const link = document.createElement("a");
+	link.href = URL.createObjectURL(content);
+	link.download = file.file_name;
+	link.click();
+	URL.revokeObjectURL(link.href);
+
+	setStatus("");
+}
+
+$("login-form").addEventListener("submit", (e) => {
+	e.preventDefault();
+	login().catch((err) => setStatus(err.message));
+});
+
+$("register").addEventListener("click", () => {
+	authenticate("/auth/register")
+		.then(login)
+		.catch((err) => setStatus(err.message));
+});
+
+$("logout").addEventListener("click", logout);
+
+const drop = $("drop");
+drop.addEventListener("dragover", (e) => {
+	e.preventDefault();
+	drop.classList.add("over");
+});
+drop.addEventListener("dragleave", () => drop.classList.remove("over"));
+drop.addEventListener("drop", (e) => {
+	e.preventDefault();
+	drop.classList.remove("over");
+	upload(e.dataTransfer.files).catch((err) => setStatus(err.message));
+});
+
+$("picker").addEventListener("change", (e) => {
+	upload(e.target.files).catch((err) => setStatus(err.message));
+	e.target.value = "";
+});
+
+show();
@@ -0,0 +1,37 @@
+package web_test
+
+import (
+	"cloud-storage/web"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		path         string
+		expectedCode int
+		contentType  string
+	}{
+		{path: "/", expectedCode: http.StatusOK, contentType: "text/html"},
+		{path: "/app.js", expectedCode: http.StatusOK, contentType: "text/javascript"},
+		{path: "/style.css", expectedCode: http.StatusOK, contentType: "text/css"},
+		{path: "/missing.js", expectedCode: http.StatusNotFound},
+	}
+
+	h := web.Handler()
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.path, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedCode, w.Result().StatusCode)
+			assert.True(t, strings.HasPrefix(w.Result().Header.Get("Content-Type"), tc.contentType))
+			assert.Equal(t, "default-src 'self'", w.Result().Header.Get("Content-Security-Policy"))
+		})
+	}
+}